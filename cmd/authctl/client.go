@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+// client calls the admin API
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(baseURL, token string) *client {
+	return &client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// withTimeout returns a copy of c whose requests may take up to d
+func (c *client) withTimeout(d time.Duration) *client {
+	copied := *c
+	copied.http = &http.Client{Transport: c.http.Transport, Timeout: d}
+	return &copied
+}
+
+// newInProcessClient calls the admin API of handler without a network
+func newInProcessClient(handler http.Handler, token string) *client {
+	c := newClient("http://authctl", token)
+	c.http.Transport = handlerTransport{handler}
+	return c
+}
+
+// handlerTransport serves requests with a handler in the same process
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.RemoteAddr = "127.0.0.1:0"
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
+
+// adminUser is a user as the admin API returns it
+type adminUser struct {
+	ID      int64     `json:"id"`
+	Email   string    `json:"email"`
+	Role    string    `json:"role"`
+	Status  string    `json:"status"`
+	Created time.Time `json:"created_at"`
+}
+
+// do sends a request with body encoded as JSON and decodes the response into
+// out. Responses other than 2xx are returned as errors with the API's message,
+// and decoded into out as well for the results they may carry.
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			problem.Problem
+			Violations []struct {
+				Message string `json:"message"`
+			} `json:"violations"`
+		}
+		message := strings.TrimSpace(string(b))
+		if json.Unmarshal(b, &apiErr) == nil && apiErr.Code != "" {
+			message = apiErr.Detail
+			if message == "" {
+				message = apiErr.Title
+			}
+			for _, v := range apiErr.Violations {
+				message += "; password " + v.Message
+			}
+		}
+		if out != nil {
+			json.Unmarshal(b, out)
+		}
+		return fmt.Errorf("%s %s: %s (%s)", method, path, message, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
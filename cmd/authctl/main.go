@@ -0,0 +1,423 @@
+// Command authctl runs routine administration tasks against the admin API,
+// so operators do not have to write SQL for them:
+//
+//	authctl user create -email user@example.com [-password-stdin]
+//	authctl user list [-email prefix] [-locked] [-disabled] [-limit 50] [-cursor c]
+//	authctl user import [-dry-run] [-batch 1000] <file.csv or file.json>
+//	authctl user unlock <id or email>
+//	authctl session revoke <user id or email>
+//	authctl key rotate
+//
+// Requests go to the service at -url with the admin token (ADMIN_API_TOKEN).
+// With -dsn, authctl instead connects to the database itself, wiring the
+// services from the same configuration as the server (-config and the
+// environment), so passwords are hashed and events recorded the same way.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"github.com/Stewz00/go-auth-service/internal/logging"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/Stewz00/go-auth-service/pkg/server"
+)
+
+const usage = `usage: authctl [flags] <command> [arguments]
+
+commands:
+  user create -email <email> [-password-stdin]   create a user; without a password, a temporary one is printed
+  user list [-email <prefix>] [-locked] [-disabled] [-limit <n>] [-cursor <c>]
+  user import [-dry-run] [-batch <n>] <file>     import users from a CSV or JSON file, see the README
+  user unlock <id or email>                      clear a lockout after failed sign-ins
+  session revoke <user id or email>              sign a user out everywhere
+  key rotate                                     print JWT_SECRETS with a new secret in front
+
+flags:
+`
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "authctl:", err)
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned for command lines authctl does not understand
+var errUsage = errors.New("see authctl -h for usage")
+
+// run executes the command line args
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("authctl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	apiURL := flags.String("url", envOr("AUTHCTL_URL", "http://localhost:8080"), "base URL of the service")
+	token := flags.String("token", os.Getenv("ADMIN_API_TOKEN"), "admin API token")
+	dsn := flags.String("dsn", "", "connect to this database directly instead of the service")
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "with -dsn, the server's YAML or TOML configuration file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if len(args) < 2 {
+		if len(args) == 1 && args[0] == "help" {
+			flags.SetOutput(stdout)
+			flags.Usage()
+			return nil
+		}
+		return errUsage
+	}
+
+	// Generating a secret needs neither the service nor the database
+	if args[0] == "key" {
+		if args[1] != "rotate" || len(args) > 2 {
+			return errUsage
+		}
+		return rotateKey(stdout)
+	}
+
+	var c *client
+	if *dsn != "" {
+		srv, adminToken, err := openServer(*dsn, *configFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			srv.Shutdown(ctx)
+		}()
+		c = newInProcessClient(srv.Handler(), adminToken)
+	} else {
+		if *token == "" {
+			return errors.New("an admin token is required: set ADMIN_API_TOKEN or -token, or use -dsn")
+		}
+		c = newClient(*apiURL, *token)
+	}
+
+	switch args[0] + " " + args[1] {
+	case "user create":
+		return createUser(ctx, c, args[2:], stdin, stdout)
+	case "user list":
+		return listUsers(ctx, c, args[2:], stdout)
+	case "user import":
+		return importUsers(ctx, c, args[2:], stdout)
+	case "user unlock":
+		id, err := userArg(ctx, c, args[2:])
+		if err != nil {
+			return err
+		}
+		if err := c.do(ctx, "DELETE", fmt.Sprintf("/admin/users/%d/lockout", id), nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Unlocked user %d\n", id)
+		return nil
+	case "session revoke":
+		id, err := userArg(ctx, c, args[2:])
+		if err != nil {
+			return err
+		}
+		var resp struct {
+			Revoked int64 `json:"revoked"`
+		}
+		if err := c.do(ctx, "POST", fmt.Sprintf("/admin/users/%d/sessions/revoke", id), nil, &resp); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Revoked %d sessions of user %d\n", resp.Revoked, id)
+		return nil
+	}
+	return errUsage
+}
+
+// openServer wires the server's services to the database at dsn without
+// listening, returning it with an admin token for its handler. The token is
+// generated when none is configured.
+func openServer(dsn, configFile string) (*server.Server, string, error) {
+	// Only warnings, so request logs do not mix with the output
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelWarn))
+
+	os.Setenv("DATABASE_URL", dsn)
+	if os.Getenv("ADMIN_API_TOKEN") == "" {
+		token, err := randomSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		os.Setenv("ADMIN_API_TOKEN", token)
+	}
+	cfg, err := config.LoadFile(configFile)
+	if err != nil {
+		return nil, "", err
+	}
+	srv, err := server.New(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return srv, cfg.AdminAPIToken, nil
+}
+
+func createUser(ctx context.Context, c *client, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	email := flags.String("email", "", "email address of the new user")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of standard input")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || flags.NArg() > 0 {
+		return errors.New("user create needs -email and no other arguments")
+	}
+
+	req := map[string]string{"email": *email}
+	if *passwordStdin {
+		line, err := readLine(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read the password: %w", err)
+		}
+		req["password"] = line
+	}
+
+	var resp struct {
+		adminUser
+		TemporaryPassword string `json:"temporary_password"`
+	}
+	if err := c.do(ctx, "POST", "/admin/users", req, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Created user %d (%s)\n", resp.ID, resp.Email)
+	if resp.TemporaryPassword != "" {
+		fmt.Fprintf(stdout, "Temporary password, to be changed at the first sign-in: %s\n", resp.TemporaryPassword)
+	}
+	return nil
+}
+
+func listUsers(ctx context.Context, c *client, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("user list", flag.ContinueOnError)
+	email := flags.String("email", "", "only users whose email starts with this prefix")
+	locked := flags.Bool("locked", false, "only locked users")
+	disabled := flags.Bool("disabled", false, "only disabled users")
+	limit := flags.Int("limit", 50, "users per page")
+	cursor := flags.String("cursor", "", "cursor of the page to list, printed after the previous page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	q := url.Values{"limit": {strconv.Itoa(*limit)}}
+	if *email != "" {
+		q.Set("email", *email)
+	}
+	if *locked {
+		q.Set("locked", "true")
+	}
+	if *disabled {
+		q.Set("disabled", "true")
+	}
+	if *cursor != "" {
+		q.Set("cursor", *cursor)
+	}
+	var resp struct {
+		Users      []adminUser `json:"users"`
+		NextCursor string      `json:"next_cursor"`
+	}
+	if err := c.do(ctx, "GET", "/admin/users?"+q.Encode(), nil, &resp); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEMAIL\tROLE\tSTATUS\tCREATED")
+	for _, u := range resp.Users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", u.ID, u.Email, u.Role, u.Status, u.Created.UTC().Format(time.DateOnly))
+	}
+	tw.Flush()
+	if resp.NextCursor != "" {
+		fmt.Fprintf(stdout, "\nMore users: authctl user list -cursor %s\n", resp.NextCursor)
+	}
+	return nil
+}
+
+// importTimeout bounds an import request, which takes as long as hashing the
+// passwords of its users
+const importTimeout = 10 * time.Minute
+
+func importUsers(ctx context.Context, c *client, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("user import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only validate the users")
+	batch := flags.Int("batch", 100, "users per request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *batch < 1 || *batch > service.MaxImportRows {
+		return errors.New("user import needs one CSV or JSON file and a -batch of 1 to " + strconv.Itoa(service.MaxImportRows))
+	}
+	users, err := readImportFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return errors.New("no users to import")
+	}
+
+	path := "/admin/users/import"
+	if *dryRun {
+		path += "?dry_run=true"
+	}
+	// The server hashes the passwords of a request before it responds
+	c = c.withTimeout(importTimeout)
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tEMAIL\tERROR")
+	var total service.ImportResult
+	for start := 0; start < len(users); start += *batch {
+		var resp service.ImportResult
+		end := min(start+*batch, len(users))
+		err := c.do(ctx, "POST", path, map[string]any{"users": users[start:end]}, &resp)
+		for _, row := range resp.Rows {
+			if row.Status == service.ImportStatusFailed {
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", start+row.Row, row.Email, row.Error)
+			}
+		}
+		total.Created += resp.Created
+		total.Valid += resp.Valid
+		total.Failed += resp.Failed
+		if err != nil && len(resp.Rows) > 0 {
+			// The import stopped partway and reported every row of the request
+			tw.Flush()
+			return fmt.Errorf("imported %d users; the rows above failed and rows %d and later were not sent: %w", total.Created, end+1, err)
+		}
+		if err != nil {
+			tw.Flush()
+			return fmt.Errorf("rows %d and later were not imported: %w", start+1, err)
+		}
+	}
+	if total.Failed > 0 {
+		tw.Flush()
+		fmt.Fprintln(stdout)
+	}
+	if *dryRun {
+		fmt.Fprintf(stdout, "%d users valid, %d failed; nothing was imported\n", total.Valid, total.Failed)
+	} else {
+		fmt.Fprintf(stdout, "Imported %d users, %d failed\n", total.Created, total.Failed)
+	}
+	return nil
+}
+
+// readImportFile reads the users of a CSV file, or a JSON file holding an
+// array of users or an object with a users array
+func readImportFile(name string) ([]service.ImportUser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return service.ReadImportCSV(f)
+	}
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var users []service.ImportUser
+	if err := json.Unmarshal(b, &users); err != nil {
+		var wrapped struct {
+			Users []service.ImportUser `json:"users"`
+		}
+		if json.Unmarshal(b, &wrapped) != nil {
+			return nil, fmt.Errorf("%s is neither CSV nor a JSON array of users: %w", name, err)
+		}
+		users = wrapped.Users
+	}
+	return users, nil
+}
+
+// userArg returns the ID of the single user argument, which is an ID or an
+// email address
+func userArg(ctx context.Context, c *client, args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, errors.New("expected one user ID or email address")
+	}
+	if id, err := strconv.ParseInt(args[0], 10, 64); err == nil {
+		return id, nil
+	}
+
+	var resp struct {
+		Users []adminUser `json:"users"`
+	}
+	q := url.Values{"email": {args[0]}, "limit": {"200"}}
+	if err := c.do(ctx, "GET", "/admin/users?"+q.Encode(), nil, &resp); err != nil {
+		return 0, err
+	}
+	// The filter matches a prefix, so pick the exact address
+	i := slices.IndexFunc(resp.Users, func(u adminUser) bool { return strings.EqualFold(u.Email, args[0]) })
+	if i < 0 {
+		return 0, fmt.Errorf("no user with email %s", args[0])
+	}
+	return resp.Users[i].ID, nil
+}
+
+// rotateKey prints the JWT_SECRETS setting with a new secret in front of the
+// current ones, from JWT_SECRETS or JWT_SECRET
+func rotateKey(stdout io.Writer) error {
+	secret, err := randomSecret()
+	if err != nil {
+		return err
+	}
+	secrets := []string{secret}
+	if current := os.Getenv("JWT_SECRETS"); current != "" {
+		secrets = append(secrets, strings.Split(current, ",")...)
+	} else if current := os.Getenv("JWT_SECRET"); current != "" {
+		secrets = append(secrets, current)
+	}
+
+	fmt.Fprintf(stdout, "JWT_SECRETS=%s\n", strings.Join(secrets, ","))
+	if len(secrets) > 1 {
+		fmt.Fprintln(stdout, "\nDeploy it in place of JWT_SECRET or JWT_SECRETS and reload the service. Once tokens")
+		fmt.Fprintln(stdout, "signed with the old secrets have expired (TOKEN_TTL), remove them from the list.")
+	}
+	return nil
+}
+
+// randomSecret returns 48 random bytes encoded as base64
+func randomSecret() (string, error) {
+	b := make([]byte, 48)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// readLine reads the first line of r without its line ending
+func readLine(r io.Reader) (string, error) {
+	b, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(b), "\n")
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" {
+		return "", errors.New("empty password")
+	}
+	return line, nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
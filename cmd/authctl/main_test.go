@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"github.com/Stewz00/go-auth-service/pkg/server"
+)
+
+// authctl runs a command line and returns its output
+func authctl(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var out strings.Builder
+	err := run(context.Background(), args, strings.NewReader(stdin), &out)
+	return out.String(), err
+}
+
+func TestRunAgainstAPI(t *testing.T) {
+	srv, err := server.New(&config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		Storage:       "memory",
+		AdminAPIToken: "admin-token",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	api := []string{"-url", ts.URL, "-token", "admin-token"}
+	importFile := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(importFile, []byte("email,password\nbob@example.com,password123\nalice@example.com,password123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{name: "create with a temporary password", args: []string{"user", "create", "-email", "temp@example.com"},
+			want: "Temporary password, to be changed at the first sign-in: "},
+		{name: "create with a password", args: []string{"user", "create", "-email", "alice@example.com", "-password-stdin"},
+			stdin: "password123\n", want: "Created user 2 (alice@example.com)\n"},
+		{name: "weak password", args: []string{"user", "create", "-email", "weak@example.com", "-password-stdin"},
+			stdin: "short\n", wantErr: "Password does not meet the requirements; password must be at least"},
+		{name: "duplicate email", args: []string{"user", "create", "-email", "alice@example.com"},
+			wantErr: "Email is already registered (409 Conflict)"},
+		{name: "list", args: []string{"user", "list", "-email", "alice"}, want: "alice@example.com  user  active"},
+		{name: "import dry run", args: []string{"user", "import", "-dry-run", importFile}, want: "1 users valid, 1 failed; nothing was imported\n"},
+		{name: "import", args: []string{"user", "import", "-batch", "1", importFile},
+			want: "2    alice@example.com  email is already registered\n\nImported 1 users, 1 failed\n"},
+		{name: "revoke by email", args: []string{"session", "revoke", "alice@example.com"}, want: "Revoked 1 sessions of user 2\n"},
+		{name: "unlock by ID", args: []string{"user", "unlock", "2"}, want: "Unlocked user 2\n"},
+		{name: "unknown user", args: []string{"user", "unlock", "carol@example.com"}, wantErr: "no user with email carol@example.com"},
+		{name: "unknown command", args: []string{"user", "promote", "2"}, wantErr: errUsage.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "revoke by email" {
+				resp, err := http.Post(ts.URL+"/auth/login", "application/json", strings.NewReader(`{"email":"alice@example.com","password":"password123"}`))
+				if err != nil || resp.StatusCode != http.StatusOK {
+					t.Fatalf("login failed: %v", err)
+				}
+				resp.Body.Close()
+			}
+
+			out, err := authctl(t, tt.stdin, append(api, tt.args...)...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("got output %q, want %q", out, tt.want)
+			}
+		})
+	}
+
+	if _, err := authctl(t, "", "-url", ts.URL, "-token", "wrong", "user", "list"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("got error %v with a wrong token, want 401", err)
+	}
+}
+
+func TestRunAgainstDatabase(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("ADMIN_API_TOKEN", "")
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "auth.db")
+
+	if _, err := authctl(t, "password123", "-dsn", dsn, "user", "create", "-email", "carol@example.com", "-password-stdin"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	out, err := authctl(t, "", "-dsn", dsn, "user", "list")
+	if err != nil || !strings.Contains(out, "carol@example.com") {
+		t.Errorf("got %q (%v), want the user created in the previous run", out, err)
+	}
+}
+
+func TestKeyRotate(t *testing.T) {
+	t.Setenv("JWT_SECRETS", "")
+	t.Setenv("JWT_SECRET", "current-secret")
+
+	out, err := authctl(t, "", "key", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setting, _, _ := strings.Cut(out, "\n")
+	secrets := strings.Split(strings.TrimPrefix(setting, "JWT_SECRETS="), ",")
+	if len(secrets) != 2 || len(secrets[0]) != 64 || secrets[1] != "current-secret" {
+		t.Errorf("got %q, want a new secret in front of the current one", setting)
+	}
+}
@@ -0,0 +1,26 @@
+// Command breakglass generates a sealed break-glass operator credential.
+// Store the credential offline and deploy only its hash and expiry.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+func main() {
+	validFor := flag.Duration("valid-for", 90*24*time.Hour, "how long the credential can be redeemed")
+	flag.Parse()
+
+	credential, hash, err := service.GenerateBreakGlassCredential()
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Failed to generate credential: %v", err))
+	}
+
+	fmt.Printf("Credential (store offline, shown once): %s\n\n", credential)
+	fmt.Printf("BREAK_GLASS_CREDENTIAL_HASH=%s\n", hash)
+	fmt.Printf("BREAK_GLASS_EXPIRES_AT=%s\n", time.Now().Add(*validFor).UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,144 @@
+// Command migrate applies, rolls back and lists the online schema migrations
+// independently of server startup.
+//
+//	migrate [flags] up      apply pending migrations of -phase
+//	migrate [flags] down    roll back the latest applied migration
+//	migrate status          list every migration and when it was applied
+//
+// With -dry-run, up and down print the statements they would run without
+// touching the schema; up also runs the rewrite preflight.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/database/migrate"
+)
+
+func main() {
+	phase := flag.String("phase", string(migrate.Expand), "phase to apply with up: expand or contract")
+	dryRun := flag.Bool("dry-run", false, "print the pending statements instead of running them")
+	lockTimeout := flag.Duration("lock-timeout", 2*time.Second, "how long a statement waits for a table lock")
+	maxRewriteRows := flag.Int64("max-rewrite-rows", 100000, "estimated row count above which table rewrites are refused")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up|down|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if p := migrate.Phase(*phase); p != migrate.Expand && p != migrate.Contract {
+		log.Fatal(fmt.Sprintf("Invalid -phase %q", *phase))
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+	if driver, err := database.DriverFor(dbURL); err != nil || driver != database.DriverPostgres {
+		log.Fatal("Online migrations require PostgreSQL; load internal/database/schema_mysql.sql on MySQL, SQLite creates its schema on open")
+	}
+
+	db, err := database.New(dbURL)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Failed to connect to database: %v", err))
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator := migrate.New(db.Pool,
+		migrate.WithLockTimeout(*lockTimeout),
+		migrate.WithMaxRewriteRows(*maxRewriteRows),
+	)
+
+	switch flag.Arg(0) {
+	case "up":
+		err = up(ctx, migrator, migrate.Phase(*phase), *dryRun)
+	case "down":
+		err = down(ctx, migrator, *dryRun)
+	case "status":
+		err = status(ctx, migrator)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func up(ctx context.Context, migrator *migrate.Migrator, phase migrate.Phase, dryRun bool) error {
+	if !dryRun {
+		applied, err := migrator.Apply(ctx, database.Migrations, phase)
+		for _, mig := range applied {
+			fmt.Printf("Applied %d %s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Printf("No pending %s migrations\n", phase)
+		}
+		return err
+	}
+
+	todo, err := migrator.Pending(ctx, database.Migrations, phase)
+	if err != nil {
+		return err
+	}
+	if len(todo) == 0 {
+		fmt.Printf("No pending %s migrations\n", phase)
+		return nil
+	}
+	for _, mig := range todo {
+		fmt.Printf("Would apply %d %s (%s)\n", mig.Version, mig.Name, mig.Phase)
+		printSteps(mig.Steps)
+	}
+	return migrator.Preflight(ctx, database.Migrations, phase)
+}
+
+func down(ctx context.Context, migrator *migrate.Migrator, dryRun bool) error {
+	if !dryRun {
+		mig, err := migrator.Rollback(ctx, database.Migrations)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d %s\n", mig.Version, mig.Name)
+		return nil
+	}
+
+	mig, err := migrator.Latest(ctx, database.Migrations)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Would roll back %d %s (%s)\n", mig.Version, mig.Name, mig.Phase)
+	printSteps(mig.Down)
+	return nil
+}
+
+func status(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx, database.Migrations)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Printf("%4d  %-8s  %-30s  %s\n", s.Version, s.Phase, s.Name, applied)
+	}
+	return nil
+}
+
+func printSteps(steps []migrate.Step) {
+	for _, step := range steps {
+		fmt.Printf("    %s;\n", step.SQL)
+	}
+}
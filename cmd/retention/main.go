@@ -0,0 +1,71 @@
+// Command retention permanently deletes users that were soft-deleted longer
+// ago than the retention period, together with their sessions, identities,
+// and API keys. Run it on a schedule, e.g. daily from cron.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+func main() {
+	retain := flag.Duration("retain", 30*24*time.Hour, "how long soft-deleted users are kept before they are purged")
+	flag.Parse()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+	if *retain < 0 {
+		log.Fatal("-retain must not be negative")
+	}
+
+	users, closeDB, err := openUsers(dbURL)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Failed to connect to database: %v", err))
+	}
+	defer closeDB()
+
+	cutoff := time.Now().Add(-*retain)
+	purged, err := users.PurgeDeletedUsers(context.Background(), cutoff)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Failed to purge deleted users: %v", err))
+	}
+	fmt.Printf("Purged %d users deleted before %s\n", purged, cutoff.UTC().Format(time.RFC3339))
+}
+
+// openUsers connects to the database selected by the scheme of dbURL
+func openUsers(dbURL string) (interfaces.UserRepository, func(), error) {
+	driver, err := database.DriverFor(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch driver {
+	case database.DriverMySQL:
+		db, err := database.NewMySQL(dbURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repository.NewMySQLUserRepository(db), db.Close, nil
+	case database.DriverSQLite:
+		db, err := database.NewSQLite(dbURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repository.NewSQLiteUserRepository(db), db.Close, nil
+	}
+
+	db, err := database.New(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repository.NewUserRepository(db), db.Close, nil
+}
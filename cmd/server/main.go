@@ -2,86 +2,68 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"net/http"
+	"flag"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/Stewz00/go-auth-service/internal/config"
-	"github.com/Stewz00/go-auth-service/internal/database"
-	"github.com/Stewz00/go-auth-service/internal/handler"
-	"github.com/Stewz00/go-auth-service/internal/middleware"
-	"github.com/Stewz00/go-auth-service/internal/repository"
-	"github.com/Stewz00/go-auth-service/internal/service"
-	"github.com/go-chi/chi/v5"
-	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/Stewz00/go-auth-service/internal/logging"
+	"github.com/Stewz00/go-auth-service/internal/tracing"
+	"github.com/Stewz00/go-auth-service/pkg/server"
 )
 
 func main() {
+	// Log as JSON from the start; the configured level applies once loaded
+	// and changes on reload
+	var logLevel slog.LevelVar
+	slog.SetDefault(logging.New(os.Stdout, &logLevel))
+
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file; environment variables override it")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadFile(*configFile)
 	if err != nil {
-		log.Fatal(err)
+		fatal("invalid configuration", err)
 	}
+	logLevel.Set(cfg.LogLevel)
 
-	// Initialize database
-	db, err := database.New(cfg.DbURL)
-	if err != nil {
-		log.Fatal(fmt.Sprintf("Failed to connect to database: %v", err))
+	// Export spans when an OTLP endpoint is configured
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.TracingEnabled {
+		if shutdownTracing, err = tracing.Setup(context.Background()); err != nil {
+			fatal("failed to set up tracing", err)
+		}
 	}
-	defer db.Close()
-
-	// Initialize repositories, services, and handlers
-	userRepo := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepo, cfg.JwtSecret)
-	authHandler := handler.NewAuthHandler(authService)
-
-	// Create router with middleware
-	r := chi.NewRouter()
-
-	// Global middleware
-	r.Use(chimiddleware.Logger)
-	r.Use(chimiddleware.Recoverer)
-	r.Use(chimiddleware.RequestID)
-	r.Use(chimiddleware.RealIP)
-	r.Use(middleware.RateLimiter())
-
-	// Health check endpoint
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
-
-	// Auth routes with strict rate limiting
-	r.Group(func(r chi.Router) {
-		r.Use(middleware.StrictRateLimiter())
-		r.Post("/auth/register", authHandler.Register)
-		r.Post("/auth/login", authHandler.Login)
-	})
 
-	// Protected routes
-	r.Group(func(r chi.Router) {
-		r.Use(middleware.RateLimiter())
-		r.Post("/auth/logout", authHandler.Logout)
-	})
-
-	// Create server with timeouts
-	srv := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// Wire the database, services, and routes
+	srv, err := server.New(cfg)
+	if err != nil {
+		fatal("failed to create server", err)
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal(fmt.Sprintf("Server failed to start: %v", err))
+		if err := srv.ListenAndServe(); err != nil {
+			fatal("server failed to start", err)
+		}
+	}()
+
+	// SIGHUP reloads the settings that can change without a restart
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			next, err := config.Reload(*configFile)
+			if err != nil {
+				slog.Error("configuration not reloaded, keeping the current one", "err", err)
+				continue
+			}
+			logLevel.Set(next.LogLevel)
+			srv.Reload(next)
 		}
 	}()
 
@@ -90,13 +72,22 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Server is shutting down...")
+	slog.Info("server is shutting down")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal(fmt.Sprintf("Server forced to shutdown: %v", err))
+		fatal("server forced to shutdown", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush spans", "err", err)
 	}
 
-	log.Println("Server exited properly")
+	slog.Info("server exited properly")
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// BatchLogger is implemented by sinks that write several events at once more
+// cheaply than one at a time, such as a database table
+type BatchLogger interface {
+	Logger
+	RecordBatch(ctx context.Context, events []Event)
+}
+
+// Defaults used by NewAsyncLogger
+const (
+	DefaultQueueSize = 4096
+	DefaultBatchSize = 100
+)
+
+// AsyncLogger moves audit writes off the request path. Events are queued in a
+// bounded buffer and written by a background worker, in batches when the sink
+// is a BatchLogger. When the queue is full, events are dropped and counted
+// rather than blocking the request.
+type AsyncLogger struct {
+	next      Logger
+	queue     chan queuedEvent
+	batchSize int
+	done      chan struct{}
+
+	mu     sync.RWMutex // guards closed against sends on the closed queue
+	closed bool
+
+	recorded atomic.Int64
+	dropped  atomic.Int64
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+// Verify that AsyncLogger implements Logger interface
+var _ Logger = (*AsyncLogger)(nil)
+
+// NewAsyncLogger starts a background writer in front of next. Non-positive
+// sizes fall back to DefaultQueueSize and DefaultBatchSize.
+func NewAsyncLogger(next Logger, queueSize, batchSize int) *AsyncLogger {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	l := &AsyncLogger{
+		next:      next,
+		queue:     make(chan queuedEvent, queueSize),
+		batchSize: batchSize,
+		done:      make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Record queues the event without blocking. The event keeps the values of ctx
+// but not its cancellation, since the request usually ends before the write.
+func (l *AsyncLogger) Record(ctx context.Context, event Event) {
+	stamp(ctx, &event)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.drop(event)
+		return
+	}
+
+	select {
+	case l.queue <- queuedEvent{ctx: context.WithoutCancel(ctx), event: event}:
+	default:
+		l.drop(event)
+	}
+}
+
+// drop counts an event that could not be queued
+func (l *AsyncLogger) drop(event Event) {
+	// Log the first drop and every thousandth after it to avoid flooding the log
+	if n := l.dropped.Add(1); n%1000 == 1 {
+		slog.Warn("audit: queue full, dropped event", "event_type", event.Type, "dropped", n)
+	}
+}
+
+// Recorded returns how many events have been handed to the sink
+func (l *AsyncLogger) Recorded() int64 {
+	return l.recorded.Load()
+}
+
+// Dropped returns how many events were discarded because the queue was full
+func (l *AsyncLogger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// QueueLength returns how many events are waiting to be written
+func (l *AsyncLogger) QueueLength() int {
+	return len(l.queue)
+}
+
+// Close stops accepting events and waits until the queued events are written
+// or ctx is done
+func (l *AsyncLogger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run writes queued events until the queue is closed and drained
+func (l *AsyncLogger) run() {
+	defer close(l.done)
+
+	batch := make([]queuedEvent, 0, l.batchSize)
+	for first := range l.queue {
+		batch = append(batch[:0], first)
+
+		// Take whatever else is already waiting, up to the batch size
+	fill:
+		for len(batch) < l.batchSize {
+			select {
+			case item, ok := <-l.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, item)
+			default:
+				break fill
+			}
+		}
+
+		l.write(batch)
+	}
+}
+
+// write hands a batch to the sink
+func (l *AsyncLogger) write(batch []queuedEvent) {
+	if sink, ok := l.next.(BatchLogger); ok && len(batch) > 1 {
+		events := make([]Event, len(batch))
+		for i, item := range batch {
+			events[i] = item.event
+		}
+		sink.RecordBatch(batch[0].ctx, events)
+	} else {
+		for _, item := range batch {
+			l.next.Record(item.ctx, item.event)
+		}
+	}
+	l.recorded.Add(int64(len(batch)))
+}
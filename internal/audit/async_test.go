@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchSink records the batches it receives and can block writes until released
+type batchSink struct {
+	mu      sync.Mutex
+	events  []Event
+	batches int
+	release chan struct{}
+}
+
+func (s *batchSink) Record(ctx context.Context, event Event) {
+	s.RecordBatch(ctx, []Event{event})
+}
+
+func (s *batchSink) RecordBatch(ctx context.Context, events []Event) {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	s.batches++
+}
+
+func TestAsyncLoggerFlushesOnClose(t *testing.T) {
+	sink := &batchSink{release: make(chan struct{})}
+	logger := NewAsyncLogger(sink, 100, 10)
+
+	for i := 0; i < 25; i++ {
+		logger.Record(context.Background(), Event{Type: "login"})
+	}
+	close(sink.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := logger.Close(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sink.events) != 25 || logger.Recorded() != 25 {
+		t.Errorf("got %d events written, want 25", len(sink.events))
+	}
+	// The first event is written alone while the rest queue up behind it
+	if sink.batches >= 25 {
+		t.Errorf("got %d batches, want events to be batched", sink.batches)
+	}
+	if sink.events[0].Time.IsZero() {
+		t.Error("expected event time to be set")
+	}
+
+	logger.Record(context.Background(), Event{Type: "late"})
+	if logger.Dropped() != 1 {
+		t.Errorf("got %d dropped, want event after close to be dropped", logger.Dropped())
+	}
+}
+
+func TestAsyncLoggerDropsWhenFull(t *testing.T) {
+	sink := &batchSink{release: make(chan struct{})}
+	logger := NewAsyncLogger(sink, 2, 1)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		logger.Record(context.Background(), Event{Type: "login"})
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Record blocked on a full queue")
+	}
+
+	// One event is held by the blocked sink and two wait in the queue
+	if dropped := logger.Dropped(); dropped < 7 {
+		t.Errorf("got %d dropped, want at least 7", dropped)
+	}
+
+	close(sink.release)
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := logger.Recorded() + logger.Dropped(); got != 10 {
+		t.Errorf("got %d events accounted for, want 10", got)
+	}
+}
+
+func TestAsyncLoggerCloseTimeout(t *testing.T) {
+	sink := &batchSink{release: make(chan struct{})}
+	defer close(sink.release)
+	logger := NewAsyncLogger(sink, 10, 10)
+	logger.Record(context.Background(), Event{Type: "login"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := logger.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
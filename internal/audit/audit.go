@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// Severity levels for audit events
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityHigh    = "high"
+)
+
+// Types of the events of password sign-in attempts, which make up a user's
+// login history
+const (
+	LoginSucceeded = "auth.login_succeeded"
+	LoginFailed    = "auth.login_failed"
+)
+
+// Impersonated is the type of the event recorded for a user when an admin
+// starts impersonating them, shown in their login history
+const Impersonated = "auth.impersonated"
+
+// Event describes a security-relevant action or anomaly
+type Event struct {
+	ID        int64          `json:"id,omitempty"` // set on events read back from storage
+	Type      string         `json:"type"`
+	Severity  string         `json:"severity"`
+	ActorID   int64          `json:"actor_id,omitempty"`
+	IPAddress string         `json:"ip_address,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Time      time.Time      `json:"time"`
+}
+
+// Filter selects stored events in listings. Zero fields match every event.
+type Filter struct {
+	ActorID int64
+	Types   []string // any of the types
+	Page    pagination.Page
+}
+
+// Match reports whether e is selected by f, ignoring the page
+func (f Filter) Match(e Event) bool {
+	return (f.ActorID == 0 || e.ActorID == f.ActorID) && (len(f.Types) == 0 || slices.Contains(f.Types, e.Type))
+}
+
+type clientKey struct{}
+
+type client struct {
+	ip        string
+	userAgent string
+}
+
+// WithClient stores the address and user agent of the client making a request,
+// which loggers add to events that do not set them
+func WithClient(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client{ip: ip, userAgent: userAgent})
+}
+
+type impersonatorKey struct{}
+
+// WithImpersonator marks the request as made by the admin with the given ID on
+// behalf of another user. Loggers tag its events with an impersonator_id detail.
+func WithImpersonator(ctx context.Context, adminID int64) context.Context {
+	return context.WithValue(ctx, impersonatorKey{}, adminID)
+}
+
+// stamp fills in the time, the client stored by WithClient and the admin
+// stored by WithImpersonator
+func stamp(ctx context.Context, event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	c, _ := ctx.Value(clientKey{}).(client)
+	if event.IPAddress == "" {
+		event.IPAddress = c.ip
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = c.userAgent
+	}
+	if id, ok := ctx.Value(impersonatorKey{}).(int64); ok {
+		if _, set := event.Details["impersonator_id"]; !set {
+			// Copy, as callers may share the details map
+			details := make(map[string]any, len(event.Details)+1)
+			maps.Copy(details, event.Details)
+			details["impersonator_id"] = id
+			event.Details = details
+		}
+	}
+}
+
+// Logger records audit events. Implementations must be safe for concurrent use
+// and must not fail the request that produced the event.
+type Logger interface {
+	Record(ctx context.Context, event Event)
+}
+
+// LogLogger writes audit events to the default structured logger
+type LogLogger struct{}
+
+// Verify that LogLogger implements Logger interface
+var _ Logger = LogLogger{}
+
+// Record logs the event, with the request ID of ctx when there is one
+func (LogLogger) Record(ctx context.Context, event Event) {
+	stamp(ctx, &event)
+	slog.InfoContext(ctx, "audit", "event", event)
+}
+
+// MultiLogger fans events out to several loggers
+type MultiLogger []Logger
+
+// Verify that MultiLogger implements Logger interface
+var _ Logger = MultiLogger(nil)
+
+// Record sends the event to every logger
+func (m MultiLogger) Record(ctx context.Context, event Event) {
+	stamp(ctx, &event)
+	for _, l := range m {
+		l.Record(ctx, event)
+	}
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithClient(t *testing.T) {
+	ctx := WithClient(context.Background(), "203.0.113.7", "curl/8.0")
+
+	tests := []struct {
+		name          string
+		event         Event
+		wantIP        string
+		wantUserAgent string
+	}{
+		{
+			name:          "filled from context",
+			event:         Event{Type: "auth.logout"},
+			wantIP:        "203.0.113.7",
+			wantUserAgent: "curl/8.0",
+		},
+		{
+			name:          "event values kept",
+			event:         Event{Type: "auth.logout", IPAddress: "198.51.100.1", UserAgent: "app/1.0"},
+			wantIP:        "198.51.100.1",
+			wantUserAgent: "app/1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &batchSink{}
+			MultiLogger{sink}.Record(ctx, tt.event)
+
+			got := sink.events[0]
+			if got.IPAddress != tt.wantIP || got.UserAgent != tt.wantUserAgent {
+				t.Errorf("got client %q %q, want %q %q", got.IPAddress, got.UserAgent, tt.wantIP, tt.wantUserAgent)
+			}
+			if got.Time.IsZero() {
+				t.Error("expected event time to be set")
+			}
+		})
+	}
+}
+
+func TestWithImpersonator(t *testing.T) {
+	details := map[string]any{"reason": "ticket 42"}
+	sink := &batchSink{}
+	MultiLogger{sink}.Record(WithImpersonator(context.Background(), 7), Event{Type: "user.profile_updated", Details: details})
+
+	got := sink.events[0]
+	if got.Details["impersonator_id"] != int64(7) || got.Details["reason"] != "ticket 42" {
+		t.Errorf("got details %v, want impersonator_id 7 and the reason", got.Details)
+	}
+	if _, ok := details["impersonator_id"]; ok {
+		t.Error("expected the caller's details map to be left unchanged")
+	}
+
+	sink = &batchSink{}
+	MultiLogger{sink}.Record(context.Background(), Event{Type: "user.profile_updated"})
+	if _, ok := sink.events[0].Details["impersonator_id"]; ok {
+		t.Error("expected no impersonator_id without WithImpersonator")
+	}
+}
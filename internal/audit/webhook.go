@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WebhookLogger posts high-severity events as JSON to an alerting webhook.
+// Delivery is asynchronous and best effort so requests are never delayed.
+type WebhookLogger struct {
+	URL    string
+	Client *http.Client
+}
+
+// Verify that WebhookLogger implements Logger interface
+var _ Logger = (*WebhookLogger)(nil)
+
+// NewWebhookLogger creates a webhook logger for the given URL
+func NewWebhookLogger(url string) *WebhookLogger {
+	return &WebhookLogger{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Record posts the event when its severity is high
+func (l *WebhookLogger) Record(ctx context.Context, event Event) {
+	if event.Severity != SeverityHigh {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.ErrorContext(ctx, "audit: failed to encode event", "event_type", event.Type, "err", err)
+		return
+	}
+
+	go func() {
+		resp, err := l.Client.Post(l.URL, "application/json", bytes.NewReader(data))
+		if err != nil {
+			slog.ErrorContext(ctx, "audit: failed to deliver alert", "event_type", event.Type, "err", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			slog.ErrorContext(ctx, "audit: alert webhook rejected event", "event_type", event.Type, "status", resp.StatusCode)
+		}
+	}()
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookLogger(t *testing.T) {
+	received := make(chan Event, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer srv.Close()
+
+	logger := NewWebhookLogger(srv.URL)
+	logger.Record(context.Background(), Event{Type: "routine", Severity: SeverityInfo})
+	logger.Record(context.Background(), Event{Type: "alert", Severity: SeverityHigh})
+
+	select {
+	case event := <-received:
+		if event.Type != "alert" {
+			t.Errorf("got event %q, want alert", event.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+
+	select {
+	case event := <-received:
+		t.Errorf("unexpected delivery of %q", event.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
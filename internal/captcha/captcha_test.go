@@ -0,0 +1,48 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSiteVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("secret") != "site-secret" || r.PostForm.Get("remoteip") != "203.0.113.9" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": r.PostForm.Get("response") == "solved"})
+	}))
+	defer server.Close()
+
+	verifier, err := NewSiteVerifier("Turnstile", "site-secret")
+	if err != nil {
+		t.Fatalf("NewSiteVerifier: %v", err)
+	}
+	verifier.VerifyURL = server.URL
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "solved", token: "solved"},
+		{name: "rejected", token: "forged", wantErr: ErrVerificationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifier.Verify(context.Background(), tt.token, "203.0.113.9"); err != tt.wantErr {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewSiteVerifier("geetest", "secret"); err != ErrUnknownProvider {
+		t.Errorf("got %v, want %v", err, ErrUnknownProvider)
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// ACME obtains TLS certificates automatically, from Let's Encrypt by default,
+// so the service can serve HTTPS itself. No domains disables it.
+type ACME struct {
+	// Host names to obtain certificates for; requests for other names are
+	// refused during the TLS handshake
+	Domains []string
+
+	// Directory where certificates and the account key are kept across
+	// restarts
+	CacheDir string
+
+	// Contact for expiry and revocation notices; optional
+	Email string
+
+	// Port answering HTTP-01 challenges and redirecting other plain HTTP
+	// requests to HTTPS. Let's Encrypt only connects to port 80.
+	HTTPPort int
+
+	// ACME directory of the certificate authority; empty selects Let's
+	// Encrypt production
+	DirectoryURL string
+}
+
+// Enabled reports whether certificates are obtained with ACME
+func (a ACME) Enabled() bool {
+	return len(a.Domains) > 0
+}
+
+// ParseACME parses the comma-separated domains, the cache directory (default
+// "acme-cache"), the contact email, the HTTP port (default 80) and the
+// directory URL
+func ParseACME(domains, cacheDir, email, httpPort, directoryURL string) (ACME, error) {
+	acme := ACME{Domains: splitList(domains), CacheDir: cacheDir, Email: email, HTTPPort: 80, DirectoryURL: directoryURL}
+	if !acme.Enabled() {
+		return ACME{}, nil
+	}
+	for i, domain := range acme.Domains {
+		if strings.ContainsAny(domain, "*:/") {
+			return ACME{}, fmt.Errorf("invalid domain %q, want a host name such as auth.example.com", domain)
+		}
+		acme.Domains[i] = strings.ToLower(domain)
+	}
+	if acme.CacheDir == "" {
+		acme.CacheDir = "acme-cache"
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return ACME{}, fmt.Errorf("invalid email %q", email)
+		}
+	}
+	if httpPort != "" {
+		n, err := strconv.Atoi(httpPort)
+		if err != nil || n < 1 || n > 65535 {
+			return ACME{}, fmt.Errorf("invalid HTTP port %q", httpPort)
+		}
+		acme.HTTPPort = n
+	}
+	if err := checkURL(directoryURL, "https"); err != nil {
+		return ACME{}, fmt.Errorf("directory URL %v", err)
+	}
+	return acme, nil
+}
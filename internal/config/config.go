@@ -1,16 +1,313 @@
 package config
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/logging"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/secrets"
+	"github.com/Stewz00/go-auth-service/internal/webhook"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// Session modes for Config.SessionMode
+const (
+	SessionModeToken  = "token"
+	SessionModeCookie = "cookie"
+)
+
+// ValidationError lists every invalid or missing setting Load found
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid configuration: " + e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d problems): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
 type Config struct {
-	Port      string
-	JwtSecret string
+	Port      int    // PORT, from 1 to 65535
+	JwtSecret string // JWT_SECRET, or the first of JWT_SECRETS; signs new tokens
 	DbURL     string
+
+	// The rest of JWT_SECRETS: tokens signed with them are still accepted,
+	// so the secret can be rotated without signing everyone out
+	PreviousJwtSecrets []string
+
+	// How long issued tokens stay valid (TOKEN_TTL, default 24h)
+	TokenTTL time.Duration
+
+	// How long tokens stay valid when the user signs in with remember_me
+	// (REMEMBER_ME_TTL, default 720h; 0 ignores remember_me)
+	RememberMeTTL time.Duration
+
+	// Reject tokens issued to a client that sent a device fingerprint when
+	// they are used without it (DEVICE_BINDING=true)
+	DeviceBinding bool
+
+	// Current versions of the terms of service (TOS_VERSION) and privacy
+	// policy (PRIVACY_POLICY_VERSION). Users who have not accepted them are
+	// asked to at sign-in; empty versions are not tracked.
+	TosVersion           string
+	PrivacyPolicyVersion string
+
+	// Vault (VAULT_ADDR) resolves JWT_SECRET(S), DATABASE_URL and
+	// PASSWORD_PEPPERS given as vault:<path>#<key>. It authenticates with
+	// VAULT_TOKEN, or as the Kubernetes role VAULT_K8S_ROLE (VAULT_K8S_MOUNT,
+	// default "kubernetes"; VAULT_K8S_TOKEN_PATH). nil without VAULT_ADDR.
+	Vault *secrets.Vault
+
+	// Database credentials issued by Vault from VAULT_DB_CREDS_PATH (e.g.
+	// database/creds/auth-service) and set in DbURL. PostgreSQL only, refused
+	// with other databases; nil unless configured.
+	DBCredentials *secrets.Credentials
+
+	// Where data lives: "database" (the default, selected by DbURL) or
+	// "memory", which keeps everything in process memory for demos, local
+	// development, and CI, and does not need DATABASE_URL
+	Storage string
+
+	// How long startup keeps retrying an unreachable database before giving
+	// up (DB_CONNECT_TIMEOUT, default 30s; 0 tries once)
+	DBConnectTimeout time.Duration
+
+	// Statements taking at least this long are logged with their SQL
+	// (SLOW_QUERY_THRESHOLD, e.g. 200ms; 0 disables, the default). PostgreSQL
+	// only; refused with other databases.
+	SlowQueryThreshold time.Duration
+
+	// Environment selects the deployment profile (development, test, production)
+	Environment string
+
+	// RoutePolicies selects how each route authenticates
+	RoutePolicies RoutePolicies
+
+	// Optional GitHub social login, enabled when a client ID is set
+	GitHubClientID     string
+	GitHubClientSecret string
+	GitHubRedirectURL  string
+
+	// Optional OpenID Provider, enabled when an issuer URL is set
+	OIDCIssuer         string
+	OIDCSigningKeyFile string
+
+	// Optional admin API, enabled when a token is set
+	AdminAPIToken string
+
+	// Serve pprof profiles and runtime stats under /debug to holders of the
+	// admin token (DEBUG_ENDPOINTS=true; requires ADMIN_API_TOKEN)
+	DebugEndpoints bool
+
+	// Serve register, login, me and sessions over GraphQL at /graphql
+	// (GRAPHQL_ENABLED=true)
+	GraphQL bool
+
+	// Serve Swagger UI for /openapi.json at /docs (API_DOCS=true)
+	APIDocs bool
+
+	// Directory of translation catalogs that override or add to the
+	// built-in ones (LOCALES_DIR)
+	LocalesDir string
+
+	// Optional break-glass operator credential (SHA-256 hex of the sealed
+	// credential) that can unlock the admin API until it expires
+	BreakGlassCredentialHash string
+	BreakGlassExpiresAt      time.Time
+
+	// Optional SAML service provider, enabled when a root URL is set
+	SAMLRootURL     string
+	SAMLIdPMetadata string // URL or file path of the IdP metadata
+	SAMLCertFile    string
+	SAMLKeyFile     string
+
+	// Optional webhook that receives high-severity security alerts
+	AlertWebhookURL string
+
+	// Endpoints notified of user.registered, user.login, user.locked and
+	// session.revoked events (WEBHOOKS, a JSON array; see ParseWebhooks)
+	Webhooks []webhook.Endpoint
+
+	// Event bus the same events are published to (EVENT_BUS): "kafka"
+	// (through the REST proxy at KafkaRESTURL), "nats", or empty for none
+	EventBus     string
+	KafkaRESTURL string
+	KafkaTopic   string // default auth-events
+	NATSURL      string
+	NATSSubject  string // subject prefix, default auth.events
+
+	// How account emails are sent (EMAIL_SENDER): "smtp", "sendgrid", "ses",
+	// "log" (written to the log instead, for development), or empty for none.
+	// EmailFrom (EMAIL_FROM) is the sender address, templates in
+	// EmailTemplatesDir (EMAIL_TEMPLATES_DIR) replace the built-in ones, and
+	// EmailRateLimit (EMAIL_RATE_LIMIT) caps emails per second (0 for no cap;
+	// SES defaults to 1, its sandbox rate).
+	EmailSender         string
+	EmailFrom           string
+	EmailTemplatesDir   string
+	EmailRateLimit      float64
+	SMTPHost            string
+	SMTPPort            int    // default 587, or 465 when SMTPTLS is tls
+	SMTPUsername        string // authenticates when set
+	SMTPPassword        string
+	SMTPTLS             string // starttls (default), tls or none
+	SendGridAPIKey      string
+	SESConfigurationSet string // optional; AWS credentials and region come from the SDK's usual sources
+
+	// How long to ban IPs that try to sign in to canary accounts (0 disables banning)
+	CanaryBanDuration time.Duration
+
+	// Scopes users may request at login (defaults to the service's built-in scopes)
+	UserScopes []string
+
+	// When failed logins lock an account (LOCKOUT_MAX_FAILED_ATTEMPTS, default 5)
+	Lockout model.LockoutPolicy
+
+	// How new password hashes are made (PASSWORD_HASH): "argon2id" (default)
+	// with the Argon2 cost (ARGON2_MEMORY in KiB, ARGON2_TIME,
+	// ARGON2_PARALLELISM; defaults to password.DefaultArgon2Params), or
+	// "bcrypt" with BcryptCost (BCRYPT_COST, default 12). At most
+	// PasswordHashWorkers (PASSWORD_HASH_WORKERS, 0 for one per CPU) hashes
+	// run at once.
+	PasswordHash        string
+	Argon2              password.Argon2Params
+	BcryptCost          int
+	PasswordHashWorkers int
+
+	// Secret peppers mixed into new password hashes (PASSWORD_PEPPERS, a
+	// comma-separated list of id=base64 secrets of at least 32 bytes). New
+	// hashes use PasswordPepperID (PASSWORD_PEPPER_ID, default the first
+	// listed); the others only verify existing hashes until users sign in.
+	PasswordPeppers  map[string][]byte
+	PasswordPepperID string
+
+	// Passwords users may choose: PASSWORD_MIN_LENGTH (default 8),
+	// PASSWORD_MAX_LENGTH (default 128), PASSWORD_REQUIRE (any of upper, lower,
+	// digit, symbol), PASSWORD_DENY_COMMON=true, PASSWORD_DENYLIST_FILE (one
+	// password per line) and PASSWORD_DISALLOW_EMAIL=true
+	PasswordPolicy password.Policy
+
+	// Reject new passwords found in Have I Been Pwned (PWNED_PASSWORDS=true).
+	// Lookups give up after PwnedPasswordsTimeout (PWNED_PASSWORDS_TIMEOUT,
+	// default 2s); then the password is accepted unless PWNED_PASSWORDS_FAIL
+	// is "closed" instead of "open".
+	PwnedPasswords         bool
+	PwnedPasswordsTimeout  time.Duration
+	PwnedPasswordsFailOpen bool
+
+	// Passwords older than this must be changed at the next sign-in
+	// (PASSWORD_MAX_AGE, e.g. 2160h; 0, the default, never expires them)
+	PasswordMaxAge time.Duration
+
+	// Capacity of the audit event queue (AUDIT_QUEUE_SIZE, 0 uses the default)
+	AuditQueueSize int
+
+	// Where rate limit counters live: "memory" (per replica, the default) or
+	// "redis" (shared by every replica, requires RedisURL)
+	RateLimitStore string
+	RedisURL       string
+
+	// Where sessions live: "database" (the default) or "redis" (requires
+	// RedisURL), which validates tokens without a database query
+	SessionStore string
+
+	// How long token validation trusts a session found valid or revoked
+	// before checking the session store again (SESSION_CACHE_TTL); zero, the
+	// default, checks every time
+	SessionCacheTTL time.Duration
+
+	// Rate limit tiers (RATE_LIMITS) and per-route overrides (RATE_LIMIT_ROUTES)
+	RateLimits RateLimits
+
+	// Networks of the reverse proxies whose X-Forwarded-For and X-Real-IP
+	// headers are believed (TRUSTED_PROXIES); none by default
+	TrustedProxies []*net.IPNet
+
+	// Automatic TLS certificates (ACME_DOMAINS, ACME_CACHE_DIR, ACME_EMAIL,
+	// ACME_HTTP_PORT, ACME_DIRECTORY_URL). With domains, PORT serves HTTPS.
+	ACME ACME
+
+	// Cross-origin access for browser apps (CORS_ALLOWED_ORIGINS,
+	// CORS_ALLOWED_METHODS, CORS_ALLOWED_HEADERS, CORS_ALLOW_CREDENTIALS,
+	// CORS_MAX_AGE); disabled unless origins are listed
+	CORS CORS
+
+	// How long responses to requests with an Idempotency-Key are replayed
+	// (IDEMPOTENCY_TTL, default 24h)
+	IdempotencyTTL time.Duration
+
+	// Minimum level of the JSON logs (LOG_LEVEL: debug, info, warn or error;
+	// default info)
+	LogLevel slog.Level
+
+	// Largest request body accepted, in bytes (MAX_BODY_BYTES, default 1 MB)
+	MaxBodyBytes int64
+
+	// How Login delivers tokens by default (SESSION_MODE): SessionModeToken in
+	// the JSON body, or SessionModeCookie in an HttpOnly cookie. Clients can
+	// choose per request with the Accept-Auth header.
+	SessionMode string
+
+	// Content-Security-Policy sent on every response (CONTENT_SECURITY_POLICY;
+	// empty uses the built-in policy, "off" omits the header) and how long
+	// browsers remember to use HTTPS only (HSTS_MAX_AGE, default two years,
+	// 0 omits Strict-Transport-Security)
+	ContentSecurityPolicy string
+	HSTSMaxAge            time.Duration
+
+	// CAPTCHA demanded from addresses with recent failed sign-ins:
+	// CAPTCHA_PROVIDER (recaptcha, hcaptcha or turnstile; empty disables),
+	// CAPTCHA_SECRET and CAPTCHA_AFTER_FAILURES (default 3)
+	CaptchaProvider      string
+	CaptchaSecret        string
+	CaptchaAfterFailures int
+
+	// Restrict login and registration by the country of the client address,
+	// looked up in a MaxMind database (GEOIP_DATABASE): clients in
+	// GEOIP_BLOCK_COUNTRIES are refused and those in GEOIP_CHALLENGE_COUNTRIES
+	// must solve a CAPTCHA. Both are comma-separated ISO 3166-1 alpha-2 codes.
+	GeoIPDatabase           string
+	GeoIPBlockCountries     []string
+	GeoIPChallengeCountries []string
+
+	// Refuse registrations with email addresses at the comma-separated
+	// BLOCKED_EMAIL_DOMAINS or their subdomains, and at well-known disposable
+	// email services when BLOCK_DISPOSABLE_EMAILS is true. Admins block more
+	// domains at runtime through /admin/email-domains.
+	BlockedEmailDomains   []string
+	BlockDisposableEmails bool
+
+	// Store and look up Gmail addresses by the inbox they reach, ignoring dots
+	// and +tags, so one inbox cannot register several accounts
+	FoldGmailAddresses bool
+
+	// Export OpenTelemetry spans over OTLP/HTTP, enabled by setting
+	// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT unless
+	// OTEL_SDK_DISABLED is true. The exporter reads the other OTEL_* variables.
+	TracingEnabled bool
 }
 
 // Load reads the configuration from a .env file or environment variables and returns a Config struct.
@@ -18,20 +315,827 @@ type Config struct {
 func Load() (*Config, error) {
 	// Try to load .env file, ignore error if it doesn't exist
 	_ = godotenv.Load()
+	return load(&environment{})
+}
 
-	port := os.Getenv("PORT")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	dbURL := os.Getenv("DATABASE_URL")
+// load reads the configuration from e
+func load(e *environment) (*Config, error) {
+	vault, err := loadVault(e)
+	if err != nil {
+		return nil, err
+	}
+	e.vault = vault
 
-	// Optional: validate required variables
-	if port == "" || jwtSecret == "" || dbURL == "" {
-		return nil, fmt.Errorf("missing required environment variables: PORT=%q, JWT_SECRET=%q, DATABASE_URL=%q", port, jwtSecret, dbURL)
+	// Every problem found is collected, so one attempt reports them all
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	jwtSecret, err := e.secret("JWT_SECRET")
+	if err != nil {
+		invalid("%v", err)
+	}
+	var previousJwtSecrets []string
+	if list, err := e.secret("JWT_SECRETS"); err != nil {
+		invalid("%v", err)
+	} else if list != "" && jwtSecret != "" {
+		invalid("set JWT_SECRET or JWT_SECRETS, not both")
+	} else if list != "" {
+		all, err := splitSecrets(list)
+		if err != nil {
+			invalid("JWT_SECRETS %v", err)
+		} else {
+			jwtSecret, previousJwtSecrets = all[0], all[1:]
+		}
+	}
+	dbURL, err := e.secret("DATABASE_URL")
+	if err != nil {
+		invalid("%v", err)
+	}
+	storage := e.get("STORAGE")
+
+	var missing []string
+	for name, value := range map[string]string{"PORT": e.get("PORT"), "JWT_SECRET": jwtSecret, "DATABASE_URL": dbURL} {
+		if value == "" && e.get(name) == "" && (name != "JWT_SECRET" || e.get("JWT_SECRETS") == "") && (name != "DATABASE_URL" || storage != "memory") {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		invalid("missing required environment variables: %s", strings.Join(missing, ", "))
 	}
 
 	cfg := &Config{
-		Port:      port,
-		JwtSecret: jwtSecret,
-		DbURL:     dbURL,
+		JwtSecret:          jwtSecret,
+		PreviousJwtSecrets: previousJwtSecrets,
+		DbURL:              dbURL,
+		Storage:            storage,
+		Vault:              vault,
+
+		Environment: e.get("APP_ENV"),
+
+		GitHubClientID:     e.get("GITHUB_CLIENT_ID"),
+		GitHubClientSecret: e.get("GITHUB_CLIENT_SECRET"),
+		GitHubRedirectURL:  e.get("GITHUB_REDIRECT_URL"),
+
+		OIDCIssuer:         e.get("OIDC_ISSUER"),
+		OIDCSigningKeyFile: e.get("OIDC_SIGNING_KEY_FILE"),
+
+		AdminAPIToken:  e.get("ADMIN_API_TOKEN"),
+		DebugEndpoints: e.get("DEBUG_ENDPOINTS") == "true",
+		GraphQL:        e.get("GRAPHQL_ENABLED") == "true",
+		APIDocs:        e.get("API_DOCS") == "true",
+		LocalesDir:     e.get("LOCALES_DIR"),
+
+		BreakGlassCredentialHash: e.get("BREAK_GLASS_CREDENTIAL_HASH"),
+
+		SAMLRootURL:     e.get("SAML_ROOT_URL"),
+		SAMLIdPMetadata: e.get("SAML_IDP_METADATA"),
+		SAMLCertFile:    e.get("SAML_SP_CERT_FILE"),
+		SAMLKeyFile:     e.get("SAML_SP_KEY_FILE"),
+
+		AlertWebhookURL: e.get("ALERT_WEBHOOK_URL"),
+
+		EventBus:     e.get("EVENT_BUS"),
+		KafkaRESTURL: e.get("KAFKA_REST_URL"),
+		KafkaTopic:   e.get("KAFKA_TOPIC"),
+		NATSURL:      e.get("NATS_URL"),
+		NATSSubject:  e.get("NATS_SUBJECT"),
+
+		EmailSender:         e.get("EMAIL_SENDER"),
+		EmailFrom:           e.get("EMAIL_FROM"),
+		EmailTemplatesDir:   e.get("EMAIL_TEMPLATES_DIR"),
+		SMTPHost:            e.get("SMTP_HOST"),
+		SMTPUsername:        e.get("SMTP_USERNAME"),
+		SMTPPassword:        e.get("SMTP_PASSWORD"),
+		SMTPTLS:             e.get("SMTP_TLS"),
+		SendGridAPIKey:      e.get("SENDGRID_API_KEY"),
+		SESConfigurationSet: e.get("SES_CONFIGURATION_SET"),
+
+		RateLimitStore: e.get("RATE_LIMIT_STORE"),
+		RedisURL:       e.get("REDIS_URL"),
+		SessionStore:   e.get("SESSION_STORE"),
+
+		CaptchaProvider: e.get("CAPTCHA_PROVIDER"),
+		CaptchaSecret:   e.get("CAPTCHA_SECRET"),
+
+		GeoIPDatabase: e.get("GEOIP_DATABASE"),
+
+		SessionMode: e.get("SESSION_MODE"),
+
+		TracingEnabled: (e.get("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || e.get("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") &&
+			!strings.EqualFold(e.get("OTEL_SDK_DISABLED"), "true"),
+
+		ContentSecurityPolicy: e.get("CONTENT_SECURITY_POLICY"),
+		HSTSMaxAge:            2 * 365 * 24 * time.Hour,
+
+		DBConnectTimeout: 30 * time.Second,
+		TokenTTL:         24 * time.Hour,
+		RememberMeTTL:    30 * 24 * time.Hour,
+		DeviceBinding:    e.get("DEVICE_BINDING") == "true",
+
+		TosVersion:           e.get("TOS_VERSION"),
+		PrivacyPolicyVersion: e.get("PRIVACY_POLICY_VERSION"),
+
+		UserScopes:   strings.Fields(e.get("USER_SCOPES")),
+		Lockout:      model.DefaultLockoutPolicy,
+		Argon2:       password.DefaultArgon2Params,
+		PasswordHash: e.get("PASSWORD_HASH"),
+		BcryptCost:   password.DefaultBcryptCost,
+
+		PasswordPolicy: password.DefaultPolicy,
+
+		PwnedPasswords:         e.get("PWNED_PASSWORDS") == "true",
+		BlockDisposableEmails:  e.get("BLOCK_DISPOSABLE_EMAILS") == "true",
+		FoldGmailAddresses:     e.get("FOLD_GMAIL_ADDRESSES") == "true",
+		PwnedPasswordsTimeout:  2 * time.Second,
+		PwnedPasswordsFailOpen: true,
+	}
+
+	if port := e.get("PORT"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			invalid("PORT must be a port number from 1 to 65535")
+		}
+		cfg.Port = n
+	}
+	var driver string
+	if cfg.DbURL != "" && cfg.Storage != "memory" {
+		driver, err = database.DriverFor(cfg.DbURL)
+		if err != nil {
+			invalid("DATABASE_URL must be a postgres://, mysql://, or sqlite:// URL")
+		} else if driver != database.DriverPostgres {
+			// Settings of the pgx pool, which other databases would ignore
+			for _, name := range []string{"VAULT_DB_CREDS_PATH", "SLOW_QUERY_THRESHOLD"} {
+				if e.get(name) != "" {
+					invalid("%s is only supported with a postgres:// DATABASE_URL", name)
+				}
+			}
+		}
+	}
+	for _, setting := range []struct {
+		name, value string
+		schemes     []string
+	}{
+		{"GITHUB_REDIRECT_URL", cfg.GitHubRedirectURL, []string{"http", "https"}},
+		{"OIDC_ISSUER", cfg.OIDCIssuer, []string{"http", "https"}},
+		{"SAML_ROOT_URL", cfg.SAMLRootURL, []string{"http", "https"}},
+		{"ALERT_WEBHOOK_URL", cfg.AlertWebhookURL, []string{"http", "https"}},
+		{"KAFKA_REST_URL", cfg.KafkaRESTURL, []string{"http", "https"}},
+		{"REDIS_URL", cfg.RedisURL, []string{"redis", "rediss", "unix"}},
+	} {
+		if err := checkURL(setting.value, setting.schemes...); err != nil {
+			invalid("%s %v", setting.name, err)
+		}
+	}
+	if cfg.GitHubClientID != "" && (cfg.GitHubClientSecret == "" || cfg.GitHubRedirectURL == "") {
+		invalid("GITHUB_CLIENT_SECRET and GITHUB_REDIRECT_URL are required when GITHUB_CLIENT_ID is set")
+	}
+	routePolicies, err := ParseRoutePolicies(DefaultRoutePolicies(), e.get("AUTH_ROUTE_POLICIES"))
+	if err != nil {
+		invalid("invalid AUTH_ROUTE_POLICIES: %v", err)
+	}
+	cfg.RoutePolicies = routePolicies
+
+	rateLimits, err := ParseRateLimits(DefaultRateLimits(), e.get("RATE_LIMITS"), e.get("RATE_LIMIT_ROUTES"))
+	if err != nil {
+		invalid("invalid RATE_LIMITS or RATE_LIMIT_ROUTES: %v", err)
+	}
+	cfg.RateLimits = rateLimits
+
+	webhooks, err := ParseWebhooks(e.get("WEBHOOKS"))
+	if err != nil {
+		invalid("invalid WEBHOOKS: %v", err)
+	}
+	cfg.Webhooks = webhooks
+
+	trustedProxies, err := ParseTrustedProxies(e.get("TRUSTED_PROXIES"))
+	if err != nil {
+		invalid("invalid TRUSTED_PROXIES: %v", err)
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	acme, err := ParseACME(e.get("ACME_DOMAINS"), e.get("ACME_CACHE_DIR"), e.get("ACME_EMAIL"),
+		e.get("ACME_HTTP_PORT"), e.get("ACME_DIRECTORY_URL"))
+	if err != nil {
+		invalid("invalid ACME configuration: %v", err)
+	} else if acme.Enabled() && acme.HTTPPort == cfg.Port {
+		invalid("ACME_HTTP_PORT must differ from PORT, which serves HTTPS when ACME_DOMAINS is set")
+	}
+	cfg.ACME = acme
+
+	cors, err := ParseCORS(DefaultCORS(), e.get("CORS_ALLOWED_ORIGINS"), e.get("CORS_ALLOWED_METHODS"),
+		e.get("CORS_ALLOWED_HEADERS"), e.get("CORS_ALLOW_CREDENTIALS"), e.get("CORS_MAX_AGE"))
+	if err != nil {
+		invalid("invalid CORS configuration: %v", err)
+	}
+	cfg.CORS = cors
+
+	if cfg.SAMLRootURL != "" && (cfg.SAMLIdPMetadata == "" || cfg.SAMLCertFile == "" || cfg.SAMLKeyFile == "") {
+		invalid("SAML_IDP_METADATA, SAML_SP_CERT_FILE and SAML_SP_KEY_FILE are required when SAML_ROOT_URL is set")
 	}
+	if cfg.BreakGlassCredentialHash != "" {
+		// A break-glass credential must always auto-expire
+		expiresAt, err := time.Parse(time.RFC3339, e.get("BREAK_GLASS_EXPIRES_AT"))
+		if err != nil {
+			invalid("BREAK_GLASS_EXPIRES_AT must be an RFC 3339 timestamp when BREAK_GLASS_CREDENTIAL_HASH is set")
+		}
+		cfg.BreakGlassExpiresAt = expiresAt
+	}
+	if banDuration := e.get("CANARY_BAN_DURATION"); banDuration != "" {
+		d, err := time.ParseDuration(banDuration)
+		if err != nil {
+			invalid("CANARY_BAN_DURATION must be a duration such as 24h: %v", err)
+		}
+		cfg.CanaryBanDuration = d
+	}
+	if timeout := e.get("DB_CONNECT_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d < 0 {
+			invalid("DB_CONNECT_TIMEOUT must be a duration such as 30s, or 0 to try once")
+		}
+		cfg.DBConnectTimeout = d
+	}
+	if threshold := e.get("SLOW_QUERY_THRESHOLD"); threshold != "" {
+		d, err := time.ParseDuration(threshold)
+		if err != nil || d < 0 {
+			invalid("SLOW_QUERY_THRESHOLD must be a duration such as 200ms, or 0 to disable")
+		}
+		cfg.SlowQueryThreshold = d
+	}
+	if maxAttempts := e.get("LOCKOUT_MAX_FAILED_ATTEMPTS"); maxAttempts != "" {
+		n, err := strconv.ParseInt(maxAttempts, 10, 64)
+		if err != nil || n < 1 {
+			invalid("LOCKOUT_MAX_FAILED_ATTEMPTS must be a positive integer")
+		}
+		cfg.Lockout.MaxFailedAttempts = n
+	}
+	if memory := e.get("ARGON2_MEMORY"); memory != "" {
+		n, err := strconv.ParseUint(memory, 10, 32)
+		if err != nil {
+			invalid("ARGON2_MEMORY must be a number of KiB")
+		}
+		cfg.Argon2.Memory = uint32(n)
+	}
+	if passes := e.get("ARGON2_TIME"); passes != "" {
+		n, err := strconv.ParseUint(passes, 10, 32)
+		if err != nil {
+			invalid("ARGON2_TIME must be a positive integer")
+		}
+		cfg.Argon2.Time = uint32(n)
+	}
+	if lanes := e.get("ARGON2_PARALLELISM"); lanes != "" {
+		n, err := strconv.ParseUint(lanes, 10, 8)
+		if err != nil {
+			invalid("ARGON2_PARALLELISM must be an integer from 1 to 255")
+		}
+		cfg.Argon2.Parallelism = uint8(n)
+	}
+	if err := cfg.Argon2.Validate(); err != nil {
+		invalid("invalid ARGON2 settings: %v", err)
+	}
+	switch cfg.PasswordHash {
+	case "", "argon2id", "bcrypt":
+	default:
+		invalid("PASSWORD_HASH must be argon2id or bcrypt")
+	}
+	if cost := e.get("BCRYPT_COST"); cost != "" {
+		n, err := strconv.Atoi(cost)
+		if err != nil || n < bcrypt.MinCost || n > bcrypt.MaxCost {
+			invalid("BCRYPT_COST must be an integer from %d to %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		cfg.BcryptCost = n
+	}
+	if workers := e.get("PASSWORD_HASH_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 0 {
+			invalid("PASSWORD_HASH_WORKERS must be a positive integer, or 0 for one per CPU")
+		}
+		cfg.PasswordHashWorkers = n
+	}
+	if path := e.get("VAULT_DB_CREDS_PATH"); path != "" && driver == database.DriverPostgres && !e.reloading {
+		if err := loadDBCredentials(cfg, path); err != nil {
+			invalid("%v", err)
+		}
+	}
+	if err := loadPeppers(cfg, e); err != nil {
+		invalid("%v", err)
+	}
+	if err := loadPasswordPolicy(cfg, e); err != nil {
+		invalid("%v", err)
+	}
+	if timeout := e.get("PWNED_PASSWORDS_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			invalid("PWNED_PASSWORDS_TIMEOUT must be a positive duration such as 2s")
+		}
+		cfg.PwnedPasswordsTimeout = d
+	}
+	switch e.get("PWNED_PASSWORDS_FAIL") {
+	case "", "open":
+	case "closed":
+		cfg.PwnedPasswordsFailOpen = false
+	default:
+		invalid("PWNED_PASSWORDS_FAIL must be open or closed")
+	}
+	if ttl := e.get("TOKEN_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < time.Minute {
+			invalid("TOKEN_TTL must be a duration of at least 1m, such as 24h")
+		}
+		cfg.TokenTTL = d
+	}
+	if ttl := e.get("REMEMBER_ME_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || (d != 0 && d < cfg.TokenTTL) {
+			invalid("REMEMBER_ME_TTL must be a duration no shorter than TOKEN_TTL, such as 720h, or 0 to ignore remember_me")
+		}
+		cfg.RememberMeTTL = d
+	}
+	if maxAge := e.get("PASSWORD_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil || d < 0 {
+			invalid("PASSWORD_MAX_AGE must be a duration such as 2160h, or 0 to never expire passwords")
+		}
+		cfg.PasswordMaxAge = d
+	}
+	if queueSize := e.get("AUDIT_QUEUE_SIZE"); queueSize != "" {
+		n, err := strconv.Atoi(queueSize)
+		if err != nil || n < 1 {
+			invalid("AUDIT_QUEUE_SIZE must be a positive integer")
+		}
+		cfg.AuditQueueSize = n
+	}
+	logLevel, err := logging.ParseLevel(e.get("LOG_LEVEL"))
+	if err != nil {
+		invalid("invalid LOG_LEVEL: %v", err)
+	}
+	cfg.LogLevel = logLevel
+	if ttl := e.get("IDEMPOTENCY_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			invalid("IDEMPOTENCY_TTL must be a positive duration such as 24h")
+		}
+		cfg.IdempotencyTTL = d
+	}
+	if ttl := e.get("SESSION_CACHE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < 0 {
+			invalid("SESSION_CACHE_TTL must be a duration such as 5s, or 0 to disable the cache")
+		}
+		cfg.SessionCacheTTL = d
+	}
+	if maxBody := e.get("MAX_BODY_BYTES"); maxBody != "" {
+		n, err := strconv.ParseInt(maxBody, 10, 64)
+		if err != nil || n < 1 {
+			invalid("MAX_BODY_BYTES must be a positive integer")
+		}
+		cfg.MaxBodyBytes = n
+	}
+	switch cfg.SessionMode {
+	case "":
+		cfg.SessionMode = SessionModeToken
+	case SessionModeToken, SessionModeCookie:
+	default:
+		invalid("SESSION_MODE must be token or cookie")
+	}
+	if maxAge := e.get("HSTS_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil || d < 0 {
+			invalid("HSTS_MAX_AGE must be a duration such as 8760h, or 0 to disable")
+		}
+		cfg.HSTSMaxAge = d
+	}
+	if cfg.DebugEndpoints && cfg.AdminAPIToken == "" {
+		invalid("ADMIN_API_TOKEN is required when DEBUG_ENDPOINTS is true")
+	}
+	if cfg.CaptchaProvider != "" && cfg.CaptchaSecret == "" {
+		invalid("CAPTCHA_SECRET is required when CAPTCHA_PROVIDER is set")
+	}
+	if afterFailures := e.get("CAPTCHA_AFTER_FAILURES"); afterFailures != "" {
+		n, err := strconv.Atoi(afterFailures)
+		if err != nil || n < 1 {
+			invalid("CAPTCHA_AFTER_FAILURES must be a positive integer")
+		}
+		cfg.CaptchaAfterFailures = n
+	}
+	if cfg.GeoIPBlockCountries, err = ParseCountries(e.get("GEOIP_BLOCK_COUNTRIES")); err != nil {
+		invalid("invalid GEOIP_BLOCK_COUNTRIES: %v", err)
+	}
+	if cfg.GeoIPChallengeCountries, err = ParseCountries(e.get("GEOIP_CHALLENGE_COUNTRIES")); err != nil {
+		invalid("invalid GEOIP_CHALLENGE_COUNTRIES: %v", err)
+	}
+	if (len(cfg.GeoIPBlockCountries) > 0 || len(cfg.GeoIPChallengeCountries) > 0) && cfg.GeoIPDatabase == "" {
+		invalid("GEOIP_DATABASE is required when GEOIP_BLOCK_COUNTRIES or GEOIP_CHALLENGE_COUNTRIES is set")
+	}
+	if len(cfg.GeoIPChallengeCountries) > 0 && cfg.CaptchaProvider == "" {
+		invalid("CAPTCHA_PROVIDER is required when GEOIP_CHALLENGE_COUNTRIES is set")
+	}
+	if cfg.BlockedEmailDomains, err = ParseDomains(e.get("BLOCKED_EMAIL_DOMAINS")); err != nil {
+		invalid("invalid BLOCKED_EMAIL_DOMAINS: %v", err)
+	}
+	switch cfg.Storage {
+	case "":
+		cfg.Storage = "database"
+	case "database", "memory":
+	default:
+		invalid("STORAGE must be database or memory")
+	}
+	switch cfg.RateLimitStore {
+	case "":
+		cfg.RateLimitStore = "memory"
+	case "memory":
+	case "redis":
+		if cfg.RedisURL == "" {
+			invalid("REDIS_URL is required when RATE_LIMIT_STORE is redis")
+		}
+	default:
+		invalid("RATE_LIMIT_STORE must be memory or redis")
+	}
+	switch cfg.SessionStore {
+	case "":
+		cfg.SessionStore = "database"
+	case "database":
+	case "redis":
+		if cfg.RedisURL == "" {
+			invalid("REDIS_URL is required when SESSION_STORE is redis")
+		}
+	default:
+		invalid("SESSION_STORE must be database or redis")
+	}
+	switch cfg.EventBus {
+	case "":
+	case "kafka":
+		if cfg.KafkaRESTURL == "" {
+			invalid("KAFKA_REST_URL is required when EVENT_BUS is kafka")
+		}
+	case "nats":
+		if cfg.NATSURL == "" {
+			invalid("NATS_URL is required when EVENT_BUS is nats")
+		}
+	default:
+		invalid("EVENT_BUS must be kafka or nats")
+	}
+	switch cfg.EmailSender {
+	case "", "log":
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.EmailFrom == "" {
+			invalid("SMTP_HOST and EMAIL_FROM are required when EMAIL_SENDER is smtp")
+		}
+		switch cfg.SMTPTLS {
+		case "", "starttls", "tls", "none":
+		default:
+			invalid("SMTP_TLS must be starttls, tls or none")
+		}
+		if port := e.get("SMTP_PORT"); port != "" {
+			n, err := strconv.Atoi(port)
+			if err != nil || n < 1 || n > 65535 {
+				invalid("SMTP_PORT must be a port number")
+			}
+			cfg.SMTPPort = n
+		}
+	case "sendgrid":
+		if cfg.SendGridAPIKey == "" || cfg.EmailFrom == "" {
+			invalid("SENDGRID_API_KEY and EMAIL_FROM are required when EMAIL_SENDER is sendgrid")
+		}
+	case "ses":
+		if cfg.EmailFrom == "" {
+			invalid("EMAIL_FROM is required when EMAIL_SENDER is ses")
+		}
+		cfg.EmailRateLimit = 1
+	default:
+		invalid("EMAIL_SENDER must be smtp, sendgrid, ses or log")
+	}
+	if rateLimit := e.get("EMAIL_RATE_LIMIT"); rateLimit != "" {
+		n, err := strconv.ParseFloat(rateLimit, 64)
+		if err != nil || n < 0 {
+			invalid("EMAIL_RATE_LIMIT must be a number of emails per second, or 0 for no limit")
+		}
+		cfg.EmailRateLimit = n
+	}
+	if cfg.Environment == "" {
+		cfg.Environment = "development"
+	}
+
+	if len(errs) > 0 {
+		cfg.closeVault()
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	// Refuse to start in production with placeholder secrets
+	problems, err := cfg.CheckSecrets()
+	if err != nil {
+		cfg.closeVault()
+		return nil, err
+	}
+	for _, problem := range problems {
+		slog.Warn("unsafe configuration", "problem", problem)
+	}
+
 	return cfg, nil
 }
+
+// checkURL checks that value, when set, is an absolute URL with one of the
+// schemes
+func checkURL(value string, schemes ...string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL")
+	}
+	if !slices.Contains(schemes, u.Scheme) || (u.Host == "" && u.Scheme != "unix") {
+		return fmt.Errorf("must be an absolute %s:// URL", strings.Join(schemes, ":// or "))
+	}
+	return nil
+}
+
+// vaultTimeout bounds each request to a secret manager while loading the
+// configuration
+const vaultTimeout = 10 * time.Second
+
+// closeVault stops using the Vault connection, if any, once the
+// configuration is not used after all
+func (c *Config) closeVault() {
+	if c.Vault != nil {
+		c.Vault.Close()
+		c.Vault = nil
+	}
+}
+
+// loadVault connects to Vault when VAULT_ADDR is set
+func loadVault(e *environment) (*secrets.Vault, error) {
+	addr := e.get("VAULT_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	vault, err := secrets.NewVault(ctx, secrets.VaultConfig{
+		Address:             addr,
+		KubernetesRole:      e.get("VAULT_K8S_ROLE"),
+		KubernetesMount:     e.get("VAULT_K8S_MOUNT"),
+		KubernetesTokenPath: e.get("VAULT_K8S_TOKEN_PATH"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vault, nil
+}
+
+// environment reads settings from environment variables, falling back to
+// the configuration file, and resolves references to secret managers
+type environment struct {
+	file      map[string]string // settings of the configuration file, if any
+	reloading bool              // leases no new database credentials
+	vault     *secrets.Vault    // nil without VAULT_ADDR
+	aws       *secrets.AWS      // created for the first AWS reference
+}
+
+// get returns a setting, from the environment variable when it is set and
+// else from the configuration file
+func (e *environment) get(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return e.file[name]
+}
+
+// secret returns a setting, reading it from Vault when it is a
+// vault:<path>#<key> reference, or from AWS for a secretsmanager:// or ssm://
+// reference
+func (e *environment) secret(name string) (string, error) {
+	value := e.get(name)
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+
+	var err error
+	if ref, ok := strings.CutPrefix(value, secrets.VaultPrefix); ok {
+		if e.vault == nil {
+			return "", fmt.Errorf("%s is read from Vault, but VAULT_ADDR is not set", name)
+		}
+		value, err = e.vault.Get(ctx, ref)
+	} else if secrets.IsAWSReference(value) {
+		if e.aws == nil {
+			if e.aws, err = secrets.NewAWS(ctx); err != nil {
+				return "", fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		value, err = e.aws.Get(ctx, value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+// loadDBCredentials issues database credentials from Vault and sets them in
+// cfg.DbURL
+func loadDBCredentials(cfg *Config, path string) error {
+	if cfg.Vault == nil {
+		return fmt.Errorf("VAULT_DB_CREDS_PATH requires VAULT_ADDR")
+	}
+	u, err := url.Parse(cfg.DbURL)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return fmt.Errorf("VAULT_DB_CREDS_PATH requires DATABASE_URL to be a postgres:// URL")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	creds, err := cfg.Vault.DatabaseCredentials(ctx, path)
+	if err != nil {
+		return err
+	}
+	u.User = url.UserPassword(creds.Username, creds.Password)
+	cfg.DbURL = u.String()
+	cfg.DBCredentials = creds
+	return nil
+}
+
+// splitSecrets splits a comma-separated list of secrets, newest first
+func splitSecrets(list string) ([]string, error) {
+	var secrets []string
+	for _, secret := range strings.Split(list, ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			return nil, fmt.Errorf("must be a comma-separated list of secrets, without empty entries")
+		}
+		if slices.Contains(secrets, secret) {
+			return nil, fmt.Errorf("lists the same secret twice")
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// loadPeppers reads PASSWORD_PEPPERS and PASSWORD_PEPPER_ID into cfg
+func loadPeppers(cfg *Config, e *environment) error {
+	list, err := e.secret("PASSWORD_PEPPERS")
+	if err != nil {
+		return err
+	}
+	if list == "" {
+		if e.get("PASSWORD_PEPPER_ID") != "" {
+			return fmt.Errorf("PASSWORD_PEPPER_ID requires PASSWORD_PEPPERS")
+		}
+		return nil
+	}
+
+	cfg.PasswordPeppers = make(map[string][]byte)
+	for _, entry := range strings.Split(list, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || !password.ValidPepperID(id) {
+			return fmt.Errorf("PASSWORD_PEPPERS must be a comma-separated list of id=secret, with IDs of letters, digits, - and _")
+		}
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil || len(key) < password.MinPepperLen {
+			return fmt.Errorf("PASSWORD_PEPPERS: pepper %q must be at least %d bytes, base64-encoded", id, password.MinPepperLen)
+		}
+		if _, dup := cfg.PasswordPeppers[id]; dup {
+			return fmt.Errorf("PASSWORD_PEPPERS: pepper %q is listed twice", id)
+		}
+		if cfg.PasswordPepperID == "" {
+			cfg.PasswordPepperID = id
+		}
+		cfg.PasswordPeppers[id] = key
+	}
+	if id := e.get("PASSWORD_PEPPER_ID"); id != "" {
+		if _, ok := cfg.PasswordPeppers[id]; !ok {
+			return fmt.Errorf("PASSWORD_PEPPER_ID %q is not in PASSWORD_PEPPERS", id)
+		}
+		cfg.PasswordPepperID = id
+	}
+	return nil
+}
+
+// loadPasswordPolicy reads the PASSWORD_* policy settings into cfg
+func loadPasswordPolicy(cfg *Config, e *environment) error {
+	policy := &cfg.PasswordPolicy
+	if minLength := e.get("PASSWORD_MIN_LENGTH"); minLength != "" {
+		n, err := strconv.Atoi(minLength)
+		if err != nil || n < 1 {
+			return fmt.Errorf("PASSWORD_MIN_LENGTH must be a positive integer")
+		}
+		policy.MinLength = n
+	}
+	if maxLength := e.get("PASSWORD_MAX_LENGTH"); maxLength != "" {
+		n, err := strconv.Atoi(maxLength)
+		if err != nil || n < 0 {
+			return fmt.Errorf("PASSWORD_MAX_LENGTH must be a positive integer, or 0 for no limit")
+		}
+		policy.MaxLength = n
+	}
+	if policy.MaxLength > 0 && policy.MaxLength < policy.MinLength {
+		return fmt.Errorf("PASSWORD_MAX_LENGTH must not be below PASSWORD_MIN_LENGTH")
+	}
+	if cfg.PasswordHash == "bcrypt" && len(cfg.PasswordPeppers) == 0 {
+		policy.MaxBytes = 72 // bcrypt ignores the rest; peppered passwords are shorter
+	}
+
+	for _, class := range strings.Split(e.get("PASSWORD_REQUIRE"), ",") {
+		switch strings.TrimSpace(class) {
+		case "":
+		case "upper":
+			policy.RequireUpper = true
+		case "lower":
+			policy.RequireLower = true
+		case "digit":
+			policy.RequireDigit = true
+		case "symbol":
+			policy.RequireSymbol = true
+		default:
+			return fmt.Errorf("PASSWORD_REQUIRE must list upper, lower, digit or symbol")
+		}
+	}
+
+	denyList := make(map[string]bool)
+	if e.get("PASSWORD_DENY_COMMON") == "true" {
+		for entry := range password.CommonPasswords() {
+			denyList[entry] = true
+		}
+	}
+	if path := e.get("PASSWORD_DENYLIST_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("PASSWORD_DENYLIST_FILE: %v", err)
+		}
+		defer f.Close()
+		entries, err := password.ReadDenyList(f)
+		if err != nil {
+			return fmt.Errorf("PASSWORD_DENYLIST_FILE: %v", err)
+		}
+		for entry := range entries {
+			denyList[entry] = true
+		}
+	}
+	if len(denyList) > 0 {
+		policy.DenyList = denyList
+	}
+	policy.DisallowEmail = e.get("PASSWORD_DISALLOW_EMAIL") == "true"
+	return nil
+}
+
+// ParseTrustedProxies parses a comma-separated list of CIDR ranges and single
+// IP addresses
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ParseCountries parses a comma-separated list of ISO 3166-1 alpha-2 country
+// codes, returning them in upper case
+func ParseCountries(value string) ([]string, error) {
+	var countries []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToUpper(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if len(entry) != 2 || entry[0] < 'A' || entry[0] > 'Z' || entry[1] < 'A' || entry[1] > 'Z' {
+			return nil, fmt.Errorf("invalid country code %q", entry)
+		}
+		countries = append(countries, entry)
+	}
+	return countries, nil
+}
+
+// ParseDomains parses a comma-separated list of email domains, lowercasing
+// them and skipping empty entries
+func ParseDomains(value string) ([]string, error) {
+	var domains []string
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		domain, ok := model.NormalizeDomain(entry)
+		if !ok {
+			return nil, fmt.Errorf("invalid domain %q", strings.TrimSpace(entry))
+		}
+		domains = append(domains, domain)
+	}
+	return domains, nil
+}
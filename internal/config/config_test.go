@@ -0,0 +1,408 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/password"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.5,fd00::/8, ::1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.20.30.40", want: true},
+		{ip: "192.168.1.5", want: true},
+		{ip: "192.168.1.6", want: false},
+		{ip: "fd00::1", want: true},
+		{ip: "::1", want: true},
+		{ip: "203.0.113.9", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got := false
+			for _, network := range networks {
+				if network.Contains(net.ParseIP(tt.ip)) {
+					got = true
+				}
+			}
+			if got != tt.want {
+				t.Errorf("got trusted %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, value := range []string{"10.0.0.0/33", "proxy.internal"} {
+		if _, err := ParseTrustedProxies(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestParseCountries(t *testing.T) {
+	got, err := ParseCountries(" de, fr ,,US")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"DE", "FR", "US"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, value := range []string{"DEU", "Germany", "D1"} {
+		if _, err := ParseCountries(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestParseDomains(t *testing.T) {
+	got, err := ParseDomains(" Mailinator.com, @spam.example,,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"mailinator.com", "spam.example"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, value := range []string{"localhost", "user@spam.example", "spam..example"} {
+		if _, err := ParseDomains(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestLoadGeoIP(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DATABASE_URL", "postgres://localhost/auth")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("GEOIP_CHALLENGE_COUNTRIES", "ru")
+	_, err := Load()
+	for _, want := range []string{"GEOIP_DATABASE is required", "CAPTCHA_PROVIDER is required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error = %v, want it to mention %q", err, want)
+		}
+	}
+
+	t.Setenv("GEOIP_DATABASE", "/var/lib/GeoIP/GeoLite2-Country.mmdb")
+	t.Setenv("GEOIP_BLOCK_COUNTRIES", "kp")
+	t.Setenv("CAPTCHA_PROVIDER", "turnstile")
+	t.Setenv("CAPTCHA_SECRET", "secret")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !slices.Equal(cfg.GeoIPBlockCountries, []string{"KP"}) || !slices.Equal(cfg.GeoIPChallengeCountries, []string{"RU"}) {
+		t.Errorf("got block %v and challenge %v", cfg.GeoIPBlockCountries, cfg.GeoIPChallengeCountries)
+	}
+}
+
+func TestLoadRememberMeTTL(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DATABASE_URL", "postgres://localhost/auth")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 30 * 24 * time.Hour},
+		{value: "2160h", want: 2160 * time.Hour},
+		{value: "0", want: 0},
+		{value: "1h", wantErr: true}, // shorter than TOKEN_TTL
+		{value: "forever", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("REMEMBER_ME_TTL", tt.value)
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "REMEMBER_ME_TTL") {
+					t.Errorf("Load() error = %v, want it to mention REMEMBER_ME_TTL", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.RememberMeTTL != tt.want {
+				t.Errorf("RememberMeTTL = %v, want %v", cfg.RememberMeTTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCORS(t *testing.T) {
+	cors, err := ParseCORS(DefaultCORS(), "https://app.example.com, https://*.example.org", "get,post", "", "true", "1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cors.AllowedOrigins) != 2 || !cors.AllowCredentials || cors.MaxAge != time.Hour {
+		t.Errorf("unexpected configuration: %+v", cors)
+	}
+	if len(cors.AllowedMethods) != 2 || cors.AllowedMethods[0] != "GET" {
+		t.Errorf("got methods %v, want [GET POST]", cors.AllowedMethods)
+	}
+	if len(cors.AllowedHeaders) != len(DefaultCORS().AllowedHeaders) {
+		t.Errorf("got headers %v, want the defaults", cors.AllowedHeaders)
+	}
+
+	tests := []struct {
+		name        string
+		origins     string
+		credentials string
+		maxAge      string
+	}{
+		{name: "origin without scheme", origins: "app.example.com"},
+		{name: "origin with path", origins: "https://app.example.com/login"},
+		{name: "any origin with credentials", origins: "*", credentials: "true"},
+		{name: "invalid credentials flag", origins: "*", credentials: "yes please"},
+		{name: "invalid max age", origins: "*", maxAge: "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCORS(DefaultCORS(), tt.origins, "", "", tt.credentials, tt.maxAge); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseACME(t *testing.T) {
+	acme, err := ParseACME("Auth.example.com, login.example.com", "", "ops@example.com", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(acme.Domains, []string{"auth.example.com", "login.example.com"}) || acme.CacheDir != "acme-cache" || acme.HTTPPort != 80 {
+		t.Errorf("unexpected configuration: %+v", acme)
+	}
+	if acme, err := ParseACME("", "/var/cache/acme", "", "8080", ""); err != nil || acme.Enabled() {
+		t.Errorf("ParseACME() without domains = %+v, %v, want disabled", acme, err)
+	}
+
+	tests := []struct {
+		name, domains, email, httpPort, directoryURL string
+	}{
+		{name: "wildcard domain", domains: "*.example.com"},
+		{name: "URL as domain", domains: "https://auth.example.com"},
+		{name: "domain with port", domains: "auth.example.com:443"},
+		{name: "invalid email", domains: "auth.example.com", email: "ops"},
+		{name: "invalid port", domains: "auth.example.com", httpPort: "http"},
+		{name: "plain HTTP directory", domains: "auth.example.com", directoryURL: "http://acme.example.com/directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseACME(tt.domains, "", tt.email, tt.httpPort, tt.directoryURL); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseWebhooks(t *testing.T) {
+	endpoints, err := ParseWebhooks(`[
+		{"url": "https://hooks.example.com/auth", "secret": "0123456789abcdef", "events": ["user.login", "user.locked"]},
+		{"url": "http://localhost:9000/hook", "secret": "0123456789abcdef"}
+	]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(endpoints) != 2 || !endpoints[0].Subscribes("user.locked") || endpoints[0].Subscribes("user.registered") || !endpoints[1].Subscribes("session.revoked") {
+		t.Errorf("unexpected endpoints: %+v", endpoints)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not an array", value: `{"url": "https://hooks.example.com"}`},
+		{name: "relative URL", value: `[{"url": "/hook", "secret": "0123456789abcdef"}]`},
+		{name: "other scheme", value: `[{"url": "ftp://hooks.example.com", "secret": "0123456789abcdef"}]`},
+		{name: "short secret", value: `[{"url": "https://hooks.example.com", "secret": "short"}]`},
+		{name: "unknown event", value: `[{"url": "https://hooks.example.com", "secret": "0123456789abcdef", "events": ["user.deleted"]}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseWebhooks(tt.value); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadPasswordPolicy(t *testing.T) {
+	denyList := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(denyList, []byte("# company names\nAcmeCorp2026\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_REQUIRE", "upper, digit")
+	t.Setenv("PASSWORD_DENY_COMMON", "true")
+	t.Setenv("PASSWORD_DENYLIST_FILE", denyList)
+
+	cfg := &Config{PasswordHash: "bcrypt", PasswordPolicy: password.DefaultPolicy}
+	if err := loadPasswordPolicy(cfg, &environment{}); err != nil {
+		t.Fatal(err)
+	}
+	policy := cfg.PasswordPolicy
+	if policy.MinLength != 12 || policy.MaxLength != 128 || policy.MaxBytes != 72 ||
+		!policy.RequireUpper || policy.RequireLower || !policy.RequireDigit || policy.DisallowEmail {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+	if !policy.DenyList["acmecorp2026"] || !policy.DenyList["password123"] {
+		t.Error("deny list is missing the file or the common passwords")
+	}
+
+	t.Setenv("PASSWORD_REQUIRE", "emoji")
+	if err := loadPasswordPolicy(&Config{}, &environment{}); err == nil {
+		t.Error("expected error for an unknown character class")
+	}
+}
+
+func TestLoadPeppers(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, password.MinPepperLen))
+	tests := []struct {
+		name    string
+		peppers string
+		current string
+		wantID  string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "first is current", peppers: "2026=" + secret + ", 2025=" + secret, wantID: "2026"},
+		{name: "current chosen", peppers: "2026=" + secret + ",2025=" + secret, current: "2025", wantID: "2025"},
+		{name: "unknown current", peppers: "2026=" + secret, current: "2027", wantErr: true},
+		{name: "current without peppers", current: "2026", wantErr: true},
+		{name: "short secret", peppers: "2026=c2hvcnQ=", wantErr: true},
+		{name: "invalid ID", peppers: "a$b=" + secret, wantErr: true},
+		{name: "duplicate ID", peppers: "1=" + secret + ",1=" + secret, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PASSWORD_PEPPERS", tt.peppers)
+			t.Setenv("PASSWORD_PEPPER_ID", tt.current)
+			cfg := &Config{}
+			err := loadPeppers(cfg, &environment{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPeppers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.PasswordPepperID != tt.wantID {
+				t.Errorf("PasswordPepperID = %q, want %q", cfg.PasswordPepperID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestLoadJWTSecrets(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("STORAGE", "memory")
+	tests := []struct {
+		name         string
+		secret       string
+		secrets      string
+		wantSecret   string
+		wantPrevious []string
+		wantErr      bool
+	}{
+		{name: "single secret", secret: "only", wantSecret: "only"},
+		{name: "list", secrets: "new, old,older", wantSecret: "new", wantPrevious: []string{"old", "older"}},
+		{name: "list of one", secrets: "only", wantSecret: "only"},
+		{name: "both", secret: "one", secrets: "new,old", wantErr: true},
+		{name: "empty entry", secrets: "new,,old", wantErr: true},
+		{name: "duplicate", secrets: "new,old,new", wantErr: true},
+		{name: "neither", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", tt.secret)
+			t.Setenv("JWT_SECRETS", tt.secrets)
+			cfg, err := load(&environment{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.JwtSecret != tt.wantSecret || !slices.Equal(cfg.PreviousJwtSecrets, tt.wantPrevious)) {
+				t.Errorf("got %q and previous %q, want %q and %q", cfg.JwtSecret, cfg.PreviousJwtSecrets, tt.wantSecret, tt.wantPrevious)
+			}
+		})
+	}
+}
+
+func TestLoadPostgresOnlySettings(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("JWT_SECRET", "test-secret")
+	tests := []struct {
+		name    string
+		dbURL   string
+		setting string
+		value   string
+		wantErr bool
+	}{
+		{name: "slow queries with postgres", dbURL: "postgres://localhost/auth", setting: "SLOW_QUERY_THRESHOLD", value: "200ms"},
+		{name: "slow queries with mysql", dbURL: "mysql://localhost/auth", setting: "SLOW_QUERY_THRESHOLD", value: "200ms", wantErr: true},
+		{name: "vault credentials with mysql", dbURL: "mysql://localhost/auth", setting: "VAULT_DB_CREDS_PATH", value: "database/creds/auth-service", wantErr: true},
+		{name: "vault credentials with sqlite", dbURL: "sqlite://auth.db", setting: "VAULT_DB_CREDS_PATH", value: "database/creds/auth-service", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", tt.dbURL)
+			t.Setenv(tt.setting, tt.value)
+			_, err := load(&environment{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.setting) {
+				t.Errorf("error %q does not mention %s", err, tt.setting)
+			}
+		})
+	}
+}
+
+func TestEnvironmentSecret(t *testing.T) {
+	e := &environment{}
+	t.Setenv("JWT_SECRET", "plain-secret")
+	if got, err := e.secret("JWT_SECRET"); err != nil || got != "plain-secret" {
+		t.Errorf("secret() = %q, %v, want the plain value", got, err)
+	}
+
+	t.Setenv("JWT_SECRET", "vault:secret/data/auth-service#jwt_secret")
+	if _, err := e.secret("JWT_SECRET"); err == nil {
+		t.Error("expected an error for a Vault reference without VAULT_ADDR")
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Setenv("PORT", "99999")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("DATABASE_URL", "ftp://db.example.com/auth")
+	t.Setenv("REDIS_URL", "localhost:6379")
+	t.Setenv("SESSION_MODE", "bearer")
+	t.Setenv("IDEMPOTENCY_TTL", "forever")
+	t.Setenv("SESSION_CACHE_TTL", "-5s")
+
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	for _, want := range []string{"JWT_SECRET", "PORT", "DATABASE_URL", "REDIS_URL", "SESSION_MODE", "IDEMPOTENCY_TTL", "SESSION_CACHE_TTL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if len(verr.Errors) != 7 {
+		t.Errorf("got %d problems, want 7: %v", len(verr.Errors), err)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures which browser origins may call the API cross-origin. No
+// allowed origins disables CORS, so browsers block cross-origin calls.
+type CORS struct {
+	// Exact origins such as https://app.example.com, a wildcard subdomain such
+	// as https://*.example.com, or "*" for any origin
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// Whether browsers send cookies and Authorization headers; cannot be
+	// combined with the "*" origin
+	AllowCredentials bool
+
+	// How long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// DefaultCORS returns the methods, headers and preflight lifetime used when
+// CORS is enabled without overriding them
+func DefaultCORS() CORS {
+	return CORS{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token", "Accept-Auth", "Idempotency-Key"},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// ParseCORS overrides base with the comma-separated origins, methods and
+// headers, the credentials flag ("true" or "false") and the preflight max age
+// (a duration such as 10m); empty values keep base
+func ParseCORS(base CORS, origins, methods, headers, credentials, maxAge string) (CORS, error) {
+	cors := base
+	if list := splitList(origins); list != nil {
+		for _, origin := range list {
+			if origin == "*" {
+				continue
+			}
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				return CORS{}, fmt.Errorf("invalid origin %q, want scheme://host[:port]", origin)
+			}
+		}
+		cors.AllowedOrigins = list
+	}
+	if list := splitList(methods); list != nil {
+		for i := range list {
+			list[i] = strings.ToUpper(list[i])
+		}
+		cors.AllowedMethods = list
+	}
+	if list := splitList(headers); list != nil {
+		cors.AllowedHeaders = list
+	}
+	if credentials != "" {
+		allow, err := strconv.ParseBool(credentials)
+		if err != nil {
+			return CORS{}, fmt.Errorf("invalid credentials flag %q, want true or false", credentials)
+		}
+		cors.AllowCredentials = allow
+	}
+	if maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil || d < 0 {
+			return CORS{}, fmt.Errorf("invalid max age %q, want a duration such as 10m", maxAge)
+		}
+		cors.MaxAge = d
+	}
+
+	if cors.AllowCredentials {
+		for _, origin := range cors.AllowedOrigins {
+			if origin == "*" {
+				return CORS{}, fmt.Errorf("credentials cannot be allowed for every origin; list the origins instead")
+			}
+		}
+	}
+	return cors, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is a YAML or TOML configuration file. Each setting stands for
+// the environment variable in its comment, which takes precedence when set.
+type fileConfig struct {
+	Server struct {
+		Port           value `yaml:"port" toml:"port"`                       // PORT
+		Environment    value `yaml:"environment" toml:"environment"`         // APP_ENV
+		LogLevel       value `yaml:"log_level" toml:"log_level"`             // LOG_LEVEL
+		Storage        value `yaml:"storage" toml:"storage"`                 // STORAGE
+		MaxBodyBytes   value `yaml:"max_body_bytes" toml:"max_body_bytes"`   // MAX_BODY_BYTES
+		TrustedProxies value `yaml:"trusted_proxies" toml:"trusted_proxies"` // TRUSTED_PROXIES
+		SessionMode    value `yaml:"session_mode" toml:"session_mode"`       // SESSION_MODE
+		GraphQL        value `yaml:"graphql" toml:"graphql"`                 // GRAPHQL_ENABLED
+		APIDocs        value `yaml:"api_docs" toml:"api_docs"`               // API_DOCS
+		LocalesDir     value `yaml:"locales_dir" toml:"locales_dir"`         // LOCALES_DIR
+	} `yaml:"server" toml:"server"`
+
+	ACME struct {
+		Domains      value `yaml:"domains" toml:"domains"`             // ACME_DOMAINS
+		CacheDir     value `yaml:"cache_dir" toml:"cache_dir"`         // ACME_CACHE_DIR
+		Email        value `yaml:"email" toml:"email"`                 // ACME_EMAIL
+		HTTPPort     value `yaml:"http_port" toml:"http_port"`         // ACME_HTTP_PORT
+		DirectoryURL value `yaml:"directory_url" toml:"directory_url"` // ACME_DIRECTORY_URL
+	} `yaml:"acme" toml:"acme"`
+
+	GeoIP struct {
+		Database           value `yaml:"database" toml:"database"`                       // GEOIP_DATABASE
+		BlockCountries     value `yaml:"block_countries" toml:"block_countries"`         // GEOIP_BLOCK_COUNTRIES
+		ChallengeCountries value `yaml:"challenge_countries" toml:"challenge_countries"` // GEOIP_CHALLENGE_COUNTRIES
+	} `yaml:"geoip" toml:"geoip"`
+
+	Registration struct {
+		BlockedEmailDomains   value `yaml:"blocked_email_domains" toml:"blocked_email_domains"`     // BLOCKED_EMAIL_DOMAINS
+		BlockDisposableEmails value `yaml:"block_disposable_emails" toml:"block_disposable_emails"` // BLOCK_DISPOSABLE_EMAILS
+		FoldGmailAddresses    value `yaml:"fold_gmail_addresses" toml:"fold_gmail_addresses"`       // FOLD_GMAIL_ADDRESSES
+	} `yaml:"registration" toml:"registration"`
+
+	Database struct {
+		URL                value `yaml:"url" toml:"url"`                                   // DATABASE_URL
+		ConnectTimeout     value `yaml:"connect_timeout" toml:"connect_timeout"`           // DB_CONNECT_TIMEOUT
+		SlowQueryThreshold value `yaml:"slow_query_threshold" toml:"slow_query_threshold"` // SLOW_QUERY_THRESHOLD
+	} `yaml:"database" toml:"database"`
+
+	JWT struct {
+		Secret        value `yaml:"secret" toml:"secret"`                       // JWT_SECRET
+		Secrets       value `yaml:"secrets" toml:"secrets"`                     // JWT_SECRETS
+		TokenTTL      value `yaml:"token_ttl" toml:"token_ttl"`                 // TOKEN_TTL
+		RememberMeTTL value `yaml:"remember_me_ttl" toml:"remember_me_ttl"`     // REMEMBER_ME_TTL
+		DeviceBinding value `yaml:"device_binding" toml:"device_binding"`       // DEVICE_BINDING
+		SessionCache  value `yaml:"session_cache_ttl" toml:"session_cache_ttl"` // SESSION_CACHE_TTL
+	} `yaml:"jwt" toml:"jwt"`
+
+	Terms struct {
+		TosVersion           value `yaml:"tos_version" toml:"tos_version"`                       // TOS_VERSION
+		PrivacyPolicyVersion value `yaml:"privacy_policy_version" toml:"privacy_policy_version"` // PRIVACY_POLICY_VERSION
+	} `yaml:"terms" toml:"terms"`
+
+	RateLimit struct {
+		Store    value            `yaml:"store" toml:"store"`         // RATE_LIMIT_STORE
+		RedisURL value            `yaml:"redis_url" toml:"redis_url"` // REDIS_URL
+		Tiers    map[string]value `yaml:"tiers" toml:"tiers"`         // RATE_LIMITS
+		Routes   map[string]value `yaml:"routes" toml:"routes"`       // RATE_LIMIT_ROUTES
+	} `yaml:"rate_limit" toml:"rate_limit"`
+
+	Email struct {
+		Sender       value `yaml:"sender" toml:"sender"`               // EMAIL_SENDER
+		From         value `yaml:"from" toml:"from"`                   // EMAIL_FROM
+		TemplatesDir value `yaml:"templates_dir" toml:"templates_dir"` // EMAIL_TEMPLATES_DIR
+		RateLimit    value `yaml:"rate_limit" toml:"rate_limit"`       // EMAIL_RATE_LIMIT
+		SMTP         struct {
+			Host     value `yaml:"host" toml:"host"`         // SMTP_HOST
+			Port     value `yaml:"port" toml:"port"`         // SMTP_PORT
+			Username value `yaml:"username" toml:"username"` // SMTP_USERNAME
+			Password value `yaml:"password" toml:"password"` // SMTP_PASSWORD
+			TLS      value `yaml:"tls" toml:"tls"`           // SMTP_TLS
+		} `yaml:"smtp" toml:"smtp"`
+		SendGridAPIKey      value `yaml:"sendgrid_api_key" toml:"sendgrid_api_key"`           // SENDGRID_API_KEY
+		SESConfigurationSet value `yaml:"ses_configuration_set" toml:"ses_configuration_set"` // SES_CONFIGURATION_SET
+	} `yaml:"email" toml:"email"`
+}
+
+// value is a setting as its environment variable would hold it: numbers and
+// booleans as written, lists comma-separated
+type value string
+
+func (v *value) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*v = value(node.Value)
+	case yaml.SequenceNode:
+		items := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: list items must be plain values", item.Line)
+			}
+			items[i] = item.Value
+		}
+		*v = value(strings.Join(items, ","))
+	default:
+		return fmt.Errorf("line %d: expected a value or a list", node.Line)
+	}
+	return nil
+}
+
+func (v *value) UnmarshalTOML(data any) error {
+	switch data := data.(type) {
+	case []any:
+		items := make([]string, len(data))
+		for i, item := range data {
+			items[i] = fmt.Sprint(item)
+		}
+		*v = value(strings.Join(items, ","))
+	case map[string]any:
+		return fmt.Errorf("expected a value or a list")
+	default:
+		*v = value(fmt.Sprint(data))
+	}
+	return nil
+}
+
+// LoadFile reads a YAML (.yaml, .yml) or TOML (.toml) configuration file,
+// then loads the configuration as Load does. Environment variables override
+// the file, as does a .env file; settings in none of them get Load's
+// defaults.
+func LoadFile(path string) (*Config, error) {
+	_ = godotenv.Load()
+	e := &environment{}
+	if path != "" {
+		var err error
+		if e.file, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return load(e)
+}
+
+// Reload reads the configuration again, from the file at path and the
+// environment, for a running server to apply the settings that can change
+// without a restart. Secret references are resolved again, but no database
+// credentials are leased, and Config.Vault is nil.
+func Reload(path string) (*Config, error) {
+	e := &environment{reloading: true}
+	if path != "" {
+		var err error
+		if e.file, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := load(e)
+	if err != nil {
+		return nil, err
+	}
+	cfg.closeVault()
+	return cfg, nil
+}
+
+// readFile parses a configuration file into the environment variables its
+// settings stand for
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %v", err)
+	}
+
+	var file fileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&file); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("config file %s: %v", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("config file %s: unknown setting %s", path, undecoded[0])
+		}
+	default:
+		return nil, fmt.Errorf("config file %s: must end in .yaml, .yml or .toml", path)
+	}
+	return file.env(), nil
+}
+
+// env returns the environment variables of the settings in the file
+func (f *fileConfig) env() map[string]string {
+	env := make(map[string]string)
+	set := func(name string, v value) {
+		if v != "" {
+			env[name] = string(v)
+		}
+	}
+
+	set("PORT", f.Server.Port)
+	set("APP_ENV", f.Server.Environment)
+	set("LOG_LEVEL", f.Server.LogLevel)
+	set("STORAGE", f.Server.Storage)
+	set("MAX_BODY_BYTES", f.Server.MaxBodyBytes)
+	set("TRUSTED_PROXIES", f.Server.TrustedProxies)
+	set("SESSION_MODE", f.Server.SessionMode)
+	set("GRAPHQL_ENABLED", f.Server.GraphQL)
+	set("API_DOCS", f.Server.APIDocs)
+	set("LOCALES_DIR", f.Server.LocalesDir)
+
+	set("ACME_DOMAINS", f.ACME.Domains)
+	set("ACME_CACHE_DIR", f.ACME.CacheDir)
+	set("ACME_EMAIL", f.ACME.Email)
+	set("ACME_HTTP_PORT", f.ACME.HTTPPort)
+	set("ACME_DIRECTORY_URL", f.ACME.DirectoryURL)
+
+	set("GEOIP_DATABASE", f.GeoIP.Database)
+	set("GEOIP_BLOCK_COUNTRIES", f.GeoIP.BlockCountries)
+	set("GEOIP_CHALLENGE_COUNTRIES", f.GeoIP.ChallengeCountries)
+
+	set("BLOCKED_EMAIL_DOMAINS", f.Registration.BlockedEmailDomains)
+	set("BLOCK_DISPOSABLE_EMAILS", f.Registration.BlockDisposableEmails)
+	set("FOLD_GMAIL_ADDRESSES", f.Registration.FoldGmailAddresses)
+
+	set("DATABASE_URL", f.Database.URL)
+	set("DB_CONNECT_TIMEOUT", f.Database.ConnectTimeout)
+	set("SLOW_QUERY_THRESHOLD", f.Database.SlowQueryThreshold)
+
+	set("JWT_SECRET", f.JWT.Secret)
+	set("JWT_SECRETS", f.JWT.Secrets)
+	set("TOKEN_TTL", f.JWT.TokenTTL)
+	set("REMEMBER_ME_TTL", f.JWT.RememberMeTTL)
+	set("DEVICE_BINDING", f.JWT.DeviceBinding)
+	set("SESSION_CACHE_TTL", f.JWT.SessionCache)
+
+	set("TOS_VERSION", f.Terms.TosVersion)
+	set("PRIVACY_POLICY_VERSION", f.Terms.PrivacyPolicyVersion)
+
+	set("RATE_LIMIT_STORE", f.RateLimit.Store)
+	set("REDIS_URL", f.RateLimit.RedisURL)
+	set("RATE_LIMITS", limitList(f.RateLimit.Tiers))
+	set("RATE_LIMIT_ROUTES", limitList(f.RateLimit.Routes))
+
+	set("EMAIL_SENDER", f.Email.Sender)
+	set("EMAIL_FROM", f.Email.From)
+	set("EMAIL_TEMPLATES_DIR", f.Email.TemplatesDir)
+	set("EMAIL_RATE_LIMIT", f.Email.RateLimit)
+	set("SMTP_HOST", f.Email.SMTP.Host)
+	set("SMTP_PORT", f.Email.SMTP.Port)
+	set("SMTP_USERNAME", f.Email.SMTP.Username)
+	set("SMTP_PASSWORD", f.Email.SMTP.Password)
+	set("SMTP_TLS", f.Email.SMTP.TLS)
+	set("SENDGRID_API_KEY", f.Email.SendGridAPIKey)
+	set("SES_CONFIGURATION_SET", f.Email.SESConfigurationSet)
+	return env
+}
+
+// limitList formats rate limits keyed by tier or route in the form
+// ParseRateLimits accepts
+func limitList(limits map[string]value) value {
+	entries := make([]string, 0, len(limits))
+	for _, key := range slices.Sorted(maps.Keys(limits)) {
+		entries = append(entries, key+"="+string(limits[key]))
+	}
+	return value(strings.Join(entries, ","))
+}
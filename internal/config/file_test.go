@@ -0,0 +1,187 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const yamlConfig = `
+server:
+  port: 9090
+  environment: staging
+  trusted_proxies: [10.0.0.0/8, 192.168.1.1]
+database:
+  url: postgres://auth@db/auth
+  connect_timeout: 1m
+jwt:
+  secret: file-secret
+terms:
+  tos_version: "2026-10"
+rate_limit:
+  tiers:
+    strict: 5/1m:10
+    default: 200/1m
+  routes:
+    /auth/login: 3/1m
+email:
+  sender: smtp
+  from: auth@example.com
+  smtp:
+    host: smtp.example.com
+    port: 465
+`
+
+const tomlConfig = `
+[server]
+port = 9090
+environment = "staging"
+trusted_proxies = ["10.0.0.0/8", "192.168.1.1"]
+
+[database]
+url = "postgres://auth@db/auth"
+connect_timeout = "1m"
+
+[jwt]
+secret = "file-secret"
+
+[terms]
+tos_version = "2026-10"
+
+[rate_limit.tiers]
+strict = "5/1m:10"
+default = "200/1m"
+
+[rate_limit.routes]
+"/auth/login" = "3/1m"
+
+[email]
+sender = "smtp"
+from = "auth@example.com"
+
+[email.smtp]
+host = "smtp.example.com"
+port = 465
+`
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFile(t *testing.T) {
+	want := map[string]string{
+		"PORT":               "9090",
+		"APP_ENV":            "staging",
+		"TRUSTED_PROXIES":    "10.0.0.0/8,192.168.1.1",
+		"DATABASE_URL":       "postgres://auth@db/auth",
+		"DB_CONNECT_TIMEOUT": "1m",
+		"JWT_SECRET":         "file-secret",
+		"TOS_VERSION":        "2026-10",
+		"RATE_LIMITS":        "default=200/1m,strict=5/1m:10",
+		"RATE_LIMIT_ROUTES":  "/auth/login=3/1m",
+		"EMAIL_SENDER":       "smtp",
+		"EMAIL_FROM":         "auth@example.com",
+		"SMTP_HOST":          "smtp.example.com",
+		"SMTP_PORT":          "465",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "server.yaml", content: yamlConfig, want: want},
+		{name: "server.toml", content: tomlConfig, want: want},
+		{name: "empty.yml", content: "# nothing yet\n", want: map[string]string{}},
+		{name: "unknown.yaml", content: "server:\n  prot: 9090\n", wantErr: true},
+		{name: "unknown.toml", content: "[jwt]\nsecrte = \"x\"\n", wantErr: true},
+		{name: "nested.yaml", content: "jwt:\n  secret:\n    value: x\n", wantErr: true},
+		{name: "server.json", content: "{}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readFile(writeConfigFile(t, tt.name, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readFile() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("readFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileEnvOverrides(t *testing.T) {
+	path := writeConfigFile(t, "server.yaml", yamlConfig)
+	env, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cleared so the file's settings apply
+	for name := range env {
+		t.Setenv(name, "")
+	}
+	t.Setenv("PORT", "8081")
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("DATABASE_URL", "")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if cfg.Port != 8081 {
+		t.Errorf("Port = %d, want the environment's 8081", cfg.Port)
+	}
+	if cfg.JwtSecret != "file-secret" || cfg.Environment != "staging" || cfg.SMTPPort != 465 {
+		t.Errorf("settings from the file not applied: %+v", cfg)
+	}
+	if cfg.RateLimits.Strict.Burst != 10 || cfg.RateLimits.Admin != DefaultRateLimits().Admin {
+		t.Errorf("RateLimits = %+v, want strict from the file and the default admin tier", cfg.RateLimits)
+	}
+	if cfg.DBConnectTimeout.String() != "1m0s" {
+		t.Errorf("DBConnectTimeout = %v, want 1m", cfg.DBConnectTimeout)
+	}
+}
+
+func TestReloadFollowsFileChanges(t *testing.T) {
+	t.Setenv("PORT", "8081")
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("TOKEN_TTL", "")
+	t.Setenv("RATE_LIMITS", "")
+	path := writeConfigFile(t, "server.yaml", "jwt:\n  secret: first-secret\n  token_ttl: 1h\n")
+
+	cfg, err := Reload(path)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if cfg.JwtSecret != "first-secret" || cfg.TokenTTL != time.Hour {
+		t.Errorf("got secret %q and TTL %v, want the file's", cfg.JwtSecret, cfg.TokenTTL)
+	}
+
+	if err := os.WriteFile(path, []byte("jwt:\n  secret: second-secret\nrate_limit:\n  tiers: {strict: 3/1m}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Reload(path)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if cfg.JwtSecret != "second-secret" || cfg.TokenTTL != 24*time.Hour || cfg.RateLimits.Strict.Requests != 3 {
+		t.Errorf("got secret %q, TTL %v and strict limit %v, want the changed file's", cfg.JwtSecret, cfg.TokenTTL, cfg.RateLimits.Strict)
+	}
+
+	if err := os.WriteFile(path, []byte("jwt:\n  token_ttl: soon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Reload(path); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsafeConfig is returned when production configuration contains unsafe values
+var ErrUnsafeConfig = errors.New("unsafe configuration")
+
+// minProductionSecretLength is the shortest HMAC secret accepted in production
+const minProductionSecretLength = 32
+
+// minSecretEntropyBits is the least entropy an HMAC secret should have, as
+// estimated from the frequency of its characters
+const minSecretEntropyBits = 96
+
+// minProductionArgon2Memory is the smallest Argon2id memory cost (KiB) OWASP
+// recommends for password storage
+const minProductionArgon2Memory = 19 * 1024
+
+// minProductionBcryptCost is the smallest bcrypt cost OWASP recommends
+const minProductionBcryptCost = 10
+
+// knownDefaultSecrets are placeholder values from docs, examples, and tests
+var knownDefaultSecrets = map[string]bool{
+	"secret":          true,
+	"test-secret":     true,
+	"testsecret":      true,
+	"changeme":        true,
+	"change-me":       true,
+	"changeit":        true,
+	"mysecretkey":     true,
+	"your-jwt-secret": true,
+	"jwt-secret":      true,
+	"jwtsecret":       true,
+	"password":        true,
+	"default":         true,
+	"admin":           true,
+	"postgres":        true,
+	"testme":          true,
+}
+
+// IsProduction reports whether the service runs with the production profile
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// UnsafeSettings returns a description of every obviously unsafe value in the
+// configuration. It is checked at startup and whenever configuration is reloaded.
+func (c *Config) UnsafeSettings() []string {
+	var problems []string
+
+	problems = append(problems, unsafeJWTSecret("JWT_SECRET", c.JwtSecret)...)
+	for i, secret := range c.PreviousJwtSecrets {
+		// A weak previous secret still lets anyone forge tokens
+		problems = append(problems, unsafeJWTSecret(fmt.Sprintf("JWT_SECRETS entry %d", i+2), secret)...)
+	}
+
+	if c.AdminAPIToken != "" && (isDefaultSecret(c.AdminAPIToken) || len(c.AdminAPIToken) < minProductionSecretLength) {
+		problems = append(problems, fmt.Sprintf("ADMIN_API_TOKEN must be a random value of at least %d characters", minProductionSecretLength))
+	}
+
+	for _, e := range c.Webhooks {
+		if c.IsProduction() && !strings.HasPrefix(e.URL, "https://") {
+			problems = append(problems, fmt.Sprintf("WEBHOOKS endpoint %s must use https", e.URL))
+		}
+	}
+
+	if c.IsProduction() && c.EmailSender == "log" {
+		problems = append(problems, "EMAIL_SENDER=log writes emails to the log and is for development only")
+	}
+	if c.IsProduction() && c.EmailSender == "smtp" && c.SMTPTLS == "none" {
+		problems = append(problems, "SMTP_TLS=none sends emails and SMTP credentials in plain text")
+	}
+
+	if c.IsProduction() && c.PasswordHash == "bcrypt" && c.BcryptCost < minProductionBcryptCost {
+		problems = append(problems, fmt.Sprintf("BCRYPT_COST must be at least %d", minProductionBcryptCost))
+	} else if c.IsProduction() && c.PasswordHash != "bcrypt" && c.Argon2.Memory != 0 && c.Argon2.Memory < minProductionArgon2Memory {
+		problems = append(problems, fmt.Sprintf("ARGON2_MEMORY must be at least %d KiB", minProductionArgon2Memory))
+	}
+
+	if c.IsProduction() && c.Storage == "memory" {
+		problems = append(problems, "STORAGE=memory loses every account on restart and is for development and tests only")
+	} else if c.IsProduction() {
+		problems = append(problems, unsafeDatabaseURL(c.DbURL)...)
+	}
+
+	return problems
+}
+
+// CheckSecrets fails when unsafe settings are found in production. Outside of
+// production the problems are returned so they can be logged as warnings.
+func (c *Config) CheckSecrets() ([]string, error) {
+	problems := c.UnsafeSettings()
+	if len(problems) > 0 && c.IsProduction() {
+		return problems, fmt.Errorf("%w: %s", ErrUnsafeConfig, strings.Join(problems, "; "))
+	}
+	return problems, nil
+}
+
+// unsafeJWTSecret checks an HMAC secret for tokens, named as in the messages
+func unsafeJWTSecret(name, secret string) []string {
+	if isDefaultSecret(secret) {
+		return []string{name + " is a well-known default value"}
+	} else if len(secret) < minProductionSecretLength {
+		return []string{fmt.Sprintf("%s must be at least %d characters", name, minProductionSecretLength)}
+	} else if bits := entropyBits(secret); bits < minSecretEntropyBits {
+		return []string{fmt.Sprintf("%s is too predictable (about %.0f bits of entropy, want %d); generate one with openssl rand -base64 48", name, bits, minSecretEntropyBits)}
+	}
+	return nil
+}
+
+// unsafeDatabaseURL checks a postgres or mysql URL for missing or default
+// credentials and disabled TLS. SQLite is never safe in production.
+func unsafeDatabaseURL(dbURL string) []string {
+	if strings.HasPrefix(strings.ToLower(dbURL), "sqlite://") {
+		return []string{"DATABASE_URL selects SQLite, which is for development and tests only"}
+	}
+
+	u, err := url.Parse(dbURL)
+	if err != nil || u.User == nil {
+		return []string{"DATABASE_URL must include credentials"}
+	}
+
+	var problems []string
+	password, _ := u.User.Password()
+	if password == "" {
+		problems = append(problems, "DATABASE_URL has an empty password")
+	} else if isDefaultSecret(password) {
+		problems = append(problems, "DATABASE_URL uses a well-known default password")
+	}
+
+	if u.Query().Get("sslmode") == "disable" {
+		problems = append(problems, "DATABASE_URL disables TLS (sslmode=disable)")
+	}
+	if u.Query().Get("tls") == "false" {
+		problems = append(problems, "DATABASE_URL disables TLS (tls=false)")
+	}
+
+	return problems
+}
+
+// entropyBits estimates the entropy of a secret from how often each of its
+// characters occurs. Repeated or mostly repeated text scores low; random
+// base64 or hex of 32 characters or more scores above minSecretEntropyBits.
+func entropyBits(secret string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range secret {
+		counts[c]++
+		n++
+	}
+	var perChar float64
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * float64(n)
+}
+
+// Helper function to check a value against known placeholder secrets
+func isDefaultSecret(value string) bool {
+	return knownDefaultSecrets[strings.ToLower(strings.TrimSpace(value))]
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/webhook"
+)
+
+func TestCheckSecrets(t *testing.T) {
+	strongSecret := "vG4rX9qLm2Tz7WbKc1NpE8sYdHf3UaJ6"
+
+	tests := []struct {
+		name         string
+		cfg          Config
+		wantProblems int
+		wantErr      bool
+	}{
+		{
+			name:         "default secret in development only warns",
+			cfg:          Config{Environment: "development", JwtSecret: "test-secret", DbURL: "postgres://u@localhost/db"},
+			wantProblems: 1,
+			wantErr:      false,
+		},
+		{
+			name:         "default secret in production",
+			cfg:          Config{Environment: "production", JwtSecret: "changeme", DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "empty database password in production",
+			cfg:          Config{Environment: "production", JwtSecret: strongSecret, DbURL: "postgres://u@db/authdb?sslmode=require"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "short secret and disabled tls in production",
+			cfg:          Config{Environment: "production", JwtSecret: "short-but-unique", DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=disable"},
+			wantProblems: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "predictable secret in production",
+			cfg:          Config{Environment: "production", JwtSecret: strings.Repeat("k3y", 12), DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "short secret in development only warns",
+			cfg:          Config{Environment: "development", JwtSecret: "short-but-unique", DbURL: "postgres://u@localhost/db"},
+			wantProblems: 1,
+			wantErr:      false,
+		},
+		{
+			name:         "mysql with disabled tls in production",
+			cfg:          Config{Environment: "production", JwtSecret: strongSecret, DbURL: "mysql://u:" + strongSecret + "@db/authdb?tls=false"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "sqlite in production",
+			cfg:          Config{Environment: "production", JwtSecret: strongSecret, DbURL: "sqlite:///var/lib/auth.db"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "memory storage in production",
+			cfg:          Config{Environment: "production", JwtSecret: strongSecret, Storage: "memory"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name: "plain http webhook in production",
+			cfg: Config{Environment: "production", JwtSecret: strongSecret, DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require",
+				Webhooks: []webhook.Endpoint{{URL: "http://hooks.internal/auth", Secret: strongSecret}}},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name: "plain text smtp in production",
+			cfg: Config{Environment: "production", JwtSecret: strongSecret, DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require",
+				EmailSender: "smtp", SMTPTLS: "none"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name: "weak argon2 in production",
+			cfg: Config{Environment: "production", JwtSecret: strongSecret, DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require",
+				Argon2: password.Argon2Params{Memory: 4096, Time: 1, Parallelism: 1}},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name: "weak bcrypt in production",
+			cfg: Config{Environment: "production", JwtSecret: strongSecret, DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require",
+				PasswordHash: "bcrypt", BcryptCost: 8},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name: "weak previous secret in production",
+			cfg: Config{Environment: "production", JwtSecret: strongSecret, PreviousJwtSecrets: []string{"changeme"},
+				DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require"},
+			wantProblems: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "safe production config",
+			cfg:          Config{Environment: "production", JwtSecret: strongSecret, DbURL: "postgres://u:" + strongSecret + "@db/authdb?sslmode=require"},
+			wantProblems: 0,
+			wantErr:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := tt.cfg.CheckSecrets()
+
+			if len(problems) != tt.wantProblems {
+				t.Errorf("got problems %q, want %d", problems, tt.wantProblems)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsafeConfig) {
+				t.Errorf("got error %v, want ErrUnsafeConfig", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests per Window on average, with bursts of up to Burst
+// requests at once
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+}
+
+// RateLimits configures the rate limit tiers and per-route overrides. A zero
+// tier uses the service's built-in limit.
+type RateLimits struct {
+	Default RateLimit // every request per IP, and authenticated routes per user
+	Strict  RateLimit // sign-in, registration, SAML, OIDC, and break-glass
+	Admin   RateLimit // the admin API
+
+	// Routes overrides the limits for route patterns: an exact path or a prefix
+	// ending in "/*"; the longest matching pattern wins
+	Routes map[string]RateLimit
+}
+
+// DefaultRateLimits returns the built-in limits
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Default: RateLimit{Requests: 100, Window: time.Minute, Burst: 100},
+		Strict:  RateLimit{Requests: 10, Window: time.Minute, Burst: 10},
+		Admin:   RateLimit{Requests: 30, Window: time.Minute, Burst: 30},
+	}
+}
+
+// ParseRateLimit parses a limit in the form "10/1m", or "10/1m:20" for a burst
+// other than the request count
+func ParseRateLimit(value string) (RateLimit, error) {
+	spec, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+	requests, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, want requests/window such as 10/1m", value)
+	}
+
+	var limit RateLimit
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests < 1 {
+		return RateLimit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+	if limit.Window, err = time.ParseDuration(window); err != nil || limit.Window <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid window in rate limit %q", value)
+	}
+	limit.Burst = limit.Requests
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid burst in rate limit %q", value)
+		}
+	}
+	return limit, nil
+}
+
+// String formats the limit in the form ParseRateLimit accepts
+func (l RateLimit) String() string {
+	s := fmt.Sprintf("%d/%s", l.Requests, l.Window)
+	if l.Burst != l.Requests {
+		s += fmt.Sprintf(":%d", l.Burst)
+	}
+	return s
+}
+
+// ParseRateLimits merges tier overrides in the form "default=100/1m,strict=5/1m:10"
+// and route overrides in the form "/auth/login=5/1m,/health=1000/1m" over base
+func ParseRateLimits(base RateLimits, tiers, routes string) (RateLimits, error) {
+	limits := base
+	limits.Routes = make(map[string]RateLimit, len(base.Routes))
+	for pattern, limit := range base.Routes {
+		limits.Routes[pattern] = limit
+	}
+
+	err := parseLimitList(tiers, func(name string, limit RateLimit) error {
+		switch name {
+		case "default":
+			limits.Default = limit
+		case "strict":
+			limits.Strict = limit
+		case "admin":
+			limits.Admin = limit
+		default:
+			return fmt.Errorf("unknown rate limit tier %q", name)
+		}
+		return nil
+	})
+	if err != nil {
+		return RateLimits{}, err
+	}
+
+	err = parseLimitList(routes, func(pattern string, limit RateLimit) error {
+		if !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("invalid route pattern %q", pattern)
+		}
+		limits.Routes[pattern] = limit
+		return nil
+	})
+	if err != nil {
+		return RateLimits{}, err
+	}
+	return limits, nil
+}
+
+func parseLimitList(value string, set func(key string, limit RateLimit) error) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid rate limit entry %q", entry)
+		}
+		limit, err := ParseRateLimit(spec)
+		if err != nil {
+			return err
+		}
+		if err := set(strings.TrimSpace(key), limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits(DefaultRateLimits(), "strict=5/1m:20, admin=100/1h", "/auth/login=3/1m,/health=1000/1m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := RateLimits{
+		Default: RateLimit{Requests: 100, Window: time.Minute, Burst: 100},
+		Strict:  RateLimit{Requests: 5, Window: time.Minute, Burst: 20},
+		Admin:   RateLimit{Requests: 100, Window: time.Hour, Burst: 100},
+		Routes: map[string]RateLimit{
+			"/auth/login": {Requests: 3, Window: time.Minute, Burst: 3},
+			"/health":     {Requests: 1000, Window: time.Minute, Burst: 1000},
+		},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("got %+v, want %+v", limits, want)
+	}
+}
+
+func TestParseRateLimitsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		tiers  string
+		routes string
+	}{
+		{name: "unknown tier", tiers: "relaxed=5/1m"},
+		{name: "missing window", tiers: "strict=5"},
+		{name: "zero requests", tiers: "strict=0/1m"},
+		{name: "bad window", tiers: "strict=5/soon"},
+		{name: "bad burst", tiers: "strict=5/1m:x"},
+		{name: "route without slash", routes: "auth/login=5/1m"},
+		{name: "entry without limit", routes: "/auth/login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRateLimits(DefaultRateLimits(), tt.tiers, tt.routes); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AuthStrategy names a way a request can authenticate
+type AuthStrategy string
+
+// Supported authentication strategies
+const (
+	StrategyPublic AuthStrategy = "public"
+	StrategyJWT    AuthStrategy = "jwt"
+	StrategyAPIKey AuthStrategy = "api_key"
+	StrategyMTLS   AuthStrategy = "mtls"
+)
+
+// RoutePolicies maps route patterns to the strategies any one of which a request
+// must satisfy. A pattern is an exact path or a prefix ending in "/*"; the
+// longest matching pattern wins. Unmatched routes are public.
+type RoutePolicies map[string][]AuthStrategy
+
+// DefaultRoutePolicies protects the user-facing endpoints of the service. Admin,
+// OIDC, and SAML routes authenticate with their own credentials and stay public here.
+// API keys cannot manage API keys; those routes need a token with the api-keys scope.
+func DefaultRoutePolicies() RoutePolicies {
+	return RoutePolicies{
+		"/auth/logout":     {StrategyJWT},
+		"/auth/me":         {StrategyJWT, StrategyAPIKey},
+		"/auth/me/*":       {StrategyJWT, StrategyAPIKey},
+		"/auth/api-keys":   {StrategyJWT},
+		"/auth/api-keys/*": {StrategyJWT},
+	}
+}
+
+// Match returns the strategies required for path
+func (p RoutePolicies) Match(path string) []AuthStrategy {
+	if strategies, ok := p[path]; ok {
+		return strategies
+	}
+
+	var best string
+	for pattern := range p {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(path, prefix) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return []AuthStrategy{StrategyPublic}
+	}
+	return p[best]
+}
+
+// ParseRoutePolicies parses overrides in the form
+// "/path=jwt|api_key,/internal/*=mtls" and merges them over base
+func ParseRoutePolicies(base RoutePolicies, value string) (RoutePolicies, error) {
+	policies := make(RoutePolicies, len(base))
+	for pattern, strategies := range base {
+		policies[pattern] = strategies
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pattern, list, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid route policy %q", entry)
+		}
+
+		var strategies []AuthStrategy
+		for _, name := range strings.Split(list, "|") {
+			strategy := AuthStrategy(strings.TrimSpace(name))
+			switch strategy {
+			case StrategyPublic, StrategyJWT, StrategyAPIKey, StrategyMTLS:
+				strategies = append(strategies, strategy)
+			default:
+				return nil, fmt.Errorf("unknown auth strategy %q for route %s", name, pattern)
+			}
+		}
+		policies[pattern] = strategies
+	}
+
+	return policies, nil
+}
+
+// String formats the policies in the same form ParseRoutePolicies accepts
+func (p RoutePolicies) String() string {
+	patterns := make([]string, 0, len(p))
+	for pattern := range p {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	entries := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		names := make([]string, len(p[pattern]))
+		for j, strategy := range p[pattern] {
+			names[j] = string(strategy)
+		}
+		entries[i] = pattern + "=" + strings.Join(names, "|")
+	}
+	return strings.Join(entries, ",")
+}
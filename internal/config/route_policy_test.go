@@ -0,0 +1,41 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoutePolicies(t *testing.T) {
+	policies, err := ParseRoutePolicies(DefaultRoutePolicies(), "/internal/*=mtls, /auth/me/consents=jwt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want []AuthStrategy
+	}{
+		{path: "/auth/logout", want: []AuthStrategy{StrategyJWT}},
+		{path: "/auth/me/consents", want: []AuthStrategy{StrategyJWT}},
+		{path: "/auth/me/other", want: []AuthStrategy{StrategyJWT, StrategyAPIKey}},
+		{path: "/auth/api-keys/7", want: []AuthStrategy{StrategyJWT}},
+		{path: "/internal/metrics", want: []AuthStrategy{StrategyMTLS}},
+		{path: "/auth/login", want: []AuthStrategy{StrategyPublic}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := policies.Match(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoutePoliciesErrors(t *testing.T) {
+	for _, value := range []string{"/x=password", "no-slash=jwt", "/x"} {
+		if _, err := ParseRoutePolicies(nil, value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
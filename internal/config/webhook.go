@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/webhook"
+)
+
+// minWebhookSecretLength is the shortest webhook signing secret accepted
+const minWebhookSecretLength = 16
+
+// ParseWebhooks reads the webhook endpoints from a JSON array such as
+// [{"url":"https://hooks.example.com/auth","secret":"...","events":["user.login"]}].
+// An endpoint without events receives every event. Empty input configures none.
+func ParseWebhooks(value string) ([]webhook.Endpoint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var endpoints []webhook.Endpoint
+	if err := json.Unmarshal([]byte(value), &endpoints); err != nil {
+		return nil, fmt.Errorf("want a JSON array of endpoints: %v", err)
+	}
+	for _, e := range endpoints {
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint URL %q, want an http(s) URL", e.URL)
+		}
+		if len(e.Secret) < minWebhookSecretLength {
+			return nil, fmt.Errorf("the secret of %s must be at least %d characters", e.URL, minWebhookSecretLength)
+		}
+		for _, eventType := range e.Events {
+			if !slices.Contains(events.Types, eventType) {
+				return nil, fmt.Errorf("unknown event %q for %s", eventType, e.URL)
+			}
+		}
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,431 @@
+// Package migrate applies schema changes to a live database without blocking
+// login traffic. Changes follow the expand/contract pattern: expand migrations
+// only add (columns, indexes, unvalidated constraints) and are safe to run
+// while the previous release is serving; contract migrations remove what the
+// new release no longer reads and run once every instance is upgraded.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrPreflightFailed = errors.New("migration preflight failed")
+	ErrLocked          = errors.New("another migration is running")
+	ErrExpandPending   = errors.New("expand migrations must be applied before contract migrations")
+	ErrIrreversible    = errors.New("migration cannot be rolled back")
+	ErrNothingApplied  = errors.New("no migration has been applied")
+)
+
+// Phase is the half of an expand/contract change a migration belongs to
+type Phase string
+
+const (
+	Expand   Phase = "expand"
+	Contract Phase = "contract"
+)
+
+// Migration is a versioned list of steps applied together
+type Migration struct {
+	Version int
+	Name    string
+	Phase   Phase
+	Steps   []Step
+	Down    []Step // undo Steps on rollback; nil when the migration is irreversible
+}
+
+// Status reports whether a migration has been applied, and when
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// advisoryLockKey serialises migrators across instances
+const advisoryLockKey = 0x617574686d6967 // "authmig"
+
+// sqlstate for lock_timeout expiry
+const lockNotAvailable = "55P03"
+
+// Migrator applies migrations with a lock timeout, so a statement waiting
+// behind long transactions gives up instead of queueing every login behind it
+type Migrator struct {
+	pool           *pgxpool.Pool
+	lockTimeout    time.Duration
+	retries        int
+	retryBackoff   time.Duration
+	maxRewriteRows int64
+	logger         *slog.Logger
+}
+
+// Option configures a Migrator
+type Option func(*Migrator)
+
+// WithLockTimeout sets how long a statement waits for a table lock
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) { m.lockTimeout = d }
+}
+
+// WithRetries sets how often a step is retried after a lock timeout, with
+// linearly growing backoff
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(m *Migrator) {
+		m.retries = n
+		m.retryBackoff = backoff
+	}
+}
+
+// WithMaxRewriteRows sets the estimated row count above which the preflight
+// refuses steps that rewrite a table
+func WithMaxRewriteRows(n int64) Option {
+	return func(m *Migrator) { m.maxRewriteRows = n }
+}
+
+// WithLogger sets the progress logger, slog.Default() unless set
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Migrator) { m.logger = logger }
+}
+
+// New creates a Migrator with a 2s lock timeout, 5 retries and a 100k row rewrite limit
+func New(pool *pgxpool.Pool, opts ...Option) *Migrator {
+	m := &Migrator{
+		pool:           pool,
+		lockTimeout:    2 * time.Second,
+		retries:        5,
+		retryBackoff:   time.Second,
+		maxRewriteRows: 100000,
+		logger:         slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Pending returns the migrations of phase not yet applied, in version order
+func (m *Migrator) Pending(ctx context.Context, migrations []Migration, phase Phase) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pending(migrations, applied, phase), nil
+}
+
+// Preflight estimates the size of every table a pending migration of phase
+// would rewrite and fails with ErrPreflightFailed if any exceeds the limit
+func (m *Migrator) Preflight(ctx context.Context, migrations []Migration, phase Phase) error {
+	todo, err := m.Pending(ctx, migrations, phase)
+	if err != nil {
+		return err
+	}
+	rows := map[string]int64{}
+	for _, mig := range todo {
+		for _, step := range mig.Steps {
+			if !step.Rewrites || step.Table == "" {
+				continue
+			}
+			if _, ok := rows[step.Table]; ok {
+				continue
+			}
+			var n int64
+			err := m.pool.QueryRow(ctx,
+				"SELECT COALESCE((SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1)), 0)",
+				step.Table).Scan(&n)
+			if err != nil {
+				return fmt.Errorf("error estimating size of %s: %v", step.Table, err)
+			}
+			rows[step.Table] = n
+		}
+	}
+	if problems := checkRewrites(todo, rows, m.maxRewriteRows); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrPreflightFailed, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Apply runs the preflight and then every pending migration of phase,
+// returning the migrations applied. Contract migrations are refused while an
+// earlier expand migration is pending.
+func (m *Migrator) Apply(ctx context.Context, migrations []Migration, phase Phase) ([]Migration, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error acquiring connection: %v", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("error taking migration lock: %v", err)
+	}
+	if !locked {
+		return nil, ErrLocked
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+
+	if err := m.Preflight(ctx, migrations, phase); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo := pending(migrations, applied, phase)
+	if phase == Contract && len(todo) > 0 {
+		for _, mig := range pending(migrations, applied, Expand) {
+			if mig.Version < todo[len(todo)-1].Version {
+				return nil, fmt.Errorf("%w: %d %s", ErrExpandPending, mig.Version, mig.Name)
+			}
+		}
+	}
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET lock_timeout = %d", m.lockTimeout.Milliseconds())); err != nil {
+		return nil, fmt.Errorf("error setting lock timeout: %v", err)
+	}
+	defer conn.Exec(context.Background(), "RESET lock_timeout")
+
+	var done []Migration
+	for _, mig := range todo {
+		m.logger.Info("migrate: applying migration", "version", mig.Version, "name", mig.Name, "phase", mig.Phase)
+		for i, step := range mig.Steps {
+			if err := m.runStep(ctx, conn, step); err != nil {
+				return done, fmt.Errorf("migration %d %s step %d: %w", mig.Version, mig.Name, i+1, err)
+			}
+		}
+		if _, err := conn.Exec(ctx,
+			"INSERT INTO schema_migrations (version, name, phase) VALUES ($1, $2, $3) ON CONFLICT (version) DO NOTHING",
+			mig.Version, mig.Name, string(mig.Phase)); err != nil {
+			return done, fmt.Errorf("error recording migration %d: %v", mig.Version, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Status returns every migration in version order with the time it was applied
+func (m *Migrator) Status(ctx context.Context, migrations []Migration) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.pool.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[v] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, mig := range migrations {
+		statuses[i] = Status{Migration: mig}
+		if at, ok := appliedAt[mig.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Latest returns the applied migration with the highest version, which is
+// the one Rollback undoes
+func (m *Migrator) Latest(ctx context.Context, migrations []Migration) (Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return Migration{}, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	return latest(migrations, applied)
+}
+
+// Rollback runs the Down steps of the latest applied migration and forgets
+// it, returning the migration rolled back. Roll back only once no running
+// release reads what the migration added.
+func (m *Migrator) Rollback(ctx context.Context, migrations []Migration) (Migration, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return Migration{}, fmt.Errorf("error acquiring connection: %v", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&locked); err != nil {
+		return Migration{}, fmt.Errorf("error taking migration lock: %v", err)
+	}
+	if !locked {
+		return Migration{}, ErrLocked
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+
+	mig, err := m.Latest(ctx, migrations)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET lock_timeout = %d", m.lockTimeout.Milliseconds())); err != nil {
+		return Migration{}, fmt.Errorf("error setting lock timeout: %v", err)
+	}
+	defer conn.Exec(context.Background(), "RESET lock_timeout")
+
+	m.logger.Info("migrate: rolling back migration", "version", mig.Version, "name", mig.Name, "phase", mig.Phase)
+	for i, step := range mig.Down {
+		if err := m.runStep(ctx, conn, step); err != nil {
+			return Migration{}, fmt.Errorf("rollback of migration %d %s step %d: %w", mig.Version, mig.Name, i+1, err)
+		}
+	}
+	if _, err := conn.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version); err != nil {
+		return Migration{}, fmt.Errorf("error forgetting migration %d: %v", mig.Version, err)
+	}
+	return mig, nil
+}
+
+func (m *Migrator) runStep(ctx context.Context, conn *pgxpool.Conn, step Step) error {
+	if step.Skip != "" {
+		var skip bool
+		if err := conn.QueryRow(ctx, step.Skip).Scan(&skip); err != nil {
+			return err
+		}
+		if skip {
+			return nil
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := execStep(ctx, conn, step)
+		if err == nil || !isLockTimeout(err) || attempt > m.retries {
+			return err
+		}
+		m.logger.Warn("migrate: lock timeout, retrying", "table", step.Table, "attempt", attempt, "retries", m.retries)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * m.retryBackoff):
+		}
+	}
+}
+
+func execStep(ctx context.Context, conn *pgxpool.Conn, step Step) error {
+	switch {
+	case step.Batched:
+		for {
+			tag, err := conn.Exec(ctx, step.SQL)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return nil
+			}
+		}
+	case step.NoTx:
+		_, err := conn.Exec(ctx, step.SQL)
+		return err
+	default:
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+		if _, err := tx.Exec(ctx, step.SQL); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		phase VARCHAR(16) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]bool, error) {
+	rows, err := m.pool.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	applied := map[int]bool{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+func pending(migrations []Migration, applied map[int]bool, phase Phase) []Migration {
+	var todo []Migration
+	for _, mig := range migrations {
+		if mig.Phase == phase && !applied[mig.Version] {
+			todo = append(todo, mig)
+		}
+	}
+	sort.Slice(todo, func(i, j int) bool { return todo[i].Version < todo[j].Version })
+	return todo
+}
+
+// latest returns the applied migration with the highest version, failing
+// when it cannot be rolled back
+func latest(migrations []Migration, applied map[int]bool) (Migration, error) {
+	var last *Migration
+	for i, mig := range migrations {
+		if applied[mig.Version] && (last == nil || mig.Version > last.Version) {
+			last = &migrations[i]
+		}
+	}
+	if last == nil {
+		return Migration{}, ErrNothingApplied
+	}
+	if last.Down == nil {
+		return Migration{}, fmt.Errorf("%w: %d %s", ErrIrreversible, last.Version, last.Name)
+	}
+	return *last, nil
+}
+
+// checkRewrites lists the steps that would rewrite a table estimated above max rows
+func checkRewrites(migrations []Migration, rows map[string]int64, max int64) []string {
+	var problems []string
+	for _, mig := range migrations {
+		for i, step := range mig.Steps {
+			if step.Rewrites && rows[step.Table] > max {
+				problems = append(problems, fmt.Sprintf("migration %d step %d rewrites %s (~%d rows, limit %d)",
+					mig.Version, i+1, step.Table, rows[step.Table], max))
+			}
+		}
+	}
+	return problems
+}
+
+func isLockTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == lockNotAvailable
+}
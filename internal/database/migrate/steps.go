@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Step is one statement of a migration. Steps must be idempotent: a migration
+// that fails part way is retried from its first step.
+type Step struct {
+	SQL string
+
+	// Table the statement locks; the preflight checks its size when Rewrites is set
+	Table string
+
+	// Rewrites marks statements that rewrite or scan the table while holding a
+	// lock that blocks reads or writes
+	Rewrites bool
+
+	// NoTx runs the statement outside a transaction, as CREATE INDEX
+	// CONCURRENTLY requires
+	NoTx bool
+
+	// Batched statements run repeatedly, each batch in its own transaction,
+	// until they affect no rows
+	Batched bool
+
+	// Skip is an optional query returning true when the step is already applied,
+	// so reruns do not even take the table lock
+	Skip string
+}
+
+// Steps flattens the steps returned by the helpers into one list
+func Steps(groups ...[]Step) []Step {
+	var steps []Step
+	for _, group := range groups {
+		steps = append(steps, group...)
+	}
+	return steps
+}
+
+// Exec runs a statement that does not touch existing tables, such as CREATE
+// TABLE IF NOT EXISTS
+func Exec(sql string) []Step {
+	return []Step{{SQL: sql}}
+}
+
+// Locking runs an arbitrary statement against table. It is assumed to rewrite
+// the table under a blocking lock, so the preflight refuses it on large tables.
+func Locking(table, sql string) []Step {
+	return []Step{{SQL: sql, Table: table, Rewrites: true}}
+}
+
+// AddColumn adds a nullable column, or one with a constant default, which
+// PostgreSQL 11+ does without rewriting the table. Volatile defaults such as
+// now() rewrite the table; add the column without one and Backfill instead.
+func AddColumn(table, column, definition string) []Step {
+	return []Step{{
+		SQL:   fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, definition),
+		Table: table,
+		Skip: fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = '%s' AND column_name = '%s')`, table, column),
+	}}
+}
+
+// DropColumn removes a column the running code no longer reads. Use it only in
+// a contract migration.
+func DropColumn(table, column string) []Step {
+	return []Step{{
+		SQL:   fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", table, column),
+		Table: table,
+		Skip: fmt.Sprintf(`SELECT NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = '%s' AND column_name = '%s')`, table, column),
+	}}
+}
+
+// CreateIndex builds an index without blocking writes. An invalid index left by
+// an interrupted concurrent build is dropped and rebuilt.
+func CreateIndex(name, table string, columns ...string) []Step {
+	return createIndex("INDEX", name, table, strings.Join(columns, ", "), "")
+}
+
+// CreateUniqueIndex builds a unique index like CreateIndex. The build fails,
+// leaving nothing behind, if existing rows are not unique.
+func CreateUniqueIndex(name, table string, columns ...string) []Step {
+	return createIndex("UNIQUE INDEX", name, table, strings.Join(columns, ", "), "")
+}
+
+// CreatePartialIndex builds an index over the rows matching where without blocking writes
+func CreatePartialIndex(name, table, where string, columns ...string) []Step {
+	return createIndex("INDEX", name, table, strings.Join(columns, ", "), " WHERE "+where)
+}
+
+func createIndex(kind, name, table, columns, where string) []Step {
+	valid := fmt.Sprintf("SELECT COALESCE((SELECT indisvalid FROM pg_index WHERE indexrelid = to_regclass('%s')), false)", name)
+	return []Step{
+		{
+			SQL:   fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", name),
+			Table: table,
+			NoTx:  true,
+			Skip:  fmt.Sprintf("SELECT to_regclass('%s') IS NULL OR (%s)", name, valid),
+		},
+		{
+			SQL:   fmt.Sprintf("CREATE %s CONCURRENTLY IF NOT EXISTS %s ON %s (%s)%s", kind, name, table, columns, where),
+			Table: table,
+			NoTx:  true,
+			Skip:  valid,
+		},
+	}
+}
+
+// DropIndex removes an index without blocking writes
+func DropIndex(name, table string) []Step {
+	return []Step{{
+		SQL:   fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", name),
+		Table: table,
+		NoTx:  true,
+	}}
+}
+
+// AddForeignKey adds a foreign key without blocking writes while existing rows
+// are checked: the constraint is added NOT VALID and validated separately.
+func AddForeignKey(table, name, column, refTable, refColumn, onDelete string) []Step {
+	definition := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", column, refTable, refColumn)
+	if onDelete != "" {
+		definition += " ON DELETE " + onDelete
+	}
+	return addConstraint(table, name, definition)
+}
+
+// SetNotNull makes a column NOT NULL without a blocking table scan, through a
+// validated CHECK constraint that PostgreSQL 12+ uses as proof. Backfill the
+// column first.
+func SetNotNull(table, column string) []Step {
+	check := table + "_" + column + "_not_null"
+	return Steps(
+		addConstraint(table, check, fmt.Sprintf("CHECK (%s IS NOT NULL)", column)),
+		[]Step{
+			{SQL: fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column), Table: table},
+			{SQL: fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", table, check), Table: table},
+		},
+	)
+}
+
+func addConstraint(table, name, definition string) []Step {
+	exists := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%s' AND conrelid = to_regclass('%s'))", name, table)
+	return []Step{
+		{
+			SQL:   fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s NOT VALID", table, name, definition),
+			Table: table,
+			Skip:  exists,
+		},
+		{
+			// Validation only takes a SHARE UPDATE EXCLUSIVE lock, so reads and writes continue
+			SQL:   fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, name),
+			Table: table,
+			Skip:  fmt.Sprintf("SELECT COALESCE((SELECT convalidated FROM pg_constraint WHERE conname = '%s' AND conrelid = to_regclass('%s')), false)", name, table),
+		},
+	}
+}
+
+// Backfill sets columns on the rows matching where, batchSize rows per
+// transaction, so row locks are held only briefly. where must stop matching
+// rows once they are updated.
+func Backfill(table, set, where string, batchSize int) []Step {
+	return []Step{{
+		SQL: fmt.Sprintf(`UPDATE %s SET %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s LIMIT %d)`,
+			table, set, table, where, batchSize),
+		Table:   table,
+		Batched: true,
+	}}
+}
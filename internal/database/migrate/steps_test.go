@@ -0,0 +1,118 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCreateIndexIsConcurrent(t *testing.T) {
+	steps := CreatePartialIndex("idx_users_type", "users", "type <> 'human'", "type")
+	if len(steps) != 2 {
+		t.Fatalf("expected drop and create steps, got %d", len(steps))
+	}
+	for _, step := range steps {
+		if !step.NoTx || !strings.Contains(step.SQL, "CONCURRENTLY") || step.Skip == "" {
+			t.Errorf("step must run concurrently outside a transaction with a skip check: %+v", step)
+		}
+	}
+	if want := "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_users_type ON users (type) WHERE type <> 'human'"; steps[1].SQL != want {
+		t.Errorf("got %q, want %q", steps[1].SQL, want)
+	}
+
+	steps = CreateUniqueIndex("idx_users_canonical_email", "users", "canonical_email")
+	if want := "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_users_canonical_email ON users (canonical_email)"; steps[1].SQL != want {
+		t.Errorf("got %q, want %q", steps[1].SQL, want)
+	}
+}
+
+func TestForeignKeyValidatedSeparately(t *testing.T) {
+	steps := AddForeignKey("users", "users_tenant_id_fkey", "tenant_id", "tenants", "id", "CASCADE")
+	if len(steps) != 2 {
+		t.Fatalf("expected add and validate steps, got %d", len(steps))
+	}
+	if !strings.HasSuffix(steps[0].SQL, "ON DELETE CASCADE NOT VALID") {
+		t.Errorf("constraint must be added NOT VALID: %q", steps[0].SQL)
+	}
+	if !strings.Contains(steps[1].SQL, "VALIDATE CONSTRAINT users_tenant_id_fkey") {
+		t.Errorf("constraint must be validated: %q", steps[1].SQL)
+	}
+}
+
+func TestSetNotNullUsesCheckConstraint(t *testing.T) {
+	steps := SetNotNull("users", "tenant_id")
+	var sql []string
+	for _, step := range steps {
+		if step.Rewrites {
+			t.Errorf("step must not rewrite the table: %q", step.SQL)
+		}
+		sql = append(sql, step.SQL)
+	}
+	got := strings.Join(sql, "\n")
+	for _, want := range []string{"CHECK (tenant_id IS NOT NULL) NOT VALID", "VALIDATE CONSTRAINT", "SET NOT NULL", "DROP CONSTRAINT"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestBackfillIsBatched(t *testing.T) {
+	steps := Backfill("users", "role = 'user'", "role IS NULL", 500)
+	if !steps[0].Batched || !strings.Contains(steps[0].SQL, "LIMIT 500") {
+		t.Errorf("backfill must be batched: %+v", steps[0])
+	}
+}
+
+func TestCheckRewrites(t *testing.T) {
+	migrations := []Migration{{
+		Version: 7,
+		Name:    "users_email_type",
+		Phase:   Expand,
+		Steps: Steps(
+			AddColumn("users", "locale", "VARCHAR(8)"),
+			Locking("users", "ALTER TABLE users ALTER COLUMN email TYPE TEXT"),
+			Locking("tenants", "ALTER TABLE tenants ALTER COLUMN slug TYPE TEXT"),
+		),
+	}}
+	rows := map[string]int64{"users": 2000000, "tenants": 40}
+
+	problems := checkRewrites(migrations, rows, 100000)
+	if len(problems) != 1 || !strings.Contains(problems[0], "step 2 rewrites users") {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	if problems := checkRewrites(migrations, rows, 5000000); len(problems) != 0 {
+		t.Errorf("expected no problems under the limit, got %v", problems)
+	}
+}
+
+func TestPendingOrdersByVersion(t *testing.T) {
+	migrations := []Migration{
+		{Version: 3, Phase: Expand},
+		{Version: 1, Phase: Expand},
+		{Version: 2, Phase: Contract},
+		{Version: 4, Phase: Expand},
+	}
+	got := pending(migrations, map[int]bool{4: true}, Expand)
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 3 {
+		t.Errorf("unexpected pending migrations: %+v", got)
+	}
+}
+
+func TestLatestRollsBackHighestApplied(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Down: Exec("SELECT 1")},
+		{Version: 3, Down: Exec("SELECT 3")},
+		{Version: 2},
+	}
+
+	got, err := latest(migrations, map[int]bool{1: true, 3: true})
+	if err != nil || got.Version != 3 {
+		t.Errorf("got version %d (%v), want 3", got.Version, err)
+	}
+	if _, err := latest(migrations, map[int]bool{1: true, 2: true}); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("got error %v, want %v", err, ErrIrreversible)
+	}
+	if _, err := latest(migrations, map[int]bool{}); err != ErrNothingApplied {
+		t.Errorf("got error %v, want %v", err, ErrNothingApplied)
+	}
+}
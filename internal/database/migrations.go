@@ -0,0 +1,287 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/database/migrate"
+)
+
+// Migrations upgrade an existing deployment to schema.sql without blocking the
+// users table: columns are added without rewrites, indexes are built
+// concurrently and foreign keys are validated separately. Fresh installs can
+// load schema.sql directly; applying these afterwards only records them.
+// Down steps drop what a migration added, so rolling back loses its data.
+var Migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "oauth_pkce_and_grants",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("oauth_clients", "is_public", "BOOLEAN NOT NULL DEFAULT false"),
+			migrate.AddColumn("oauth_authorization_codes", "code_challenge", "VARCHAR(128) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("oauth_authorization_codes", "code_challenge_method", "VARCHAR(10) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("oauth_clients", "grant_types", "TEXT[] NOT NULL DEFAULT '{authorization_code}'"),
+			migrate.AddColumn("oauth_clients", "scopes", "TEXT[] NOT NULL DEFAULT '{}'"),
+		),
+		Down: migrate.Steps(
+			migrate.DropColumn("oauth_clients", "scopes"),
+			migrate.DropColumn("oauth_clients", "grant_types"),
+			migrate.DropColumn("oauth_authorization_codes", "code_challenge_method"),
+			migrate.DropColumn("oauth_authorization_codes", "code_challenge"),
+			migrate.DropColumn("oauth_clients", "is_public"),
+		),
+	},
+	{
+		Version: 2,
+		Name:    "users_canary",
+		Phase:   migrate.Expand,
+		Steps:   migrate.AddColumn("users", "is_canary", "BOOLEAN NOT NULL DEFAULT false"),
+		Down:    migrate.DropColumn("users", "is_canary"),
+	},
+	{
+		Version: 3,
+		Name:    "users_type",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("users", "type", "VARCHAR(16) NOT NULL DEFAULT 'human'"),
+			migrate.CreatePartialIndex("idx_users_type", "users", "type <> 'human'", "type"),
+		),
+		Down: migrate.Steps(
+			migrate.DropIndex("idx_users_type", "users"),
+			migrate.DropColumn("users", "type"),
+		),
+	},
+	{
+		Version: 4,
+		Name:    "users_tenants",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.Exec(`CREATE TABLE IF NOT EXISTS tenants (
+				id SERIAL PRIMARY KEY,
+				slug VARCHAR(63) UNIQUE NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				status VARCHAR(16) NOT NULL DEFAULT 'active',
+				settings JSONB NOT NULL DEFAULT '{}',
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			)`),
+			migrate.AddColumn("users", "tenant_id", "INTEGER"),
+			migrate.AddForeignKey("users", "users_tenant_id_fkey", "tenant_id", "tenants", "id", "CASCADE"),
+			migrate.AddColumn("users", "role", "VARCHAR(32) NOT NULL DEFAULT 'user'"),
+			migrate.CreateIndex("idx_users_tenant_id", "users", "tenant_id"),
+		),
+		Down: migrate.Steps(
+			migrate.DropIndex("idx_users_tenant_id", "users"),
+			migrate.DropColumn("users", "role"),
+			migrate.DropColumn("users", "tenant_id"),
+			migrate.Exec("DROP TABLE IF EXISTS tenants"),
+		),
+	},
+	{
+		Version: 5,
+		Name:    "audit_events",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.Exec(`CREATE TABLE IF NOT EXISTS audit_events (
+				id BIGSERIAL PRIMARY KEY,
+				type VARCHAR(64) NOT NULL,
+				severity VARCHAR(16) NOT NULL,
+				actor_id BIGINT,
+				ip_address VARCHAR(45) NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				details JSONB,
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`),
+			migrate.CreateIndex("idx_audit_events_actor_id", "audit_events", "actor_id", "created_at"),
+			migrate.CreateIndex("idx_audit_events_type", "audit_events", "type", "created_at"),
+		),
+		Down: migrate.Exec("DROP TABLE IF EXISTS audit_events"),
+	},
+	{
+		Version: 6,
+		Name:    "users_disabled",
+		Phase:   migrate.Expand,
+		Steps:   migrate.AddColumn("users", "disabled_at", "TIMESTAMP WITH TIME ZONE"),
+		Down:    migrate.DropColumn("users", "disabled_at"),
+	},
+	{
+		Version: 7,
+		Name:    "users_search",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("users", "email_verified_at", "TIMESTAMP WITH TIME ZONE"),
+			migrate.CreateIndex("idx_users_email_prefix", "users", "email text_pattern_ops"),
+			migrate.CreateIndex("idx_users_created_at", "users", "created_at"),
+			migrate.CreatePartialIndex("idx_users_role", "users", "role <> 'user'", "role"),
+			migrate.CreatePartialIndex("idx_users_locked", "users", "is_active = false", "id"),
+		),
+		Down: migrate.Steps(
+			migrate.DropIndex("idx_users_locked", "users"),
+			migrate.DropIndex("idx_users_role", "users"),
+			migrate.DropIndex("idx_users_created_at", "users"),
+			migrate.DropIndex("idx_users_email_prefix", "users"),
+			migrate.DropColumn("users", "email_verified_at"),
+		),
+	},
+	{
+		Version: 8,
+		Name:    "users_soft_delete",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("users", "deleted_at", "TIMESTAMP WITH TIME ZONE"),
+			migrate.CreatePartialIndex("idx_users_deleted_at", "users", "deleted_at IS NOT NULL", "deleted_at"),
+		),
+		Down: migrate.Steps(
+			migrate.DropIndex("idx_users_deleted_at", "users"),
+			migrate.DropColumn("users", "deleted_at"),
+		),
+	},
+	{
+		Version: 9,
+		Name:    "webhook_deliveries",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.Exec(`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id BIGSERIAL PRIMARY KEY,
+				event_id VARCHAR(64) NOT NULL,
+				event_type VARCHAR(64) NOT NULL,
+				url TEXT NOT NULL,
+				attempt INTEGER NOT NULL,
+				status_code INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				delivered BOOLEAN NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`),
+			migrate.CreateIndex("idx_webhook_deliveries_event_id", "webhook_deliveries", "event_id"),
+		),
+		Down: migrate.Exec("DROP TABLE IF EXISTS webhook_deliveries"),
+	},
+	{
+		Version: 10,
+		Name:    "event_outbox",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.Exec(`CREATE TABLE IF NOT EXISTS event_outbox (
+				id BIGSERIAL PRIMARY KEY,
+				event_id VARCHAR(64) NOT NULL,
+				event_type VARCHAR(64) NOT NULL,
+				payload JSONB NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				published_at TIMESTAMP WITH TIME ZONE
+			)`),
+			migrate.CreatePartialIndex("idx_event_outbox_unpublished", "event_outbox", "published_at IS NULL", "id"),
+		),
+		Down: migrate.Exec("DROP TABLE IF EXISTS event_outbox"),
+	},
+	{
+		Version: 11,
+		Name:    "users_password_expiry",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("users", "password_changed_at", "TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP"),
+			migrate.AddColumn("users", "password_expires_at", "TIMESTAMP WITH TIME ZONE"),
+		),
+		Down: migrate.Steps(
+			migrate.DropColumn("users", "password_expires_at"),
+			migrate.DropColumn("users", "password_changed_at"),
+		),
+	},
+	{
+		Version: 12,
+		Name:    "sessions_device_fingerprint",
+		Phase:   migrate.Expand,
+		Steps:   migrate.AddColumn("sessions", "device_fingerprint", "VARCHAR(64) NOT NULL DEFAULT ''"),
+		Down:    migrate.DropColumn("sessions", "device_fingerprint"),
+	},
+	{
+		Version: 13,
+		Name:    "sessions_remember_me",
+		Phase:   migrate.Expand,
+		Steps:   migrate.AddColumn("sessions", "remember_me", "BOOLEAN NOT NULL DEFAULT false"),
+		Down:    migrate.DropColumn("sessions", "remember_me"),
+	},
+	{
+		Version: 14,
+		Name:    "users_metadata",
+		Phase:   migrate.Expand,
+		Steps:   migrate.AddColumn("users", "metadata", "JSONB NOT NULL DEFAULT '{}'"),
+		Down:    migrate.DropColumn("users", "metadata"),
+	},
+	{
+		Version: 15,
+		Name:    "users_profile",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("users", "display_name", "VARCHAR(100) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("users", "given_name", "VARCHAR(100) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("users", "family_name", "VARCHAR(100) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("users", "locale", "VARCHAR(35) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("users", "timezone", "VARCHAR(64) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("users", "avatar_url", "VARCHAR(2048) NOT NULL DEFAULT ''"),
+		),
+		Down: migrate.Steps(
+			migrate.DropColumn("users", "avatar_url"),
+			migrate.DropColumn("users", "timezone"),
+			migrate.DropColumn("users", "locale"),
+			migrate.DropColumn("users", "family_name"),
+			migrate.DropColumn("users", "given_name"),
+			migrate.DropColumn("users", "display_name"),
+		),
+	},
+	{
+		Version: 16,
+		Name:    "blocked_email_domains",
+		Phase:   migrate.Expand,
+		Steps: migrate.Exec(`CREATE TABLE IF NOT EXISTS blocked_email_domains (
+			domain VARCHAR(253) PRIMARY KEY,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`),
+		Down: migrate.Exec("DROP TABLE IF EXISTS blocked_email_domains"),
+	},
+	{
+		Version: 17,
+		Name:    "users_canonical_email",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("users", "canonical_email", "VARCHAR(255)"),
+			migrate.Backfill("users", "canonical_email = LOWER(TRIM(email))", "canonical_email IS NULL", 1000),
+			migrate.CreateUniqueIndex("idx_users_canonical_email", "users", "canonical_email"),
+		),
+		Down: migrate.Steps(
+			migrate.DropIndex("idx_users_canonical_email", "users"),
+			migrate.DropColumn("users", "canonical_email"),
+		),
+	},
+	{
+		// Users created by the previous release while 17 rolled out have no
+		// canonical email yet
+		Version: 18,
+		Name:    "users_canonical_email_not_null",
+		Phase:   migrate.Contract,
+		Steps: migrate.Steps(
+			migrate.Backfill("users", "canonical_email = LOWER(TRIM(email))", "canonical_email IS NULL", 1000),
+			migrate.SetNotNull("users", "canonical_email"),
+		),
+		Down: []migrate.Step{{SQL: "ALTER TABLE users ALTER COLUMN canonical_email DROP NOT NULL", Table: "users"}},
+	},
+	{
+		Version: 19,
+		Name:    "break_glass_sessions",
+		Phase:   migrate.Expand,
+		Steps: migrate.Steps(
+			migrate.AddColumn("break_glass_redemptions", "session_hash", "VARCHAR(64) NOT NULL DEFAULT ''"),
+			migrate.AddColumn("break_glass_redemptions", "session_expires_at", "TIMESTAMP WITH TIME ZONE"),
+		),
+		Down: migrate.Steps(
+			migrate.DropColumn("break_glass_redemptions", "session_expires_at"),
+			migrate.DropColumn("break_glass_redemptions", "session_hash"),
+		),
+	},
+}
+
+// Migrate applies the pending migrations of phase
+func (db *DB) Migrate(ctx context.Context, phase migrate.Phase, opts ...migrate.Option) ([]migrate.Migration, error) {
+	return migrate.New(db.Pool, opts...).Apply(ctx, Migrations, phase)
+}
@@ -3,8 +3,14 @@ package database
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sync"
+	"time"
 
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/Stewz00/go-auth-service/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DB represents a PostgreSQL database connection pool
@@ -12,36 +18,133 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
+// Option configures the connection pool created by New
+type Option func(*pgxpool.Config)
+
+// WithQueryTracing records every statement as a span of the request that ran it
+func WithQueryTracing() Option {
+	return func(c *pgxpool.Config) {
+		addTracer(c, queryTracer{tracer: tracing.Tracer()})
+	}
+}
+
+// WithSlowQueryLog logs a warning with the SQL of every statement or batch
+// that takes threshold or longer. Waiting for a free connection is not
+// included; the pool's wait metrics cover it.
+func WithSlowQueryLog(threshold time.Duration) Option {
+	return func(c *pgxpool.Config) {
+		addTracer(c, slowQueryLogger{threshold: threshold})
+	}
+}
+
+// Credentials are the user and password new connections log in with, for
+// credentials that rotate while the pool is open
+type Credentials struct {
+	mu       sync.RWMutex
+	user     string
+	password string
+}
+
+// Set replaces the credentials used by new connections
+func (c *Credentials) Set(user, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user, c.password = user, password
+}
+
+// WithCredentials logs new connections in with creds instead of the user and
+// password in the URL. Call Reconnect after changing them.
+func WithCredentials(creds *Credentials) Option {
+	return func(c *pgxpool.Config) {
+		c.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			creds.mu.RLock()
+			defer creds.mu.RUnlock()
+			cc.User, cc.Password = creds.user, creds.password
+			return nil
+		}
+	}
+}
+
+// addTracer adds t to the tracers already set on the pool's connections
+func addTracer(c *pgxpool.Config, t pgx.QueryTracer) {
+	if c.ConnConfig.Tracer == nil {
+		c.ConnConfig.Tracer = t
+		return
+	}
+	c.ConnConfig.Tracer = multitracer.New(c.ConnConfig.Tracer, t)
+}
+
 // New creates a new database connection pool using the provided connection URL
-// It implements connection pooling and handles reconnection automatically
-func New(dbURL string) (*DB, error) {
+// It implements connection pooling and handles reconnection automatically.
+//
+// The pool is tuned with pgxpool's URL parameters: pool_max_conns (default
+// 25), pool_min_conns (default 5), pool_min_idle_conns,
+// pool_max_conn_lifetime, pool_max_conn_lifetime_jitter,
+// pool_max_conn_idle_time and pool_health_check_period. Statements are
+// prepared once per connection and cached (statement_cache_capacity); behind
+// a transaction-mode PgBouncer set default_query_exec_mode=exec or
+// simple_protocol instead.
+func New(dbURL string, opts ...Option) (*DB, error) {
+	return NewContext(context.Background(), dbURL, opts...)
+}
+
+// NewContext is New with the initial ping bounded by ctx
+func NewContext(ctx context.Context, dbURL string, opts ...Option) (*DB, error) {
 	// Create a connection pool configuration
 	poolConfig, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing database URL: %v", err)
 	}
 
-	// Set some reasonable pool limits
-	poolConfig.MaxConns = 25
-	poolConfig.MinConns = 5
+	// Set some reasonable pool limits unless the URL sets its own
+	params := urlParams(dbURL)
+	if !params.Has("pool_max_conns") {
+		poolConfig.MaxConns = 25
+	}
+	if !params.Has("pool_min_conns") {
+		poolConfig.MinConns = 5
+	}
+	if !params.Has("default_query_exec_mode") {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	}
+	for _, opt := range opts {
+		opt(poolConfig)
+	}
 
 	// Create the connection pool
-	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %v", err)
 	}
 
 	// Verify the connection
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("unable to ping database: %v", err)
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, pingError(err)
 	}
 
 	return &DB{Pool: pool}, nil
 }
 
+// Reconnect closes every connection, so new ones pick up changed
+// credentials. Connections in use are closed when they are released.
+func (db *DB) Reconnect() {
+	db.Pool.Reset()
+}
+
 // Close closes the database connection pool
 func (db *DB) Close() {
 	if db.Pool != nil {
 		db.Pool.Close()
 	}
 }
+
+// urlParams returns the query parameters of a postgres:// URL, or none for a
+// keyword/value connection string
+func urlParams(dbURL string) url.Values {
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return url.Values{}
+	}
+	return u.Query()
+}
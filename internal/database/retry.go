@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrUnreachable is wrapped by the errors of New and NewMySQL when the server
+// did not answer, which Retry retries
+var ErrUnreachable = errors.New("database unreachable")
+
+// Backoff between attempts of Retry
+const (
+	retryInitialWait = 250 * time.Millisecond
+	retryMaxWait     = 5 * time.Second
+)
+
+// Retry calls connect until it succeeds, fails with an error other than
+// ErrUnreachable, or timeout has passed, doubling the wait between attempts
+// from 250ms up to 5s. This lets the service start before its database, as
+// container orchestrators often do. connect is passed a context ending with
+// the timeout, so an attempt against a server that never answers does not
+// outlast it. A timeout of 0 calls connect once, without a deadline.
+func Retry[T any](timeout time.Duration, connect func(ctx context.Context) (T, error)) (T, error) {
+	ctx := context.Background()
+	deadline := time.Now().Add(timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	wait := retryInitialWait
+	for {
+		conn, err := connect(ctx)
+		if err == nil || !errors.Is(err, ErrUnreachable) {
+			return conn, err
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return conn, err
+		}
+		slog.Warn("database unreachable, retrying", "err", err, "retry_in", wait)
+		time.Sleep(wait)
+		wait = min(2*wait, retryMaxWait)
+	}
+}
+
+// pingError describes a failed ping, wrapping ErrUnreachable unless the server
+// answered with an error that retrying will not fix, like a wrong password.
+// PostgreSQL refusing connections while it starts up (SQLSTATE 57P03) is
+// retried.
+func pingError(err error) error {
+	var pgErr *pgconn.PgError
+	var myErr *mysql.MySQLError
+	if (errors.As(err, &pgErr) && pgErr.Code != "57P03") || errors.As(err, &myErr) {
+		return fmt.Errorf("unable to ping database: %v", err)
+	}
+	return fmt.Errorf("%w: unable to ping database: %v", ErrUnreachable, err)
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetry(t *testing.T) {
+	errRefused := pingError(errors.New("connection refused"))
+	errAuth := pingError(&pgconn.PgError{Code: "28P01"})
+
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		failures []error // returned by the first attempts
+		attempts int
+		err      error
+	}{
+		{name: "reachable", timeout: time.Minute, attempts: 1},
+		{name: "comes up", timeout: time.Minute, failures: []error{errRefused, errRefused}, attempts: 3},
+		{name: "never up", timeout: 500 * time.Millisecond, failures: []error{errRefused, errRefused, errRefused, errRefused}, attempts: 2, err: ErrUnreachable},
+		{name: "no retries", failures: []error{errRefused}, attempts: 1, err: ErrUnreachable},
+		{name: "wrong password", timeout: time.Minute, failures: []error{errAuth}, attempts: 1, err: errAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			_, err := Retry(tt.timeout, func(ctx context.Context) (int, error) {
+				attempts++
+				if attempts <= len(tt.failures) {
+					return 0, tt.failures[attempts-1]
+				}
+				return 1, nil
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Retry returned %v, want %v", err, tt.err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("connect called %d times, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryBoundsAttempts(t *testing.T) {
+	start := time.Now()
+	_, err := Retry(300*time.Millisecond, func(ctx context.Context) (int, error) {
+		<-ctx.Done() // a server dropping packets
+		return 0, pingError(ctx.Err())
+	})
+	if !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Retry returned %v, want %v", err, ErrUnreachable)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Retry took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestPingError(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{err: errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"), retryable: true},
+		{err: fmt.Errorf("failed to connect: %w", &pgconn.PgError{Code: "57P03"}), retryable: true},
+		{err: fmt.Errorf("failed to connect: %w", &pgconn.PgError{Code: "28P01"})},
+		{err: &mysql.MySQLError{Number: 1045}},
+	}
+
+	for _, tt := range tests {
+		if got := errors.Is(pingError(tt.err), ErrUnreachable); got != tt.retryable {
+			t.Errorf("pingError(%v) retryable = %v, want %v", tt.err, got, tt.retryable)
+		}
+	}
+}
@@ -25,4 +25,230 @@ CREATE TABLE IF NOT EXISTS sessions (
     expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
     is_revoked BOOLEAN DEFAULT false,
     CONSTRAINT unique_active_session UNIQUE (user_id, token_id)
-);
\ No newline at end of file
+);
+
+-- Create identities table linking external login providers to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_user_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_provider_identity UNIQUE (provider, provider_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Create OAuth clients table for applications delegating login to this service
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(255) UNIQUE NOT NULL,
+    client_secret_hash VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create authorization codes table for the OpenID Connect code flow
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    nonce VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create consent receipts table; rows are append-only so history is preserved
+CREATE TABLE IF NOT EXISTS consent_receipts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    scope TEXT NOT NULL DEFAULT '',
+    version VARCHAR(50) NOT NULL DEFAULT '',
+    granted BOOLEAN NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_consent_receipts_user_id ON consent_receipts(user_id, created_at);
+
+-- PKCE support: public clients have no secret and codes may carry a challenge
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS code_challenge VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS code_challenge_method VARCHAR(10) NOT NULL DEFAULT '';
+
+-- Machine clients: which grants a client may use and which scopes it may request
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS grant_types TEXT[] NOT NULL DEFAULT '{authorization_code}';
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+
+-- Create API keys table; only SHA-256 hashes of the keys are stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+-- Break-glass credentials are single use; a row marks a credential as redeemed
+CREATE TABLE IF NOT EXISTS break_glass_redemptions (
+    credential_hash VARCHAR(64) PRIMARY KEY,
+    ip_address VARCHAR(45) NOT NULL,
+    redeemed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Canary accounts are decoys; any sign-in attempt against them raises an alert
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_canary BOOLEAN NOT NULL DEFAULT false;
+
+-- Service accounts are non-human users that authenticate only with API keys
+ALTER TABLE users ADD COLUMN IF NOT EXISTS type VARCHAR(16) NOT NULL DEFAULT 'human';
+CREATE INDEX IF NOT EXISTS idx_users_type ON users(type) WHERE type <> 'human';
+
+-- Tenants group users under shared settings; deleting a tenant deletes its users
+CREATE TABLE IF NOT EXISTS tenants (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(63) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'user';
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
+
+-- Usage metering for billing, per month, tenant (0 for none), and OAuth client ('' for none)
+CREATE TABLE IF NOT EXISTS usage_counters (
+    period DATE NOT NULL,
+    tenant_id INTEGER NOT NULL DEFAULT 0,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    metric VARCHAR(32) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (period, tenant_id, client_id, metric)
+);
+
+-- A row per user active in a period, for monthly active user counts
+CREATE TABLE IF NOT EXISTS usage_active_users (
+    period DATE NOT NULL,
+    tenant_id INTEGER NOT NULL DEFAULT 0,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (period, tenant_id, client_id, user_id)
+);
+
+-- Security-relevant actions, append-only. actor_id has no foreign key so the
+-- trail outlives deleted users.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    severity VARCHAR(16) NOT NULL,
+    actor_id BIGINT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_type ON audit_events(type, created_at);
+
+-- Users disabled by an administrator cannot sign in until re-enabled
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+
+-- Emails vouched for by an identity provider are marked verified
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Indexes backing the admin user search
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users(email text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role) WHERE role <> 'user';
+CREATE INDEX IF NOT EXISTS idx_users_locked ON users(id) WHERE is_active = false;
+
+-- Soft-deleted users are hidden from sign-in and purged by the retention job
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Delivery log of outbound webhooks, one row per attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    delivered BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries(event_id);
+
+-- Events written in the same transaction as the change they describe, until
+-- the outbox relay publishes them
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_unpublished ON event_outbox(id) WHERE published_at IS NULL;
+
+-- Passwords older than PASSWORD_MAX_AGE, or past an expiry forced by an
+-- administrator, must be changed before the account can be used
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- The hash of the device fingerprint a client sent when signing in, which
+-- tokens of the session are bound to
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_fingerprint VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Sessions signed in with remember_me, which outlive a browser session
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT false;
+
+-- Free-form JSON for consuming applications: {"app_metadata": {...},
+-- "user_metadata": {...}}
+ALTER TABLE users ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+-- Optional profile details shown by consuming applications
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS given_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS family_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048) NOT NULL DEFAULT '';
+
+-- Email domains refused at registration, managed by admins at runtime
+CREATE TABLE IF NOT EXISTS blocked_email_domains (
+    domain VARCHAR(253) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The trimmed, lowercased email, so addresses differing only in case cannot
+-- register twice and sign-ins are case-insensitive
+ALTER TABLE users ADD COLUMN IF NOT EXISTS canonical_email VARCHAR(255);
+UPDATE users SET canonical_email = LOWER(TRIM(email)) WHERE canonical_email IS NULL;
+ALTER TABLE users ALTER COLUMN canonical_email SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_canonical_email ON users(canonical_email);
+
+-- The admin session a break-glass credential was redeemed for, so that every
+-- replica accepts it
+ALTER TABLE break_glass_redemptions ADD COLUMN IF NOT EXISTS session_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE break_glass_redemptions ADD COLUMN IF NOT EXISTS session_expires_at TIMESTAMP WITH TIME ZONE;
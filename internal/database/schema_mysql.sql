@@ -0,0 +1,215 @@
+-- Schema for MySQL 8.0.16+ and MariaDB 10.5+, equivalent to schema.sql.
+-- PostgreSQL arrays are stored as JSON lists, partial indexes are plain
+-- indexes, and times are DATETIME(6) in UTC.
+
+-- Tenants group users under shared settings; deleting a tenant deletes its users
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    settings JSON NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+-- Create users table with secure password storage and audit fields
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    canonical_email VARCHAR(255) NOT NULL UNIQUE, -- trimmed and lowercased email
+    password_hash VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    last_login DATETIME(6),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    is_canary BOOLEAN NOT NULL DEFAULT false,
+    type VARCHAR(16) NOT NULL DEFAULT 'human',
+    tenant_id BIGINT,
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+    disabled_at DATETIME(6),
+    email_verified_at DATETIME(6),
+    deleted_at DATETIME(6),
+    password_changed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    password_expires_at DATETIME(6),
+    metadata JSON, -- NULL until first set; JSON columns cannot default to a literal
+    display_name VARCHAR(100) NOT NULL DEFAULT '',
+    given_name VARCHAR(100) NOT NULL DEFAULT '',
+    family_name VARCHAR(100) NOT NULL DEFAULT '',
+    locale VARCHAR(35) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    avatar_url VARCHAR(2048) NOT NULL DEFAULT '',
+    CONSTRAINT users_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT email_format CHECK (
+        email REGEXP '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\\.[A-Za-z]{2,}$'
+    ),
+    INDEX idx_users_type (type),
+    INDEX idx_users_tenant_id (tenant_id),
+    INDEX idx_users_created_at (created_at),
+    INDEX idx_users_role (role),
+    INDEX idx_users_locked (is_active),
+    INDEX idx_users_deleted_at (deleted_at)
+);
+
+-- Create sessions table for tracking active JWT tokens
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT,
+    token_id VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    expires_at DATETIME(6) NOT NULL,
+    is_revoked BOOLEAN NOT NULL DEFAULT false,
+    device_fingerprint VARCHAR(64) NOT NULL DEFAULT '',
+    remember_me BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT unique_active_session UNIQUE (user_id, token_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_sessions_token_id (token_id)
+);
+
+-- Create identities table linking external login providers to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    provider_user_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT unique_provider_identity UNIQUE (provider, provider_user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create OAuth clients table for applications delegating login to this service
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL UNIQUE,
+    client_secret_hash VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris JSON NOT NULL,
+    is_public BOOLEAN NOT NULL DEFAULT false,
+    grant_types JSON NOT NULL,
+    scopes JSON NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+-- Create authorization codes table for the OpenID Connect code flow
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    user_id BIGINT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    nonce VARCHAR(255),
+    expires_at DATETIME(6) NOT NULL,
+    used_at DATETIME(6),
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',
+    code_challenge_method VARCHAR(10) NOT NULL DEFAULT '',
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create consent receipts table; rows are append-only so history is preserved
+CREATE TABLE IF NOT EXISTS consent_receipts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    purpose VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    scope TEXT NOT NULL,
+    version VARCHAR(50) NOT NULL DEFAULT '',
+    granted BOOLEAN NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_consent_receipts_user_id (user_id, created_at)
+);
+
+-- Create API keys table; only SHA-256 hashes of the keys are stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    revoked_at DATETIME(6),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Break-glass credentials are single use; a row marks a credential as redeemed
+CREATE TABLE IF NOT EXISTS break_glass_redemptions (
+    credential_hash VARCHAR(64) PRIMARY KEY,
+    ip_address VARCHAR(45) NOT NULL,
+    redeemed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    session_hash VARCHAR(64) NOT NULL DEFAULT '',
+    session_expires_at DATETIME(6)
+);
+
+-- Usage metering for billing, per month, tenant (0 for none), and OAuth client ('' for none)
+CREATE TABLE IF NOT EXISTS usage_counters (
+    `period` DATE NOT NULL,
+    tenant_id BIGINT NOT NULL DEFAULT 0,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    metric VARCHAR(32) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`period`, tenant_id, client_id, metric)
+);
+
+-- A row per user active in a period, for monthly active user counts
+CREATE TABLE IF NOT EXISTS usage_active_users (
+    `period` DATE NOT NULL,
+    tenant_id BIGINT NOT NULL DEFAULT 0,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    user_id BIGINT NOT NULL,
+    PRIMARY KEY (`period`, tenant_id, client_id, user_id)
+);
+
+-- Security-relevant actions, append-only. actor_id has no foreign key so the
+-- trail outlives deleted users.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    severity VARCHAR(16) NOT NULL,
+    actor_id BIGINT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL,
+    details JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_audit_events_actor_id (actor_id, created_at),
+    INDEX idx_audit_events_type (type, created_at)
+);
+
+-- Delivery log of outbound webhooks, one row per attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL,
+    delivered BOOLEAN NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_webhook_deliveries_event_id (event_id)
+);
+
+-- Events written in the same transaction as the change they describe, until
+-- the outbox relay publishes them
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    published_at DATETIME(6) NULL,
+    INDEX idx_event_outbox_published_at (published_at)
+);
+
+-- Email domains refused at registration, managed by admins at runtime
+CREATE TABLE IF NOT EXISTS blocked_email_domains (
+    domain VARCHAR(253) PRIMARY KEY,
+    reason TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
@@ -0,0 +1,213 @@
+-- Schema for SQLite 3.35+, equivalent to schema.sql, for local development
+-- and tests. PostgreSQL arrays are stored as TEXT lists and times are written
+-- in UTC by the application.
+
+-- Tenants group users under shared settings; deleting a tenant deletes its users
+CREATE TABLE IF NOT EXISTS tenants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    settings TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+-- Create users table with secure password storage and audit fields
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    canonical_email VARCHAR(255) NOT NULL UNIQUE, -- trimmed and lowercased email
+    password_hash VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    updated_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    last_login DATETIME,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    is_canary BOOLEAN NOT NULL DEFAULT false,
+    type VARCHAR(16) NOT NULL DEFAULT 'human',
+    tenant_id BIGINT,
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+    disabled_at DATETIME,
+    email_verified_at DATETIME,
+    deleted_at DATETIME,
+    password_changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    password_expires_at DATETIME,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    display_name VARCHAR(100) NOT NULL DEFAULT '',
+    given_name VARCHAR(100) NOT NULL DEFAULT '',
+    family_name VARCHAR(100) NOT NULL DEFAULT '',
+    locale VARCHAR(35) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    avatar_url VARCHAR(2048) NOT NULL DEFAULT '',
+    CONSTRAINT email_format CHECK (email LIKE '%_@_%._%'),
+    CONSTRAINT users_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_users_type ON users(type);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+CREATE INDEX IF NOT EXISTS idx_users_locked ON users(is_active);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
+
+-- Create sessions table for tracking active JWT tokens
+CREATE TABLE IF NOT EXISTS sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT,
+    token_id VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    expires_at DATETIME NOT NULL,
+    is_revoked BOOLEAN NOT NULL DEFAULT false,
+    device_fingerprint VARCHAR(64) NOT NULL DEFAULT '',
+    remember_me BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT unique_active_session UNIQUE (user_id, token_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_token_id ON sessions(token_id);
+
+-- Create identities table linking external login providers to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    provider_user_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    CONSTRAINT unique_provider_identity UNIQUE (provider, provider_user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create OAuth clients table for applications delegating login to this service
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    client_id VARCHAR(255) NOT NULL UNIQUE,
+    client_secret_hash VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT NOT NULL,
+    is_public BOOLEAN NOT NULL DEFAULT false,
+    grant_types TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+-- Create authorization codes table for the OpenID Connect code flow
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    user_id BIGINT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    nonce VARCHAR(255),
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',
+    code_challenge_method VARCHAR(10) NOT NULL DEFAULT '',
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create consent receipts table; rows are append-only so history is preserved
+CREATE TABLE IF NOT EXISTS consent_receipts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL,
+    purpose VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    scope TEXT NOT NULL,
+    version VARCHAR(50) NOT NULL DEFAULT '',
+    granted BOOLEAN NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_consent_receipts_user_id ON consent_receipts(user_id, created_at);
+
+-- Create API keys table; only SHA-256 hashes of the keys are stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    revoked_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Break-glass credentials are single use; a row marks a credential as redeemed
+CREATE TABLE IF NOT EXISTS break_glass_redemptions (
+    credential_hash VARCHAR(64) PRIMARY KEY,
+    ip_address VARCHAR(45) NOT NULL,
+    redeemed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    session_hash VARCHAR(64) NOT NULL DEFAULT '',
+    session_expires_at DATETIME
+);
+
+-- Usage metering for billing, per month, tenant (0 for none), and OAuth client ('' for none)
+CREATE TABLE IF NOT EXISTS usage_counters (
+    period DATE NOT NULL,
+    tenant_id BIGINT NOT NULL DEFAULT 0,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    metric VARCHAR(32) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (period, tenant_id, client_id, metric)
+);
+
+-- A row per user active in a period, for monthly active user counts
+CREATE TABLE IF NOT EXISTS usage_active_users (
+    period DATE NOT NULL,
+    tenant_id BIGINT NOT NULL DEFAULT 0,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    user_id BIGINT NOT NULL,
+    PRIMARY KEY (period, tenant_id, client_id, user_id)
+);
+
+-- Security-relevant actions, append-only. actor_id has no foreign key so the
+-- trail outlives deleted users.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type VARCHAR(64) NOT NULL,
+    severity VARCHAR(16) NOT NULL,
+    actor_id BIGINT,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL,
+    details TEXT,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_type ON audit_events(type, created_at);
+
+-- Delivery log of outbound webhooks, one row per attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    delivered BOOLEAN NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries(event_id);
+
+-- Events written in the same transaction as the change they describe, until
+-- the outbox relay publishes them
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    published_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_event_outbox_unpublished ON event_outbox(id) WHERE published_at IS NULL;
+
+-- Email domains refused at registration, managed by admins at runtime
+CREATE TABLE IF NOT EXISTS blocked_email_domains (
+    domain VARCHAR(253) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// slowQueryLogger logs the statements and batches that take longer than
+// threshold, with their SQL but never their arguments
+type slowQueryLogger struct {
+	threshold time.Duration
+}
+
+// Verify that slowQueryLogger implements the pgx tracer interfaces
+var (
+	_ pgx.QueryTracer = slowQueryLogger{}
+	_ pgx.BatchTracer = slowQueryLogger{}
+)
+
+// startedQuery is what TraceQueryEnd and TraceBatchEnd need from the start
+type startedQuery struct {
+	sql   string
+	start time.Time
+}
+
+type startedQueryKey struct{}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (l slowQueryLogger) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, startedQueryKey{}, startedQuery{sql: data.SQL, start: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (l slowQueryLogger) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	l.end(ctx, data.Err)
+}
+
+// TraceBatchStart implements pgx.BatchTracer. A batch is logged as a whole,
+// with its statements separated by semicolons.
+func (l slowQueryLogger) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	statements := make([]string, 0, data.Batch.Len())
+	for _, q := range data.Batch.QueuedQueries {
+		statements = append(statements, q.SQL)
+	}
+	return context.WithValue(ctx, startedQueryKey{}, startedQuery{sql: strings.Join(statements, "; "), start: time.Now()})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer
+func (l slowQueryLogger) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+}
+
+// TraceBatchEnd implements pgx.BatchTracer
+func (l slowQueryLogger) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	l.end(ctx, data.Err)
+}
+
+func (l slowQueryLogger) end(ctx context.Context, err error) {
+	started, ok := ctx.Value(startedQueryKey{}).(startedQuery)
+	if !ok {
+		return
+	}
+	took := time.Since(started.start)
+	if took < l.threshold {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", started.sql),
+		slog.Float64("latency_ms", float64(took.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+	slog.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+}
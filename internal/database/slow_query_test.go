@@ -0,0 +1,54 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestSlowQueryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	logger := slowQueryLogger{threshold: 10 * time.Millisecond}
+	ctx := context.Background()
+
+	// Fast statements are not logged
+	fast := logger.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1", Args: []any{"secret"}})
+	logger.TraceQueryEnd(fast, nil, pgx.TraceQueryEndData{})
+	if buf.Len() != 0 {
+		t.Fatalf("fast statement logged: %s", buf.String())
+	}
+
+	slow := logger.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep($1)", Args: []any{"secret"}})
+	time.Sleep(10 * time.Millisecond)
+	logger.TraceQueryEnd(slow, nil, pgx.TraceQueryEndData{Err: errors.New("canceled")})
+	got := buf.String()
+	for _, want := range []string{"slow query", "SELECT pg_sleep($1)", "latency_ms=", "err=canceled"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log %q does not contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("log %q contains a statement argument", got)
+	}
+
+	// Batches are logged as a whole
+	buf.Reset()
+	batch := &pgx.Batch{}
+	batch.Queue("UPDATE users SET last_login = now()")
+	batch.Queue("INSERT INTO sessions DEFAULT VALUES")
+	slow = logger.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: batch})
+	time.Sleep(10 * time.Millisecond)
+	logger.TraceBatchEnd(slow, nil, pgx.TraceBatchEndData{})
+	if want := "UPDATE users SET last_login = now(); INSERT INTO sessions DEFAULT VALUES"; !strings.Contains(buf.String(), want) {
+		t.Errorf("log %q does not contain %q", buf.String(), want)
+	}
+}
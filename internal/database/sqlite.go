@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DriverSQLite is selected by sqlite:// URLs, for local development and tests
+const DriverSQLite = "sqlite"
+
+//go:embed schema_sqlite.sql
+var sqliteSchema string
+
+// SQLite represents a SQLite database, for local development and tests
+type SQLite struct {
+	DB *sql.DB
+}
+
+// NewSQLite opens the database file of a sqlite://path URL, e.g.
+// sqlite://auth.db or sqlite:///var/lib/auth.db, creating the file and the
+// schema when missing. sqlite://:memory: opens a private in-memory database.
+func NewSQLite(dbURL string) (*SQLite, error) {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dbURL, "sqlite://"), "?")
+	if path == "" {
+		return nil, fmt.Errorf("error parsing database URL: missing file path")
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=1&_busy_timeout=5000&_loc=UTC")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing database URL: %v", err)
+	}
+	// SQLite allows one writer at a time, and every connection to :memory:
+	// would open a separate database
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(context.Background(), sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create schema: %v", err)
+	}
+
+	return &SQLite{DB: db}, nil
+}
+
+// Close closes the database
+func (db *SQLite) Close() {
+	if db.DB != nil {
+		db.DB.Close()
+	}
+}
@@ -0,0 +1,18 @@
+//go:build cgo
+
+package database
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// isSQLiteUniqueViolation reports whether err is a SQLite unique or primary key violation
+func isSQLiteUniqueViolation(err error) bool {
+	var liteErr sqlite3.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.ExtendedCode == sqlite3.ErrConstraintUnique || liteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build !cgo
+
+package database
+
+// isSQLiteUniqueViolation is always false without cgo, where the SQLite
+// driver cannot open databases
+func isSQLiteUniqueViolation(err error) bool {
+	return false
+}
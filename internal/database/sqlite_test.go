@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewSQLite(t *testing.T) {
+	if _, err := NewSQLite("sqlite://"); err == nil {
+		t.Error("expected an error for a URL without a file path")
+	}
+
+	db, err := NewSQLite("sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	insert := `INSERT INTO users (email, canonical_email, password_hash) VALUES ('dup@example.com', 'dup@example.com', 'hash')`
+	if _, err := db.DB.ExecContext(ctx, insert); err != nil {
+		t.Fatalf("schema not created: %v", err)
+	}
+	if _, err := db.DB.ExecContext(ctx, insert); !IsUniqueViolation(err) {
+		t.Errorf("duplicate email: got %v, want a unique violation", err)
+	}
+	if _, err := db.DB.ExecContext(ctx, `INSERT INTO sessions (user_id, token_id, expires_at) VALUES (999, 't', '2030-01-01')`); err == nil || IsUniqueViolation(err) {
+		t.Errorf("foreign keys must be enforced, got %v", err)
+	}
+}
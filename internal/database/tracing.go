@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer records each statement as a span of the context it ran in
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+// Verify that queryTracer implements the pgx tracer interfaces
+var (
+	_ pgx.QueryTracer = queryTracer{}
+	_ pgx.BatchTracer = queryTracer{}
+)
+
+// TraceQueryStart implements pgx.QueryTracer. Statement arguments are never
+// recorded, since they include password hashes and tokens.
+func (t queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "pgx.Query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBQueryText(data.SQL)))
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(ctx, data.Err)
+}
+
+// TraceBatchStart implements pgx.BatchTracer with one span for the batch
+func (t queryTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "pgx.Batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL))
+	return ctx
+}
+
+// TraceBatchQuery implements pgx.BatchTracer by adding each statement to the
+// batch's span as an event
+func (t queryTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	trace.SpanFromContext(ctx).AddEvent("pgx.Query", trace.WithAttributes(semconv.DBQueryText(data.SQL)))
+}
+
+// TraceBatchEnd implements pgx.BatchTracer
+func (t queryTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(ctx, data.Err)
+}
+
+func endSpan(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
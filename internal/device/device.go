@@ -0,0 +1,48 @@
+// Package device carries the fingerprint a client computes of its device, so
+// sessions can be bound to the device that signed in. Fingerprints are
+// opaque to the service and only their hashes are kept.
+package device
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Header is the request header clients send their fingerprint in
+const Header = "X-Device-Fingerprint"
+
+// Hash returns the hex-encoded SHA-256 hash of fingerprint, or "" for an
+// empty one
+func Hash(fingerprint string) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+	if fingerprint == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fingerprint))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// WithFingerprint returns a context carrying the hash of fingerprint
+func WithFingerprint(ctx context.Context, fingerprint string) context.Context {
+	return context.WithValue(ctx, contextKey{}, Hash(fingerprint))
+}
+
+// FromContext returns the hash stored by WithFingerprint or Middleware, or ""
+// when the client sent no fingerprint
+func FromContext(ctx context.Context) string {
+	hash, _ := ctx.Value(contextKey{}).(string)
+	return hash
+}
+
+// Middleware stores the hash of the request's fingerprint header in its
+// context, for FromContext
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithFingerprint(r.Context(), r.Header.Get(Header))))
+	})
+}
@@ -0,0 +1,51 @@
+package device
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	if got := Hash("  "); got != "" {
+		t.Errorf("Hash(blank) = %q, want empty", got)
+	}
+	if Hash("a") == Hash("b") {
+		t.Error("Hash() gave two fingerprints the same hash")
+	}
+	if got := Hash(" a "); got != Hash("a") || len(got) != 64 {
+		t.Errorf("Hash(\" a \") = %q, want the 64-digit hash of \"a\"", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "fingerprint", header: "device-1", want: Hash("device-1")},
+		{name: "none", header: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("FromContext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := FromContext(context.Background()); got != "" {
+		t.Errorf("FromContext(empty) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,136 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultQueueSize is used by NewAsyncSender for non-positive sizes
+const DefaultQueueSize = 1000
+
+// Temporary failures are retried up to maxSendAttempts times, waiting
+// retryBackoff, then twice as long, between attempts
+const (
+	maxSendAttempts = 3
+	retryBackoff    = time.Second
+)
+
+// AsyncSender moves sending off the request path, so a slow mail server never
+// delays sign-ins. Emails are queued in a bounded buffer and sent in order by
+// a background worker. Temporary failures (see Temporary) are retried a few
+// times; other failures are logged. When the queue is full, emails are
+// dropped and counted rather than blocking the request.
+type AsyncSender struct {
+	next    Sender
+	queue   chan queuedMessage
+	done    chan struct{}
+	backoff time.Duration
+
+	mu     sync.RWMutex // guards closed against sends on the closed queue
+	closed bool
+
+	dropped atomic.Int64
+}
+
+type queuedMessage struct {
+	ctx context.Context
+	msg Message
+}
+
+// Verify that AsyncSender implements Sender interface
+var _ Sender = (*AsyncSender)(nil)
+
+// NewAsyncSender starts a background worker sending through next
+func NewAsyncSender(next Sender, queueSize int) *AsyncSender {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	s := &AsyncSender{
+		next:    next,
+		queue:   make(chan queuedMessage, queueSize),
+		done:    make(chan struct{}),
+		backoff: retryBackoff,
+	}
+	go s.run()
+	return s
+}
+
+// Send queues the message without blocking. It only fails for messages that
+// could never be sent. The message keeps the values of ctx but not its
+// cancellation.
+func (s *AsyncSender) Send(ctx context.Context, msg Message) error {
+	if _, err := msg.recipient(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.drop()
+		return nil
+	}
+
+	select {
+	case s.queue <- queuedMessage{ctx: context.WithoutCancel(ctx), msg: msg}:
+	default:
+		s.drop()
+	}
+	return nil
+}
+
+// drop counts a message that could not be queued
+func (s *AsyncSender) drop() {
+	// Log the first drop and every hundredth after it to avoid flooding the log
+	if n := s.dropped.Add(1); n%100 == 1 {
+		slog.Warn("email: queue full, dropped email", "dropped", n)
+	}
+}
+
+// Dropped returns how many emails were discarded because the queue was full
+func (s *AsyncSender) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops accepting emails and waits until the queued ones are sent or
+// ctx is done
+func (s *AsyncSender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run sends queued emails until the queue is closed and drained
+func (s *AsyncSender) run() {
+	defer close(s.done)
+	for item := range s.queue {
+		if err := s.send(item); err != nil {
+			slog.ErrorContext(item.ctx, "email: failed to send", "subject", item.msg.Subject, "err", err)
+		}
+	}
+}
+
+// send sends a queued email, retrying temporary failures
+func (s *AsyncSender) send(item queuedMessage) error {
+	backoff := s.backoff
+	for attempt := 1; ; attempt++ {
+		err := s.next.Send(item.ctx, item.msg)
+		if err == nil || !Temporary(err) || attempt == maxSendAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
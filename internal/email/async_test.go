@@ -0,0 +1,82 @@
+package email
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySender fails with the queued errors before succeeding
+type flakySender struct {
+	mu       sync.Mutex
+	errs     []error
+	attempts int
+	sent     []time.Time
+}
+
+func (f *flakySender) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	f.sent = append(f.sent, time.Now())
+	return nil
+}
+
+func TestAsyncSenderRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantSent     int
+	}{
+		{name: "sent", wantAttempts: 1, wantSent: 1},
+		{name: "throttled then sent", errs: []error{ErrThrottled, ErrUnavailable}, wantAttempts: 3, wantSent: 1},
+		{name: "gives up", errs: []error{ErrUnavailable, ErrUnavailable, ErrUnavailable}, wantAttempts: 3},
+		{name: "rejected is not retried", errs: []error{ErrRejected}, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &flakySender{errs: tt.errs}
+			s := NewAsyncSender(next, 0)
+			s.backoff = time.Millisecond
+			if err := s.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hi", Text: "Hi"}); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := s.Close(ctx); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if next.attempts != tt.wantAttempts || len(next.sent) != tt.wantSent {
+				t.Errorf("attempts = %d, sent = %d, want %d and %d", next.attempts, len(next.sent), tt.wantAttempts, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestThrottledSender(t *testing.T) {
+	next := &flakySender{}
+	s := NewThrottledSender(next, 50) // one email every 20ms
+	for range 3 {
+		if err := s.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hi", Text: "Hi"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if gap := next.sent[2].Sub(next.sent[0]); gap < 35*time.Millisecond {
+		t.Errorf("three emails sent within %v, want them 20ms apart", gap)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.nextAt = time.Now().Add(time.Hour)
+	if err := s.Send(ctx, Message{To: "jane@example.com", Subject: "Hi", Text: "Hi"}); err != context.Canceled {
+		t.Errorf("Send() with a canceled context = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,23 @@
+package email
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+//go:embed disposable.txt
+var disposableDomains string
+
+// DisposableDomains returns the domains of well-known disposable email
+// services, lowercased
+var DisposableDomains = sync.OnceValue(func() map[string]bool {
+	domains := make(map[string]bool)
+	for line := range strings.Lines(disposableDomains) {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains[strings.ToLower(line)] = true
+		}
+	}
+	return domains
+})
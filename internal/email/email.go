@@ -0,0 +1,64 @@
+// Package email sends account emails, such as lockout notices and password
+// reset notifications, through a pluggable Sender
+package email
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// Errors returned for messages that cannot be sent
+var (
+	ErrInvalidRecipient = errors.New("email: invalid recipient address")
+	ErrInvalidSubject   = errors.New("email: subject must be a single line")
+)
+
+// Errors senders map their provider's failures to, wrapped with the
+// provider's message, so callers can tell them apart with errors.Is whatever
+// the provider. ErrThrottled and ErrUnavailable are temporary and worth
+// retrying; the others are not.
+var (
+	ErrRejected    = errors.New("email: message rejected")      // e.g. invalid or suppressed recipient, unverified sender
+	ErrNotAllowed  = errors.New("email: sending not allowed")   // e.g. invalid credentials, suspended account
+	ErrThrottled   = errors.New("email: sending rate exceeded") // retry later
+	ErrUnavailable = errors.New("email: provider unavailable")  // network failures and server errors; retry later
+)
+
+// Temporary reports whether sending failed for a reason that may pass, so
+// sending again later may succeed
+func Temporary(err error) bool {
+	return errors.Is(err, ErrThrottled) || errors.Is(err, ErrUnavailable)
+}
+
+// Message is an email to one recipient. Text is required; HTML is an optional
+// alternative for clients that display it.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// recipient validates the message and returns its parsed recipient. Header
+// values come from user input, so line breaks are refused rather than
+// allowed to inject headers.
+func (m Message) recipient() (*mail.Address, error) {
+	if strings.ContainsAny(m.To, "\r\n") {
+		return nil, ErrInvalidRecipient
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, ErrInvalidRecipient
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, ErrInvalidSubject
+	}
+	return to, nil
+}
@@ -0,0 +1,22 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogSender logs emails instead of sending them, for development and tests.
+// The log includes the body, so do not use it where the logs are shared.
+type LogSender struct{}
+
+// Verify that LogSender implements Sender interface
+var _ Sender = LogSender{}
+
+// Send logs the message
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	if _, err := msg.recipient(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "email: not sent, logged instead", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
@@ -0,0 +1,130 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// sendGridEndpoint is SendGrid's v3 Mail Send API
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender sends emails through the SendGrid v3 Mail Send API
+type SendGridSender struct {
+	apiKey   string
+	from     *mail.Address
+	endpoint string
+	client   *http.Client
+}
+
+// Verify that SendGridSender implements Sender interface
+var _ Sender = (*SendGridSender)(nil)
+
+// NewSendGridSender creates a sender authenticating with a SendGrid API key
+// that has the Mail Send permission. from must be a verified sender.
+func NewSendGridSender(apiKey, from string) (*SendGridSender, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("email: SendGrid API key is required")
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("email: invalid from address: %v", err)
+	}
+	return &SendGridSender{
+		apiKey:   apiKey,
+		from:     fromAddr,
+		endpoint: sendGridEndpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Send delivers the message. SendGrid accepts it for delivery; bounces are
+// only reported by its event webhook.
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	to, err := msg.recipient()
+	if err != nil {
+		return err
+	}
+	content := []sendGridContent{{Type: "text/plain", Value: msg.Text}}
+	if msg.HTML != "" {
+		content = append(content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+	body, err := json.Marshal(map[string]any{
+		"personalizations": []map[string]any{{"to": []sendGridAddress{{Email: to.Address, Name: to.Name}}}},
+		"from":             sendGridAddress{Email: s.from.Address, Name: s.from.Name},
+		"subject":          msg.Subject,
+		"content":          content,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: sendgrid: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	return fmt.Errorf("%w: sendgrid: %s: %s", sendGridError(resp.StatusCode), resp.Status, sendGridMessage(resp.Body))
+}
+
+// sendGridError maps a SendGrid status code to the senders' errors
+func sendGridError(status int) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrNotAllowed
+	case status == http.StatusTooManyRequests:
+		return ErrThrottled
+	case status >= 500:
+		return ErrUnavailable
+	default:
+		return ErrRejected
+	}
+}
+
+// sendGridMessage returns the messages of a SendGrid error response
+func sendGridMessage(body io.Reader) string {
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, 4096)).Decode(&result); err != nil || len(result.Errors) == 0 {
+		return "no details"
+	}
+	messages := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		if e.Field != "" {
+			messages = append(messages, e.Field+": "+e.Message)
+		} else {
+			messages = append(messages, e.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
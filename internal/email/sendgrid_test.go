@@ -0,0 +1,60 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendGridSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "invalid request", status: http.StatusBadRequest, body: `{"errors":[{"message":"The from address does not match a verified Sender Identity.","field":"from"}]}`, wantErr: ErrRejected},
+		{name: "invalid api key", status: http.StatusUnauthorized, body: `{"errors":[{"message":"The provided authorization grant is invalid, expired, or revoked"}]}`, wantErr: ErrNotAllowed},
+		{name: "missing permission", status: http.StatusForbidden, wantErr: ErrNotAllowed},
+		{name: "rate limited", status: http.StatusTooManyRequests, wantErr: ErrThrottled},
+		{name: "outage", status: http.StatusServiceUnavailable, wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer SG.key" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			sender, err := NewSendGridSender("SG.key", "Acme <no-reply@acme.example>")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sender.endpoint = server.URL
+			err = sender.Send(context.Background(), Message{To: "Jane <jane@example.com>", Subject: "Hi", Text: "Hello", HTML: "<p>Hello</p>"})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && Temporary(err) != (tt.wantErr == ErrThrottled || tt.wantErr == ErrUnavailable) {
+				t.Errorf("Temporary(%v) = %v", err, Temporary(err))
+			}
+
+			personalizations, _ := got["personalizations"].([]any)
+			content, _ := got["content"].([]any)
+			if got["subject"] != "Hi" || len(personalizations) != 1 || len(content) != 2 {
+				t.Errorf("request = %v", got)
+			}
+		})
+	}
+}
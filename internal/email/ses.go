@@ -0,0 +1,94 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/aws/smithy-go"
+)
+
+// sesAPI is the part of the SES v2 client SESSender uses
+type sesAPI interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+}
+
+// SESSender sends emails through the Amazon SES v2 API
+type SESSender struct {
+	client           sesAPI
+	from             string
+	configurationSet string
+}
+
+// Verify that SESSender implements Sender interface
+var _ Sender = (*SESSender)(nil)
+
+// NewSESSender creates a sender with the AWS SDK's default configuration:
+// credentials and region come from the environment (AWS_REGION,
+// AWS_ACCESS_KEY_ID, ...), shared config files, or the instance or task role.
+// from must be a verified identity; configurationSet, when set, names the SES
+// configuration set that tracks the emails.
+func NewSESSender(ctx context.Context, from, configurationSet string) (*SESSender, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("email: invalid from address: %v", err)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("email: failed to load AWS configuration: %v", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("email: AWS_REGION is required for SES")
+	}
+	return &SESSender{client: sesv2.NewFromConfig(cfg), from: from, configurationSet: configurationSet}, nil
+}
+
+// Send delivers the message
+func (s *SESSender) Send(ctx context.Context, msg Message) error {
+	to, err := msg.recipient()
+	if err != nil {
+		return err
+	}
+	body := &types.Body{Text: &types.Content{Data: aws.String(msg.Text), Charset: aws.String("UTF-8")}}
+	if msg.HTML != "" {
+		body.Html = &types.Content{Data: aws.String(msg.HTML), Charset: aws.String("UTF-8")}
+	}
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(s.from),
+		Destination:      &types.Destination{ToAddresses: []string{to.String()}},
+		Content: &types.EmailContent{Simple: &types.Message{
+			Subject: &types.Content{Data: aws.String(msg.Subject), Charset: aws.String("UTF-8")},
+			Body:    body,
+		}},
+	}
+	if s.configurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.configurationSet)
+	}
+	if _, err := s.client.SendEmail(ctx, input); err != nil {
+		return fmt.Errorf("%w: ses: %v", sesError(err), err)
+	}
+	return nil
+}
+
+// sesError maps an SES error to the senders' errors
+func sesError(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return ErrUnavailable
+	}
+	switch apiErr.ErrorCode() {
+	case "MessageRejected", "MailFromDomainNotVerifiedException", "BadRequestException", "NotFoundException":
+		return ErrRejected
+	case "AccountSuspendedException", "SendingPausedException", "AccessDeniedException",
+		"UnrecognizedClientException", "InvalidClientTokenId", "SignatureDoesNotMatch", "ExpiredTokenException":
+		return ErrNotAllowed
+	case "TooManyRequestsException", "LimitExceededException", "ThrottlingException":
+		return ErrThrottled
+	default:
+		return ErrUnavailable
+	}
+}
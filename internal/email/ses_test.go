@@ -0,0 +1,53 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/smithy-go"
+)
+
+// fakeSES records the emails sent through it, failing with err
+type fakeSES struct {
+	inputs []*sesv2.SendEmailInput
+	err    error
+}
+
+func (f *fakeSES) SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &sesv2.SendEmailOutput{}, f.err
+}
+
+func TestSESSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "sent"},
+		{name: "unverified identity", err: &smithy.GenericAPIError{Code: "MessageRejected", Message: "Email address is not verified."}, wantErr: ErrRejected},
+		{name: "sending paused", err: &smithy.GenericAPIError{Code: "SendingPausedException"}, wantErr: ErrNotAllowed},
+		{name: "invalid credentials", err: &smithy.GenericAPIError{Code: "UnrecognizedClientException"}, wantErr: ErrNotAllowed},
+		{name: "maximum send rate exceeded", err: &smithy.GenericAPIError{Code: "TooManyRequestsException"}, wantErr: ErrThrottled},
+		{name: "network failure", err: errors.New("dial tcp: i/o timeout"), wantErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSES{err: tt.err}
+			sender := &SESSender{client: client, from: "no-reply@acme.example", configurationSet: "auth"}
+			err := sender.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hi", Text: "Hello"})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			input := client.inputs[0]
+			if *input.FromEmailAddress != "no-reply@acme.example" || input.Destination.ToAddresses[0] != "<jane@example.com>" ||
+				*input.ConfigurationSetName != "auth" || input.Content.Simple.Body.Html != nil {
+				t.Errorf("input = %+v", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of SMTPConfig
+const (
+	TLSStartTLS = "starttls" // upgrade a plain connection; refuse servers that cannot
+	TLSImplicit = "tls"      // connect over TLS, usually to port 465
+	TLSNone     = "none"     // plain text, for local relays such as MailHog only
+)
+
+// ErrNoStartTLS is returned when the SMTP server does not offer STARTTLS
+var ErrNoStartTLS = errors.New("email: SMTP server does not support STARTTLS")
+
+// SMTPConfig configures an SMTPSender
+type SMTPConfig struct {
+	Host     string
+	Port     int    // default 587, or 465 with TLSImplicit
+	Username string // authenticates with PLAIN when set
+	Password string
+	From     string // sender address, e.g. "Acme <no-reply@acme.example>"
+	TLS      string // TLSStartTLS (default), TLSImplicit or TLSNone
+	Timeout  time.Duration
+}
+
+// SMTPSender sends emails through an SMTP server, one connection per email
+type SMTPSender struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+// Verify that SMTPSender implements Sender interface
+var _ Sender = (*SMTPSender)(nil)
+
+// NewSMTPSender creates a sender for the server of cfg
+func NewSMTPSender(cfg SMTPConfig) (*SMTPSender, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("email: SMTP host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("email: invalid from address: %v", err)
+	}
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = TLSStartTLS
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("email: SMTP TLS mode must be starttls, tls or none")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == TLSImplicit {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTPSender{cfg: cfg, from: from}, nil
+}
+
+// Send delivers the message, failing if the server does not accept it
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	to, err := msg.recipient()
+	if err != nil {
+		return err
+	}
+	body, err := s.build(to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	client, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: smtp: connection failed: %v", smtpError(err), err)
+	}
+	defer client.Close()
+
+	if s.cfg.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return ErrNoStartTLS
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("%w: smtp: STARTTLS failed: %v", smtpError(err), err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("%w: smtp: authentication failed: %v", smtpError(err), err)
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("%w: smtp: sender refused: %v", smtpError(err), err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("%w: smtp: recipient refused: %v", smtpError(err), err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("%w: smtp: DATA failed: %v", smtpError(err), err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("%w: smtp: DATA failed: %v", smtpError(err), err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%w: smtp: message refused: %v", smtpError(err), err)
+	}
+	// The message is accepted; a failed QUIT does not undo that
+	client.Quit()
+	return nil
+}
+
+// smtpError maps an SMTP failure to the senders' errors by its reply code:
+// 4xx replies are temporary, authentication failures are not allowed, and
+// other 5xx replies reject the message
+func smtpError(err error) error {
+	var reply *textproto.Error
+	if !errors.As(err, &reply) {
+		return ErrUnavailable
+	}
+	switch {
+	case reply.Code == 530 || reply.Code == 534 || reply.Code == 535:
+		return ErrNotAllowed
+	case reply.Code >= 500:
+		return ErrRejected
+	default:
+		return ErrUnavailable
+	}
+}
+
+// dial connects to the server, giving the whole session the deadline of ctx
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	var conn net.Conn
+	var err error
+	if s.cfg.TLS == TLSImplicit {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// build encodes the message as MIME, with a multipart/alternative body when
+// it has HTML
+func (s *SMTPSender) build(to *mail.Address, msg Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(s.from.Address))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{`text/plain; charset="utf-8"`, msg.Text},
+		{`text/html; charset="utf-8"`, msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID returns a unique Message-ID in the domain of the sender address
+func messageID(from string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	domain := from[strings.LastIndex(from, "@")+1:]
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
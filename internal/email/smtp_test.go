@@ -0,0 +1,189 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpServer is a minimal SMTP server accepting one message per connection.
+// It advertises AUTH but not STARTTLS, so tests use TLSNone.
+type smtpServer struct {
+	addr     string
+	received chan string // the DATA of each message
+	commands chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpServer{addr: ln.Addr().String(), received: make(chan string, 10), commands: make(chan string, 100)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		s.commands <- verb
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 Authentication successful")
+		case "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.received <- data.String()
+			reply("250 OK: queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) config(tlsMode string) SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.addr)
+	n, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: n, Username: "user", Password: "pass", From: "Acme <no-reply@acme.example>", TLS: tlsMode}
+}
+
+func TestSMTPSender(t *testing.T) {
+	server := newSMTPServer(t)
+	sender, err := NewSMTPSender(server.config(TLSNone))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		msg      Message
+		wantType string
+		wantErr  error
+	}{
+		{
+			name:     "text",
+			msg:      Message{To: "jane@example.com", Subject: "Your account has been locked", Text: "Hello,\nyour account was locked."},
+			wantType: "text/plain",
+		},
+		{
+			name:     "text and html",
+			msg:      Message{To: "Jane <jane@example.com>", Subject: "Bienvenue à bord", Text: "Hello", HTML: "<p>Hello</p>"},
+			wantType: "multipart/alternative",
+		},
+		{
+			name:    "header injection in recipient",
+			msg:     Message{To: "jane@example.com\r\nBcc: all@example.com", Subject: "Hi", Text: "Hi"},
+			wantErr: ErrInvalidRecipient,
+		},
+		{
+			name:    "header injection in subject",
+			msg:     Message{To: "jane@example.com", Subject: "Hi\r\nBcc: all@example.com", Text: "Hi"},
+			wantErr: ErrInvalidSubject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sender.Send(context.Background(), tt.msg)
+			if err != tt.wantErr {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(<-server.received))
+			if err != nil {
+				t.Fatalf("invalid message: %v", err)
+			}
+			var dec mime.WordDecoder
+			if subject, _ := dec.DecodeHeader(msg.Header.Get("Subject")); subject != tt.msg.Subject {
+				t.Errorf("Subject = %q, want %q", subject, tt.msg.Subject)
+			}
+			if from := msg.Header.Get("From"); from != `"Acme" <no-reply@acme.example>` {
+				t.Errorf("From = %q", from)
+			}
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil || mediaType != tt.wantType {
+				t.Fatalf("Content-Type = %q, want %s", msg.Header.Get("Content-Type"), tt.wantType)
+			}
+			if mediaType == "multipart/alternative" {
+				parts := multipart.NewReader(msg.Body, params["boundary"])
+				var bodies []string
+				for {
+					part, err := parts.NextPart()
+					if err != nil {
+						break
+					}
+					body, _ := io.ReadAll(part) // quoted-printable is decoded by NextPart
+					bodies = append(bodies, string(body))
+				}
+				if len(bodies) != 2 || bodies[0] != tt.msg.Text || bodies[1] != tt.msg.HTML {
+					t.Errorf("parts = %q, want the text and HTML bodies", bodies)
+				}
+			}
+		})
+	}
+}
+
+func TestSMTPSenderRequiresStartTLS(t *testing.T) {
+	server := newSMTPServer(t)
+	sender, err := NewSMTPSender(server.config(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sender.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hi", Text: "Hi"})
+	if err != ErrNoStartTLS {
+		t.Fatalf("Send() error = %v, want %v", err, ErrNoStartTLS)
+	}
+	close(server.commands)
+	for verb := range server.commands {
+		if verb == "AUTH" || verb == "MAIL" {
+			t.Errorf("sent %s over a plain connection", verb)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/i18n"
+)
+
+// Names of the account emails. Each is rendered from <name>.txt, which also
+// defines the "subject" template, and <name>.html, which defines the
+// "content" template placed in layout.html. Templates translate text with
+// {{t "English text" args...}} and format durations with {{duration .D}} in
+// the recipient's language, which {{lang}} returns.
+const (
+	TemplateVerification  = "verification"   // VerificationData
+	TemplatePasswordReset = "password_reset" // PasswordResetData
+	TemplateLockout       = "lockout"        // LockoutData
+	TemplateNewDevice     = "new_device"     // NewDeviceData
+)
+
+// templateNames lists every email LoadTemplates parses
+var templateNames = []string{TemplateVerification, TemplatePasswordReset, TemplateLockout, TemplateNewDevice}
+
+// ErrUnknownTemplate is returned when rendering an email that has no template
+var ErrUnknownTemplate = errors.New("email: unknown template")
+
+// VerificationData is rendered by TemplateVerification
+type VerificationData struct {
+	VerifyURL string
+	Expires   time.Duration
+}
+
+// PasswordResetData is rendered by TemplatePasswordReset. Without a ResetURL,
+// it tells the user an administrator reset their password.
+type PasswordResetData struct {
+	ResetURL string
+	Expires  time.Duration
+}
+
+// LockoutData is rendered by TemplateLockout
+type LockoutData struct {
+	FailedAttempts int64
+}
+
+// NewDeviceData is rendered by TemplateNewDevice
+type NewDeviceData struct {
+	Device    string
+	IPAddress string
+	Time      time.Time
+}
+
+//go:embed templates
+var embedded embed.FS
+
+// Templates renders account emails from the embedded templates, or from the
+// files of a deployment's templates directory that replace them
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// funcs are available in every template, bound to the recipient's language
+// when a message is rendered
+func funcs(l *i18n.Localizer) map[string]any {
+	return map[string]any{
+		"t":        l.Text,
+		"duration": func(d time.Duration) string { return humanDuration(l, d) },
+		"lang":     l.Language,
+	}
+}
+
+// LoadTemplates parses the email templates. Files in dir, when set, replace
+// the embedded files of the same name, so a deployment can brand its emails
+// by providing its own layout.html and any other templates it wants to
+// change. Every template is parsed up front, so mistakes fail at startup.
+func LoadTemplates(dir string) (*Templates, error) {
+	base, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	files := base
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("email: templates directory %q not found", dir)
+		}
+		files = overlayFS{top: os.DirFS(dir), base: base}
+	}
+
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template, len(templateNames)),
+		html: make(map[string]*htmltemplate.Template, len(templateNames)),
+	}
+	for _, name := range templateNames {
+		text, err := texttemplate.New(name+".txt").Funcs(funcs(nil)).ParseFS(files, name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("email: invalid %s template: %v", name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("email: %s.txt does not define a subject", name)
+		}
+		html, err := htmltemplate.New("layout.html").Funcs(funcs(nil)).ParseFS(files, "layout.html", name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("email: invalid %s template: %v", name, err)
+		}
+		t.text[name], t.html[name] = text, html
+	}
+	return t, nil
+}
+
+// DefaultTemplates returns the embedded templates
+var DefaultTemplates = sync.OnceValue(func() *Templates {
+	t, err := LoadTemplates("")
+	if err != nil {
+		panic(err)
+	}
+	return t
+})
+
+// Message renders the named email to a recipient in the language of l, or
+// in English when l is nil
+func (t *Templates) Message(l *i18n.Localizer, name, to string, data any) (Message, error) {
+	if t.text[name] == nil {
+		return Message{}, ErrUnknownTemplate
+	}
+	// The parsed templates are never executed, so they can be cloned for
+	// each language; an executed html/template cannot be
+	text, err := t.text[name].Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	html, err := t.html[name].Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	text.Funcs(funcs(l))
+	html.Funcs(funcs(l))
+
+	var subject, body, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+// overlayFS opens files from top, falling back to base
+type overlayFS struct {
+	top, base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.base.Open(name)
+	}
+	return f, err
+}
+
+// humanDuration formats a duration for people, e.g. "24 hours" or "30 minutes"
+func humanDuration(l *i18n.Localizer, d time.Duration) string {
+	unit, n := "minute", int64(d/time.Minute)
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		unit, n = "day", int64(d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		unit, n = "hour", int64(d/time.Hour)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return l.Text("%d "+unit, n)
+}
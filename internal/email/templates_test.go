@@ -0,0 +1,114 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/i18n"
+)
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        any
+		wantSubject string
+		wantText    string
+	}{
+		{TemplateVerification, VerificationData{VerifyURL: "https://auth.example.com/verify?t=abc", Expires: 24 * time.Hour}, "Confirm your email address", "expires in 24 hours"},
+		{TemplatePasswordReset, PasswordResetData{ResetURL: "https://auth.example.com/reset?t=abc", Expires: 30 * time.Minute}, "Reset your password", "expires in 30 minutes"},
+		{TemplatePasswordReset, PasswordResetData{}, "Your password has been reset", "An administrator reset your password"},
+		{TemplateLockout, LockoutData{FailedAttempts: 5}, "Your account has been locked", "after 5 failed sign-in attempts"},
+		{TemplateNewDevice, NewDeviceData{Device: "Firefox on Linux", IPAddress: "203.0.113.7", Time: time.Now()}, "New sign-in to your account", "IP address: 203.0.113.7"},
+	}
+
+	templates := DefaultTemplates()
+	for _, tt := range tests {
+		t.Run(tt.wantSubject, func(t *testing.T) {
+			msg, err := templates.Message(nil, tt.name, "jane@example.com", tt.data)
+			if err != nil {
+				t.Fatalf("Message() error = %v", err)
+			}
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.Text, tt.wantText) || strings.HasPrefix(msg.Text, "\n") {
+				t.Errorf("Text = %q, want it to contain %q", msg.Text, tt.wantText)
+			}
+			if !strings.Contains(msg.HTML, "<!DOCTYPE html>") {
+				t.Errorf("HTML is not placed in the layout: %q", msg.HTML)
+			}
+		})
+	}
+
+	if _, err := templates.Message(nil, "welcome", "jane@example.com", nil); err != ErrUnknownTemplate {
+		t.Errorf("Message(welcome) error = %v, want %v", err, ErrUnknownTemplate)
+	}
+}
+
+func TestTemplatesEscapeHTML(t *testing.T) {
+	msg, err := DefaultTemplates().Message(nil, TemplateNewDevice, "jane@example.com", NewDeviceData{Device: "<script>alert(1)</script>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Errorf("device name was not escaped: %q", msg.HTML)
+	}
+}
+
+func TestTemplatesTranslate(t *testing.T) {
+	l := i18n.DefaultCatalog().Localizer("de-DE,de;q=0.9,en;q=0.5")
+	msg, err := DefaultTemplates().Message(l, TemplateVerification, "jane@example.com", VerificationData{VerifyURL: "https://auth.example.com/verify?t=abc", Expires: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Bestätigen Sie Ihre E-Mail-Adresse" {
+		t.Errorf("Subject = %q, want the German subject", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Der Link läuft in 1 Stunde ab.") {
+		t.Errorf("Text = %q, want the German expiry", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, `<html lang="de">`) {
+		t.Errorf("HTML = %q, want lang=de", msg.HTML)
+	}
+}
+
+func TestLoadTemplatesOverrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("layout.html", `<div class="acme">{{template "content" .}}</div>`)
+	write("lockout.txt", `{{define "subject"}}Acme account locked{{end}}Locked after {{.FailedAttempts}} tries.`)
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates() error = %v", err)
+	}
+	msg, err := templates.Message(nil, TemplateLockout, "jane@example.com", LockoutData{FailedAttempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Acme account locked" || msg.Text != "Locked after 3 tries.\n" {
+		t.Errorf("got %q / %q, want the overridden text template", msg.Subject, msg.Text)
+	}
+	if !strings.HasPrefix(msg.HTML, `<div class="acme">`) || !strings.Contains(msg.HTML, "failed sign-in attempts") {
+		t.Errorf("HTML = %q, want the embedded content in the overridden layout", msg.HTML)
+	}
+
+	write("lockout.txt", `Locked after {{.FailedAttempts}} tries.`)
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Error("LoadTemplates() accepted a template without a subject")
+	}
+	write("lockout.txt", `{{define "subject"}}Locked{{end}}{{.FailedAttempts`)
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Error("LoadTemplates() accepted an invalid template")
+	}
+	if _, err := LoadTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadTemplates() accepted a missing directory")
+	}
+}
@@ -0,0 +1,49 @@
+package email
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ThrottledSender spaces out emails to stay within a provider's sending rate,
+// such as an SES account's maximum send rate. Send waits for its turn rather
+// than failing, so use it behind an AsyncSender.
+type ThrottledSender struct {
+	next     Sender
+	interval time.Duration
+
+	mu     sync.Mutex
+	nextAt time.Time // when the next email may be sent
+}
+
+// Verify that ThrottledSender implements Sender interface
+var _ Sender = (*ThrottledSender)(nil)
+
+// NewThrottledSender sends through next at most perSecond emails a second
+func NewThrottledSender(next Sender, perSecond float64) *ThrottledSender {
+	return &ThrottledSender{next: next, interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Send waits for the next free slot, or until ctx is done, and sends the
+// message
+func (s *ThrottledSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	at := time.Now()
+	if s.nextAt.After(at) {
+		at = s.nextAt
+	}
+	s.nextAt = at.Add(s.interval)
+	s.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.next.Send(ctx, msg)
+}
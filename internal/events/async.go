@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultQueueSize is used by NewAsyncPublisher for non-positive sizes
+const DefaultQueueSize = 4096
+
+// AsyncPublisher moves publishing off the request path, so a slow or
+// unreachable event bus never delays sign-ins. Events are queued in a bounded
+// buffer and published in order by a background worker; failures are logged.
+// When the queue is full, events are dropped and counted rather than blocking
+// the request.
+type AsyncPublisher struct {
+	next  Publisher
+	queue chan queuedEvent
+	done  chan struct{}
+
+	mu     sync.RWMutex // guards closed against sends on the closed queue
+	closed bool
+
+	dropped atomic.Int64
+}
+
+type queuedEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+// Verify that AsyncPublisher implements Publisher interface
+var _ Publisher = (*AsyncPublisher)(nil)
+
+// NewAsyncPublisher starts a background worker publishing to next
+func NewAsyncPublisher(next Publisher, queueSize int) *AsyncPublisher {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	p := &AsyncPublisher{
+		next:  next,
+		queue: make(chan queuedEvent, queueSize),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues the event without blocking and never fails. The event keeps
+// the values of ctx but not its cancellation.
+func (p *AsyncPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.drop(event)
+		return nil
+	}
+
+	select {
+	case p.queue <- queuedEvent{ctx: context.WithoutCancel(ctx), event: event}:
+	default:
+		p.drop(event)
+	}
+	return nil
+}
+
+// drop counts an event that could not be queued
+func (p *AsyncPublisher) drop(event Event) {
+	// Log the first drop and every thousandth after it to avoid flooding the log
+	if n := p.dropped.Add(1); n%1000 == 1 {
+		slog.Warn("events: queue full, dropped event", "event_type", event.Type, "dropped", n)
+	}
+}
+
+// Dropped returns how many events were discarded because the queue was full
+func (p *AsyncPublisher) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Close stops accepting events and waits until the queued events are
+// published or ctx is done
+func (p *AsyncPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run publishes queued events until the queue is closed and drained
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+	for item := range p.queue {
+		if err := p.next.Publish(item.ctx, item.event); err != nil {
+			slog.ErrorContext(item.ctx, "events: failed to publish event", "event_id", item.event.ID, "event_type", item.event.Type, "err", err)
+		}
+	}
+}
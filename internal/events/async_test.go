@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects published events, blocking until release is closed
+type recorder struct {
+	mu      sync.Mutex
+	types   []string
+	release chan struct{}
+	err     error
+}
+
+func (r *recorder) Publish(ctx context.Context, event Event) error {
+	<-r.release
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = append(r.types, event.Type)
+	return r.err
+}
+
+func TestAsyncPublisher(t *testing.T) {
+	next := &recorder{release: make(chan struct{})}
+	p := NewAsyncPublisher(next, 2)
+
+	// The worker holds the first event, so two fit in the queue and the
+	// fourth is dropped instead of blocking
+	for _, eventType := range []string{UserRegistered, UserLogin, UserLocked, SessionRevoked} {
+		if err := p.Publish(context.Background(), New(eventType, nil)); err != nil {
+			t.Fatalf("Publish returned %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(next.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+	if got := len(next.types); got != 3 || next.types[0] != UserRegistered || next.types[2] != UserLocked {
+		t.Errorf("published %v, want the first three events in order", next.types)
+	}
+	if p.Dropped() != 1 {
+		t.Errorf("dropped %d events, want 1", p.Dropped())
+	}
+
+	p.Publish(context.Background(), New(UserLogin, nil))
+	if p.Dropped() != 2 {
+		t.Errorf("dropped %d events after Close, want 2", p.Dropped())
+	}
+}
+
+func TestMulti(t *testing.T) {
+	released := make(chan struct{})
+	close(released)
+	failing := &recorder{release: released, err: errors.New("bus unavailable")}
+	working := &recorder{release: released}
+
+	err := Multi{failing, working}.Publish(context.Background(), New(UserLogin, nil))
+	if err == nil {
+		t.Error("Publish returned nil, want the failing publisher's error")
+	}
+	if len(working.types) != 1 {
+		t.Errorf("working publisher got %d events, want 1", len(working.types))
+	}
+}
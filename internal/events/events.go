@@ -0,0 +1,74 @@
+// Package events streams auth events to other systems. The service layer
+// emits an Event after each registration, sign-in, lockout, and session
+// revocation, and Publishers carry it to an event bus or to webhooks.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Event types
+const (
+	UserRegistered = "user.registered"
+	UserLogin      = "user.login"
+	UserLocked     = "user.locked"
+	SessionRevoked = "session.revoked"
+)
+
+// Types lists every event type
+var Types = []string{UserRegistered, UserLogin, UserLocked, SessionRevoked}
+
+// Event is an auth event as published, encoded as JSON
+type Event struct {
+	ID   string         `json:"id"`
+	Type string         `json:"type"`
+	Time time.Time      `json:"time"`
+	Data map[string]any `json:"data"`
+}
+
+// New creates an event of the given type with a random ID
+func New(eventType string, data map[string]any) Event {
+	var id [16]byte
+	rand.Read(id[:])
+	return Event{ID: "evt_" + hex.EncodeToString(id[:]), Type: eventType, Time: time.Now().UTC(), Data: data}
+}
+
+// Key returns the user the event is about, so buses that partition by key
+// deliver each user's events in order. It is empty for events without a user.
+func (e Event) Key() string {
+	switch id := e.Data["user_id"].(type) {
+	case int64:
+		return strconv.FormatInt(id, 10)
+	case int:
+		return strconv.Itoa(id)
+	}
+	return ""
+}
+
+// Publisher sends events to other systems. Implementations must be safe for
+// concurrent use.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Multi publishes events to several publishers
+type Multi []Publisher
+
+// Verify that Multi implements Publisher interface
+var _ Publisher = Multi(nil)
+
+// Publish sends the event to every publisher, returning their errors joined
+func (m Multi) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
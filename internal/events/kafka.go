@@ -0,0 +1,93 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultKafkaTopic is the topic used when none is configured
+const DefaultKafkaTopic = "auth-events"
+
+// KafkaPublisher produces events to a Kafka topic through the REST Proxy API
+// (v2), as served by Confluent REST Proxy and Redpanda's HTTP proxy. Events
+// are keyed by user ID, so each user's events land on one partition in order.
+type KafkaPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+// Verify that KafkaPublisher implements Publisher interface
+var _ Publisher = (*KafkaPublisher)(nil)
+
+// NewKafkaPublisher creates a publisher producing to topic through the REST
+// proxy at proxyURL. Credentials in the URL are sent as basic auth.
+func NewKafkaPublisher(proxyURL, topic string) *KafkaPublisher {
+	if topic == "" {
+		topic = DefaultKafkaTopic
+	}
+	return &KafkaPublisher{
+		endpoint: strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// kafkaRecord is a record of a REST proxy produce request
+type kafkaRecord struct {
+	Key   *string `json:"key"` // null lets the proxy spread records over partitions
+	Value Event   `json:"value"`
+}
+
+// kafkaOffset is the result of producing one record
+type kafkaOffset struct {
+	ErrorCode *int   `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+// Publish produces the event as a JSON record
+func (p *KafkaPublisher) Publish(ctx context.Context, event Event) error {
+	record := kafkaRecord{Value: event}
+	if key := event.Key(); key != "" {
+		record.Key = &key
+	}
+	body, err := json.Marshal(map[string]any{"records": []kafkaRecord{record}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	// Records can fail individually inside a successful response
+	var result struct {
+		Offsets []kafkaOffset `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("kafka rest proxy: invalid response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rest proxy: error %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
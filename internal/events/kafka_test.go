@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKafkaPublisher(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  bool
+	}{
+		{name: "produced", status: http.StatusOK, response: `{"offsets":[{"partition":0,"offset":7}]}`},
+		{name: "record failed", status: http.StatusOK, response: `{"offsets":[{"error_code":50002,"error":"leader not available"}]}`, wantErr: true},
+		{name: "unknown topic", status: http.StatusNotFound, response: `{"error_code":40401,"message":"Topic not found"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Records []struct {
+					Key   *string `json:"key"`
+					Value Event   `json:"value"`
+				} `json:"records"`
+			}
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/topics/auth-events" {
+					t.Errorf("produced to %s, want /topics/auth-events", r.URL.Path)
+				}
+				if got := r.Header.Get("Content-Type"); got != "application/vnd.kafka.json.v2+json" {
+					t.Errorf("Content-Type = %q", got)
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer proxy.Close()
+
+			event := New(UserLogin, map[string]any{"user_id": int64(42)})
+			err := NewKafkaPublisher(proxy.URL+"/", "").Publish(context.Background(), event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish returned %v, want error %v", err, tt.wantErr)
+			}
+			if len(body.Records) != 1 || body.Records[0].Key == nil || *body.Records[0].Key != "42" || body.Records[0].Value.ID != event.ID {
+				t.Errorf("produced %+v, want the event keyed by user ID", body.Records)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultNATSSubject is the subject prefix used when none is configured
+const DefaultNATSSubject = "auth.events"
+
+// NATSPublisher publishes events to NATS on "<subject>.<event type>", e.g.
+// "auth.events.user.login", so consumers can subscribe to "auth.events.>" or
+// to single event types
+type NATSPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// Verify that NATSPublisher implements Publisher interface
+var _ Publisher = (*NATSPublisher)(nil)
+
+// NewNATSPublisher connects to the NATS servers at url (comma-separated for a
+// cluster). The connection reconnects on its own for as long as the service
+// runs, buffering events meanwhile.
+func NewNATSPublisher(url, subject string) (*NATSPublisher, error) {
+	if subject == "" {
+		subject = DefaultNATSSubject
+	}
+	conn, err := nats.Connect(url, nats.Name("go-auth-service"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{conn: conn, subject: subject}, nil
+}
+
+// Publish sends the event as JSON
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.conn.Publish(p.subject+"."+event.Type, payload)
+}
+
+// Close sends the buffered events and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// Defaults used by NewOutboxRelay
+const (
+	DefaultRelayInterval   = time.Second
+	DefaultRelayBatchSize  = 100
+	DefaultOutboxRetention = 24 * time.Hour
+	maxRelayBackoff        = time.Minute
+)
+
+// OutboxEvent encodes an event for the outbox, so it can be stored in the
+// same transaction as the change it describes
+func OutboxEvent(event Event) (*model.OutboxEvent, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OutboxEvent{EventID: event.ID, EventType: event.Type, Payload: payload}, nil
+}
+
+// OutboxRelay publishes the events stored in the outbox, oldest first, and
+// marks them published. It polls every interval and keeps going without a
+// pause while full batches are waiting. When publishing fails, the event is
+// retried with a wait that doubles up to a minute, and later events wait
+// behind it so they stay in order. Events are published at least once: an
+// event is published again if the relay stops before marking it.
+type OutboxRelay struct {
+	repo      interfaces.OutboxRepository
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewOutboxRelay starts a relay publishing the events of repo to publisher,
+// polling every interval (DefaultRelayInterval when not positive). Events
+// published more than DefaultOutboxRetention ago are deleted hourly.
+func NewOutboxRelay(repo interfaces.OutboxRepository, publisher Publisher, interval time.Duration) *OutboxRelay {
+	if interval <= 0 {
+		interval = DefaultRelayInterval
+	}
+	r := &OutboxRelay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: DefaultRelayBatchSize,
+		retention: DefaultOutboxRetention,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Close stops the relay after the batch in progress, waiting until it is
+// marked or ctx is done. Unpublished events stay in the outbox for the next
+// start.
+func (r *OutboxRelay) Close(ctx context.Context) error {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run relays batches until the relay is closed
+func (r *OutboxRelay) run() {
+	defer close(r.done)
+
+	wait := time.Duration(0)
+	var purged time.Time
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(wait):
+		}
+
+		if time.Since(purged) >= time.Hour {
+			r.purge()
+			purged = time.Now()
+		}
+
+		published, err := r.relay()
+		switch {
+		case err != nil:
+			slog.Error("events: failed to relay outbox", "published", published, "err", err)
+			wait = min(max(2*wait, r.interval), maxRelayBackoff)
+		case published == r.batchSize:
+			wait = 0 // more events are waiting
+		default:
+			wait = r.interval
+		}
+	}
+}
+
+// relay publishes one batch of events, returning how many were published
+func (r *OutboxRelay) relay() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	return r.repo.RelayOutbox(ctx, r.batchSize, func(stored []*model.OutboxEvent) (int, error) {
+		for i, e := range stored {
+			var event Event
+			if err := json.Unmarshal(e.Payload, &event); err != nil {
+				// Retrying cannot fix a payload, so skip it rather than block the outbox
+				slog.Error("events: skipped undecodable outbox event", "event_id", e.EventID, "err", err)
+				continue
+			}
+			if err := r.publisher.Publish(ctx, event); err != nil {
+				return i, err
+			}
+		}
+		return len(stored), nil
+	})
+}
+
+// purge deletes events published longer ago than the retention period
+func (r *OutboxRelay) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := r.repo.PurgeOutbox(ctx, time.Now().Add(-r.retention)); err != nil {
+		slog.Error("events: failed to purge published outbox events", "err", err)
+	}
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// memoryOutbox keeps outbox events in a slice, marking them like the
+// repositories do
+type memoryOutbox struct {
+	mu     sync.Mutex
+	events []*model.OutboxEvent
+	next   int // index of the first unpublished event
+}
+
+func (o *memoryOutbox) RelayOutbox(ctx context.Context, limit int, publish func(events []*model.OutboxEvent) (int, error)) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	batch := o.events[o.next:min(o.next+limit, len(o.events))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	published, err := publish(batch)
+	o.next += published
+	if err != nil && published < len(batch) {
+		batch[published].Attempts++
+	}
+	return published, err
+}
+
+func (o *memoryOutbox) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (o *memoryOutbox) relayed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.next == len(o.events)
+}
+
+// flaky records published events, failing the first fail attempts
+type flaky struct {
+	mu    sync.Mutex
+	fail  int
+	types []string
+}
+
+func (f *flaky) Publish(ctx context.Context, event Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("unavailable")
+	}
+	f.types = append(f.types, event.Type)
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	outbox := &memoryOutbox{}
+	for i, eventType := range []string{UserRegistered, UserLogin, SessionRevoked} {
+		stored, err := OutboxEvent(New(eventType, map[string]any{"user_id": int64(1)}))
+		if err != nil {
+			t.Fatalf("OutboxEvent returned %v", err)
+		}
+		outbox.events = append(outbox.events, stored)
+		if i == 0 {
+			// An event that cannot be decoded is skipped rather than blocking the rest
+			outbox.events = append(outbox.events, &model.OutboxEvent{EventID: "bad", Payload: []byte("{")})
+		}
+	}
+
+	publisher := &flaky{fail: 2}
+	relay := NewOutboxRelay(outbox, publisher, 10*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for !outbox.relayed() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := relay.Close(ctx); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	if !outbox.relayed() {
+		t.Fatal("outbox events not all marked published")
+	}
+	want := []string{UserRegistered, UserLogin, SessionRevoked}
+	if len(publisher.types) != len(want) {
+		t.Fatalf("published %v, want %v", publisher.types, want)
+	}
+	for i := range want {
+		if publisher.types[i] != want[i] {
+			t.Errorf("published %v, want %v in order", publisher.types, want)
+			break
+		}
+	}
+	if outbox.events[0].Attempts != 2 {
+		t.Errorf("first event attempts = %d, want 2 failed publishes recorded", outbox.events[0].Attempts)
+	}
+}
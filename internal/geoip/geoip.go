@@ -0,0 +1,74 @@
+// Package geoip looks up the country of client addresses in a MaxMind
+// database: GeoLite2 or GeoIP2 Country or City, or another database in the
+// same format with a country.iso_code field
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Locator finds the country of an IP address
+type Locator interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country of ip, or
+	// "" when it is not known
+	Country(ip string) (string, error)
+}
+
+// DB is a MaxMind database opened from a file
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// Verify that DB implements Locator interface
+var _ Locator = (*DB)(nil)
+
+// Open opens the database at path, memory-mapping the file
+func Open(path string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: failed to open %s: %v", path, err)
+	}
+	return &DB{reader: reader}, nil
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	// The country of the network's registrant, for networks such as
+	// anycast ranges without a location
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Country returns the country of ip. Invalid and private addresses and those
+// missing from the database have no country.
+func (db *DB) Country(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", nil
+	}
+	if db.reader.Metadata.IPVersion == 4 {
+		if addr = addr.To4(); addr == nil {
+			return "", nil
+		}
+	}
+
+	var r record
+	if err := db.reader.Lookup(addr, &r); err != nil {
+		return "", err
+	}
+	if r.Country.ISOCode != "" {
+		return r.Country.ISOCode, nil
+	}
+	return r.RegisteredCountry.ISOCode, nil
+}
+
+// Close unmaps the database file
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
@@ -0,0 +1,180 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCountry(t *testing.T) {
+	db, err := Open(writeTestDB(t, map[string]string{
+		"81.2.69.0/24":  "GB",
+		"89.160.0.0/16": "SE",
+		"2.125.0.0/16":  "",
+	}))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"81.2.69.160", "GB"},
+		{"89.160.20.112", "SE"},
+		{"81.2.70.1", ""},
+		{"2.125.160.216", ""}, // a network without a country
+		{"10.0.0.1", ""},
+		{"::ffff:81.2.69.160", "GB"},
+		{"2001:db8::1", ""},
+		{"not an address", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := db.Country(tt.ip)
+			if err != nil {
+				t.Fatalf("Country() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Country() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("Open() accepted a missing file")
+	}
+}
+
+// writeTestDB writes an IPv4 MaxMind database mapping networks to countries
+// and returns its path. An empty country stores a record without one.
+func writeTestDB(t *testing.T, networks map[string]string) string {
+	t.Helper()
+
+	// A binary trie of the networks' bits; leaves hold a data offset
+	type node struct {
+		children [2]*node
+		data     [2]int // offset+1 in the data section, 0 for none
+	}
+	root := &node{}
+	var data bytes.Buffer
+	offsets := map[string]int{}
+	for cidr, country := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := offsets[country]; !ok {
+			offsets[country] = data.Len()
+			if country == "" {
+				writeMap(&data, map[string]any{"continent": map[string]any{"code": "EU"}})
+			} else {
+				writeMap(&data, map[string]any{"country": map[string]any{"iso_code": country}})
+			}
+		}
+		ones, _ := network.Mask.Size()
+		n := root
+		for i := range ones {
+			bit := network.IP.To4()[i/8] >> (7 - i%8) & 1
+			if i == ones-1 {
+				n.data[bit] = offsets[country] + 1
+				break
+			}
+			if n.children[bit] == nil {
+				n.children[bit] = &node{}
+			}
+			n = n.children[bit]
+		}
+	}
+
+	var nodes []*node
+	index := map[*node]int{}
+	var number func(n *node)
+	number = func(n *node) {
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, child := range n.children {
+			if child != nil {
+				number(child)
+			}
+		}
+	}
+	number(root)
+
+	// Records are 24 bits: a node index, the node count for no data, or a
+	// pointer past the 16-byte separator into the data section
+	var out bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for bit := range 2 {
+			value := count
+			if n.children[bit] != nil {
+				value = index[n.children[bit]]
+			} else if n.data[bit] != 0 {
+				value = count + 16 + n.data[bit] - 1
+			}
+			out.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.WriteString("\xab\xcd\xefMaxMind.com")
+	writeMap(&out, map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               "Test-Country",
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"description":                 map[string]any{"en": "test"},
+	})
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeMap encodes m in the MaxMind DB data format
+func writeMap(b *bytes.Buffer, m map[string]any) {
+	b.WriteByte(7<<5 | byte(len(m)))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		writeValue(b, key)
+		writeValue(b, m[key])
+	}
+}
+
+func writeValue(b *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case string:
+		b.WriteByte(2<<5 | byte(len(v)))
+		b.WriteString(v)
+	case uint16:
+		b.WriteByte(5<<5 | 2)
+		binary.Write(b, binary.BigEndian, v)
+	case uint32:
+		b.WriteByte(6<<5 | 4)
+		binary.Write(b, binary.BigEndian, v)
+	case uint64:
+		// Extended types store their type minus 7 in a second byte
+		b.Write([]byte{8, 9 - 7})
+		binary.Write(b, binary.BigEndian, v)
+	case []any:
+		b.Write([]byte{byte(len(v)), 11 - 7})
+		for _, item := range v {
+			writeValue(b, item)
+		}
+	case map[string]any:
+		writeMap(b, v)
+	}
+}
@@ -0,0 +1,544 @@
+// Package graphql executes GraphQL queries and mutations against a schema of
+// Go resolvers. It implements the parts of the language clients send:
+// operations with variables, aliases, fragments, inline fragments, @skip and
+// @include, and __typename. Introspection and subscriptions are not supported.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Resolver computes a field of source, the value of the parent object (nil
+// for root fields), from the field's arguments
+type Resolver func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// Field is a field of an object type
+type Field struct {
+	Type *Object // the object type of the value; nil for scalars
+	List bool    // the value is a slice of Type, or of scalars
+
+	// Argument names and types: String, ID, Int or Boolean, with a trailing
+	// ! when required. Arguments not given are missing from the resolver's args.
+	Args map[string]string
+
+	Resolve Resolver
+}
+
+// Object is an object type
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Schema holds the root types of queries and mutations
+type Schema struct {
+	Query    *Object
+	Mutation *Object // nil when there are no mutations
+}
+
+// Request is a GraphQL request as clients send it over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request could
+// not be executed, and null fields in it have an entry in Errors.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is an error in a request, or of the field at Path. Resolvers can
+// return an *Error to add extensions, such as an error code.
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Operation is a validated operation of a request, ready to execute
+type Operation struct {
+	op        *operation
+	root      *Object
+	fragments map[string]*fragment
+	variables map[string]any
+}
+
+// Prepare parses a request, selects its operation and checks it against the
+// schema. The returned error is an *Error.
+func (s *Schema) Prepare(req Request) (*Operation, error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, &Error{Message: err.Error()}
+	}
+
+	var op *operation
+	if req.OperationName == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "operationName is required for a document with several operations"}
+		}
+		op = doc.operations[0]
+	}
+	for _, candidate := range doc.operations {
+		if req.OperationName != "" && candidate.name == req.OperationName {
+			op = candidate
+		}
+	}
+	if op == nil {
+		return nil, &Error{Message: fmt.Sprintf("unknown operation %q", req.OperationName)}
+	}
+
+	o := &Operation{op: op, fragments: doc.fragments}
+	switch op.kind {
+	case "query":
+		o.root = s.Query
+	case "mutation":
+		o.root = s.Mutation
+	}
+	if o.root == nil {
+		return nil, &Error{Message: fmt.Sprintf("%s operations are not supported", op.kind)}
+	}
+	if o.variables, err = coerceVariables(op.variables, req.Variables); err != nil {
+		return nil, &Error{Message: err.Error()}
+	}
+	if err := o.validate(o.root, op.selections, nil); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Kind returns "query" or "mutation"
+func (o *Operation) Kind() string {
+	return o.op.kind
+}
+
+// RootFields returns the number of root fields the operation selects
+func (o *Operation) RootFields() int {
+	keys, _, _ := o.collect(o.root, o.op.selections)
+	return len(keys)
+}
+
+// Execute resolves the operation. Fields are resolved one after another, so
+// the mutations of an operation run in order.
+func (o *Operation) Execute(ctx context.Context) *Response {
+	resp := &Response{}
+	resp.Data = o.execute(ctx, o.root, nil, o.op.selections, nil, &resp.Errors)
+	return resp
+}
+
+// Execute prepares and executes a request
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	op, err := s.Prepare(req)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+	return op.Execute(ctx)
+}
+
+// validate checks that the selections exist on obj, with valid arguments and
+// subfields for object types only
+func (o *Operation) validate(obj *Object, selections []*selection, path []any) error {
+	keys, fields, err := o.collect(obj, selections)
+	if err != nil {
+		return &Error{Message: err.Error(), Path: path}
+	}
+	for _, key := range keys {
+		group := fields[key]
+		first := group[0]
+		fieldPath := append(path[:len(path):len(path)], key)
+		for _, other := range group[1:] {
+			if other.name != first.name {
+				return &Error{Message: fmt.Sprintf("fields %q and %q conflict under the name %q", first.name, other.name, key), Path: fieldPath}
+			}
+		}
+		subselections := mergeSelections(group)
+
+		if first.name == "__typename" {
+			if len(subselections) > 0 {
+				return &Error{Message: "__typename cannot have subfields", Path: fieldPath}
+			}
+			continue
+		}
+		field, ok := obj.Fields[first.name]
+		if !ok {
+			return &Error{Message: fmt.Sprintf("cannot query field %q on type %q", first.name, obj.Name), Path: fieldPath}
+		}
+		if _, err := o.arguments(field, first); err != nil {
+			return &Error{Message: err.Error(), Path: fieldPath}
+		}
+		switch {
+		case field.Type != nil && len(subselections) == 0:
+			return &Error{Message: fmt.Sprintf("field %q of type %q must have a selection of subfields", first.name, field.Type.Name), Path: fieldPath}
+		case field.Type == nil && len(subselections) > 0:
+			return &Error{Message: fmt.Sprintf("field %q is a scalar and cannot have subfields", first.name), Path: fieldPath}
+		case field.Type != nil:
+			if err := o.validate(field.Type, subselections, fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// execute resolves the selections of obj for source, recording the errors of
+// fields in errs
+func (o *Operation) execute(ctx context.Context, obj *Object, source any, selections []*selection, path []any, errs *[]*Error) *orderedMap {
+	keys, fields, _ := o.collect(obj, selections)
+	result := &orderedMap{values: make(map[string]any, len(keys))}
+	for _, key := range keys {
+		group := fields[key]
+		first := group[0]
+		fieldPath := append(path[:len(path):len(path)], key)
+		result.keys = append(result.keys, key)
+
+		if first.name == "__typename" {
+			result.values[key] = obj.Name
+			continue
+		}
+		field := obj.Fields[first.name]
+		args, _ := o.arguments(field, first)
+		value, err := field.Resolve(ctx, source, args)
+		if err != nil {
+			fieldErr := &Error{Message: err.Error(), Path: fieldPath}
+			var resolverErr *Error
+			if errors.As(err, &resolverErr) {
+				fieldErr.Extensions = resolverErr.Extensions
+			}
+			*errs = append(*errs, fieldErr)
+			result.values[key] = nil
+			continue
+		}
+		result.values[key] = o.complete(ctx, field, value, mergeSelections(group), fieldPath, errs)
+	}
+	return result
+}
+
+// complete turns a resolved value into its result: the selections of an
+// object, or a scalar as it is
+func (o *Operation) complete(ctx context.Context, field *Field, value any, selections []*selection, path []any, errs *[]*Error) any {
+	v := reflect.ValueOf(value)
+	if value == nil || (v.Kind() == reflect.Pointer || v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil() {
+		if field.List {
+			return []any{}
+		}
+		return nil
+	}
+	if field.List {
+		if v.Kind() != reflect.Slice {
+			*errs = append(*errs, &Error{Message: "internal error: list field did not resolve to a slice", Path: path})
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			item := v.Index(i).Interface()
+			if field.Type != nil {
+				list[i] = o.execute(ctx, field.Type, item, selections, append(path[:len(path):len(path)], i), errs)
+			} else {
+				list[i] = item
+			}
+		}
+		return list
+	}
+	if field.Type != nil {
+		return o.execute(ctx, field.Type, value, selections, path, errs)
+	}
+	return value
+}
+
+// collect groups the fields of selections by response key, in order,
+// expanding fragments and applying @skip and @include. Fragments cannot
+// spread themselves, which parse checks.
+func (o *Operation) collect(obj *Object, selections []*selection) ([]string, map[string][]*selection, error) {
+	var keys []string
+	fields := make(map[string][]*selection)
+	var walk func(selections []*selection) error
+	walk = func(selections []*selection) error {
+		for _, sel := range selections {
+			include, err := o.included(sel)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+			switch {
+			case sel.name != "":
+				key := sel.responseKey()
+				if _, seen := fields[key]; !seen {
+					keys = append(keys, key)
+				}
+				fields[key] = append(fields[key], sel)
+			case sel.spread != "":
+				f, ok := o.fragments[sel.spread]
+				if !ok {
+					return fmt.Errorf("unknown fragment %q", sel.spread)
+				}
+				if f.typeCondition != obj.Name {
+					continue
+				}
+				if err := walk(f.selections); err != nil {
+					return err
+				}
+			default:
+				if sel.typeCondition != "" && sel.typeCondition != obj.Name {
+					continue
+				}
+				if err := walk(sel.selections); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err := walk(selections)
+	return keys, fields, err
+}
+
+// included applies the @skip and @include directives of a selection
+func (o *Operation) included(sel *selection) (bool, error) {
+	for _, d := range sel.directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		if len(d.arguments) != 1 || d.arguments[0].name != "if" {
+			return false, fmt.Errorf("@%s requires exactly the argument \"if\"", d.name)
+		}
+		value, err := o.substitute(d.arguments[0].value)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := value.(bool)
+		if !ok {
+			return false, fmt.Errorf("the argument \"if\" of @%s must be a Boolean", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments coerces the arguments of a field selection to the types the
+// field declares
+func (o *Operation) arguments(field *Field, sel *selection) (map[string]any, error) {
+	args := make(map[string]any, len(sel.arguments))
+	for _, arg := range sel.arguments {
+		typ, ok := field.Args[arg.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown argument %q on field %q", arg.name, sel.name)
+		}
+		if _, dup := args[arg.name]; dup {
+			return nil, fmt.Errorf("argument %q is given twice", arg.name)
+		}
+		if name, isVar := arg.value.(variable); isVar {
+			if _, defined := o.variables[string(name)]; !defined && o.declared(string(name)) {
+				continue // an optional variable the request left out
+			}
+		}
+		value, err := o.substitute(arg.value)
+		if err != nil {
+			return nil, err
+		}
+		if args[arg.name], err = coerce(typ, value); err != nil {
+			return nil, fmt.Errorf("argument %q: %v", arg.name, err)
+		}
+	}
+	for name, typ := range field.Args {
+		if _, given := args[name]; !given && strings.HasSuffix(typ, "!") {
+			return nil, fmt.Errorf("argument %q of type %s is required on field %q", name, typ, sel.name)
+		}
+	}
+	return args, nil
+}
+
+// declared reports whether the operation defines a variable
+func (o *Operation) declared(name string) bool {
+	for _, v := range o.op.variables {
+		if v.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// substitute replaces the variables in an argument value with their values
+func (o *Operation) substitute(value any) (any, error) {
+	switch value := value.(type) {
+	case variable:
+		if !o.declared(string(value)) {
+			return nil, fmt.Errorf("variable $%s is not defined", value)
+		}
+		return o.variables[string(value)], nil
+	case []any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			var err error
+			if list[i], err = o.substitute(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]any:
+		obj := make(map[string]any, len(value))
+		for k, item := range value {
+			var err error
+			if obj[k], err = o.substitute(item); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+	return value, nil
+}
+
+// coerceVariables checks the variables of a request against their
+// definitions, applying defaults
+func coerceVariables(definitions []*variableDefinition, values map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(definitions))
+	for _, def := range definitions {
+		value, given := values[def.name]
+		if !given && def.hasDefault {
+			value, given = def.defaultValue, true
+		}
+		if !given {
+			if strings.HasSuffix(def.typ, "!") {
+				return nil, fmt.Errorf("variable $%s of required type %s was not provided", def.name, def.typ)
+			}
+			continue
+		}
+		coerced, err := coerce(def.typ, value)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %v", def.name, err)
+		}
+		vars[def.name] = coerced
+	}
+	return vars, nil
+}
+
+// coerce converts a literal or JSON value to an input type: String, ID, Int
+// or Boolean, a list of them, or a non-null variant. Values of other types are
+// passed through.
+func coerce(typ string, value any) (any, error) {
+	base, nonNull := strings.CutSuffix(typ, "!")
+	if value == nil {
+		if nonNull {
+			return nil, fmt.Errorf("expected a non-null %s", base)
+		}
+		return nil, nil
+	}
+
+	if inner, ok := strings.CutPrefix(base, "["); ok {
+		inner = strings.TrimSuffix(inner, "]")
+		items, ok := value.([]any)
+		if !ok {
+			items = []any{value} // a single value stands for a list of one
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			if list[i], err = coerce(inner, item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+
+	switch base {
+	case "String":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case json.Number:
+			if _, err := v.Int64(); err == nil {
+				return v.String(), nil
+			}
+		case float64:
+			if v == math.Trunc(v) {
+				return strconv.FormatFloat(v, 'f', -1, 64), nil
+			}
+		}
+	case "Int":
+		var n int64
+		var ok bool
+		switch v := value.(type) {
+		case int:
+			n, ok = int64(v), true // a variable, already coerced
+		case int64:
+			n, ok = v, true
+		case json.Number:
+			i, err := v.Int64()
+			n, ok = i, err == nil
+		case float64:
+			n, ok = int64(v), v == math.Trunc(v)
+		}
+		if ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int(n), nil
+		}
+	case "Boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("expected a value of type %s", base)
+}
+
+// mergeSelections returns the subselections of fields sharing a response key
+func mergeSelections(group []*selection) []*selection {
+	if len(group) == 1 {
+		return group[0].selections
+	}
+	var merged []*selection
+	for _, sel := range group {
+		merged = append(merged, sel.selections...)
+	}
+	return merged
+}
+
+// orderedMap is an object of the result, which keeps its fields in the order
+// they were selected
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,143 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID      string
+	Name    string
+	Friends []*testUser
+}
+
+// testSchema serves two users and a counter
+func testSchema() (*Schema, *int) {
+	alice := &testUser{ID: "1", Name: "Alice"}
+	bob := &testUser{ID: "2", Name: "Bob", Friends: []*testUser{alice}}
+	users := map[string]*testUser{"1": alice, "2": bob}
+	counter := 0
+
+	user := &Object{Name: "User"}
+	user.Fields = map[string]*Field{
+		"id": {Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) { return source.(*testUser).ID, nil }},
+		"name": {Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) {
+			return source.(*testUser).Name, nil
+		}},
+		"friends": {Type: user, List: true, Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) {
+			return source.(*testUser).Friends, nil
+		}},
+	}
+	schema := &Schema{
+		Query: &Object{Name: "Query", Fields: map[string]*Field{
+			"hello": {Args: map[string]string{"name": "String"}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				if name, ok := args["name"].(string); ok {
+					return "Hello, " + name, nil
+				}
+				return "Hello", nil
+			}},
+			"user": {Type: user, Args: map[string]string{"id": "ID!"}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				if u, ok := users[args["id"].(string)]; ok {
+					return u, nil
+				}
+				return nil, errors.New("no such user")
+			}},
+		}},
+		Mutation: &Object{Name: "Mutation", Fields: map[string]*Field{
+			"increment": {Args: map[string]string{"by": "Int!"}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				counter += args["by"].(int)
+				return counter, nil
+			}},
+		}},
+	}
+	return schema, &counter
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables string
+		want      string
+	}{
+		{name: "shorthand query", query: `{ hello }`, want: `{"data":{"hello":"Hello"}}`},
+		{name: "arguments and aliases", query: `query { a: hello(name: "Ann") b: hello(name: "Ben\n") }`,
+			want: `{"data":{"a":"Hello, Ann","b":"Hello, Ben\n"}}`},
+		{name: "nested lists", query: `{ user(id: 2) { name friends { id name } } }`,
+			want: `{"data":{"user":{"name":"Bob","friends":[{"id":"1","name":"Alice"}]}}}`},
+		{name: "variables", query: `query Q($id: ID!, $greet: String = "Sam") { user(id: $id) { name } hello(name: $greet) }`, variables: `{"id": "1"}`,
+			want: `{"data":{"user":{"name":"Alice"},"hello":"Hello, Sam"}}`},
+		{name: "optional variable left out", query: `query ($n: String) { hello(name: $n) }`, want: `{"data":{"hello":"Hello"}}`},
+		{name: "fragments", query: `{ user(id: "2") { ...Names ... on User { id } ... { __typename } } } fragment Names on User { name friends { name } }`,
+			want: `{"data":{"user":{"name":"Bob","friends":[{"name":"Alice"}],"id":"2","__typename":"User"}}}`},
+		{name: "skip and include", query: `query ($yes: Boolean!) { a: hello @include(if: $yes) b: hello @skip(if: $yes) }`, variables: `{"yes": true}`,
+			want: `{"data":{"a":"Hello"}}`},
+		{name: "resolver error", query: `{ user(id: 9) { name } hello }`,
+			want: `{"data":{"user":null,"hello":"Hello"},"errors":[{"message":"no such user","path":["user"]}]}`},
+		{name: "named operation", query: `query A { hello } query B { b: hello }`, operation: "B", want: `{"data":{"b":"Hello"}}`},
+		{name: "several operations without a name", query: `query A { hello } query B { hello }`,
+			want: `{"errors":[{"message":"operationName is required for a document with several operations"}]}`},
+		{name: "unknown field", query: `{ user(id: 1) { email } }`,
+			want: `{"errors":[{"message":"cannot query field \"email\" on type \"User\"","path":["user","email"]}]}`},
+		{name: "missing subfields", query: `{ user(id: 1) }`,
+			want: `{"errors":[{"message":"field \"user\" of type \"User\" must have a selection of subfields","path":["user"]}]}`},
+		{name: "missing argument", query: `{ user { id } }`,
+			want: `{"errors":[{"message":"argument \"id\" of type ID! is required on field \"user\"","path":["user"]}]}`},
+		{name: "wrong argument type", query: `{ hello(name: 5) }`,
+			want: `{"errors":[{"message":"argument \"name\": expected a value of type String","path":["hello"]}]}`},
+		{name: "undefined variable", query: `{ hello(name: $who) }`,
+			want: `{"errors":[{"message":"variable $who is not defined","path":["hello"]}]}`},
+		{name: "missing variable", query: `query ($id: ID!) { user(id: $id) { id } }`,
+			want: `{"errors":[{"message":"variable $id of required type ID! was not provided"}]}`},
+		{name: "fragment cycle", query: `{ user(id: 1) { ...A } } fragment A on User { friends { ...A } }`,
+			want: `{"errors":[{"message":"fragment \"A\" spreads itself"}]}`},
+		{name: "syntax error", query: `{ hello(name: "x) }`,
+			want: `{"errors":[{"message":"syntax error at 1:15: unterminated string"}]}`},
+		{name: "subscription", query: `subscription { hello }`,
+			want: `{"errors":[{"message":"subscription operations are not supported"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, _ := testSchema()
+			req := Request{Query: tt.query, OperationName: tt.operation}
+			if tt.variables != "" {
+				if err := json.Unmarshal([]byte(tt.variables), &req.Variables); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := json.Marshal(schema.Execute(context.Background(), req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutationsRunInOrder(t *testing.T) {
+	schema, counter := testSchema()
+	op, err := schema.Prepare(Request{Query: `mutation ($n: Int!) { first: increment(by: $n) second: increment(by: 10) }`, Variables: map[string]any{"n": 1.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Kind() != "mutation" || op.RootFields() != 2 {
+		t.Errorf("Kind() = %q, RootFields() = %d, want mutation and 2", op.Kind(), op.RootFields())
+	}
+	got, _ := json.Marshal(op.Execute(context.Background()))
+	if string(got) != `{"data":{"first":1,"second":11}}` || *counter != 11 {
+		t.Errorf("got %s with counter %d", got, *counter)
+	}
+
+	if _, err := schema.Prepare(Request{Query: `mutation { increment(by: 1) bogus }`}); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("Prepare() error = %v, want the unknown field", err)
+	}
+	if *counter != 11 {
+		t.Error("an invalid mutation must not run any of its fields")
+	}
+}
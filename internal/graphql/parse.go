@@ -0,0 +1,651 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed request: its operations and fragments
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query or mutation
+type operation struct {
+	kind       string // "query", "mutation" or "subscription"
+	name       string
+	variables  []*variableDefinition
+	selections []*selection
+}
+
+type variableDefinition struct {
+	name         string
+	typ          string // as written, e.g. "String!" or "[ID]"
+	defaultValue any    // nil when there is none
+	hasDefault   bool
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selections    []*selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	// A field when name is set
+	alias, name string
+	arguments   []*argument
+	selections  []*selection
+
+	spread        string // the fragment of a spread
+	typeCondition string // of an inline fragment; empty applies to any type
+
+	directives []*directive
+	line, col  int
+}
+
+// responseKey is the name of the field in the result
+func (s *selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type argument struct {
+	name  string
+	value any // a literal, variable, []any or map[string]any
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+}
+
+// variable is a reference to a variable in an argument value
+type variable string
+
+// enumValue is an unquoted name in an argument value
+type enumValue string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind      tokenKind
+	value     string
+	line, col int
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace, commas
+// and comments
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...any) error {
+	return fmt.Errorf("syntax error at %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) next() (token, error) {
+skip:
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.pos++
+			l.line++
+			l.col = 1
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			l.pos++
+			l.col++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			break skip
+		}
+	}
+	line, col := l.line, l.col
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, line: line, col: col}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		return token{kind: tokenPunct, value: "...", line: line, col: col}, nil
+	case strings.ContainsRune("!$():=@[]{}", rune(c)):
+		l.advance(1)
+		return token{kind: tokenPunct, value: string(c), line: line, col: col}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], line: line, col: col}, nil
+	case c == '-' || isDigit(c):
+		kind := tokenInt
+		l.advance(1)
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+		}
+		if l.pos < len(l.src) && l.src[l.pos] == '.' {
+			kind = tokenFloat
+			l.advance(1)
+			for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+				l.advance(1)
+			}
+		}
+		if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+			kind = tokenFloat
+			l.advance(1)
+			if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+				l.advance(1)
+			}
+			for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+				l.advance(1)
+			}
+		}
+		return token{kind: kind, value: l.src[start:l.pos], line: line, col: col}, nil
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return token{}, l.errorf(line, col, "block strings are not supported")
+		}
+		return l.string(line, col)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(line, col, "unexpected character %q", r)
+}
+
+// string reads a quoted string, decoding its escape sequences
+func (l *lexer) string(line, col int) (token, error) {
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return token{kind: tokenString, value: b.String(), line: line, col: col}, nil
+		case c == '\n':
+			return token{}, l.errorf(line, col, "unterminated string")
+		case c == '\\' && l.pos+1 < len(l.src):
+			esc := l.src[l.pos+1]
+			if esc == 'u' && l.pos+6 <= len(l.src) {
+				n, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(l.line, l.col, "invalid unicode escape")
+				}
+				b.WriteRune(rune(n))
+				l.advance(6)
+				continue
+			}
+			decoded, ok := map[byte]byte{'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}[esc]
+			if !ok {
+				return token{}, l.errorf(l.line, l.col, "invalid escape sequence \\%c", esc)
+			}
+			b.WriteByte(decoded)
+			l.advance(2)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return token{}, l.errorf(line, col, "unterminated string")
+}
+
+func (l *lexer) advance(n int) {
+	l.pos += n
+	l.col += n
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// parser builds a document from the tokens of the lexer, one token ahead
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parse parses an executable GraphQL document
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"), p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[f.name]; dup {
+				return nil, fmt.Errorf("there can be only one fragment named %q", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("the document contains no operation")
+	}
+	for _, f := range doc.fragments {
+		if err := doc.checkCycles(f, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// checkCycles fails when a fragment spreads itself, directly or through other
+// fragments at any depth
+func (doc *document) checkCycles(f *fragment, visiting map[string]bool) error {
+	if visiting[f.name] {
+		return fmt.Errorf("fragment %q spreads itself", f.name)
+	}
+	visiting[f.name] = true
+	defer delete(visiting, f.name)
+
+	var walk func(selections []*selection) error
+	walk = func(selections []*selection) error {
+		for _, sel := range selections {
+			if next, ok := doc.fragments[sel.spread]; ok {
+				if err := doc.checkCycles(next, visiting); err != nil {
+					return err
+				}
+			}
+			if err := walk(sel.selections); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(f.selections)
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.value == name
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return p.lex.errorf(p.tok.line, p.tok.col, "unexpected end of document")
+	}
+	return p.lex.errorf(p.tok.line, p.tok.col, "unexpected %q", p.tok.value)
+}
+
+// expect consumes the punctuator, or fails
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// name consumes a name
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: "query"}
+	if !p.peek("{") {
+		op.kind = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName {
+			op.name = p.tok.value
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek("(") {
+			vars, err := p.variableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = vars
+		}
+		if p.peek("@") {
+			return nil, p.lex.errorf(p.tok.line, p.tok.col, "directives on operations are not supported")
+		}
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*variableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var vars []*variableDefinition
+	for !p.peek(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		v := &variableDefinition{name: name, typ: typ}
+		if p.peek("=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if v.defaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+			v.hasDefault = true
+		}
+		vars = append(vars, v)
+	}
+	return vars, p.advance()
+}
+
+// typeRef reads a type such as String!, [ID] or [User!]!
+func (p *parser) typeRef() (string, error) {
+	var typ string
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.peek("!") {
+		typ += "!"
+		return typ, p.advance()
+	}
+	return typ, nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, p.lex.errorf(p.tok.line, p.tok.col, "a fragment cannot be named \"on\"")
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	typeCondition, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, typeCondition: typeCondition, selections: selections}, nil
+}
+
+func (p *parser) selectionSet() ([]*selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*selection
+	for !p.peek("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, p.lex.errorf(p.tok.line, p.tok.col, "empty selection set")
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) selection() (*selection, error) {
+	sel := &selection{line: p.tok.line, col: p.tok.col}
+	var err error
+
+	if p.peek("...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName && !p.peekName("on") {
+			sel.spread = p.tok.value
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		if p.peekName("on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if sel.typeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		sel.selections, err = p.selectionSet()
+		return sel, err
+	}
+
+	if sel.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		sel.alias = sel.name
+		if sel.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if sel.arguments, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if sel.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []*argument
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &argument{name: name, value: value})
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d := &directive{name: name}
+		if p.peek("(") {
+			if d.arguments, err = p.arguments(false); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// value reads an argument value; constant values cannot refer to variables
+func (p *parser) value(constant bool) (any, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, p.lex.errorf(tok.line, tok.col, "invalid integer %s", tok.value)
+		}
+		return n, p.advance()
+	case tokenFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.lex.errorf(tok.line, tok.col, "invalid number %s", tok.value)
+		}
+		return f, p.advance()
+	case tokenString:
+		return tok.value, p.advance()
+	case tokenName:
+		var v any
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = enumValue(tok.value)
+		}
+		return v, p.advance()
+	}
+
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []any{}
+		for !p.peek("]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, p.advance()
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		obj := map[string]any{}
+		for !p.peek("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.advance()
+	}
+	return nil, p.unexpected()
+}
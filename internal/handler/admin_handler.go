@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	authService *service.AuthService
+	oidcService *service.OIDCService // nil when the OpenID Provider is disabled
+	auditLogger audit.Logger
+}
+
+func NewAdminHandler(authService *service.AuthService, oidcService *service.OIDCService, auditLogger audit.Logger) *AdminHandler {
+	return &AdminHandler{
+		authService: authService,
+		oidcService: oidcService,
+		auditLogger: auditLogger,
+	}
+}
+
+type CreateClientRequest struct {
+	Name         string   `json:"name" validate:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Public       bool     `json:"public"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+}
+
+type CreateClientResponse struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Public       bool     `json:"public"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+}
+
+type SetCanaryRequest struct {
+	Canary bool `json:"canary"`
+}
+
+// SetCanary marks or unmarks the user in the URL as a canary account
+func (h *AdminHandler) SetCanary(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid user ID")
+		return
+	}
+
+	var req SetCanaryRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.authService.SetCanary(r.Context(), userID, req.Canary); err != nil {
+		if err == repository.ErrUserNotFound {
+			problem.Error(w, r, http.StatusNotFound, problem.NotFound, "User not found")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      "admin.canary_updated",
+		Severity:  audit.SeverityInfo,
+		IPAddress: clientIP(r),
+		Details:   map[string]any{"user_id": userID, "canary": req.Canary},
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": userID, "canary": req.Canary})
+}
+
+// CreateClient registers an OAuth client for the OpenID Provider. The secret
+// of a confidential client is only returned in this response.
+func (h *AdminHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	if h.oidcService == nil {
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "OpenID Provider is not enabled")
+		return
+	}
+
+	var req CreateClientRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	client, secret, err := h.oidcService.RegisterClient(r.Context(), &service.ClientRegistration{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Public:       req.Public,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+	})
+	if err != nil {
+		if err == service.ErrInvalidRegistration {
+			problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, "Authorization code clients need redirect URIs and client credentials clients cannot be public")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      "admin.client_created",
+		Severity:  audit.SeverityInfo,
+		IPAddress: clientIP(r),
+		Details:   map[string]any{"client_id": client.ClientID, "name": client.Name},
+	})
+
+	writeJSON(w, http.StatusCreated, CreateClientResponse{
+		ClientID:     client.ClientID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Public:       client.IsPublic,
+		GrantTypes:   client.GrantTypes,
+		Scopes:       client.Scopes,
+	})
+}
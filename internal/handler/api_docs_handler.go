@@ -0,0 +1,68 @@
+package handler
+
+import "net/http"
+
+// swaggerUIVersion pins the Swagger UI release the docs page loads
+const swaggerUIVersion = "5.17.14"
+
+// apiDocsPolicy lets the docs page load Swagger UI from unpkg and fetch the
+// document, replacing the default policy that allows nothing. Swagger UI sets
+// inline styles.
+const apiDocsPolicy = "default-src 'none'; script-src 'self' https://unpkg.com; style-src 'unsafe-inline' https://unpkg.com; " +
+	"img-src data: https://unpkg.com; connect-src 'self'; frame-ancestors 'none'; base-uri 'none'"
+
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>go-auth-service API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js"></script>
+<script src="/docs/init.js"></script>
+</body>
+</html>
+`
+
+// The page may not run inline scripts, so Swagger UI is started from a file
+const apiDocsScript = `window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+`
+
+// APIDocsHandler serves the OpenAPI document of the service and, optionally,
+// Swagger UI for it
+type APIDocsHandler struct {
+	document []byte
+}
+
+// NewAPIDocsHandler serves the document given to SetDocument
+func NewAPIDocsHandler() *APIDocsHandler {
+	return &APIDocsHandler{}
+}
+
+// SetDocument sets the encoded OpenAPI document. Routes describe themselves
+// only once registered, so it is set after the handler is routed, and must be
+// before serving.
+func (h *APIDocsHandler) SetDocument(document []byte) {
+	h.document = document
+}
+
+// Document returns the OpenAPI document
+func (h *APIDocsHandler) Document(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.document)
+}
+
+// UI returns a page rendering the document with Swagger UI
+func (h *APIDocsHandler) UI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", apiDocsPolicy)
+	w.Write([]byte(apiDocsPage))
+}
+
+// Script starts Swagger UI on the page from UI
+func (h *APIDocsHandler) Script(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Write([]byte(apiDocsScript))
+}
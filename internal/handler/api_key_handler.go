@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	authService   *service.AuthService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, authService *service.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		authService:   authService,
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type CreateAPIKeyResponse struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	Key    string `json:"key"`
+}
+
+// Create issues a new API key. The key is only returned in this response.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	key, plaintext, err := h.apiKeyService.CreateAPIKey(r.Context(), userID, req.Name)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+		ID:     key.ID,
+		Name:   key.Name,
+		Prefix: key.Prefix,
+		Key:    plaintext,
+	})
+}
+
+// List returns the authenticated user's active API keys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"api_keys": keys})
+}
+
+// Revoke revokes the API key in the URL
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		if err == repository.ErrAPIKeyNotFound {
+			problem.Error(w, r, http.StatusNotFound, problem.NotFound, "API key not found")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "API key revoked"})
+}
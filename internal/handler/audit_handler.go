@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+// AuditHandler serves stored audit events to admins
+type AuditHandler struct {
+	auditRepo interfaces.AuditRepository
+}
+
+func NewAuditHandler(auditRepo interfaces.AuditRepository) *AuditHandler {
+	return &AuditHandler{auditRepo: auditRepo}
+}
+
+// List returns audit events newest first, optionally filtered by actor_id and
+// type (repeatable), paged with limit and cursor
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pagination.FromQuery(q)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid page")
+		return
+	}
+
+	filter := audit.Filter{Types: q["type"], Page: page}
+	if v := q.Get("actor_id"); v != "" {
+		if filter.ActorID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid actor ID")
+			return
+		}
+	}
+
+	events, next, err := h.auditRepo.ListEvents(r.Context(), filter)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	if events == nil {
+		events = []audit.Event{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": events, "next_cursor": next})
+}
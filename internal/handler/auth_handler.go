@@ -1,145 +1,433 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
-	"regexp"
 	"strings"
 
+	"github.com/Stewz00/go-auth-service/internal/device"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/problem"
 	"github.com/Stewz00/go-auth-service/internal/repository"
 	"github.com/Stewz00/go-auth-service/internal/service"
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService    *service.AuthService
+	consentService *service.ConsentService
+	canary         *CanaryTripwire
+	captcha        *service.CaptchaService // nil never demands a CAPTCHA
+	geo            *service.GeoPolicy      // nil allows every country
+
+	csrf             *middleware.CSRF // nil disables session cookies
+	cookiesByDefault bool
+}
+
+// AuthHandlerOption configures optional AuthHandler dependencies
+type AuthHandlerOption func(*AuthHandler)
+
+// WithConsentService records consent receipts submitted at registration
+func WithConsentService(consentService *service.ConsentService) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.consentService = consentService
+	}
+}
+
+// WithCanaryTripwire alerts on sign-in attempts against canary accounts
+func WithCanaryTripwire(canary *CanaryTripwire) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.canary = canary
+	}
+}
+
+// WithCaptcha demands a solved CAPTCHA at login and registration from addresses
+// with recent failed sign-ins
+func WithCaptcha(captcha *service.CaptchaService) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.captcha = captcha
+	}
+}
+
+// WithGeoPolicy blocks or challenges login and registration by the country of
+// the client address. Challenges need WithCaptcha.
+func WithGeoPolicy(geo *service.GeoPolicy) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.geo = geo
+	}
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{
+func NewAuthHandler(authService *service.AuthService, opts ...AuthHandlerOption) *AuthHandler {
+	h := &AuthHandler{
 		authService: authService,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"` // checked against the password policy
+
+	// Optional profile details, see ProfileFields
+	ProfileFields
+
+	// Optional consents captured on the sign-up form
+	TosVersion           string `json:"tos_version,omitempty"`
+	PrivacyPolicyVersion string `json:"privacy_policy_version,omitempty"`
+	MarketingOptIn       *bool  `json:"marketing_opt_in,omitempty"`
+
+	// Required when an earlier attempt failed with the CAPTCHA_REQUIRED code
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Scope    string `json:"scope,omitempty"` // space-separated; defaults to every user scope
+
+	// Required when an earlier attempt failed with the CAPTCHA_REQUIRED code
+	CaptchaToken string `json:"captcha_token,omitempty"`
+
+	// An opaque fingerprint of the client's device, taking the place of the
+	// X-Device-Fingerprint header, that the session is bound to
+	DeviceFingerprint string `json:"device_fingerprint,omitempty"`
+
+	// Keep the user signed in beyond the browser session
+	RememberMe bool `json:"remember_me,omitempty"`
 }
 
 type AuthResponse struct {
 	Token string `json:"token,omitempty"`
-	Error string `json:"error,omitempty"`
+
+	// Set instead of Token when the token was delivered in the session cookie
+	CSRFToken string `json:"csrf_token,omitempty"`
+
+	// Set when the token may only be used to change the expired password
+	PasswordExpired bool `json:"password_expired,omitempty"`
+
+	// Set when the user has yet to accept the current terms, see GET /auth/me/terms
+	TermsAcceptanceRequired bool `json:"terms_acceptance_required,omitempty"`
 }
 
-// Validate checks if an email is valid
-func isValidEmail(email string) bool {
-	// Simple regex for email validation
-	emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	re := regexp.MustCompile(emailRegex)
-	return re.MatchString(email)
+// ChangePasswordRequest is the body of a change-password request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
 
 // Register handles user registration
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	// Basic validation
-	if req.Email == "" || req.Password == "" {
-		sendJSONError(w, "Email and password are required", http.StatusBadRequest)
+	if !h.checkClient(w, r, service.GeoActionRegister, req.CaptchaToken) {
 		return
 	}
 
-	if !isValidEmail(req.Email) {
-		sendJSONError(w, "Invalid email format", http.StatusBadRequest)
+	var profile model.Profile
+	req.ProfileFields.apply(&profile)
+	user, err := h.authService.RegisterUserWithProfile(r.Context(), req.Email, req.Password, profile)
+	if sendPasswordViolations(w, r, err) {
 		return
 	}
-
-	if len(req.Password) < 8 {
-		sendJSONError(w, "Password must be at least 8 characters long", http.StatusBadRequest)
+	switch err {
+	case nil:
+	case service.ErrInvalidCredentials:
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, err.Error())
+		return
+	case repository.ErrDuplicateEmail:
+		problem.Error(w, r, http.StatusConflict, problem.EmailTaken, "Email is already registered")
+		return
+	case service.ErrEmailDomainBlocked:
+		problem.Error(w, r, http.StatusForbidden, problem.EmailDomainBlocked, "Registrations from this email domain are not allowed")
+		return
+	case service.ErrBreachCheckFailed:
+		problem.Error(w, r, http.StatusServiceUnavailable, problem.ServiceUnavailable, err.Error())
+		return
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
 		return
 	}
 
-	user, err := h.authService.RegisterUser(r.Context(), req.Email, req.Password)
-	if err != nil {
-		code := http.StatusInternalServerError
-		if err == service.ErrInvalidCredentials {
-			code = http.StatusBadRequest
-		}
-		sendJSONError(w, err.Error(), code)
+	h.recordRegistrationConsents(r, user.ID, &req)
+
+	writeJSON(w, http.StatusCreated, map[string]string{"message": "User registered successfully", "email": user.Email})
+}
+
+// recordRegistrationConsents stores receipts for consents given on the sign-up form.
+// Failures are logged rather than failing a registration that already succeeded.
+func (h *AuthHandler) recordRegistrationConsents(r *http.Request, userID int64, req *RegisterRequest) {
+	if h.consentService == nil {
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully", "email": user.Email})
+	var receipts []*model.ConsentReceipt
+	if req.TosVersion != "" {
+		receipts = append(receipts, &model.ConsentReceipt{Purpose: model.ConsentTermsOfService, Version: req.TosVersion, Granted: true})
+	}
+	if req.PrivacyPolicyVersion != "" {
+		receipts = append(receipts, &model.ConsentReceipt{Purpose: model.ConsentPrivacyPolicy, Version: req.PrivacyPolicyVersion, Granted: true})
+	}
+	if req.MarketingOptIn != nil {
+		receipts = append(receipts, &model.ConsentReceipt{Purpose: model.ConsentMarketing, Granted: *req.MarketingOptIn})
+	}
+
+	for _, receipt := range receipts {
+		receipt.UserID = userID
+		receipt.IPAddress = clientIP(r)
+		receipt.UserAgent = r.UserAgent()
+		if err := h.consentService.RecordConsent(r.Context(), receipt); err != nil {
+			slog.ErrorContext(r.Context(), "failed to record consent", "purpose", receipt.Purpose, "user_id", userID, "err", err)
+		}
+	}
 }
 
-// Login handles user authentication and returns a JWT token
+// Login handles user authentication and returns a JWT token, or sets it in the
+// session cookie when the client asks for one
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	token, err := h.authService.LoginUser(r.Context(), req.Email, req.Password)
+	if !h.checkClient(w, r, service.GeoActionLogin, req.CaptchaToken) {
+		return
+	}
+
+	ctx := service.ContextWithClientIP(r.Context(), clientIP(r))
+	if req.DeviceFingerprint != "" {
+		ctx = device.WithFingerprint(ctx, req.DeviceFingerprint)
+	}
+	result, err := h.authService.Login(ctx, req.Email, req.Password, req.Scope, req.RememberMe)
 	if err != nil {
 		switch err {
+		case service.ErrInvalidUserScope:
+			problem.Error(w, r, http.StatusBadRequest, problem.ScopeNotAllowed, "Requested scope is not allowed")
+			return
 		case service.ErrInvalidCredentials:
-			sendJSONError(w, "Invalid email or password", http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, problem.InvalidCredentials, "Invalid email or password")
+			return
+		case service.ErrCanaryAccount:
+			// Respond exactly like a wrong password so the attacker is not tipped off
+			h.canary.trip(r, req.Email)
+			problem.Error(w, r, http.StatusUnauthorized, problem.InvalidCredentials, "Invalid email or password")
 			return
 		case service.ErrAccountLocked, repository.ErrTooManyAttempts:
-			sendJSONError(w, "Account is locked due to too many failed attempts", http.StatusForbidden)
+			problem.Error(w, r, http.StatusForbidden, problem.AccountLocked, "Account is locked due to too many failed attempts")
+			return
+		case service.ErrTenantSuspended:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountSuspended, "Account is suspended")
+			return
+		case service.ErrAccountDisabled:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountDisabled, "Account is disabled")
+			return
+		case service.ErrLoginThrottled:
+			problem.Error(w, r, http.StatusTooManyRequests, problem.LoginThrottled, "Too many failed sign-in attempts, try again later")
 			return
 		default:
-			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+			return
+		}
+	}
+
+	h.sendToken(w, r, result.Token, result.RememberMe, AuthResponse{
+		PasswordExpired:         result.PasswordExpired,
+		TermsAcceptanceRequired: h.termsAcceptanceRequired(r, result.UserID),
+	})
+}
+
+// termsAcceptanceRequired reports whether the user must accept the current
+// terms. Failures are logged rather than failing the sign-in.
+func (h *AuthHandler) termsAcceptanceRequired(r *http.Request, userID int64) bool {
+	if h.consentService == nil {
+		return false
+	}
+	required, err := h.consentService.TermsAcceptanceRequired(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to check terms acceptance", "user_id", userID, "err", err)
+	}
+	return required
+}
+
+// sendToken responds with a token issued to the user and the flags in resp,
+// or sets it in the session cookie when the client asks for one. Only
+// remembered sessions outlive the browser session.
+func (h *AuthHandler) sendToken(w http.ResponseWriter, r *http.Request, token string, remember bool, resp AuthResponse) {
+	if h.wantsCookie(r) {
+		csrfToken, err := h.startCookieSession(w, token, remember)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to start cookie session", "err", err)
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
 			return
 		}
+		resp.CSRFToken = csrfToken
+		writeJSON(w, http.StatusOK, resp)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(AuthResponse{Token: token})
+	resp.Token = token
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ChangePassword replaces the password of the user authenticated by
+// middleware.Authenticate, which also accepts tokens for an expired password.
+// The user's other sessions are revoked and a new token is returned.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "No token provided")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	token, err := h.authService.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword)
+	if sendPasswordViolations(w, r, err) {
+		return
+	}
+	switch err {
+	case nil:
+	case service.ErrInvalidCredentials:
+		problem.Error(w, r, http.StatusUnauthorized, problem.InvalidCredentials, "Current password is incorrect")
+		return
+	case service.ErrInvalidToken:
+		problem.Error(w, r, http.StatusUnauthorized, problem.InvalidToken, err.Error())
+		return
+	case service.ErrBreachCheckFailed:
+		problem.Error(w, r, http.StatusServiceUnavailable, problem.ServiceUnavailable, err.Error())
+		return
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	h.sendToken(w, r, token, false, AuthResponse{})
+}
+
+// admit checks whether the client at ip may take action (login or register):
+// clients in blocked countries are refused, and those in challenged countries
+// or at suspicious addresses must solve a CAPTCHA
+func (h *AuthHandler) admit(ctx context.Context, ip, action, captchaToken string) error {
+	switch h.geo.Check(ctx, ip, action) {
+	case service.GeoBlock:
+		return service.ErrCountryBlocked
+	case service.GeoChallenge:
+		return h.captcha.Verify(ctx, ip, captchaToken)
+	}
+	return h.captcha.Check(ctx, ip, captchaToken)
 }
 
-// Logout handles user logout by revoking the JWT token
+// checkClient admits the client of r to take action, writing the error
+// response and returning false when it is not
+func (h *AuthHandler) checkClient(w http.ResponseWriter, r *http.Request, action, captchaToken string) bool {
+	err := h.admit(r.Context(), clientIP(r), action, captchaToken)
+	switch err {
+	case nil:
+		return true
+	case service.ErrCountryBlocked:
+		problem.Error(w, r, http.StatusForbidden, problem.CountryBlocked, "Sign-in is not available in your country")
+	case service.ErrCaptchaRequired, service.ErrCaptchaFailed:
+		problem.Error(w, r, http.StatusForbidden, problem.CaptchaRequired, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "CAPTCHA verification unavailable", "err", err)
+		problem.Error(w, r, http.StatusServiceUnavailable, problem.ServiceUnavailable, "CAPTCHA verification unavailable")
+	}
+	return false
+}
+
+// Logout handles user logout by revoking the JWT token validated by
+// middleware.Authenticate
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	token := extractToken(r)
-	if token == "" {
-		sendJSONError(w, "No token provided", http.StatusUnauthorized)
+	token, ok := middleware.TokenFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "No token provided")
 		return
 	}
 
-	if err := h.authService.LogoutUser(r.Context(), token); err != nil {
-		code := http.StatusInternalServerError
-		if err == service.ErrInvalidToken {
-			code = http.StatusUnauthorized
-		}
-		sendJSONError(w, err.Error(), code)
+	switch err := h.authService.LogoutUser(r.Context(), token); err {
+	case nil:
+	case service.ErrInvalidToken:
+		problem.Error(w, r, http.StatusUnauthorized, problem.InvalidToken, err.Error())
+		return
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+	endCookieSession(w, r)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
 }
 
 // Helper function to extract JWT token from Authorization header
 func extractToken(r *http.Request) string {
-	bearerToken := r.Header.Get("Authorization")
-	if len(strings.Split(bearerToken, " ")) == 2 {
-		return strings.Split(bearerToken, " ")[1]
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || scheme == "" || strings.Contains(token, " ") {
+		return ""
+	}
+	return token
+}
+
+// Helper function to authenticate a request by its Bearer token, returning the user ID.
+// Requests already authenticated by middleware are accepted.
+func authenticateRequest(authService *service.AuthService, r *http.Request) (int64, error) {
+	if userID, ok := UserFromContext(r.Context()); ok {
+		return userID, nil
+	}
+
+	token := extractToken(r)
+	if token == "" {
+		return 0, service.ErrInvalidToken
+	}
+
+	claims, err := authService.ValidateToken(r.Context(), token)
+	if err != nil {
+		return 0, err
 	}
-	return ""
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, service.ErrInvalidToken
+	}
+	return int64(sub), nil
 }
 
-// Helper function to send JSON error responses
-func sendJSONError(w http.ResponseWriter, message string, code int) {
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(AuthResponse{Error: message})
+// Helper function to get the client IP without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// passwordPolicyProblem is the response to a password the policy rejects
+type passwordPolicyProblem struct {
+	problem.Problem
+	Violations password.Violations `json:"violations"`
+}
+
+// sendPasswordViolations responds with 400 and every broken rule when err is
+// password.Violations, and reports whether it did
+func sendPasswordViolations(w http.ResponseWriter, r *http.Request, err error) bool {
+	var violations password.Violations
+	if !errors.As(err, &violations) {
+		return false
+	}
+	problem.Write(w, r, http.StatusBadRequest, passwordPolicyProblem{
+		Problem:    problem.New(r, http.StatusBadRequest, problem.PasswordRejected, "Password does not meet the requirements"),
+		Violations: violations,
+	})
+	return true
 }
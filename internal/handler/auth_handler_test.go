@@ -2,18 +2,26 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Stewz00/go-auth-service/internal/captcha"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/problem"
 	"github.com/Stewz00/go-auth-service/internal/service"
 	"github.com/Stewz00/go-auth-service/internal/test"
 )
 
 func TestAuthHandler_Register(t *testing.T) {
 	mockRepo := test.NewMockUserRepository()
-	authService := service.NewAuthService(mockRepo, "test-secret")
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
 	handler := NewAuthHandler(authService)
 
 	tests := []struct {
@@ -40,6 +48,15 @@ func TestAuthHandler_Register(t *testing.T) {
 			wantStatusCode: http.StatusBadRequest,
 			wantErr:        true,
 		},
+		{
+			name: "short password",
+			requestBody: map[string]string{
+				"email":    "test@example.com",
+				"password": "short",
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        true,
+		},
 		// TODO: Add more test cases
 	}
 
@@ -56,12 +73,12 @@ func TestAuthHandler_Register(t *testing.T) {
 				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
 			}
 
-			var response map[string]string
+			var response map[string]any
 			json.NewDecoder(w.Body).Decode(&response)
 
 			if tt.wantErr {
-				if response["error"] == "" {
-					t.Error("expected error message but got none")
+				if response["code"] == nil || response["detail"] == nil {
+					t.Errorf("got %v, want a problem with a code and detail", response)
 				}
 			} else {
 				if response["email"] != tt.requestBody["email"] {
@@ -72,4 +89,348 @@ func TestAuthHandler_Register(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_RegisterPasswordPolicy(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	policy := password.Policy{MinLength: 10, RequireDigit: true, DenyList: password.CommonPasswords(), DisallowEmail: true}
+	handler := NewAuthHandler(service.NewAuthService(mockRepo, mockRepo, "test-secret", service.WithPasswordPolicy(policy)))
+
+	body := strings.NewReader(`{"email": "jane@example.com", "password": "jane-password"}`)
+	w := httptest.NewRecorder()
+	handler.Register(w, httptest.NewRequest("POST", "/auth/register", body))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %v, want %v", w.Code, http.StatusBadRequest)
+	}
+	if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("got content type %q, want %q", got, problem.ContentType)
+	}
+	var response passwordPolicyProblem
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != problem.PasswordRejected || response.Status != http.StatusBadRequest {
+		t.Errorf("got code %s and status %d, want %s and 400", response.Code, response.Status, problem.PasswordRejected)
+	}
+	var codes []string
+	for _, v := range response.Violations {
+		codes = append(codes, v.Code)
+	}
+	if got := strings.Join(codes, ","); got != "missing_digit,contains_email" {
+		t.Errorf("violations = %s, want missing_digit,contains_email", got)
+	}
+}
+
+func TestAuthHandler_RegisterBlockedDomain(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	handler := NewAuthHandler(service.NewAuthService(mockRepo, mockRepo, "test-secret",
+		service.WithEmailDomainPolicy(service.NewEmailDomainPolicy(nil, []string{"spam.example"}, true))))
+
+	tests := []struct {
+		email    string
+		wantCode int
+	}{
+		{email: "jane@spam.example", wantCode: http.StatusForbidden},
+		{email: "jane@yopmail.com", wantCode: http.StatusForbidden},
+		{email: "jane@example.com", wantCode: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			body := strings.NewReader(`{"email": "` + tt.email + `", "password": "password123"}`)
+			w := httptest.NewRecorder()
+			handler.Register(w, httptest.NewRequest("POST", "/auth/register", body))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("got status %v, want %v", w.Code, tt.wantCode)
+			}
+			if w.Code == http.StatusForbidden {
+				var response problem.Problem
+				json.NewDecoder(w.Body).Decode(&response)
+				if response.Code != problem.EmailDomainBlocked {
+					t.Errorf("got code %q, want %q", response.Code, problem.EmailDomainBlocked)
+				}
+			}
+		})
+	}
+}
+
+// countryOf places every client in one country
+type countryOf string
+
+func (c countryOf) Country(ip string) (string, error) { return string(c), nil }
+
+// rejectToken fails every CAPTCHA
+type rejectToken struct{}
+
+func (rejectToken) Verify(ctx context.Context, token, remoteIP string) error {
+	return captcha.ErrVerificationFailed
+}
+
+func TestAuthHandler_GeoPolicy(t *testing.T) {
+	tests := []struct {
+		country  string
+		wantCode problem.Code
+	}{
+		{country: "KP", wantCode: problem.CountryBlocked},
+		{country: "RU", wantCode: problem.CaptchaRequired},
+		{country: "DE", wantCode: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			mockRepo := test.NewMockUserRepository()
+			handler := NewAuthHandler(service.NewAuthService(mockRepo, mockRepo, "test-secret"),
+				WithCaptcha(service.NewCaptchaService(rejectToken{}, nil, 0, nil)),
+				WithGeoPolicy(service.NewGeoPolicy(countryOf(tt.country), []string{"KP"}, []string{"RU"}, nil)))
+
+			body := strings.NewReader(`{"email": "jane@example.com", "password": "password123"}`)
+			w := httptest.NewRecorder()
+			handler.Register(w, httptest.NewRequest("POST", "/auth/register", body))
+
+			var response problem.Problem
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Code != tt.wantCode {
+				t.Errorf("got status %d and code %q, want code %q", w.Code, response.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
 // TODO: Add tests for Login and Logout handlers
+
+func TestAuthHandler_LoginCookieMode(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
+	csrf := middleware.NewCSRF("test-secret")
+	handler := NewAuthHandler(authService, WithSessionCookies(csrf, false))
+	if _, err := authService.RegisterUser(context.Background(), "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	login := func(acceptAuth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+		if acceptAuth != "" {
+			req.Header.Set(AcceptAuthHeader, acceptAuth)
+		}
+		w := httptest.NewRecorder()
+		handler.Login(w, req)
+		return w
+	}
+
+	var tokenResponse AuthResponse
+	json.NewDecoder(login("").Body).Decode(&tokenResponse)
+	if tokenResponse.Token == "" {
+		t.Error("expected the token in the body by default")
+	}
+
+	w := login("cookie")
+	var cookieResponse AuthResponse
+	json.NewDecoder(w.Body).Decode(&cookieResponse)
+	if cookieResponse.Token != "" || cookieResponse.CSRFToken == "" {
+		t.Fatalf("got %+v, want only a CSRF token in the body", cookieResponse)
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	session := cookies[middleware.SessionCookieName]
+	if session == nil || !session.HttpOnly || !session.Secure || session.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("got session cookie %+v, want a Secure, HttpOnly, SameSite cookie", session)
+	}
+	if session.MaxAge != 0 {
+		t.Errorf("got session cookie max age %d, want a browser-session cookie", session.MaxAge)
+	}
+
+	// Remembered sessions keep the cookie until the token expires
+	req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123","remember_me":true}`))
+	req.Header.Set(AcceptAuthHeader, "cookie")
+	remembered := httptest.NewRecorder()
+	handler.Login(remembered, req)
+	for _, cookie := range remembered.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName && cookie.MaxAge != int(service.DefaultRememberMeExpiry/time.Second) {
+			t.Errorf("got remembered session cookie max age %d, want %d", cookie.MaxAge, int(service.DefaultRememberMeExpiry/time.Second))
+		}
+	}
+
+	// Logging out with the cookie, through the CSRF check, clears it
+	logout := csrf.Middleware(middleware.Authenticate(authService)(http.HandlerFunc(handler.Logout)))
+	req = httptest.NewRequest("POST", "/auth/logout", nil)
+	req.AddCookie(session)
+	req.AddCookie(cookies[middleware.CSRFCookieName])
+	req.Header.Set(middleware.CSRFHeaderName, cookieResponse.CSRFToken)
+	w = httptest.NewRecorder()
+	logout.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logout: got status %v, want %v", w.Code, http.StatusOK)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == middleware.SessionCookieName && cookie.MaxAge >= 0 {
+			t.Error("expected the session cookie to be cleared")
+		}
+	}
+}
+
+func TestAuthHandler_ChangeExpiredPassword(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
+	handler := NewAuthHandler(authService)
+	user, err := authService.RegisterUser(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	if err := mockRepo.ExpirePassword(context.Background(), user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123"}`)))
+	var login AuthResponse
+	json.NewDecoder(w.Body).Decode(&login)
+	if w.Code != http.StatusOK || !login.PasswordExpired || login.Token == "" {
+		t.Fatalf("login: got %v %+v, want a token with password_expired", w.Code, login)
+	}
+
+	// The token is refused everywhere but the change-password endpoint
+	protected := middleware.Authenticate(authService)(http.HandlerFunc(handler.Logout))
+	change := middleware.Authenticate(authService.PasswordChangeTokens())(http.HandlerFunc(handler.ChangePassword))
+	request := func(h http.Handler, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+login.Token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	if w := request(protected, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("protected endpoint: got status %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"wrong current password", `{"current_password":"wrongpassword","new_password":"newpassword456"}`, http.StatusUnauthorized},
+		{"reused password", `{"current_password":"password123","new_password":"password123"}`, http.StatusBadRequest},
+		{"changed", `{"current_password":"password123","new_password":"newpassword456"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(change, tt.body); w.Code != tt.want {
+				t.Errorf("got status %v, want %v: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_TermsAcceptance(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
+	consentService := service.NewConsentService(test.NewMockConsentRepository(), service.WithTermsVersions(service.TermsVersions{TermsOfService: "2026-01"}))
+	handler := NewAuthHandler(authService, WithConsentService(consentService))
+	consents := NewConsentHandler(consentService, authService)
+
+	w := httptest.NewRecorder()
+	handler.Register(w, httptest.NewRequest("POST", "/auth/register", strings.NewReader(`{"email":"test@example.com","password":"password123","tos_version":"2026-01"}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: got status %v, want %v", w.Code, http.StatusCreated)
+	}
+
+	login := func() AuthResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.Login(w, httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123"}`)))
+		var resp AuthResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: got status %v, want %v", w.Code, http.StatusOK)
+		}
+		return resp
+	}
+	if login().TermsAcceptanceRequired {
+		t.Error("expected no acceptance required for the version accepted at registration")
+	}
+
+	consentService.SetTermsVersions(service.TermsVersions{TermsOfService: "2026-10"})
+	resp := login()
+	if !resp.TermsAcceptanceRequired {
+		t.Fatal("expected acceptance required after the terms changed")
+	}
+
+	accept := func(body string) int {
+		req := httptest.NewRequest("POST", "/auth/me/terms", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		w := httptest.NewRecorder()
+		consents.AcceptTerms(w, req)
+		return w.Code
+	}
+	if code := accept(`{"tos_version":"2026-01"}`); code != http.StatusConflict {
+		t.Errorf("accepting an old version: got status %v, want %v", code, http.StatusConflict)
+	}
+	if code := accept(`{"tos_version":"2026-10"}`); code != http.StatusCreated {
+		t.Fatalf("accepting the current version: got status %v, want %v", code, http.StatusCreated)
+	}
+	if login().TermsAcceptanceRequired {
+		t.Error("expected no acceptance required after accepting the current terms")
+	}
+}
+
+func TestAuthHandler_Profile(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
+	handler := NewAuthHandler(authService)
+	profiles := NewProfileHandler(service.NewProfileService(mockRepo), authService)
+
+	w := httptest.NewRecorder()
+	handler.Register(w, httptest.NewRequest("POST", "/auth/register", strings.NewReader(
+		`{"email":"test@example.com","password":"password123","display_name":"Ann","locale":"de-CH"}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: got status %v, want %v", w.Code, http.StatusCreated)
+	}
+	w = httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123"}`)))
+	var auth AuthResponse
+	json.NewDecoder(w.Body).Decode(&auth)
+
+	update := func(body string) (int, ProfileResponse) {
+		req := httptest.NewRequest("PATCH", "/auth/me", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		w := httptest.NewRecorder()
+		profiles.UpdateMe(w, req)
+		var resp ProfileResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+		wantProfile    model.Profile
+	}{
+		{
+			name:           "set fields",
+			body:           `{"timezone":"Europe/Zurich","avatar_url":"https://example.com/ann.png"}`,
+			wantStatusCode: http.StatusOK,
+			wantProfile:    model.Profile{DisplayName: "Ann", Locale: "de-CH", Timezone: "Europe/Zurich", AvatarURL: "https://example.com/ann.png"},
+		},
+		{
+			name:           "clear a field",
+			body:           `{"display_name":""}`,
+			wantStatusCode: http.StatusOK,
+			wantProfile:    model.Profile{Locale: "de-CH", Timezone: "Europe/Zurich", AvatarURL: "https://example.com/ann.png"},
+		},
+		{name: "invalid timezone", body: `{"timezone":"Mars/Olympus"}`, wantStatusCode: http.StatusBadRequest},
+		{name: "invalid avatar URL", body: `{"avatar_url":"javascript:alert(1)"}`, wantStatusCode: http.StatusBadRequest},
+		{name: "app_metadata", body: `{"app_metadata":{"plan":"pro"}}`, wantStatusCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := update(tt.body)
+			if code != tt.wantStatusCode {
+				t.Fatalf("got status %v, want %v", code, tt.wantStatusCode)
+			}
+			if code == http.StatusOK && resp.Profile != tt.wantProfile {
+				t.Errorf("got profile %+v, want %+v", resp.Profile, tt.wantProfile)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+type BreakGlassHandler struct {
+	breakGlassService *service.BreakGlassService
+}
+
+func NewBreakGlassHandler(breakGlassService *service.BreakGlassService) *BreakGlassHandler {
+	return &BreakGlassHandler{
+		breakGlassService: breakGlassService,
+	}
+}
+
+type BreakGlassRequest struct {
+	Credential string `json:"credential" validate:"required"`
+}
+
+type BreakGlassResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Redeem exchanges the sealed break-glass credential for a short-lived admin session token
+func (h *BreakGlassHandler) Redeem(w http.ResponseWriter, r *http.Request) {
+	var req BreakGlassRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	token, expiresAt, err := h.breakGlassService.Redeem(r.Context(), req.Credential, clientIP(r))
+	if err != nil {
+		switch err {
+		case service.ErrInvalidBreakGlassCredential, service.ErrBreakGlassExpired, service.ErrBreakGlassUsed:
+			problem.Error(w, r, http.StatusUnauthorized, problem.InvalidCredentials, err.Error())
+		default:
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, BreakGlassResponse{Token: token, ExpiresAt: expiresAt})
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+)
+
+// CanaryTripwire raises an alert, and optionally bans the client IP, when
+// someone tries to sign in to a canary account
+type CanaryTripwire struct {
+	auditLogger audit.Logger
+	banList     *middleware.IPBanList // nil disables automatic banning
+	banDuration time.Duration
+}
+
+// NewCanaryTripwire creates a tripwire. Pass a nil ban list to only alert.
+func NewCanaryTripwire(auditLogger audit.Logger, banList *middleware.IPBanList, banDuration time.Duration) *CanaryTripwire {
+	return &CanaryTripwire{
+		auditLogger: auditLogger,
+		banList:     banList,
+		banDuration: banDuration,
+	}
+}
+
+// trip records the attempt against the canary account and bans the client if configured
+func (c *CanaryTripwire) trip(r *http.Request, email string) {
+	if c == nil {
+		return
+	}
+
+	ip := clientIP(r)
+	banned := c.banList != nil && c.banDuration > 0
+	if banned {
+		c.banList.Ban(ip, c.banDuration)
+	}
+
+	c.auditLogger.Record(r.Context(), audit.Event{
+		Type:      "auth.canary_triggered",
+		Severity:  audit.SeverityHigh,
+		IPAddress: ip,
+		Details: map[string]any{
+			"email":      email,
+			"path":       r.URL.Path,
+			"user_agent": r.UserAgent(),
+			"ip_banned":  banned,
+		},
+	})
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+type ConsentHandler struct {
+	consentService *service.ConsentService
+	authService    *service.AuthService
+}
+
+func NewConsentHandler(consentService *service.ConsentService, authService *service.AuthService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: consentService,
+		authService:    authService,
+	}
+}
+
+type ConsentRequest struct {
+	Purpose string `json:"purpose" validate:"required"`
+	Version string `json:"version"`
+	Granted bool   `json:"granted"`
+}
+
+// AcceptTermsRequest names the versions of the documents the user was shown
+// and accepted, each of which must be the current one
+type AcceptTermsRequest struct {
+	TosVersion           string `json:"tos_version,omitempty"`
+	PrivacyPolicyVersion string `json:"privacy_policy_version,omitempty"`
+}
+
+// TermsResponse lists the documents users must accept and whether the user
+// has accepted their current versions
+type TermsResponse struct {
+	Terms              []model.TermsStatus `json:"terms"`
+	AcceptanceRequired bool                `json:"acceptance_required"`
+}
+
+// List returns the authenticated user's consent receipts as JSON, or as a CSV
+// download when called with ?format=csv
+func (h *ConsentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	receipts, err := h.consentService.ListConsents(r.Context(), userID)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="consents.csv"`)
+		service.WriteConsentsCSV(w, receipts)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"consents": receipts})
+}
+
+// Record stores a consent change, such as opting in or out of marketing
+func (h *ConsentHandler) Record(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	var req ConsentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// OAuth scope consents are only recorded by the authorize flow
+	if req.Purpose == model.ConsentOAuthScopes {
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, service.ErrInvalidConsent.Error())
+		return
+	}
+
+	receipt := &model.ConsentReceipt{
+		UserID:    userID,
+		Purpose:   req.Purpose,
+		Version:   req.Version,
+		Granted:   req.Granted,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+	switch err := h.consentService.RecordConsent(r.Context(), receipt); err {
+	case nil:
+	case service.ErrInvalidConsent:
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, err.Error())
+		return
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, receipt)
+}
+
+// Terms returns whether the authenticated user accepted the current versions
+// of the terms of service and privacy policy
+func (h *ConsentHandler) Terms(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	statuses, err := h.consentService.TermsStatus(r.Context(), userID)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	resp := TermsResponse{Terms: statuses}
+	for _, status := range statuses {
+		resp.AcceptanceRequired = resp.AcceptanceRequired || !status.UpToDate
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AcceptTerms records the authenticated user accepting the current versions
+// of the terms of service or privacy policy
+func (h *ConsentHandler) AcceptTerms(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	var req AcceptTermsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	receipts, err := h.consentService.AcceptTerms(r.Context(),
+		model.ConsentReceipt{UserID: userID, IPAddress: clientIP(r), UserAgent: r.UserAgent()},
+		service.TermsVersions{TermsOfService: req.TosVersion, PrivacyPolicy: req.PrivacyPolicyVersion})
+	switch err {
+	case nil:
+	case service.ErrInvalidConsent:
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, "A terms version is required")
+		return
+	case service.ErrTermsVersionNotCurrent:
+		problem.Error(w, r, http.StatusConflict, problem.Conflict, "Terms version is not the current one")
+		return
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"consents": receipts})
+}
+
+// Helper function to map token validation errors to responses
+func sendAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case service.ErrInvalidToken:
+		problem.Error(w, r, http.StatusUnauthorized, problem.InvalidToken, err.Error())
+	case service.ErrTokenExpired:
+		problem.Error(w, r, http.StatusUnauthorized, problem.TokenExpired, err.Error())
+	case service.ErrPasswordExpired:
+		problem.Error(w, r, http.StatusForbidden, problem.PasswordExpired, err.Error())
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+	}
+}
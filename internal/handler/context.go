@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UserFromContext returns the ID of the user authenticated by middleware
+// such as middleware.Authenticate or middleware.RouteAuth
+func UserFromContext(ctx context.Context) (int64, bool) {
+	return middleware.UserIDFromContext(ctx)
+}
+
+// ClaimsFromContext returns the claims of the token validated by middleware;
+// requests authenticated without a token, such as with an API key, have none
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	return middleware.ClaimsFromContext(ctx)
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+type CSRFHandler struct {
+	csrf *middleware.CSRF
+}
+
+func NewCSRFHandler(csrf *middleware.CSRF) *CSRFHandler {
+	return &CSRFHandler{csrf: csrf}
+}
+
+// Token issues a CSRF token for single-page apps, which send it back in the
+// X-CSRF-Token header of state-changing requests. The token is bound to the
+// current session, so apps fetch a new one after signing in.
+func (h *CSRFHandler) Token(w http.ResponseWriter, r *http.Request) {
+	token, err := h.csrf.Issue(w, h.csrf.Session(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to issue CSRF token", "err", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"csrf_token": token})
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// EmailDomainHandler serves the admin API for blocking registrations from
+// email domains at runtime
+type EmailDomainHandler struct {
+	policy      *service.EmailDomainPolicy
+	auditLogger audit.Logger
+}
+
+func NewEmailDomainHandler(policy *service.EmailDomainPolicy, auditLogger audit.Logger) *EmailDomainHandler {
+	return &EmailDomainHandler{
+		policy:      policy,
+		auditLogger: auditLogger,
+	}
+}
+
+type BlockDomainRequest struct {
+	Domain string `json:"domain" validate:"required"`
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// List returns the domains blocked at runtime. Domains blocked by
+// configuration are not included.
+func (h *EmailDomainHandler) List(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.policy.BlockedDomains(r.Context())
+	if err != nil {
+		sendEmailDomainError(w, r, err)
+		return
+	}
+
+	if domains == nil {
+		domains = []*model.BlockedDomain{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"domains": domains})
+}
+
+// Block blocks registrations from the domain in the request and its subdomains
+func (h *EmailDomainHandler) Block(w http.ResponseWriter, r *http.Request) {
+	var req BlockDomainRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	domain, err := h.policy.BlockDomain(r.Context(), req.Domain, req.Reason)
+	if err != nil {
+		sendEmailDomainError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.email_domain_blocked", map[string]any{"domain": domain.Domain, "reason": domain.Reason})
+	writeJSON(w, http.StatusCreated, domain)
+}
+
+// Unblock allows registrations from the domain in the URL again
+func (h *EmailDomainHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+	if err := h.policy.UnblockDomain(r.Context(), domain); err != nil {
+		sendEmailDomainError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.email_domain_unblocked", map[string]any{"domain": domain})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Domain unblocked"})
+}
+
+// record emits an audit event for a change to the blocked domains
+func (h *EmailDomainHandler) record(r *http.Request, eventType string, details map[string]any) {
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      eventType,
+		Severity:  audit.SeverityWarning,
+		IPAddress: clientIP(r),
+		Details:   details,
+	})
+}
+
+// sendEmailDomainError maps blocked domain errors to responses
+func sendEmailDomainError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case service.ErrInvalidDomain:
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, "Invalid domain")
+	case repository.ErrDomainAlreadyBlocked:
+		problem.Error(w, r, http.StatusConflict, problem.Conflict, "Domain is already blocked")
+	case repository.ErrDomainNotFound:
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "Domain is not blocked")
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+	}
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/graphql"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+// GraphQLHandler serves registration, sign-in, the signed-in user and their
+// sessions over GraphQL, through the same services and checks as the REST
+// routes:
+//
+//	type Query {
+//	  me: User
+//	  sessions(first: Int, after: String): SessionPage
+//	}
+//	type Mutation {
+//	  register(email: String!, password: String!, captchaToken: String): User
+//	  login(email: String!, password: String!, scope: String, captchaToken: String, rememberMe: Boolean): AuthPayload
+//	  logout: Boolean
+//	}
+//	type User { id: ID! email: String! role: String! createdAt: String! }
+//	type Session { id: ID! createdAt: String! expiresAt: String! rememberMe: Boolean! }
+//	type SessionPage { sessions: [Session!]! nextCursor: String }
+//	type AuthPayload { token: String! passwordExpired: Boolean! termsAcceptanceRequired: Boolean! }
+//
+// me, sessions and logout need the request to be authenticated, which
+// middleware.Identify does for this route.
+type GraphQLHandler struct {
+	auth   *AuthHandler
+	schema *graphql.Schema
+}
+
+// graphQLRequestKey holds the HTTP request in the context of resolvers
+type graphQLRequestKey struct{}
+
+// graphQLRequest is the body of a POST request; extensions, such as those of
+// persisted queries, are ignored
+type graphQLRequest struct {
+	graphql.Request
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+}
+
+// NewGraphQLHandler serves GraphQL with the services, CAPTCHA check and
+// canary tripwire of the auth handler
+func NewGraphQLHandler(auth *AuthHandler) *GraphQLHandler {
+	h := &GraphQLHandler{auth: auth}
+
+	user := &graphql.Object{Name: "User", Fields: map[string]*graphql.Field{
+		"id":        {Resolve: userField(func(u *model.User) any { return strconv.FormatInt(u.ID, 10) })},
+		"email":     {Resolve: userField(func(u *model.User) any { return u.Email })},
+		"role":      {Resolve: userField(func(u *model.User) any { return u.Role })},
+		"createdAt": {Resolve: userField(func(u *model.User) any { return u.Created.UTC().Format(time.RFC3339) })},
+	}}
+	session := &graphql.Object{Name: "Session", Fields: map[string]*graphql.Field{
+		"id":         {Resolve: sessionField(func(s *model.Session) any { return strconv.FormatInt(s.ID, 10) })},
+		"createdAt":  {Resolve: sessionField(func(s *model.Session) any { return s.Created.UTC().Format(time.RFC3339) })},
+		"expiresAt":  {Resolve: sessionField(func(s *model.Session) any { return s.ExpiresAt.UTC().Format(time.RFC3339) })},
+		"rememberMe": {Resolve: sessionField(func(s *model.Session) any { return s.RememberMe })},
+	}}
+	sessionPage := &graphql.Object{Name: "SessionPage", Fields: map[string]*graphql.Field{
+		"sessions":   {Type: session, List: true, Resolve: pageField(func(p *sessionPage) any { return p.sessions })},
+		"nextCursor": {Resolve: pageField(func(p *sessionPage) any { return p.nextCursor })},
+	}}
+	authPayload := &graphql.Object{Name: "AuthPayload", Fields: map[string]*graphql.Field{
+		"token":           {Resolve: payloadField(func(r *service.LoginResult) any { return r.Token })},
+		"passwordExpired": {Resolve: payloadField(func(r *service.LoginResult) any { return r.PasswordExpired })},
+		"termsAcceptanceRequired": {Resolve: func(ctx context.Context, source any, _ map[string]any) (any, error) {
+			return h.auth.termsAcceptanceRequired(requestFrom(ctx), source.(*service.LoginResult).UserID), nil
+		}},
+	}}
+
+	h.schema = &graphql.Schema{
+		Query: &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+			"me":       {Type: user, Resolve: h.me},
+			"sessions": {Type: sessionPage, Args: map[string]string{"first": "Int", "after": "String"}, Resolve: h.sessions},
+		}},
+		Mutation: &graphql.Object{Name: "Mutation", Fields: map[string]*graphql.Field{
+			"register": {Type: user, Args: map[string]string{"email": "String!", "password": "String!", "captchaToken": "String"}, Resolve: h.register},
+			"login":    {Type: authPayload, Args: map[string]string{"email": "String!", "password": "String!", "scope": "String", "captchaToken": "String", "rememberMe": "Boolean"}, Resolve: h.login},
+			"logout":   {Resolve: h.logout},
+		}},
+	}
+	return h
+}
+
+// Serve executes a GraphQL request: a query or mutation POSTed as JSON, or a
+// query in the query, operationName and variables parameters of a GET.
+// Each request may run one mutation, so a single request cannot try several
+// passwords past the rate limit.
+func (h *GraphQLHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Query == "" {
+		writeGraphQLError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	op, err := h.schema.Prepare(req.Request)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, graphql.Response{Errors: []*graphql.Error{err.(*graphql.Error)}})
+		return
+	}
+	if op.Kind() == "mutation" && r.Method == http.MethodGet {
+		w.Header().Set("Allow", http.MethodPost)
+		writeGraphQLError(w, http.StatusMethodNotAllowed, "mutations must be sent with POST")
+		return
+	}
+	if op.Kind() == "mutation" && op.RootFields() > 1 {
+		writeGraphQLError(w, http.StatusBadRequest, "only one mutation may be sent per request")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), graphQLRequestKey{}, r)
+	writeJSON(w, http.StatusOK, op.Execute(ctx))
+}
+
+// writeGraphQLError writes a response with a request error and no data
+func writeGraphQLError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, graphql.Response{Errors: []*graphql.Error{{Message: message}}})
+}
+
+// gqlError is an error for a GraphQL client, with a code in its extensions
+func gqlError(code, message string) *graphql.Error {
+	return &graphql.Error{Message: message, Extensions: map[string]any{"code": code}}
+}
+
+var errUnauthenticated = gqlError("UNAUTHENTICATED", "Authentication required")
+
+// internalError logs err and hides it from the client
+func internalError(ctx context.Context, field string, err error) *graphql.Error {
+	slog.ErrorContext(ctx, "graphql: resolver failed", "field", field, "err", err)
+	return gqlError("INTERNAL_SERVER_ERROR", "Internal server error")
+}
+
+func requestFrom(ctx context.Context) *http.Request {
+	return ctx.Value(graphQLRequestKey{}).(*http.Request)
+}
+
+func (h *GraphQLHandler) me(ctx context.Context, _ any, _ map[string]any) (any, error) {
+	userID, ok := UserFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	user, err := h.auth.authService.User(ctx, userID)
+	if err != nil {
+		return nil, internalError(ctx, "me", err)
+	}
+	return user, nil
+}
+
+// sessionPage is the result of the sessions query
+type sessionPage struct {
+	sessions   []*model.Session
+	nextCursor *string
+}
+
+func (h *GraphQLHandler) sessions(ctx context.Context, _ any, args map[string]any) (any, error) {
+	userID, ok := UserFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	page := pagination.Page{Limit: pagination.DefaultLimit}
+	if first, ok := args["first"].(int); ok {
+		if first < 1 {
+			return nil, gqlError("BAD_USER_INPUT", "first must be positive")
+		}
+		page.Limit = min(first, pagination.MaxLimit)
+	}
+	page.Cursor, _ = args["after"].(string)
+	if _, err := page.After(); err != nil {
+		return nil, gqlError("BAD_USER_INPUT", "Invalid cursor")
+	}
+
+	sessions, next, err := h.auth.authService.ListSessions(ctx, userID, page)
+	if err != nil {
+		return nil, internalError(ctx, "sessions", err)
+	}
+	result := &sessionPage{sessions: sessions}
+	if next != "" {
+		result.nextCursor = &next
+	}
+	return result, nil
+}
+
+func (h *GraphQLHandler) register(ctx context.Context, _ any, args map[string]any) (any, error) {
+	if err := h.checkClient(ctx, service.GeoActionRegister, args); err != nil {
+		return nil, err
+	}
+	email, plain := args["email"].(string), args["password"].(string)
+	user, err := h.auth.authService.RegisterUser(ctx, email, plain)
+	var violations password.Violations
+	switch {
+	case err == nil:
+		return user, nil
+	case errors.As(err, &violations):
+		return nil, &graphql.Error{Message: "Password does not meet the requirements",
+			Extensions: map[string]any{"code": "BAD_USER_INPUT", "violations": violations}}
+	case err == service.ErrInvalidCredentials:
+		return nil, gqlError("BAD_USER_INPUT", err.Error())
+	case err == service.ErrEmailDomainBlocked:
+		return nil, gqlError("FORBIDDEN", "Registrations from this email domain are not allowed")
+	case err == service.ErrBreachCheckFailed:
+		return nil, gqlError("SERVICE_UNAVAILABLE", err.Error())
+	}
+	return nil, internalError(ctx, "register", err)
+}
+
+func (h *GraphQLHandler) login(ctx context.Context, _ any, args map[string]any) (any, error) {
+	if err := h.checkClient(ctx, service.GeoActionLogin, args); err != nil {
+		return nil, err
+	}
+	r := requestFrom(ctx)
+	email, plain := args["email"].(string), args["password"].(string)
+	scope, _ := args["scope"].(string)
+	rememberMe, _ := args["rememberMe"].(bool)
+	result, err := h.auth.authService.Login(service.ContextWithClientIP(ctx, clientIP(r)), email, plain, scope, rememberMe)
+	switch err {
+	case nil:
+		return result, nil
+	case service.ErrInvalidUserScope:
+		return nil, gqlError("BAD_USER_INPUT", "Requested scope is not allowed")
+	case service.ErrCanaryAccount:
+		// Respond exactly like a wrong password so the attacker is not tipped off
+		h.auth.canary.trip(r, email)
+		return nil, gqlError("UNAUTHENTICATED", "Invalid email or password")
+	case service.ErrInvalidCredentials:
+		return nil, gqlError("UNAUTHENTICATED", "Invalid email or password")
+	case service.ErrAccountLocked, repository.ErrTooManyAttempts:
+		return nil, gqlError("FORBIDDEN", "Account is locked due to too many failed attempts")
+	case service.ErrTenantSuspended:
+		return nil, gqlError("FORBIDDEN", "Account is suspended")
+	case service.ErrAccountDisabled:
+		return nil, gqlError("FORBIDDEN", "Account is disabled")
+	case service.ErrLoginThrottled:
+		return nil, gqlError("TOO_MANY_REQUESTS", "Too many failed sign-in attempts, try again later")
+	}
+	return nil, internalError(ctx, "login", err)
+}
+
+func (h *GraphQLHandler) logout(ctx context.Context, _ any, _ map[string]any) (any, error) {
+	token, ok := middleware.TokenFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	if err := h.auth.authService.LogoutUser(ctx, token); err != nil {
+		if err == service.ErrInvalidToken {
+			return nil, errUnauthenticated
+		}
+		return nil, internalError(ctx, "logout", err)
+	}
+	return true, nil
+}
+
+// checkClient admits the client to take action, verifying the captchaToken
+// argument when it must solve a CAPTCHA
+func (h *GraphQLHandler) checkClient(ctx context.Context, action string, args map[string]any) error {
+	token, _ := args["captchaToken"].(string)
+	err := h.auth.admit(ctx, clientIP(requestFrom(ctx)), action, token)
+	switch err {
+	case nil:
+		return nil
+	case service.ErrCountryBlocked:
+		return gqlError("FORBIDDEN", "Sign-in is not available in your country")
+	case service.ErrCaptchaRequired, service.ErrCaptchaFailed:
+		return gqlError("CAPTCHA_REQUIRED", err.Error())
+	}
+	slog.ErrorContext(ctx, "CAPTCHA verification unavailable", "err", err)
+	return gqlError("SERVICE_UNAVAILABLE", "CAPTCHA verification unavailable")
+}
+
+// userField resolves a field of a User from the *model.User
+func userField(get func(*model.User) any) graphql.Resolver {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(*model.User)), nil
+	}
+}
+
+func sessionField(get func(*model.Session) any) graphql.Resolver {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(*model.Session)), nil
+	}
+}
+
+func pageField(get func(*sessionPage) any) graphql.Resolver {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(*sessionPage)), nil
+	}
+}
+
+func payloadField(get func(*service.LoginResult) any) graphql.Resolver {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(*service.LoginResult)), nil
+	}
+}
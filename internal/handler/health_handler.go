@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultReadinessTimeout bounds each dependency check of Ready
+const DefaultReadinessTimeout = 2 * time.Second
+
+// HealthCheck pings a dependency the service cannot serve requests without
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+// NewHealthHandler creates probes that check the dependencies in checks, each
+// within timeout (DefaultReadinessTimeout when not positive)
+func NewHealthHandler(timeout time.Duration, checks ...HealthCheck) *HealthHandler {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	return &HealthHandler{checks: checks, timeout: timeout}
+}
+
+// dependencyStatus is the result of one readiness check
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// readiness is the body of the readiness probe
+type readiness struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies,omitempty"`
+}
+
+// Live reports that the process is up and serving HTTP. It checks no
+// dependencies, so an outage of the database does not restart every replica.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, readiness{Status: "ok"})
+}
+
+// Ready checks every dependency concurrently and returns 503 when any is down,
+// so load balancers stop routing to the replica until it recovers. Failure
+// details are logged rather than returned, since the probe is public.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	resp := readiness{Status: "ok", Dependencies: make(map[string]dependencyStatus, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(ctx)
+			status := dependencyStatus{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				status.Status = "down"
+				slog.WarnContext(r.Context(), "readiness check failed", "dependency", check.Name, "err", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Dependencies[check.Name] = status
+			if err != nil {
+				resp.Status = "unavailable"
+			}
+		}()
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if resp.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, resp)
+}
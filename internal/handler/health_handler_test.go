@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler(t *testing.T) {
+	up := HealthCheck{Name: "postgres", Check: func(ctx context.Context) error { return nil }}
+	down := HealthCheck{Name: "redis", Check: func(ctx context.Context) error { return errors.New("connection refused") }}
+	hung := HealthCheck{Name: "redis", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	tests := []struct {
+		name           string
+		probe          func(h *HealthHandler) http.HandlerFunc
+		checks         []HealthCheck
+		wantStatusCode int
+		wantStatus     string
+		wantDeps       map[string]string
+	}{
+		{
+			name:           "liveness ignores dependencies",
+			probe:          func(h *HealthHandler) http.HandlerFunc { return h.Live },
+			checks:         []HealthCheck{down},
+			wantStatusCode: http.StatusOK,
+			wantStatus:     "ok",
+		},
+		{
+			name:           "ready when every dependency is up",
+			probe:          func(h *HealthHandler) http.HandlerFunc { return h.Ready },
+			checks:         []HealthCheck{up},
+			wantStatusCode: http.StatusOK,
+			wantStatus:     "ok",
+			wantDeps:       map[string]string{"postgres": "ok"},
+		},
+		{
+			name:           "unavailable when a dependency is down",
+			probe:          func(h *HealthHandler) http.HandlerFunc { return h.Ready },
+			checks:         []HealthCheck{up, down},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantStatus:     "unavailable",
+			wantDeps:       map[string]string{"postgres": "ok", "redis": "down"},
+		},
+		{
+			name:           "unavailable when a dependency times out",
+			probe:          func(h *HealthHandler) http.HandlerFunc { return h.Ready },
+			checks:         []HealthCheck{up, hung},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantStatus:     "unavailable",
+			wantDeps:       map[string]string{"postgres": "ok", "redis": "down"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(50*time.Millisecond, tt.checks...)
+			rr := httptest.NewRecorder()
+			tt.probe(h)(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rr.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", rr.Code, tt.wantStatusCode)
+			}
+			var resp struct {
+				Status       string `json:"status"`
+				Dependencies map[string]struct {
+					Status string `json:"status"`
+				} `json:"dependencies"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if len(resp.Dependencies) != len(tt.wantDeps) {
+				t.Errorf("got dependencies %v, want %v", resp.Dependencies, tt.wantDeps)
+			}
+			for name, want := range tt.wantDeps {
+				if got := resp.Dependencies[name].Status; got != want {
+					t.Errorf("%s: got %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
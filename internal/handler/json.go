@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/validate"
+)
+
+// jsonContentType is shared so setting the header does not allocate per response
+var jsonContentType = []string{"application/json"}
+
+// maxPooledBufferSize keeps unusually large responses from pinning memory in the pool
+const maxPooledBufferSize = 64 << 10
+
+// jsonBuffer is a reusable buffer with an encoder bound to it
+type jsonBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+var jsonBufferPool = sync.Pool{
+	New: func() any {
+		b := &jsonBuffer{}
+		b.enc = json.NewEncoder(&b.Buffer)
+		return b
+	},
+}
+
+// writeJSON encodes v into a pooled buffer and writes it with the status code.
+// Encoding before writing the header means an encoding failure still produces
+// a clean 500 instead of a truncated body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b := jsonBufferPool.Get().(*jsonBuffer)
+	defer func() {
+		if b.Cap() <= maxPooledBufferSize {
+			b.Reset()
+			jsonBufferPool.Put(b)
+		}
+	}()
+
+	if err := b.enc.Encode(v); err != nil {
+		problem.Error(w, nil, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	header := w.Header()
+	if _, exists := header["Content-Type"]; !exists {
+		header["Content-Type"] = jsonContentType
+	}
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+// validationProblem is the response to a request body whose fields break the
+// rules in their `validate` tags
+type validationProblem struct {
+	problem.Problem
+	Errors validate.Errors `json:"errors"`
+}
+
+// decodeJSON strictly decodes a request body holding a single JSON object into
+// dst and validates it against its `validate` tags. Unknown fields, trailing
+// data and bodies over the middleware.MaxBodySize limit are rejected; on
+// failure the error response is written and false is returned.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errTrailingData
+	}
+	if err == nil {
+		if err := validateBody(dst); err != nil {
+			problem.Write(w, r, http.StatusBadRequest, validationProblem{
+				Problem: problem.New(r, http.StatusBadRequest, problem.ValidationFailed, "Invalid request body"),
+				Errors:  err,
+			})
+			return false
+		}
+		return true
+	}
+
+	status, code, detail := http.StatusBadRequest, problem.BadRequest, ""
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		status, code, detail = http.StatusRequestEntityTooLarge, problem.BodyTooLarge, fmt.Sprintf("body must not exceed %d bytes", sizeErr.Limit)
+	case errors.As(err, &syntaxErr):
+		detail = fmt.Sprintf("malformed JSON at byte %d", syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		detail = "malformed JSON"
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		detail = fmt.Sprintf("field %q must be %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind()))
+	case errors.As(err, &typeErr):
+		detail = "body must be a JSON object"
+	case errors.Is(err, io.EOF):
+		detail = "body is empty"
+	case errors.Is(err, errTrailingData):
+		detail = err.Error()
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		detail = "unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	default:
+		detail = "body could not be decoded"
+	}
+	problem.Error(w, r, status, code, detail)
+	return false
+}
+
+// validateBody validates dst when it is a struct, returning the invalid fields
+func validateBody(dst any) validate.Errors {
+	if reflect.Indirect(reflect.ValueOf(dst)).Kind() != reflect.Struct {
+		return nil
+	}
+	if err := validate.Struct(dst); err != nil {
+		return err.(validate.Errors)
+	}
+	return nil
+}
+
+var errTrailingData = errors.New("body must contain a single JSON object")
+
+// jsonTypeName describes the JSON type a Go kind decodes from
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
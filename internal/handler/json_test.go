@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusCreated, AuthResponse{Token: "abc"})
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("got status %v, want %v", w.Code, http.StatusCreated)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got content type %q", got)
+	}
+	if w.Body.String() != "{\"token\":\"abc\"}\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+
+	t.Run("unencodable value", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeJSON(w, http.StatusOK, map[string]any{"bad": make(chan int)})
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("got status %v, want %v", w.Code, http.StatusInternalServerError)
+		}
+	})
+}
+
+// discardWriter is a ResponseWriter that keeps allocations out of the benchmarks
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func BenchmarkJSONResponse(b *testing.B) {
+	resp := AuthResponse{Token: "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzdWIiOjEsImVtYWlsIjoidGVzdEBleGFtcGxlLmNvbSJ9.signature"}
+
+	// Each iteration gets a fresh header map, as a real request does
+	b.Run("NewEncoder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w := &discardWriter{header: http.Header{}}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(resp)
+		}
+	})
+
+	b.Run("Pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			w := &discardWriter{header: http.Header{}}
+			writeJSON(w, http.StatusOK, resp)
+		}
+	})
+
+	b.Run("PooledParallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				w := &discardWriter{header: http.Header{}}
+				writeJSON(w, http.StatusOK, resp)
+			}
+		})
+	})
+}
+
+// BenchmarkTokenValidationPath measures a protected request end to end:
+// Bearer token validation followed by a JSON response
+func BenchmarkTokenValidationPath(b *testing.B) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		b.Fatalf("failed to create test user: %v", err)
+	}
+	token, err := authService.LoginUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		b.Fatalf("failed to log in: %v", err)
+	}
+
+	handler := NewConsentHandler(service.NewConsentService(test.NewMockConsentRepository()), authService)
+	req := httptest.NewRequest("GET", "/auth/me/consents", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.List(&discardWriter{header: http.Header{}}, req)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type request struct {
+		Email string `json:"email"`
+		Admin bool   `json:"admin"`
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		limit          int64
+		wantStatusCode int
+		wantDetail     string
+	}{
+		{name: "valid object", body: `{"email":"a@example.com"}`},
+		{name: "unknown field", body: `{"email":"a@example.com","role":"admin"}`, wantStatusCode: http.StatusBadRequest, wantDetail: `unknown field "role"`},
+		{name: "trailing object", body: `{"email":"a@example.com"}{"admin":true}`, wantStatusCode: http.StatusBadRequest, wantDetail: "body must contain a single JSON object"},
+		{name: "trailing garbage", body: `{"email":"a@example.com"} junk`, wantStatusCode: http.StatusBadRequest, wantDetail: "body must contain a single JSON object"},
+		{name: "wrong type", body: `{"admin":"yes"}`, wantStatusCode: http.StatusBadRequest, wantDetail: `field "admin" must be a boolean`},
+		{name: "not an object", body: `["a@example.com"]`, wantStatusCode: http.StatusBadRequest, wantDetail: "body must be a JSON object"},
+		{name: "malformed", body: `{"email":`, wantStatusCode: http.StatusBadRequest, wantDetail: "malformed JSON"},
+		{name: "empty", body: ``, wantStatusCode: http.StatusBadRequest, wantDetail: "body is empty"},
+		{name: "too large", body: `{"email":"a@example.com"}`, limit: 8, wantStatusCode: http.StatusRequestEntityTooLarge, wantDetail: "body must not exceed 8 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, tt.limit)
+			}
+
+			var req request
+			ok := decodeJSON(w, r, &req)
+			if ok != (tt.wantStatusCode == 0) {
+				t.Fatalf("got ok %v for status %v", ok, w.Code)
+			}
+			if ok {
+				return
+			}
+			var resp problem.Problem
+			json.NewDecoder(w.Body).Decode(&resp)
+			if w.Code != tt.wantStatusCode || resp.Detail != tt.wantDetail {
+				t.Errorf("got %v %q, want %v %q", w.Code, resp.Detail, tt.wantStatusCode, tt.wantDetail)
+			}
+		})
+	}
+}
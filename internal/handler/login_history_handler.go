@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+// LoginHistoryHandler serves users the sign-in attempts on their account
+// from the audit log, so they can spot access that was not theirs
+type LoginHistoryHandler struct {
+	auditRepo   interfaces.AuditRepository
+	authService *service.AuthService
+}
+
+func NewLoginHistoryHandler(auditRepo interfaces.AuditRepository, authService *service.AuthService) *LoginHistoryHandler {
+	return &LoginHistoryHandler{auditRepo: auditRepo, authService: authService}
+}
+
+// LoginAttempt is a password sign-in attempt on the user's account, or an
+// admin impersonating the user
+type LoginAttempt struct {
+	Time      time.Time `json:"time"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Succeeded bool      `json:"succeeded"`
+	// Outcome is success, invalid_credentials, locked, or impersonated, and
+	// failed for attempts recorded before outcomes were stored
+	Outcome        string `json:"outcome"`
+	ImpersonatorID int64  `json:"impersonator_id,omitempty"`
+}
+
+// List returns the user's sign-in attempts newest first, paged with limit
+// and cursor
+func (h *LoginHistoryHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid page")
+		return
+	}
+
+	events, next, err := h.auditRepo.ListEvents(r.Context(), audit.Filter{
+		ActorID: userID,
+		Types:   []string{audit.LoginSucceeded, audit.LoginFailed, audit.Impersonated},
+		Page:    page,
+	})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	attempts := make([]LoginAttempt, len(events))
+	for i, e := range events {
+		attempts[i] = LoginAttempt{
+			Time:      e.Time,
+			IPAddress: e.IPAddress,
+			UserAgent: e.UserAgent,
+			Succeeded: e.Type != audit.LoginFailed,
+		}
+		if e.Type == audit.Impersonated {
+			attempts[i].Outcome = "impersonated"
+			// Numbers read back from stored details are float64
+			switch id := e.Details["impersonator_id"].(type) {
+			case int64:
+				attempts[i].ImpersonatorID = id
+			case float64:
+				attempts[i].ImpersonatorID = int64(id)
+			}
+			continue
+		}
+		attempts[i].Outcome, _ = e.Details["outcome"].(string)
+		if attempts[i].Outcome == "" {
+			attempts[i].Outcome = "failed"
+			if attempts[i].Succeeded {
+				attempts[i].Outcome = "success"
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"attempts": attempts, "next_cursor": next})
+}
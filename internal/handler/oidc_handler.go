@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+// loginPage is the minimal sign-in form shown by the authorize endpoint
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<h1>Sign in</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="POST" action="/authorize">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<label>Email <input type="email" name="email" required></label>
+<label>Password <input type="password" name="password" required></label>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>`))
+
+type OIDCHandler struct {
+	oidcService    *service.OIDCService
+	authService    *service.AuthService
+	consentService *service.ConsentService
+	canary         *CanaryTripwire // nil disables canary alerts
+	csrf           *middleware.CSRF
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, authService *service.AuthService, consentService *service.ConsentService, canary *CanaryTripwire, csrf *middleware.CSRF) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:    oidcService,
+		authService:    authService,
+		consentService: consentService,
+		canary:         canary,
+		csrf:           csrf,
+	}
+}
+
+// tokenError is the OAuth 2.0 error response body
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Discovery serves the OpenID Provider configuration document
+func (h *OIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.oidcService.Discovery())
+}
+
+// JWKS serves the public keys used to sign ID tokens
+func (h *OIDCHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Lets clients such as pkg/authmw cache the keys between rotations
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, http.StatusOK, h.oidcService.JWKS())
+}
+
+// Authorize starts the authorization code flow. A user that already holds a
+// valid access token is issued a code immediately; otherwise a sign-in form is
+// shown. The form carries a CSRF token, so other sites cannot post credentials
+// of their choosing through the user's browser.
+func (h *OIDCHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid request")
+		return
+	}
+
+	req := &service.AuthorizeRequest{
+		ClientID:     r.Form.Get("client_id"),
+		RedirectURI:  r.Form.Get("redirect_uri"),
+		ResponseType: r.Form.Get("response_type"),
+		Scope:        r.Form.Get("scope"),
+		State:        r.Form.Get("state"),
+		Nonce:        r.Form.Get("nonce"),
+
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+	}
+
+	if _, err := h.oidcService.ValidateAuthorizeRequest(r.Context(), req); err != nil {
+		switch err {
+		case service.ErrInvalidClient, service.ErrInvalidRedirectURI:
+			// Never redirect to an unverified URI
+			problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, err.Error())
+		case service.ErrUnsupportedResponseType:
+			redirectWithError(w, r, req, "unsupported_response_type")
+		case service.ErrInvalidScope:
+			redirectWithError(w, r, req, "invalid_scope")
+		case service.ErrPKCERequired, service.ErrInvalidCodeChallenge:
+			redirectWithError(w, r, req, "invalid_request")
+		case service.ErrUnauthorizedClient:
+			redirectWithError(w, r, req, "unauthorized_client")
+		default:
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		}
+		return
+	}
+
+	var userID int64
+	if r.Method == http.MethodPost {
+		if !h.csrf.Verify(r, r.PostForm.Get(middleware.CSRFFieldName), "") {
+			problem.Error(w, r, http.StatusForbidden, problem.CSRFTokenInvalid, "Missing or invalid CSRF token")
+			return
+		}
+		ctx := service.ContextWithClientIP(r.Context(), clientIP(r))
+		user, err := h.authService.Authenticate(ctx, r.PostForm.Get("email"), r.PostForm.Get("password"))
+		if err != nil {
+			switch err {
+			case service.ErrInvalidCredentials:
+				h.renderLogin(w, r, req, "Invalid email or password", http.StatusUnauthorized)
+			case service.ErrCanaryAccount:
+				h.canary.trip(r, r.PostForm.Get("email"))
+				h.renderLogin(w, r, req, "Invalid email or password", http.StatusUnauthorized)
+			case service.ErrAccountLocked, repository.ErrTooManyAttempts:
+				h.renderLogin(w, r, req, "Account is locked due to too many failed attempts", http.StatusForbidden)
+			case service.ErrTenantSuspended:
+				h.renderLogin(w, r, req, "Account is suspended", http.StatusForbidden)
+			case service.ErrAccountDisabled:
+				h.renderLogin(w, r, req, "Account is disabled", http.StatusForbidden)
+			case service.ErrLoginThrottled:
+				h.renderLogin(w, r, req, "Too many failed sign-in attempts, try again later", http.StatusTooManyRequests)
+			default:
+				problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+			}
+			return
+		}
+		if h.authService.PasswordExpired(user) {
+			// The authorization code flow has no way to force the change
+			h.renderLogin(w, r, req, "Your password has expired; change it before signing in", http.StatusForbidden)
+			return
+		}
+		userID = user.ID
+	} else {
+		token := extractToken(r)
+		if token == "" {
+			h.renderLogin(w, r, req, "", http.StatusOK)
+			return
+		}
+		claims, err := h.authService.ValidateToken(r.Context(), token)
+		if err != nil {
+			h.renderLogin(w, r, req, "", http.StatusOK)
+			return
+		}
+		sub, _ := claims["sub"].(float64)
+		userID = int64(sub)
+	}
+
+	code, err := h.oidcService.IssueAuthorizationCode(r.Context(), req, userID)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	// Record which scopes the user released to the client
+	err = h.consentService.RecordConsent(r.Context(), &model.ConsentReceipt{
+		UserID:    userID,
+		Purpose:   model.ConsentOAuthScopes,
+		Subject:   req.ClientID,
+		Scope:     req.Scope,
+		Granted:   true,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	http.Redirect(w, r, appendQuery(req.RedirectURI, params), http.StatusFound)
+}
+
+// Token issues tokens for the authorization code, client credentials, and
+// token exchange grants
+func (h *OIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
+	h.token(w, r, "")
+}
+
+// TokenExchange serves the RFC 8693 token exchange grant on its own endpoint,
+// where grant_type may be omitted
+func (h *OIDCHandler) TokenExchange(w http.ResponseWriter, r *http.Request) {
+	h.token(w, r, model.GrantTokenExchange)
+}
+
+// token handles a token request, restricted to one grant type when grant is set
+func (h *OIDCHandler) token(w http.ResponseWriter, r *http.Request, grant string) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		sendTokenError(w, "invalid_request", "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	if grant != "" {
+		if grantType != "" && grantType != grant {
+			sendTokenError(w, "unsupported_grant_type", "", http.StatusBadRequest)
+			return
+		}
+		grantType = grant
+	}
+
+	// Accept client_secret_basic and client_secret_post authentication, or
+	// just a client_id for public clients using PKCE
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	var resp *service.TokenResponse
+	var err error
+	switch grantType {
+	case model.GrantAuthorizationCode:
+		resp, err = h.oidcService.ExchangeAuthorizationCode(r.Context(), &service.TokenRequest{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Code:         r.PostForm.Get("code"),
+			RedirectURI:  r.PostForm.Get("redirect_uri"),
+			CodeVerifier: r.PostForm.Get("code_verifier"),
+		})
+	case model.GrantClientCredentials:
+		resp, err = h.oidcService.ClientCredentials(r.Context(), clientID, clientSecret, r.PostForm.Get("scope"))
+	case model.GrantTokenExchange:
+		resp, err = h.oidcService.ExchangeToken(r.Context(), &service.TokenExchangeRequest{
+			ClientID:         clientID,
+			ClientSecret:     clientSecret,
+			SubjectToken:     r.PostForm.Get("subject_token"),
+			SubjectTokenType: r.PostForm.Get("subject_token_type"),
+			Scope:            r.PostForm.Get("scope"),
+			Audience:         r.PostForm.Get("audience"),
+		})
+	default:
+		sendTokenError(w, "unsupported_grant_type", "", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		switch err {
+		case service.ErrInvalidClient:
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+			sendTokenError(w, "invalid_client", "", http.StatusUnauthorized)
+		case service.ErrInvalidGrant:
+			sendTokenError(w, "invalid_grant", err.Error(), http.StatusBadRequest)
+		case service.ErrUnauthorizedClient:
+			sendTokenError(w, "unauthorized_client", err.Error(), http.StatusBadRequest)
+		case service.ErrScopeNotAllowed:
+			sendTokenError(w, "invalid_scope", err.Error(), http.StatusBadRequest)
+		case service.ErrInvalidSubjectToken:
+			sendTokenError(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		default:
+			sendTokenError(w, "server_error", "", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UserInfo returns claims about the user owning the access token
+func (h *OIDCHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "No token provided")
+		return
+	}
+
+	info, err := h.oidcService.UserInfo(r.Context(), token)
+	if err != nil {
+		if err == service.ErrInvalidToken || err == service.ErrTokenExpired {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		}
+		sendAuthError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// Helper function to render the sign-in form
+func (h *OIDCHandler) renderLogin(w http.ResponseWriter, r *http.Request, req *service.AuthorizeRequest, message string, code int) {
+	csrfToken, err := h.csrf.Issue(w, "")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to issue CSRF token", "err", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	loginPage.Execute(w, struct {
+		Request   *service.AuthorizeRequest
+		Error     string
+		CSRFToken string
+	}{req, message, csrfToken})
+}
+
+// Helper function to report an authorization error back to the client
+func redirectWithError(w http.ResponseWriter, r *http.Request, req *service.AuthorizeRequest, code string) {
+	params := url.Values{"error": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	http.Redirect(w, r, appendQuery(req.RedirectURI, params), http.StatusFound)
+}
+
+// Helper function to add query parameters to a redirect URI that may already have some
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Helper function to send OAuth token endpoint errors
+func sendTokenError(w http.ResponseWriter, code, description string, status int) {
+	writeJSON(w, status, tokenError{Error: code, ErrorDescription: description})
+}
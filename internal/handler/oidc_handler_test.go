@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/oidc"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestOIDCHandler_AuthorizeCSRF(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := service.NewAuthService(mockRepo, mockRepo, "test-secret")
+	key, err := oidc.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	oidcService := service.NewOIDCService(authService, mockRepo, test.NewMockOAuthRepository(), key, "https://auth.example.com")
+	consentService := service.NewConsentService(test.NewMockConsentRepository())
+	handler := NewOIDCHandler(oidcService, authService, consentService, nil, middleware.NewCSRF("test-secret"))
+
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	client, _, err := oidcService.RegisterClient(ctx, &service.ClientRegistration{Name: "test app", RedirectURIs: []string{"https://app.example.com/callback"}})
+	if err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+	form := url.Values{
+		"client_id":     {client.ClientID},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"response_type": {"code"},
+		"scope":         {"openid email"},
+		"email":         {"test@example.com"},
+		"password":      {"password123"},
+	}
+
+	// The sign-in form carries the token that is also set in the CSRF cookie
+	w := httptest.NewRecorder()
+	handler.Authorize(w, httptest.NewRequest("GET", "/authorize?"+form.Encode(), nil))
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == middleware.CSRFCookieName {
+			cookie = c
+		}
+	}
+	match := regexp.MustCompile(`name="csrf_token" value="([^"]+)"`).FindStringSubmatch(w.Body.String())
+	if w.Code != http.StatusOK || cookie == nil || match == nil || match[1] != cookie.Value {
+		t.Fatalf("got status %d, cookie %v, and a form without the CSRF token", w.Code, cookie)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		cookie         *http.Cookie
+		wantStatusCode int
+	}{
+		{name: "no token", cookie: cookie, wantStatusCode: http.StatusForbidden},
+		{name: "no cookie", token: cookie.Value, wantStatusCode: http.StatusForbidden},
+		{name: "token of another form", token: strings.Replace(cookie.Value, ".", "x.", 1), cookie: cookie, wantStatusCode: http.StatusForbidden},
+		{name: "token and cookie", token: cookie.Value, cookie: cookie, wantStatusCode: http.StatusFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := url.Values{}
+			for k, v := range form {
+				body[k] = v
+			}
+			if tt.token != "" {
+				body.Set(middleware.CSRFFieldName, tt.token)
+			}
+			req := httptest.NewRequest("POST", "/authorize", strings.NewReader(body.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+
+			w := httptest.NewRecorder()
+			handler.Authorize(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if location := w.Header().Get("Location"); (tt.wantStatusCode == http.StatusFound) != strings.Contains(location, "code=") {
+				t.Errorf("got redirect %q; a code must be issued only with a valid CSRF token", location)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	authService    *service.AuthService
+}
+
+func NewProfileHandler(profileService *service.ProfileService, authService *service.AuthService) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		authService:    authService,
+	}
+}
+
+type ProfileResponse struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	TenantID  *int64     `json:"tenant_id,omitempty"`
+	Status    string     `json:"status"`
+	Created   time.Time  `json:"created_at"`
+	LastLogin *time.Time `json:"last_login,omitempty"`
+	model.Profile
+	AppMetadata  map[string]any `json:"app_metadata"`
+	UserMetadata map[string]any `json:"user_metadata"`
+}
+
+// ProfileFields are the optional profile details a user sets at registration
+// or later. Omitted fields are left unchanged and empty strings clear them.
+type ProfileFields struct {
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=100"`
+	GivenName   *string `json:"given_name,omitempty" validate:"omitempty,max=100"`
+	FamilyName  *string `json:"family_name,omitempty" validate:"omitempty,max=100"`
+	Locale      *string `json:"locale,omitempty" validate:"omitempty,max=35,locale"`
+	Timezone    *string `json:"timezone,omitempty" validate:"omitempty,max=64,timezone"`
+	AvatarURL   *string `json:"avatar_url,omitempty" validate:"omitempty,max=2048,url"`
+}
+
+// set reports whether any field is given
+func (f *ProfileFields) set() bool {
+	return *f != ProfileFields{}
+}
+
+// apply copies the given fields to profile
+func (f *ProfileFields) apply(profile *model.Profile) {
+	for dst, src := range map[*string]*string{
+		&profile.DisplayName: f.DisplayName,
+		&profile.GivenName:   f.GivenName,
+		&profile.FamilyName:  f.FamilyName,
+		&profile.Locale:      f.Locale,
+		&profile.Timezone:    f.Timezone,
+		&profile.AvatarURL:   f.AvatarURL,
+	} {
+		if src != nil {
+			*dst = *src
+		}
+	}
+}
+
+// UpdateProfileRequest is the body of a request to update the user's own
+// account. Keys set to null in user_metadata are removed; app_metadata is
+// only accepted so that the refusal is explicit.
+type UpdateProfileRequest struct {
+	ProfileFields
+	UserMetadata map[string]any `json:"user_metadata,omitempty"`
+	AppMetadata  map[string]any `json:"app_metadata,omitempty"`
+}
+
+// Me returns the authenticated user with their profile and metadata
+func (h *ProfileHandler) Me(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+	h.writeProfile(w, r, userID)
+}
+
+// UpdateMe updates the authenticated user's profile fields given in the
+// request and merges its user_metadata into theirs, returning the result
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, err := authenticateRequest(h.authService, r)
+	if err != nil {
+		sendAuthError(w, r, err)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.AppMetadata != nil {
+		problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "app_metadata can only be changed by administrators")
+		return
+	}
+
+	if req.ProfileFields.set() {
+		if _, err := h.profileService.UpdateProfile(r.Context(), userID, req.ProfileFields.apply); err != nil {
+			sendProfileError(w, r, err)
+			return
+		}
+	}
+	if req.UserMetadata != nil {
+		if _, err := h.profileService.UpdateUserMetadata(r.Context(), userID, req.UserMetadata); err != nil {
+			sendProfileError(w, r, err)
+			return
+		}
+	}
+	h.writeProfile(w, r, userID)
+}
+
+// writeProfile responds with the user's profile and metadata
+func (h *ProfileHandler) writeProfile(w http.ResponseWriter, r *http.Request, userID int64) {
+	user, metadata, err := h.profileService.Profile(r.Context(), userID)
+	if err != nil {
+		sendProfileError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProfileResponse{
+		ID:           user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		TenantID:     user.TenantID,
+		Status:       user.Status(),
+		Created:      user.Created,
+		Profile:      user.Profile,
+		LastLogin:    user.LastLogin,
+		AppMetadata:  metadata.AppMetadata,
+		UserMetadata: metadata.UserMetadata,
+	})
+}
+
+// sendProfileError maps profile errors to responses
+func sendProfileError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case repository.ErrUserNotFound:
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "User not found")
+	case service.ErrMetadataTooLarge:
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, "Metadata is too large")
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+	}
+}
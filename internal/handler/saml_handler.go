@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/saml"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+// samlRequestCookie holds the AuthnRequest ID between the redirect and the ACS POST
+const samlRequestCookie = "saml_request"
+
+type SAMLHandler struct {
+	sp            *saml.ServiceProvider
+	socialService *service.SocialAuthService
+}
+
+func NewSAMLHandler(sp *saml.ServiceProvider, socialService *service.SocialAuthService) *SAMLHandler {
+	return &SAMLHandler{
+		sp:            sp,
+		socialService: socialService,
+	}
+}
+
+// Metadata serves the SP metadata to register with the identity provider
+func (h *SAMLHandler) Metadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := h.sp.Metadata()
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(metadata)
+}
+
+// Login redirects the user to the identity provider to sign in
+func (h *SAMLHandler) Login(w http.ResponseWriter, r *http.Request) {
+	redirectURL, requestID, err := h.sp.AuthnRequestURL("")
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	// The IdP posts back cross-site, so the cookie must be SameSite=None
+	http.SetCookie(w, &http.Cookie{
+		Name:     samlRequestCookie,
+		Value:    requestID,
+		Path:     "/saml",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// ACS consumes the identity provider's response and returns a JWT token
+func (h *SAMLHandler) ACS(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(samlRequestCookie)
+	if err != nil || cookie.Value == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "No SAML sign-in in progress")
+		return
+	}
+
+	// The request ID is single-use
+	http.SetCookie(w, &http.Cookie{Name: samlRequestCookie, Path: "/saml", MaxAge: -1})
+
+	identity, err := h.sp.ParseResponse(r, cookie.Value)
+	if err != nil {
+		switch {
+		case errors.Is(err, saml.ErrMissingNameID), errors.Is(err, saml.ErrMissingEmail):
+			problem.Error(w, r, http.StatusUnauthorized, problem.ExternalLogin, "The identity provider did not supply an email address")
+		default:
+			problem.Error(w, r, http.StatusUnauthorized, problem.ExternalLogin, "Invalid SAML response")
+		}
+		return
+	}
+
+	token, err := h.socialService.LoginWithIdentity(r.Context(), identity)
+	if err != nil {
+		switch err {
+		case service.ErrAccountLocked, repository.ErrTooManyAttempts:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountLocked, "Account is locked due to too many failed attempts")
+		case service.ErrTenantSuspended:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountSuspended, "Account is suspended")
+		case service.ErrAccountDisabled:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountDisabled, "Account is disabled")
+		case service.ErrInvalidCredentials:
+			problem.Error(w, r, http.StatusForbidden, problem.LoginNotAllowed, "This account cannot sign in with SAML")
+		default:
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AuthResponse{Token: token})
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// ServiceAccountHandler serves the admin API for service accounts
+type ServiceAccountHandler struct {
+	serviceAccounts *service.ServiceAccountService
+	auditLogger     audit.Logger
+}
+
+func NewServiceAccountHandler(serviceAccounts *service.ServiceAccountService, auditLogger audit.Logger) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		serviceAccounts: serviceAccounts,
+		auditLogger:     auditLogger,
+	}
+}
+
+type CreateServiceAccountRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ServiceAccountResponse struct {
+	ID      int64     `json:"id"`
+	Email   string    `json:"email"`
+	Type    string    `json:"type"`
+	Locked  bool      `json:"locked"`
+	Created time.Time `json:"created_at"`
+}
+
+type SetLockedRequest struct {
+	Locked bool `json:"locked"`
+}
+
+func newServiceAccountResponse(user *model.User) ServiceAccountResponse {
+	return ServiceAccountResponse{
+		ID:      user.ID,
+		Email:   user.Email,
+		Type:    user.Type,
+		Locked:  user.IsLocked,
+		Created: user.Created,
+	}
+}
+
+// Create creates a service account
+func (h *ServiceAccountHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateServiceAccountRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.serviceAccounts.CreateServiceAccount(r.Context(), req.Email)
+	if err != nil {
+		if err == repository.ErrDuplicateEmail {
+			problem.Error(w, r, http.StatusConflict, problem.EmailTaken, "Email already registered")
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	h.record(r, "admin.service_account_created", user.ID, nil)
+	writeJSON(w, http.StatusCreated, newServiceAccountResponse(user))
+}
+
+// List returns every service account
+func (h *ServiceAccountHandler) List(w http.ResponseWriter, r *http.Request) {
+	users, err := h.serviceAccounts.ListServiceAccounts(r.Context())
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	accounts := make([]ServiceAccountResponse, len(users))
+	for i, user := range users {
+		accounts[i] = newServiceAccountResponse(user)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"service_accounts": accounts})
+}
+
+// SetLocked locks or unlocks the service account in the URL
+func (h *ServiceAccountHandler) SetLocked(w http.ResponseWriter, r *http.Request) {
+	userID, ok := serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetLockedRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.serviceAccounts.SetLocked(r.Context(), userID, req.Locked); err != nil {
+		sendServiceAccountError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.service_account_locked", userID, map[string]any{"locked": req.Locked})
+	writeJSON(w, http.StatusOK, map[string]any{"id": userID, "locked": req.Locked})
+}
+
+// Delete deletes the service account in the URL together with its API keys
+func (h *ServiceAccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceAccounts.DeleteServiceAccount(r.Context(), userID); err != nil {
+		sendServiceAccountError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.service_account_deleted", userID, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Service account deleted"})
+}
+
+// CreateAPIKey issues an API key for the service account in the URL. The key
+// is only returned in this response.
+func (h *ServiceAccountHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := serviceAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	key, plaintext, err := h.serviceAccounts.CreateAPIKey(r.Context(), userID, req.Name)
+	if err != nil {
+		sendServiceAccountError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.service_account_key_created", userID, map[string]any{"key_id": key.ID, "prefix": key.Prefix})
+	writeJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+		ID:     key.ID,
+		Name:   key.Name,
+		Prefix: key.Prefix,
+		Key:    plaintext,
+	})
+}
+
+// record emits an audit event for a service account change
+func (h *ServiceAccountHandler) record(r *http.Request, eventType string, userID int64, details map[string]any) {
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["user_id"] = userID
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      eventType,
+		Severity:  audit.SeverityInfo,
+		IPAddress: clientIP(r),
+		Details:   details,
+	})
+}
+
+// serviceAccountID parses the account ID in the URL, writing an error when it is invalid
+func serviceAccountID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid user ID")
+		return 0, false
+	}
+	return userID, true
+}
+
+// sendServiceAccountError maps service account errors to responses
+func sendServiceAccountError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case repository.ErrUserNotFound, service.ErrNotServiceAccount:
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "Service account not found")
+	case service.ErrAccountLocked:
+		problem.Error(w, r, http.StatusConflict, problem.AccountLocked, "Service account is locked")
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+	}
+}
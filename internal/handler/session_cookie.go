@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+)
+
+// AcceptAuthHeader lets a client choose how Login delivers the token: "cookie"
+// for an HttpOnly session cookie or "token" for the JSON body
+const AcceptAuthHeader = "Accept-Auth"
+
+// WithSessionCookies lets browser clients receive the token in a Secure,
+// HttpOnly, SameSite session cookie that scripts cannot read. Login also
+// issues a CSRF token bound to the new session. With byDefault, cookies are
+// used unless the client sends "Accept-Auth: token".
+func WithSessionCookies(csrf *middleware.CSRF, byDefault bool) AuthHandlerOption {
+	return func(h *AuthHandler) {
+		h.csrf = csrf
+		h.cookiesByDefault = byDefault
+	}
+}
+
+// wantsCookie reports whether the token should be delivered in a cookie
+func (h *AuthHandler) wantsCookie(r *http.Request) bool {
+	if h.csrf == nil {
+		return false
+	}
+	switch strings.ToLower(r.Header.Get(AcceptAuthHeader)) {
+	case "cookie":
+		return true
+	case "token":
+		return false
+	default:
+		return h.cookiesByDefault
+	}
+}
+
+// startCookieSession sets the session cookie to token and returns a CSRF
+// token for the new session. The cookie of a remembered session persists
+// until the token expires; others end with the browser session.
+func (h *AuthHandler) startCookieSession(w http.ResponseWriter, token string, remember bool) (string, error) {
+	csrfToken, err := h.csrf.Issue(w, token)
+	if err != nil {
+		return "", err
+	}
+	cookie := &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if remember {
+		cookie.MaxAge = int(h.authService.RememberMeExpiry() / time.Second)
+	}
+	http.SetCookie(w, cookie)
+	return csrfToken, nil
+}
+
+// endCookieSession removes the session and CSRF cookies of r, if any
+func endCookieSession(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie(middleware.SessionCookieName); err != nil {
+		return
+	}
+	for _, name := range []string{middleware.SessionCookieName, middleware.CSRFCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: name == middleware.SessionCookieName,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
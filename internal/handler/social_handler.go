@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/oauth"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// oauthStateCookie holds the CSRF state between the redirect and the callback
+const oauthStateCookie = "oauth_state"
+
+type SocialHandler struct {
+	socialService *service.SocialAuthService
+}
+
+func NewSocialHandler(socialService *service.SocialAuthService) *SocialHandler {
+	return &SocialHandler{
+		socialService: socialService,
+	}
+}
+
+// Login redirects the user to the provider's authorization page
+func (h *SocialHandler) Login(w http.ResponseWriter, r *http.Request) {
+	state, err := generateState()
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	authURL, err := h.socialService.AuthCodeURL(chi.URLParam(r, "provider"), state)
+	if err != nil {
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "Unknown login provider")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/auth",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes the provider login and returns a JWT token
+func (h *SocialHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oauthStateCookie)
+	state := r.URL.Query().Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid OAuth state")
+		return
+	}
+
+	// The state is single-use
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/auth", MaxAge: -1})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Authorization code is required")
+		return
+	}
+
+	token, err := h.socialService.LoginWithProvider(r.Context(), chi.URLParam(r, "provider"), code)
+	if err != nil {
+		switch {
+		case err == service.ErrUnknownProvider:
+			problem.Error(w, r, http.StatusNotFound, problem.NotFound, "Unknown login provider")
+		case err == service.ErrAccountLocked, err == repository.ErrTooManyAttempts:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountLocked, "Account is locked due to too many failed attempts")
+		case err == service.ErrTenantSuspended:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountSuspended, "Account is suspended")
+		case err == service.ErrAccountDisabled:
+			problem.Error(w, r, http.StatusForbidden, problem.AccountDisabled, "Account is disabled")
+		case err == service.ErrInvalidCredentials:
+			problem.Error(w, r, http.StatusForbidden, problem.LoginNotAllowed, "This account cannot sign in with a social login")
+		case errors.Is(err, oauth.ErrNoVerifiedEmail):
+			problem.Error(w, r, http.StatusUnauthorized, problem.EmailNotVerified, "A verified email address is required")
+		case errors.Is(err, oauth.ErrExchangeFailed), errors.Is(err, oauth.ErrProviderResponse):
+			problem.Error(w, r, http.StatusUnauthorized, problem.ExternalLogin, "Login with provider failed")
+		default:
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AuthResponse{Token: token})
+}
+
+// Helper function to generate a random OAuth state value
+func generateState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
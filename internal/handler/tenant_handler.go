@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// TenantHandler serves the admin API for onboarding and managing tenants
+type TenantHandler struct {
+	tenantService *service.TenantService
+	auditLogger   audit.Logger
+}
+
+func NewTenantHandler(tenantService *service.TenantService, auditLogger audit.Logger) *TenantHandler {
+	return &TenantHandler{
+		tenantService: tenantService,
+		auditLogger:   auditLogger,
+	}
+}
+
+type CreateTenantRequest struct {
+	Slug          string               `json:"slug"`
+	Name          string               `json:"name"`
+	Settings      model.TenantSettings `json:"settings"`
+	AdminEmail    string               `json:"admin_email" validate:"required,email"`
+	AdminPassword string               `json:"admin_password,omitempty"` // generated when omitted
+}
+
+type TenantResponse struct {
+	ID       int64                `json:"id"`
+	Slug     string               `json:"slug"`
+	Name     string               `json:"name"`
+	Status   string               `json:"status"`
+	Settings model.TenantSettings `json:"settings"`
+	Created  time.Time            `json:"created_at"`
+}
+
+type CreateTenantResponse struct {
+	Tenant        TenantResponse `json:"tenant"`
+	AdminID       int64          `json:"admin_id"`
+	AdminEmail    string         `json:"admin_email"`
+	AdminPassword string         `json:"admin_password,omitempty"` // only when generated
+}
+
+func newTenantResponse(tenant *model.Tenant) TenantResponse {
+	return TenantResponse{
+		ID:       tenant.ID,
+		Slug:     tenant.Slug,
+		Name:     tenant.Name,
+		Status:   tenant.Status,
+		Settings: tenant.Settings,
+		Created:  tenant.Created,
+	}
+}
+
+// Create onboards a tenant together with its first admin user
+func (h *TenantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateTenantRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tenant, admin, password, err := h.tenantService.OnboardTenant(r.Context(), &service.TenantOnboarding{
+		Slug:          req.Slug,
+		Name:          req.Name,
+		Settings:      req.Settings,
+		AdminEmail:    req.AdminEmail,
+		AdminPassword: req.AdminPassword,
+	})
+	if sendPasswordViolations(w, r, err) {
+		return
+	}
+	if err != nil {
+		sendTenantError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.tenant_created", tenant.ID, map[string]any{"slug": tenant.Slug, "admin_id": admin.ID})
+	writeJSON(w, http.StatusCreated, CreateTenantResponse{
+		Tenant:        newTenantResponse(tenant),
+		AdminID:       admin.ID,
+		AdminEmail:    admin.Email,
+		AdminPassword: password,
+	})
+}
+
+// List returns every tenant
+func (h *TenantHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.tenantService.ListTenants(r.Context())
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	resp := make([]TenantResponse, len(tenants))
+	for i, tenant := range tenants {
+		resp[i] = newTenantResponse(tenant)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tenants": resp})
+}
+
+// Get returns the tenant in the URL
+func (h *TenantHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := tenantID(w, r)
+	if !ok {
+		return
+	}
+
+	tenant, err := h.tenantService.GetTenant(r.Context(), tenantID)
+	if err != nil {
+		sendTenantError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTenantResponse(tenant))
+}
+
+// Configure replaces the settings of the tenant in the URL
+func (h *TenantHandler) Configure(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := tenantID(w, r)
+	if !ok {
+		return
+	}
+
+	var settings model.TenantSettings
+	if !decodeJSON(w, r, &settings) {
+		return
+	}
+
+	if err := h.tenantService.ConfigureTenant(r.Context(), tenantID, settings); err != nil {
+		sendTenantError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.tenant_configured", tenantID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": tenantID, "settings": settings})
+}
+
+// Suspend suspends the tenant in the URL and revokes its users' sessions
+func (h *TenantHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := tenantID(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.tenantService.SuspendTenant(r.Context(), tenantID)
+	if err != nil {
+		sendTenantError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.tenant_suspended", tenantID, map[string]any{"sessions_revoked": revoked})
+	writeJSON(w, http.StatusOK, map[string]any{"id": tenantID, "status": model.TenantStatusSuspended, "sessions_revoked": revoked})
+}
+
+// Activate lifts the suspension of the tenant in the URL
+func (h *TenantHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := tenantID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService.ActivateTenant(r.Context(), tenantID); err != nil {
+		sendTenantError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.tenant_activated", tenantID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": tenantID, "status": model.TenantStatusActive})
+}
+
+// Delete deletes the tenant in the URL with all of its users
+func (h *TenantHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := tenantID(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.tenantService.DeleteTenant(r.Context(), tenantID)
+	if err != nil {
+		sendTenantError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.tenant_deleted", tenantID, map[string]any{"users_deleted": deleted})
+	writeJSON(w, http.StatusOK, map[string]any{"id": tenantID, "users_deleted": deleted})
+}
+
+// record emits an audit event for a tenant change
+func (h *TenantHandler) record(r *http.Request, eventType string, tenantID int64, details map[string]any) {
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["tenant_id"] = tenantID
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      eventType,
+		Severity:  audit.SeverityWarning,
+		IPAddress: clientIP(r),
+		Details:   details,
+	})
+}
+
+// tenantID parses the tenant ID in the URL, writing an error when it is invalid
+func tenantID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid tenant ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// sendTenantError maps tenant errors to responses
+func sendTenantError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case err == repository.ErrTenantNotFound:
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "Tenant not found")
+	case err == repository.ErrDuplicateTenantSlug:
+		problem.Error(w, r, http.StatusConflict, problem.Conflict, "Tenant slug already exists")
+	case err == repository.ErrDuplicateEmail:
+		problem.Error(w, r, http.StatusConflict, problem.EmailTaken, "Email already registered")
+	case errors.Is(err, service.ErrInvalidTenant), errors.Is(err, service.ErrInvalidTenantSettings):
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, err.Error())
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+	}
+}
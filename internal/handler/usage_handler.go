@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/service"
+)
+
+// UsageHandler serves metered usage to admins, billing exports, and Prometheus
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// Get returns the usage of the month given as ?period=YYYY-MM, by default the current one
+func (h *UsageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.report(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Export returns the usage of a month as a CSV file for billing systems. Tenant
+// rows total all of a tenant's clients; client rows break them down.
+func (h *UsageHandler) Export(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.report(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="usage-%s.csv"`, report.Period.Format("2006-01")))
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"period", "level", "tenant_id", "client_id", "monthly_active_users", "logins", "tokens_issued", "emails_sent", "sms_sent"})
+	write := func(level string, records []*model.UsageRecord) {
+		for _, u := range records {
+			out.Write([]string{
+				report.Period.Format("2006-01"), level,
+				strconv.FormatInt(u.TenantID, 10), u.ClientID,
+				strconv.FormatInt(u.MonthlyActiveUsers, 10),
+				strconv.FormatInt(u.Logins, 10),
+				strconv.FormatInt(u.TokensIssued, 10),
+				strconv.FormatInt(u.EmailsSent, 10),
+				strconv.FormatInt(u.SMSSent, 10),
+			})
+		}
+	}
+	write("tenant", report.Tenants)
+	write("client", report.Clients)
+	out.Flush()
+}
+
+// Metrics exposes the current month's usage in the Prometheus text format
+func (h *UsageHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	report, err := h.usageService.Report(r.Context(), time.Now())
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	var b strings.Builder
+	gauge := func(name, help string, records []*model.UsageRecord, value func(*model.UsageRecord) int64, withClient bool) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, u := range records {
+			if withClient {
+				fmt.Fprintf(&b, "%s{tenant=\"%d\",client=%q} %d\n", name, u.TenantID, u.ClientID, value(u))
+			} else {
+				fmt.Fprintf(&b, "%s{tenant=\"%d\"} %d\n", name, u.TenantID, value(u))
+			}
+		}
+	}
+	gauge("auth_usage_tenant_monthly_active_users", "Distinct users of the tenant who signed in this month.",
+		report.Tenants, func(u *model.UsageRecord) int64 { return u.MonthlyActiveUsers }, false)
+	gauge("auth_usage_monthly_active_users", "Distinct users who signed in this month, per client.",
+		report.Clients, func(u *model.UsageRecord) int64 { return u.MonthlyActiveUsers }, true)
+	gauge("auth_usage_logins", "Sign-ins this month.",
+		report.Clients, func(u *model.UsageRecord) int64 { return u.Logins }, true)
+	gauge("auth_usage_tokens_issued", "Access tokens issued this month.",
+		report.Clients, func(u *model.UsageRecord) int64 { return u.TokensIssued }, true)
+	gauge("auth_usage_emails_sent", "Emails sent this month.",
+		report.Clients, func(u *model.UsageRecord) int64 { return u.EmailsSent }, true)
+	gauge("auth_usage_sms_sent", "SMS messages sent this month.",
+		report.Clients, func(u *model.UsageRecord) int64 { return u.SMSSent }, true)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// report loads the usage of the requested month, writing an error response on failure
+func (h *UsageHandler) report(w http.ResponseWriter, r *http.Request) (*model.UsageReport, bool) {
+	period := time.Now()
+	if value := r.URL.Query().Get("period"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid period, use YYYY-MM")
+			return nil, false
+		}
+		period = parsed
+	}
+
+	report, err := h.usageService.Report(r.Context(), period)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return nil, false
+	}
+	return report, true
+}
@@ -0,0 +1,819 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// UserAdminHandler serves the admin API for user accounts. Routes must be
+// behind middleware.RequireAdmin, which decides the tenant an administrator
+// may manage.
+type UserAdminHandler struct {
+	users       *service.UserAdminService
+	auditLogger audit.Logger
+}
+
+func NewUserAdminHandler(users *service.UserAdminService, auditLogger audit.Logger) *UserAdminHandler {
+	return &UserAdminHandler{
+		users:       users,
+		auditLogger: auditLogger,
+	}
+}
+
+type AdminUserResponse struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email"`
+	Type      string     `json:"type"`
+	Role      string     `json:"role"`
+	TenantID  *int64     `json:"tenant_id,omitempty"`
+	Locked    bool       `json:"locked"`
+	Disabled  bool       `json:"disabled"`
+	Verified  bool       `json:"email_verified"`
+	Canary    bool       `json:"canary"`
+	Status    string     `json:"status"` // a model.UserStatus* value
+	Created   time.Time  `json:"created_at"`
+	LastLogin *time.Time `json:"last_login,omitempty"`
+	Deleted   *time.Time `json:"deleted_at,omitempty"`
+	model.Profile
+}
+
+type LockoutResponse struct {
+	FailedAttempts    int64 `json:"failed_attempts"`
+	MaxFailedAttempts int64 `json:"max_failed_attempts"`
+	Locked            bool  `json:"locked"`
+}
+
+type AdminUserDetailResponse struct {
+	AdminUserResponse
+	Lockout LockoutResponse `json:"lockout"`
+}
+
+// CreateUserRequest is the body of an admin request to create a user; without
+// a password a temporary one is generated
+type CreateUserRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password,omitempty"`
+}
+
+type CreateUserResponse struct {
+	AdminUserResponse
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+}
+
+// ImportUsersRequest is the JSON body of an admin request to import users
+type ImportUsersRequest struct {
+	Users []service.ImportUser `json:"users" validate:"min=1"`
+}
+
+// importBaseTime and importRowTime make up the write deadline of an import:
+// the server's usual write timeout, plus enough per row to hash a password
+// with the default Argon2id parameters on one worker
+const (
+	importBaseTime = 15 * time.Second
+	importRowTime  = 250 * time.Millisecond
+)
+
+// An export gets exportPageTime, the server's usual write timeout, to write
+// each exportPageRows rows, the users it reads per query
+const (
+	exportPageRows = 1000
+	exportPageTime = 15 * time.Second
+)
+
+// importProblem is the error response of an import that stopped partway, with
+// the outcome of every row
+type importProblem struct {
+	problem.Problem
+	*service.ImportResult
+}
+
+// UpdateMetadataRequest is the body of an admin request to update a user's
+// metadata; each section is merged into the stored one, removing keys set to null
+type UpdateMetadataRequest struct {
+	AppMetadata  map[string]any `json:"app_metadata,omitempty"`
+	UserMetadata map[string]any `json:"user_metadata,omitempty"`
+}
+
+type SetDisabledRequest struct {
+	Disabled bool `json:"disabled"`
+}
+
+// ImpersonateRequest is the body of an admin request to act as a user. The
+// token is valid for expires_in seconds, 15 minutes by default and at most an hour.
+type ImpersonateRequest struct {
+	Reason    string `json:"reason" validate:"required,max=500"`
+	ExpiresIn int    `json:"expires_in,omitempty" validate:"min=0,max=3600"`
+}
+
+type ImpersonateResponse struct {
+	UserID    int64     `json:"user_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newAdminUserResponse(user *model.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Type:      user.Type,
+		Role:      user.Role,
+		TenantID:  user.TenantID,
+		Locked:    user.IsLocked,
+		Disabled:  user.IsDisabled,
+		Verified:  user.EmailVerified,
+		Canary:    user.IsCanary,
+		Status:    user.Status(),
+		Created:   user.Created,
+		LastLogin: user.LastLogin,
+		Deleted:   user.DeletedAt,
+		Profile:   user.Profile,
+	}
+}
+
+// List returns the users matching the query filters: email (prefix), role,
+// type, locked, disabled, verified, tenant_id, created_after and
+// created_before (RFC 3339), paged with limit and cursor. Soft-deleted users
+// are listed instead of live ones with deleted=true.
+func (h *UserAdminHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := adminTenant(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid filter")
+		return
+	}
+
+	users, next, err := h.users.SearchUsers(r.Context(), tenantID, filter)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	resp := make([]AdminUserResponse, len(users))
+	for i, user := range users {
+		resp[i] = newAdminUserResponse(user)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"users": resp, "next_cursor": next})
+}
+
+// userExportField is a field of the user export
+type userExportField struct {
+	name  string
+	value func(*model.User) any
+}
+
+// userExportFields are the fields an export can select, in the order of a
+// full export. Password hashes and metadata are never exported.
+var userExportFields = []userExportField{
+	{"id", func(u *model.User) any { return u.ID }},
+	{"email", func(u *model.User) any { return u.Email }},
+	{"type", func(u *model.User) any { return u.Type }},
+	{"role", func(u *model.User) any { return u.Role }},
+	{"tenant_id", func(u *model.User) any { return u.TenantID }},
+	{"status", func(u *model.User) any { return u.Status() }},
+	{"locked", func(u *model.User) any { return u.IsLocked }},
+	{"disabled", func(u *model.User) any { return u.IsDisabled }},
+	{"email_verified", func(u *model.User) any { return u.EmailVerified }},
+	{"canary", func(u *model.User) any { return u.IsCanary }},
+	{"created_at", func(u *model.User) any { return u.Created }},
+	{"last_login", func(u *model.User) any { return u.LastLogin }},
+	{"deleted_at", func(u *model.User) any { return u.DeletedAt }},
+	{"display_name", func(u *model.User) any { return u.DisplayName }},
+	{"given_name", func(u *model.User) any { return u.GivenName }},
+	{"family_name", func(u *model.User) any { return u.FamilyName }},
+	{"locale", func(u *model.User) any { return u.Locale }},
+	{"timezone", func(u *model.User) any { return u.Timezone }},
+	{"avatar_url", func(u *model.User) any { return u.AvatarURL }},
+}
+
+// Export streams the users matching the List filters as CSV, or as NDJSON
+// with format=ndjson, limited to the comma-separated fields when given. Users
+// are read and written a page at a time, so large exports use little memory.
+func (h *UserAdminHandler) Export(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := adminTenant(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid filter")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid format")
+		return
+	}
+	fields := userExportFields
+	if v := r.URL.Query().Get("fields"); v != "" {
+		fields = fields[:0:0]
+		for _, name := range strings.Split(v, ",") {
+			i := slices.IndexFunc(userExportFields, func(f userExportField) bool { return f.name == strings.TrimSpace(name) })
+			if i < 0 {
+				problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid fields")
+				return
+			}
+			fields = append(fields, userExportFields[i])
+		}
+	}
+
+	// Large exports outlast the server's write timeout, so the deadline moves
+	// forward with every page of rows written, and a client that stops reading
+	// still times out. Writers without deadlines need none.
+	rc := http.NewResponseController(w)
+	extendDeadline := func(rows int) {
+		if err := rc.SetWriteDeadline(time.Now().Add(exportPageTime)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.WarnContext(r.Context(), "failed to extend the write deadline of an export", "rows", rows, "err", err)
+		}
+	}
+	extendDeadline(0)
+	filename := fmt.Sprintf("users-%s.%s", time.Now().UTC().Format(time.DateOnly), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	var write func(*model.User) error
+	var flush func() error
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		out := csv.NewWriter(w)
+		record := make([]string, len(fields))
+		for i, f := range fields {
+			record[i] = f.name
+		}
+		out.Write(record)
+		write = func(u *model.User) error {
+			for i, f := range fields {
+				record[i] = csvValue(f.value(u))
+			}
+			return out.Write(record)
+		}
+		flush = func() error {
+			out.Flush()
+			return out.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		object := make(map[string]any, len(fields))
+		write = func(u *model.User) error {
+			for _, f := range fields {
+				object[f.name] = f.value(u)
+			}
+			return enc.Encode(object)
+		}
+		flush = func() error { return nil }
+	}
+
+	rows := 0
+	err = h.users.ExportUsers(r.Context(), tenantID, filter, func(u *model.User) error {
+		rows++
+		if rows%exportPageRows == 0 {
+			extendDeadline(rows)
+		}
+		return write(u)
+	})
+	if err == nil {
+		err = flush()
+	}
+	actorID, _ := UserFromContext(r.Context())
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      "admin.users_exported",
+		Severity:  audit.SeverityWarning,
+		ActorID:   actorID,
+		IPAddress: clientIP(r),
+		Details:   map[string]any{"format": format, "rows": rows, "complete": err == nil},
+	})
+	if err != nil {
+		if rows == 0 {
+			problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+			return
+		}
+		// The status is sent, so abort the response to show it is incomplete
+		slog.ErrorContext(r.Context(), "user export failed", "rows", rows, "err", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// csvValue formats a user export field for CSV. Strings starting with a
+// character spreadsheets read as a formula are prefixed with a quote.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// Create creates a user. Routes must be limited to the admin token, since the
+// user is not added to the tenant of an administrator.
+func (h *UserAdminHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, temporary, err := h.users.CreateUser(r.Context(), req.Email, req.Password)
+	if sendPasswordViolations(w, r, err) {
+		return
+	}
+	switch err {
+	case nil:
+	case repository.ErrDuplicateEmail:
+		problem.Error(w, r, http.StatusConflict, problem.EmailTaken, "Email is already registered")
+		return
+	case service.ErrEmailDomainBlocked:
+		problem.Error(w, r, http.StatusForbidden, problem.EmailDomainBlocked, "Registrations from this email domain are not allowed")
+		return
+	case service.ErrInvalidCredentials:
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, err.Error())
+		return
+	case service.ErrBreachCheckFailed:
+		problem.Error(w, r, http.StatusServiceUnavailable, problem.ServiceUnavailable, err.Error())
+		return
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	h.record(r, "admin.user_created", audit.SeverityInfo, user.ID, map[string]any{"email": user.Email})
+	writeJSON(w, http.StatusCreated, CreateUserResponse{AdminUserResponse: newAdminUserResponse(user), TemporaryPassword: temporary})
+}
+
+// Import creates users from a CSV body (text/csv) or a JSON one, reporting
+// on every row; with dry_run=true the rows are only validated. Routes must be
+// limited to the admin token, like Create.
+func (h *UserAdminHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var dryRun bool
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid dry_run")
+			return
+		}
+	}
+
+	var users []service.ImportUser
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		var err error
+		users, err = service.ReadImportCSV(r.Body)
+		var sizeErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &sizeErr):
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.BodyTooLarge, fmt.Sprintf("body must not exceed %d bytes", sizeErr.Limit))
+			return
+		case err != nil:
+			problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, err.Error())
+			return
+		case len(users) == 0:
+			problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, "No users to import")
+			return
+		}
+	} else {
+		var req ImportUsersRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		users = req.Users
+	}
+
+	// Hashing the passwords of a large import outlasts the server's write
+	// timeout, so the deadline grows with the rows. Writers without deadlines,
+	// such as authctl's in-process transport, need none.
+	deadline := time.Now().Add(importBaseTime + importRowTime*time.Duration(len(users)))
+	if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.WarnContext(r.Context(), "failed to extend the write deadline of an import", "rows", len(users), "err", err)
+	}
+	result, err := h.users.ImportUsers(r.Context(), users, dryRun)
+	switch {
+	case err == nil:
+	case err == service.ErrImportTooLarge:
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, err.Error())
+		return
+	case result == nil:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	default:
+		slog.ErrorContext(r.Context(), "import stopped partway", "created", result.Created, "err", err)
+	}
+
+	if !dryRun {
+		actorID, _ := UserFromContext(r.Context())
+		h.auditLogger.Record(r.Context(), audit.Event{
+			Type:      "admin.users_imported",
+			Severity:  audit.SeverityWarning,
+			ActorID:   actorID,
+			IPAddress: clientIP(r),
+			Details:   map[string]any{"created": result.Created, "failed": result.Failed, "complete": err == nil},
+		})
+	}
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, importProblem{
+			Problem:      problem.New(r, http.StatusInternalServerError, problem.InternalError, "The import stopped at an error"),
+			ImportResult: result,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Get returns the user in the URL with the state of its account lockout
+func (h *UserAdminHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, lockout, err := h.users.GetUser(r.Context(), tenantID, userID)
+	if err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AdminUserDetailResponse{
+		AdminUserResponse: newAdminUserResponse(user),
+		Lockout: LockoutResponse{
+			FailedAttempts:    lockout.FailedAttempts,
+			MaxFailedAttempts: lockout.MaxFailedAttempts,
+			Locked:            lockout.Locked,
+		},
+	})
+}
+
+// Metadata returns the app and user metadata of the user in the URL
+func (h *UserAdminHandler) Metadata(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	metadata, err := h.users.Metadata(r.Context(), tenantID, userID)
+	if err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+// UpdateMetadata merges the request's app_metadata and user_metadata into
+// those of the user in the URL
+func (h *UserAdminHandler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateMetadataRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	metadata, err := h.users.UpdateMetadata(r.Context(), tenantID, userID, req.AppMetadata, req.UserMetadata)
+	if err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.metadata_updated", audit.SeverityInfo, userID, map[string]any{
+		"app_metadata":  len(req.AppMetadata) > 0,
+		"user_metadata": len(req.UserMetadata) > 0,
+	})
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+// SetDisabled disables or re-enables the user in the URL. Disabled users
+// cannot sign in and their sessions are revoked.
+func (h *UserAdminHandler) SetDisabled(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetDisabledRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.users.SetDisabled(r.Context(), tenantID, userID, req.Disabled); err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	eventType := "admin.user_enabled"
+	if req.Disabled {
+		eventType = "admin.user_disabled"
+	}
+	h.record(r, eventType, audit.SeverityWarning, userID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": userID, "disabled": req.Disabled})
+}
+
+// ResetPassword replaces the password of the user in the URL with a temporary
+// one, which is only returned in this response
+func (h *UserAdminHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	password, err := h.users.ResetPassword(r.Context(), tenantID, userID)
+	if err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.password_reset", audit.SeverityWarning, userID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": userID, "temporary_password": password})
+}
+
+// ExpirePassword forces the user in the URL to change their password at the
+// next sign-in
+func (h *UserAdminHandler) ExpirePassword(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.users.ExpirePassword(r.Context(), tenantID, userID); err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.password_expired", audit.SeverityInfo, userID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": userID, "password_expired": true})
+}
+
+// Unlock clears the lockout of the user in the URL
+func (h *UserAdminHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.users.Unlock(r.Context(), tenantID, userID); err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.user_unlocked", audit.SeverityInfo, userID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": userID, "locked": false})
+}
+
+// Delete soft-deletes the user in the URL and revokes its sessions
+func (h *UserAdminHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.users.DeleteUser(r.Context(), tenantID, userID); err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.user_deleted", audit.SeverityWarning, userID, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "User deleted"})
+}
+
+// Restore undoes the soft deletion of the user in the URL
+func (h *UserAdminHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.users.RestoreUser(r.Context(), tenantID, userID); err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.user_restored", audit.SeverityInfo, userID, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "User restored"})
+}
+
+// Sessions returns the active sessions of the user in the URL, paged with limit and cursor
+func (h *UserAdminHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid page")
+		return
+	}
+
+	sessions, next, err := h.users.ListSessions(r.Context(), tenantID, userID, page)
+	if err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	if sessions == nil {
+		sessions = []*model.Session{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions, "next_cursor": next})
+}
+
+// RevokeSessions revokes every active session of the user in the URL
+func (h *UserAdminHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.users.RevokeSessions(r.Context(), tenantID, userID)
+	if err != nil {
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.sessions_revoked", audit.SeverityWarning, userID, map[string]any{"revoked": revoked})
+	writeJSON(w, http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+// Impersonate issues a time-boxed token for the user in the URL that lets the
+// signed-in admin act as them. The token carries an act claim naming the admin,
+// and the impersonation shows in the user's login history.
+func (h *UserAdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := adminUserTarget(w, r)
+	if !ok {
+		return
+	}
+	adminID, ok := UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Impersonation requires a signed-in admin")
+		return
+	}
+
+	var req ImpersonateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	expiry := time.Duration(req.ExpiresIn) * time.Second
+	impersonation, err := h.users.Impersonate(r.Context(), tenantID, adminID, userID, expiry, req.Reason)
+	switch err {
+	case nil:
+	case service.ErrImpersonationNotAllowed:
+		problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Admins and yourself cannot be impersonated")
+		return
+	case service.ErrAccountDisabled:
+		problem.Error(w, r, http.StatusConflict, problem.AccountDisabled, "Account is disabled")
+		return
+	default:
+		sendUserAdminError(w, r, err)
+		return
+	}
+
+	h.record(r, "admin.impersonation_started", audit.SeverityHigh, userID, map[string]any{
+		"reason":     req.Reason,
+		"expires_at": impersonation.ExpiresAt,
+	})
+	writeJSON(w, http.StatusOK, ImpersonateResponse{UserID: userID, Token: impersonation.Token, ExpiresAt: impersonation.ExpiresAt})
+}
+
+// record emits an audit event for a user management action, attributed to
+// the administrator when it signed in as a user
+func (h *UserAdminHandler) record(r *http.Request, eventType string, severity string, userID int64, details map[string]any) {
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["user_id"] = userID
+	actorID, _ := UserFromContext(r.Context())
+	h.auditLogger.Record(r.Context(), audit.Event{
+		Type:      eventType,
+		Severity:  severity,
+		ActorID:   actorID,
+		IPAddress: clientIP(r),
+		Details:   details,
+	})
+}
+
+// parseUserFilter reads the admin user listing filters from query parameters
+func parseUserFilter(q url.Values) (model.UserFilter, error) {
+	page, err := pagination.FromQuery(q)
+	if err != nil {
+		return model.UserFilter{}, err
+	}
+	filter := model.UserFilter{
+		EmailPrefix: q.Get("email"),
+		Role:        q.Get("role"),
+		Type:        q.Get("type"),
+		Page:        page,
+	}
+
+	for name, dst := range map[string]**bool{"locked": &filter.Locked, "disabled": &filter.Disabled, "verified": &filter.Verified} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return filter, err
+			}
+			*dst = &b
+		}
+	}
+	if v := q.Get("tenant_id"); v != "" {
+		tenantID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, err
+		}
+		filter.TenantID = &tenantID
+	}
+	if v := q.Get("deleted"); v != "" {
+		if filter.Deleted, err = strconv.ParseBool(v); err != nil {
+			return filter, err
+		}
+	}
+	for name, dst := range map[string]*time.Time{"created_after": &filter.CreatedAfter, "created_before": &filter.CreatedBefore} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, err
+			}
+			*dst = t
+		}
+	}
+	return filter, nil
+}
+
+// adminTenant returns the administrator's tenant scope, rejecting requests
+// that did not pass middleware.RequireAdmin
+func adminTenant(w http.ResponseWriter, r *http.Request) (*int64, bool) {
+	tenantID, ok := middleware.AdminTenantFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Forbidden")
+	}
+	return tenantID, ok
+}
+
+// adminUserTarget returns the administrator's scope and the user ID in the URL
+func adminUserTarget(w http.ResponseWriter, r *http.Request) (*int64, int64, bool) {
+	tenantID, ok := adminTenant(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid user ID")
+		return nil, 0, false
+	}
+	return tenantID, userID, true
+}
+
+// sendUserAdminError maps user management errors to responses
+func sendUserAdminError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case repository.ErrUserNotFound:
+		problem.Error(w, r, http.StatusNotFound, problem.NotFound, "User not found")
+	case service.ErrMetadataTooLarge:
+		problem.Error(w, r, http.StatusBadRequest, problem.ValidationFailed, "Metadata is too large")
+	case service.ErrHumanUsersOnly:
+		problem.Error(w, r, http.StatusConflict, problem.Conflict, "Not supported for service accounts")
+	default:
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/Stewz00/go-auth-service/internal/test"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestParseUserFilter(t *testing.T) {
+	yes := true
+	no := false
+	tenantID := int64(3)
+	firstPage := pagination.Page{Limit: pagination.DefaultLimit}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    model.UserFilter
+		wantErr bool
+	}{
+		{name: "no filters", query: "", want: model.UserFilter{Page: firstPage}},
+		{
+			name:  "all filters",
+			query: "email=ann&role=admin&type=human&locked=true&disabled=false&verified=1&tenant_id=3&limit=10",
+			want: model.UserFilter{
+				EmailPrefix: "ann", Role: model.RoleAdmin, Type: model.UserTypeHuman,
+				Locked: &yes, Disabled: &no, Verified: &yes, TenantID: &tenantID,
+				Page: pagination.Page{Limit: 10},
+			},
+		},
+		{
+			name:  "created range",
+			query: "created_after=2026-01-01T00:00:00Z&created_before=2026-02-01T00:00:00Z",
+			want: model.UserFilter{
+				CreatedAfter:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+				Page:          firstPage,
+			},
+		},
+		{name: "invalid date", query: "created_after=yesterday", wantErr: true},
+		{name: "invalid flag", query: "locked=maybe", wantErr: true},
+		{name: "invalid tenant", query: "tenant_id=acme", wantErr: true},
+		{name: "invalid cursor", query: "cursor=%21", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := parseUserFilter(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// failingCreateRepository fails to create users once it created limit of them
+type failingCreateRepository struct {
+	*test.MockUserRepository
+	limit int
+}
+
+func (r *failingCreateRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	return fn(r)
+}
+
+func (r *failingCreateRepository) CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error) {
+	if r.limit == 0 {
+		return nil, errors.New("database unavailable")
+	}
+	r.limit--
+	return r.MockUserRepository.CreateUser(ctx, email, passwordHash)
+}
+
+// recordingAuditLogger keeps the audit events it records
+type recordingAuditLogger struct {
+	events []audit.Event
+}
+
+func (l *recordingAuditLogger) Record(ctx context.Context, event audit.Event) {
+	l.events = append(l.events, event)
+}
+
+func TestUserAdminHandler_ImportStoppedPartway(t *testing.T) {
+	repo := &failingCreateRepository{MockUserRepository: test.NewMockUserRepository(), limit: 500}
+	authService := service.NewAuthService(repo, repo, "test-secret")
+	logger := &recordingAuditLogger{}
+	handler := NewUserAdminHandler(service.NewUserAdminService(repo, authService), logger)
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("legacy-secret"), bcrypt.MinCost)
+
+	// The second batch of 500 fails after the first was committed
+	var body strings.Builder
+	body.WriteString("email,password_hash\n")
+	for i := range 501 {
+		fmt.Fprintf(&body, "user%d@example.com,%s\n", i, legacy)
+	}
+	req := httptest.NewRequest("POST", "/admin/users/import", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	handler.Import(w, req)
+
+	var resp struct {
+		problem.Problem
+		service.ImportResult
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusInternalServerError || resp.Code != problem.InternalError || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("got status %d and problem %+v, want a 500 problem", w.Code, resp.Problem)
+	}
+	if resp.Created != 500 || resp.Failed != 1 || len(resp.Rows) != 501 {
+		t.Fatalf("got %d created, %d failed, %d rows; want 500, 1, 501", resp.Created, resp.Failed, len(resp.Rows))
+	}
+	if resp.Rows[0].Status != service.ImportStatusCreated || resp.Rows[0].UserID == 0 || resp.Rows[500].Status != service.ImportStatusFailed {
+		t.Errorf("got rows %+v and %+v, want the first created and the last failed", resp.Rows[0], resp.Rows[500])
+	}
+
+	if len(logger.events) != 1 || logger.events[0].Type != "admin.users_imported" || logger.events[0].Details["complete"] != false {
+		t.Errorf("got audit events %+v, want an incomplete admin.users_imported", logger.events)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+// WebhookHandler serves the webhook delivery log to admins
+type WebhookHandler struct {
+	deliveries interfaces.WebhookDeliveryRepository
+}
+
+func NewWebhookHandler(deliveries interfaces.WebhookDeliveryRepository) *WebhookHandler {
+	return &WebhookHandler{deliveries: deliveries}
+}
+
+// ListDeliveries returns delivery attempts newest first, optionally filtered
+// by event_id, event_type and failed=true, paged with limit and cursor
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, err := pagination.FromQuery(q)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid page")
+		return
+	}
+
+	filter := model.WebhookDeliveryFilter{
+		EventID:    q.Get("event_id"),
+		EventType:  q.Get("event_type"),
+		FailedOnly: q.Get("failed") == "true",
+		Page:       page,
+	}
+	deliveries, next, err := h.deliveries.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.InternalError, "Internal server error")
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []*model.WebhookDelivery{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": deliveries, "next_cursor": next})
+}
@@ -0,0 +1,181 @@
+// Package i18n translates user-facing messages, such as error details and
+// account emails, into the language a client asks for with Accept-Language.
+//
+// Messages are keyed by their English text, so code and templates stay
+// readable and English needs no catalog. Each other language has a TOML file
+// named after its BCP 47 tag (de.toml, pt-BR.toml) mapping English messages
+// to translations:
+//
+//	"Account is disabled" = "Das Konto ist deaktiviert"
+//	"%d hours" = "%d Stunden"
+//
+// Messages missing from a catalog are left in English.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.toml
+var embedded embed.FS
+
+// Catalog holds the translations of every supported language
+type Catalog struct {
+	messages map[language.Tag]map[string]string
+	tags     []language.Tag // English first, as the fallback of the matcher
+	matcher  language.Matcher
+}
+
+// LoadCatalog reads the built-in catalogs and, when dir is set, the catalogs
+// in it. Entries in dir override built-in ones of the same language, so a
+// deployment can reword a few messages or add a language. Every file is
+// checked up front, so mistakes fail at startup.
+func LoadCatalog(dir string) (*Catalog, error) {
+	c := &Catalog{messages: map[language.Tag]map[string]string{}}
+	base, err := fs.Sub(embedded, "locales")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.load(base); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("i18n: locales directory %q not found", dir)
+		}
+		if err := c.load(os.DirFS(dir)); err != nil {
+			return nil, err
+		}
+	}
+
+	c.tags = []language.Tag{language.English}
+	for tag := range c.messages {
+		c.tags = append(c.tags, tag)
+	}
+	slices.SortFunc(c.tags[1:], func(a, b language.Tag) int { return strings.Compare(a.String(), b.String()) })
+	c.matcher = language.NewMatcher(c.tags)
+	return c, nil
+}
+
+// load adds the catalogs in files to c
+func (c *Catalog) load(files fs.FS) error {
+	names, err := fs.Glob(files, "*.toml")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		tag, err := language.Parse(strings.TrimSuffix(name, path.Ext(name)))
+		if err != nil {
+			return fmt.Errorf("i18n: %s is not named after a language tag", name)
+		}
+		b, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		var entries map[string]string
+		if err := toml.Unmarshal(b, &entries); err != nil {
+			return fmt.Errorf("i18n: invalid %s: %v", name, err)
+		}
+		for msg, translation := range entries {
+			if !slices.Equal(verbs(msg), verbs(translation)) {
+				return fmt.Errorf("i18n: %s: translation of %q must use the verbs %v", name, msg, verbs(msg))
+			}
+		}
+		if c.messages[tag] == nil {
+			c.messages[tag] = map[string]string{}
+		}
+		for msg, translation := range entries {
+			c.messages[tag][msg] = translation
+		}
+	}
+	return nil
+}
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// verbs returns the formatting verbs of a message, which a translation must
+// keep in the same order
+func verbs(msg string) []string {
+	return verbPattern.FindAllString(msg, -1)
+}
+
+// DefaultCatalog returns the built-in catalogs
+var DefaultCatalog = sync.OnceValue(func() *Catalog {
+	c, err := LoadCatalog("")
+	if err != nil {
+		panic(err)
+	}
+	return c
+})
+
+// Localizer returns a localizer for the best supported match of an
+// Accept-Language header, English when nothing matches
+func (c *Catalog) Localizer(acceptLanguage string) *Localizer {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, i, _ := c.matcher.Match(tags...)
+	tag := c.tags[i]
+	return &Localizer{tag: tag, messages: c.messages[tag]}
+}
+
+// Localizer translates messages into one language. A nil Localizer leaves
+// them in English.
+type Localizer struct {
+	tag      language.Tag
+	messages map[string]string
+}
+
+// Language returns the BCP 47 tag of the localizer's language
+func (l *Localizer) Language() string {
+	if l == nil {
+		return language.English.String()
+	}
+	return l.tag.String()
+}
+
+// Text translates msg and, with args, formats it like fmt.Sprintf
+func (l *Localizer) Text(msg string, args ...any) string {
+	if l != nil {
+		if translation, ok := l.messages[msg]; ok {
+			msg = translation
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+type contextKey struct{}
+
+// WithLocalizer returns a context carrying l
+func WithLocalizer(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the localizer stored by Middleware, or nil for English
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
+
+// Middleware stores a localizer for the request's Accept-Language header in
+// its context, for FromContext
+func (c *Catalog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := c.Localizer(r.Header.Get("Accept-Language"))
+		next.ServeHTTP(w, r.WithContext(WithLocalizer(r.Context(), l)))
+	})
+}
@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalizer(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		wantLanguage   string
+		wantText       string
+	}{
+		{"", "en", "Account is disabled"},
+		{"de", "de", "Das Konto ist deaktiviert"},
+		{"de-AT", "de", "Das Konto ist deaktiviert"},
+		{"es-MX,es;q=0.9", "es", "La cuenta está desactivada"},
+		{"fr-FR,fr;q=0.9", "en", "Account is disabled"},
+		{"fr,es;q=0.5,de;q=0.8", "de", "Das Konto ist deaktiviert"},
+		{"not a language", "en", "Account is disabled"},
+	}
+	c := DefaultCatalog()
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			l := c.Localizer(tt.acceptLanguage)
+			if got := l.Language(); got != tt.wantLanguage {
+				t.Errorf("Language() = %q, want %q", got, tt.wantLanguage)
+			}
+			if got := l.Text("Account is disabled"); got != tt.wantText {
+				t.Errorf("Text() = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+
+	var l *Localizer
+	if got := l.Text("%d hours", 3); got != "3 hours" || l.Language() != "en" {
+		t.Errorf("nil Localizer: Text() = %q, Language() = %q, want English", got, l.Language())
+	}
+	if got := c.Localizer("de").Text("%d hours", 3); got != "3 Stunden" {
+		t.Errorf("Text() = %q, want %q", got, "3 Stunden")
+	}
+	if got := c.Localizer("de").Text("Not in any catalog"); got != "Not in any catalog" {
+		t.Errorf("Text() = %q, want the English message", got)
+	}
+}
+
+func TestLoadCatalogOverrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("de.toml", `"Account is disabled" = "Konto deaktiviert"`)
+	write("pt-BR.toml", `"Account is disabled" = "A conta está desativada"`)
+
+	c, err := LoadCatalog(dir)
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+	de := c.Localizer("de")
+	if got := de.Text("Account is disabled"); got != "Konto deaktiviert" {
+		t.Errorf("Text() = %q, want the overridden translation", got)
+	}
+	if got := de.Text("Account is suspended"); got != "Das Konto ist gesperrt" {
+		t.Errorf("Text() = %q, want the built-in translation", got)
+	}
+	if got := c.Localizer("pt-BR").Language(); got != "pt-BR" {
+		t.Errorf("Language() = %q, want the added language", got)
+	}
+
+	write("de.toml", `"%d hours" = "Stunden"`)
+	if _, err := LoadCatalog(dir); err == nil {
+		t.Error("LoadCatalog() accepted a translation without the message's verbs")
+	}
+	write("de.toml", `"Account is disabled" = `)
+	if _, err := LoadCatalog(dir); err == nil {
+		t.Error("LoadCatalog() accepted an invalid file")
+	}
+	os.Remove(filepath.Join(dir, "de.toml"))
+	write("german.toml", `"Account is disabled" = "Konto deaktiviert"`)
+	if _, err := LoadCatalog(dir); err == nil {
+		t.Error("LoadCatalog() accepted a file not named after a language tag")
+	}
+	if _, err := LoadCatalog(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadCatalog() accepted a missing directory")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got string
+	h := DefaultCatalog().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context()).Text("Invalid token")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "es")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "Token no válido" {
+		t.Errorf("got %q, want the Spanish translation", got)
+	}
+}
@@ -4,16 +4,156 @@ import (
 	"context"
 	"time"
 
+	"github.com/Stewz00/go-auth-service/internal/audit"
 	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
 )
 
-// UserRepository defines the interface for user-related database operations
+// UserRepository combines the user and session stores of backends that keep both
 type UserRepository interface {
+	UserStore
+	SessionStore
+
+	// WithTx runs fn with a repository whose writes are applied atomically:
+	// committed when fn returns nil and rolled back otherwise
+	WithTx(ctx context.Context, fn func(repo UserRepository) error) error
+}
+
+// LoginRecorder is implemented by user repositories that record a sign-in
+// more cheaply than UpdateLastLogin and CreateSession in a transaction
+type LoginRecorder interface {
+	// RecordLogin resets the user's failed attempts, updates their last login
+	// and stores the session atomically
+	RecordLogin(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error
+}
+
+// EventOutbox is implemented by user repositories that keep an outbox of
+// events, so an event is stored in the same transaction as the change it
+// describes and published by an outbox relay after the commit
+type EventOutbox interface {
+	AddOutboxEvent(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// UserStore defines the interface for user account storage
+type UserStore interface {
 	CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByID(ctx context.Context, userID int64) (*model.User, error)
+	SetCanary(ctx context.Context, userID int64, canary bool) error
+	CreateServiceAccount(ctx context.Context, email string) (*model.User, error)
+	ListServiceAccounts(ctx context.Context) ([]*model.User, error)
+	SetServiceAccountLocked(ctx context.Context, userID int64, locked bool) error
+	DeleteServiceAccount(ctx context.Context, userID int64) error
+	SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error)
+	GetUserForAdmin(ctx context.Context, userID int64) (*model.User, error)
+	SetUserDisabled(ctx context.Context, userID int64, disabled bool) error
+	GetUserMetadata(ctx context.Context, userID int64) (*model.UserMetadata, error)
+	SetUserMetadata(ctx context.Context, userID int64, metadata *model.UserMetadata) error
+	UpdateUserProfile(ctx context.Context, userID int64, profile model.Profile) error
+	UpdatePassword(ctx context.Context, userID int64, passwordHash string) error
+	RehashPassword(ctx context.Context, userID int64, oldHash, newHash string) error
+	ExpirePassword(ctx context.Context, userID int64) error
+	UnlockUser(ctx context.Context, userID int64) error
+	MarkEmailVerified(ctx context.Context, userID int64) error
+	SoftDeleteUser(ctx context.Context, userID int64) error
+	RestoreUser(ctx context.Context, userID int64) error
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
 	UpdateLastLogin(ctx context.Context, userID int64) error
-	IncrementFailedAttempts(ctx context.Context, userID int64) error
-	CreateSession(ctx context.Context, userID int64, tokenID string, expiresAt time.Time) error
+	IncrementFailedAttempts(ctx context.Context, userID int64, policy model.LockoutPolicy) error
+}
+
+// SessionStore defines the interface for session storage, which may live in a
+// different backend than users
+type SessionStore interface {
+	CreateSession(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error
 	RevokeSession(ctx context.Context, tokenID string) error
+	RevokeAllSessions(ctx context.Context, userID int64) (int64, error)
+	ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error)
 	IsSessionValid(ctx context.Context, tokenID string) (bool, error)
 }
+
+// AuditRepository defines the interface for storing and reading back audit events
+type AuditRepository interface {
+	audit.BatchLogger
+	ListEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error)
+}
+
+// IdentityRepository defines the interface for linking external provider identities to users
+type IdentityRepository interface {
+	GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*model.User, error)
+	LinkIdentity(ctx context.Context, userID int64, provider, providerUserID, email string) error
+}
+
+// OAuthRepository defines the interface for OAuth client and authorization code storage
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *model.OAuthClient) error
+	GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	SaveAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error)
+}
+
+// ConsentRepository defines the interface for storing consent receipts
+type ConsentRepository interface {
+	CreateConsentReceipt(ctx context.Context, receipt *model.ConsentReceipt) error
+	ListConsentReceipts(ctx context.Context, userID int64) ([]*model.ConsentReceipt, error)
+}
+
+// APIKeyRepository defines the interface for storing user API keys
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	ListAPIKeys(ctx context.Context, userID int64) ([]*model.APIKey, error)
+	UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID int64) error
+}
+
+// BreakGlassRepository defines the interface for tracking single-use break-glass
+// credentials and the admin sessions they were redeemed for
+type BreakGlassRepository interface {
+	RedeemBreakGlassCredential(ctx context.Context, credentialHash, ipAddress, sessionHash string, sessionExpiresAt time.Time) error
+	IsBreakGlassSessionValid(ctx context.Context, sessionHash string) (bool, error)
+}
+
+// EmailDomainRepository defines the interface for storing email domains
+// blocked at registration
+type EmailDomainRepository interface {
+	BlockDomain(ctx context.Context, domain *model.BlockedDomain) error
+	UnblockDomain(ctx context.Context, domain string) error
+	ListBlockedDomains(ctx context.Context) ([]*model.BlockedDomain, error)
+	IsDomainBlocked(ctx context.Context, domains ...string) (bool, error)
+}
+
+// TenantRepository defines the interface for tenant lifecycle and settings storage
+type TenantRepository interface {
+	OnboardTenant(ctx context.Context, tenant *model.Tenant, adminEmail, adminPasswordHash string) (*model.User, error)
+	GetTenant(ctx context.Context, tenantID int64) (*model.Tenant, error)
+	ListTenants(ctx context.Context) ([]*model.Tenant, error)
+	UpdateTenantSettings(ctx context.Context, tenantID int64, settings model.TenantSettings) error
+	SetTenantStatus(ctx context.Context, tenantID int64, status string) error
+	RevokeTenantSessions(ctx context.Context, tenantID int64) (int64, error)
+	DeleteTenant(ctx context.Context, tenantID int64) (int64, error)
+}
+
+// UsageRepository defines the interface for storing usage metering counters
+type UsageRepository interface {
+	AddUsage(ctx context.Context, period time.Time, counts []model.UsageCount, active []model.ActiveUser) error
+	GetUsage(ctx context.Context, period time.Time) (*model.UsageReport, error)
+}
+
+// WebhookDeliveryRepository defines the interface for the webhook delivery log
+type WebhookDeliveryRepository interface {
+	RecordDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, string, error)
+}
+
+// OutboxRepository defines the interface the outbox relay publishes stored events with
+type OutboxRepository interface {
+	// RelayOutbox passes up to limit unpublished events, oldest first, to
+	// publish and marks the events it published. publish returns how many
+	// events, counted from the first, it published and the error that
+	// stopped it, which is recorded on the next event. Concurrent relays take
+	// turns, so events are published in order.
+	RelayOutbox(ctx context.Context, limit int, publish func(events []*model.OutboxEvent) (int, error)) (int, error)
+
+	// PurgeOutbox deletes events published before the given time
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+}
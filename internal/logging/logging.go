@@ -0,0 +1,103 @@
+// Package logging configures the service's structured JSON logs. Records
+// carry the attributes stored in their context, such as the request ID, and
+// attributes named after secrets are redacted.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys, compared case-insensitively and ignoring
+// dashes, whose values must never reach the logs
+var sensitiveKeys = map[string]bool{
+	"password":             true,
+	"new_password":         true,
+	"token":                true,
+	"access_token":         true,
+	"refresh_token":        true,
+	"id_token":             true,
+	"authorization":        true,
+	"cookie":               true,
+	"set_cookie":           true,
+	"secret":               true,
+	"client_secret":        true,
+	"credential":           true,
+	"api_key":              true,
+	"x_api_key":            true,
+	"captcha_token":        true,
+	"csrf_token":           true,
+	"x_csrf_token":         true,
+	"device_fingerprint":   true,
+	"x_device_fingerprint": true,
+	"code":                 true,
+	"code_verifier":        true,
+	"admin_password":       true,
+}
+
+// New returns a logger writing JSON records at level and above to w
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: Redact,
+	})})
+}
+
+// ParseLevel parses debug, info, warn or error; empty means info
+func ParseLevel(value string) (slog.Level, error) {
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q, want debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// Redact replaces the values of sensitive attributes, for use as
+// slog.HandlerOptions.ReplaceAttr
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ReplaceAll(strings.ToLower(a.Key), "-", "_")
+	if sensitiveKeys[key] {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+type attrsKey struct{}
+
+// WithAttrs returns a context whose log records carry attrs, in addition to
+// those already stored in ctx
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+	combined = append(append(combined, existing...), attrs...)
+	return context.WithValue(ctx, attrsKey{}, combined)
+}
+
+// contextHandler adds the attributes stored by WithAttrs to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
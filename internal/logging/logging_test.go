@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	ctx := WithAttrs(context.Background(), slog.String("request_id", "req-1"))
+	logger.InfoContext(ctx, "login",
+		"email", "a@example.com",
+		"password", "hunter22",
+		"Authorization", "Bearer abc",
+		slog.Group("body", "client_secret", "s3cret", "scope", "openid"),
+	)
+	logger.Debug("hidden")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+
+	tests := []struct {
+		key  string
+		want any
+	}{
+		{key: "request_id", want: "req-1"},
+		{key: "email", want: "a@example.com"},
+		{key: "password", want: Redacted},
+		{key: "Authorization", want: Redacted},
+	}
+	for _, tt := range tests {
+		if got := record[tt.key]; got != tt.want {
+			t.Errorf("got %s %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	body, _ := record["body"].(map[string]any)
+	if body["client_secret"] != Redacted || body["scope"] != "openid" {
+		t.Errorf("got body %v, want only the secret redacted", body)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{value: "", want: slog.LevelInfo},
+		{value: "debug", want: slog.LevelDebug},
+		{value: "WARN", want: slog.LevelWarn},
+		{value: "error", want: slog.LevelError},
+		{value: "verbose", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLevel(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package metering counts billable usage, such as monthly active users, logins,
+// and issued tokens, per tenant and OAuth client.
+package metering
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// DefaultFlushInterval is used by NewMeter for non-positive intervals
+const DefaultFlushInterval = 30 * time.Second
+
+// Meter aggregates usage in memory and adds it to the repository in the
+// background, so counting never adds a database write to a request. Usage that
+// fails to be written is kept for the next flush. A nil Meter discards usage.
+type Meter struct {
+	repo interfaces.UsageRepository
+	now  func() time.Time
+
+	mu     sync.Mutex
+	counts map[countKey]int64
+	active map[activeKey]struct{}
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type countKey struct {
+	period time.Time
+	key    model.UsageKey
+	metric string
+}
+
+type activeKey struct {
+	period time.Time
+	key    model.UsageKey
+	userID int64
+}
+
+// NewMeter starts a meter that flushes to repo every interval
+func NewMeter(repo interfaces.UsageRepository, interval time.Duration) *Meter {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	m := &Meter{
+		repo:   repo,
+		now:    time.Now,
+		counts: make(map[countKey]int64),
+		active: make(map[activeKey]struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go m.run(interval)
+	return m
+}
+
+// Count adds n to a metric
+func (m *Meter) Count(key model.UsageKey, metric string, n int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[countKey{model.UsagePeriod(m.now()), key, metric}] += n
+}
+
+// Login counts a sign-in and marks the user active for the month
+func (m *Meter) Login(key model.UsageKey, userID int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	period := model.UsagePeriod(m.now())
+	m.counts[countKey{period, key, model.UsageLogins}]++
+	m.active[activeKey{period, key, userID}] = struct{}{}
+}
+
+// Flush writes the usage counted since the last flush
+func (m *Meter) Flush(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	counts, active := m.counts, m.active
+	m.counts = make(map[countKey]int64)
+	m.active = make(map[activeKey]struct{})
+	m.mu.Unlock()
+
+	// Usage is stored per period; a flush spans two only around month ends
+	periodCounts := make(map[time.Time][]model.UsageCount)
+	for k, n := range counts {
+		periodCounts[k.period] = append(periodCounts[k.period], model.UsageCount{UsageKey: k.key, Metric: k.metric, Count: n})
+	}
+	periodActive := make(map[time.Time][]model.ActiveUser)
+	for k := range active {
+		periodActive[k.period] = append(periodActive[k.period], model.ActiveUser{UsageKey: k.key, UserID: k.userID})
+	}
+	periods := make(map[time.Time]bool)
+	for period := range periodCounts {
+		periods[period] = true
+	}
+	for period := range periodActive {
+		periods[period] = true
+	}
+
+	var firstErr error
+	for period := range periods {
+		if err := m.repo.AddUsage(ctx, period, periodCounts[period], periodActive[period]); err != nil {
+			m.restore(period, periodCounts[period], periodActive[period])
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// restore puts back usage that could not be written
+func (m *Meter) restore(period time.Time, counts []model.UsageCount, active []model.ActiveUser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range counts {
+		m.counts[countKey{period, c.UsageKey, c.Metric}] += c.Count
+	}
+	for _, a := range active {
+		m.active[activeKey{period, a.UsageKey, a.UserID}] = struct{}{}
+	}
+}
+
+// Close stops the background flushes and writes the remaining usage
+func (m *Meter) Close(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.closeOnce.Do(func() { close(m.stop) })
+
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return m.Flush(ctx)
+}
+
+// run flushes every interval until Close
+func (m *Meter) run(interval time.Duration) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Flush(context.Background()); err != nil {
+				slog.Error("metering: failed to write usage", "err", err)
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestMeter(t *testing.T) {
+	ctx := context.Background()
+	repo := test.NewMockUsageRepository()
+	meter := NewMeter(repo, time.Hour)
+
+	now := time.Date(2026, time.March, 31, 23, 59, 0, 0, time.UTC)
+	meter.now = func() time.Time { return now }
+
+	acme := model.UsageKey{TenantID: 1}
+	acmeApp := model.UsageKey{TenantID: 1, ClientID: "app"}
+	meter.Login(acme, 10)
+	meter.Login(acme, 10)
+	meter.Login(acmeApp, 10)
+	meter.Login(acmeApp, 11)
+	meter.Count(acmeApp, model.UsageTokensIssued, 2)
+
+	// A failed flush keeps the usage for the next one
+	repo.SetErr(errors.New("database unavailable"))
+	if err := meter.Flush(ctx); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	repo.SetErr(nil)
+
+	// Usage after the month ends belongs to the next period
+	now = now.Add(time.Hour)
+	meter.Count(acme, model.UsageEmailsSent, 1)
+
+	if err := meter.Close(ctx); err != nil {
+		t.Fatalf("failed to close meter: %v", err)
+	}
+	meter.Count(acme, model.UsageSMSSent, 1) // kept in memory, never flushed
+
+	march, _ := repo.GetUsage(ctx, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if len(march.Tenants) != 1 || len(march.Clients) != 2 {
+		t.Fatalf("unexpected March report: %+v", march)
+	}
+	want := model.UsageRecord{UsageKey: acme, MonthlyActiveUsers: 2, Logins: 4, TokensIssued: 2}
+	if *march.Tenants[0] != want {
+		t.Errorf("got tenant usage %+v, want %+v", *march.Tenants[0], want)
+	}
+	want = model.UsageRecord{UsageKey: acmeApp, MonthlyActiveUsers: 2, Logins: 2, TokensIssued: 2}
+	if *march.Clients[1] != want {
+		t.Errorf("got client usage %+v, want %+v", *march.Clients[1], want)
+	}
+
+	april, _ := repo.GetUsage(ctx, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC))
+	if len(april.Tenants) != 1 || april.Tenants[0].EmailsSent != 1 || april.Tenants[0].SMSSent != 0 {
+		t.Errorf("unexpected April report: %+v", april.Tenants)
+	}
+}
+
+func TestNilMeter(t *testing.T) {
+	var meter *Meter
+	meter.Login(model.UsageKey{}, 1)
+	meter.Count(model.UsageKey{}, model.UsageLogins, 1)
+	if err := meter.Close(context.Background()); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Outcomes of sign-in attempts, the values of the outcome label of
+// auth_logins_total
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginLocked             = "locked"
+	LoginThrottled          = "throttled"
+	LoginRejected           = "rejected" // canary accounts and suspended tenants
+	LoginError              = "error"
+)
+
+// Results of token validations, the values of the result label of
+// auth_token_validations_total
+const (
+	TokenValid   = "valid"
+	TokenExpired = "expired"
+	TokenInvalid = "invalid"
+	TokenError   = "error"
+)
+
+// Auth counts sign-ins, registrations and token validations. A nil Auth
+// discards events.
+type Auth struct {
+	logins           *prometheus.CounterVec
+	registrations    prometheus.Counter
+	tokenValidations *prometheus.CounterVec
+}
+
+// NewAuth creates the authentication counters and registers them with reg
+func NewAuth(reg prometheus.Registerer) *Auth {
+	m := &Auth{
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "auth",
+			Name:      "logins_total",
+			Help:      "Password sign-in attempts by outcome.",
+		}, []string{"outcome"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "auth",
+			Name:      "registrations_total",
+			Help:      "User accounts registered.",
+		}),
+		tokenValidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "auth",
+			Name:      "token_validations_total",
+			Help:      "Access token validations by result.",
+		}, []string{"result"}),
+	}
+	// Start every series at zero so rate() works before the first event
+	for _, outcome := range []string{LoginSuccess, LoginInvalidCredentials, LoginLocked, LoginThrottled, LoginRejected, LoginError} {
+		m.logins.WithLabelValues(outcome)
+	}
+	for _, result := range []string{TokenValid, TokenExpired, TokenInvalid, TokenError} {
+		m.tokenValidations.WithLabelValues(result)
+	}
+	reg.MustRegister(m.logins, m.registrations, m.tokenValidations)
+	return m
+}
+
+// Login counts a sign-in attempt with one of the Login* outcomes
+func (m *Auth) Login(outcome string) {
+	if m != nil {
+		m.logins.WithLabelValues(outcome).Inc()
+	}
+}
+
+// Registered counts a registered account
+func (m *Auth) Registered() {
+	if m != nil {
+		m.registrations.Inc()
+	}
+}
+
+// TokenValidation counts a token validation with one of the Token* results
+func (m *Auth) TokenValidation(result string) {
+	if m != nil {
+		m.tokenValidations.WithLabelValues(result).Inc()
+	}
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterHasher exports the load on the password hashing workers, read at
+// scrape time. A queue that stays above zero means sign-ins wait for CPU.
+func RegisterHasher(reg prometheus.Registerer, hasher *password.Hasher) {
+	gauge := func(name, help string, value func() int) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "auth",
+			Subsystem: "password_hash",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value()) })
+	}
+
+	reg.MustRegister(
+		gauge("queue_depth", "Password hashes and checks waiting for a worker.", hasher.Queued),
+		gauge("busy_workers", "Password hashes and checks running.", hasher.Busy),
+		gauge("workers", "Password hashes and checks that may run at once.", hasher.Workers),
+	)
+}
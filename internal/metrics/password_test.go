@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegisterHasher(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	RegisterHasher(reg, password.NewHasher(password.DefaultArgon2Params, password.WithWorkers(3)))
+
+	want := `
+# HELP auth_password_hash_queue_depth Password hashes and checks waiting for a worker.
+# TYPE auth_password_hash_queue_depth gauge
+auth_password_hash_queue_depth 0
+# HELP auth_password_hash_workers Password hashes and checks that may run at once.
+# TYPE auth_password_hash_workers gauge
+auth_password_hash_workers 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "auth_password_hash_queue_depth", "auth_password_hash_workers"); err != nil {
+		t.Error(err)
+	}
+}
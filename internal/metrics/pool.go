@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterPool exports the connection counts of a database pool and how long
+// requests waited for a connection, read at scrape time
+func RegisterPool(reg prometheus.Registerer, pool *pgxpool.Pool) {
+	gauge := func(name, help string, value func(*pgxpool.Stat) int32) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "auth",
+			Subsystem: "db_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(pool.Stat())) })
+	}
+
+	reg.MustRegister(
+		gauge("acquired_connections", "Connections currently in use.", (*pgxpool.Stat).AcquiredConns),
+		gauge("idle_connections", "Idle connections in the pool.", (*pgxpool.Stat).IdleConns),
+		gauge("total_connections", "Open connections, in use, idle or being established.", (*pgxpool.Stat).TotalConns),
+		gauge("max_connections", "Maximum size of the pool.", (*pgxpool.Stat).MaxConns),
+		waitCounters(func() (int64, time.Duration) {
+			stat := pool.Stat()
+			return stat.EmptyAcquireCount(), stat.EmptyAcquireWaitTime()
+		}),
+	)
+}
+
+// RegisterSQLPool exports the same connection counts for a database/sql pool,
+// as used for MySQL
+func RegisterSQLPool(reg prometheus.Registerer, db *sql.DB) {
+	gauge := func(name, help string, value func(sql.DBStats) int) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "auth",
+			Subsystem: "db_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(db.Stats())) })
+	}
+
+	reg.MustRegister(
+		gauge("acquired_connections", "Connections currently in use.", func(s sql.DBStats) int { return s.InUse }),
+		gauge("idle_connections", "Idle connections in the pool.", func(s sql.DBStats) int { return s.Idle }),
+		gauge("total_connections", "Open connections, in use, idle or being established.", func(s sql.DBStats) int { return s.OpenConnections }),
+		gauge("max_connections", "Maximum size of the pool.", func(s sql.DBStats) int { return s.MaxOpenConnections }),
+		waitCounters(func() (int64, time.Duration) {
+			stats := db.Stats()
+			return stats.WaitCount, stats.WaitDuration
+		}),
+	)
+}
+
+// waitCounters exports how often requests waited for a connection because
+// every one was in use, and for how long in total, as read by stats
+func waitCounters(stats func() (int64, time.Duration)) prometheus.Collector {
+	return &poolWaitCollector{
+		stats: stats,
+		count: prometheus.NewDesc("auth_db_pool_acquire_waits_total",
+			"Connection acquisitions that waited because every connection was in use.", nil, nil),
+		duration: prometheus.NewDesc("auth_db_pool_acquire_wait_seconds_total",
+			"Time spent waiting for a connection because every connection was in use.", nil, nil),
+	}
+}
+
+// poolWaitCollector reads both wait counters from one snapshot of the stats
+type poolWaitCollector struct {
+	stats           func() (int64, time.Duration)
+	count, duration *prometheus.Desc
+}
+
+func (c *poolWaitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.count
+	ch <- c.duration
+}
+
+func (c *poolWaitCollector) Collect(ch chan<- prometheus.Metric) {
+	count, duration := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.count, prometheus.CounterValue, float64(count))
+	ch <- prometheus.MustNewConstMetric(c.duration, prometheus.CounterValue, duration.Seconds())
+}
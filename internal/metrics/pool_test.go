@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWaitCounters(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(waitCounters(func() (int64, time.Duration) {
+		return 3, 1500 * time.Millisecond
+	}))
+
+	want := `
+# HELP auth_db_pool_acquire_wait_seconds_total Time spent waiting for a connection because every connection was in use.
+# TYPE auth_db_pool_acquire_wait_seconds_total counter
+auth_db_pool_acquire_wait_seconds_total 1.5
+# HELP auth_db_pool_acquire_waits_total Connection acquisitions that waited because every connection was in use.
+# TYPE auth_db_pool_acquire_waits_total counter
+auth_db_pool_acquire_waits_total 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
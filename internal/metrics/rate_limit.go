@@ -0,0 +1,38 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// RateLimit reports on an in-memory rate limit store. A nil RateLimit discards
+// events.
+type RateLimit struct {
+	evictions prometheus.Counter
+}
+
+// NewRateLimit registers the store metrics with reg. size is called at scrape
+// time for the number of tracked clients.
+func NewRateLimit(reg prometheus.Registerer, size func() int) *RateLimit {
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "auth",
+		Subsystem: "ratelimit",
+		Name:      "visitors",
+		Help:      "Clients with a rate limit bucket held in memory.",
+	}, func() float64 { return float64(size()) }))
+
+	m := &RateLimit{
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "auth",
+			Subsystem: "ratelimit",
+			Name:      "evictions_total",
+			Help:      "Idle rate limit buckets removed from memory.",
+		}),
+	}
+	reg.MustRegister(m.evictions)
+	return m
+}
+
+// Evicted counts n buckets removed from memory
+func (m *RateLimit) Evicted(n int) {
+	if m != nil {
+		m.evictions.Add(float64(n))
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSecurity(t *testing.T) {
+	m := NewSecurity(prometheus.NewRegistry())
+
+	tenant := int64(7)
+	m.Lockout(&tenant)
+	m.Lockout(&tenant)
+	m.Lockout(nil)
+	m.Ban(nil)
+
+	tests := []struct {
+		name    string
+		counter *prometheus.CounterVec
+		tenant  string
+		want    float64
+	}{
+		{name: "tenant lockouts", counter: m.lockouts, tenant: "7", want: 2},
+		{name: "untenanted lockouts", counter: m.lockouts, tenant: "", want: 1},
+		{name: "bans", counter: m.bans, tenant: "", want: 1},
+		{name: "series start at zero", counter: m.mfaFailures, tenant: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(tt.counter.WithLabelValues(tt.tenant)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var disabled *Security
+	disabled.Lockout(&tenant) // must not panic
+}
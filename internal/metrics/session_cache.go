@@ -0,0 +1,45 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// SessionCache reports on the cache of session validity. A nil SessionCache
+// discards events.
+type SessionCache struct {
+	lookups *prometheus.CounterVec
+}
+
+// NewSessionCache registers the cache metrics with reg. size is called at
+// scrape time for the number of cached sessions.
+func NewSessionCache(reg prometheus.Registerer, size func() int) *SessionCache {
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "auth",
+		Subsystem: "session_cache",
+		Name:      "entries",
+		Help:      "Sessions whose validity is cached in memory.",
+	}, func() float64 { return float64(size()) }))
+
+	m := &SessionCache{
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "auth",
+			Subsystem: "session_cache",
+			Name:      "lookups_total",
+			Help:      "Session validity lookups by result, hit or miss.",
+		}, []string{"result"}),
+	}
+	m.lookups.WithLabelValues("hit")
+	m.lookups.WithLabelValues("miss")
+	reg.MustRegister(m.lookups)
+	return m
+}
+
+// Lookup counts a lookup answered from the cache, or one that was not
+func (m *SessionCache) Lookup(hit bool) {
+	if m == nil {
+		return
+	}
+	if hit {
+		m.lookups.WithLabelValues("hit").Inc()
+	} else {
+		m.lookups.WithLabelValues("miss").Inc()
+	}
+}
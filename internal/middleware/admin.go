@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+type windowCount struct {
+	count int
+	start time.Time
+}
+
+// windowCounter counts events per key within a fixed time window
+type windowCounter struct {
+	sync.Mutex
+	counts map[string]*windowCount
+	window time.Duration
+}
+
+func newWindowCounter(window time.Duration) *windowCounter {
+	return &windowCounter{
+		counts: make(map[string]*windowCount),
+		window: window,
+	}
+}
+
+// increment records an event for key and returns the count in the current window
+func (c *windowCounter) increment(key string) int {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	v, exists := c.counts[key]
+	if !exists || now.Sub(v.start) > c.window {
+		c.counts[key] = &windowCount{1, now}
+		return 1
+	}
+
+	v.count++
+	return v.count
+}
+
+// RequireAdminToken restricts routes to requests carrying the static admin API
+// token, or to requests already authorized by BreakGlassAccess
+func RequireAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isBreakGlass(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "Admin API token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+const adminTenantKey contextKey = "admin_tenant"
+
+// AdminLookup reports whether a user holds the admin role and which tenant it
+// administers; a nil tenant means the user administers every user
+type AdminLookup func(ctx context.Context, userID int64) (isAdmin bool, tenantID *int64, err error)
+
+// RequireAdmin restricts routes to administrators. The static admin API token
+// and break-glass sessions administer every user; a JWT whose user holds the
+// admin role is scoped to the user's tenant. The scope is available to
+// handlers through AdminTenantFromContext.
+func RequireAdmin(token string, validator TokenValidator, lookup AdminLookup) func(http.Handler) http.Handler {
+	authenticate := JWTAuthenticator(validator)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if isBreakGlass(r.Context()) || (token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminTenantKey, (*int64)(nil))))
+				return
+			}
+
+			authenticated, ok := authenticate(r)
+			if !ok {
+				unauthorized(w, authenticated)
+				return
+			}
+			userID, _ := UserIDFromContext(authenticated.Context())
+			isAdmin, tenantID, err := lookup(r.Context(), userID)
+			if err != nil || !isAdmin {
+				problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Admin role required")
+				return
+			}
+			next.ServeHTTP(w, authenticated.WithContext(context.WithValue(authenticated.Context(), adminTenantKey, tenantID)))
+		})
+	}
+}
+
+// AdminTenantFromContext returns the tenant the administrator authorized by
+// RequireAdmin is scoped to, nil meaning every user. ok is false outside RequireAdmin.
+func AdminTenantFromContext(ctx context.Context) (tenantID *int64, ok bool) {
+	tenantID, ok = ctx.Value(adminTenantKey).(*int64)
+	return tenantID, ok
+}
+
+// AdminRateLimiter creates a stricter rate limiter for the admin API
+// (30 requests per minute per IP by default). The first rejection for a client
+// in each minute is reported as a high-severity audit event.
+func AdminRateLimiter(logger audit.Logger, opts ...Option) func(http.Handler) http.Handler {
+	rejections := newWindowCounter(time.Minute)
+	reject := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := hostOnly(r.RemoteAddr)
+		if rejections.increment(ip) == 1 {
+			logger.Record(r.Context(), audit.Event{
+				Type:      "admin.rate_limited",
+				Severity:  audit.SeverityHigh,
+				IPAddress: ip,
+				Details:   map[string]any{"method": r.Method, "path": r.URL.Path},
+			})
+		}
+		problem.Error(w, r, http.StatusTooManyRequests, problem.RateLimited, "Too many requests")
+	})
+	return RateLimiter(append([]Option{WithName("admin"), WithLimit(AdminLimit), WithRejectionHandler(reject)}, opts...)...)
+}
+
+// VelocityAlert reports a high-severity audit event when a client performs more
+// than threshold operations of the given kind within window. Requests are not
+// blocked; the alert fires once per window when the threshold is crossed.
+func VelocityAlert(operation string, threshold int, window time.Duration, logger audit.Logger) func(http.Handler) http.Handler {
+	counter := newWindowCounter(window)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := hostOnly(r.RemoteAddr)
+			if counter.increment(ip) == threshold+1 {
+				logger.Record(r.Context(), audit.Event{
+					Type:      "admin.velocity_exceeded",
+					Severity:  audit.SeverityHigh,
+					IPAddress: ip,
+					Details: map[string]any{
+						"operation": operation,
+						"threshold": threshold,
+						"window":    window.String(),
+					},
+				})
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
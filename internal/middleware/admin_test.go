@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+)
+
+// recordingLogger captures audit events for assertions
+type recordingLogger struct {
+	sync.Mutex
+	events []audit.Event
+}
+
+func (l *recordingLogger) Record(ctx context.Context, event audit.Event) {
+	l.Lock()
+	defer l.Unlock()
+	l.events = append(l.events, event)
+}
+
+func TestAdminRateLimiter(t *testing.T) {
+	logger := &recordingLogger{}
+	handler := AdminRateLimiter(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var lastStatus int
+	for i := 0; i < 35; i++ {
+		req := httptest.NewRequest("GET", "/admin/test", nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		lastStatus = w.Code
+	}
+
+	if lastStatus != http.StatusTooManyRequests {
+		t.Errorf("got status %v, want %v", lastStatus, http.StatusTooManyRequests)
+	}
+	if len(logger.events) != 1 || logger.events[0].Severity != audit.SeverityHigh {
+		t.Errorf("expected a single high-severity alert, got %+v", logger.events)
+	}
+}
+
+func TestVelocityAlert(t *testing.T) {
+	logger := &recordingLogger{}
+	handler := VelocityAlert("bulk_revoke", 3, time.Minute, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest("POST", "/admin/bulk", nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		// Velocity alerts never block the request
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %v, want %v", i+1, w.Code, http.StatusOK)
+		}
+		if i < 3 && len(logger.events) != 0 {
+			t.Fatalf("request %d: unexpected alert before threshold", i+1)
+		}
+	}
+
+	if len(logger.events) != 1 || logger.events[0].Type != "admin.velocity_exceeded" {
+		t.Errorf("expected a single velocity alert, got %+v", logger.events)
+	}
+}
+
+func TestRequireAdminToken(t *testing.T) {
+	handler := RequireAdminToken("admin-secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		header         string
+		wantStatusCode int
+	}{
+		{name: "valid token", header: "Bearer admin-secret", wantStatusCode: http.StatusOK},
+		{name: "wrong token", header: "Bearer nope", wantStatusCode: http.StatusUnauthorized},
+		{name: "missing token", header: "", wantStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/test", nil)
+			req.Header.Set("Authorization", tt.header)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	tenantID := int64(7)
+	validator := staticTokens{
+		"global-admin": {"sub": float64(1)},
+		"tenant-admin": {"sub": float64(2)},
+		"user":         {"sub": float64(3)},
+	}
+	lookup := func(ctx context.Context, userID int64) (bool, *int64, error) {
+		switch userID {
+		case 1:
+			return true, nil, nil
+		case 2:
+			return true, &tenantID, nil
+		}
+		return false, nil, nil
+	}
+	handler := RequireAdmin("admin-token", validator, lookup)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := AdminTenantFromContext(r.Context())
+		if !ok {
+			t.Error("expected an admin scope")
+		}
+		if scope == nil {
+			w.Write([]byte("all"))
+			return
+		}
+		w.Write([]byte(strconv.FormatInt(*scope, 10)))
+	}))
+
+	tests := []struct {
+		name           string
+		token          string
+		wantStatusCode int
+		wantScope      string
+	}{
+		{name: "admin token", token: "admin-token", wantStatusCode: http.StatusOK, wantScope: "all"},
+		{name: "admin role without tenant", token: "global-admin", wantStatusCode: http.StatusOK, wantScope: "all"},
+		{name: "tenant admin", token: "tenant-admin", wantStatusCode: http.StatusOK, wantScope: "7"},
+		{name: "regular user", token: "user", wantStatusCode: http.StatusForbidden},
+		{name: "invalid token", token: "forged", wantStatusCode: http.StatusUnauthorized},
+		{name: "no token", wantStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/users", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if tt.wantScope != "" && w.Body.String() != tt.wantScope {
+				t.Errorf("got scope %q, want %q", w.Body.String(), tt.wantScope)
+			}
+		})
+	}
+}
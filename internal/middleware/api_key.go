@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+type contextKey string
+
+const userIDKey contextKey = "user_id"
+
+// APIKeyValidator resolves an API key to the ID of the user that owns it
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, key string) (int64, error)
+}
+
+// APIKeyAuth authenticates requests carrying an X-API-Key header as an
+// alternative to a Bearer JWT. Requests without the header pass through
+// unchanged; an invalid key is rejected with 401.
+func APIKeyAuth(validator APIKeyValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, err := validator.ValidateAPIKey(r.Context(), key)
+			if err != nil {
+				problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "Invalid API key")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withUserID(r.Context(), userID)))
+		})
+	}
+}
+
+// UserIDFromContext returns the user ID stored by an authentication middleware
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey).(int64)
+	return userID, ok
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type staticValidator map[string]int64
+
+func (v staticValidator) ValidateAPIKey(ctx context.Context, key string) (int64, error) {
+	userID, ok := v[key]
+	if !ok {
+		return 0, errors.New("invalid api key")
+	}
+	return userID, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	handler := APIKeyAuth(staticValidator{"ak_valid": 42})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID, ok := UserIDFromContext(r.Context()); ok {
+			w.Header().Set("X-User-ID", "set")
+			if userID != 42 {
+				t.Errorf("got user %d, want 42", userID)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		wantStatusCode int
+		wantUser       bool
+	}{
+		{name: "valid key", apiKey: "ak_valid", wantStatusCode: http.StatusOK, wantUser: true},
+		{name: "invalid key", apiKey: "ak_invalid", wantStatusCode: http.StatusUnauthorized},
+		{name: "no key passes through", wantStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if got := w.Header().Get("X-User-ID") == "set"; got != tt.wantUser {
+				t.Errorf("got user in context %v, want %v", got, tt.wantUser)
+			}
+		})
+	}
+}
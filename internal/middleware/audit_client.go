@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+)
+
+// AuditClient records the client address and user agent in the request
+// context, so audit events emitted below the handlers carry them. Use it after
+// RealIP.
+func AuditClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := audit.WithClient(r.Context(), hostOnly(r.RemoteAddr), r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+const tokenKey contextKey = "token"
+
+// Authenticate requires a valid JWT in the Authorization header or session
+// cookie, storing its subject, claims and raw token for UserIDFromContext,
+// ClaimsFromContext and TokenFromContext. Requests without one are rejected
+// with 401 before reaching the handler; requests whose token was already
+// validated earlier in the chain pass through.
+func Authenticate(validator TokenValidator) func(http.Handler) http.Handler {
+	authenticate := JWTAuthenticator(validator)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := TokenFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authenticated, ok := authenticate(r)
+			if !ok {
+				challenge := "Bearer"
+				if r.Header.Get("Authorization") != "" {
+					challenge = `Bearer error="invalid_token"`
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+				unauthorized(w, authenticated)
+				return
+			}
+			next.ServeHTTP(w, authenticated)
+		})
+	}
+}
+
+// TokenFromContext returns the raw Bearer token validated by JWTAuthenticator
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type staticTokens map[string]jwt.MapClaims
+
+func (v staticTokens) ValidateToken(ctx context.Context, token string) (jwt.MapClaims, error) {
+	claims, ok := v[token]
+	if token == "expired" {
+		return nil, jwt.ErrTokenExpired
+	}
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	validator := staticTokens{
+		"valid":      {"sub": float64(42)},
+		"no-subject": {},
+	}
+	handler := Authenticate(validator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := UserIDFromContext(r.Context())
+		token, _ := TokenFromContext(r.Context())
+		w.Write([]byte(strconv.FormatInt(userID, 10) + " " + token))
+	}))
+
+	tests := []struct {
+		name           string
+		authorization  string
+		wantStatusCode int
+		wantBody       string
+		wantChallenge  string
+		wantCode       problem.Code
+	}{
+		{name: "valid token", authorization: "Bearer valid", wantStatusCode: http.StatusOK, wantBody: "42 valid"},
+		{name: "missing token", wantStatusCode: http.StatusUnauthorized, wantChallenge: "Bearer", wantCode: problem.Unauthorized},
+		{name: "invalid token", authorization: "Bearer forged", wantStatusCode: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`, wantCode: problem.Unauthorized},
+		{name: "expired token", authorization: "Bearer expired", wantStatusCode: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`, wantCode: problem.TokenExpired},
+		{name: "token without subject", authorization: "Bearer no-subject", wantStatusCode: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`, wantCode: problem.Unauthorized},
+		{name: "wrong scheme", authorization: "Basic dXNlcjpwYXNz", wantStatusCode: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`, wantCode: problem.Unauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("got challenge %q, want %q", got, tt.wantChallenge)
+			}
+			if tt.wantCode != "" {
+				var p problem.Problem
+				json.NewDecoder(w.Body).Decode(&p)
+				if p.Code != tt.wantCode || w.Header().Get("Content-Type") != problem.ContentType {
+					t.Errorf("got code %q as %q, want %q", p.Code, w.Header().Get("Content-Type"), tt.wantCode)
+				}
+			}
+		})
+	}
+}
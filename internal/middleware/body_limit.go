@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+// DefaultMaxBodySize caps request bodies at 1 MB
+const DefaultMaxBodySize = 1 << 20
+
+// MaxBodySize caps request bodies at limit bytes. Requests that declare a
+// larger Content-Length are rejected with 413 up front; bodies that turn out
+// larger fail when read past the limit, which handlers report as 413.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.BodyTooLarge, "Request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	handler := MaxBodySize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		body           string
+		chunked        bool
+		wantStatusCode int
+	}{
+		{name: "within limit", body: "12345678", wantStatusCode: http.StatusOK},
+		{name: "declared too large", body: "123456789", wantStatusCode: http.StatusRequestEntityTooLarge},
+		{name: "streamed too large", body: "123456789", chunked: true, wantStatusCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/auth/register", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
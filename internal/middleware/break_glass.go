@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+)
+
+const breakGlassKey contextKey = "break_glass"
+
+// BreakGlassValidator reports whether a bearer token is an active break-glass session
+type BreakGlassValidator interface {
+	ValidateSession(ctx context.Context, token string) (bool, error)
+}
+
+// BreakGlassAccess lets an active break-glass session through RequireAdminToken.
+// Every request made with the session is reported as a high-severity audit event.
+func BreakGlassAccess(validator BreakGlassValidator, logger audit.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			valid, err := validator.ValidateSession(r.Context(), token)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "break-glass session store unavailable", "err", err)
+			}
+			if !valid {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Record(r.Context(), audit.Event{
+				Type:      "breakglass.admin_request",
+				Severity:  audit.SeverityHigh,
+				IPAddress: r.RemoteAddr,
+				Details:   map[string]any{"method": r.Method, "path": r.URL.Path},
+			})
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), breakGlassKey, true)))
+		})
+	}
+}
+
+// Helper function to check whether the request was authorized by a break-glass session
+func isBreakGlass(ctx context.Context) bool {
+	ok, _ := ctx.Value(breakGlassKey).(bool)
+	return ok
+}
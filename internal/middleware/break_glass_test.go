@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type sessionSet map[string]bool
+
+func (s sessionSet) ValidateSession(ctx context.Context, token string) (bool, error) {
+	return s[token], nil
+}
+
+func TestBreakGlassAccess(t *testing.T) {
+	logger := &recordingLogger{}
+	handler := BreakGlassAccess(sessionSet{"bg-session": true}, logger)(
+		RequireAdminToken("admin-token")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+
+	tests := []struct {
+		name           string
+		token          string
+		wantStatusCode int
+		wantEvents     int
+	}{
+		{name: "admin token", token: "admin-token", wantStatusCode: http.StatusOK},
+		{name: "break-glass session", token: "bg-session", wantStatusCode: http.StatusOK, wantEvents: 1},
+		{name: "unknown token", token: "other", wantStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.events = nil
+			req := httptest.NewRequest("POST", "/admin/oauth/clients", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if len(logger.events) != tt.wantEvents {
+				t.Errorf("got %d audit events, want %d", len(logger.events), tt.wantEvents)
+			}
+		})
+	}
+}
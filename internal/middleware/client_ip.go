@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP replaces r.RemoteAddr with the client address. Forwarding headers are
+// only believed from peers in trusted, the networks of the service's own
+// reverse proxies and load balancers, so clients cannot spoof their address to
+// escape rate limits and bans.
+func RealIP(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = ClientIP(r, trusted)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address of the client that sent r. When the peer is a
+// trusted proxy, X-Forwarded-For is read from the right, skipping trusted
+// hops, since only the entries appended by trusted proxies can be relied on;
+// X-Real-IP is used when there is no X-Forwarded-For.
+func ClientIP(r *http.Request, trusted []*net.IPNet) string {
+	peer := hostOnly(r.RemoteAddr)
+	if !isTrusted(net.ParseIP(peer), trusted) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return peer
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// Anything left of a malformed entry could have been written by anyone
+			break
+		}
+		client = ip.String()
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
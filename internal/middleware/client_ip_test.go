@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	var trusted []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "fd00::/8"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		trusted = append(trusted, network)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.9:5000", want: "203.0.113.9"},
+		{name: "spoofed header from untrusted peer", remoteAddr: "203.0.113.9:5000", forwarded: []string{"1.2.3.4"}, want: "203.0.113.9"},
+		{name: "behind load balancer", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "client prepends a fake hop", remoteAddr: "10.0.0.2:5000", forwarded: []string{"1.2.3.4, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.7, 10.1.1.1", "10.2.2.2"}, want: "198.51.100.7"},
+		{name: "every hop trusted", remoteAddr: "10.0.0.2:5000", forwarded: []string{"10.3.3.3, 10.1.1.1"}, want: "10.3.3.3"},
+		{name: "malformed hop", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.7, garbage, 10.1.1.1"}, want: "10.1.1.1"},
+		{name: "ipv6 proxy", remoteAddr: "[fd00::1]:5000", forwarded: []string{"2001:db8::7"}, want: "2001:db8::7"},
+		{name: "x-real-ip from trusted proxy", remoteAddr: "10.0.0.2:5000", realIP: "198.51.100.8", want: "198.51.100.8"},
+		{name: "x-real-ip from untrusted peer", remoteAddr: "203.0.113.9:5000", realIP: "198.51.100.8", want: "203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(req, trusted); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
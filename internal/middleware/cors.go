@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+// corsExposedHeaders are the response headers browser apps may read besides
+// the CORS-safelisted ones, so they can back off when rate limited and tell
+// replayed responses apart
+var corsExposedHeaders = strings.Join([]string{
+	"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "Idempotent-Replayed",
+}, ", ")
+
+// CORS lets the configured browser origins call the API cross-origin. It
+// answers preflight requests itself, so it must run before authentication and
+// rate limiting. Requests from other origins pass through without CORS headers
+// and are blocked by the browser; their preflights are rejected with 403.
+func CORS(cors config.CORS) func(http.Handler) http.Handler {
+	methods := strings.Join(cors.AllowedMethods, ", ")
+	maxAge := strconv.Itoa(int(cors.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		if len(cors.AllowedOrigins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			allowOrigin, ok := corsOrigin(cors, origin)
+			if !ok {
+				if preflight {
+					problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Origin not allowed")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.Set("Access-Control-Allow-Origin", allowOrigin)
+			if cors.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			headers := requestedHeaders(r)
+			if !slices.Contains(cors.AllowedMethods, method) || !corsHeadersAllowed(cors.AllowedHeaders, headers) {
+				problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Method or headers not allowed")
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", methods)
+			if len(headers) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+			}
+			if cors.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// corsOrigin returns the Access-Control-Allow-Origin value for origin, and
+// whether the origin is allowed at all
+func corsOrigin(cors config.CORS, origin string) (string, bool) {
+	for _, allowed := range cors.AllowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+		// https://*.example.com matches subdomains of example.com only
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			prefix := scheme + "://"
+			if len(origin) > len(prefix) && strings.EqualFold(origin[:len(prefix)], prefix) &&
+				strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(domain)) {
+				return origin, true
+			}
+		}
+	}
+	return "", false
+}
+
+// requestedHeaders returns the headers listed in a preflight request
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+	return headers
+}
+
+// corsHeadersAllowed reports whether every requested header is allowed,
+// ignoring case
+func corsHeadersAllowed(allowed, requested []string) bool {
+	for _, header := range requested {
+		if !slices.ContainsFunc(allowed, func(a string) bool { return a == "*" || strings.EqualFold(a, header) }) {
+			return false
+		}
+	}
+	return true
+}
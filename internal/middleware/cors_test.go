@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	cors := config.DefaultCORS()
+	cors.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	cors.AllowCredentials = true
+	cors.MaxAge = 5 * time.Minute
+
+	handler := CORS(cors)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		origin         string
+		requestMethod  string
+		requestHeaders string
+		wantStatusCode int
+		wantOrigin     string
+		wantMaxAge     string
+	}{
+		{name: "same-origin request", method: "GET", wantStatusCode: http.StatusOK},
+		{name: "allowed origin", method: "GET", origin: "https://app.example.com", wantStatusCode: http.StatusOK, wantOrigin: "https://app.example.com"},
+		{name: "wildcard subdomain", method: "POST", origin: "https://eu.example.org", wantStatusCode: http.StatusOK, wantOrigin: "https://eu.example.org"},
+		{name: "wildcard does not match the apex", method: "GET", origin: "https://example.org", wantStatusCode: http.StatusOK},
+		{name: "wildcard does not match a lookalike", method: "GET", origin: "https://evilexample.org", wantStatusCode: http.StatusOK},
+		{name: "unknown origin", method: "GET", origin: "https://evil.test", wantStatusCode: http.StatusOK},
+		{
+			name: "preflight", method: "OPTIONS", origin: "https://app.example.com",
+			requestMethod: "POST", requestHeaders: "content-type, authorization",
+			wantStatusCode: http.StatusNoContent, wantOrigin: "https://app.example.com", wantMaxAge: "300",
+		},
+		{name: "preflight from unknown origin", method: "OPTIONS", origin: "https://evil.test", requestMethod: "POST", wantStatusCode: http.StatusForbidden},
+		{name: "preflight for disallowed method", method: "OPTIONS", origin: "https://app.example.com", requestMethod: "TRACE", wantStatusCode: http.StatusForbidden, wantOrigin: "https://app.example.com"},
+		{name: "preflight for disallowed header", method: "OPTIONS", origin: "https://app.example.com", requestMethod: "GET", requestHeaders: "X-Debug", wantStatusCode: http.StatusForbidden, wantOrigin: "https://app.example.com"},
+		{name: "plain OPTIONS request", method: "OPTIONS", origin: "https://app.example.com", wantStatusCode: http.StatusOK, wantOrigin: "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/auth/login", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			if tt.requestHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.requestHeaders)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("got allowed origin %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("got max age %q, want %q", got, tt.wantMaxAge)
+			}
+			if tt.wantOrigin != "" && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected credentials to be allowed")
+			}
+		})
+	}
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	cors := config.DefaultCORS()
+	cors.AllowedOrigins = []string{"*"}
+	handler := CORS(cors)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("got allowed origin %q, want *", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got == "" {
+		t.Error("expected the rate limit headers to be exposed")
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	csrf := NewCSRF("test-secret")
+	handler := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	issue := func(session string) string {
+		token, err := csrf.Issue(httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		return token
+	}
+	valid := issue("session-a")
+	otherSession := issue("session-b")
+	forged := NewCSRF("other-secret")
+	forgedToken, _ := forged.Issue(httptest.NewRecorder(), "session-a")
+
+	tests := []struct {
+		name           string
+		method         string
+		session        string
+		cookie         string
+		header         string
+		wantStatusCode int
+	}{
+		{name: "safe method", method: "GET", session: "session-a", wantStatusCode: http.StatusOK},
+		{name: "no session cookie", method: "POST", wantStatusCode: http.StatusOK},
+		{name: "valid token", method: "POST", session: "session-a", cookie: valid, header: valid, wantStatusCode: http.StatusOK},
+		{name: "missing header", method: "POST", session: "session-a", cookie: valid, wantStatusCode: http.StatusForbidden},
+		{name: "missing cookie", method: "DELETE", session: "session-a", header: valid, wantStatusCode: http.StatusForbidden},
+		{name: "header differs from cookie", method: "POST", session: "session-a", cookie: valid, header: otherSession, wantStatusCode: http.StatusForbidden},
+		{name: "token from another session", method: "POST", session: "session-a", cookie: otherSession, header: otherSession, wantStatusCode: http.StatusForbidden},
+		{name: "token signed with another key", method: "PUT", session: "session-a", cookie: forgedToken, header: forgedToken, wantStatusCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/auth/logout", nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: tt.session})
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
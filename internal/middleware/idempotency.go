@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+const (
+	// IdempotencyKeyHeader names the client-chosen key that identifies retries
+	// of one request
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// DefaultIdempotencyTTL is how long responses are kept for replay
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// idempotencyLockTTL bounds how long a key stays reserved by a request that
+	// never finishes, such as one on a replica that crashed
+	idempotencyLockTTL = time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+// ErrIdempotencyInProgress is returned by IdempotencyStore.Reserve while
+// another request holds the key
+var ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+
+// StoredResponse is a response kept for replay to retries
+type StoredResponse struct {
+	Fingerprint string `json:"fingerprint"` // identifies the request body the key was first used with
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// IdempotencyStore keeps the responses to requests with an idempotency key
+type IdempotencyStore interface {
+	// Reserve claims key for a new request for up to lockTTL. It returns the
+	// stored response when the key was used before, or
+	// ErrIdempotencyInProgress while another request holds it.
+	Reserve(ctx context.Context, key string, lockTTL time.Duration) (*StoredResponse, error)
+
+	// Save stores the response for a reserved key until ttl passes
+	Save(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error
+
+	// Release frees a reserved key without a response, so it can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency replays the original response to retries of a request that
+// carries an Idempotency-Key header, for ttl after it was first answered.
+// Retries while the first request is still running get 409, and reusing a key
+// with a different body gets 422. Server errors are not stored, so they can be
+// retried. If the store fails, requests are handled without replay.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var sizeErr *http.MaxBytesError
+				if errors.As(err, &sizeErr) {
+					problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.BodyTooLarge, "Request body too large")
+					return
+				}
+				problem.Error(w, r, http.StatusBadRequest, problem.BadRequest, "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := sha256.Sum256(body)
+
+			key = "idempotency:" + r.Method + ":" + r.URL.Path + ":" + key
+			stored, err := store.Reserve(r.Context(), key, idempotencyLockTTL)
+			switch {
+			case err == ErrIdempotencyInProgress:
+				w.Header().Set("Retry-After", "1")
+				problem.Error(w, r, http.StatusConflict, problem.IdempotencyInProgress, "A request with this Idempotency-Key is in progress")
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "idempotency store unavailable, handling request without replay", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			case stored != nil:
+				if stored.Fingerprint != hex.EncodeToString(fingerprint[:]) {
+					problem.Error(w, r, http.StatusUnprocessableEntity, problem.IdempotencyKeyReused, "Idempotency-Key was used with a different request")
+					return
+				}
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// Detach from the request so a client hanging up does not lose the result
+			ctx := context.WithoutCancel(r.Context())
+			if rec.status >= http.StatusInternalServerError {
+				err = store.Release(ctx, key)
+			} else {
+				err = store.Save(ctx, key, &StoredResponse{
+					Fingerprint: hex.EncodeToString(fingerprint[:]),
+					Status:      rec.status,
+					ContentType: w.Header().Get("Content-Type"),
+					Body:        rec.body.Bytes(),
+				}, ttl)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to store idempotent response", "err", err)
+			}
+		})
+	}
+}
+
+// responseRecorder copies the status and body of a response as it is written
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// idempotencyEntry is a reserved (resp nil) or answered key
+type idempotencyEntry struct {
+	resp      *StoredResponse
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore keeps responses in memory, so retries must reach the
+// same replica. Use RedisIdempotencyStore when running several.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+// Verify that MemoryIdempotencyStore implements IdempotencyStore interface
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+// NewMemoryIdempotencyStore creates an empty store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]*idempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string, lockTTL time.Duration) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		if entry.resp == nil {
+			return nil, ErrIdempotencyInProgress
+		}
+		return entry.resp, nil
+	}
+	s.entries[key] = &idempotencyEntry{expiresAt: now.Add(lockTTL)}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.entries[key] = &idempotencyEntry{resp: resp, expiresAt: now.Add(ttl)}
+
+	// Forget expired responses at most once a minute
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
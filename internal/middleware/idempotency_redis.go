@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisIdempotencyStore keeps responses in Redis, so retries can reach any
+// replica. A reserved key holds an empty value until its response is saved.
+type RedisIdempotencyStore struct {
+	client redis.UniversalClient
+}
+
+// Verify that RedisIdempotencyStore implements IdempotencyStore interface
+var _ IdempotencyStore = (*RedisIdempotencyStore)(nil)
+
+// NewRedisIdempotencyStore creates a store using client
+func NewRedisIdempotencyStore(client redis.UniversalClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+func (s *RedisIdempotencyStore) Reserve(ctx context.Context, key string, lockTTL time.Duration) (*StoredResponse, error) {
+	reserved, err := s.client.SetNX(ctx, key, "", lockTTL).Result()
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	value, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil || value == "" {
+		// Still reserved, or expired since SETNX; either way the client retries
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp StoredResponse
+	if err := json.Unmarshal([]byte(value), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (s *RedisIdempotencyStore) Save(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error {
+	value, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
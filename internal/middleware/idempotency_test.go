@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if strings.Contains(r.URL.Path, "fail") {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	}))
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		key            string
+		body           string
+		wantStatusCode int
+		wantBody       string
+		wantReplayed   bool
+	}{
+		{name: "first request", path: "/auth/register", key: "k1", body: `{"email":"a"}`, wantStatusCode: http.StatusCreated, wantBody: `{"call":1}`},
+		{name: "retry replays", path: "/auth/register", key: "k1", body: `{"email":"a"}`, wantStatusCode: http.StatusCreated, wantBody: `{"call":1}`, wantReplayed: true},
+		{name: "key reused with another body", path: "/auth/register", key: "k1", body: `{"email":"b"}`, wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "no key", path: "/auth/register", body: `{"email":"a"}`, wantStatusCode: http.StatusCreated, wantBody: `{"call":2}`},
+		{name: "same key on another route", path: "/other", key: "k1", body: `{"email":"a"}`, wantStatusCode: http.StatusCreated, wantBody: `{"call":3}`},
+		{name: "server error", path: "/fail", key: "k2", wantStatusCode: http.StatusInternalServerError},
+		{name: "server error is retried", path: "/fail", key: "k2", wantStatusCode: http.StatusInternalServerError},
+		{name: "key too long", path: "/auth/register", key: strings.Repeat("k", 256), wantStatusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.path, tt.key, tt.body)
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplayed {
+				t.Errorf("got replayed %v, want %v", replayed, tt.wantReplayed)
+			}
+		})
+	}
+	if calls != 5 {
+		t.Errorf("handler ran %d times, want 5", calls)
+	}
+}
+
+func TestIdempotencyStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	stores := map[string]IdempotencyStore{
+		"memory": NewMemoryIdempotencyStore(),
+		"redis":  NewRedisIdempotencyStore(client),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if stored, err := store.Reserve(ctx, "k", time.Minute); stored != nil || err != nil {
+				t.Fatalf("first reservation: got %v, %v", stored, err)
+			}
+			if _, err := store.Reserve(ctx, "k", time.Minute); err != ErrIdempotencyInProgress {
+				t.Errorf("second reservation: got %v, want ErrIdempotencyInProgress", err)
+			}
+
+			resp := &StoredResponse{Fingerprint: "f", Status: http.StatusCreated, ContentType: "application/json", Body: []byte(`{}`)}
+			if err := store.Save(ctx, "k", resp, time.Hour); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			stored, err := store.Reserve(ctx, "k", time.Minute)
+			if err != nil || stored == nil || stored.Status != http.StatusCreated || string(stored.Body) != `{}` {
+				t.Errorf("after save: got %+v, %v", stored, err)
+			}
+
+			store.Reserve(ctx, "released", time.Minute)
+			if err := store.Release(ctx, "released"); err != nil {
+				t.Fatalf("release: %v", err)
+			}
+			if stored, err := store.Reserve(ctx, "released", time.Minute); stored != nil || err != nil {
+				t.Errorf("after release: got %v, %v", stored, err)
+			}
+		})
+	}
+}
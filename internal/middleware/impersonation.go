@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// impersonator returns the ID of the admin in the act claim of an
+// impersonation token
+func impersonator(claims jwt.MapClaims) (int64, bool) {
+	act, _ := claims["act"].(map[string]any)
+	sub, ok := act["sub"].(float64)
+	return int64(sub), ok
+}
+
+// ImpersonatorFromContext returns the ID of the admin impersonating the user
+// when the request was authenticated with an impersonation token
+func ImpersonatorFromContext(ctx context.Context) (int64, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return impersonator(claims)
+}
+
+// DenyImpersonation rejects requests made with an impersonation token with
+// 403, for actions an admin must not take on a user's behalf
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ImpersonatorFromContext(r.Context()); ok {
+			problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "Not allowed while impersonating a user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestDenyImpersonation(t *testing.T) {
+	handler := DenyImpersonation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		claims         jwt.MapClaims
+		wantStatusCode int
+	}{
+		{name: "user token", claims: jwt.MapClaims{"sub": float64(2)}, wantStatusCode: http.StatusOK},
+		{name: "impersonation token", claims: jwt.MapClaims{"sub": float64(2), "act": map[string]any{"sub": float64(1)}}, wantStatusCode: http.StatusForbidden},
+		{name: "malformed act claim", claims: jwt.MapClaims{"sub": float64(2), "act": "1"}, wantStatusCode: http.StatusOK},
+		{name: "no claims", wantStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), tt.claims))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/metrics"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+)
+
+// IPBanList temporarily blocks client IPs, e.g. after tripping a canary account
+type IPBanList struct {
+	sync.Mutex
+	bans    map[string]time.Time // IP -> ban expiry
+	metrics *metrics.Security
+}
+
+// IPBanOption configures an IPBanList
+type IPBanOption func(*IPBanList)
+
+// WithBanMetrics counts every ban in the security metrics
+func WithBanMetrics(m *metrics.Security) IPBanOption {
+	return func(b *IPBanList) {
+		b.metrics = m
+	}
+}
+
+// NewIPBanList creates an empty ban list
+func NewIPBanList(opts ...IPBanOption) *IPBanList {
+	b := &IPBanList{
+		bans: make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Ban blocks ip for the given duration
+func (b *IPBanList) Ban(ip string, duration time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+	b.bans[hostOnly(ip)] = time.Now().Add(duration)
+	b.metrics.Ban(nil)
+}
+
+// IsBanned reports whether ip is currently banned
+func (b *IPBanList) IsBanned(ip string) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	ip = hostOnly(ip)
+	expiresAt, exists := b.bans[ip]
+	if !exists {
+		return false
+	}
+	if time.Now().After(expiresAt) {
+		delete(b.bans, ip)
+		return false
+	}
+	return true
+}
+
+// Middleware rejects requests from banned IPs with 403
+func (b *IPBanList) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.IsBanned(r.RemoteAddr) {
+			problem.Error(w, r, http.StatusForbidden, problem.Forbidden, "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Helper function to strip the port from an address
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
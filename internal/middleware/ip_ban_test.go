@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIPBanList(t *testing.T) {
+	bans := NewIPBanList()
+	bans.Ban("10.0.0.1", time.Minute)
+	bans.Ban("10.0.0.2", -time.Second)
+
+	handler := bans.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		wantStatusCode int
+	}{
+		{name: "banned ip with port", remoteAddr: "10.0.0.1:4321", wantStatusCode: http.StatusForbidden},
+		{name: "expired ban", remoteAddr: "10.0.0.2:4321", wantStatusCode: http.StatusOK},
+		{name: "other ip", remoteAddr: "10.0.0.3:4321", wantStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
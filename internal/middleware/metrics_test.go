@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewHTTP(reg)
+
+	r := chi.NewRouter()
+	r.Use(Metrics(m))
+	r.Use(RateLimiter(WithName("test"), WithLimit(Limit{Rate: 0.001, Burst: 2}), WithRejectionMetrics(m)))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// Observations per "method route status" series
+	counts := map[string]uint64{}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "auth_http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var labels []string
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			counts[strings.Join(labels, " ")] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+
+	tests := []struct {
+		name   string
+		series string
+		want   uint64
+	}{
+		{name: "served requests by route pattern", series: "GET /users/{id} 418", want: 2},
+		{name: "rejected requests before routing", series: "GET unmatched 429", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := counts[tt.series]; got != tt.want {
+				t.Errorf("got %d observations, want %d (series %v)", got, tt.want, counts)
+			}
+		})
+	}
+
+	want := `
+# HELP auth_ratelimit_rejections_total Requests rejected by a rate limiter.
+# TYPE auth_ratelimit_rejections_total counter
+auth_ratelimit_rejections_total{limiter="test"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "auth_ratelimit_rejections_total"); err != nil {
+		t.Error(err)
+	}
+}
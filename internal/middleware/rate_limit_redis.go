@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript atomically refills the bucket for the time since its last
+// request, takes a token, and returns whether it did with the tokens left. It reads the clock from Redis so replicas with
+// skewed clocks still agree on the refill. Times are in milliseconds, which Lua
+// can round-trip through Redis strings without losing precision.
+var tokenBucketScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisRateLimitStore keeps token buckets in Redis, so every replica shares
+// the same limits. A bucket expires once it would have refilled completely.
+type RedisRateLimitStore struct {
+	client redis.UniversalClient
+}
+
+// Verify that RedisRateLimitStore implements RateLimitStore interface
+var _ RateLimitStore = (*RedisRateLimitStore)(nil)
+
+// NewRedisRateLimitStore creates a store using client
+func NewRedisRateLimitStore(client redis.UniversalClient) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+// Allow takes a token from the bucket for key, refilled at limit.Rate
+func (s *RedisRateLimitStore) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	res, err := tokenBucketScript.Run(ctx, s.client, []string{"ratelimit:" + key},
+		limit.Rate/1000, limit.Burst).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(res) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected token bucket reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	tokensLeft, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensLeft, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("unexpected token count %q: %v", tokensLeft, err)
+	}
+	return newRateLimitResult(allowed == 1, tokens, limit), nil
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mr.SetTime(start)
+	limit := Every(4, time.Minute, 4)
+
+	// Two replicas sharing one Redis share one limit
+	replicas := []*RedisRateLimitStore{NewRedisRateLimitStore(client), NewRedisRateLimitStore(client)}
+
+	for i := 0; i < 4; i++ {
+		res, err := replicas[i%2].Allow(ctx, "strict:10.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d rejected within limit", i+1)
+		}
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{name: "over limit on either replica", key: "strict:10.0.0.1", want: false},
+		{name: "other client unaffected", key: "strict:10.0.0.2", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, store := range replicas {
+				res, err := store.Allow(ctx, tt.key, limit)
+				if err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+				if res.Allowed != tt.want {
+					t.Errorf("got allowed %v, want %v", res.Allowed, tt.want)
+				}
+			}
+		})
+	}
+
+	if ttl := mr.TTL("ratelimit:strict:10.0.0.1"); ttl <= 0 || ttl > time.Minute+time.Second {
+		t.Errorf("got TTL %v, want at most the time to refill", ttl)
+	}
+
+	// One token refills every 15 seconds
+	mr.SetTime(start.Add(15 * time.Second))
+	for i, want := range []bool{true, false} {
+		res, err := replicas[0].Allow(ctx, "strict:10.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		if res.Allowed != want {
+			t.Errorf("request %d after refill: got allowed %v, want %v", i+1, res.Allowed, want)
+		}
+	}
+}
@@ -1,68 +1,384 @@
 package middleware
 
 import (
+	"context"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/metrics"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Limit configures a token bucket: a client may make up to Burst requests at
+// once, after which the bucket refills at Rate requests per second. Short
+// bursts are absorbed while sustained traffic is held to the refill rate.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns a limit refilling n requests per interval with room for burst
+// requests at once
+func Every(n int, interval time.Duration, burst int) Limit {
+	return Limit{Rate: float64(n) / interval.Seconds(), Burst: burst}
+}
+
+var (
+	// DefaultLimit applies to regular endpoints: 100 requests/min, bursts of 100
+	DefaultLimit = Every(100, time.Minute, 100)
+
+	// StrictLimit applies to sign-in and registration: 10 requests/min, bursts of 10
+	StrictLimit = Every(10, time.Minute, 10)
+
+	// AdminLimit applies to the admin API: 30 requests/min, bursts of 30
+	AdminLimit = Every(30, time.Minute, 30)
+)
+
+// RouteLimits overrides limits for route patterns: an exact path or a prefix
+// ending in "/*"; the longest matching pattern wins. Requests to an overridden
+// route are counted in a bucket of their own.
+type RouteLimits map[string]Limit
+
+// Match returns the pattern and limit overriding path, if any
+func (l RouteLimits) Match(path string) (string, Limit, bool) {
+	if limit, ok := l[path]; ok {
+		return path, limit, true
+	}
+
+	var best string
+	for pattern := range l {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(path, prefix) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return "", Limit{}, false
+	}
+	return best, l[best], true
+}
+
+// LiveLimits holds limits by tier name, with route overrides, that can be
+// replaced while the server runs. Limiters built WithLiveLimits read them on
+// every request, and buckets keep their tokens when the limits change.
+type LiveLimits struct {
+	mu     sync.RWMutex
+	tiers  map[string]Limit
+	routes RouteLimits
+}
+
+// NewLiveLimits creates limits for the tiers and routes
+func NewLiveLimits(tiers map[string]Limit, routes RouteLimits) *LiveLimits {
+	l := &LiveLimits{}
+	l.Set(tiers, routes)
+	return l
+}
+
+// Set replaces every tier and route limit
+func (l *LiveLimits) Set(tiers map[string]Limit, routes RouteLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tiers, l.routes = tiers, routes
+}
+
+// get returns the limit of tier, or fallback when it has none, and the route
+// overrides
+func (l *LiveLimits) get(tier string, fallback Limit) (Limit, RouteLimits) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if limit, ok := l.tiers[tier]; ok {
+		return limit, l.routes
+	}
+	return fallback, l.routes
+}
+
+// RateLimitStore keeps a token bucket per key. With a store shared between
+// replicas, such as RedisRateLimitStore, limits apply to the whole deployment
+// instead of to each replica separately.
+type RateLimitStore interface {
+	// Allow takes a token from the bucket for key if one is available
+	Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error)
+}
+
+// RateLimitResult is the state of a bucket after a request
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int // whole tokens left
+
+	// RetryAfter is the wait until the next token; zero while tokens remain
+	RetryAfter time.Duration
+
+	// Reset is the wait until the bucket is full again
+	Reset time.Duration
+}
+
+func newRateLimitResult(allowed bool, tokens float64, limit Limit) RateLimitResult {
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second)),
+	}
+	if tokens < 1 {
+		res.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	}
+	return res
+}
+
 type visitor struct {
-	count      int
+	tokens     float64
 	lastAccess time.Time
+	full       time.Time // when the bucket has refilled, so it can be forgotten
 }
 
-type rateLimiter struct {
-	sync.RWMutex
-	visitors  map[string]*visitor
-	limit     int
-	timeframe time.Duration
+// DefaultEvictionInterval is how often NewMemoryRateLimitStore removes idle buckets
+const DefaultEvictionInterval = time.Minute
+
+// MemoryRateLimitStore keeps buckets in process memory, so each replica
+// enforces its limits independently. Buckets that have refilled are removed in
+// the background, since they are indistinguishable from new ones.
+type MemoryRateLimitStore struct {
+	sync.Mutex
+	visitors map[string]*visitor
+	now      func() time.Time
+	interval time.Duration
+	metrics  *metrics.RateLimit
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// Verify that MemoryRateLimitStore implements RateLimitStore interface
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+// MemoryStoreOption configures a MemoryRateLimitStore
+type MemoryStoreOption func(*MemoryRateLimitStore)
+
+// WithEvictionInterval sets how often idle buckets are removed
+func WithEvictionInterval(d time.Duration) MemoryStoreOption {
+	return func(s *MemoryRateLimitStore) {
+		s.interval = d
+	}
 }
 
-func newRateLimiter(limit int, timeframe time.Duration) *rateLimiter {
-	return &rateLimiter{
-		visitors:  make(map[string]*visitor),
-		limit:     limit,
-		timeframe: timeframe,
+// WithStoreMetrics exports the number of buckets held and evicted to reg
+func WithStoreMetrics(reg prometheus.Registerer) MemoryStoreOption {
+	return func(s *MemoryRateLimitStore) {
+		s.metrics = metrics.NewRateLimit(reg, s.Len)
 	}
 }
 
-func (rl *rateLimiter) isAllowed(ip string) bool {
-	rl.Lock()
-	defer rl.Unlock()
+// NewMemoryRateLimitStore creates an empty in-memory store and starts evicting
+// idle buckets until Close
+func NewMemoryRateLimitStore(opts ...MemoryStoreOption) *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		visitors: make(map[string]*visitor),
+		now:      time.Now,
+		interval: DefaultEvictionInterval,
+		stop:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	go s.run()
+	return s
+}
+
+// Allow refills the bucket for the time since its last request and takes a token.
+// New keys start with a full bucket.
+func (s *MemoryRateLimitStore) Allow(ctx context.Context, key string, limit Limit) (RateLimitResult, error) {
+	s.Lock()
+	defer s.Unlock()
 
-	now := time.Now()
-	v, exists := rl.visitors[ip]
+	now := s.now()
+	v, exists := s.visitors[key]
 
 	if !exists {
-		rl.visitors[ip] = &visitor{1, now}
-		return true
+		v = &visitor{tokens: float64(limit.Burst), lastAccess: now}
+		s.visitors[key] = v
+	} else {
+		v.tokens = math.Min(float64(limit.Burst), v.tokens+now.Sub(v.lastAccess).Seconds()*limit.Rate)
+		v.lastAccess = now
 	}
 
-	// Reset if timeframe has passed
-	if now.Sub(v.lastAccess) > rl.timeframe {
-		v.count = 1
-		v.lastAccess = now
-		return true
+	allowed := v.tokens >= 1
+	if allowed {
+		v.tokens--
+	}
+	res := newRateLimitResult(allowed, v.tokens, limit)
+	v.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// Len returns the number of buckets held
+func (s *MemoryRateLimitStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.visitors)
+}
+
+// Evict removes the buckets that have refilled and returns how many it removed
+func (s *MemoryRateLimitStore) Evict() int {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	evicted := 0
+	for key, v := range s.visitors {
+		if !now.Before(v.full) {
+			delete(s.visitors, key)
+			evicted++
+		}
 	}
+	s.metrics.Evicted(evicted)
+	return evicted
+}
+
+// Close stops the background eviction
+func (s *MemoryRateLimitStore) Close() {
+	s.closeOnce.Do(func() { close(s.stop) })
+}
 
-	if v.count >= rl.limit {
-		return false
+// run evicts idle buckets every interval until Close
+func (s *MemoryRateLimitStore) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Evict()
+		case <-s.stop:
+			return
+		}
 	}
+}
 
-	v.count++
-	v.lastAccess = now
-	return true
+// KeyFunc derives the bucket a request is counted in. An empty key exempts the
+// request from the limiter.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP counts requests per client IP address
+func KeyByIP(r *http.Request) string {
+	return hostOnly(r.RemoteAddr)
+}
+
+// KeyByUser counts authenticated requests per user ID and other requests per
+// client IP address. It must run after the authentication middleware.
+func KeyByUser(r *http.Request) string {
+	if userID, ok := UserIDFromContext(r.Context()); ok {
+		return "user:" + strconv.FormatInt(userID, 10)
+	}
+	return "ip:" + hostOnly(r.RemoteAddr)
+}
+
+// rateLimiter applies one limit to requests, counted in a store under its own
+// name, unless a route override applies
+type rateLimiter struct {
+	store   RateLimitStore
+	name    string
+	limit   Limit
+	routes  RouteLimits
+	live    *LiveLimits // replaces limit and routes when set
+	tier    string
+	key     KeyFunc
+	reject  http.Handler
+	metrics *metrics.HTTP
+}
+
+// Option configures a middleware built by RateLimiter
+type Option func(*rateLimiter)
+
+// WithStore sets where buckets are kept. Without it each limiter keeps its own
+// buckets in memory.
+func WithStore(store RateLimitStore) Option {
+	return func(rl *rateLimiter) {
+		rl.store = store
+	}
 }
 
-// RateLimiter creates a middleware that limits requests based on IP address
-// It allows 100 requests per minute per IP address for regular endpoints
-func RateLimiter() func(http.Handler) http.Handler {
-	rl := newRateLimiter(100, time.Minute)
+// WithName sets the name buckets are counted under, so limiters sharing a
+// store are counted separately
+func WithName(name string) Option {
+	return func(rl *rateLimiter) {
+		rl.name = name
+	}
+}
+
+// WithLimit sets the limit applied to each bucket
+func WithLimit(limit Limit) Option {
+	return func(rl *rateLimiter) {
+		rl.limit = limit
+	}
+}
+
+// WithRouteLimits overrides the limit for matching routes
+func WithRouteLimits(routes RouteLimits) Option {
+	return func(rl *rateLimiter) {
+		rl.routes = routes
+	}
+}
+
+// WithLiveLimits reads the limit of tier and the route overrides from limits
+// on every request, so they follow LiveLimits.Set. A tier without a limit
+// keeps the one of WithLimit.
+func WithLiveLimits(limits *LiveLimits, tier string) Option {
+	return func(rl *rateLimiter) {
+		rl.live, rl.tier = limits, tier
+	}
+}
+
+// WithKeyFunc sets how requests are assigned to buckets (KeyByIP by default)
+func WithKeyFunc(key KeyFunc) Option {
+	return func(rl *rateLimiter) {
+		rl.key = key
+	}
+}
+
+// WithRejectionHandler sets the handler for rejected requests, which by
+// default get a 429 problem. The rate limit headers are already set when it runs.
+func WithRejectionHandler(h http.Handler) Option {
+	return func(rl *rateLimiter) {
+		rl.reject = h
+	}
+}
+
+// WithRejectionMetrics counts rejected requests under the limiter's name
+func WithRejectionMetrics(m *metrics.HTTP) Option {
+	return func(rl *rateLimiter) {
+		rl.metrics = m
+	}
+}
+
+// RateLimiter creates a middleware that limits requests per bucket. By default
+// it allows 100 requests per minute per IP address, the limit for regular
+// endpoints, with buckets in memory.
+func RateLimiter(opts ...Option) func(http.Handler) http.Handler {
+	rl := &rateLimiter{
+		name:  "default",
+		limit: DefaultLimit,
+		key:   KeyByIP,
+		reject: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			problem.Error(w, r, http.StatusTooManyRequests, problem.RateLimited, "Too many requests")
+		}),
+	}
+	for _, opt := range opts {
+		opt(rl)
+	}
+	if rl.store == nil {
+		rl.store = NewMemoryRateLimitStore()
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-			if !rl.isAllowed(ip) {
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			key := rl.key(r)
+			if key != "" && !rl.allow(w, r, key) {
+				rl.metrics.RateLimited(rl.name)
+				rl.reject.ServeHTTP(w, r)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -71,17 +387,47 @@ func RateLimiter() func(http.Handler) http.Handler {
 }
 
 // StrictRateLimiter creates a more restrictive rate limiter for sensitive endpoints
-// like login and registration (10 requests per minute per IP)
-func StrictRateLimiter() func(http.Handler) http.Handler {
-	rl := newRateLimiter(10, time.Minute)
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-			if !rl.isAllowed(ip) {
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
+// like login and registration (10 requests per minute per IP by default)
+func StrictRateLimiter(opts ...Option) func(http.Handler) http.Handler {
+	return RateLimiter(append([]Option{WithName("strict"), WithLimit(StrictLimit)}, opts...)...)
+}
+
+// UserRateLimiter limits authenticated requests per user ID (100 requests per
+// minute by default), so users sharing an IP address, such as behind a NAT, are
+// limited individually and an abusive account is limited whichever IP it uses.
+// Requests without an authenticated user are limited per IP address. It must
+// run after the authentication middleware.
+func UserRateLimiter(opts ...Option) func(http.Handler) http.Handler {
+	return RateLimiter(append([]Option{WithName("user"), WithKeyFunc(KeyByUser)}, opts...)...)
+}
+
+// allow reports whether the client may make another request and sets the
+// X-RateLimit-* and Retry-After headers so clients can throttle themselves.
+// If the store fails, requests are allowed rather than taking the service
+// down with it.
+func (rl *rateLimiter) allow(w http.ResponseWriter, r *http.Request, key string) bool {
+	limit, routes, key := rl.limit, rl.routes, rl.name+":"+key
+	if rl.live != nil {
+		limit, routes = rl.live.get(rl.tier, limit)
 	}
+	if pattern, override, ok := routes.Match(r.URL.Path); ok {
+		limit, key = override, rl.name+":"+pattern+":"+key
+	}
+
+	res, err := rl.store.Allow(r.Context(), key, limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "rate limit store unavailable, allowing request", "limiter", rl.name, "err", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+	return res.Allowed
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
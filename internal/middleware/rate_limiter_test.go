@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestRateLimiter(t *testing.T) {
@@ -12,7 +17,7 @@ func TestRateLimiter(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	limiter := StrictRateLimiter()(handler) // Use StrictRateLimiter which has lower limits
+	limiter := StrictRateLimiter(WithStore(NewMemoryRateLimitStore()), WithName("test"))(handler) // Use StrictRateLimiter which has lower limits
 
 	tests := []struct {
 		name           string
@@ -55,3 +60,290 @@ func TestRateLimiter(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryRateLimitStoreTokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	limit := Every(6, time.Minute, 3) // one token every 10 seconds
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    []bool
+	}{
+		{name: "burst allowed at once", want: []bool{true, true, true, false}},
+		{name: "partial refill", advance: 5 * time.Second, want: []bool{false}},
+		{name: "one token refilled", advance: 5 * time.Second, want: []bool{true, false}},
+		{name: "refill capped at burst", advance: time.Hour, want: []bool{true, true, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			for i, want := range tt.want {
+				res, err := store.Allow(context.Background(), "strict:10.0.0.1", limit)
+				if err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+				if res.Allowed != want {
+					t.Errorf("request %d: got allowed %v, want %v", i+1, res.Allowed, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	handler := RateLimiter(WithStore(store), WithName("test"), WithLimit(Every(6, time.Minute, 2)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		wantStatus int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{name: "first request", wantStatus: http.StatusOK, remaining: "1", reset: "10", retryAfter: "0"},
+		{name: "bucket drained", wantStatus: http.StatusOK, remaining: "0", reset: "20", retryAfter: "10"},
+		{name: "rejected", wantStatus: http.StatusTooManyRequests, remaining: "0", reset: "20", retryAfter: "10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "127.0.0.1:12345"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatus)
+			}
+			for header, want := range map[string]string{
+				"X-RateLimit-Limit":     "2",
+				"X-RateLimit-Remaining": tt.remaining,
+				"X-RateLimit-Reset":     tt.reset,
+				"Retry-After":           tt.retryAfter,
+			} {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRateLimiter(t *testing.T) {
+	handler := UserRateLimiter(WithName("test"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(userID int64, ip string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = ip
+		if userID != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), userIDKey, userID))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// User 1 exhausts their bucket from behind a shared NAT address
+	for i := 0; i < DefaultLimit.Burst; i++ {
+		if code := request(1, "203.0.113.1:1000"); code != http.StatusOK {
+			t.Fatalf("request %d rejected within limit: %d", i+1, code)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		ip     string
+		want   int
+	}{
+		{name: "same user over limit", userID: 1, ip: "203.0.113.1:1000", want: http.StatusTooManyRequests},
+		{name: "same user from another IP", userID: 1, ip: "198.51.100.7:2000", want: http.StatusTooManyRequests},
+		{name: "other user behind the same NAT", userID: 2, ip: "203.0.113.1:1001", want: http.StatusOK},
+		{name: "anonymous request from the same NAT", ip: "203.0.113.1:1000", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := request(tt.userID, tt.ip); got != tt.want {
+				t.Errorf("got status %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteLimits(t *testing.T) {
+	routes := RouteLimits{
+		"/auth/login":   Every(1, time.Minute, 1),
+		"/auth/*":       Every(2, time.Minute, 2),
+		"/auth/admin/*": Every(3, time.Minute, 3),
+	}
+	handler := RateLimiter(WithLimit(Every(100, time.Minute, 100)), WithRouteLimits(routes))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path      string
+		wantLimit string
+		allowed   int
+	}{
+		{path: "/auth/login", wantLimit: "1", allowed: 1},
+		{path: "/auth/register", wantLimit: "2", allowed: 2},
+		{path: "/auth/admin/users", wantLimit: "3", allowed: 3},
+		{path: "/health", wantLimit: "100", allowed: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			for i := 0; i <= tt.allowed; i++ {
+				req := httptest.NewRequest("GET", tt.path, nil)
+				req.RemoteAddr = "127.0.0.1:12345"
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				want := http.StatusOK
+				if i == tt.allowed {
+					want = http.StatusTooManyRequests
+				}
+				if w.Code != want {
+					t.Fatalf("request %d: got status %v, want %v", i+1, w.Code, want)
+				}
+				if got := w.Header().Get("X-RateLimit-Limit"); got != tt.wantLimit {
+					t.Errorf("X-RateLimit-Limit = %q, want %q", got, tt.wantLimit)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveLimits(t *testing.T) {
+	limits := NewLiveLimits(map[string]Limit{"strict": Every(2, time.Minute, 2)}, nil)
+	handler := RateLimiter(WithLiveLimits(limits, "strict"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w := request("/auth/login"); w.Code != want {
+			t.Fatalf("request %d: got status %v, want %v", i+1, w.Code, want)
+		}
+	}
+
+	// The new limit applies from the next request
+	limits.Set(map[string]Limit{"strict": Every(5, time.Minute, 5)}, RouteLimits{"/health": Every(1, time.Minute, 1)})
+	if w := request("/auth/login"); w.Header().Get("X-RateLimit-Limit") != "5" {
+		t.Errorf("after Set: got limit %q, want 5", w.Header().Get("X-RateLimit-Limit"))
+	}
+	if w := request("/health"); w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Errorf("route override: got limit %q, want 1", w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	// Without a limit for the tier the default applies
+	limits.Set(nil, nil)
+	if w := request("/auth/login"); w.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Errorf("tier without a limit: got limit %q, want the default 100", w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	store := NewMemoryRateLimitStore(WithStoreMetrics(reg))
+	defer store.Close()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	limit := Every(6, time.Minute, 3) // one token every 10 seconds
+
+	ctx := context.Background()
+	store.Allow(ctx, "a", limit) // refilled after 10s
+	for i := 0; i < 3; i++ {
+		store.Allow(ctx, "b", limit) // refilled after 30s
+	}
+
+	tests := []struct {
+		name        string
+		advance     time.Duration
+		wantEvicted int
+		wantLen     int
+	}{
+		{name: "nothing refilled", advance: 5 * time.Second, wantEvicted: 0, wantLen: 2},
+		{name: "one bucket refilled", advance: 5 * time.Second, wantEvicted: 1, wantLen: 1},
+		{name: "all buckets refilled", advance: 20 * time.Second, wantEvicted: 1, wantLen: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			if got := store.Evict(); got != tt.wantEvicted {
+				t.Errorf("evicted %d, want %d", got, tt.wantEvicted)
+			}
+			if got := store.Len(); got != tt.wantLen {
+				t.Errorf("len %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+
+	expected := `
+# HELP auth_ratelimit_evictions_total Idle rate limit buckets removed from memory.
+# TYPE auth_ratelimit_evictions_total counter
+auth_ratelimit_evictions_total 2
+# HELP auth_ratelimit_visitors Clients with a rate limit bucket held in memory.
+# TYPE auth_ratelimit_visitors gauge
+auth_ratelimit_visitors 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimiterOptions(t *testing.T) {
+	handler := RateLimiter(
+		WithLimit(Every(1, time.Minute, 1)),
+		WithKeyFunc(func(r *http.Request) string { return r.Header.Get("X-Tenant") }),
+		WithRejectionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		tenant string
+		want   int
+	}{
+		{name: "first request for tenant", tenant: "acme", want: http.StatusOK},
+		{name: "custom rejection", tenant: "acme", want: http.StatusServiceUnavailable},
+		{name: "other tenant", tenant: "globex", want: http.StatusOK},
+		{name: "empty key is exempt", want: http.StatusOK},
+		{name: "empty key stays exempt", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-Tenant", tt.tenant)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("got status %v, want %v", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/logging"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const logEntryKey contextKey = "log_entry"
+
+// logEntry collects details learned while handling a request, such as the
+// authenticated user, which inner middleware stores in a derived context
+type logEntry struct {
+	userID int64
+}
+
+// RequestLogger logs one record per request with its request ID, route,
+// status, latency and, once authenticated, user ID. Records logged while
+// handling the request carry the request ID too. It must run after
+// chimiddleware.RequestID. Query strings are left out because they can carry
+// codes and tokens.
+func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &logEntry{}
+			ctx := context.WithValue(r.Context(), logEntryKey, entry)
+			ctx = logging.WithAttrs(ctx, slog.String("request_id", chimiddleware.GetReqID(ctx)))
+			r = r.WithContext(ctx)
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", chi.RouteContext(ctx).RoutePattern()),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_ip", hostOnly(r.RemoteAddr)),
+			}
+			if entry.userID != 0 {
+				attrs = append(attrs, slog.Int64("user_id", entry.userID))
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(ctx, level, "request", attrs...)
+		})
+	}
+}
+
+// withUserID stores the authenticated user for UserIDFromContext and the
+// request log
+func withUserID(ctx context.Context, userID int64) context.Context {
+	if entry, ok := ctx.Value(logEntryKey).(*logEntry); ok {
+		entry.userID = userID
+	}
+	return context.WithValue(ctx, userIDKey, userID)
+}
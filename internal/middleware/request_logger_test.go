@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/logging"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, slog.LevelInfo)
+
+	r := chi.NewRouter()
+	r.Use(chimiddleware.RequestID)
+	r.Use(RequestLogger(logger))
+	r.Use(APIKeyAuth(staticValidator{"ak_valid": 42}))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "inside handler")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest("GET", "/users/7?code=secret", nil)
+	req.Header.Set("X-API-Key", "ak_valid")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var records []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("invalid log record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+
+	inner, request := records[0], records[1]
+	if inner["request_id"] == nil || inner["request_id"] != request["request_id"] {
+		t.Errorf("handler record request ID %v does not match request record %v", inner["request_id"], request["request_id"])
+	}
+
+	tests := []struct {
+		key  string
+		want any
+	}{
+		{key: "msg", want: "request"},
+		{key: "method", want: "GET"},
+		{key: "path", want: "/users/7"},
+		{key: "route", want: "/users/{id}"},
+		{key: "status", want: float64(http.StatusTeapot)},
+		{key: "user_id", want: float64(42)},
+	}
+	for _, tt := range tests {
+		if got := request[tt.key]; got != tt.want {
+			t.Errorf("got %s %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if _, ok := request["latency_ms"].(float64); !ok {
+		t.Error("expected latency_ms")
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Authenticator checks a request against one authentication strategy. When the
+// request satisfies it, the authenticator returns the request with the
+// authenticated identity (user ID, token claims) stored in its context;
+// otherwise it may return the request with the reason stored for the rejection.
+type Authenticator func(r *http.Request) (*http.Request, bool)
+
+const authErrorKey contextKey = "auth_error"
+
+// TokenValidator validates a Bearer JWT and returns its claims
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (jwt.MapClaims, error)
+}
+
+// JWTAuthenticator accepts requests with a valid Bearer JWT, or one in the
+// session cookie of browser clients, and stores its subject, claims and raw
+// token for UserIDFromContext, ClaimsFromContext and TokenFromContext. Audit
+// events of requests with an impersonation token carry the impersonating admin.
+func JWTAuthenticator(validator TokenValidator) Authenticator {
+	return func(r *http.Request) (*http.Request, bool) {
+		token := bearerOrSessionToken(r)
+		if token == "" {
+			return r, false
+		}
+
+		claims, err := validator.ValidateToken(r.Context(), token)
+		if err != nil {
+			return r.WithContext(context.WithValue(r.Context(), authErrorKey, err)), false
+		}
+
+		sub, ok := claims["sub"].(float64)
+		if !ok {
+			return r, false
+		}
+
+		ctx := withUserID(r.Context(), int64(sub))
+		ctx = context.WithValue(ctx, claimsKey, claims)
+		ctx = context.WithValue(ctx, tokenKey, token)
+		if adminID, ok := impersonator(claims); ok {
+			ctx = audit.WithImpersonator(ctx, adminID)
+		}
+		return r.WithContext(ctx), true
+	}
+}
+
+// bearerOrSessionToken returns the Bearer token of r, falling back to the
+// session cookie. Cookie-authenticated requests are checked by CSRF.Middleware.
+func bearerOrSessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// APIKeyAuthenticator accepts requests with a valid X-API-Key header
+func APIKeyAuthenticator(validator APIKeyValidator) Authenticator {
+	return func(r *http.Request) (*http.Request, bool) {
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			return r, false
+		}
+
+		userID, err := validator.ValidateAPIKey(r.Context(), key)
+		if err != nil {
+			return r, false
+		}
+		return r.WithContext(withUserID(r.Context(), userID)), true
+	}
+}
+
+// MTLSAuthenticator accepts requests over TLS with a verified client certificate
+func MTLSAuthenticator() Authenticator {
+	return func(r *http.Request) (*http.Request, bool) {
+		return r, r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+	}
+}
+
+// Identify stores the identity of requests that satisfy one of the
+// authenticators, like RouteAuth, but lets every other request through
+// anonymously, for routes that serve both, such as a GraphQL endpoint whose
+// fields decide which need a user
+func Identify(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := UserIDFromContext(r.Context()); !ok {
+				for _, authenticate := range authenticators {
+					if authenticated, ok := authenticate(r); ok {
+						r = authenticated
+						break
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RouteAuth enforces the route policies from configuration. A request must
+// satisfy any one of the strategies its route requires, or it is rejected with
+// 401. Authenticated user IDs are available through UserIDFromContext.
+func RouteAuth(policies config.RoutePolicies, authenticators map[config.AuthStrategy]Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rejected := r
+			for _, strategy := range policies.Match(r.URL.Path) {
+				if strategy == config.StrategyPublic {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				authenticate, exists := authenticators[strategy]
+				if !exists {
+					continue
+				}
+				authenticated, ok := authenticate(r)
+				if ok {
+					next.ServeHTTP(w, authenticated)
+					return
+				}
+				if _, failed := authenticated.Context().Value(authErrorKey).(error); failed {
+					rejected = authenticated
+				}
+			}
+
+			unauthorized(w, rejected)
+		})
+	}
+}
+
+// unauthorized rejects a request that failed authentication with 401, telling
+// clients whose token expired, which can refresh it, apart from the rest
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	if err, _ := r.Context().Value(authErrorKey).(error); errors.Is(err, jwt.ErrTokenExpired) {
+		problem.Error(w, r, http.StatusUnauthorized, problem.TokenExpired, "Token has expired")
+		return
+	}
+	problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "Authentication required")
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/config"
+)
+
+func TestRouteAuth(t *testing.T) {
+	policies := config.RoutePolicies{
+		"/me":         {config.StrategyJWT, config.StrategyAPIKey},
+		"/internal/*": {config.StrategyMTLS},
+	}
+	authenticators := map[config.AuthStrategy]Authenticator{
+		config.StrategyAPIKey: APIKeyAuthenticator(staticValidator{"ak_valid": 42}),
+		config.StrategyMTLS:   MTLSAuthenticator(),
+	}
+
+	handler := RouteAuth(policies, authenticators)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		apiKey         string
+		clientCert     bool
+		wantStatusCode int
+	}{
+		{name: "public route", path: "/auth/login", wantStatusCode: http.StatusOK},
+		{name: "api key accepted", path: "/me", apiKey: "ak_valid", wantStatusCode: http.StatusOK},
+		{name: "missing credentials", path: "/me", wantStatusCode: http.StatusUnauthorized},
+		{name: "invalid api key", path: "/me", apiKey: "ak_invalid", wantStatusCode: http.StatusUnauthorized},
+		{name: "mtls required", path: "/internal/stats", apiKey: "ak_valid", wantStatusCode: http.StatusUnauthorized},
+		{name: "verified client certificate", path: "/internal/stats", clientCert: true, wantStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if tt.clientCert {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	handler := Identify(APIKeyAuthenticator(staticValidator{"ak_valid": 42}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID, ok := UserIDFromContext(r.Context()); ok {
+			w.Header().Set("X-User", strconv.FormatInt(userID, 10))
+		}
+	}))
+
+	for apiKey, wantUser := range map[string]string{"ak_valid": "42", "ak_invalid": "", "": ""} {
+		req := httptest.NewRequest("POST", "/graphql", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("X-User") != wantUser {
+			t.Errorf("API key %q: got status %d and user %q, want 200 and %q", apiKey, w.Code, w.Header().Get("X-User"), wantUser)
+		}
+	}
+}
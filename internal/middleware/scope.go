@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const claimsKey contextKey = "claims"
+
+// ClaimsFromContext returns the token claims stored by JWTAuthenticator
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsKey).(jwt.MapClaims)
+	return claims, ok
+}
+
+// WithClaims stores token claims in the context, for consumers that validate
+// tokens with their own middleware before using RequireScope
+func WithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// HasScope reports whether the space-separated scope claim contains scope
+func HasScope(claims jwt.MapClaims, scope string) bool {
+	granted, _ := claims["scope"].(string)
+	return slices.Contains(strings.Fields(granted), scope)
+}
+
+// RequireScope rejects requests whose token does not carry scope with 403.
+// It must run after a middleware that stores the token claims; requests
+// authenticated without a token, such as with an API key, carry no scopes.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok || !HasScope(claims, scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				problem.Error(w, r, http.StatusForbidden, problem.InsufficientScope, "Token lacks the required scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
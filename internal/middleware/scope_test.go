@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireScope(t *testing.T) {
+	handler := RequireScope("users:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		claims         jwt.MapClaims
+		wantStatusCode int
+	}{
+		{name: "scope granted", claims: jwt.MapClaims{"scope": "users:read users:write"}, wantStatusCode: http.StatusOK},
+		{name: "scope missing", claims: jwt.MapClaims{"scope": "users:read"}, wantStatusCode: http.StatusForbidden},
+		{name: "prefix is not a match", claims: jwt.MapClaims{"scope": "users:writer"}, wantStatusCode: http.StatusForbidden},
+		{name: "no scope claim", claims: jwt.MapClaims{}, wantStatusCode: http.StatusForbidden},
+		{name: "no claims", wantStatusCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), tt.claims))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
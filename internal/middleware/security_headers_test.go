@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []SecurityHeadersOption
+		wantHeaders map[string]string
+	}{
+		{
+			name: "defaults",
+			wantHeaders: map[string]string{
+				"Strict-Transport-Security": "max-age=63072000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+				"Content-Security-Policy":   DefaultContentSecurityPolicy,
+			},
+		},
+		{
+			name: "custom policy without HSTS",
+			opts: []SecurityHeadersOption{WithContentSecurityPolicy("default-src 'self'"), WithHSTSMaxAge(0)},
+			wantHeaders: map[string]string{
+				"Strict-Transport-Security": "",
+				"X-Frame-Options":           "DENY",
+				"Content-Security-Policy":   "default-src 'self'",
+			},
+		},
+		{
+			name:        "policy disabled",
+			opts:        []SecurityHeadersOption{WithContentSecurityPolicy("")},
+			wantHeaders: map[string]string{"Content-Security-Policy": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SecurityHeaders(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+
+			for header, want := range tt.wantHeaders {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("got %s %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
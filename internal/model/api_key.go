@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// APIKey is a long-lived credential a user can issue for CLI tools and CI.
+// Only a hash of the key is stored; the prefix identifies it in listings.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Created    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Consent purposes recorded in receipts
+const (
+	ConsentTermsOfService = "tos"
+	ConsentPrivacyPolicy  = "privacy_policy"
+	ConsentMarketing      = "marketing"
+	ConsentOAuthScopes    = "oauth_scopes"
+)
+
+// ConsentReceipt is an immutable record of a user granting or withdrawing consent
+type ConsentReceipt struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	Subject   string    `json:"subject,omitempty"` // e.g. the OAuth client ID
+	Scope     string    `json:"scope,omitempty"`   // e.g. the granted OAuth scopes
+	Version   string    `json:"version,omitempty"`
+	Granted   bool      `json:"granted"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Created   time.Time `json:"created_at"`
+}
+
+// TermsStatus tells whether a user accepted the current version of a document
+// they must agree to, such as the terms of service
+type TermsStatus struct {
+	Purpose         string     `json:"purpose"` // ConsentTermsOfService or ConsentPrivacyPolicy
+	CurrentVersion  string     `json:"current_version"`
+	AcceptedVersion string     `json:"accepted_version,omitempty"` // the latest accepted, if any
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	UpToDate        bool       `json:"up_to_date"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "strings"
+
+// NormalizeEmail trims and lowercases an email address, so addresses that
+// differ only in case belong to the same account. Stored users are unique by
+// their normalized address.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// FoldGmailAddress maps a normalized Gmail address to the mailbox it is
+// delivered to: dots and anything after a + in the local part are ignored,
+// and googlemail.com is gmail.com. Other addresses are returned unchanged.
+func FoldGmailAddress(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if domain != "gmail.com" && domain != "googlemail.com" {
+		return email
+	}
+	if plus := strings.IndexByte(local, '+'); plus >= 0 {
+		local = local[:plus]
+	}
+	if local = strings.ReplaceAll(local, ".", ""); local == "" {
+		return email
+	}
+	return local + "@gmail.com"
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// BlockedDomain is an email domain refused at registration, along with its
+// subdomains
+type BlockedDomain struct {
+	Domain  string    `json:"domain"`
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"created_at"`
+}
+
+// NormalizeDomain lowercases a domain name, accepting a leading @ as in
+// "@example.com", and reports whether it is a valid host name with at least
+// two labels
+func NormalizeDomain(domain string) (string, bool) {
+	domain = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@"), ".")
+	if len(domain) > 253 || !strings.Contains(domain, ".") {
+		return "", false
+	}
+	for label := range strings.SplitSeq(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return "", false
+			}
+		}
+	}
+	return domain, true
+}
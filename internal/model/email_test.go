@@ -0,0 +1,29 @@
+package model
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email      string
+		normalized string
+		folded     string
+	}{
+		{email: " Foo@X.com ", normalized: "foo@x.com", folded: "foo@x.com"},
+		{email: "J.Doe+news@Gmail.com", normalized: "j.doe+news@gmail.com", folded: "jdoe@gmail.com"},
+		{email: "jdoe@googlemail.com", normalized: "jdoe@googlemail.com", folded: "jdoe@gmail.com"},
+		{email: "j.doe+news@example.com", normalized: "j.doe+news@example.com", folded: "j.doe+news@example.com"},
+		{email: "+tag@gmail.com", normalized: "+tag@gmail.com", folded: "+tag@gmail.com"},
+		{email: "not-an-email", normalized: "not-an-email", folded: "not-an-email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			normalized := NormalizeEmail(tt.email)
+			if normalized != tt.normalized {
+				t.Errorf("NormalizeEmail() = %q, want %q", normalized, tt.normalized)
+			}
+			if got := FoldGmailAddress(normalized); got != tt.folded {
+				t.Errorf("FoldGmailAddress() = %q, want %q", got, tt.folded)
+			}
+		})
+	}
+}
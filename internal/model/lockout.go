@@ -0,0 +1,21 @@
+package model
+
+// LockoutPolicy decides when repeated failed sign-ins lock an account
+type LockoutPolicy struct {
+	MaxFailedAttempts int64 // failed attempts that lock the account
+}
+
+// DefaultLockoutPolicy locks accounts after 5 failed login attempts
+var DefaultLockoutPolicy = LockoutPolicy{MaxFailedAttempts: 5}
+
+// IsLocked reports whether an account with the given failed attempts is locked
+func (p LockoutPolicy) IsLocked(failedAttempts int64) bool {
+	return failedAttempts >= p.MaxFailedAttempts
+}
+
+// LockoutState reports how close an account is to being locked
+type LockoutState struct {
+	FailedAttempts    int64
+	MaxFailedAttempts int64
+	Locked            bool
+}
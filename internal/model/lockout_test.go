@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestLockoutPolicyIsLocked(t *testing.T) {
+	policy := LockoutPolicy{MaxFailedAttempts: 3}
+
+	tests := []struct {
+		attempts int64
+		want     bool
+	}{
+		{attempts: 0, want: false},
+		{attempts: 2, want: false},
+		{attempts: 3, want: true},
+		{attempts: 4, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := policy.IsLocked(tt.attempts); got != tt.want {
+			t.Errorf("IsLocked(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
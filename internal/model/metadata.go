@@ -0,0 +1,27 @@
+package model
+
+import "maps"
+
+// UserMetadata is free-form JSON kept with a user for consuming applications,
+// stored in the users.metadata column. AppMetadata can only be written
+// through the admin API; UserMetadata also by the user.
+type UserMetadata struct {
+	AppMetadata  map[string]any `json:"app_metadata"`
+	UserMetadata map[string]any `json:"user_metadata"`
+}
+
+// MergeMetadata applies patch to metadata like a JSON merge patch (RFC 7396)
+// of its top-level keys: keys in patch replace those in metadata, and keys
+// set to null are removed. The result is never nil.
+func MergeMetadata(metadata, patch map[string]any) map[string]any {
+	merged := make(map[string]any, len(metadata)+len(patch))
+	maps.Copy(merged, metadata)
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
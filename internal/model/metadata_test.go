@@ -0,0 +1,28 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]any
+		patch    map[string]any
+		want     map[string]any
+	}{
+		{name: "empty", want: map[string]any{}},
+		{name: "add", metadata: map[string]any{"theme": "dark"}, patch: map[string]any{"lang": "de"}, want: map[string]any{"theme": "dark", "lang": "de"}},
+		{name: "replace", metadata: map[string]any{"theme": "dark"}, patch: map[string]any{"theme": map[string]any{"mode": "light"}}, want: map[string]any{"theme": map[string]any{"mode": "light"}}},
+		{name: "remove with null", metadata: map[string]any{"theme": "dark", "lang": "de"}, patch: map[string]any{"theme": nil}, want: map[string]any{"lang": "de"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeMetadata(tt.metadata, tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
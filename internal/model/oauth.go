@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// OAuthClient is an application allowed to delegate login to this service
+type OAuthClient struct {
+	ID           int64
+	ClientID     string
+	SecretHash   string // hashed, empty for public clients
+	Name         string
+	IsPublic     bool // public clients cannot keep a secret and must use PKCE
+	RedirectURIs []string
+	GrantTypes   []string // e.g. authorization_code, client_credentials
+	Scopes       []string // scopes a machine client may request
+	Created      time.Time
+}
+
+// OAuth grant types supported by the token endpoint
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+	GrantTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// TokenTypeAccessToken identifies access tokens in RFC 8693 token exchange
+const TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+// AuthorizationCode is a single-use grant issued by the authorize endpoint
+type AuthorizationCode struct {
+	CodeHash    string
+	ClientID    string
+	UserID      int64
+	RedirectURI string
+	Scope       string
+	Nonce       string
+	ExpiresAt   time.Time
+
+	// PKCE challenge the token request's code_verifier must match
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
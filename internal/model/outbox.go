@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// OutboxEvent is an event stored in the outbox, in the same transaction as
+// the change it describes, until the outbox relay publishes it
+type OutboxEvent struct {
+	ID        int64
+	EventID   string
+	EventType string
+	Payload   []byte // the event encoded as JSON
+	Attempts  int    // failed attempts to publish it so far
+	Created   time.Time
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Session is an issued access token that can be revoked before it expires.
+// The token ID is the jti claim of the token.
+type Session struct {
+	ID      int64  `json:"id"`
+	UserID  int64  `json:"user_id"`
+	TokenID string `json:"-"`
+	// DeviceFingerprint is the SHA-256 hash, hex-encoded, of the fingerprint
+	// the client sent when signing in, or empty when it sent none
+	DeviceFingerprint string `json:"device_fingerprint,omitempty"`
+	// RememberMe marks a long-lived session the user asked to stay signed in
+	// with, rather than one for a single browser session
+	RememberMe bool      `json:"remember_me"`
+	Created    time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// Tenant lifecycle states. Users of a suspended tenant cannot sign in or use
+// API keys until it is reactivated.
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+)
+
+// Tenant is an organization whose users are isolated and configured together
+type Tenant struct {
+	ID       int64
+	Slug     string // URL-safe unique name
+	Name     string
+	Status   string
+	Settings TenantSettings
+	Created  time.Time
+}
+
+// TenantSettings holds the per-tenant configuration, stored as JSON
+type TenantSettings struct {
+	Lockout           *LockoutPolicy `json:"lockout,omitempty"` // nil uses the service default
+	Branding          TenantBranding `json:"branding"`
+	IdentityProviders []TenantIdP    `json:"identity_providers,omitempty"`
+	Keys              []TenantKey    `json:"keys,omitempty"`
+}
+
+// TenantBranding customizes the sign-in pages shown to the tenant's users
+type TenantBranding struct {
+	DisplayName  string `json:"display_name,omitempty"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"` // #RRGGBB
+}
+
+// TenantIdP is an external identity provider the tenant's users sign in with
+type TenantIdP struct {
+	Type        string `json:"type"` // saml or oidc
+	Name        string `json:"name"`
+	MetadataURL string `json:"metadata_url,omitempty"` // SAML IdP metadata
+	Issuer      string `json:"issuer,omitempty"`       // OIDC issuer
+	ClientID    string `json:"client_id,omitempty"`    // OIDC client registered at the issuer
+}
+
+// TenantKey is a public key the tenant registers, e.g. to sign assertions it sends us
+type TenantKey struct {
+	ID           string `json:"kid"`
+	PublicKeyPEM string `json:"public_key_pem"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// Usage metrics counted for billing
+const (
+	UsageLogins       = "logins"
+	UsageTokensIssued = "tokens_issued"
+	UsageEmailsSent   = "emails_sent"
+	UsageSMSSent      = "sms_sent"
+)
+
+// UsageKey identifies who usage is attributed to. TenantID is 0 for users
+// without a tenant and ClientID is empty outside OAuth client flows.
+type UsageKey struct {
+	TenantID int64  `json:"tenant_id"`
+	ClientID string `json:"client_id"`
+}
+
+// UsageCount is an increment of one metric
+type UsageCount struct {
+	UsageKey
+	Metric string
+	Count  int64
+}
+
+// ActiveUser marks a user as active for a key in a period
+type ActiveUser struct {
+	UsageKey
+	UserID int64
+}
+
+// UsageRecord totals one month of usage for a key
+type UsageRecord struct {
+	UsageKey
+	MonthlyActiveUsers int64 `json:"monthly_active_users"`
+	Logins             int64 `json:"logins"`
+	TokensIssued       int64 `json:"tokens_issued"`
+	EmailsSent         int64 `json:"emails_sent"`
+	SMSSent            int64 `json:"sms_sent"`
+}
+
+// UsageReport is one month of usage. Tenant rows total every client of the
+// tenant, so a user active through several clients counts once there.
+type UsageReport struct {
+	Period  time.Time      `json:"period"` // first day of the month, UTC
+	Tenants []*UsageRecord `json:"tenants"`
+	Clients []*UsageRecord `json:"clients"`
+}
+
+// UsagePeriod returns the billing period containing t
+func UsagePeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Add adds count to the record's field for metric. Unknown metrics are ignored.
+func (record *UsageRecord) Add(metric string, count int64) {
+	switch metric {
+	case UsageLogins:
+		record.Logins += count
+	case UsageTokensIssued:
+		record.TokensIssued += count
+	case UsageEmailsSent:
+		record.EmailsSent += count
+	case UsageSMSSent:
+		record.SMSSent += count
+	}
+}
@@ -1,6 +1,31 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// User roles. Admins of a tenant manage that tenant's users.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Account statuses, from the most to the least severe
+const (
+	UserStatusDeleted  = "deleted"
+	UserStatusDisabled = "disabled"
+	UserStatusLocked   = "locked"
+	UserStatusActive   = "active"
+)
+
+// User types. Service accounts are non-human identities for automation; they
+// cannot sign in with a password and authenticate only with API keys.
+const (
+	UserTypeHuman   = "human"
+	UserTypeService = "service"
+)
 
 type User struct {
 	ID             int64
@@ -8,4 +33,74 @@ type User struct {
 	Password       string // hashed
 	Created        time.Time
 	FailedAttempts int64
+	IsCanary       bool       // decoy account; any sign-in attempt is an intrusion signal
+	Type           string     // UserTypeHuman or UserTypeService
+	IsLocked       bool       // only set by listings; lookups of locked users fail instead
+	IsDisabled     bool       // disabled by an administrator; likewise only set by listings
+	EmailVerified  bool       // vouched for by an identity provider; only set by listings
+	DeletedAt      *time.Time // soft-deleted; lookups of deleted users fail, so only set by listings
+	Role           string     // RoleUser or RoleAdmin
+	TenantID       *int64     // nil for users outside any tenant
+
+	PasswordChangedAt time.Time  // when the password was last set; only set by lookups
+	PasswordExpiresAt *time.Time // set when an administrator forced a password change
+	LastLogin         *time.Time // last successful sign-in; nil if the user never signed in
+
+	Profile
+}
+
+// Profile holds optional details about a user for consuming applications to
+// display. Empty fields are unset.
+type Profile struct {
+	DisplayName string `json:"display_name,omitempty"`
+	GivenName   string `json:"given_name,omitempty"`
+	FamilyName  string `json:"family_name,omitempty"`
+	Locale      string `json:"locale,omitempty"`   // BCP 47 language tag, e.g. de-CH
+	Timezone    string `json:"timezone,omitempty"` // IANA time zone, e.g. Europe/Zurich
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+// IsServiceAccount reports whether the user is a non-human service account
+func (u *User) IsServiceAccount() bool {
+	return u.Type == UserTypeService
+}
+
+// Status returns whether the account is deleted, disabled, locked, or active.
+// Lockouts last until an administrator unlocks the account.
+func (u *User) Status() string {
+	switch {
+	case u.DeletedAt != nil:
+		return UserStatusDeleted
+	case u.IsDisabled:
+		return UserStatusDisabled
+	case u.IsLocked:
+		return UserStatusLocked
+	default:
+		return UserStatusActive
+	}
+}
+
+// PasswordExpired reports whether the user must change their password at
+// now, because an administrator forced it or because the password is older
+// than maxAge (0 for no limit)
+func (u *User) PasswordExpired(maxAge time.Duration, now time.Time) bool {
+	if u.PasswordExpiresAt != nil && !now.Before(*u.PasswordExpiresAt) {
+		return true
+	}
+	return maxAge > 0 && !u.PasswordChangedAt.IsZero() && now.Sub(u.PasswordChangedAt) >= maxAge
+}
+
+// UserFilter selects users in admin searches. Zero fields match every user.
+type UserFilter struct {
+	TenantID      *int64
+	EmailPrefix   string
+	Role          string
+	Type          string
+	Locked        *bool
+	Disabled      *bool
+	Verified      *bool
+	Deleted       bool      // match soft-deleted users instead of live ones
+	CreatedAfter  time.Time // inclusive
+	CreatedBefore time.Time // exclusive
+	Page          pagination.Page
 }
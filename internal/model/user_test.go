@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserStatus(t *testing.T) {
+	deleted := time.Now()
+
+	tests := []struct {
+		name string
+		user User
+		want string
+	}{
+		{name: "active", user: User{}, want: UserStatusActive},
+		{name: "locked", user: User{IsLocked: true}, want: UserStatusLocked},
+		{name: "disabled outranks locked", user: User{IsLocked: true, IsDisabled: true}, want: UserStatusDisabled},
+		{name: "deleted outranks disabled", user: User{IsDisabled: true, DeletedAt: &deleted}, want: UserStatusDeleted},
+	}
+
+	for _, tt := range tests {
+		if got := tt.user.Status(); got != tt.want {
+			t.Errorf("%s: Status() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
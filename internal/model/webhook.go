@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// WebhookDelivery records one attempt to deliver an event to a webhook
+// endpoint
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"` // 0 when no response was received
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered"`
+	Created    time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryFilter selects deliveries in listings. Zero fields match
+// every delivery.
+type WebhookDeliveryFilter struct {
+	EventID    string
+	EventType  string
+	FailedOnly bool // only attempts that did not deliver the event
+	Page       pagination.Page
+}
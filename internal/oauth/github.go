@@ -0,0 +1,197 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Common errors that can be returned by OAuth providers
+var (
+	ErrExchangeFailed   = errors.New("failed to exchange authorization code")
+	ErrNoVerifiedEmail  = errors.New("no verified email address available from provider")
+	ErrProviderResponse = errors.New("unexpected response from provider")
+)
+
+// Identity is the subset of a provider's user profile needed to sign a user in
+type Identity struct {
+	Provider       string
+	ProviderUserID string
+	Email          string // verified by the provider
+}
+
+// Provider defines the operations a social login provider must support
+type Provider interface {
+	Name() string
+	AuthCodeURL(state string) string
+	Exchange(ctx context.Context, code string) (*Identity, error)
+}
+
+// GitHubProvider implements the OAuth web application flow against GitHub
+type GitHubProvider struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	httpClient   *http.Client
+
+	// Endpoints are fields so tests can point the provider at a fake server
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+}
+
+// Verify that GitHubProvider implements Provider interface
+var _ Provider = (*GitHubProvider)(nil)
+
+// NewGitHubProvider creates a GitHub provider for the given OAuth app credentials
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) *GitHubProvider {
+	return &GitHubProvider{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		APIURL:       "https://api.github.com",
+	}
+}
+
+// Name returns the provider identifier used in routes and stored identities
+func (p *GitHubProvider) Name() string {
+	return "github"
+}
+
+// AuthCodeURL returns the GitHub authorization URL the user is redirected to
+func (p *GitHubProvider) AuthCodeURL(state string) string {
+	params := url.Values{
+		"client_id":    {p.clientID},
+		"redirect_uri": {p.redirectURL},
+		"scope":        {"read:user user:email"},
+		"state":        {state},
+	}
+	return p.AuthURL + "?" + params.Encode()
+}
+
+// Exchange trades the callback code for an access token and resolves the user's identity
+func (p *GitHubProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	accessToken, err := p.exchangeCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := p.getJSON(ctx, accessToken, "/user", &profile); err != nil {
+		return nil, err
+	}
+	if profile.ID == 0 {
+		return nil, ErrProviderResponse
+	}
+
+	// The email on /user is only the public profile email and may be empty or
+	// unverified, so always resolve it from the emails endpoint instead
+	email, err := p.verifiedEmail(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:       p.Name(),
+		ProviderUserID: strconv.FormatInt(profile.ID, 10),
+		Email:          email,
+	}, nil
+}
+
+// exchangeCode requests an access token for the authorization code
+func (p *GitHubProvider) exchangeCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	// GitHub reports most token errors with a 200 status and an error field
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" || body.AccessToken == "" {
+		return "", fmt.Errorf("%w: %s", ErrExchangeFailed, body.Error)
+	}
+
+	return body.AccessToken, nil
+}
+
+// verifiedEmail returns the primary verified email, falling back to any verified email
+func (p *GitHubProvider) verifiedEmail(ctx context.Context, accessToken string) (string, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, accessToken, "/user/emails", &emails); err != nil {
+		return "", err
+	}
+
+	fallback := ""
+	for _, e := range emails {
+		if !e.Verified {
+			continue
+		}
+		if e.Primary {
+			return e.Email, nil
+		}
+		if fallback == "" {
+			fallback = e.Email
+		}
+	}
+	if fallback == "" {
+		return "", ErrNoVerifiedEmail
+	}
+	return fallback, nil
+}
+
+// getJSON performs an authenticated GET against the GitHub API and decodes the response
+func (p *GitHubProvider) getJSON(ctx context.Context, accessToken, path string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.APIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned status %d", ErrProviderResponse, path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
@@ -0,0 +1,104 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newFakeGitHub(t *testing.T, emails []map[string]any) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat", "email": nil})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(emails)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitHubProvider_Exchange(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		emails    []map[string]any
+		wantEmail string
+		wantErr   error
+	}{
+		{
+			name: "primary verified email",
+			code: "good-code",
+			emails: []map[string]any{
+				{"email": "other@example.com", "primary": false, "verified": true},
+				{"email": "octo@example.com", "primary": true, "verified": true},
+			},
+			wantEmail: "octo@example.com",
+		},
+		{
+			name: "unverified primary falls back to verified email",
+			code: "good-code",
+			emails: []map[string]any{
+				{"email": "octo@example.com", "primary": true, "verified": false},
+				{"email": "backup@example.com", "primary": false, "verified": true},
+			},
+			wantEmail: "backup@example.com",
+		},
+		{
+			name: "no verified email",
+			code: "good-code",
+			emails: []map[string]any{
+				{"email": "octo@example.com", "primary": true, "verified": false},
+			},
+			wantErr: ErrNoVerifiedEmail,
+		},
+		{
+			name:    "bad code",
+			code:    "bad-code",
+			wantErr: ErrExchangeFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeGitHub(t, tt.emails)
+			p := NewGitHubProvider("client", "secret", "http://localhost/callback")
+			p.TokenURL = srv.URL + "/login/oauth/access_token"
+			p.APIURL = srv.URL
+
+			identity, err := p.Exchange(context.Background(), tt.code)
+			if tt.wantErr != nil {
+				if err == nil || !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Email != tt.wantEmail {
+				t.Errorf("got email %q, want %q", identity.Email, tt.wantEmail)
+			}
+			if identity.ProviderUserID != "42" || identity.Provider != "github" {
+				t.Errorf("unexpected identity: %+v", identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// ErrInvalidKey is returned when a signing key file cannot be used
+var ErrInvalidKey = errors.New("invalid RSA signing key")
+
+// SigningKey is the RSA key used to sign ID tokens, published through JWKS
+type SigningKey struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+}
+
+// JWK is the JSON Web Key representation of a public key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadSigningKey reads a PEM encoded RSA private key (PKCS#1 or PKCS#8) from disk
+func LoadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed any
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key, ok = parsed.(*rsa.PrivateKey); !ok {
+				return nil, ErrInvalidKey
+			}
+		}
+	default:
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	return NewSigningKey(key), nil
+}
+
+// GenerateSigningKey creates an ephemeral key, suitable only for development
+// since tokens signed with it cannot be verified after a restart
+func GenerateSigningKey() (*SigningKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(key), nil
+}
+
+// NewSigningKey wraps an RSA key, deriving its key ID from the public key
+func NewSigningKey(key *rsa.PrivateKey) *SigningKey {
+	der := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	sum := sha256.Sum256(der)
+	return &SigningKey{
+		ID:         base64.RawURLEncoding.EncodeToString(sum[:8]),
+		PrivateKey: key,
+	}
+}
+
+// JWK returns the public half of the key in JWK format
+func (k *SigningKey) JWK() JWK {
+	pub := k.PrivateKey.PublicKey
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.ID,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// PublicKey decodes an RSA JWK into a public key
+func (j JWK) PublicKey() (*rsa.PublicKey, error) {
+	if j.Kty != "RSA" {
+		return nil, ErrInvalidKey
+	}
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, ErrInvalidKey
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
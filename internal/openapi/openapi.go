@@ -0,0 +1,224 @@
+// Package openapi builds OpenAPI 3.0 documents from route descriptions,
+// deriving the JSON schemas of request and response bodies from the Go types
+// handlers encode and decode.
+package openapi
+
+import (
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Document is an OpenAPI 3.0 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// componentTypes holds the Go type of each named schema
+	componentTypes map[string]reflect.Type
+	// errorResponse is the default response of operations added from now on
+	errorResponse *Response
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations of a path by lowercase method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Route describes an operation for Document.Add
+type Route struct {
+	Method  string
+	Path    string // with chi-style {name} parameters
+	Tag     string
+	Summary string
+	// Security lists the schemes of which any one authenticates the request;
+	// none means the route is public
+	Security []string
+	// Query lists the names of optional query parameters
+	Query []string
+	// Request is a value of the type of the JSON request body, if any
+	Request any
+	// Form lists the fields of a form-encoded request body
+	Form []string
+	// Status is the status of a successful response, 200 by default
+	Status int
+	// Response is a value of the type of the JSON response body. Maps with
+	// entries are described by their keys and the types of their values, so
+	// a handler's map literal can be mirrored with zero values.
+	Response any
+	// ContentType is the media type of a response that is not JSON
+	ContentType string
+}
+
+// New returns an empty document
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{},
+		},
+	}
+}
+
+// AddSecurityScheme declares a scheme that routes can list in Security
+func (d *Document) AddSecurityScheme(name string, scheme *SecurityScheme) {
+	d.Components.SecuritySchemes[name] = scheme
+}
+
+// SetErrorResponse declares body, encoded as contentType, the response to
+// every failed request of the operations added after it
+func (d *Document) SetErrorResponse(contentType string, body any) {
+	d.errorResponse = &Response{Description: "Error", Content: map[string]MediaType{
+		contentType: {Schema: d.SchemaOf(body)},
+	}}
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Add adds operations for routes
+func (d *Document) Add(routes ...Route) {
+	for _, route := range routes {
+		op := &Operation{Summary: route.Summary, Responses: map[string]*Response{}}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			schema := &Schema{Type: "string"}
+			if m[1] == "id" {
+				schema = &Schema{Type: "integer", Format: "int64"}
+			}
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+		}
+		for _, name := range route.Query {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+		}
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/json": {Schema: d.SchemaOf(route.Request)},
+			}}
+		}
+		if len(route.Form) > 0 {
+			form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			for _, name := range route.Form {
+				form.Properties[name] = &Schema{Type: "string"}
+			}
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/x-www-form-urlencoded": {Schema: form},
+			}}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := &Response{Description: http.StatusText(status)}
+		switch {
+		case route.ContentType != "":
+			resp.Content = map[string]MediaType{route.ContentType: {}}
+		case route.Response != nil:
+			resp.Content = map[string]MediaType{"application/json": {Schema: d.SchemaOf(route.Response)}}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+		if d.errorResponse != nil {
+			op.Responses["default"] = d.errorResponse
+		}
+		for _, scheme := range route.Security {
+			op.Security = append(op.Security, map[string][]string{scheme: {}})
+		}
+
+		item := d.Paths[route.Path]
+		if item == nil {
+			item = &PathItem{}
+			d.Paths[route.Path] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = op
+	}
+}
+
+// Operations returns the documented operations as "METHOD /path", sorted
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, item := range d.Paths {
+		for method := range *item {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	slices.Sort(ops)
+	return ops
+}
+
+// Filter returns a copy of the document with only the operations keep
+// accepts. Schemas no operation refers to any more are kept.
+func (d *Document) Filter(keep func(method, path string) bool) *Document {
+	filtered := *d
+	filtered.Paths = map[string]*PathItem{}
+	for path, item := range d.Paths {
+		kept := PathItem{}
+		for method, op := range *item {
+			if keep(strings.ToUpper(method), path) {
+				kept[method] = op
+			}
+		}
+		if len(kept) > 0 {
+			filtered.Paths[path] = &kept
+		}
+	}
+	filtered.Components.Schemas = maps.Clone(d.Components.Schemas)
+	return &filtered
+}
@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI schema object that Go types map to
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// SchemaOf returns the schema of the JSON encoding of v. Named struct types
+// are added to the components and referred to. Struct fields are required
+// when tagged validate:"required".
+func (d *Document) SchemaOf(v any) *Schema {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String && value.Len() > 0 {
+		return d.mapSchema(value)
+	}
+	return d.typeSchema(value.Type())
+}
+
+// mapSchema describes a map literal by its keys and the types of its values
+func (d *Document) mapSchema(value reflect.Value) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	iter := value.MapRange()
+	for iter.Next() {
+		elem := iter.Value()
+		if elem.Kind() == reflect.Interface {
+			elem = elem.Elem()
+		}
+		if !elem.IsValid() {
+			s.Properties[iter.Key().String()] = &Schema{Nullable: true}
+			continue
+		}
+		s.Properties[iter.Key().String()] = d.SchemaOf(elem.Interface())
+	}
+	return s
+}
+
+func (d *Document) typeSchema(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := *d.typeSchema(t.Elem())
+		if s.Ref != "" {
+			// Siblings of $ref are ignored in OpenAPI 3.0
+			return &s
+		}
+		s.Nullable = true
+		return &s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+			return &Schema{}
+		}
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := d.componentName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Registered before the fields so recursive types end in a reference
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// Interfaces and anything else can hold any value
+	return &Schema{}
+}
+
+// componentName names the schema of a struct type after it, qualified with
+// its package when another package has a type of the same name
+func (d *Document) componentName(t reflect.Type) string {
+	name := t.Name()
+	if d.componentTypes == nil {
+		d.componentTypes = map[string]reflect.Type{}
+	}
+	if other, ok := d.componentTypes[name]; ok && other != t {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+	d.componentTypes[name] = t
+	return name
+}
+
+// structSchema describes the exported fields of a struct, with the fields of
+// embedded structs inlined as encoding/json does
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for field := range fields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = d.typeSchema(field.Type)
+		if slices.Contains(strings.Split(field.Tag.Get("validate"), ","), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// fields yields the encoded fields of t, descending into embedded structs
+func fields(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		var walk func(t reflect.Type) bool
+		walk = func(t reflect.Type) bool {
+			for i := range t.NumField() {
+				field := t.Field(i)
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+					if !walk(field.Type) {
+						return false
+					}
+					continue
+				}
+				if !field.IsExported() {
+					continue
+				}
+				if !yield(field) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(t)
+	}
+}
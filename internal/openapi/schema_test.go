@@ -0,0 +1,83 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID int64 `json:"id"`
+}
+
+type testNode struct {
+	testBase
+	Name     string            `json:"name" validate:"required,max=10"`
+	Parent   *testNode         `json:"parent,omitempty"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Created  time.Time         `json:"created_at"`
+	Deleted  *time.Time        `json:"deleted_at"`
+	Secret   string            `json:"-"`
+	internal bool
+}
+
+func TestSchemaOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "scalar", value: int64(0), want: `{"type":"integer","format":"int64"}`},
+		{name: "named struct", value: testNode{}, want: `{"$ref":"#/components/schemas/testNode"}`},
+		{name: "slice of pointers", value: []*testNode{}, want: `{"type":"array","items":{"$ref":"#/components/schemas/testNode"}}`},
+		{name: "map literal", value: map[string]any{"nodes": []testNode{}, "next": "", "missing": nil},
+			want: `{"type":"object","properties":{"missing":{"nullable":true},"next":{"type":"string"},"nodes":{"type":"array","items":{"$ref":"#/components/schemas/testNode"}}}}`},
+		{name: "empty map", value: map[string]int{}, want: `{"type":"object","additionalProperties":{"type":"integer"}}`},
+		{name: "anonymous struct", value: struct {
+			OK bool `json:"ok"`
+		}{}, want: `{"type":"object","properties":{"ok":{"type":"boolean"}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := json.Marshal(New("test", "1").SchemaOf(tt.value))
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	doc := New("test", "1")
+	doc.SchemaOf(testNode{})
+	got, _ := json.Marshal(doc.Components.Schemas["testNode"])
+	want := `{"type":"object","properties":{"created_at":{"type":"string","format":"date-time"},` +
+		`"deleted_at":{"type":"string","format":"date-time","nullable":true},"id":{"type":"integer","format":"int64"},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},"name":{"type":"string"},` +
+		`"parent":{"$ref":"#/components/schemas/testNode"},"tags":{"type":"array","items":{"type":"string"}}},"required":["name"]}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestDocumentFilter(t *testing.T) {
+	doc := New("test", "1")
+	doc.SetErrorResponse("application/problem+json", map[string]string{"detail": ""})
+	doc.Add(
+		Route{Method: "GET", Path: "/items/{id}", Response: testNode{}},
+		Route{Method: "DELETE", Path: "/items/{id}"},
+		Route{Method: "POST", Path: "/items", Request: testNode{}, Status: 201},
+	)
+	filtered := doc.Filter(func(method, path string) bool { return method == "GET" })
+
+	got, want := filtered.Operations(), []string{"GET /items/{id}"}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got operations %v, want %v", got, want)
+	}
+	if len(doc.Operations()) != 3 {
+		t.Errorf("filtering changed the original document: %v", doc.Operations())
+	}
+	op := (*filtered.Paths["/items/{id}"])["get"]
+	if len(op.Parameters) != 1 || op.Parameters[0].Schema.Type != "integer" || op.Responses["default"] == nil {
+		t.Errorf("got operation %+v, want an integer id parameter and an error response", op)
+	}
+}
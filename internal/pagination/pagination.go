@@ -0,0 +1,109 @@
+// Package pagination implements keyset pagination for listings ordered by a
+// unique int64 key, such as a serial primary key. Clients page through a
+// listing by passing back the opaque next_cursor of the previous page, so
+// pages stay stable while rows are inserted or deleted.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Page sizes of listings requested through FromQuery
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var (
+	// ErrInvalidCursor is returned for a cursor that was not issued by Encode
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidLimit is returned for a limit that is not a positive number
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+// Page selects one page of a listing
+type Page struct {
+	Cursor string // next_cursor of the previous page; empty for the first page
+	Limit  int
+}
+
+// After returns the key of the last item of the previous page, or 0 for the first page
+func (p Page) After() (int64, error) {
+	if p.Cursor == "" {
+		return 0, nil
+	}
+	return Decode(p.Cursor)
+}
+
+// Fetch is the number of rows to query: one more than the limit, to learn
+// whether another page follows
+func (p Page) Fetch() int {
+	return p.Limit + 1
+}
+
+// Trim cuts items fetched with Fetch down to the page and returns the cursor
+// of the next page, or "" when this is the last one
+func Trim[T any](items []T, p Page, key func(T) int64) ([]T, string) {
+	if len(items) <= p.Limit {
+		return items, ""
+	}
+	items = items[:p.Limit]
+	return items, Encode(key(items[len(items)-1]))
+}
+
+// Slice pages through items already in listing order, for in-memory stores.
+// desc reports whether keys are in descending order.
+func Slice[T any](items []T, p Page, desc bool, key func(T) int64) ([]T, string, error) {
+	after, err := p.After()
+	if err != nil {
+		return nil, "", err
+	}
+	start := 0
+	for after != 0 && start < len(items) {
+		if k := key(items[start]); (!desc && k > after) || (desc && k < after) {
+			break
+		}
+		start++
+	}
+	items = items[start:]
+	page, next := Trim(items[:min(p.Fetch(), len(items))], p, key)
+	return page, next, nil
+}
+
+// Encode returns the cursor for the page after the item with the given key
+func Encode(key int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(key, 10)))
+}
+
+// Decode returns the key a cursor was encoded from
+func Decode(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	key, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || key <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return key, nil
+}
+
+// FromQuery reads the cursor and limit query parameters. The limit defaults
+// to DefaultLimit and is capped at MaxLimit.
+func FromQuery(q url.Values) (Page, error) {
+	p := Page{Cursor: q.Get("cursor"), Limit: DefaultLimit}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return p, ErrInvalidLimit
+		}
+		p.Limit = min(limit, MaxLimit)
+	}
+	if _, err := p.After(); err != nil {
+		return p, err
+	}
+	return p, nil
+}
@@ -0,0 +1,74 @@
+package pagination
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func identity(k int64) int64 { return k }
+
+func TestSlice(t *testing.T) {
+	asc := []int64{1, 2, 3, 5, 8}
+	desc := []int64{8, 5, 3, 2, 1}
+
+	tests := []struct {
+		name     string
+		items    []int64
+		desc     bool
+		want     [][]int64
+		pageSize int
+	}{
+		{name: "ascending", items: asc, pageSize: 2, want: [][]int64{{1, 2}, {3, 5}, {8}}},
+		{name: "descending", items: desc, desc: true, pageSize: 2, want: [][]int64{{8, 5}, {3, 2}, {1}}},
+		{name: "exact page", items: asc, pageSize: 5, want: [][]int64{asc}},
+		{name: "empty", items: nil, pageSize: 3, want: [][]int64{nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := Page{Limit: tt.pageSize}
+			for i, want := range tt.want {
+				got, next, err := Slice(tt.items, page, tt.desc, identity)
+				if err != nil {
+					t.Fatalf("page %d: unexpected error: %v", i, err)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("page %d: got %v, want %v", i, got, want)
+				}
+				if last := i == len(tt.want)-1; last != (next == "") {
+					t.Fatalf("page %d: got next cursor %q, last page %v", i, next, last)
+				}
+				page.Cursor = next
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Page
+		wantErr error
+	}{
+		{query: "", want: Page{Limit: DefaultLimit}},
+		{query: "limit=10&cursor=" + Encode(42), want: Page{Limit: 10, Cursor: Encode(42)}},
+		{query: "limit=1000", want: Page{Limit: MaxLimit}},
+		{query: "limit=0", wantErr: ErrInvalidLimit},
+		{query: "cursor=not-a-cursor", wantErr: ErrInvalidCursor},
+		{query: "cursor=" + Encode(-1), wantErr: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := FromQuery(q)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
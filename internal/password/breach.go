@@ -0,0 +1,80 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ViolationBreached is reported for passwords found in a data breach
+const ViolationBreached = "breached_password"
+
+// BreachChecker reports whether a password has appeared in a data breach
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// PwnedPasswords checks passwords against the Have I Been Pwned range API
+// with k-anonymity: only the first five hex characters of the password's
+// SHA-1 hash are sent, and the match is made locally among the hundreds of
+// suffixes returned.
+type PwnedPasswords struct {
+	httpClient *http.Client
+
+	// RangeURL is a field so tests can point the checker at a fake server
+	RangeURL string
+}
+
+// Verify that PwnedPasswords implements BreachChecker interface
+var _ BreachChecker = (*PwnedPasswords)(nil)
+
+// NewPwnedPasswords creates a checker whose lookups give up after timeout
+func NewPwnedPasswords(timeout time.Duration) *PwnedPasswords {
+	return &PwnedPasswords{
+		httpClient: &http.Client{Timeout: timeout},
+		RangeURL:   "https://api.pwnedpasswords.com/range/",
+	}
+}
+
+// Breached looks up the SHA-1 hash of password
+func (p *PwnedPasswords) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.RangeURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the real number of suffixes from anyone watching response sizes
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("pwned passwords unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords returned %s", resp.Status)
+	}
+
+	// Each line is SUFFIX:COUNT; padding lines have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if candidate == suffix {
+			n, err := strconv.Atoi(count)
+			return err == nil && n > 0, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("pwned passwords: %v", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,58 @@
+package password
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPwnedPasswords(t *testing.T) {
+	// suffix returns the part of a password's SHA-1 hash that is never sent
+	suffix := func(password string) string {
+		sum := sha1.Sum([]byte(password))
+		return strings.ToUpper(hex.EncodeToString(sum[:]))[5:]
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		status   int
+		body     string
+		want     bool
+		wantErr  bool
+	}{
+		{name: "breached", password: "password", status: http.StatusOK, body: "003D68EB55068C33ACE09247EE4C639306B:3\r\n" + suffix("password") + ":9659365\r\n", want: true},
+		{name: "padding entry", password: "password", status: http.StatusOK, body: suffix("password") + ":0\r\n"},
+		{name: "not breached", password: "Tr0ub4dor&3-kestrel", status: http.StatusOK, body: "003D68EB55068C33ACE09247EE4C639306B:3\r\n"},
+		{name: "outage", password: "password", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sum := sha1.Sum([]byte(tt.password))
+				if want := "/range/" + strings.ToUpper(hex.EncodeToString(sum[:]))[:5]; r.URL.Path != want {
+					t.Errorf("requested %s, want %s", r.URL.Path, want)
+				}
+				if r.Header.Get("Add-Padding") != "true" {
+					t.Error("padding not requested")
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			checker := NewPwnedPasswords(time.Second)
+			checker.RangeURL = server.URL + "/range/"
+			got, err := checker.Breached(context.Background(), tt.password)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("Breached() = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Package password hashes and verifies user passwords. New hashes use
+// Argon2id, or bcrypt when configured; hashes of either kind verify, and
+// Verify reports when a hash should be replaced so users migrate as they
+// sign in.
+package password
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatch    = errors.New("password does not match")
+	ErrUnknownHash = errors.New("unrecognized password hash")
+	ErrInvalidHash = errors.New("malformed password hash")
+)
+
+// Argon2Params are the Argon2id cost parameters
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Time        uint32 // passes over memory
+	Parallelism uint8  // lanes
+}
+
+// DefaultArgon2Params are the second recommended option of RFC 9106 (64 MiB,
+// three passes) with four lanes
+var DefaultArgon2Params = Argon2Params{Memory: 64 * 1024, Time: 3, Parallelism: 4}
+
+// Validate rejects parameters too weak to be useful or too large for argon2
+func (p Argon2Params) Validate() error {
+	switch {
+	case p.Parallelism == 0:
+		return errors.New("argon2 parallelism must be at least 1")
+	case p.Time == 0:
+		return errors.New("argon2 time must be at least 1")
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("argon2 memory must be at least %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+	}
+	return nil
+}
+
+const (
+	argon2Prefix  = "$argon2id$"
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// DefaultBcryptCost is the bcrypt cost used by WithBcrypt for non-positive costs
+const DefaultBcryptCost = 12
+
+// Hasher creates and verifies password hashes. Hashing is deliberately slow,
+// so at most a fixed number of hashes run at once and further callers queue:
+// a burst of sign-ins then waits its turn instead of starving every other
+// goroutine of CPU.
+type Hasher struct {
+	params     Argon2Params
+	bcryptCost int               // new hashes use bcrypt when positive
+	pepperID   string            // pepper of new hashes; empty for none
+	peppers    map[string][]byte // by ID
+
+	slots  chan struct{} // one per running hash
+	queued atomic.Int64
+}
+
+// HasherOption configures a Hasher
+type HasherOption func(*Hasher)
+
+// WithBcrypt creates bcrypt hashes of the given cost instead of Argon2id
+// hashes. Argon2id hashes still verify and are replaced on sign-in.
+func WithBcrypt(cost int) HasherOption {
+	return func(h *Hasher) {
+		if cost <= 0 {
+			cost = DefaultBcryptCost
+		}
+		h.bcryptCost = cost
+	}
+}
+
+// WithWorkers runs at most n hashes at once (default GOMAXPROCS)
+func WithWorkers(n int) HasherOption {
+	return func(h *Hasher) {
+		if n > 0 {
+			h.slots = make(chan struct{}, n)
+		}
+	}
+}
+
+// NewHasher hashes new passwords with Argon2id and params
+func NewHasher(params Argon2Params, opts ...HasherOption) *Hasher {
+	h := &Hasher{params: params, slots: make(chan struct{}, runtime.GOMAXPROCS(0))}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Default hashes with DefaultArgon2Params
+var Default = NewHasher(DefaultArgon2Params)
+
+// Queued returns how many callers are waiting for a hashing worker
+func (h *Hasher) Queued() int {
+	return int(h.queued.Load())
+}
+
+// Busy returns how many hashes are running
+func (h *Hasher) Busy() int {
+	return len(h.slots)
+}
+
+// Workers returns how many hashes may run at once
+func (h *Hasher) Workers() int {
+	return cap(h.slots)
+}
+
+// acquire waits for a free worker, or until ctx is done
+func (h *Hasher) acquire(ctx context.Context) error {
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	h.queued.Add(1)
+	defer h.queued.Add(-1)
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the worker taken by acquire
+func (h *Hasher) release() {
+	<-h.slots
+}
+
+// Hash returns an Argon2id hash of password in the PHC string format, such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>, or a bcrypt hash when
+// configured with WithBcrypt. With WithPepper, the hash is of the peppered
+// password and prefixed with the pepper ID. It waits for a free worker, or
+// until ctx is done.
+func (h *Hasher) Hash(ctx context.Context, password string) (string, error) {
+	if err := h.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer h.release()
+
+	if h.pepperID == "" {
+		return h.hash(password)
+	}
+	key, ok := h.peppers[h.pepperID]
+	if !ok {
+		return "", ErrUnknownPepper
+	}
+	hash, err := h.hash(pepper(key, password))
+	if err != nil {
+		return "", err
+	}
+	return pepperPrefix + h.pepperID + hash, nil
+}
+
+// hash hashes password with the configured algorithm
+func (h *Hasher) hash(password string) (string, error) {
+	if h.bcryptCost > 0 {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		return string(hash), err
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Time, h.params.Memory, h.params.Parallelism, argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		h.params.Memory, h.params.Time, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks password against hash, choosing the algorithm by the hash
+// prefix. It returns ErrMismatch for a wrong password. rehash reports that
+// the password matched a hash of another algorithm, cost, or pepper than h
+// creates, and should be hashed again with Hash. Like Hash, it waits for a
+// free worker.
+func (h *Hasher) Verify(ctx context.Context, hash, password string) (rehash bool, err error) {
+	if err := h.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer h.release()
+
+	id, hash := splitPepper(hash)
+	if id != "" {
+		key, ok := h.peppers[id]
+		if !ok {
+			return false, ErrUnknownPepper
+		}
+		password = pepper(key, password)
+	}
+	rehash, err = h.verify(hash, password)
+	return err == nil && (rehash || id != h.pepperID), err
+}
+
+// verify checks password against a hash without a pepper prefix
+func (h *Hasher) verify(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, argon2Prefix):
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, err
+		}
+		got := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, ErrMismatch
+		}
+		return h.bcryptCost > 0 || params != h.params || len(key) != argon2KeyLen, nil
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		switch err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err {
+		case nil:
+			cost, _ := bcrypt.Cost([]byte(hash))
+			return cost != h.bcryptCost, nil
+		case bcrypt.ErrMismatchedHashAndPassword:
+			return false, ErrMismatch
+		default:
+			return false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+// IsBcryptHash reports whether hash is a well-formed bcrypt hash, such as
+// one imported from another system, which Verify accepts
+func IsBcryptHash(hash string) bool {
+	if len(hash) != 60 || !(strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")) {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// decodeArgon2 parses an Argon2id hash in the PHC string format
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	parts := strings.Split(hash, "$") // "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Parallelism); err != nil || params.Validate() != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,134 @@
+package password
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast
+var testParams = Argon2Params{Memory: 64, Time: 1, Parallelism: 1}
+
+func TestHasherVerify(t *testing.T) {
+	ctx := context.Background()
+	hasher := NewHasher(testParams)
+	current, err := hasher.Hash(ctx, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(current, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("Hash() = %q", current)
+	}
+	weaker, _ := NewHasher(Argon2Params{Memory: 32, Time: 1, Parallelism: 1}).Hash(ctx, "correct horse")
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	bcryptHasher := NewHasher(testParams, WithBcrypt(bcrypt.MinCost))
+
+	tests := []struct {
+		name       string
+		hasher     *Hasher
+		hash       string
+		password   string
+		wantRehash bool
+		wantErr    error
+	}{
+		{name: "argon2id", hasher: hasher, hash: current, password: "correct horse"},
+		{name: "argon2id wrong password", hasher: hasher, hash: current, password: "battery staple", wantErr: ErrMismatch},
+		{name: "argon2id with other parameters", hasher: hasher, hash: weaker, password: "correct horse", wantRehash: true},
+		{name: "bcrypt", hasher: hasher, hash: string(legacy), password: "correct horse", wantRehash: true},
+		{name: "bcrypt wrong password", hasher: hasher, hash: string(legacy), password: "battery staple", wantErr: ErrMismatch},
+		{name: "bcrypt configured", hasher: bcryptHasher, hash: string(legacy), password: "correct horse"},
+		{name: "bcrypt configured with another cost", hasher: NewHasher(testParams, WithBcrypt(bcrypt.MinCost+1)), hash: string(legacy), password: "correct horse", wantRehash: true},
+		{name: "argon2id when bcrypt is configured", hasher: bcryptHasher, hash: current, password: "correct horse", wantRehash: true},
+		{name: "service account", hasher: hasher, hash: "!", password: "", wantErr: ErrUnknownHash},
+		{name: "malformed argon2id", hasher: hasher, hash: "$argon2id$v=19$m=64,t=1$c2FsdA$a2V5", password: "correct horse", wantErr: ErrInvalidHash},
+		{name: "unsupported argon2 version", hasher: hasher, hash: strings.Replace(current, "v=19", "v=16", 1), password: "correct horse", wantErr: ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rehash, err := tt.hasher.Verify(ctx, tt.hash, tt.password)
+			if err != tt.wantErr || rehash != tt.wantRehash {
+				t.Errorf("Verify() = %v, %v, want %v, %v", rehash, err, tt.wantRehash, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasherWorkers(t *testing.T) {
+	hasher := NewHasher(testParams, WithWorkers(1))
+	if err := hasher.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// With the only worker busy, hashing queues until ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := hasher.Hash(ctx, "correct horse")
+		done <- err
+	}()
+	for hasher.Queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if hasher.Busy() != 1 || hasher.Workers() != 1 {
+		t.Errorf("Busy() = %d, Workers() = %d, want 1 and 1", hasher.Busy(), hasher.Workers())
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Hash() while queued error = %v, want %v", err, context.Canceled)
+	}
+	if hasher.Queued() != 0 {
+		t.Errorf("Queued() = %d after the caller gave up", hasher.Queued())
+	}
+
+	// Once the worker is free, hashing proceeds
+	hasher.release()
+	if _, err := hasher.Hash(context.Background(), "correct horse"); err != nil || hasher.Busy() != 0 {
+		t.Errorf("Hash() error = %v, Busy() = %d", err, hasher.Busy())
+	}
+}
+
+func TestArgon2ParamsValidate(t *testing.T) {
+	tests := []struct {
+		params  Argon2Params
+		wantErr bool
+	}{
+		{params: DefaultArgon2Params},
+		{params: Argon2Params{Memory: 64, Time: 1, Parallelism: 8}},
+		{params: Argon2Params{Memory: 63, Time: 1, Parallelism: 8}, wantErr: true},
+		{params: Argon2Params{Memory: 65536, Time: 0, Parallelism: 1}, wantErr: true},
+		{params: Argon2Params{Memory: 65536, Time: 1, Parallelism: 0}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.params, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIsBcryptHash(t *testing.T) {
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	current, _ := NewHasher(testParams).Hash(context.Background(), "correct horse")
+
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{hash: string(legacy), want: true},
+		{hash: strings.Replace(string(legacy), "$2a$", "$2y$", 1), want: true},
+		{hash: string(legacy[:59])},
+		{hash: strings.Replace(string(legacy), "$04$", "$99$", 1)},
+		{hash: current},
+		{hash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+	}
+
+	for _, tt := range tests {
+		if got := IsBcryptHash(tt.hash); got != tt.want {
+			t.Errorf("IsBcryptHash(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
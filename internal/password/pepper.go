@@ -0,0 +1,68 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrUnknownPepper is returned for a hash made with a pepper the Hasher does
+// not have, e.g. one removed from the configuration too early
+var ErrUnknownPepper = errors.New("password hash uses an unknown pepper")
+
+const pepperPrefix = "$pepper$"
+
+// MinPepperLen is the shortest pepper WithPepper should be given, in bytes
+const MinPepperLen = 32
+
+// WithPepper mixes a secret pepper into every password before it is hashed,
+// so a leaked database alone is not enough to crack the hashes. Peppers are
+// keyed by an ID stored with each hash, such as
+// $pepper$2$argon2id$v=19$...; new hashes use peppers[current]. To rotate,
+// add a pepper under a new ID and make it current: hashes made with the
+// others keep verifying and are replaced on sign-in, like outdated hashes.
+// Hashes made before any pepper was configured are upgraded the same way.
+func WithPepper(current string, peppers map[string][]byte) HasherOption {
+	return func(h *Hasher) {
+		h.pepperID = current
+		h.peppers = peppers
+	}
+}
+
+// ValidPepperID reports whether id can be stored with a hash: 1 to 32
+// letters, digits, - and _, so the hash still fits in 255 characters
+func ValidPepperID(id string) bool {
+	if id == "" || len(id) > 32 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// pepper returns the HMAC-SHA256 of password keyed with the pepper, encoded
+// so bcrypt, which stops at a NUL byte, sees all of it
+func pepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// splitPepper returns the pepper ID of a peppered hash and the hash of the
+// peppered password, or no ID and the hash unchanged
+func splitPepper(hash string) (string, string) {
+	rest, ok := strings.CutPrefix(hash, pepperPrefix)
+	if !ok {
+		return "", hash
+	}
+	id, inner, ok := strings.Cut(rest, "$")
+	if !ok {
+		return "", hash
+	}
+	return id, "$" + inner
+}
@@ -0,0 +1,63 @@
+package password
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHasherPepper(t *testing.T) {
+	ctx := context.Background()
+	peppers := map[string][]byte{
+		"1": bytes.Repeat([]byte{1}, MinPepperLen),
+		"2": bytes.Repeat([]byte{2}, MinPepperLen),
+	}
+	plain := NewHasher(testParams)
+	first := NewHasher(testParams, WithPepper("1", peppers))
+	rotated := NewHasher(testParams, WithPepper("2", peppers))
+	bcryptPeppered := NewHasher(testParams, WithBcrypt(bcrypt.MinCost), WithPepper("2", peppers))
+
+	unpeppered, _ := plain.Hash(ctx, "correct horse")
+	old, err := first.Hash(ctx, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(old, "$pepper$1$argon2id$v=19$") {
+		t.Fatalf("Hash() = %q, want the pepper ID before the argon2id hash", old)
+	}
+	current, _ := rotated.Hash(ctx, "correct horse")
+	bcryptHash, _ := bcryptPeppered.Hash(ctx, "correct horse")
+	if !strings.HasPrefix(bcryptHash, "$pepper$2$2a$") {
+		t.Fatalf("Hash() with bcrypt = %q", bcryptHash)
+	}
+
+	tests := []struct {
+		name       string
+		hasher     *Hasher
+		hash       string
+		password   string
+		wantRehash bool
+		wantErr    error
+	}{
+		{name: "current pepper", hasher: rotated, hash: current, password: "correct horse"},
+		{name: "wrong password", hasher: rotated, hash: current, password: "battery staple", wantErr: ErrMismatch},
+		{name: "previous pepper", hasher: rotated, hash: old, password: "correct horse", wantRehash: true},
+		{name: "previous pepper wrong password", hasher: rotated, hash: old, password: "battery staple", wantErr: ErrMismatch},
+		{name: "no pepper yet", hasher: rotated, hash: unpeppered, password: "correct horse", wantRehash: true},
+		{name: "bcrypt", hasher: bcryptPeppered, hash: bcryptHash, password: "correct horse"},
+		{name: "pepper removed", hasher: plain, hash: current, password: "correct horse", wantErr: ErrUnknownPepper},
+		{name: "pepper hash is not the plain hash", hasher: plain, hash: strings.TrimPrefix(current, "$pepper$2"), password: "correct horse", wantErr: ErrMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rehash, err := tt.hasher.Verify(ctx, tt.hash, tt.password)
+			if err != tt.wantErr || rehash != tt.wantRehash {
+				t.Errorf("Verify() = %v, %v, want %v, %v", rehash, err, tt.wantRehash, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package password
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy decides which passwords users may choose. The zero value accepts
+// any password.
+type Policy struct {
+	MinLength int // characters
+	MaxLength int // characters, 0 for no limit
+	MaxBytes  int // 0 for no limit; bcrypt only hashes the first 72 bytes
+
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool // anything but a letter, digit or space
+
+	// Passwords that are rejected whatever their strength, lowercased
+	DenyList map[string]bool
+
+	// Reject passwords containing the user's email or its local part
+	DisallowEmail bool
+}
+
+// DefaultPolicy only bounds the length
+var DefaultPolicy = Policy{MinLength: 8, MaxLength: 128}
+
+// Violation codes, stable for clients to branch on
+const (
+	ViolationTooShort      = "too_short"
+	ViolationTooLong       = "too_long"
+	ViolationMissingUpper  = "missing_uppercase"
+	ViolationMissingLower  = "missing_lowercase"
+	ViolationMissingDigit  = "missing_digit"
+	ViolationMissingSymbol = "missing_symbol"
+	ViolationCommon        = "common_password"
+	ViolationContainsEmail = "contains_email"
+	ViolationReused        = "reused_password" // when changing to the current password
+)
+
+// Violation is one rule of a Policy a password breaks
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Violations lists every rule of a Policy a password breaks
+type Violations []Violation
+
+func (v Violations) Error() string {
+	messages := make([]string, len(v))
+	for i, violation := range v {
+		messages[i] = violation.Message
+	}
+	return "password " + strings.Join(messages, "; ")
+}
+
+// minEmailPartLength keeps short local parts such as "jo" from rejecting
+// every password containing them
+const minEmailPartLength = 3
+
+// Check returns Violations listing every rule password breaks for the user
+// with the given email, or nil when it is acceptable
+func (p Policy) Check(password, email string) error {
+	var v Violations
+	add := func(code, format string, args ...any) {
+		v = append(v, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	switch {
+	case length < p.MinLength:
+		add(ViolationTooShort, "must be at least %d characters", p.MinLength)
+	case p.MaxLength > 0 && length > p.MaxLength:
+		add(ViolationTooLong, "must be at most %d characters", p.MaxLength)
+	case p.MaxBytes > 0 && len(password) > p.MaxBytes:
+		add(ViolationTooLong, "must be at most %d bytes", p.MaxBytes)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		add(ViolationMissingUpper, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		add(ViolationMissingLower, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		add(ViolationMissingDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		add(ViolationMissingSymbol, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if p.DenyList[lowered] {
+		add(ViolationCommon, "is too common")
+	}
+	if p.DisallowEmail && email != "" {
+		email = strings.ToLower(email)
+		local, _, _ := strings.Cut(email, "@")
+		if strings.Contains(lowered, email) || (len(local) >= minEmailPartLength && strings.Contains(lowered, local)) {
+			add(ViolationContainsEmail, "must not contain your email address")
+		}
+	}
+
+	if len(v) > 0 {
+		return v
+	}
+	return nil
+}
+
+// generatedLength is the length of generated passwords unless the policy
+// demands otherwise
+const generatedLength = 24
+
+// Generate returns a random password for the user with the given email that
+// satisfies the policy, for temporary passwords handed out by administrators
+func (p Policy) Generate(email string) (string, error) {
+	length := max(generatedLength, p.MinLength)
+	if p.MaxLength > 0 {
+		length = min(length, p.MaxLength)
+	}
+	if p.MaxBytes > 0 {
+		length = min(length, p.MaxBytes)
+	}
+
+	// Base64url mixes both cases, digits and the symbols - and _, so a few
+	// attempts find a password with every required class
+	buf := make([]byte, length)
+	for range 100 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		password := base64.RawURLEncoding.EncodeToString(buf)[:length]
+		if p.Check(password, email) == nil {
+			return password, nil
+		}
+	}
+	return "", errors.New("password: policy cannot be satisfied by a generated password")
+}
+
+//go:embed common.txt
+var commonPasswords string
+
+// CommonPasswords returns a deny list of the passwords seen most often in
+// breaches, for Policy.DenyList
+var CommonPasswords = sync.OnceValue(func() map[string]bool {
+	list, _ := ReadDenyList(strings.NewReader(commonPasswords))
+	return list
+})
+
+// ReadDenyList reads a deny list with one password per line, skipping blank
+// lines and lines starting with #, and lowercases the entries
+func ReadDenyList(r io.Reader) (map[string]bool, error) {
+	list := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			list[strings.ToLower(line)] = true
+		}
+	}
+	return list, scanner.Err()
+}
@@ -0,0 +1,83 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	strict := Policy{
+		MinLength:     10,
+		MaxLength:     64,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		DenyList:      CommonPasswords(),
+		DisallowEmail: true,
+	}
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		password  string
+		email     string
+		wantCodes []string
+	}{
+		{name: "default accepts eight characters", policy: DefaultPolicy, password: "password"},
+		{name: "default rejects seven characters", policy: DefaultPolicy, password: "passwor", wantCodes: []string{ViolationTooShort}},
+		{name: "length counts characters", policy: DefaultPolicy, password: "pässwörd"},
+		{name: "too long", policy: DefaultPolicy, password: strings.Repeat("a", 129), wantCodes: []string{ViolationTooLong}},
+		{name: "too many bytes", policy: Policy{MaxBytes: 72}, password: strings.Repeat("ü", 37), wantCodes: []string{ViolationTooLong}},
+		{name: "strong", policy: strict, password: "Tr0ub4dor&3x", email: "jane@example.com"},
+		{name: "missing classes", policy: strict, password: "correcthorsebattery", wantCodes: []string{ViolationMissingUpper, ViolationMissingDigit, ViolationMissingSymbol}},
+		{name: "common password in any case", policy: Policy{DenyList: CommonPasswords()}, password: "PASSWORD123", wantCodes: []string{ViolationCommon}},
+		{name: "contains email local part", policy: strict, password: "Jane.Doe#2026x", email: "jane.doe@example.com", wantCodes: []string{ViolationContainsEmail}},
+		{name: "short local part is ignored", policy: strict, password: "Jo!Kestrel2026", email: "jo@example.com"},
+		{name: "every violation at once", policy: strict, password: "abc", wantCodes: []string{ViolationTooShort, ViolationMissingUpper, ViolationMissingDigit, ViolationMissingSymbol}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password, tt.email)
+			var codes []string
+			if v, ok := err.(Violations); ok {
+				for _, violation := range v {
+					codes = append(codes, violation.Code)
+				}
+			} else if err != nil {
+				t.Fatalf("Check() error = %v, want Violations", err)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.wantCodes, ",") {
+				t.Errorf("Check() violations = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestPolicyGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantLen int
+	}{
+		{name: "default", policy: DefaultPolicy, wantLen: 24},
+		{name: "every class", policy: Policy{MinLength: 12, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}, wantLen: 24},
+		{name: "long minimum", policy: Policy{MinLength: 40}, wantLen: 40},
+		{name: "short maximum", policy: Policy{MinLength: 8, MaxLength: 16}, wantLen: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				password, err := tt.policy.Generate("jane@example.com")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(password) != tt.wantLen || tt.policy.Check(password, "jane@example.com") != nil {
+					t.Fatalf("Generate() = %q, want %d characters satisfying the policy", password, tt.wantLen)
+				}
+			}
+		})
+	}
+}
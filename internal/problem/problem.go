@@ -0,0 +1,109 @@
+// Package problem writes error responses as RFC 7807 problem details
+// (application/problem+json), carrying a stable machine-readable code clients
+// can branch on instead of matching the human-readable detail. The title and
+// detail are translated into the request's language (see package i18n).
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/i18n"
+)
+
+// ContentType is the media type of problem details
+const ContentType = "application/problem+json"
+
+// Code identifies the kind of error. Codes are part of the API: once
+// published they keep their meaning, while details may be reworded.
+type Code string
+
+const (
+	// Requests
+	BadRequest            Code = "BAD_REQUEST"
+	ValidationFailed      Code = "VALIDATION_FAILED"
+	BodyTooLarge          Code = "BODY_TOO_LARGE"
+	NotFound              Code = "NOT_FOUND"
+	Conflict              Code = "CONFLICT"
+	IdempotencyKeyReused  Code = "IDEMPOTENCY_KEY_REUSED"
+	IdempotencyInProgress Code = "IDEMPOTENCY_IN_PROGRESS"
+	RateLimited           Code = "RATE_LIMITED"
+	InternalError         Code = "INTERNAL_ERROR"
+	ServiceUnavailable    Code = "SERVICE_UNAVAILABLE"
+
+	// Authentication and authorization
+	Unauthorized       Code = "UNAUTHORIZED"
+	TokenExpired       Code = "TOKEN_EXPIRED"
+	InvalidToken       Code = "INVALID_TOKEN"
+	Forbidden          Code = "FORBIDDEN"
+	InsufficientScope  Code = "INSUFFICIENT_SCOPE"
+	CSRFTokenInvalid   Code = "CSRF_TOKEN_INVALID"
+	InvalidCredentials Code = "INVALID_CREDENTIALS"
+	ScopeNotAllowed    Code = "SCOPE_NOT_ALLOWED"
+	CaptchaRequired    Code = "CAPTCHA_REQUIRED"
+	LoginThrottled     Code = "LOGIN_THROTTLED"
+	ExternalLogin      Code = "EXTERNAL_LOGIN_FAILED"
+	EmailNotVerified   Code = "EMAIL_NOT_VERIFIED"
+	LoginNotAllowed    Code = "LOGIN_METHOD_NOT_ALLOWED"
+	PasswordExpired    Code = "PASSWORD_EXPIRED"
+	CountryBlocked     Code = "COUNTRY_BLOCKED"
+
+	// Accounts
+	AccountLocked      Code = "ACCOUNT_LOCKED"
+	AccountSuspended   Code = "ACCOUNT_SUSPENDED"
+	AccountDisabled    Code = "ACCOUNT_DISABLED"
+	EmailTaken         Code = "EMAIL_TAKEN"
+	EmailDomainBlocked Code = "EMAIL_DOMAIN_BLOCKED"
+	PasswordRejected   Code = "PASSWORD_POLICY_VIOLATION"
+)
+
+// Problem is the body of an error response. Responses with more members
+// embed it in a struct of their own.
+type Problem struct {
+	// Type is about:blank, so Title is the status text and Code tells
+	// errors of the same status apart
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   Code   `json:"code"`
+}
+
+// New returns the problem for an error with status and code, described by
+// detail in English and translated for r. A nil r leaves it in English.
+func New(r *http.Request, status int, code Code, detail string) Problem {
+	l := localizer(r)
+	return Problem{Type: "about:blank", Title: l.Text(http.StatusText(status)), Status: status, Detail: l.Text(detail), Code: code}
+}
+
+// Error responds to r with the problem for an error with status and code
+func Error(w http.ResponseWriter, r *http.Request, status int, code Code, detail string) {
+	Write(w, r, status, New(r, status, code, detail))
+}
+
+// Write responds to r with status and body, a Problem or a struct embedding one
+func Write(w http.ResponseWriter, r *http.Request, status int, body any) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(body); err != nil {
+		b.Reset()
+		status = http.StatusInternalServerError
+		json.NewEncoder(&b).Encode(New(r, status, InternalError, ""))
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", ContentType)
+	h.Set("Content-Language", localizer(r).Language())
+	h.Add("Vary", "Accept-Language")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+// localizer returns the localizer of r, nil for English
+func localizer(r *http.Request) *i18n.Localizer {
+	if r == nil {
+		return nil
+	}
+	return i18n.FromContext(r.Context())
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAPIKeyNotFound is returned when an API key does not exist or was revoked
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepositoryImpl implements the APIKeyRepository interface
+type APIKeyRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that APIKeyRepositoryImpl implements APIKeyRepository interface
+var _ interfaces.APIKeyRepository = (*APIKeyRepositoryImpl)(nil)
+
+// NewAPIKeyRepository creates a new APIKeyRepository instance
+func NewAPIKeyRepository(db *database.DB) interfaces.APIKeyRepository {
+	return &APIKeyRepositoryImpl{db: db}
+}
+
+// CreateAPIKey stores a newly issued API key
+func (r *APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		key.UserID, key.Name, key.Prefix, key.KeyHash).Scan(&key.ID, &key.Created)
+}
+
+// ListAPIKeys retrieves a user's active API keys, newest first
+func (r *APIKeyRepositoryImpl) ListAPIKeys(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT id, user_id, name, prefix, last_used_at, created_at
+		 FROM api_keys
+		 WHERE user_id = $1 AND revoked_at IS NULL
+		 ORDER BY created_at DESC, id DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		var k model.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.LastUsedAt, &k.Created); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+// UseAPIKey looks up an active key by its hash and records that it was used
+func (r *APIKeyRepositoryImpl) UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	var k model.APIKey
+	err := r.db.Pool.QueryRow(ctx,
+		`UPDATE api_keys
+		 SET last_used_at = CURRENT_TIMESTAMP
+		 WHERE key_hash = $1 AND revoked_at IS NULL
+		 RETURNING id, user_id, name, prefix, last_used_at, created_at`,
+		keyHash).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.LastUsedAt, &k.Created)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// RevokeAPIKey revokes one of a user's API keys
+func (r *APIKeyRepositoryImpl) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	result, err := r.db.Pool.Exec(ctx,
+		`UPDATE api_keys
+		 SET revoked_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
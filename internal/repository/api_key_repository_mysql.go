@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MySQLAPIKeyRepository implements the APIKeyRepository interface on MySQL and MariaDB
+type MySQLAPIKeyRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLAPIKeyRepository implements APIKeyRepository interface
+var _ interfaces.APIKeyRepository = (*MySQLAPIKeyRepository)(nil)
+
+// NewMySQLAPIKeyRepository creates a new APIKeyRepository backed by MySQL
+func NewMySQLAPIKeyRepository(db *database.MySQL) interfaces.APIKeyRepository {
+	return &MySQLAPIKeyRepository{db: db}
+}
+
+// CreateAPIKey stores a newly issued API key
+func (r *MySQLAPIKeyRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	result, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash)
+		 VALUES (?, ?, ?, ?)`,
+		key.UserID, key.Name, key.Prefix, key.KeyHash)
+	if err != nil {
+		return err
+	}
+	if key.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	return r.db.DB.QueryRowContext(ctx,
+		`SELECT created_at FROM api_keys WHERE id = ?`,
+		key.ID).Scan(&key.Created)
+}
+
+// ListAPIKeys retrieves a user's active API keys, newest first
+func (r *MySQLAPIKeyRepository) ListAPIKeys(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT id, user_id, name, prefix, last_used_at, created_at
+		 FROM api_keys
+		 WHERE user_id = ? AND revoked_at IS NULL
+		 ORDER BY created_at DESC, id DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		var k model.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.LastUsedAt, &k.Created); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+// UseAPIKey looks up an active key by its hash and records that it was used
+func (r *MySQLAPIKeyRepository) UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE api_keys
+		 SET last_used_at = CURRENT_TIMESTAMP(6)
+		 WHERE key_hash = ? AND revoked_at IS NULL`,
+		keyHash)
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	var k model.APIKey
+	err = r.db.DB.QueryRowContext(ctx,
+		`SELECT id, user_id, name, prefix, last_used_at, created_at
+		 FROM api_keys
+		 WHERE key_hash = ?`,
+		keyHash).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.LastUsedAt, &k.Created)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// RevokeAPIKey revokes one of a user's API keys
+func (r *MySQLAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE api_keys
+		 SET revoked_at = CURRENT_TIMESTAMP(6)
+		 WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditRepositoryImpl writes audit events to the audit_events table
+type AuditRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that AuditRepositoryImpl implements AuditRepository interface
+var _ interfaces.AuditRepository = (*AuditRepositoryImpl)(nil)
+
+// NewAuditRepository creates a new audit event store backed by PostgreSQL
+func NewAuditRepository(db *database.DB) interfaces.AuditRepository {
+	return &AuditRepositoryImpl{db: db}
+}
+
+// Record stores a single event
+func (r *AuditRepositoryImpl) Record(ctx context.Context, event audit.Event) {
+	r.RecordBatch(ctx, []audit.Event{event})
+}
+
+// RecordBatch stores events with a single COPY. Failures are logged, since
+// audit writes must not fail the request that produced them.
+func (r *AuditRepositoryImpl) RecordBatch(ctx context.Context, events []audit.Event) {
+	rows := make([][]any, 0, len(events))
+	for _, e := range events {
+		var actorID any
+		if e.ActorID != 0 {
+			actorID = e.ActorID
+		}
+		var details []byte
+		if len(e.Details) > 0 {
+			details, _ = json.Marshal(e.Details)
+		}
+		rows = append(rows, []any{e.Type, e.Severity, actorID, e.IPAddress, e.UserAgent, details, e.Time})
+	}
+
+	_, err := r.db.Pool.CopyFrom(ctx,
+		pgx.Identifier{"audit_events"},
+		[]string{"type", "severity", "actor_id", "ip_address", "user_agent", "details", "created_at"},
+		pgx.CopyFromRows(rows))
+	if err != nil {
+		slog.ErrorContext(ctx, "audit: failed to store events", "count", len(events), "err", err)
+	}
+}
+
+// ListEvents returns a page of the events matching filter, newest first, with
+// the cursor of the next page
+func (r *AuditRepositoryImpl) ListEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error) {
+	after, err := filter.Page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if after != 0 {
+		add("id < $%d", after)
+	}
+	if filter.ActorID != 0 {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if len(filter.Types) > 0 {
+		add("type = ANY($%d)", filter.Types)
+	}
+
+	query := `SELECT id, type, severity, COALESCE(actor_id, 0), ip_address, user_agent, details, created_at 
+		 FROM audit_events`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Page.Fetch())
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+	for rows.Next() {
+		var e audit.Event
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.Severity, &e.ActorID, &e.IPAddress, &e.UserAgent, &details, &e.Time); err != nil {
+			return nil, "", err
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, "", err
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	events, next := pagination.Trim(events, filter.Page, func(e audit.Event) int64 { return e.ID })
+	return events, next, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// MySQLAuditRepository writes audit events to the audit_events table on MySQL and MariaDB
+type MySQLAuditRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLAuditRepository implements AuditRepository interface
+var _ interfaces.AuditRepository = (*MySQLAuditRepository)(nil)
+
+// NewMySQLAuditRepository creates a new audit event store backed by MySQL
+func NewMySQLAuditRepository(db *database.MySQL) interfaces.AuditRepository {
+	return &MySQLAuditRepository{db: db}
+}
+
+// Record stores a single event
+func (r *MySQLAuditRepository) Record(ctx context.Context, event audit.Event) {
+	r.RecordBatch(ctx, []audit.Event{event})
+}
+
+// RecordBatch stores events with a single multi-row INSERT. Failures are
+// logged, since audit writes must not fail the request that produced them.
+func (r *MySQLAuditRepository) RecordBatch(ctx context.Context, events []audit.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	args := make([]any, 0, len(events)*7)
+	for _, e := range events {
+		var actorID any
+		if e.ActorID != 0 {
+			actorID = e.ActorID
+		}
+		// JSON columns reject binary strings, so details are sent as text
+		var details any
+		if len(e.Details) > 0 {
+			data, _ := json.Marshal(e.Details)
+			details = string(data)
+		}
+		args = append(args, e.Type, e.Severity, actorID, e.IPAddress, e.UserAgent, details, e.Time)
+	}
+
+	_, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO audit_events (type, severity, actor_id, ip_address, user_agent, details, created_at)
+		 VALUES `+valueGroups(len(events), 7),
+		args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "audit: failed to store events", "count", len(events), "err", err)
+	}
+}
+
+// valueGroups returns the placeholders of a multi-row INSERT of rows rows with columns columns
+func valueGroups(rows, columns int) string {
+	group := "(" + strings.TrimSuffix(strings.Repeat("?, ", columns), ", ") + ")"
+	return strings.TrimSuffix(strings.Repeat(group+", ", rows), ", ")
+}
+
+// ListEvents returns a page of the events matching filter, newest first, with
+// the cursor of the next page
+func (r *MySQLAuditRepository) ListEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error) {
+	after, err := filter.Page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, condition)
+	}
+	if after != 0 {
+		add("id < ?", after)
+	}
+	if filter.ActorID != 0 {
+		add("actor_id = ?", filter.ActorID)
+	}
+	if len(filter.Types) > 0 {
+		where = append(where, "type IN (?"+strings.Repeat(", ?", len(filter.Types)-1)+")")
+		for _, t := range filter.Types {
+			args = append(args, t)
+		}
+	}
+
+	query := `SELECT id, type, severity, COALESCE(actor_id, 0), ip_address, user_agent, details, created_at
+		 FROM audit_events`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, filter.Page.Fetch())
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+	for rows.Next() {
+		var e audit.Event
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.Severity, &e.ActorID, &e.IPAddress, &e.UserAgent, &details, &e.Time); err != nil {
+			return nil, "", err
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, "", err
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	events, next := pagination.Trim(events, filter.Page, func(e audit.Event) int64 { return e.ID })
+	return events, next, nil
+}
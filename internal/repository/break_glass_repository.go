@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrCredentialAlreadyUsed is returned when a single-use credential was already redeemed
+var ErrCredentialAlreadyUsed = errors.New("credential already used")
+
+// BreakGlassRepositoryImpl implements the BreakGlassRepository interface
+type BreakGlassRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that BreakGlassRepositoryImpl implements BreakGlassRepository interface
+var _ interfaces.BreakGlassRepository = (*BreakGlassRepositoryImpl)(nil)
+
+// NewBreakGlassRepository creates a new BreakGlassRepository instance
+func NewBreakGlassRepository(db *database.DB) interfaces.BreakGlassRepository {
+	return &BreakGlassRepositoryImpl{db: db}
+}
+
+// RedeemBreakGlassCredential marks a credential as used and stores the admin
+// session it was redeemed for. Redeeming the same credential twice fails,
+// even across restarts or replicas.
+func (r *BreakGlassRepositoryImpl) RedeemBreakGlassCredential(ctx context.Context, credentialHash, ipAddress, sessionHash string, sessionExpiresAt time.Time) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO break_glass_redemptions (credential_hash, ip_address, session_hash, session_expires_at)
+		 VALUES ($1, $2, $3, $4)`,
+		credentialHash, ipAddress, sessionHash, sessionExpiresAt)
+
+	if database.IsUniqueViolation(err) {
+		return ErrCredentialAlreadyUsed
+	}
+	return err
+}
+
+// IsBreakGlassSessionValid reports whether a redemption stored the session and it has not expired
+func (r *BreakGlassRepositoryImpl) IsBreakGlassSessionValid(ctx context.Context, sessionHash string) (bool, error) {
+	var expiresAt time.Time
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT session_expires_at
+		 FROM break_glass_redemptions
+		 WHERE session_hash = $1`,
+		sessionHash).Scan(&expiresAt)
+
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Before(expiresAt), nil
+}
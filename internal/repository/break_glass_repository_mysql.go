@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+)
+
+// MySQLBreakGlassRepository implements the BreakGlassRepository interface on MySQL and MariaDB
+type MySQLBreakGlassRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLBreakGlassRepository implements BreakGlassRepository interface
+var _ interfaces.BreakGlassRepository = (*MySQLBreakGlassRepository)(nil)
+
+// NewMySQLBreakGlassRepository creates a new BreakGlassRepository backed by MySQL
+func NewMySQLBreakGlassRepository(db *database.MySQL) interfaces.BreakGlassRepository {
+	return &MySQLBreakGlassRepository{db: db}
+}
+
+// RedeemBreakGlassCredential marks a credential as used and stores the admin
+// session it was redeemed for. Redeeming the same credential twice fails,
+// even across restarts or replicas.
+func (r *MySQLBreakGlassRepository) RedeemBreakGlassCredential(ctx context.Context, credentialHash, ipAddress, sessionHash string, sessionExpiresAt time.Time) error {
+	_, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO break_glass_redemptions (credential_hash, ip_address, session_hash, session_expires_at)
+		 VALUES (?, ?, ?, ?)`,
+		credentialHash, ipAddress, sessionHash, sessionExpiresAt)
+
+	if database.IsUniqueViolation(err) {
+		return ErrCredentialAlreadyUsed
+	}
+	return err
+}
+
+// IsBreakGlassSessionValid reports whether a redemption stored the session and it has not expired
+func (r *MySQLBreakGlassRepository) IsBreakGlassSessionValid(ctx context.Context, sessionHash string) (bool, error) {
+	var expiresAt time.Time
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT session_expires_at
+		 FROM break_glass_redemptions
+		 WHERE session_hash = ?`,
+		sessionHash).Scan(&expiresAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Before(expiresAt), nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+)
+
+// SQLiteBreakGlassRepository implements the BreakGlassRepository interface on SQLite
+type SQLiteBreakGlassRepository struct {
+	db *database.SQLite
+}
+
+// Verify that SQLiteBreakGlassRepository implements BreakGlassRepository interface
+var _ interfaces.BreakGlassRepository = (*SQLiteBreakGlassRepository)(nil)
+
+// NewSQLiteBreakGlassRepository creates a new BreakGlassRepository backed by SQLite
+func NewSQLiteBreakGlassRepository(db *database.SQLite) interfaces.BreakGlassRepository {
+	return &SQLiteBreakGlassRepository{db: db}
+}
+
+// RedeemBreakGlassCredential marks a credential as used and stores the admin
+// session it was redeemed for. Redeeming the same credential twice fails,
+// even across restarts or replicas.
+func (r *SQLiteBreakGlassRepository) RedeemBreakGlassCredential(ctx context.Context, credentialHash, ipAddress, sessionHash string, sessionExpiresAt time.Time) error {
+	_, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO break_glass_redemptions (credential_hash, ip_address, session_hash, session_expires_at)
+		 VALUES (?, ?, ?, ?)`,
+		credentialHash, ipAddress, sessionHash, sessionExpiresAt.UTC())
+
+	if database.IsUniqueViolation(err) {
+		return ErrCredentialAlreadyUsed
+	}
+	return err
+}
+
+// IsBreakGlassSessionValid reports whether a redemption stored the session and it has not expired
+func (r *SQLiteBreakGlassRepository) IsBreakGlassSessionValid(ctx context.Context, sessionHash string) (bool, error) {
+	var expiresAt time.Time
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT session_expires_at
+		 FROM break_glass_redemptions
+		 WHERE session_hash = ?`,
+		sessionHash).Scan(&expiresAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Before(expiresAt), nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+)
+
+// CombinedRepository joins a user store and a session store kept in different
+// backends into a UserRepository, for code written against the single interface
+type CombinedRepository struct {
+	interfaces.UserStore
+	interfaces.SessionStore
+}
+
+// Verify that CombinedRepository implements UserRepository interface
+var _ interfaces.UserRepository = (*CombinedRepository)(nil)
+
+// NewCombinedRepository creates a UserRepository keeping users in users and
+// sessions in sessions
+func NewCombinedRepository(users interfaces.UserStore, sessions interfaces.SessionStore) interfaces.UserRepository {
+	return &CombinedRepository{UserStore: users, SessionStore: sessions}
+}
+
+// WithTx runs fn in a transaction of the user store when it keeps the sessions
+// too. Stores in different backends cannot share a transaction, so otherwise
+// fn runs without one.
+func (r *CombinedRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	if repo, ok := r.UserStore.(interfaces.UserRepository); ok && any(repo) == any(r.SessionStore) {
+		return repo.WithTx(ctx, fn)
+	}
+	return fn(r)
+}
+
+// Verify that CombinedRepository implements LoginRecorder interface
+var _ interfaces.LoginRecorder = (*CombinedRepository)(nil)
+
+// RecordLogin records a sign-in with the user store's RecordLogin when it
+// keeps the sessions too, and like RecordLogin otherwise
+func (r *CombinedRepository) RecordLogin(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	if repo, ok := r.UserStore.(interfaces.UserRepository); ok && any(repo) == any(r.SessionStore) {
+		return RecordLogin(ctx, repo, userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
+	}
+	return recordLoginTx(ctx, r, userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
+}
+
+// RecordLogin resets the user's failed attempts, updates their last login and
+// stores the session, batched when repo is an interfaces.LoginRecorder and in
+// one WithTx unit of work otherwise
+func RecordLogin(ctx context.Context, repo interfaces.UserRepository, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	if recorder, ok := repo.(interfaces.LoginRecorder); ok {
+		return recorder.RecordLogin(ctx, userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
+	}
+	return recordLoginTx(ctx, repo, userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
+}
+
+func recordLoginTx(ctx context.Context, repo interfaces.UserRepository, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	return repo.WithTx(ctx, func(tx interfaces.UserRepository) error {
+		if err := tx.UpdateLastLogin(ctx, userID); err != nil {
+			return err
+		}
+		return tx.CreateSession(ctx, userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
+	})
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// ConsentRepositoryImpl implements the ConsentRepository interface
+type ConsentRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that ConsentRepositoryImpl implements ConsentRepository interface
+var _ interfaces.ConsentRepository = (*ConsentRepositoryImpl)(nil)
+
+// NewConsentRepository creates a new ConsentRepository instance
+func NewConsentRepository(db *database.DB) interfaces.ConsentRepository {
+	return &ConsentRepositoryImpl{db: db}
+}
+
+// CreateConsentReceipt stores a new consent receipt
+func (r *ConsentRepositoryImpl) CreateConsentReceipt(ctx context.Context, receipt *model.ConsentReceipt) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO consent_receipts (user_id, purpose, subject, scope, version, granted, ip_address, user_agent)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, created_at`,
+		receipt.UserID, receipt.Purpose, receipt.Subject, receipt.Scope, receipt.Version,
+		receipt.Granted, receipt.IPAddress, receipt.UserAgent).Scan(&receipt.ID, &receipt.Created)
+}
+
+// ListConsentReceipts retrieves all consent receipts for a user, oldest first
+func (r *ConsentRepositoryImpl) ListConsentReceipts(ctx context.Context, userID int64) ([]*model.ConsentReceipt, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT id, user_id, purpose, subject, scope, version, granted, ip_address, user_agent, created_at
+		 FROM consent_receipts
+		 WHERE user_id = $1
+		 ORDER BY created_at, id`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []*model.ConsentReceipt
+	for rows.Next() {
+		var c model.ConsentReceipt
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Purpose, &c.Subject, &c.Scope, &c.Version,
+			&c.Granted, &c.IPAddress, &c.UserAgent, &c.Created); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, &c)
+	}
+	return receipts, rows.Err()
+}
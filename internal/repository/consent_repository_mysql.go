@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MySQLConsentRepository implements the ConsentRepository interface on MySQL and MariaDB
+type MySQLConsentRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLConsentRepository implements ConsentRepository interface
+var _ interfaces.ConsentRepository = (*MySQLConsentRepository)(nil)
+
+// NewMySQLConsentRepository creates a new ConsentRepository backed by MySQL
+func NewMySQLConsentRepository(db *database.MySQL) interfaces.ConsentRepository {
+	return &MySQLConsentRepository{db: db}
+}
+
+// CreateConsentReceipt stores a new consent receipt
+func (r *MySQLConsentRepository) CreateConsentReceipt(ctx context.Context, receipt *model.ConsentReceipt) error {
+	result, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO consent_receipts (user_id, purpose, subject, scope, version, granted, ip_address, user_agent)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		receipt.UserID, receipt.Purpose, receipt.Subject, receipt.Scope, receipt.Version,
+		receipt.Granted, receipt.IPAddress, receipt.UserAgent)
+	if err != nil {
+		return err
+	}
+	if receipt.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	return r.db.DB.QueryRowContext(ctx,
+		`SELECT created_at FROM consent_receipts WHERE id = ?`,
+		receipt.ID).Scan(&receipt.Created)
+}
+
+// ListConsentReceipts retrieves all consent receipts for a user, oldest first
+func (r *MySQLConsentRepository) ListConsentReceipts(ctx context.Context, userID int64) ([]*model.ConsentReceipt, error) {
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT id, user_id, purpose, subject, scope, version, granted, ip_address, user_agent, created_at
+		 FROM consent_receipts
+		 WHERE user_id = ?
+		 ORDER BY created_at, id`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []*model.ConsentReceipt
+	for rows.Next() {
+		var c model.ConsentReceipt
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Purpose, &c.Subject, &c.Scope, &c.Version,
+			&c.Granted, &c.IPAddress, &c.UserAgent, &c.Created); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, &c)
+	}
+	return receipts, rows.Err()
+}
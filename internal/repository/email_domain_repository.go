@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+var (
+	ErrDomainAlreadyBlocked = errors.New("domain is already blocked")
+	ErrDomainNotFound       = errors.New("domain is not blocked")
+)
+
+// EmailDomainRepositoryImpl implements the EmailDomainRepository interface
+type EmailDomainRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that EmailDomainRepositoryImpl implements EmailDomainRepository interface
+var _ interfaces.EmailDomainRepository = (*EmailDomainRepositoryImpl)(nil)
+
+// NewEmailDomainRepository creates a new EmailDomainRepository instance
+func NewEmailDomainRepository(db *database.DB) interfaces.EmailDomainRepository {
+	return &EmailDomainRepositoryImpl{db: db}
+}
+
+// BlockDomain adds a domain to the blocked ones
+func (r *EmailDomainRepositoryImpl) BlockDomain(ctx context.Context, domain *model.BlockedDomain) error {
+	err := r.db.Pool.QueryRow(ctx,
+		`INSERT INTO blocked_email_domains (domain, reason)
+		 VALUES ($1, $2)
+		 RETURNING created_at`,
+		domain.Domain, domain.Reason).Scan(&domain.Created)
+
+	if database.IsUniqueViolation(err) {
+		return ErrDomainAlreadyBlocked
+	}
+	return err
+}
+
+// UnblockDomain removes a domain from the blocked ones
+func (r *EmailDomainRepositoryImpl) UnblockDomain(ctx context.Context, domain string) error {
+	result, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM blocked_email_domains WHERE domain = $1`,
+		domain)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrDomainNotFound
+	}
+	return nil
+}
+
+// ListBlockedDomains returns the blocked domains in alphabetical order
+func (r *EmailDomainRepositoryImpl) ListBlockedDomains(ctx context.Context) ([]*model.BlockedDomain, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT domain, reason, created_at
+		 FROM blocked_email_domains
+		 ORDER BY domain`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []*model.BlockedDomain
+	for rows.Next() {
+		var domain model.BlockedDomain
+		if err := rows.Scan(&domain.Domain, &domain.Reason, &domain.Created); err != nil {
+			return nil, err
+		}
+		domains = append(domains, &domain)
+	}
+	return domains, rows.Err()
+}
+
+// IsDomainBlocked reports whether any of the domains is blocked
+func (r *EmailDomainRepositoryImpl) IsDomainBlocked(ctx context.Context, domains ...string) (bool, error) {
+	var blocked bool
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM blocked_email_domains WHERE domain = ANY($1))`,
+		domains).Scan(&blocked)
+	return blocked, err
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// SQLiteEmailDomainRepository implements the EmailDomainRepository interface on SQLite
+type SQLiteEmailDomainRepository struct {
+	db *database.SQLite
+}
+
+// Verify that SQLiteEmailDomainRepository implements EmailDomainRepository interface
+var _ interfaces.EmailDomainRepository = (*SQLiteEmailDomainRepository)(nil)
+
+// NewSQLiteEmailDomainRepository creates a new EmailDomainRepository backed by SQLite
+func NewSQLiteEmailDomainRepository(db *database.SQLite) interfaces.EmailDomainRepository {
+	return &SQLiteEmailDomainRepository{db: db}
+}
+
+// BlockDomain adds a domain to the blocked ones
+func (r *SQLiteEmailDomainRepository) BlockDomain(ctx context.Context, domain *model.BlockedDomain) error {
+	_, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO blocked_email_domains (domain, reason)
+		 VALUES (?, ?)`,
+		domain.Domain, domain.Reason)
+	if database.IsUniqueViolation(err) {
+		return ErrDomainAlreadyBlocked
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.db.DB.QueryRowContext(ctx,
+		`SELECT created_at FROM blocked_email_domains WHERE domain = ?`,
+		domain.Domain).Scan(&domain.Created)
+}
+
+// UnblockDomain removes a domain from the blocked ones
+func (r *SQLiteEmailDomainRepository) UnblockDomain(ctx context.Context, domain string) error {
+	result, err := r.db.DB.ExecContext(ctx,
+		`DELETE FROM blocked_email_domains WHERE domain = ?`,
+		domain)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDomainNotFound
+	}
+	return nil
+}
+
+// ListBlockedDomains returns the blocked domains in alphabetical order
+func (r *SQLiteEmailDomainRepository) ListBlockedDomains(ctx context.Context) ([]*model.BlockedDomain, error) {
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT domain, reason, created_at
+		 FROM blocked_email_domains
+		 ORDER BY domain`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []*model.BlockedDomain
+	for rows.Next() {
+		var domain model.BlockedDomain
+		if err := rows.Scan(&domain.Domain, &domain.Reason, &domain.Created); err != nil {
+			return nil, err
+		}
+		domains = append(domains, &domain)
+	}
+	return domains, rows.Err()
+}
+
+// IsDomainBlocked reports whether any of the domains is blocked
+func (r *SQLiteEmailDomainRepository) IsDomainBlocked(ctx context.Context, domains ...string) (bool, error) {
+	if len(domains) == 0 {
+		return false, nil
+	}
+	args := make([]any, len(domains))
+	for i, domain := range domains {
+		args[i] = domain
+	}
+
+	var blocked bool
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM blocked_email_domains WHERE domain IN (?`+strings.Repeat(", ?", len(domains)-1)+`))`,
+		args...).Scan(&blocked)
+	return blocked, err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// ErrIdentityAlreadyLinked is returned when a provider identity belongs to another user
+var ErrIdentityAlreadyLinked = errors.New("identity already linked to a user")
+
+// IdentityRepositoryImpl implements the IdentityRepository interface
+type IdentityRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that IdentityRepositoryImpl implements IdentityRepository interface
+var _ interfaces.IdentityRepository = (*IdentityRepositoryImpl)(nil)
+
+// NewIdentityRepository creates a new IdentityRepository instance
+func NewIdentityRepository(db *database.DB) interfaces.IdentityRepository {
+	return &IdentityRepositoryImpl{db: db}
+}
+
+// GetUserByIdentity retrieves the user linked to a provider identity
+func (r *IdentityRepositoryImpl) GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*model.User, error) {
+	return scanUser(r.db.Pool.QueryRow(ctx,
+		`SELECT `+userColumns+`
+		 JOIN user_identities i ON i.user_id = u.id
+		 WHERE i.provider = $1 AND i.provider_user_id = $2`,
+		provider, providerUserID))
+}
+
+// LinkIdentity associates a provider identity with an existing user
+func (r *IdentityRepositoryImpl) LinkIdentity(ctx context.Context, userID int64, provider, providerUserID, email string) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO user_identities (user_id, provider, provider_user_id, email)
+		 VALUES ($1, $2, $3, $4)`,
+		userID, provider, providerUserID, email)
+
+	if database.IsUniqueViolation(err) {
+		return ErrIdentityAlreadyLinked
+	}
+	return err
+}
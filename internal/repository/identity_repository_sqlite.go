@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// SQLiteIdentityRepository implements the IdentityRepository interface on SQLite
+type SQLiteIdentityRepository struct {
+	db *database.SQLite
+}
+
+// Verify that SQLiteIdentityRepository implements IdentityRepository interface
+var _ interfaces.IdentityRepository = (*SQLiteIdentityRepository)(nil)
+
+// NewSQLiteIdentityRepository creates a new IdentityRepository backed by SQLite
+func NewSQLiteIdentityRepository(db *database.SQLite) interfaces.IdentityRepository {
+	return &SQLiteIdentityRepository{db: db}
+}
+
+// GetUserByIdentity retrieves the user linked to a provider identity
+func (r *SQLiteIdentityRepository) GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*model.User, error) {
+	return scanUser(r.db.DB.QueryRowContext(ctx,
+		`SELECT `+userColumns+`
+		 JOIN user_identities i ON i.user_id = u.id
+		 WHERE i.provider = ? AND i.provider_user_id = ?`,
+		provider, providerUserID))
+}
+
+// LinkIdentity associates a provider identity with an existing user
+func (r *SQLiteIdentityRepository) LinkIdentity(ctx context.Context, userID int64, provider, providerUserID, email string) error {
+	_, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO user_identities (user_id, provider, provider_user_id, email)
+		 VALUES (?, ?, ?, ?)`,
+		userID, provider, providerUserID, email)
+
+	if database.IsUniqueViolation(err) {
+		return ErrIdentityAlreadyLinked
+	}
+	return err
+}
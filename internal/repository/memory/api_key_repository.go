@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// APIKeyRepository keeps the API keys of the users of a Store
+type APIKeyRepository struct {
+	store *Store
+}
+
+// Verify that APIKeyRepository implements APIKeyRepository interface
+var _ interfaces.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// NewAPIKeyRepository creates an API key repository backed by store
+func NewAPIKeyRepository(store *Store) interfaces.APIKeyRepository {
+	return &APIKeyRepository{store: store}
+}
+
+// copyKey returns a copy of key without its hash, like the database listings
+func copyKey(key *model.APIKey) *model.APIKey {
+	copied := *key
+	copied.KeyHash = ""
+	if key.LastUsedAt != nil {
+		lastUsed := *key.LastUsedAt
+		copied.LastUsedAt = &lastUsed
+	}
+	return &copied
+}
+
+// CreateAPIKey stores a new API key for a user
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.users[key.UserID]; !exists {
+		return repository.ErrUserNotFound
+	}
+	r.store.lastAPIKeyID++
+	key.ID = r.store.lastAPIKeyID
+	key.Created = time.Now()
+	stored := *key
+	r.store.apiKeys[key.ID] = &stored
+	return nil
+}
+
+// ListAPIKeys returns a user's active API keys, newest first
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var keys []*model.APIKey
+	for _, key := range r.store.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, copyKey(key))
+		}
+	}
+	slices.SortFunc(keys, func(a, b *model.APIKey) int { return cmp.Compare(b.ID, a.ID) })
+	return keys, nil
+}
+
+// UseAPIKey looks up an active key by hash and records that it was used
+func (r *APIKeyRepository) UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, key := range r.store.apiKeys {
+		if key.KeyHash == keyHash {
+			now := time.Now()
+			key.LastUsedAt = &now
+			return copyKey(key), nil
+		}
+	}
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+// RevokeAPIKey revokes one of a user's API keys
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, exists := r.store.apiKeys[keyID]
+	if !exists || key.UserID != userID {
+		return repository.ErrAPIKeyNotFound
+	}
+	// Revoked keys are never read again, so they are deleted rather than flagged
+	delete(r.store.apiKeys, keyID)
+	return nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"maps"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// AuditRepository keeps audit events in a Store
+type AuditRepository struct {
+	store *Store
+}
+
+// Verify that AuditRepository implements AuditRepository interface
+var _ interfaces.AuditRepository = (*AuditRepository)(nil)
+
+// NewAuditRepository creates an audit event store backed by store
+func NewAuditRepository(store *Store) interfaces.AuditRepository {
+	return &AuditRepository{store: store}
+}
+
+// Record stores a single event
+func (r *AuditRepository) Record(ctx context.Context, event audit.Event) {
+	r.RecordBatch(ctx, []audit.Event{event})
+}
+
+// RecordBatch stores events, numbering them like a serial column
+func (r *AuditRepository) RecordBatch(ctx context.Context, events []audit.Event) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, event := range events {
+		event.ID = int64(len(r.store.events) + 1)
+		event.Details = maps.Clone(event.Details)
+		r.store.events = append(r.store.events, event)
+	}
+}
+
+// ListEvents returns a page of the events matching filter, newest first, with
+// the cursor of the next page
+func (r *AuditRepository) ListEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var events []audit.Event
+	for i := len(r.store.events) - 1; i >= 0; i-- {
+		event := r.store.events[i]
+		if filter.Match(event) {
+			event.Details = maps.Clone(event.Details)
+			events = append(events, event)
+		}
+	}
+	return pagination.Slice(events, filter.Page, true, func(e audit.Event) int64 { return e.ID })
+}
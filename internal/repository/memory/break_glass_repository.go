@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// BreakGlassRepository records redeemed break-glass credentials in a Store
+type BreakGlassRepository struct {
+	store *Store
+}
+
+// Verify that BreakGlassRepository implements BreakGlassRepository interface
+var _ interfaces.BreakGlassRepository = (*BreakGlassRepository)(nil)
+
+// NewBreakGlassRepository creates a break-glass repository backed by store
+func NewBreakGlassRepository(store *Store) interfaces.BreakGlassRepository {
+	return &BreakGlassRepository{store: store}
+}
+
+// RedeemBreakGlassCredential marks a credential as used and stores the admin
+// session it was redeemed for, failing if it already was
+func (r *BreakGlassRepository) RedeemBreakGlassCredential(ctx context.Context, credentialHash, ipAddress, sessionHash string, sessionExpiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.redeemed[credentialHash] {
+		return repository.ErrCredentialAlreadyUsed
+	}
+	r.store.redeemed[credentialHash] = true
+	r.store.breakGlass[sessionHash] = sessionExpiresAt
+	return nil
+}
+
+// IsBreakGlassSessionValid reports whether a redemption stored the session and it has not expired
+func (r *BreakGlassRepository) IsBreakGlassSessionValid(ctx context.Context, sessionHash string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	expiresAt, exists := r.store.breakGlass[sessionHash]
+	return exists && time.Now().Before(expiresAt), nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// ConsentRepository keeps consent receipts in a Store
+type ConsentRepository struct {
+	store *Store
+}
+
+// Verify that ConsentRepository implements ConsentRepository interface
+var _ interfaces.ConsentRepository = (*ConsentRepository)(nil)
+
+// NewConsentRepository creates a consent repository backed by store
+func NewConsentRepository(store *Store) interfaces.ConsentRepository {
+	return &ConsentRepository{store: store}
+}
+
+// CreateConsentReceipt stores an immutable consent receipt
+func (r *ConsentRepository) CreateConsentReceipt(ctx context.Context, receipt *model.ConsentReceipt) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.lastConsentID++
+	receipt.ID = r.store.lastConsentID
+	receipt.Created = time.Now()
+	copied := *receipt
+	r.store.consents = append(r.store.consents, &copied)
+	return nil
+}
+
+// ListConsentReceipts returns a user's consent receipts, oldest first
+func (r *ConsentRepository) ListConsentReceipts(ctx context.Context, userID int64) ([]*model.ConsentReceipt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var receipts []*model.ConsentReceipt
+	for _, receipt := range r.store.consents {
+		if receipt.UserID == userID {
+			copied := *receipt
+			receipts = append(receipts, &copied)
+		}
+	}
+	return receipts, nil
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// EmailDomainRepository stores blocked email domains in a Store
+type EmailDomainRepository struct {
+	store *Store
+}
+
+// Verify that EmailDomainRepository implements EmailDomainRepository interface
+var _ interfaces.EmailDomainRepository = (*EmailDomainRepository)(nil)
+
+// NewEmailDomainRepository creates an email domain repository backed by store
+func NewEmailDomainRepository(store *Store) interfaces.EmailDomainRepository {
+	return &EmailDomainRepository{store: store}
+}
+
+// BlockDomain adds a domain to the blocked ones
+func (r *EmailDomainRepository) BlockDomain(ctx context.Context, domain *model.BlockedDomain) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.blocked[domain.Domain]; exists {
+		return repository.ErrDomainAlreadyBlocked
+	}
+	domain.Created = time.Now()
+	r.store.blocked[domain.Domain] = *domain
+	return nil
+}
+
+// UnblockDomain removes a domain from the blocked ones
+func (r *EmailDomainRepository) UnblockDomain(ctx context.Context, domain string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.blocked[domain]; !exists {
+		return repository.ErrDomainNotFound
+	}
+	delete(r.store.blocked, domain)
+	return nil
+}
+
+// ListBlockedDomains returns the blocked domains in alphabetical order
+func (r *EmailDomainRepository) ListBlockedDomains(ctx context.Context) ([]*model.BlockedDomain, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var domains []*model.BlockedDomain
+	for _, domain := range r.store.blocked {
+		domains = append(domains, &domain)
+	}
+	slices.SortFunc(domains, func(a, b *model.BlockedDomain) int { return strings.Compare(a.Domain, b.Domain) })
+	return domains, nil
+}
+
+// IsDomainBlocked reports whether any of the domains is blocked
+func (r *EmailDomainRepository) IsDomainBlocked(ctx context.Context, domains ...string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, domain := range domains {
+		if _, exists := r.store.blocked[domain]; exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
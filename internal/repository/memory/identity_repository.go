@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// IdentityRepository keeps provider identities linked to the users of a Store
+type IdentityRepository struct {
+	store *Store
+}
+
+// Verify that IdentityRepository implements IdentityRepository interface
+var _ interfaces.IdentityRepository = (*IdentityRepository)(nil)
+
+// NewIdentityRepository creates an identity repository backed by store
+func NewIdentityRepository(store *Store) interfaces.IdentityRepository {
+	return &IdentityRepository{store: store}
+}
+
+func identityKey(provider, providerUserID string) string {
+	return provider + ":" + providerUserID
+}
+
+// GetUserByIdentity retrieves the user linked to a provider identity
+func (r *IdentityRepository) GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, exists := r.store.users[r.store.identities[identityKey(provider, providerUserID)]]
+	if !exists {
+		return nil, repository.ErrUserNotFound
+	}
+	return r.store.lookupUser(user)
+}
+
+// LinkIdentity associates a provider identity with an existing user
+func (r *IdentityRepository) LinkIdentity(ctx context.Context, userID int64, provider, providerUserID, email string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := identityKey(provider, providerUserID)
+	if _, exists := r.store.identities[key]; exists {
+		return repository.ErrIdentityAlreadyLinked
+	}
+	if _, exists := r.store.users[userID]; !exists {
+		return repository.ErrUserNotFound
+	}
+	r.store.identities[key] = userID
+	return nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// OAuthRepository keeps OAuth clients and authorization codes in a Store
+type OAuthRepository struct {
+	store *Store
+}
+
+// Verify that OAuthRepository implements OAuthRepository interface
+var _ interfaces.OAuthRepository = (*OAuthRepository)(nil)
+
+// NewOAuthRepository creates an OAuth repository backed by store
+func NewOAuthRepository(store *Store) interfaces.OAuthRepository {
+	return &OAuthRepository{store: store}
+}
+
+// copyClient returns a copy of client that shares none of its slices
+func copyClient(client *model.OAuthClient) *model.OAuthClient {
+	copied := *client
+	copied.RedirectURIs = slices.Clone(client.RedirectURIs)
+	copied.GrantTypes = slices.Clone(client.GrantTypes)
+	copied.Scopes = slices.Clone(client.Scopes)
+	return &copied
+}
+
+// CreateClient registers a new OAuth client
+func (r *OAuthRepository) CreateClient(ctx context.Context, client *model.OAuthClient) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.clients[client.ClientID]; exists {
+		return repository.ErrDuplicateClientID
+	}
+	r.store.lastClientID++
+	client.ID = r.store.lastClientID
+	client.Created = time.Now()
+	r.store.clients[client.ClientID] = copyClient(client)
+	return nil
+}
+
+// GetClient retrieves an OAuth client by its client ID
+func (r *OAuthRepository) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	client, exists := r.store.clients[clientID]
+	if !exists {
+		return nil, repository.ErrClientNotFound
+	}
+	return copyClient(client), nil
+}
+
+// SaveAuthorizationCode stores a newly issued authorization code
+func (r *OAuthRepository) SaveAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	copied := *code
+	r.store.codes[code.CodeHash] = &copied
+	return nil
+}
+
+// ConsumeAuthorizationCode atomically removes an unexpired code and returns it
+func (r *OAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	code, exists := r.store.codes[codeHash]
+	if !exists {
+		return nil, repository.ErrCodeNotFound
+	}
+	// Used and expired codes can never be redeemed, so neither is kept
+	delete(r.store.codes, codeHash)
+	if !time.Now().Before(code.ExpiresAt) {
+		return nil, repository.ErrCodeNotFound
+	}
+	return code, nil
+}
@@ -0,0 +1,169 @@
+// Package memory implements every repository in process memory, so the
+// service can run without a database for demos, local development, and CI.
+// Nothing is persisted: all data is lost when the process exits.
+package memory
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// Store holds the data of every repository. Repositories created from the
+// same Store share it, so deleting a user also deletes its sessions,
+// identities, and API keys like the database schema's cascades do. A Store is
+// safe for concurrent use.
+type Store struct {
+	mu sync.RWMutex
+
+	users      map[int64]*model.User
+	metadata   map[int64][]byte // user ID to metadata encoded as JSON
+	emails     map[string]int64 // normalized email to user ID
+	sessions   map[string]*session
+	identities map[string]int64 // provider:providerUserID to user ID
+	tenants    map[int64]*tenant
+	clients    map[string]*model.OAuthClient
+	codes      map[string]*model.AuthorizationCode
+	consents   []*model.ConsentReceipt
+	apiKeys    map[int64]*model.APIKey
+	redeemed   map[string]bool
+	breakGlass map[string]time.Time // break-glass session hash to expiry
+	blocked    map[string]model.BlockedDomain
+	usage      map[time.Time]map[model.UsageKey]*model.UsageRecord
+	active     map[time.Time]map[model.ActiveUser]bool
+	events     []audit.Event
+	deliveries []*model.WebhookDelivery
+
+	// Last IDs handed out, like serial columns
+	lastUserID    int64
+	lastSessionID int64
+	lastTenantID  int64
+	lastClientID  int64
+	lastConsentID int64
+	lastAPIKeyID  int64
+}
+
+// session is a stored session; revoked sessions are kept like in the database
+type session struct {
+	model.Session
+	revoked bool
+}
+
+// tenant is a stored tenant with its settings encoded as JSON, so callers
+// never share the settings' slices with the store
+type tenant struct {
+	model.Tenant
+	settings []byte
+}
+
+// New creates an empty store
+func New() *Store {
+	return &Store{
+		users:      make(map[int64]*model.User),
+		metadata:   make(map[int64][]byte),
+		emails:     make(map[string]int64),
+		sessions:   make(map[string]*session),
+		identities: make(map[string]int64),
+		tenants:    make(map[int64]*tenant),
+		clients:    make(map[string]*model.OAuthClient),
+		codes:      make(map[string]*model.AuthorizationCode),
+		apiKeys:    make(map[int64]*model.APIKey),
+		redeemed:   make(map[string]bool),
+		breakGlass: make(map[string]time.Time),
+		blocked:    make(map[string]model.BlockedDomain),
+		usage:      make(map[time.Time]map[model.UsageKey]*model.UsageRecord),
+		active:     make(map[time.Time]map[model.ActiveUser]bool),
+	}
+}
+
+// insertUser stores a new user. The caller must hold the write lock.
+func (s *Store) insertUser(user *model.User) {
+	s.lastUserID++
+	user.ID = s.lastUserID
+	user.Created = time.Now()
+	user.PasswordChangedAt = user.Created
+	s.users[user.ID] = user
+	s.emails[model.NormalizeEmail(user.Email)] = user.ID
+}
+
+// deleteUser deletes a user with its sessions, identities, and API keys. The
+// caller must hold the write lock.
+func (s *Store) deleteUser(user *model.User) {
+	for tokenID, session := range s.sessions {
+		if session.UserID == user.ID {
+			delete(s.sessions, tokenID)
+		}
+	}
+	for key, userID := range s.identities {
+		if userID == user.ID {
+			delete(s.identities, key)
+		}
+	}
+	for id, key := range s.apiKeys {
+		if key.UserID == user.ID {
+			delete(s.apiKeys, id)
+		}
+	}
+	delete(s.emails, model.NormalizeEmail(user.Email))
+	delete(s.metadata, user.ID)
+	delete(s.users, user.ID)
+}
+
+// lookupUser returns a copy of a user for sign-in, failing for users that
+// may not authenticate like the repository's lookups do. The caller must hold
+// the read lock.
+func (s *Store) lookupUser(user *model.User) (*model.User, error) {
+	if user.DeletedAt != nil {
+		return nil, repository.ErrUserNotFound
+	}
+	if user.TenantID != nil {
+		if t, ok := s.tenants[*user.TenantID]; ok && t.Status != model.TenantStatusActive {
+			return nil, repository.ErrTenantSuspended
+		}
+	}
+	if user.IsDisabled {
+		return nil, repository.ErrUserDisabled
+	}
+	if user.IsLocked {
+		return nil, repository.ErrTooManyAttempts
+	}
+
+	copied := *user
+	copied.TenantID = copyID(user.TenantID)
+	copied.IsLocked, copied.IsDisabled, copied.EmailVerified = false, false, false
+	return &copied, nil
+}
+
+// adminUser returns a copy of a user with its state and without its password
+// hash, like the repository's admin listings
+func adminUser(user *model.User) *model.User {
+	copied := *user
+	copied.Password = ""
+	copied.TenantID = copyID(user.TenantID)
+	if user.DeletedAt != nil {
+		deletedAt := *user.DeletedAt
+		copied.DeletedAt = &deletedAt
+	}
+	return &copied
+}
+
+func copyID(id *int64) *int64 {
+	if id == nil {
+		return nil
+	}
+	copied := *id
+	return &copied
+}
+
+// decode returns a copy of a stored tenant
+func (t *tenant) decode() (*model.Tenant, error) {
+	copied := t.Tenant
+	if err := json.Unmarshal(t.settings, &copied.Settings); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
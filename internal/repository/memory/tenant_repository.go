@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// TenantRepository keeps the tenants of a Store
+type TenantRepository struct {
+	store *Store
+}
+
+// Verify that TenantRepository implements TenantRepository interface
+var _ interfaces.TenantRepository = (*TenantRepository)(nil)
+
+// NewTenantRepository creates a tenant repository backed by store
+func NewTenantRepository(store *Store) interfaces.TenantRepository {
+	return &TenantRepository{store: store}
+}
+
+// OnboardTenant creates a tenant together with its first admin user
+func (r *TenantRepository) OnboardTenant(ctx context.Context, t *model.Tenant, adminEmail, adminPasswordHash string) (*model.User, error) {
+	settings, err := json.Marshal(t.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.tenants {
+		if existing.Slug == t.Slug {
+			return nil, repository.ErrDuplicateTenantSlug
+		}
+	}
+	if _, exists := r.store.emails[model.NormalizeEmail(adminEmail)]; exists {
+		return nil, repository.ErrDuplicateEmail
+	}
+
+	r.store.lastTenantID++
+	t.ID = r.store.lastTenantID
+	t.Status = model.TenantStatusActive
+	t.Created = time.Now()
+	r.store.tenants[t.ID] = &tenant{Tenant: *t, settings: settings}
+
+	admin := &model.User{
+		Email:    adminEmail,
+		Password: adminPasswordHash,
+		Type:     model.UserTypeHuman,
+		Role:     model.RoleAdmin,
+		TenantID: copyID(&t.ID),
+	}
+	r.store.insertUser(admin)
+	return &model.User{ID: admin.ID, Email: adminEmail, Created: admin.Created, Type: admin.Type, Role: admin.Role, TenantID: copyID(&t.ID)}, nil
+}
+
+// GetTenant retrieves a tenant by ID
+func (r *TenantRepository) GetTenant(ctx context.Context, tenantID int64) (*model.Tenant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	t, exists := r.store.tenants[tenantID]
+	if !exists {
+		return nil, repository.ErrTenantNotFound
+	}
+	return t.decode()
+}
+
+// ListTenants returns every tenant
+func (r *TenantRepository) ListTenants(ctx context.Context) ([]*model.Tenant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tenants []*model.Tenant
+	for _, t := range r.store.tenants {
+		decoded, err := t.decode()
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, decoded)
+	}
+	slices.SortFunc(tenants, func(a, b *model.Tenant) int { return cmp.Compare(a.ID, b.ID) })
+	return tenants, nil
+}
+
+// UpdateTenantSettings replaces a tenant's settings
+func (r *TenantRepository) UpdateTenantSettings(ctx context.Context, tenantID int64, settings model.TenantSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, exists := r.store.tenants[tenantID]
+	if !exists {
+		return repository.ErrTenantNotFound
+	}
+	t.settings = data
+	return nil
+}
+
+// SetTenantStatus activates or suspends a tenant
+func (r *TenantRepository) SetTenantStatus(ctx context.Context, tenantID int64, status string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, exists := r.store.tenants[tenantID]
+	if !exists {
+		return repository.ErrTenantNotFound
+	}
+	t.Status = status
+	return nil
+}
+
+// RevokeTenantSessions revokes every active session of the tenant's users
+func (r *TenantRepository) RevokeTenantSessions(ctx context.Context, tenantID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.revokeSessions(func(userID int64) bool {
+		user, exists := r.store.users[userID]
+		return exists && user.TenantID != nil && *user.TenantID == tenantID
+	}), nil
+}
+
+// DeleteTenant deletes a tenant with its users, and through them their
+// sessions, identities, and API keys. It returns how many users were deleted.
+func (r *TenantRepository) DeleteTenant(ctx context.Context, tenantID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.tenants[tenantID]; !exists {
+		return 0, repository.ErrTenantNotFound
+	}
+
+	var deleted int64
+	for _, user := range r.store.users {
+		if user.TenantID != nil && *user.TenantID == tenantID {
+			r.store.deleteUser(user)
+			deleted++
+		}
+	}
+	delete(r.store.tenants, tenantID)
+	return deleted, nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// UsageRepository keeps usage metering counters in a Store
+type UsageRepository struct {
+	store *Store
+}
+
+// Verify that UsageRepository implements UsageRepository interface
+var _ interfaces.UsageRepository = (*UsageRepository)(nil)
+
+// NewUsageRepository creates a usage repository backed by store
+func NewUsageRepository(store *Store) interfaces.UsageRepository {
+	return &UsageRepository{store: store}
+}
+
+// AddUsage adds counter increments and active users for a period
+func (r *UsageRepository) AddUsage(ctx context.Context, period time.Time, counts []model.UsageCount, active []model.ActiveUser) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.usage[period] == nil {
+		r.store.usage[period] = make(map[model.UsageKey]*model.UsageRecord)
+		r.store.active[period] = make(map[model.ActiveUser]bool)
+	}
+	for _, c := range counts {
+		record := r.store.usage[period][c.UsageKey]
+		if record == nil {
+			record = &model.UsageRecord{UsageKey: c.UsageKey}
+			r.store.usage[period][c.UsageKey] = record
+		}
+		record.Add(c.Metric, c.Count)
+	}
+	for _, a := range active {
+		r.store.active[period][a] = true
+	}
+	return nil
+}
+
+// GetUsage returns the usage of a period per tenant and per client
+func (r *UsageRepository) GetUsage(ctx context.Context, period time.Time) (*model.UsageReport, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tenants := make(map[int64]*model.UsageRecord)
+	clients := make(map[model.UsageKey]*model.UsageRecord)
+	record := func(key model.UsageKey) (*model.UsageRecord, *model.UsageRecord) {
+		if tenants[key.TenantID] == nil {
+			tenants[key.TenantID] = &model.UsageRecord{UsageKey: model.UsageKey{TenantID: key.TenantID}}
+		}
+		if clients[key] == nil {
+			clients[key] = &model.UsageRecord{UsageKey: key}
+		}
+		return tenants[key.TenantID], clients[key]
+	}
+
+	for key, counts := range r.store.usage[period] {
+		tenant, client := record(key)
+		for _, rec := range []*model.UsageRecord{tenant, client} {
+			rec.Logins += counts.Logins
+			rec.TokensIssued += counts.TokensIssued
+			rec.EmailsSent += counts.EmailsSent
+			rec.SMSSent += counts.SMSSent
+		}
+	}
+	tenantUsers := make(map[int64]map[int64]bool)
+	for a := range r.store.active[period] {
+		_, client := record(a.UsageKey)
+		client.MonthlyActiveUsers++
+		if tenantUsers[a.TenantID] == nil {
+			tenantUsers[a.TenantID] = make(map[int64]bool)
+		}
+		tenantUsers[a.TenantID][a.UserID] = true
+	}
+	for tenantID, users := range tenantUsers {
+		tenants[tenantID].MonthlyActiveUsers = int64(len(users))
+	}
+
+	report := &model.UsageReport{Period: period}
+	for _, tenant := range tenants {
+		report.Tenants = append(report.Tenants, tenant)
+	}
+	for _, client := range clients {
+		report.Clients = append(report.Clients, client)
+	}
+	byKey := func(a, b *model.UsageRecord) int {
+		return cmp.Or(cmp.Compare(a.TenantID, b.TenantID), cmp.Compare(a.ClientID, b.ClientID))
+	}
+	slices.SortFunc(report.Tenants, byKey)
+	slices.SortFunc(report.Clients, byKey)
+	return report, nil
+}
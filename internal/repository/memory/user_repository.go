@@ -0,0 +1,415 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// UserRepository keeps users and their sessions in a Store
+type UserRepository struct {
+	store *Store
+}
+
+// Verify that UserRepository implements UserRepository interface
+var _ interfaces.UserRepository = (*UserRepository)(nil)
+
+// NewUserRepository creates a user repository backed by store
+func NewUserRepository(store *Store) interfaces.UserRepository {
+	return &UserRepository{store: store}
+}
+
+// WithTx runs fn with the repository. Each write is atomic on its own and a
+// crash loses the whole store, so there is nothing to roll back after one;
+// writes made before fn fails are kept.
+func (r *UserRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	return fn(r)
+}
+
+// CreateUser creates a new user
+func (r *UserRepository) CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error) {
+	return r.create(email, passwordHash, model.UserTypeHuman)
+}
+
+func (r *UserRepository) create(email, passwordHash, userType string) (*model.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.emails[model.NormalizeEmail(email)]; exists {
+		return nil, repository.ErrDuplicateEmail
+	}
+	user := &model.User{Email: email, Password: passwordHash, Type: userType, Role: model.RoleUser}
+	r.store.insertUser(user)
+	return &model.User{ID: user.ID, Email: email, Created: user.Created, Type: userType, Role: user.Role}, nil
+}
+
+// GetUserByEmail retrieves a user by their email address, ignoring case
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	userID, exists := r.store.emails[model.NormalizeEmail(email)]
+	if !exists {
+		return nil, repository.ErrUserNotFound
+	}
+	return r.store.lookupUser(r.store.users[userID])
+}
+
+// GetUserByID retrieves a user by their ID
+func (r *UserRepository) GetUserByID(ctx context.Context, userID int64) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, exists := r.store.users[userID]
+	if !exists {
+		return nil, repository.ErrUserNotFound
+	}
+	return r.store.lookupUser(user)
+}
+
+// update applies fn to the user with the ID under the write lock, failing
+// with ErrUserNotFound when there is no such user or match rejects it
+func (r *UserRepository) update(userID int64, match func(*model.User) bool, fn func(*model.User)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, exists := r.store.users[userID]
+	if !exists || (match != nil && !match(user)) {
+		return repository.ErrUserNotFound
+	}
+	fn(user)
+	return nil
+}
+
+// SetCanary marks or unmarks a user as a canary account
+func (r *UserRepository) SetCanary(ctx context.Context, userID int64, canary bool) error {
+	return r.update(userID, nil, func(user *model.User) { user.IsCanary = canary })
+}
+
+// CreateServiceAccount creates a service account user that has no password
+func (r *UserRepository) CreateServiceAccount(ctx context.Context, email string) (*model.User, error) {
+	// Like the database repositories, store a hash no password can match
+	return r.create(email, "!", model.UserTypeService)
+}
+
+// ListServiceAccounts returns every service account, including locked ones
+func (r *UserRepository) ListServiceAccounts(ctx context.Context) ([]*model.User, error) {
+	return r.list(func(user *model.User) bool { return user.IsServiceAccount() }), nil
+}
+
+// list returns copies of the users matching keep, ordered by ID
+func (r *UserRepository) list(keep func(*model.User) bool) []*model.User {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var users []*model.User
+	for _, user := range r.store.users {
+		if keep(user) {
+			users = append(users, adminUser(user))
+		}
+	}
+	slices.SortFunc(users, func(a, b *model.User) int { return cmp.Compare(a.ID, b.ID) })
+	return users
+}
+
+// SetServiceAccountLocked locks or unlocks a service account
+func (r *UserRepository) SetServiceAccountLocked(ctx context.Context, userID int64, locked bool) error {
+	return r.update(userID, (*model.User).IsServiceAccount, func(user *model.User) {
+		user.IsLocked = locked
+		user.FailedAttempts = 0
+	})
+}
+
+// DeleteServiceAccount deletes a service account together with its API keys and sessions
+func (r *UserRepository) DeleteServiceAccount(ctx context.Context, userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, exists := r.store.users[userID]
+	if !exists || !user.IsServiceAccount() {
+		return repository.ErrUserNotFound
+	}
+	r.store.deleteUser(user)
+	return nil
+}
+
+// SearchUsers returns a page of the users matching filter, including locked
+// and disabled ones, ordered by ID, with the cursor of the next page.
+// Soft-deleted users are only returned when filter.Deleted is set.
+func (r *UserRepository) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error) {
+	users := r.list(func(user *model.User) bool {
+		switch {
+		case filter.TenantID != nil && (user.TenantID == nil || *user.TenantID != *filter.TenantID),
+			!strings.HasPrefix(user.Email, filter.EmailPrefix),
+			filter.Role != "" && user.Role != filter.Role,
+			filter.Type != "" && user.Type != filter.Type,
+			filter.Locked != nil && user.IsLocked != *filter.Locked,
+			filter.Disabled != nil && user.IsDisabled != *filter.Disabled,
+			filter.Verified != nil && user.EmailVerified != *filter.Verified,
+			(user.DeletedAt != nil) != filter.Deleted,
+			!filter.CreatedAfter.IsZero() && user.Created.Before(filter.CreatedAfter),
+			!filter.CreatedBefore.IsZero() && !user.Created.Before(filter.CreatedBefore):
+			return false
+		}
+		return true
+	})
+	return pagination.Slice(users, filter.Page, false, func(u *model.User) int64 { return u.ID })
+}
+
+// GetUserForAdmin retrieves a user by ID whatever its state, for administration
+func (r *UserRepository) GetUserForAdmin(ctx context.Context, userID int64) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, exists := r.store.users[userID]
+	if !exists {
+		return nil, repository.ErrUserNotFound
+	}
+	return adminUser(user), nil
+}
+
+// SetUserDisabled disables or re-enables a user
+func (r *UserRepository) SetUserDisabled(ctx context.Context, userID int64, disabled bool) error {
+	return r.update(userID, nil, func(user *model.User) { user.IsDisabled = disabled })
+}
+
+// GetUserMetadata returns a user's metadata
+func (r *UserRepository) GetUserMetadata(ctx context.Context, userID int64) (*model.UserMetadata, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if _, exists := r.store.users[userID]; !exists {
+		return nil, repository.ErrUserNotFound
+	}
+	metadata := model.UserMetadata{AppMetadata: map[string]any{}, UserMetadata: map[string]any{}}
+	if data := r.store.metadata[userID]; data != nil {
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return nil, err
+		}
+	}
+	return &metadata, nil
+}
+
+// SetUserMetadata replaces a user's metadata
+func (r *UserRepository) SetUserMetadata(ctx context.Context, userID int64, metadata *model.UserMetadata) error {
+	// Stored encoded, so callers never share maps with the store
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return r.update(userID, nil, func(user *model.User) { r.store.metadata[userID] = data })
+}
+
+// UpdateUserProfile replaces a user's profile
+func (r *UserRepository) UpdateUserProfile(ctx context.Context, userID int64, profile model.Profile) error {
+	return r.update(userID, nil, func(user *model.User) { user.Profile = profile })
+}
+
+// isHuman reports whether a user can have a password
+func isHuman(user *model.User) bool {
+	return user.Type == model.UserTypeHuman
+}
+
+// UpdatePassword replaces a user's password hash, restarting its age, and
+// clears any lockout or forced expiry
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	return r.update(userID, isHuman, func(user *model.User) {
+		user.Password = passwordHash
+		user.FailedAttempts = 0
+		user.IsLocked = false
+		user.PasswordChangedAt = time.Now()
+		user.PasswordExpiresAt = nil
+	})
+}
+
+// ExpirePassword forces a user to change their password at the next sign-in
+func (r *UserRepository) ExpirePassword(ctx context.Context, userID int64) error {
+	return r.update(userID, isHuman, func(user *model.User) {
+		now := time.Now()
+		user.PasswordExpiresAt = &now
+	})
+}
+
+// RehashPassword replaces a user's password hash with a stronger hash of the
+// same password, unless the password changed since oldHash was read
+func (r *UserRepository) RehashPassword(ctx context.Context, userID int64, oldHash, newHash string) error {
+	return r.update(userID, func(user *model.User) bool { return user.Password == oldHash }, func(user *model.User) {
+		user.Password = newHash
+	})
+}
+
+// UnlockUser clears the failed attempts of a user locked out by the lockout policy
+func (r *UserRepository) UnlockUser(ctx context.Context, userID int64) error {
+	return r.update(userID, isHuman, func(user *model.User) {
+		user.FailedAttempts = 0
+		user.IsLocked = false
+	})
+}
+
+// MarkEmailVerified records that an identity provider vouched for a user's email
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	err := r.update(userID, nil, func(user *model.User) { user.EmailVerified = true })
+	if err == repository.ErrUserNotFound {
+		return nil
+	}
+	return err
+}
+
+// SoftDeleteUser hides a user from sign-in and lookups until it is restored or purged
+func (r *UserRepository) SoftDeleteUser(ctx context.Context, userID int64) error {
+	return r.update(userID, func(user *model.User) bool { return user.DeletedAt == nil }, func(user *model.User) {
+		now := time.Now()
+		user.DeletedAt = &now
+	})
+}
+
+// RestoreUser undoes the soft deletion of a user
+func (r *UserRepository) RestoreUser(ctx context.Context, userID int64) error {
+	return r.update(userID, func(user *model.User) bool { return user.DeletedAt != nil }, func(user *model.User) {
+		user.DeletedAt = nil
+	})
+}
+
+// PurgeDeletedUsers permanently deletes users soft-deleted before the given
+// time, with their sessions, identities, and API keys, and returns how many
+// were deleted
+func (r *UserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var purged int64
+	for _, user := range r.store.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(before) {
+			r.store.deleteUser(user)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// UpdateLastLogin updates the last login time and resets failed attempts
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID int64) error {
+	err := r.update(userID, nil, func(user *model.User) {
+		now := time.Now()
+		user.LastLogin = &now
+		user.FailedAttempts = 0
+	})
+	if err == repository.ErrUserNotFound {
+		return nil
+	}
+	return err
+}
+
+// IncrementFailedAttempts increments the failed login attempts counter and
+// locks the account once the policy locks it
+func (r *UserRepository) IncrementFailedAttempts(ctx context.Context, userID int64, policy model.LockoutPolicy) error {
+	var locked bool
+	err := r.update(userID, nil, func(user *model.User) {
+		user.FailedAttempts++
+		user.IsLocked = policy.IsLocked(user.FailedAttempts)
+		locked = user.IsLocked
+	})
+	if err != nil {
+		return err
+	}
+	if locked {
+		return repository.ErrTooManyAttempts
+	}
+	return nil
+}
+
+// CreateSession creates a new session for a user. Expired sessions are
+// dropped meanwhile, so the store does not grow without bound.
+func (r *UserRepository) CreateSession(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for id, session := range r.store.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(r.store.sessions, id)
+		}
+	}
+
+	r.store.lastSessionID++
+	r.store.sessions[tokenID] = &session{Session: model.Session{
+		ID:                r.store.lastSessionID,
+		UserID:            userID,
+		TokenID:           tokenID,
+		DeviceFingerprint: deviceFingerprint,
+		RememberMe:        rememberMe,
+		Created:           now,
+		ExpiresAt:         expiresAt,
+	}}
+	return nil
+}
+
+// RevokeSession marks a session as revoked
+func (r *UserRepository) RevokeSession(ctx context.Context, tokenID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, exists := r.store.sessions[tokenID]
+	if !exists {
+		return repository.ErrSessionNotFound
+	}
+	session.revoked = true
+	return nil
+}
+
+// RevokeAllSessions revokes every active session of a user and returns how many were revoked
+func (r *UserRepository) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.revokeSessions(func(owner int64) bool { return owner == userID }), nil
+}
+
+// revokeSessions revokes the active sessions of the users matching owner. The
+// caller must hold the write lock.
+func (s *Store) revokeSessions(owner func(userID int64) bool) int64 {
+	now := time.Now()
+	var revoked int64
+	for _, session := range s.sessions {
+		if !session.revoked && now.Before(session.ExpiresAt) && owner(session.UserID) {
+			session.revoked = true
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// ListSessions returns a page of a user's active sessions, oldest first, with
+// the cursor of the next page
+func (r *UserRepository) ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []*model.Session
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && !session.revoked && now.Before(session.ExpiresAt) {
+			copied := session.Session
+			sessions = append(sessions, &copied)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b *model.Session) int { return cmp.Compare(a.ID, b.ID) })
+	return pagination.Slice(sessions, page, false, func(s *model.Session) int64 { return s.ID })
+}
+
+// IsSessionValid checks if a session exists and is neither revoked nor expired
+func (r *UserRepository) IsSessionValid(ctx context.Context, tokenID string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	session, exists := r.store.sessions[tokenID]
+	return exists && !session.revoked && time.Now().Before(session.ExpiresAt), nil
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+func TestUserRepositoryLockout(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(New())
+	policy := model.LockoutPolicy{MaxFailedAttempts: 3}
+
+	user, err := repo.CreateUser(ctx, "test@example.com", "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := repo.CreateUser(ctx, "test@example.com", "hash"); err != repository.ErrDuplicateEmail {
+		t.Errorf("duplicate email: got %v, want ErrDuplicateEmail", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		err := repo.IncrementFailedAttempts(ctx, user.ID, policy)
+		if want := i == 3; (err == repository.ErrTooManyAttempts) != want {
+			t.Errorf("attempt %d: got %v", i, err)
+		}
+	}
+	if _, err := repo.GetUserByEmail(ctx, "test@example.com"); err != repository.ErrTooManyAttempts {
+		t.Errorf("locked user lookup: got %v, want ErrTooManyAttempts", err)
+	}
+	if admin, _ := repo.GetUserForAdmin(ctx, user.ID); !admin.IsLocked || admin.Password != "" {
+		t.Errorf("admin view = %+v, want locked without password hash", admin)
+	}
+
+	if err := repo.UnlockUser(ctx, user.ID); err != nil {
+		t.Fatalf("failed to unlock user: %v", err)
+	}
+	repo.IncrementFailedAttempts(ctx, user.ID, policy)
+	if err := repo.UpdateLastLogin(ctx, user.ID); err != nil {
+		t.Fatalf("failed to update last login: %v", err)
+	}
+	found, err := repo.GetUserByID(ctx, user.ID)
+	if err != nil || found.FailedAttempts != 0 || found.Password != "hash" {
+		t.Fatalf("GetUserByID = %+v, %v; want no failed attempts", found, err)
+	}
+
+	// Callers get copies they cannot change the store through
+	found.IsCanary = true
+	if again, _ := repo.GetUserByID(ctx, user.ID); again.IsCanary {
+		t.Error("mutating a returned user changed the store")
+	}
+}
+
+func TestUserRepositorySessions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(New())
+
+	repo.CreateSession(ctx, 1, "a", "device-hash", false, time.Now().Add(time.Hour))
+	repo.CreateSession(ctx, 1, "b", "", false, time.Now().Add(time.Hour))
+	repo.CreateSession(ctx, 1, "expired", "", false, time.Now().Add(-time.Minute))
+	repo.CreateSession(ctx, 2, "other", "", false, time.Now().Add(time.Hour))
+
+	if valid, _ := repo.IsSessionValid(ctx, "expired"); valid {
+		t.Error("expected expired session to be invalid")
+	}
+	if err := repo.RevokeSession(ctx, "missing"); err != repository.ErrSessionNotFound {
+		t.Errorf("revoking unknown session: got %v, want ErrSessionNotFound", err)
+	}
+
+	sessions, next, err := repo.ListSessions(ctx, 1, pagination.Page{Limit: 10})
+	if err != nil || len(sessions) != 2 || sessions[0].TokenID != "a" || sessions[0].DeviceFingerprint != "device-hash" || next != "" {
+		t.Fatalf("ListSessions = %v, %q, %v; want sessions a and b", sessions, next, err)
+	}
+
+	revoked, err := repo.RevokeAllSessions(ctx, 1)
+	if err != nil || revoked != 2 {
+		t.Errorf("RevokeAllSessions = %d, %v; want 2", revoked, err)
+	}
+	for tokenID, want := range map[string]bool{"a": false, "b": false, "other": true} {
+		if valid, _ := repo.IsSessionValid(ctx, tokenID); valid != want {
+			t.Errorf("session %s valid = %v, want %v", tokenID, valid, want)
+		}
+	}
+}
+
+func TestStoreCascades(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	users := NewUserRepository(store)
+	identities := NewIdentityRepository(store)
+	apiKeys := NewAPIKeyRepository(store)
+	tenants := NewTenantRepository(store)
+
+	admin, err := tenants.OnboardTenant(ctx, &model.Tenant{Slug: "acme", Name: "Acme"}, "admin@acme.test", "hash")
+	if err != nil {
+		t.Fatalf("failed to onboard tenant: %v", err)
+	}
+	identities.LinkIdentity(ctx, admin.ID, "github", "42", "admin@acme.test")
+	apiKeys.CreateAPIKey(ctx, &model.APIKey{UserID: admin.ID, Name: "ci", KeyHash: "key"})
+	users.CreateSession(ctx, admin.ID, "token", "", false, time.Now().Add(time.Hour))
+
+	if err := tenants.SetTenantStatus(ctx, *admin.TenantID, model.TenantStatusSuspended); err != nil {
+		t.Fatalf("failed to suspend tenant: %v", err)
+	}
+	if _, err := identities.GetUserByIdentity(ctx, "github", "42"); err != repository.ErrTenantSuspended {
+		t.Errorf("user of suspended tenant: got %v, want ErrTenantSuspended", err)
+	}
+
+	deleted, err := tenants.DeleteTenant(ctx, *admin.TenantID)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteTenant = %d, %v; want 1", deleted, err)
+	}
+	if _, err := identities.GetUserByIdentity(ctx, "github", "42"); err != repository.ErrUserNotFound {
+		t.Errorf("identity of deleted user: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := apiKeys.UseAPIKey(ctx, "key"); err != repository.ErrAPIKeyNotFound {
+		t.Errorf("key of deleted user: got %v, want ErrAPIKeyNotFound", err)
+	}
+	if valid, _ := users.IsSessionValid(ctx, "token"); valid {
+		t.Error("session of deleted user is still valid")
+	}
+	if _, err := users.CreateUser(ctx, "admin@acme.test", "hash"); err != nil {
+		t.Errorf("email of deleted user not released: %v", err)
+	}
+}
+
+func TestStoreConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	users := NewUserRepository(store)
+	tenants := NewTenantRepository(store)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := users.CreateUser(ctx, fmt.Sprintf("user%d@example.com", i), "hash")
+			if err != nil {
+				t.Errorf("failed to create user: %v", err)
+				return
+			}
+			users.CreateSession(ctx, user.ID, fmt.Sprint(i), "", false, time.Now().Add(time.Hour))
+			users.IncrementFailedAttempts(ctx, user.ID, model.DefaultLockoutPolicy)
+			users.SearchUsers(ctx, model.UserFilter{Page: pagination.Page{Limit: 5}})
+			tenants.ListTenants(ctx)
+		}()
+	}
+	wg.Wait()
+
+	found, next, _ := users.SearchUsers(ctx, model.UserFilter{Page: pagination.Page{Limit: 100}})
+	if len(found) != 20 || next != "" {
+		t.Errorf("found %d users, want 20", len(found))
+	}
+	seen := make(map[int64]bool)
+	for _, user := range found {
+		if seen[user.ID] || user.FailedAttempts != 1 {
+			t.Errorf("unexpected user %+v", user)
+		}
+		seen[user.ID] = true
+	}
+}
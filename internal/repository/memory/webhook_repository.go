@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// WebhookDeliveryRepository keeps the webhook delivery log in a Store
+type WebhookDeliveryRepository struct {
+	store *Store
+}
+
+// Verify that WebhookDeliveryRepository implements WebhookDeliveryRepository interface
+var _ interfaces.WebhookDeliveryRepository = (*WebhookDeliveryRepository)(nil)
+
+// NewWebhookDeliveryRepository creates a webhook delivery log backed by store
+func NewWebhookDeliveryRepository(store *Store) interfaces.WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{store: store}
+}
+
+// RecordDelivery stores a delivery attempt, numbering it like a serial column
+func (r *WebhookDeliveryRepository) RecordDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delivery.ID = int64(len(r.store.deliveries) + 1)
+	delivery.Created = time.Now()
+	copied := *delivery
+	r.store.deliveries = append(r.store.deliveries, &copied)
+	return nil
+}
+
+// ListDeliveries returns a page of the delivery attempts matching filter,
+// newest first, with the cursor of the next page
+func (r *WebhookDeliveryRepository) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var deliveries []*model.WebhookDelivery
+	for i := len(r.store.deliveries) - 1; i >= 0; i-- {
+		d := r.store.deliveries[i]
+		if (filter.EventID == "" || d.EventID == filter.EventID) &&
+			(filter.EventType == "" || d.EventType == filter.EventType) &&
+			(!filter.FailedOnly || !d.Delivered) {
+			copied := *d
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	return pagination.Slice(deliveries, filter.Page, true, func(d *model.WebhookDelivery) int64 { return d.ID })
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// Errors returned by the OAuth repository
+var (
+	ErrClientNotFound    = errors.New("oauth client not found")
+	ErrDuplicateClientID = errors.New("client id already exists")
+	ErrCodeNotFound      = errors.New("authorization code not found or already used")
+)
+
+// OAuthRepositoryImpl implements the OAuthRepository interface
+type OAuthRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that OAuthRepositoryImpl implements OAuthRepository interface
+var _ interfaces.OAuthRepository = (*OAuthRepositoryImpl)(nil)
+
+// NewOAuthRepository creates a new OAuthRepository instance
+func NewOAuthRepository(db *database.DB) interfaces.OAuthRepository {
+	return &OAuthRepositoryImpl{db: db}
+}
+
+// CreateClient registers a new OAuth client
+func (r *OAuthRepositoryImpl) CreateClient(ctx context.Context, client *model.OAuthClient) error {
+	err := r.db.Pool.QueryRow(ctx,
+		`INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, is_public, grant_types, scopes)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		client.ClientID, client.SecretHash, client.Name, client.RedirectURIs, client.IsPublic,
+		client.GrantTypes, client.Scopes).Scan(&client.ID, &client.Created)
+
+	if database.IsUniqueViolation(err) {
+		return ErrDuplicateClientID
+	}
+	return err
+}
+
+// GetClient retrieves an OAuth client by its client ID
+func (r *OAuthRepositoryImpl) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT id, client_id, client_secret_hash, name, redirect_uris, is_public, grant_types, scopes, created_at
+		 FROM oauth_clients
+		 WHERE client_id = $1`,
+		clientID).Scan(&client.ID, &client.ClientID, &client.SecretHash, &client.Name, &client.RedirectURIs, &client.IsPublic,
+		&client.GrantTypes, &client.Scopes, &client.Created)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// SaveAuthorizationCode stores a newly issued authorization code
+func (r *OAuthRepositoryImpl) SaveAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, expires_at, code_challenge, code_challenge_method)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.Scope, code.Nonce, code.ExpiresAt,
+		code.CodeChallenge, code.CodeChallengeMethod)
+	return err
+}
+
+// ConsumeAuthorizationCode atomically marks an unexpired code as used and returns it
+func (r *OAuthRepositoryImpl) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	var code model.AuthorizationCode
+	var nonce *string
+	err := r.db.Pool.QueryRow(ctx,
+		`UPDATE oauth_authorization_codes
+		 SET used_at = CURRENT_TIMESTAMP
+		 WHERE code_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		 RETURNING code_hash, client_id, user_id, redirect_uri, scope, nonce, expires_at, code_challenge, code_challenge_method`,
+		codeHash).Scan(&code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &code.Scope, &nonce, &code.ExpiresAt,
+		&code.CodeChallenge, &code.CodeChallengeMethod)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if nonce != nil {
+		code.Nonce = *nonce
+	}
+	return &code, nil
+}
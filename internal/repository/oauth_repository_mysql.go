@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MySQLOAuthRepository implements the OAuthRepository interface on MySQL and
+// MariaDB. List columns, which are arrays in PostgreSQL, are stored as JSON.
+type MySQLOAuthRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLOAuthRepository implements OAuthRepository interface
+var _ interfaces.OAuthRepository = (*MySQLOAuthRepository)(nil)
+
+// NewMySQLOAuthRepository creates a new OAuthRepository backed by MySQL
+func NewMySQLOAuthRepository(db *database.MySQL) interfaces.OAuthRepository {
+	return &MySQLOAuthRepository{db: db}
+}
+
+// CreateClient registers a new OAuth client
+func (r *MySQLOAuthRepository) CreateClient(ctx context.Context, client *model.OAuthClient) error {
+	result, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, is_public, grant_types, scopes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		client.ClientID, client.SecretHash, client.Name, jsonList(client.RedirectURIs), client.IsPublic,
+		jsonList(client.GrantTypes), jsonList(client.Scopes))
+	if database.IsUniqueViolation(err) {
+		return ErrDuplicateClientID
+	}
+	if err != nil {
+		return err
+	}
+	if client.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	return r.db.DB.QueryRowContext(ctx,
+		`SELECT created_at FROM oauth_clients WHERE id = ?`,
+		client.ID).Scan(&client.Created)
+}
+
+// GetClient retrieves an OAuth client by its client ID
+func (r *MySQLOAuthRepository) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	var redirectURIs, grantTypes, scopes []byte
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT id, client_id, client_secret_hash, name, redirect_uris, is_public, grant_types, scopes, created_at
+		 FROM oauth_clients
+		 WHERE client_id = ?`,
+		clientID).Scan(&client.ID, &client.ClientID, &client.SecretHash, &client.Name, &redirectURIs, &client.IsPublic,
+		&grantTypes, &scopes, &client.Created)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for dst, data := range map[*[]string][]byte{&client.RedirectURIs: redirectURIs, &client.GrantTypes: grantTypes, &client.Scopes: scopes} {
+		if err := json.Unmarshal(data, dst); err != nil {
+			return nil, err
+		}
+	}
+	return &client, nil
+}
+
+// jsonList encodes a list for a JSON column, as text since JSON columns reject
+// binary strings. Nil lists are stored as empty ones.
+func jsonList(list []string) string {
+	if list == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+// SaveAuthorizationCode stores a newly issued authorization code
+func (r *MySQLOAuthRepository) SaveAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error {
+	_, err := r.db.DB.ExecContext(ctx,
+		`INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, expires_at, code_challenge, code_challenge_method)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.Scope, code.Nonce, code.ExpiresAt,
+		code.CodeChallenge, code.CodeChallengeMethod)
+	return err
+}
+
+// ConsumeAuthorizationCode atomically marks an unexpired code as used and
+// returns it. Concurrent redemptions wait on the row lock and then no longer
+// match used_at IS NULL, so only one of them succeeds.
+func (r *MySQLOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE oauth_authorization_codes
+		 SET used_at = CURRENT_TIMESTAMP(6)
+		 WHERE code_hash = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP(6)`,
+		codeHash)
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrCodeNotFound
+	}
+
+	var code model.AuthorizationCode
+	var nonce *string
+	err = r.db.DB.QueryRowContext(ctx,
+		`SELECT code_hash, client_id, user_id, redirect_uri, scope, nonce, expires_at, code_challenge, code_challenge_method
+		 FROM oauth_authorization_codes
+		 WHERE code_hash = ?`,
+		codeHash).Scan(&code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &code.Scope, &nonce, &code.ExpiresAt,
+		&code.CodeChallenge, &code.CodeChallengeMethod)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if nonce != nil {
+		code.Nonce = *nonce
+	}
+	return &code, nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// OutboxRepositoryImpl implements the OutboxRepository interface
+type OutboxRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that OutboxRepositoryImpl implements OutboxRepository interface
+var _ interfaces.OutboxRepository = (*OutboxRepositoryImpl)(nil)
+
+// NewOutboxRepository creates a new OutboxRepository instance
+func NewOutboxRepository(db *database.DB) interfaces.OutboxRepository {
+	return &OutboxRepositoryImpl{db: db}
+}
+
+// RelayOutbox passes the oldest unpublished events to publish and marks the
+// ones it published. The events stay locked until then, so another relay
+// waits for them instead of publishing them again.
+func (r *OutboxRepositoryImpl) RelayOutbox(ctx context.Context, limit int, publish func(events []*model.OutboxEvent) (int, error)) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, event_id, event_type, payload, attempts, created_at
+		 FROM event_outbox
+		 WHERE published_at IS NULL
+		 ORDER BY id
+		 LIMIT $1
+		 FOR UPDATE`,
+		limit)
+	if err != nil {
+		return 0, err
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.OutboxEvent, error) {
+		var e model.OutboxEvent
+		err := row.Scan(&e.ID, &e.EventID, &e.EventType, &e.Payload, &e.Attempts, &e.Created)
+		return &e, err
+	})
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published, publishErr := publish(events)
+	if published > 0 {
+		ids := make([]int64, published)
+		for i, e := range events[:published] {
+			ids[i] = e.ID
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE event_outbox SET published_at = CURRENT_TIMESTAMP WHERE id = ANY($1)`,
+			ids); err != nil {
+			return 0, err
+		}
+	}
+	if publishErr != nil && published < len(events) {
+		if _, err := tx.Exec(ctx,
+			`UPDATE event_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`,
+			events[published].ID, publishErr.Error()); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// PurgeOutbox deletes events published before the given time
+func (r *OutboxRepositoryImpl) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM event_outbox WHERE published_at < $1`,
+		before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// addSQLOutboxEvent stores an event in the outbox on MySQL or SQLite
+func addSQLOutboxEvent(ctx context.Context, q sqlQuerier, event *model.OutboxEvent) error {
+	event.Created = time.Now().UTC()
+	result, err := q.ExecContext(ctx,
+		`INSERT INTO event_outbox (event_id, event_type, payload, last_error, created_at)
+		 VALUES (?, ?, ?, '', ?)`,
+		event.EventID, event.EventType, string(event.Payload), event.Created)
+	if err != nil {
+		return err
+	}
+	event.ID, err = result.LastInsertId()
+	return err
+}
+
+// relaySQLOutbox relays events on MySQL or SQLite like RelayOutbox, with
+// lock appended to the query selecting them, e.g. FOR UPDATE. It returns the
+// error that stopped publish separately from the error of the statements.
+func relaySQLOutbox(ctx context.Context, q sqlQuerier, lock string, limit int, publish func(events []*model.OutboxEvent) (int, error)) (published int, publishErr, err error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, event_id, event_type, payload, attempts, created_at
+		 FROM event_outbox
+		 WHERE published_at IS NULL
+		 ORDER BY id
+		 LIMIT ? `+lock,
+		limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	var events []*model.OutboxEvent
+	for rows.Next() {
+		var e model.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.EventID, &e.EventType, &e.Payload, &e.Attempts, &e.Created); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		events = append(events, &e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(events) == 0 {
+		return 0, nil, err
+	}
+
+	published, publishErr = publish(events)
+	now := time.Now().UTC()
+	for _, e := range events[:published] {
+		if _, err := q.ExecContext(ctx,
+			`UPDATE event_outbox SET published_at = ? WHERE id = ?`,
+			now, e.ID); err != nil {
+			return 0, nil, err
+		}
+	}
+	if publishErr != nil && published < len(events) {
+		if _, err := q.ExecContext(ctx,
+			`UPDATE event_outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?`,
+			publishErr.Error(), events[published].ID); err != nil {
+			return 0, nil, err
+		}
+	}
+	return published, publishErr, nil
+}
+
+// purgeSQLOutbox deletes published events on MySQL or SQLite like PurgeOutbox
+func purgeSQLOutbox(ctx context.Context, q sqlQuerier, before time.Time) (int64, error) {
+	result, err := q.ExecContext(ctx,
+		`DELETE FROM event_outbox WHERE published_at < ?`,
+		before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MySQLOutboxRepository implements the OutboxRepository interface on MySQL and MariaDB
+type MySQLOutboxRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLOutboxRepository implements OutboxRepository interface
+var _ interfaces.OutboxRepository = (*MySQLOutboxRepository)(nil)
+
+// NewMySQLOutboxRepository creates a new OutboxRepository backed by MySQL
+func NewMySQLOutboxRepository(db *database.MySQL) interfaces.OutboxRepository {
+	return &MySQLOutboxRepository{db: db}
+}
+
+// RelayOutbox passes the oldest unpublished events to publish and marks the
+// ones it published. The events stay locked until then, so another relay
+// waits for them instead of publishing them again.
+func (r *MySQLOutboxRepository) RelayOutbox(ctx context.Context, limit int, publish func(events []*model.OutboxEvent) (int, error)) (int, error) {
+	var published int
+	var publishErr error
+	err := withSQLTx(ctx, r.db.DB, r.db.DB, func(tx sqlQuerier) error {
+		var err error
+		published, publishErr, err = relaySQLOutbox(ctx, tx, "FOR UPDATE", limit, publish)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// PurgeOutbox deletes events published before the given time
+func (r *MySQLOutboxRepository) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	return purgeSQLOutbox(ctx, r.db.DB, before)
+}
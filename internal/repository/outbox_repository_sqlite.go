@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// SQLiteOutboxRepository implements the OutboxRepository interface on SQLite
+type SQLiteOutboxRepository struct {
+	db *database.SQLite
+}
+
+// Verify that SQLiteOutboxRepository implements OutboxRepository interface
+var _ interfaces.OutboxRepository = (*SQLiteOutboxRepository)(nil)
+
+// NewSQLiteOutboxRepository creates a new OutboxRepository backed by SQLite
+func NewSQLiteOutboxRepository(db *database.SQLite) interfaces.OutboxRepository {
+	return &SQLiteOutboxRepository{db: db}
+}
+
+// RelayOutbox passes the oldest unpublished events to publish and marks the
+// ones it published. SQLite serves a single instance, so the events are not
+// locked, and no transaction holds the only connection while publishing.
+func (r *SQLiteOutboxRepository) RelayOutbox(ctx context.Context, limit int, publish func(events []*model.OutboxEvent) (int, error)) (int, error) {
+	published, publishErr, err := relaySQLOutbox(ctx, r.db.DB, "", limit, publish)
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// PurgeOutbox deletes events published before the given time
+func (r *SQLiteOutboxRepository) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	return purgeSQLOutbox(ctx, r.db.DB, before)
+}
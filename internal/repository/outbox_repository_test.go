@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	repo, outbox := setupTestRepos(t)
+	ctx := context.Background()
+
+	// addEvent stores an event in a transaction that commits unless fail is set
+	addEvent := func(eventID string, fail bool) {
+		t.Helper()
+		errRollback := errors.New("rollback")
+		err := repo.WithTx(ctx, func(tx interfaces.UserRepository) error {
+			stored, ok := tx.(interfaces.EventOutbox)
+			if !ok {
+				t.Fatalf("%T does not keep an outbox", tx)
+			}
+			event := &model.OutboxEvent{EventID: eventID, EventType: "user.login", Payload: []byte(`{"id":"` + eventID + `"}`)}
+			if err := stored.AddOutboxEvent(ctx, event); err != nil {
+				return err
+			}
+			if fail {
+				return errRollback
+			}
+			return nil
+		})
+		if err != nil && (!fail || err != errRollback) {
+			t.Fatalf("Failed to add outbox event: %v", err)
+		}
+	}
+	addEvent("first", false)
+	addEvent("rolled-back", true)
+	addEvent("second", false)
+
+	// relay passes the unpublished events to publish, returning their IDs
+	relay := func(publish func(events []*model.OutboxEvent) (int, error)) ([]string, int, error) {
+		t.Helper()
+		var seen []string
+		published, err := outbox.RelayOutbox(ctx, 10, func(events []*model.OutboxEvent) (int, error) {
+			for _, e := range events {
+				seen = append(seen, e.EventID)
+			}
+			return publish(events)
+		})
+		return seen, published, err
+	}
+
+	errUnavailable := errors.New("unavailable")
+	seen, published, err := relay(func([]*model.OutboxEvent) (int, error) { return 1, errUnavailable })
+	if err != errUnavailable || published != 1 {
+		t.Fatalf("RelayOutbox() = %d, %v, want 1, %v", published, err, errUnavailable)
+	}
+	if len(seen) != 2 || seen[0] != "first" || seen[1] != "second" {
+		t.Fatalf("RelayOutbox() passed %v, want the committed events in order", seen)
+	}
+
+	seen, published, err = relay(func(events []*model.OutboxEvent) (int, error) {
+		if events[0].Attempts != 1 {
+			t.Errorf("Attempts = %d, want 1 after a failed publish", events[0].Attempts)
+		}
+		return len(events), nil
+	})
+	if err != nil || published != 1 || len(seen) != 1 || seen[0] != "second" {
+		t.Fatalf("RelayOutbox() = %v, %d, %v, want the unpublished event", seen, published, err)
+	}
+
+	seen, _, err = relay(func(events []*model.OutboxEvent) (int, error) { return len(events), nil })
+	if err != nil || len(seen) != 0 {
+		t.Fatalf("RelayOutbox() = %v, %v, want no events left", seen, err)
+	}
+
+	if purged, err := outbox.PurgeOutbox(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("PurgeOutbox(an hour ago) = %d, %v, want 0", purged, err)
+	}
+	if purged, err := outbox.PurgeOutbox(ctx, time.Now().Add(time.Hour)); err != nil || purged != 2 {
+		t.Errorf("PurgeOutbox(in an hour) = %d, %v, want 2", purged, err)
+	}
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisSessionStore keeps sessions in Redis under keys that expire with the
+// session, so validating a token is a single EXISTS. A sorted set per user
+// indexes the user's sessions by expiry for listing and revoking them.
+// Revoked sessions are deleted rather than flagged.
+type RedisSessionStore struct {
+	client redis.UniversalClient
+}
+
+// Verify that RedisSessionStore implements SessionStore interface
+var _ interfaces.SessionStore = (*RedisSessionStore)(nil)
+
+// NewRedisSessionStore creates a session store using client
+func NewRedisSessionStore(client redis.UniversalClient) interfaces.SessionStore {
+	return &RedisSessionStore{client: client}
+}
+
+func redisSessionKey(tokenID string) string {
+	return "session:" + tokenID
+}
+
+func redisSessionIndexKey(userID int64) string {
+	return "session_index:" + strconv.FormatInt(userID, 10)
+}
+
+// CreateSession stores a session until it expires. Sessions that have
+// already expired are not stored.
+func (s *RedisSessionStore) CreateSession(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	// Session IDs only order a user's sessions for paging
+	id, err := s.client.Incr(ctx, "session_seq").Result()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(model.Session{ID: id, UserID: userID, DeviceFingerprint: deviceFingerprint, RememberMe: rememberMe, Created: time.Now().UTC(), ExpiresAt: expiresAt.UTC()})
+	if err != nil {
+		return err
+	}
+
+	// The keys may live on different cluster nodes, so they are pipelined rather than sent in MULTI
+	index := redisSessionIndexKey(userID)
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisSessionKey(tokenID), data, ttl)
+		pipe.ZAdd(ctx, index, redis.Z{Score: float64(expiresAt.UnixMilli()), Member: tokenID})
+		// The index lives as long as the user's longest session
+		pipe.ExpireNX(ctx, index, ttl)
+		pipe.ExpireGT(ctx, index, ttl)
+		return nil
+	})
+	return err
+}
+
+// RevokeSession deletes a session
+func (s *RedisSessionStore) RevokeSession(ctx context.Context, tokenID string) error {
+	n, err := s.client.Del(ctx, redisSessionKey(tokenID)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions deletes every session of a user and returns how many were active
+func (s *RedisSessionStore) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	index := redisSessionIndexKey(userID)
+	tokens, err := s.client.ZRange(ctx, index, 0, -1).Result()
+	if err != nil || len(tokens) == 0 {
+		return 0, err
+	}
+
+	deletes := make([]*redis.IntCmd, len(tokens))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tokenID := range tokens {
+			deletes[i] = pipe.Del(ctx, redisSessionKey(tokenID))
+		}
+		// Only the sessions read above, so one created meanwhile stays indexed
+		pipe.ZRem(ctx, index, stringsToAny(tokens)...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var revoked int64
+	for _, del := range deletes {
+		revoked += del.Val()
+	}
+	return revoked, nil
+}
+
+// ListSessions returns a page of a user's active sessions, oldest first, with
+// the cursor of the next page
+func (s *RedisSessionStore) ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	after, err := page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	index := redisSessionIndexKey(userID)
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	tokens, err := s.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	gets := make([]*redis.StringCmd, len(tokens))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tokenID := range tokens {
+			gets[i] = pipe.Get(ctx, redisSessionKey(tokenID))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, "", err
+	}
+
+	var sessions []*model.Session
+	var revoked []any
+	for i, get := range gets {
+		data, err := get.Bytes()
+		if err == redis.Nil {
+			revoked = append(revoked, tokens[i])
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		var session model.Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, "", err
+		}
+		if session.ID > after {
+			session.TokenID = tokens[i]
+			sessions = append(sessions, &session)
+		}
+	}
+
+	// Drop the index entries of expired and revoked sessions
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, index, "-inf", now)
+		if len(revoked) > 0 {
+			pipe.ZRem(ctx, index, revoked...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	slices.SortFunc(sessions, func(a, b *model.Session) int { return cmp.Compare(a.ID, b.ID) })
+	sessions, next := pagination.Trim(sessions, page, sessionKey)
+	return sessions, next, nil
+}
+
+// IsSessionValid checks if a session exists; revoked and expired sessions do not
+func (s *RedisSessionStore) IsSessionValid(ctx context.Context, tokenID string) (bool, error) {
+	n, err := s.client.Exists(ctx, redisSessionKey(tokenID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func stringsToAny(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisSessionStore(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisSessionStore(client)
+
+	for _, tokenID := range []string{"a", "b", "c"} {
+		if err := store.CreateSession(ctx, 1, tokenID, "", false, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+	}
+	if err := store.CreateSession(ctx, 1, "short", "", false, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.CreateSession(ctx, 2, "other", "", false, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := store.CreateSession(ctx, 1, "expired", "", false, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if ttl := mr.TTL(redisSessionIndexKey(1)); ttl < 59*time.Minute {
+		t.Errorf("index TTL %v, want the longest session's", ttl)
+	}
+
+	// Sessions expire with their key
+	mr.FastForward(2 * time.Minute)
+	if valid, _ := store.IsSessionValid(ctx, "short"); valid {
+		t.Error("expected expired session to be invalid")
+	}
+	if valid, _ := store.IsSessionValid(ctx, "expired"); valid {
+		t.Error("expected session created expired to be invalid")
+	}
+
+	if err := store.RevokeSession(ctx, "b"); err != nil {
+		t.Fatalf("failed to revoke session: %v", err)
+	}
+	if err := store.RevokeSession(ctx, "b"); err != ErrSessionNotFound {
+		t.Errorf("revoking twice: got %v, want ErrSessionNotFound", err)
+	}
+
+	first, next, err := store.ListSessions(ctx, 1, pagination.Page{Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(first) != 1 || first[0].TokenID != "a" || next == "" {
+		t.Fatalf("first page = %v, %q; want session a and a cursor", first, next)
+	}
+	rest, next, err := store.ListSessions(ctx, 1, pagination.Page{Cursor: next, Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(rest) != 1 || rest[0].TokenID != "c" || next != "" {
+		t.Errorf("second page = %v, %q; want only session c", rest, next)
+	}
+
+	revoked, err := store.RevokeAllSessions(ctx, 1)
+	if err != nil || revoked != 2 {
+		t.Errorf("RevokeAllSessions = %d, %v; want 2", revoked, err)
+	}
+	for tokenID, want := range map[string]bool{"a": false, "c": false, "other": true} {
+		if valid, _ := store.IsSessionValid(ctx, tokenID); valid != want {
+			t.Errorf("session %s valid = %v, want %v", tokenID, valid, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// Errors returned by the tenant repository
+var (
+	ErrTenantNotFound      = errors.New("tenant not found")
+	ErrDuplicateTenantSlug = errors.New("tenant slug already exists")
+)
+
+// TenantRepositoryImpl implements the TenantRepository interface
+type TenantRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that TenantRepositoryImpl implements TenantRepository interface
+var _ interfaces.TenantRepository = (*TenantRepositoryImpl)(nil)
+
+// NewTenantRepository creates a new TenantRepository instance
+func NewTenantRepository(db *database.DB) interfaces.TenantRepository {
+	return &TenantRepositoryImpl{db: db}
+}
+
+// OnboardTenant creates a tenant together with its first admin user, in one
+// transaction so a tenant never exists without an admin
+func (r *TenantRepositoryImpl) OnboardTenant(ctx context.Context, tenant *model.Tenant, adminEmail, adminPasswordHash string) (*model.User, error) {
+	settings, err := json.Marshal(tenant.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO tenants (slug, name, settings) 
+		 VALUES ($1, $2, $3) 
+		 RETURNING id, status, created_at`,
+		tenant.Slug, tenant.Name, settings).Scan(&tenant.ID, &tenant.Status, &tenant.Created)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrDuplicateTenantSlug
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var admin model.User
+	err = tx.QueryRow(ctx,
+		`INSERT INTO users (email, canonical_email, password_hash, tenant_id, role) 
+		 VALUES ($1, $2, $3, $4, $5) 
+		 RETURNING id, email, created_at, type, role, tenant_id`,
+		adminEmail, model.NormalizeEmail(adminEmail), adminPasswordHash, tenant.ID, model.RoleAdmin).Scan(&admin.ID, &admin.Email, &admin.Created, &admin.Type, &admin.Role, &admin.TenantID)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// GetTenant retrieves a tenant by ID
+func (r *TenantRepositoryImpl) GetTenant(ctx context.Context, tenantID int64) (*model.Tenant, error) {
+	return scanTenant(r.db.Pool.QueryRow(ctx,
+		`SELECT id, slug, name, status, settings, created_at 
+		 FROM tenants 
+		 WHERE id = $1`,
+		tenantID))
+}
+
+// ListTenants returns every tenant
+func (r *TenantRepositoryImpl) ListTenants(ctx context.Context) ([]*model.Tenant, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT id, slug, name, status, settings, created_at 
+		 FROM tenants 
+		 ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []*model.Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// scanTenant scans a tenant row and decodes its settings
+func scanTenant(row pgx.Row) (*model.Tenant, error) {
+	var tenant model.Tenant
+	var settings []byte
+	err := row.Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.Status, &settings, &tenant.Created)
+
+	if noRows(err) {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(settings, &tenant.Settings); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// UpdateTenantSettings replaces a tenant's settings
+func (r *TenantRepositoryImpl) UpdateTenantSettings(ctx context.Context, tenantID int64, settings model.TenantSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Pool.Exec(ctx,
+		`UPDATE tenants 
+		 SET settings = $2 
+		 WHERE id = $1`,
+		tenantID, data)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// SetTenantStatus activates or suspends a tenant
+func (r *TenantRepositoryImpl) SetTenantStatus(ctx context.Context, tenantID int64, status string) error {
+	result, err := r.db.Pool.Exec(ctx,
+		`UPDATE tenants 
+		 SET status = $2 
+		 WHERE id = $1`,
+		tenantID, status)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// RevokeTenantSessions revokes every active session of the tenant's users
+func (r *TenantRepositoryImpl) RevokeTenantSessions(ctx context.Context, tenantID int64) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx,
+		`UPDATE sessions s 
+		 SET is_revoked = true 
+		 FROM users u 
+		 WHERE s.user_id = u.id AND u.tenant_id = $1 
+		   AND s.is_revoked = false AND s.expires_at > CURRENT_TIMESTAMP`,
+		tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteTenant deletes a tenant. Its users, and through them their sessions,
+// identities, and API keys, are deleted by cascade. It returns how many users were deleted.
+func (r *TenantRepositoryImpl) DeleteTenant(ctx context.Context, tenantID int64) (int64, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var users int64
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM users WHERE tenant_id = $1`,
+		tenantID).Scan(&users); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(ctx,
+		`DELETE FROM tenants 
+		 WHERE id = $1`,
+		tenantID)
+	if err != nil {
+		return 0, err
+	}
+	if result.RowsAffected() == 0 {
+		return 0, ErrTenantNotFound
+	}
+
+	return users, tx.Commit(ctx)
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MySQLTenantRepository implements the TenantRepository interface on MySQL and MariaDB
+type MySQLTenantRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLTenantRepository implements TenantRepository interface
+var _ interfaces.TenantRepository = (*MySQLTenantRepository)(nil)
+
+// NewMySQLTenantRepository creates a new TenantRepository backed by MySQL
+func NewMySQLTenantRepository(db *database.MySQL) interfaces.TenantRepository {
+	return &MySQLTenantRepository{db: db}
+}
+
+// OnboardTenant creates a tenant together with its first admin user, in one
+// transaction so a tenant never exists without an admin
+func (r *MySQLTenantRepository) OnboardTenant(ctx context.Context, tenant *model.Tenant, adminEmail, adminPasswordHash string) (*model.User, error) {
+	settings, err := json.Marshal(tenant.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO tenants (slug, name, settings)
+		 VALUES (?, ?, ?)`,
+		tenant.Slug, tenant.Name, string(settings))
+	if database.IsUniqueViolation(err) {
+		return nil, ErrDuplicateTenantSlug
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tenant.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx,
+		`SELECT status, created_at FROM tenants WHERE id = ?`,
+		tenant.ID).Scan(&tenant.Status, &tenant.Created); err != nil {
+		return nil, err
+	}
+
+	result, err = tx.ExecContext(ctx,
+		`INSERT INTO users (email, canonical_email, password_hash, tenant_id, role)
+		 VALUES (?, ?, ?, ?, ?)`,
+		adminEmail, model.NormalizeEmail(adminEmail), adminPasswordHash, tenant.ID, model.RoleAdmin)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+	adminID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	var admin model.User
+	if err := tx.QueryRowContext(ctx,
+		`SELECT id, email, created_at, type, role, tenant_id FROM users WHERE id = ?`,
+		adminID).Scan(&admin.ID, &admin.Email, &admin.Created, &admin.Type, &admin.Role, &admin.TenantID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// GetTenant retrieves a tenant by ID
+func (r *MySQLTenantRepository) GetTenant(ctx context.Context, tenantID int64) (*model.Tenant, error) {
+	return scanTenant(r.db.DB.QueryRowContext(ctx,
+		`SELECT id, slug, name, status, settings, created_at
+		 FROM tenants
+		 WHERE id = ?`,
+		tenantID))
+}
+
+// ListTenants returns every tenant
+func (r *MySQLTenantRepository) ListTenants(ctx context.Context) ([]*model.Tenant, error) {
+	rows, err := r.db.DB.QueryContext(ctx,
+		`SELECT id, slug, name, status, settings, created_at
+		 FROM tenants
+		 ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []*model.Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// UpdateTenantSettings replaces a tenant's settings
+func (r *MySQLTenantRepository) UpdateTenantSettings(ctx context.Context, tenantID int64, settings model.TenantSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	return r.updateTenant(ctx,
+		`UPDATE tenants
+		 SET settings = ?
+		 WHERE id = ?`,
+		string(data), tenantID)
+}
+
+// SetTenantStatus activates or suspends a tenant
+func (r *MySQLTenantRepository) SetTenantStatus(ctx context.Context, tenantID int64, status string) error {
+	return r.updateTenant(ctx,
+		`UPDATE tenants
+		 SET status = ?
+		 WHERE id = ?`,
+		status, tenantID)
+}
+
+// updateTenant runs an update of one tenant, reporting ErrTenantNotFound when no row matched
+func (r *MySQLTenantRepository) updateTenant(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// RevokeTenantSessions revokes every active session of the tenant's users
+func (r *MySQLTenantRepository) RevokeTenantSessions(ctx context.Context, tenantID int64) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE sessions s
+		 JOIN users u ON s.user_id = u.id
+		 SET s.is_revoked = true
+		 WHERE u.tenant_id = ?
+		   AND s.is_revoked = false AND s.expires_at > CURRENT_TIMESTAMP(6)`,
+		tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteTenant deletes a tenant. Its users, and through them their sessions,
+// identities, and API keys, are deleted by cascade. It returns how many users were deleted.
+func (r *MySQLTenantRepository) DeleteTenant(ctx context.Context, tenantID int64) (int64, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var users int64
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE tenant_id = ?`,
+		tenantID).Scan(&users); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM tenants
+		 WHERE id = ?`,
+		tenantID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrTenantNotFound
+	}
+
+	return users, tx.Commit()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// UsageRepositoryImpl implements the UsageRepository interface
+type UsageRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that UsageRepositoryImpl implements UsageRepository interface
+var _ interfaces.UsageRepository = (*UsageRepositoryImpl)(nil)
+
+// NewUsageRepository creates a new UsageRepository instance
+func NewUsageRepository(db *database.DB) interfaces.UsageRepository {
+	return &UsageRepositoryImpl{db: db}
+}
+
+// AddUsage adds counter increments and active users for a period in one transaction
+func (r *UsageRepositoryImpl) AddUsage(ctx context.Context, period time.Time, counts []model.UsageCount, active []model.ActiveUser) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, c := range counts {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO usage_counters (period, tenant_id, client_id, metric, count) 
+			 VALUES ($1, $2, $3, $4, $5) 
+			 ON CONFLICT (period, tenant_id, client_id, metric) 
+			 DO UPDATE SET count = usage_counters.count + EXCLUDED.count`,
+			period, c.TenantID, c.ClientID, c.Metric, c.Count)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, a := range active {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO usage_active_users (period, tenant_id, client_id, user_id) 
+			 VALUES ($1, $2, $3, $4) 
+			 ON CONFLICT DO NOTHING`,
+			period, a.TenantID, a.ClientID, a.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetUsage returns the usage of a period per tenant and per tenant and client
+func (r *UsageRepositoryImpl) GetUsage(ctx context.Context, period time.Time) (*model.UsageReport, error) {
+	clients := make(map[model.UsageKey]*model.UsageRecord)
+	err := r.collectUsage(ctx, clients,
+		`SELECT tenant_id, client_id, metric, count FROM usage_counters WHERE period = $1`,
+		`SELECT tenant_id, client_id, COUNT(*) FROM usage_active_users 
+		 WHERE period = $1 GROUP BY tenant_id, client_id`,
+		period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Distinct users across clients, so a user is billed once per tenant
+	tenants := make(map[model.UsageKey]*model.UsageRecord)
+	err = r.collectUsage(ctx, tenants,
+		`SELECT tenant_id, '', metric, SUM(count) FROM usage_counters 
+		 WHERE period = $1 GROUP BY tenant_id, metric`,
+		`SELECT tenant_id, '', COUNT(DISTINCT user_id) FROM usage_active_users 
+		 WHERE period = $1 GROUP BY tenant_id`,
+		period)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UsageReport{
+		Period:  period,
+		Tenants: sortedUsage(tenants),
+		Clients: sortedUsage(clients),
+	}, nil
+}
+
+// collectUsage adds the rows of a counter query and an active user query to records
+func (r *UsageRepositoryImpl) collectUsage(ctx context.Context, records map[model.UsageKey]*model.UsageRecord, countersQuery, activeQuery string, period time.Time) error {
+	record := func(key model.UsageKey) *model.UsageRecord {
+		if records[key] == nil {
+			records[key] = &model.UsageRecord{UsageKey: key}
+		}
+		return records[key]
+	}
+
+	rows, err := r.db.Pool.Query(ctx, countersQuery, period)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key model.UsageKey
+		var metric string
+		var count int64
+		if err := rows.Scan(&key.TenantID, &key.ClientID, &metric, &count); err != nil {
+			return err
+		}
+		record(key).Add(metric, count)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = r.db.Pool.Query(ctx, activeQuery, period)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key model.UsageKey
+		var active int64
+		if err := rows.Scan(&key.TenantID, &key.ClientID, &active); err != nil {
+			return err
+		}
+		record(key).MonthlyActiveUsers = active
+	}
+	return rows.Err()
+}
+
+// sortedUsage returns records ordered by tenant and client
+func sortedUsage(records map[model.UsageKey]*model.UsageRecord) []*model.UsageRecord {
+	sorted := make([]*model.UsageRecord, 0, len(records))
+	for _, record := range records {
+		sorted = append(sorted, record)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].TenantID != sorted[j].TenantID {
+			return sorted[i].TenantID < sorted[j].TenantID
+		}
+		return sorted[i].ClientID < sorted[j].ClientID
+	})
+	return sorted
+}
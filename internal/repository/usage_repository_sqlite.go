@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// SQLiteUsageRepository implements the UsageRepository interface on SQLite
+type SQLiteUsageRepository struct {
+	db *database.SQLite
+}
+
+// Verify that SQLiteUsageRepository implements UsageRepository interface
+var _ interfaces.UsageRepository = (*SQLiteUsageRepository)(nil)
+
+// NewSQLiteUsageRepository creates a new UsageRepository backed by SQLite
+func NewSQLiteUsageRepository(db *database.SQLite) interfaces.UsageRepository {
+	return &SQLiteUsageRepository{db: db}
+}
+
+// AddUsage adds counter increments and active users for a period in one transaction
+func (r *SQLiteUsageRepository) AddUsage(ctx context.Context, period time.Time, counts []model.UsageCount, active []model.ActiveUser) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range counts {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO usage_counters (period, tenant_id, client_id, metric, count)
+			 VALUES (?, ?, ?, ?, ?)
+			 ON CONFLICT (period, tenant_id, client_id, metric)
+			 DO UPDATE SET count = usage_counters.count + excluded.count`,
+			day(period), c.TenantID, c.ClientID, c.Metric, c.Count)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, a := range active {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO usage_active_users (period, tenant_id, client_id, user_id)
+			 VALUES (?, ?, ?, ?)
+			 ON CONFLICT DO NOTHING`,
+			day(period), a.TenantID, a.ClientID, a.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUsage returns the usage of a period per tenant and per tenant and client
+func (r *SQLiteUsageRepository) GetUsage(ctx context.Context, period time.Time) (*model.UsageReport, error) {
+	clients := make(map[model.UsageKey]*model.UsageRecord)
+	err := r.collectUsage(ctx, clients,
+		`SELECT tenant_id, client_id, metric, count FROM usage_counters WHERE period = ?`,
+		`SELECT tenant_id, client_id, COUNT(*) FROM usage_active_users
+		 WHERE period = ? GROUP BY tenant_id, client_id`,
+		period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Distinct users across clients, so a user is billed once per tenant
+	tenants := make(map[model.UsageKey]*model.UsageRecord)
+	err = r.collectUsage(ctx, tenants,
+		`SELECT tenant_id, '', metric, SUM(count) FROM usage_counters
+		 WHERE period = ? GROUP BY tenant_id, metric`,
+		`SELECT tenant_id, '', COUNT(DISTINCT user_id) FROM usage_active_users
+		 WHERE period = ? GROUP BY tenant_id`,
+		period)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UsageReport{
+		Period:  period,
+		Tenants: sortedUsage(tenants),
+		Clients: sortedUsage(clients),
+	}, nil
+}
+
+// collectUsage adds the rows of a counter query and an active user query to records
+func (r *SQLiteUsageRepository) collectUsage(ctx context.Context, records map[model.UsageKey]*model.UsageRecord, countersQuery, activeQuery string, period time.Time) error {
+	record := func(key model.UsageKey) *model.UsageRecord {
+		if records[key] == nil {
+			records[key] = &model.UsageRecord{UsageKey: key}
+		}
+		return records[key]
+	}
+
+	err := r.scanRows(ctx, countersQuery, period, func(rows *sql.Rows) error {
+		var key model.UsageKey
+		var metric string
+		var count int64
+		if err := rows.Scan(&key.TenantID, &key.ClientID, &metric, &count); err != nil {
+			return err
+		}
+		record(key).Add(metric, count)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.scanRows(ctx, activeQuery, period, func(rows *sql.Rows) error {
+		var key model.UsageKey
+		var active int64
+		if err := rows.Scan(&key.TenantID, &key.ClientID, &active); err != nil {
+			return err
+		}
+		record(key).MonthlyActiveUsers = active
+		return nil
+	})
+}
+
+// scanRows runs a query for a period and calls scan for each row
+func (r *SQLiteUsageRepository) scanRows(ctx context.Context, query string, period time.Time, scan func(*sql.Rows) error) error {
+	rows, err := r.db.DB.QueryContext(ctx, query, day(period))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// day formats a period for a DATE column, which SQLite stores as text
+func day(period time.Time) string {
+	return period.UTC().Format(time.DateOnly)
+}
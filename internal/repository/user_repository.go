@@ -2,14 +2,18 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Stewz00/go-auth-service/internal/database"
 	"github.com/Stewz00/go-auth-service/internal/interfaces"
 	"github.com/Stewz00/go-auth-service/internal/model"
-	"github.com/jackc/pgconn"
-	"github.com/jackc/pgx/v4"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/jackc/pgx/v5"
 )
 
 // Common errors that can be returned by the repository
@@ -18,32 +22,67 @@ var (
 	ErrDuplicateEmail  = errors.New("email already exists")
 	ErrSessionNotFound = errors.New("session not found")
 	ErrTooManyAttempts = errors.New("too many failed login attempts")
+	ErrTenantSuspended = errors.New("tenant is suspended")
+	ErrUserDisabled    = errors.New("user is disabled")
 )
 
+// noRows reports whether a single-row query found nothing, for scanners
+// shared by the PostgreSQL and MySQL repositories
+func noRows(err error) bool {
+	return err == pgx.ErrNoRows || err == sql.ErrNoRows
+}
+
 // UserRepositoryImpl implements the UserRepository interface
 type UserRepositoryImpl struct {
 	db *database.DB
+	q  pgxQuerier
 }
 
 // Verify that UserRepositoryImpl implements UserRepository interface
 var _ interfaces.UserRepository = (*UserRepositoryImpl)(nil)
 
+// Verify that UserRepositoryImpl implements LoginRecorder interface
+var _ interfaces.LoginRecorder = (*UserRepositoryImpl)(nil)
+
+// Verify that UserRepositoryImpl implements EventOutbox interface
+var _ interfaces.EventOutbox = (*UserRepositoryImpl)(nil)
+
 // NewUserRepository creates a new UserRepository instance
 func NewUserRepository(db *database.DB) interfaces.UserRepository {
-	return &UserRepositoryImpl{db: db}
+	return &UserRepositoryImpl{db: db, q: db.Pool}
+}
+
+// WithTx runs fn with a repository whose statements share one transaction,
+// committed when fn returns nil and rolled back otherwise. Inside a
+// transaction it runs fn in that transaction.
+func (r *UserRepositoryImpl) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	if _, ok := r.q.(pgx.Tx); ok {
+		return fn(r)
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&UserRepositoryImpl{db: r.db, q: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // CreateUser creates a new user in the database
 func (r *UserRepositoryImpl) CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error) {
 	var user model.User
-	err := r.db.Pool.QueryRow(ctx,
-		`INSERT INTO users (email, password_hash) 
-		 VALUES ($1, $2) 
-		 RETURNING id, email, created_at`,
-		email, passwordHash).Scan(&user.ID, &user.Email, &user.Created)
+	err := r.q.QueryRow(ctx,
+		`INSERT INTO users (email, canonical_email, password_hash) 
+		 VALUES ($1, $2, $3) 
+		 RETURNING id, email, created_at, type, role`,
+		email, model.NormalizeEmail(email), passwordHash).Scan(&user.ID, &user.Email, &user.Created, &user.Type, &user.Role)
 
 	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+		if database.IsUniqueViolation(err) {
 			return nil, ErrDuplicateEmail
 		}
 		return nil, err
@@ -52,23 +91,41 @@ func (r *UserRepositoryImpl) CreateUser(ctx context.Context, email, passwordHash
 	return &user, nil
 }
 
-// GetUserByEmail retrieves a user by their email address
-func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+// userColumns selects a user with the status of its tenant, for scanUser
+const userColumns = `u.id, u.email, u.password_hash, u.created_at, u.failed_login_attempts, u.is_active,
+		u.deleted_at IS NOT NULL, u.disabled_at IS NOT NULL, u.is_canary, u.type, u.role, u.tenant_id, COALESCE(t.status, 'active'),
+		u.password_changed_at, u.password_expires_at, u.last_login, u.display_name, u.given_name, u.family_name, u.locale, u.timezone, u.avatar_url
+		 FROM users u
+		 LEFT JOIN tenants t ON t.id = u.tenant_id`
+
+// scanUser scans a row selected with userColumns. Soft-deleted users are
+// reported as not found. Locked and disabled users and users of suspended
+// tenants are reported as errors so they can never authenticate.
+func scanUser(row pgx.Row) (*model.User, error) {
 	var user model.User
-	var isActive bool
-	err := r.db.Pool.QueryRow(ctx,
-		`SELECT id, email, password_hash, created_at, failed_login_attempts, is_active 
-		 FROM users 
-		 WHERE email = $1`,
-		email).Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.FailedAttempts, &isActive)
+	var isActive, isDeleted, isDisabled bool
+	var tenantStatus string
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.FailedAttempts, &isActive,
+		&isDeleted, &isDisabled, &user.IsCanary, &user.Type, &user.Role, &user.TenantID, &tenantStatus,
+		&user.PasswordChangedAt, &user.PasswordExpiresAt, &user.LastLogin, &user.DisplayName, &user.GivenName, &user.FamilyName,
+		&user.Locale, &user.Timezone, &user.AvatarURL)
 
-	if err == pgx.ErrNoRows {
+	if noRows(err) {
 		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	if isDeleted {
+		return nil, ErrUserNotFound
+	}
+	if tenantStatus != model.TenantStatusActive {
+		return nil, ErrTenantSuspended
+	}
+	if isDisabled {
+		return nil, ErrUserDisabled
+	}
 	if !isActive {
 		return nil, ErrTooManyAttempts
 	}
@@ -76,9 +133,473 @@ func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (
 	return &user, nil
 }
 
+// GetUserByEmail retrieves a user by their email address, ignoring case
+func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return scanUser(r.q.QueryRow(ctx,
+		`SELECT `+userColumns+`
+		 WHERE u.canonical_email = $1`,
+		model.NormalizeEmail(email)))
+}
+
+// GetUserByID retrieves a user by their ID
+func (r *UserRepositoryImpl) GetUserByID(ctx context.Context, userID int64) (*model.User, error) {
+	return scanUser(r.q.QueryRow(ctx,
+		`SELECT `+userColumns+`
+		 WHERE u.id = $1`,
+		userID))
+}
+
+// SetCanary marks or unmarks a user as a canary account
+func (r *UserRepositoryImpl) SetCanary(ctx context.Context, userID int64, canary bool) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET is_canary = $2 
+		 WHERE id = $1`,
+		userID, canary)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// servicePasswordHash is stored for service accounts; it is not a valid bcrypt
+// hash, so no password can ever match it
+const servicePasswordHash = "!"
+
+// CreateServiceAccount creates a service account user that has no password
+func (r *UserRepositoryImpl) CreateServiceAccount(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	err := r.q.QueryRow(ctx,
+		`INSERT INTO users (email, canonical_email, password_hash, type) 
+		 VALUES ($1, $2, $3, $4) 
+		 RETURNING id, email, created_at, type, role`,
+		email, model.NormalizeEmail(email), servicePasswordHash, model.UserTypeService).Scan(&user.ID, &user.Email, &user.Created, &user.Type, &user.Role)
+
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrDuplicateEmail
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListServiceAccounts returns every service account, including locked ones
+func (r *UserRepositoryImpl) ListServiceAccounts(ctx context.Context) ([]*model.User, error) {
+	rows, err := r.q.Query(ctx,
+		`SELECT id, email, created_at, is_active, type 
+		 FROM users 
+		 WHERE type = $1 
+		 ORDER BY id`,
+		model.UserTypeService)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		var user model.User
+		var isActive bool
+		if err := rows.Scan(&user.ID, &user.Email, &user.Created, &isActive, &user.Type); err != nil {
+			return nil, err
+		}
+		user.IsLocked = !isActive
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// SetServiceAccountLocked locks or unlocks a service account
+func (r *UserRepositoryImpl) SetServiceAccountLocked(ctx context.Context, userID int64, locked bool) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET is_active = $2, 
+		     failed_login_attempts = 0 
+		 WHERE id = $1 AND type = $3`,
+		userID, !locked, model.UserTypeService)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteServiceAccount deletes a service account together with its API keys and sessions
+func (r *UserRepositoryImpl) DeleteServiceAccount(ctx context.Context, userID int64) error {
+	result, err := r.q.Exec(ctx,
+		`DELETE FROM users 
+		 WHERE id = $1 AND type = $2`,
+		userID, model.UserTypeService)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// adminUserColumns selects a user with its lock and disabled state, for scanAdminUser
+const adminUserColumns = `id, email, created_at, failed_login_attempts, is_active, disabled_at IS NOT NULL,
+		email_verified_at IS NOT NULL, deleted_at, is_canary, type, role, tenant_id, last_login,
+		display_name, given_name, family_name, locale, timezone, avatar_url
+		 FROM users`
+
+// scanAdminUser scans a row selected with adminUserColumns. Unlike scanUser it
+// reports locked and disabled users, without their password hash.
+func scanAdminUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+	var isActive bool
+	err := row.Scan(&user.ID, &user.Email, &user.Created, &user.FailedAttempts, &isActive, &user.IsDisabled,
+		&user.EmailVerified, &user.DeletedAt, &user.IsCanary, &user.Type, &user.Role, &user.TenantID, &user.LastLogin,
+		&user.DisplayName, &user.GivenName, &user.FamilyName, &user.Locale, &user.Timezone, &user.AvatarURL)
+	if noRows(err) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.IsLocked = !isActive
+	return &user, nil
+}
+
+// SearchUsers returns a page of the users matching filter, including locked
+// and disabled ones, ordered by ID, with the cursor of the next page.
+// Soft-deleted users are only returned when filter.Deleted is set.
+func (r *UserRepositoryImpl) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error) {
+	after, err := filter.Page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if after != 0 {
+		add("id > $%d", after)
+	}
+	add("(deleted_at IS NOT NULL) = $%d", filter.Deleted)
+	if filter.TenantID != nil {
+		add("tenant_id = $%d", *filter.TenantID)
+	}
+	if filter.EmailPrefix != "" {
+		add(`email LIKE $%d ESCAPE '\'`, escapeLike(filter.EmailPrefix)+"%")
+	}
+	if filter.Role != "" {
+		add("role = $%d", filter.Role)
+	}
+	if filter.Type != "" {
+		add("type = $%d", filter.Type)
+	}
+	if filter.Locked != nil {
+		add("is_active = $%d", !*filter.Locked)
+	}
+	if filter.Disabled != nil {
+		add("(disabled_at IS NOT NULL) = $%d", *filter.Disabled)
+	}
+	if filter.Verified != nil {
+		add("(email_verified_at IS NOT NULL) = $%d", *filter.Verified)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		add("created_at >= $%d", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		add("created_at < $%d", filter.CreatedBefore)
+	}
+
+	query := `SELECT ` + adminUserColumns + ` WHERE ` + strings.Join(where, " AND ")
+	args = append(args, filter.Page.Fetch())
+	query += fmt.Sprintf(` ORDER BY id LIMIT $%d`, len(args))
+
+	rows, err := r.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	users, next := pagination.Trim(users, filter.Page, userKey)
+	return users, next, nil
+}
+
+// userKey is the pagination key of users
+func userKey(user *model.User) int64 {
+	return user.ID
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetUserForAdmin retrieves a user by ID whatever its state, for administration
+func (r *UserRepositoryImpl) GetUserForAdmin(ctx context.Context, userID int64) (*model.User, error) {
+	return scanAdminUser(r.q.QueryRow(ctx,
+		`SELECT `+adminUserColumns+`
+		 WHERE id = $1`,
+		userID))
+}
+
+// SetUserDisabled disables or re-enables a user
+func (r *UserRepositoryImpl) SetUserDisabled(ctx context.Context, userID int64, disabled bool) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, CURRENT_TIMESTAMP) END,
+		     updated_at = CURRENT_TIMESTAMP 
+		 WHERE id = $1`,
+		userID, disabled)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetUserMetadata returns a user's metadata. Within a transaction, the user
+// is locked until it ends, so the metadata can be updated without losing
+// concurrent changes.
+func (r *UserRepositoryImpl) GetUserMetadata(ctx context.Context, userID int64) (*model.UserMetadata, error) {
+	return scanMetadata(r.q.QueryRow(ctx,
+		`SELECT metadata
+		 FROM users
+		 WHERE id = $1
+		 FOR UPDATE`,
+		userID))
+}
+
+// SetUserMetadata replaces a user's metadata
+func (r *UserRepositoryImpl) SetUserMetadata(ctx context.Context, userID int64, metadata *model.UserMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.q.Exec(ctx,
+		`UPDATE users
+		 SET metadata = $2,
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1`,
+		userID, data)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateUserProfile replaces a user's profile
+func (r *UserRepositoryImpl) UpdateUserProfile(ctx context.Context, userID int64, profile model.Profile) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users
+		 SET display_name = $2, given_name = $3, family_name = $4,
+		     locale = $5, timezone = $6, avatar_url = $7,
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1`,
+		userID, profile.DisplayName, profile.GivenName, profile.FamilyName,
+		profile.Locale, profile.Timezone, profile.AvatarURL)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// scanMetadata scans and decodes a metadata column, which MySQL leaves NULL
+// until metadata is first set
+func scanMetadata(row pgx.Row) (*model.UserMetadata, error) {
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		if noRows(err) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	var metadata model.UserMetadata
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return nil, err
+		}
+	}
+	if metadata.AppMetadata == nil {
+		metadata.AppMetadata = map[string]any{}
+	}
+	if metadata.UserMetadata == nil {
+		metadata.UserMetadata = map[string]any{}
+	}
+	return &metadata, nil
+}
+
+// UpdatePassword replaces a user's password hash, restarting its age, and
+// clears any lockout or forced expiry
+func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET password_hash = $2, 
+		     failed_login_attempts = 0, 
+		     is_active = true,
+		     password_changed_at = CURRENT_TIMESTAMP,
+		     password_expires_at = NULL,
+		     updated_at = CURRENT_TIMESTAMP 
+		 WHERE id = $1 AND type = $3`,
+		userID, passwordHash, model.UserTypeHuman)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ExpirePassword forces a user to change their password at the next sign-in
+func (r *UserRepositoryImpl) ExpirePassword(ctx context.Context, userID int64) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users
+		 SET password_expires_at = CURRENT_TIMESTAMP,
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND type = $2`,
+		userID, model.UserTypeHuman)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RehashPassword replaces a user's password hash with a stronger hash of the
+// same password, unless the password changed since oldHash was read
+func (r *UserRepositoryImpl) RehashPassword(ctx context.Context, userID int64, oldHash, newHash string) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2`,
+		userID, oldHash, newHash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UnlockUser clears the failed attempts of a user locked out by the lockout policy
+func (r *UserRepositoryImpl) UnlockUser(ctx context.Context, userID int64) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET failed_login_attempts = 0, 
+		     is_active = true 
+		 WHERE id = $1 AND type = $2`,
+		userID, model.UserTypeHuman)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SoftDeleteUser hides a user from sign-in and lookups until it is restored or purged
+func (r *UserRepositoryImpl) SoftDeleteUser(ctx context.Context, userID int64) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET deleted_at = CURRENT_TIMESTAMP, 
+		     updated_at = CURRENT_TIMESTAMP 
+		 WHERE id = $1 AND deleted_at IS NULL`,
+		userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RestoreUser undoes the soft deletion of a user
+func (r *UserRepositoryImpl) RestoreUser(ctx context.Context, userID int64) error {
+	result, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET deleted_at = NULL, 
+		     updated_at = CURRENT_TIMESTAMP 
+		 WHERE id = $1 AND deleted_at IS NOT NULL`,
+		userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedUsers permanently deletes users soft-deleted before the given
+// time, with their sessions, identities, and API keys, and returns how many
+// were deleted
+func (r *UserRepositoryImpl) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.q.Exec(ctx,
+		`DELETE FROM users 
+		 WHERE deleted_at < $1`,
+		before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// MarkEmailVerified records that an identity provider vouched for a user's email
+func (r *UserRepositoryImpl) MarkEmailVerified(ctx context.Context, userID int64) error {
+	_, err := r.q.Exec(ctx,
+		`UPDATE users 
+		 SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP) 
+		 WHERE id = $1`,
+		userID)
+	return err
+}
+
 // UpdateLastLogin updates the last login time and resets failed attempts
 func (r *UserRepositoryImpl) UpdateLastLogin(ctx context.Context, userID int64) error {
-	_, err := r.db.Pool.Exec(ctx,
+	_, err := r.q.Exec(ctx,
 		`UPDATE users 
 		 SET last_login = CURRENT_TIMESTAMP, 
 		     failed_login_attempts = 0 
@@ -87,40 +608,77 @@ func (r *UserRepositoryImpl) UpdateLastLogin(ctx context.Context, userID int64)
 	return err
 }
 
-// IncrementFailedAttempts increments the failed login attempts counter
-func (r *UserRepositoryImpl) IncrementFailedAttempts(ctx context.Context, userID int64) error {
-	var attempts int
-	err := r.db.Pool.QueryRow(ctx,
+// IncrementFailedAttempts increments the failed login attempts counter and
+// deactivates the account once the policy locks it
+func (r *UserRepositoryImpl) IncrementFailedAttempts(ctx context.Context, userID int64, policy model.LockoutPolicy) error {
+	var attempts int64
+	err := r.q.QueryRow(ctx,
 		`UPDATE users 
 		 SET failed_login_attempts = failed_login_attempts + 1,
-		     is_active = CASE WHEN failed_login_attempts + 1 >= 5 THEN false ELSE true END
+		     is_active = CASE WHEN failed_login_attempts + 1 >= $2 THEN false ELSE true END
 		 WHERE id = $1 
 		 RETURNING failed_login_attempts`,
-		userID).Scan(&attempts)
+		userID, policy.MaxFailedAttempts).Scan(&attempts)
 
 	if err != nil {
 		return err
 	}
 
-	if attempts >= 5 {
+	if policy.IsLocked(attempts) {
 		return ErrTooManyAttempts
 	}
 
 	return nil
 }
 
+// AddOutboxEvent implements interfaces.EventOutbox, storing the event in the
+// repository's transaction when it is in one
+func (r *UserRepositoryImpl) AddOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	return r.q.QueryRow(ctx,
+		`INSERT INTO event_outbox (event_id, event_type, payload)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, created_at`,
+		event.EventID, event.EventType, event.Payload).Scan(&event.ID, &event.Created)
+}
+
+// RecordLogin implements interfaces.LoginRecorder by sending both statements
+// of a sign-in as one batch: a single round trip that PostgreSQL runs as an
+// implicit transaction
+func (r *UserRepositoryImpl) RecordLogin(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	batch := &pgx.Batch{}
+	batch.Queue(
+		`UPDATE users 
+		 SET last_login = CURRENT_TIMESTAMP, 
+		     failed_login_attempts = 0 
+		 WHERE id = $1`,
+		userID)
+	batch.Queue(
+		`INSERT INTO sessions (user_id, token_id, device_fingerprint, remember_me, expires_at) 
+		 VALUES ($1, $2, $3, $4, $5)`,
+		userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
+
+	results := r.q.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
+
 // CreateSession creates a new session for a user
-func (r *UserRepositoryImpl) CreateSession(ctx context.Context, userID int64, tokenID string, expiresAt time.Time) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`INSERT INTO sessions (user_id, token_id, expires_at) 
-		 VALUES ($1, $2, $3)`,
-		userID, tokenID, expiresAt)
+func (r *UserRepositoryImpl) CreateSession(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	_, err := r.q.Exec(ctx,
+		`INSERT INTO sessions (user_id, token_id, device_fingerprint, remember_me, expires_at) 
+		 VALUES ($1, $2, $3, $4, $5)`,
+		userID, tokenID, deviceFingerprint, rememberMe, expiresAt)
 	return err
 }
 
 // RevokeSession marks a session as revoked
 func (r *UserRepositoryImpl) RevokeSession(ctx context.Context, tokenID string) error {
-	result, err := r.db.Pool.Exec(ctx,
+	result, err := r.q.Exec(ctx,
 		`UPDATE sessions 
 		 SET is_revoked = true 
 		 WHERE token_id = $1`,
@@ -132,12 +690,65 @@ func (r *UserRepositoryImpl) RevokeSession(ctx context.Context, tokenID string)
 	return err
 }
 
+// RevokeAllSessions revokes every active session for a user and returns how many were revoked
+func (r *UserRepositoryImpl) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	result, err := r.q.Exec(ctx,
+		`UPDATE sessions 
+		 SET is_revoked = true 
+		 WHERE user_id = $1 AND is_revoked = false AND expires_at > CURRENT_TIMESTAMP`,
+		userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// ListSessions returns a page of a user's active sessions, oldest first, with
+// the cursor of the next page
+func (r *UserRepositoryImpl) ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	after, err := page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.q.Query(ctx,
+		`SELECT id, user_id, token_id, device_fingerprint, remember_me, created_at, expires_at 
+		 FROM sessions 
+		 WHERE user_id = $1 AND id > $2 AND is_revoked = false AND expires_at > CURRENT_TIMESTAMP 
+		 ORDER BY id 
+		 LIMIT $3`,
+		userID, after, page.Fetch())
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.TokenID, &session.DeviceFingerprint, &session.RememberMe, &session.Created, &session.ExpiresAt); err != nil {
+			return nil, "", err
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	sessions, next := pagination.Trim(sessions, page, sessionKey)
+	return sessions, next, nil
+}
+
+// sessionKey is the pagination key of sessions
+func sessionKey(session *model.Session) int64 {
+	return session.ID
+}
+
 // IsSessionValid checks if a session is valid and not expired
 func (r *UserRepositoryImpl) IsSessionValid(ctx context.Context, tokenID string) (bool, error) {
 	var isRevoked bool
 	var expiresAt time.Time
 
-	err := r.db.Pool.QueryRow(ctx,
+	err := r.q.QueryRow(ctx,
 		`SELECT is_revoked, expires_at 
 		 FROM sessions 
 		 WHERE token_id = $1`,
@@ -0,0 +1,514 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// SQLiteUserRepository implements the UserRepository interface on SQLite
+type SQLiteUserRepository struct {
+	db *database.SQLite
+	q  sqlQuerier
+}
+
+// Verify that SQLiteUserRepository implements UserRepository interface
+var _ interfaces.UserRepository = (*SQLiteUserRepository)(nil)
+
+// Verify that SQLiteUserRepository implements EventOutbox interface
+var _ interfaces.EventOutbox = (*SQLiteUserRepository)(nil)
+
+// NewSQLiteUserRepository creates a new UserRepository backed by SQLite
+func NewSQLiteUserRepository(db *database.SQLite) interfaces.UserRepository {
+	return &SQLiteUserRepository{db: db, q: db.DB}
+}
+
+// WithTx runs fn with a repository whose statements share one transaction,
+// committed when fn returns nil and rolled back otherwise. Inside a
+// transaction it runs fn in that transaction.
+func (r *SQLiteUserRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	return withSQLTx(ctx, r.db.DB, r.q, func(tx sqlQuerier) error {
+		return fn(&SQLiteUserRepository{db: r.db, q: tx})
+	})
+}
+
+// CreateUser creates a new user in the database
+func (r *SQLiteUserRepository) CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error) {
+	return r.insertUser(ctx, email, passwordHash, model.UserTypeHuman)
+}
+
+// CreateServiceAccount creates a service account user that has no password
+func (r *SQLiteUserRepository) CreateServiceAccount(ctx context.Context, email string) (*model.User, error) {
+	return r.insertUser(ctx, email, servicePasswordHash, model.UserTypeService)
+}
+
+// insertUser inserts a user and reads back the columns filled in by defaults
+func (r *SQLiteUserRepository) insertUser(ctx context.Context, email, passwordHash, userType string) (*model.User, error) {
+	result, err := r.q.ExecContext(ctx,
+		`INSERT INTO users (email, canonical_email, password_hash, type)
+		 VALUES (?, ?, ?, ?)`,
+		email, model.NormalizeEmail(email), passwordHash, userType)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.User
+	err = r.q.QueryRowContext(ctx,
+		`SELECT id, email, created_at, type, role
+		 FROM users
+		 WHERE id = ?`,
+		id).Scan(&user.ID, &user.Email, &user.Created, &user.Type, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user by their email address, ignoring case
+func (r *SQLiteUserRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return scanUser(r.q.QueryRowContext(ctx,
+		`SELECT `+userColumns+`
+		 WHERE u.canonical_email = ?`,
+		model.NormalizeEmail(email)))
+}
+
+// GetUserByID retrieves a user by their ID
+func (r *SQLiteUserRepository) GetUserByID(ctx context.Context, userID int64) (*model.User, error) {
+	return scanUser(r.q.QueryRowContext(ctx,
+		`SELECT `+userColumns+`
+		 WHERE u.id = ?`,
+		userID))
+}
+
+// SetCanary marks or unmarks a user as a canary account
+func (r *SQLiteUserRepository) SetCanary(ctx context.Context, userID int64, canary bool) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET is_canary = ?
+		 WHERE id = ?`,
+		canary, userID)
+}
+
+// ListServiceAccounts returns every service account, including locked ones
+func (r *SQLiteUserRepository) ListServiceAccounts(ctx context.Context) ([]*model.User, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT id, email, created_at, is_active, type
+		 FROM users
+		 WHERE type = ?
+		 ORDER BY id`,
+		model.UserTypeService)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		var user model.User
+		var isActive bool
+		if err := rows.Scan(&user.ID, &user.Email, &user.Created, &isActive, &user.Type); err != nil {
+			return nil, err
+		}
+		user.IsLocked = !isActive
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// SetServiceAccountLocked locks or unlocks a service account
+func (r *SQLiteUserRepository) SetServiceAccountLocked(ctx context.Context, userID int64, locked bool) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET is_active = ?,
+		     failed_login_attempts = 0
+		 WHERE id = ? AND type = ?`,
+		!locked, userID, model.UserTypeService)
+}
+
+// DeleteServiceAccount deletes a service account together with its API keys and sessions
+func (r *SQLiteUserRepository) DeleteServiceAccount(ctx context.Context, userID int64) error {
+	return r.updateUser(ctx,
+		`DELETE FROM users
+		 WHERE id = ? AND type = ?`,
+		userID, model.UserTypeService)
+}
+
+// SearchUsers returns a page of the users matching filter, including locked
+// and disabled ones, ordered by ID, with the cursor of the next page.
+// Soft-deleted users are only returned when filter.Deleted is set.
+func (r *SQLiteUserRepository) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error) {
+	after, err := filter.Page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, condition)
+	}
+	if after != 0 {
+		add("id > ?", after)
+	}
+	add("(deleted_at IS NOT NULL) = ?", filter.Deleted)
+	if filter.TenantID != nil {
+		add("tenant_id = ?", *filter.TenantID)
+	}
+	if filter.EmailPrefix != "" {
+		add(`email LIKE ? ESCAPE '\'`, escapeLike(filter.EmailPrefix)+"%")
+	}
+	if filter.Role != "" {
+		add("role = ?", filter.Role)
+	}
+	if filter.Type != "" {
+		add("type = ?", filter.Type)
+	}
+	if filter.Locked != nil {
+		add("is_active = ?", !*filter.Locked)
+	}
+	if filter.Disabled != nil {
+		add("(disabled_at IS NOT NULL) = ?", *filter.Disabled)
+	}
+	if filter.Verified != nil {
+		add("(email_verified_at IS NOT NULL) = ?", *filter.Verified)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		add("created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		add("created_at < ?", filter.CreatedBefore.UTC())
+	}
+
+	query := `SELECT ` + adminUserColumns + ` WHERE ` + strings.Join(where, " AND ") + ` ORDER BY id LIMIT ?`
+	args = append(args, filter.Page.Fetch())
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	users, next := pagination.Trim(users, filter.Page, userKey)
+	return users, next, nil
+}
+
+// GetUserForAdmin retrieves a user by ID whatever its state, for administration
+func (r *SQLiteUserRepository) GetUserForAdmin(ctx context.Context, userID int64) (*model.User, error) {
+	return scanAdminUser(r.q.QueryRowContext(ctx,
+		`SELECT `+adminUserColumns+`
+		 WHERE id = ?`,
+		userID))
+}
+
+// SetUserDisabled disables or re-enables a user
+func (r *SQLiteUserRepository) SetUserDisabled(ctx context.Context, userID int64, disabled bool) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET disabled_at = CASE WHEN ? THEN COALESCE(disabled_at, strftime('%Y-%m-%d %H:%M:%f', 'now')) END,
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ?`,
+		disabled, userID)
+}
+
+// GetUserMetadata returns a user's metadata. SQLite serializes writes, so
+// updates in a transaction do not lose concurrent changes.
+func (r *SQLiteUserRepository) GetUserMetadata(ctx context.Context, userID int64) (*model.UserMetadata, error) {
+	return scanMetadata(r.q.QueryRowContext(ctx,
+		`SELECT metadata
+		 FROM users
+		 WHERE id = ?`,
+		userID))
+}
+
+// SetUserMetadata replaces a user's metadata
+func (r *SQLiteUserRepository) SetUserMetadata(ctx context.Context, userID int64, metadata *model.UserMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET metadata = ?,
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ?`,
+		string(data), userID)
+}
+
+// UpdateUserProfile replaces a user's profile
+func (r *SQLiteUserRepository) UpdateUserProfile(ctx context.Context, userID int64, profile model.Profile) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET display_name = ?, given_name = ?, family_name = ?,
+		     locale = ?, timezone = ?, avatar_url = ?,
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ?`,
+		profile.DisplayName, profile.GivenName, profile.FamilyName,
+		profile.Locale, profile.Timezone, profile.AvatarURL, userID)
+}
+
+// UpdatePassword replaces a user's password hash, restarting its age, and
+// clears any lockout or forced expiry
+func (r *SQLiteUserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET password_hash = ?,
+		     failed_login_attempts = 0,
+		     is_active = true,
+		     password_changed_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+		     password_expires_at = NULL,
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ? AND type = ?`,
+		passwordHash, userID, model.UserTypeHuman)
+}
+
+// ExpirePassword forces a user to change their password at the next sign-in
+func (r *SQLiteUserRepository) ExpirePassword(ctx context.Context, userID int64) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET password_expires_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ? AND type = ?`,
+		userID, model.UserTypeHuman)
+}
+
+// RehashPassword replaces a user's password hash with a stronger hash of the
+// same password, unless the password changed since oldHash was read
+func (r *SQLiteUserRepository) RehashPassword(ctx context.Context, userID int64, oldHash, newHash string) error {
+	return r.updateUser(ctx,
+		`UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?`,
+		newHash, userID, oldHash)
+}
+
+// UnlockUser clears the failed attempts of a user locked out by the lockout policy
+func (r *SQLiteUserRepository) UnlockUser(ctx context.Context, userID int64) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET failed_login_attempts = 0,
+		     is_active = true
+		 WHERE id = ? AND type = ?`,
+		userID, model.UserTypeHuman)
+}
+
+// SoftDeleteUser hides a user from sign-in and lookups until it is restored or purged
+func (r *SQLiteUserRepository) SoftDeleteUser(ctx context.Context, userID int64) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ? AND deleted_at IS NULL`,
+		userID)
+}
+
+// RestoreUser undoes the soft deletion of a user
+func (r *SQLiteUserRepository) RestoreUser(ctx context.Context, userID int64) error {
+	return r.updateUser(ctx,
+		`UPDATE users
+		 SET deleted_at = NULL,
+		     updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 WHERE id = ? AND deleted_at IS NOT NULL`,
+		userID)
+}
+
+// updateUser runs a statement on one user, reporting ErrUserNotFound when no row matched
+func (r *SQLiteUserRepository) updateUser(ctx context.Context, query string, args ...any) error {
+	result, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedUsers permanently deletes users soft-deleted before the given
+// time, with their sessions, identities, and API keys, and returns how many
+// were deleted
+func (r *SQLiteUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.q.ExecContext(ctx,
+		`DELETE FROM users
+		 WHERE deleted_at < ?`,
+		before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// MarkEmailVerified records that an identity provider vouched for a user's email
+func (r *SQLiteUserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	_, err := r.q.ExecContext(ctx,
+		`UPDATE users
+		 SET email_verified_at = COALESCE(email_verified_at, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+		 WHERE id = ?`,
+		userID)
+	return err
+}
+
+// UpdateLastLogin updates the last login time and resets failed attempts
+func (r *SQLiteUserRepository) UpdateLastLogin(ctx context.Context, userID int64) error {
+	_, err := r.q.ExecContext(ctx,
+		`UPDATE users
+		 SET last_login = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+		     failed_login_attempts = 0
+		 WHERE id = ?`,
+		userID)
+	return err
+}
+
+// IncrementFailedAttempts increments the failed login attempts counter and
+// deactivates the account once the policy locks it
+func (r *SQLiteUserRepository) IncrementFailedAttempts(ctx context.Context, userID int64, policy model.LockoutPolicy) error {
+	var attempts int64
+	err := r.q.QueryRowContext(ctx,
+		`UPDATE users
+		 SET failed_login_attempts = failed_login_attempts + 1,
+		     is_active = CASE WHEN failed_login_attempts + 1 >= ? THEN false ELSE true END
+		 WHERE id = ?
+		 RETURNING failed_login_attempts`,
+		policy.MaxFailedAttempts, userID).Scan(&attempts)
+	if err != nil {
+		return err
+	}
+
+	if policy.IsLocked(attempts) {
+		return ErrTooManyAttempts
+	}
+
+	return nil
+}
+
+// CreateSession creates a new session for a user
+func (r *SQLiteUserRepository) CreateSession(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
+	_, err := r.q.ExecContext(ctx,
+		`INSERT INTO sessions (user_id, token_id, device_fingerprint, remember_me, expires_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		userID, tokenID, deviceFingerprint, rememberMe, expiresAt.UTC())
+	return err
+}
+
+// RevokeSession marks a session as revoked
+func (r *SQLiteUserRepository) RevokeSession(ctx context.Context, tokenID string) error {
+	result, err := r.q.ExecContext(ctx,
+		`UPDATE sessions
+		 SET is_revoked = true
+		 WHERE token_id = ?`,
+		tokenID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every active session for a user and returns how many were revoked
+func (r *SQLiteUserRepository) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	result, err := r.q.ExecContext(ctx,
+		`UPDATE sessions
+		 SET is_revoked = true
+		 WHERE user_id = ? AND is_revoked = false AND expires_at > strftime('%Y-%m-%d %H:%M:%f', 'now')`,
+		userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListSessions returns a page of a user's active sessions, oldest first, with
+// the cursor of the next page
+func (r *SQLiteUserRepository) ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	after, err := page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT id, user_id, token_id, device_fingerprint, remember_me, created_at, expires_at
+		 FROM sessions
+		 WHERE user_id = ? AND id > ? AND is_revoked = false AND expires_at > strftime('%Y-%m-%d %H:%M:%f', 'now')
+		 ORDER BY id
+		 LIMIT ?`,
+		userID, after, page.Fetch())
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.TokenID, &session.DeviceFingerprint, &session.RememberMe, &session.Created, &session.ExpiresAt); err != nil {
+			return nil, "", err
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	sessions, next := pagination.Trim(sessions, page, sessionKey)
+	return sessions, next, nil
+}
+
+// IsSessionValid checks if a session is valid and not expired
+func (r *SQLiteUserRepository) IsSessionValid(ctx context.Context, tokenID string) (bool, error) {
+	var isRevoked bool
+	var expiresAt time.Time
+
+	err := r.q.QueryRowContext(ctx,
+		`SELECT is_revoked, expires_at
+		 FROM sessions
+		 WHERE token_id = ?`,
+		tokenID).Scan(&isRevoked, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !isRevoked && time.Now().Before(expiresAt), nil
+}
+
+// AddOutboxEvent implements interfaces.EventOutbox, storing the event in the
+// repository's transaction when it is in one
+func (r *SQLiteUserRepository) AddOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	return addSQLOutboxEvent(ctx, r.q, event)
+}
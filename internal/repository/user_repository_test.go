@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
 	"github.com/joho/godotenv"
 )
 
@@ -17,31 +20,69 @@ func init() {
 	}
 }
 
-func setupTestDB(t *testing.T) *database.DB {
+// setupTestRepo returns an empty UserRepository on the database of
+// DATABASE_URL. A sqlite:// URL runs the tests without a database server.
+func setupTestRepo(t *testing.T) interfaces.UserRepository {
+	repo, _ := setupTestRepos(t)
+	return repo
+}
+
+// setupTestRepos returns an empty UserRepository and OutboxRepository on the
+// database of DATABASE_URL
+func setupTestRepos(t *testing.T) (interfaces.UserRepository, interfaces.OutboxRepository) {
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
 		t.Fatal("DATABASE_URL environment variable is not set")
 	}
 
+	driver, err := database.DriverFor(dbURL)
+	if err != nil {
+		t.Fatalf("Unsupported test database: %v", err)
+	}
+
+	switch driver {
+	case database.DriverSQLite:
+		db, err := database.NewSQLite(dbURL)
+		if err != nil {
+			t.Fatalf("Failed to open test database: %v", err)
+		}
+		t.Cleanup(db.Close)
+		// Clean up before each test
+		if _, err := db.DB.ExecContext(context.Background(), "DELETE FROM users; DELETE FROM event_outbox"); err != nil {
+			t.Fatalf("Failed to clean test database: %v", err)
+		}
+		return NewSQLiteUserRepository(db), NewSQLiteOutboxRepository(db)
+	case database.DriverMySQL:
+		db, err := database.NewMySQL(dbURL)
+		if err != nil {
+			t.Fatalf("Failed to connect to test database: %v", err)
+		}
+		t.Cleanup(db.Close)
+		for _, table := range []string{"users", "event_outbox"} {
+			if _, err := db.DB.ExecContext(context.Background(), "DELETE FROM "+table); err != nil {
+				t.Fatalf("Failed to clean test database: %v", err)
+			}
+		}
+		return NewMySQLUserRepository(db), NewMySQLOutboxRepository(db)
+	}
+
 	db, err := database.New(dbURL)
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
+	t.Cleanup(db.Close)
 
 	// Clean up before each test
-	_, err = db.Pool.Exec(context.Background(), "TRUNCATE users, sessions CASCADE")
+	_, err = db.Pool.Exec(context.Background(), "TRUNCATE users, sessions, event_outbox CASCADE")
 	if err != nil {
 		t.Fatalf("Failed to clean test database: %v", err)
 	}
 
-	return db
+	return NewUserRepository(db), NewOutboxRepository(db)
 }
 
 func TestUserRepository_CreateUser(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
-
-	repo := NewUserRepository(db)
+	repo := setupTestRepo(t)
 
 	tests := []struct {
 		name     string
@@ -63,6 +104,13 @@ func TestUserRepository_CreateUser(t *testing.T) {
 			wantErr:  true,
 			errIs:    ErrDuplicateEmail,
 		},
+		{
+			name:     "duplicate email in another case",
+			email:    "Test@EXAMPLE.com",
+			password: "hashedpassword",
+			wantErr:  true,
+			errIs:    ErrDuplicateEmail,
+		},
 	}
 
 	for _, tt := range tests {
@@ -93,10 +141,7 @@ func TestUserRepository_CreateUser(t *testing.T) {
 }
 
 func TestUserRepository_GetUserByEmail(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
-
-	repo := NewUserRepository(db)
+	repo := setupTestRepo(t)
 	ctx := context.Background()
 
 	// Create a test user
@@ -117,6 +162,11 @@ func TestUserRepository_GetUserByEmail(t *testing.T) {
 			email:   email,
 			wantErr: nil,
 		},
+		{
+			name:    "existing user in another case",
+			email:   "Test@Example.COM",
+			wantErr: nil,
+		},
 		{
 			name:    "non-existent user",
 			email:   "nonexistent@example.com",
@@ -144,18 +194,15 @@ func TestUserRepository_GetUserByEmail(t *testing.T) {
 			if user == nil {
 				t.Error("expected user but got nil")
 			}
-			if user != nil && user.Email != tt.email {
-				t.Errorf("got email %v, want %v", user.Email, tt.email)
+			if user != nil && user.Email != email {
+				t.Errorf("got email %v, want %v", user.Email, email)
 			}
 		})
 	}
 }
 
 func TestUserRepository_IncrementFailedAttempts(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
-
-	repo := NewUserRepository(db)
+	repo := setupTestRepo(t)
 	ctx := context.Background()
 
 	// Create a test user
@@ -177,13 +224,13 @@ func TestUserRepository_IncrementFailedAttempts(t *testing.T) {
 			wantError: nil,
 		},
 		{
-			name:      "fourth attempt",
-			attempts:  4,
+			name:      "attempt before lock",
+			attempts:  int(model.DefaultLockoutPolicy.MaxFailedAttempts) - 1,
 			wantError: nil,
 		},
 		{
-			name:      "fifth attempt - should lock",
-			attempts:  5,
+			name:      "last allowed attempt - should lock",
+			attempts:  int(model.DefaultLockoutPolicy.MaxFailedAttempts),
 			wantError: ErrTooManyAttempts,
 		},
 	}
@@ -192,7 +239,7 @@ func TestUserRepository_IncrementFailedAttempts(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var lastError error
 			for i := 0; i < tt.attempts; i++ {
-				lastError = repo.IncrementFailedAttempts(ctx, user.ID)
+				lastError = repo.IncrementFailedAttempts(ctx, user.ID, model.DefaultLockoutPolicy)
 			}
 
 			if tt.wantError != nil {
@@ -208,10 +255,7 @@ func TestUserRepository_IncrementFailedAttempts(t *testing.T) {
 }
 
 func TestUserRepository_SessionManagement(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
-
-	repo := NewUserRepository(db)
+	repo := setupTestRepo(t)
 	ctx := context.Background()
 
 	// Create a test user
@@ -225,7 +269,7 @@ func TestUserRepository_SessionManagement(t *testing.T) {
 		tokenID := "test-token"
 		expiresAt := time.Now().Add(24 * time.Hour)
 
-		err := repo.CreateSession(ctx, user.ID, tokenID, expiresAt)
+		err := repo.CreateSession(ctx, user.ID, tokenID, "device-hash", false, expiresAt)
 		if err != nil {
 			t.Errorf("failed to create session: %v", err)
 		}
@@ -238,6 +282,11 @@ func TestUserRepository_SessionManagement(t *testing.T) {
 		if !valid {
 			t.Error("expected session to be valid")
 		}
+
+		sessions, _, err := repo.ListSessions(ctx, user.ID, pagination.Page{Limit: 10})
+		if err != nil || len(sessions) != 1 || sessions[0].DeviceFingerprint != "device-hash" {
+			t.Errorf("ListSessions = %+v, %v; want the session with its device fingerprint", sessions, err)
+		}
 	})
 
 	// Test RevokeSession
@@ -246,7 +295,7 @@ func TestUserRepository_SessionManagement(t *testing.T) {
 		expiresAt := time.Now().Add(24 * time.Hour)
 
 		// Create and then revoke session
-		err := repo.CreateSession(ctx, user.ID, tokenID, expiresAt)
+		err := repo.CreateSession(ctx, user.ID, tokenID, "", false, expiresAt)
 		if err != nil {
 			t.Fatalf("failed to create session: %v", err)
 		}
@@ -271,7 +320,7 @@ func TestUserRepository_SessionManagement(t *testing.T) {
 		tokenID := "test-token-3"
 		expiresAt := time.Now().Add(-1 * time.Hour) // Expired 1 hour ago
 
-		err := repo.CreateSession(ctx, user.ID, tokenID, expiresAt)
+		err := repo.CreateSession(ctx, user.ID, tokenID, "", false, expiresAt)
 		if err != nil {
 			t.Fatalf("failed to create session: %v", err)
 		}
@@ -287,10 +336,7 @@ func TestUserRepository_SessionManagement(t *testing.T) {
 }
 
 func TestUserRepository_UpdateLastLogin(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
-
-	repo := NewUserRepository(db)
+	repo := setupTestRepo(t)
 	ctx := context.Background()
 
 	// Create a test user
@@ -315,3 +361,146 @@ func TestUserRepository_UpdateLastLogin(t *testing.T) {
 		t.Errorf("expected failed attempts to be reset to 0, got %d", updatedUser.FailedAttempts)
 	}
 }
+
+func TestUserRepository_WithTx(t *testing.T) {
+	repo := setupTestRepo(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, "test@example.com", "hashedpassword")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	// A failing unit of work leaves nothing behind
+	errAbort := fmt.Errorf("abort")
+	err = repo.WithTx(ctx, func(tx interfaces.UserRepository) error {
+		if err := tx.CreateSession(ctx, user.ID, "rolled-back", "", false, time.Now().Add(time.Hour)); err != nil {
+			return err
+		}
+		if err := tx.IncrementFailedAttempts(ctx, user.ID, model.DefaultLockoutPolicy); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("WithTx returned %v, want the error of fn", err)
+	}
+	if valid, _ := repo.IsSessionValid(ctx, "rolled-back"); valid {
+		t.Error("session of a rolled back transaction is valid")
+	}
+	if found, _ := repo.GetUserByID(ctx, user.ID); found.FailedAttempts != 0 {
+		t.Errorf("failed attempts = %d after rollback, want 0", found.FailedAttempts)
+	}
+
+	// Nested units of work join the outer transaction
+	err = repo.WithTx(ctx, func(tx interfaces.UserRepository) error {
+		return tx.WithTx(ctx, func(inner interfaces.UserRepository) error {
+			return inner.CreateSession(ctx, user.ID, "committed", "", false, time.Now().Add(time.Hour))
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+	if valid, _ := repo.IsSessionValid(ctx, "committed"); !valid {
+		t.Error("session of a committed transaction is not valid")
+	}
+}
+
+func TestUserRepository_RecordLogin(t *testing.T) {
+	repo := setupTestRepo(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, "test@example.com", "hashedpassword")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	if user.LastLogin != nil {
+		t.Errorf("new user has last login %v, want none", user.LastLogin)
+	}
+	if err := repo.IncrementFailedAttempts(ctx, user.ID, model.DefaultLockoutPolicy); err != nil {
+		t.Fatalf("failed to increment failed attempts: %v", err)
+	}
+
+	if err := RecordLogin(ctx, repo, user.ID, "signed-in", "", false, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RecordLogin failed: %v", err)
+	}
+	if found, _ := repo.GetUserByID(ctx, user.ID); found.FailedAttempts != 0 || found.LastLogin == nil {
+		t.Errorf("failed attempts = %d and last login %v after sign-in, want 0 and a time", found.FailedAttempts, found.LastLogin)
+	}
+	if valid, _ := repo.IsSessionValid(ctx, "signed-in"); !valid {
+		t.Error("session of a recorded sign-in is not valid")
+	}
+
+	// A failing statement leaves the failed attempts alone
+	if err := repo.IncrementFailedAttempts(ctx, user.ID, model.DefaultLockoutPolicy); err != nil {
+		t.Fatalf("failed to increment failed attempts: %v", err)
+	}
+	if err := RecordLogin(ctx, repo, user.ID, "signed-in", "", false, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("RecordLogin reused a token ID")
+	}
+	if found, _ := repo.GetUserByID(ctx, user.ID); found.FailedAttempts != 1 {
+		t.Errorf("failed attempts = %d after a failed sign-in, want 1", found.FailedAttempts)
+	}
+}
+
+func TestUserRepository_Metadata(t *testing.T) {
+	repo := setupTestRepo(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, "test@example.com", "hashedpassword")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	// Users start with empty metadata rather than nil maps
+	metadata, err := repo.GetUserMetadata(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if metadata.AppMetadata == nil || metadata.UserMetadata == nil || len(metadata.AppMetadata)+len(metadata.UserMetadata) != 0 {
+		t.Errorf("got metadata %+v, want empty maps", metadata)
+	}
+
+	metadata.AppMetadata["plan"] = "pro"
+	metadata.UserMetadata["theme"] = "dark"
+	if err := repo.SetUserMetadata(ctx, user.ID, metadata); err != nil {
+		t.Fatalf("failed to set metadata: %v", err)
+	}
+	if metadata, err = repo.GetUserMetadata(ctx, user.ID); err != nil {
+		t.Fatalf("failed to get metadata: %v", err)
+	}
+	if metadata.AppMetadata["plan"] != "pro" || metadata.UserMetadata["theme"] != "dark" {
+		t.Errorf("got metadata %+v", metadata)
+	}
+
+	if _, err := repo.GetUserMetadata(ctx, user.ID+1); err != ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, ErrUserNotFound)
+	}
+	if err := repo.SetUserMetadata(ctx, user.ID+1, metadata); err != ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestUserRepository_UpdateUserProfile(t *testing.T) {
+	repo := setupTestRepo(t)
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, "test@example.com", "hashedpassword")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	profile := model.Profile{DisplayName: "Ann", GivenName: "Ann", FamilyName: "Example", Locale: "de-CH", Timezone: "Europe/Zurich", AvatarURL: "https://example.com/ann.png"}
+	if err := repo.UpdateUserProfile(ctx, user.ID, profile); err != nil {
+		t.Fatalf("failed to update profile: %v", err)
+	}
+	if found, err := repo.GetUserByID(ctx, user.ID); err != nil || found.Profile != profile {
+		t.Errorf("got profile %+v (%v), want %+v", found, err, profile)
+	}
+	if found, err := repo.GetUserForAdmin(ctx, user.ID); err != nil || found.Profile != profile {
+		t.Errorf("got admin profile %+v (%v), want %+v", found, err, profile)
+	}
+	if err := repo.UpdateUserProfile(ctx, user.ID+1, profile); err != ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, ErrUserNotFound)
+	}
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+)
+
+// WebhookDeliveryRepositoryImpl implements the WebhookDeliveryRepository interface
+type WebhookDeliveryRepositoryImpl struct {
+	db *database.DB
+}
+
+// Verify that WebhookDeliveryRepositoryImpl implements WebhookDeliveryRepository interface
+var _ interfaces.WebhookDeliveryRepository = (*WebhookDeliveryRepositoryImpl)(nil)
+
+// NewWebhookDeliveryRepository creates a new WebhookDeliveryRepository instance
+func NewWebhookDeliveryRepository(db *database.DB) interfaces.WebhookDeliveryRepository {
+	return &WebhookDeliveryRepositoryImpl{db: db}
+}
+
+// RecordDelivery stores a delivery attempt
+func (r *WebhookDeliveryRepositoryImpl) RecordDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (event_id, event_type, url, attempt, status_code, error, delivered)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		delivery.EventID, delivery.EventType, delivery.URL, delivery.Attempt,
+		delivery.StatusCode, delivery.Error, delivery.Delivered).Scan(&delivery.ID, &delivery.Created)
+}
+
+// ListDeliveries returns a page of the delivery attempts matching filter,
+// newest first, with the cursor of the next page
+func (r *WebhookDeliveryRepositoryImpl) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, string, error) {
+	after, err := filter.Page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if after != 0 {
+		add("id < $%d", after)
+	}
+	if filter.EventID != "" {
+		add("event_id = $%d", filter.EventID)
+	}
+	if filter.EventType != "" {
+		add("event_type = $%d", filter.EventType)
+	}
+	if filter.FailedOnly {
+		where = append(where, "NOT delivered")
+	}
+
+	query := `SELECT id, event_id, event_type, url, attempt, status_code, error, delivered, created_at
+		 FROM webhook_deliveries`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Page.Fetch())
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.EventID, &d.EventType, &d.URL, &d.Attempt,
+			&d.StatusCode, &d.Error, &d.Delivered, &d.Created); err != nil {
+			return nil, "", err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	deliveries, next := pagination.Trim(deliveries, filter.Page, deliveryKey)
+	return deliveries, next, nil
+}
+
+func deliveryKey(d *model.WebhookDelivery) int64 {
+	return d.ID
+}
+
+// recordSQLDelivery stores a delivery attempt on MySQL or SQLite
+func recordSQLDelivery(ctx context.Context, q sqlQuerier, delivery *model.WebhookDelivery) error {
+	delivery.Created = time.Now().UTC()
+	result, err := q.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (event_id, event_type, url, attempt, status_code, error, delivered, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.EventID, delivery.EventType, delivery.URL, delivery.Attempt,
+		delivery.StatusCode, delivery.Error, delivery.Delivered, delivery.Created)
+	if err != nil {
+		return err
+	}
+	delivery.ID, err = result.LastInsertId()
+	return err
+}
+
+// listSQLDeliveries lists delivery attempts on MySQL or SQLite like
+// ListDeliveries
+func listSQLDeliveries(ctx context.Context, q sqlQuerier, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, string, error) {
+	after, err := filter.Page.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, condition)
+	}
+	if after != 0 {
+		add("id < ?", after)
+	}
+	if filter.EventID != "" {
+		add("event_id = ?", filter.EventID)
+	}
+	if filter.EventType != "" {
+		add("event_type = ?", filter.EventType)
+	}
+	if filter.FailedOnly {
+		where = append(where, "NOT delivered")
+	}
+
+	query := `SELECT id, event_id, event_type, url, attempt, status_code, error, delivered, created_at
+		 FROM webhook_deliveries`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, filter.Page.Fetch())
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.EventID, &d.EventType, &d.URL, &d.Attempt,
+			&d.StatusCode, &d.Error, &d.Delivered, &d.Created); err != nil {
+			return nil, "", err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	deliveries, next := pagination.Trim(deliveries, filter.Page, deliveryKey)
+	return deliveries, next, nil
+}
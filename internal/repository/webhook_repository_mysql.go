@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MySQLWebhookDeliveryRepository implements the WebhookDeliveryRepository interface on MySQL and MariaDB
+type MySQLWebhookDeliveryRepository struct {
+	db *database.MySQL
+}
+
+// Verify that MySQLWebhookDeliveryRepository implements WebhookDeliveryRepository interface
+var _ interfaces.WebhookDeliveryRepository = (*MySQLWebhookDeliveryRepository)(nil)
+
+// NewMySQLWebhookDeliveryRepository creates a new WebhookDeliveryRepository backed by MySQL
+func NewMySQLWebhookDeliveryRepository(db *database.MySQL) interfaces.WebhookDeliveryRepository {
+	return &MySQLWebhookDeliveryRepository{db: db}
+}
+
+// RecordDelivery stores a delivery attempt
+func (r *MySQLWebhookDeliveryRepository) RecordDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return recordSQLDelivery(ctx, r.db.DB, delivery)
+}
+
+// ListDeliveries returns a page of the delivery attempts matching filter,
+// newest first, with the cursor of the next page
+func (r *MySQLWebhookDeliveryRepository) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, string, error) {
+	return listSQLDeliveries(ctx, r.db.DB, filter)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// SQLiteWebhookDeliveryRepository implements the WebhookDeliveryRepository interface on SQLite
+type SQLiteWebhookDeliveryRepository struct {
+	db *database.SQLite
+}
+
+// Verify that SQLiteWebhookDeliveryRepository implements WebhookDeliveryRepository interface
+var _ interfaces.WebhookDeliveryRepository = (*SQLiteWebhookDeliveryRepository)(nil)
+
+// NewSQLiteWebhookDeliveryRepository creates a new WebhookDeliveryRepository backed by SQLite
+func NewSQLiteWebhookDeliveryRepository(db *database.SQLite) interfaces.WebhookDeliveryRepository {
+	return &SQLiteWebhookDeliveryRepository{db: db}
+}
+
+// RecordDelivery stores a delivery attempt
+func (r *SQLiteWebhookDeliveryRepository) RecordDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return recordSQLDelivery(ctx, r.db.DB, delivery)
+}
+
+// ListDeliveries returns a page of the delivery attempts matching filter,
+// newest first, with the cursor of the next page
+func (r *SQLiteWebhookDeliveryRepository) ListDeliveries(ctx context.Context, filter model.WebhookDeliveryFilter) ([]*model.WebhookDelivery, string, error) {
+	return listSQLDeliveries(ctx, r.db.DB, filter)
+}
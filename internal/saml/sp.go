@@ -0,0 +1,182 @@
+// Package saml implements a SAML 2.0 service provider that maps assertions
+// from a corporate identity provider to local users.
+package saml
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/oauth"
+	crewsaml "github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+)
+
+// ProviderName identifies SAML identities when they are linked to users
+const ProviderName = "saml"
+
+// Errors returned while processing SAML responses
+var (
+	ErrInvalidResponse = errors.New("invalid saml response")
+	ErrMissingNameID   = errors.New("saml assertion has no name id")
+	ErrMissingEmail    = errors.New("saml assertion has no email address")
+)
+
+// emailAttributes are the attribute names IdPs commonly use for the email address
+var emailAttributes = []string{
+	"email",
+	"mail",
+	"emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+}
+
+// ServiceProvider is a SAML SP with metadata served at {root}/saml/metadata
+// and its Assertion Consumer Service at {root}/saml/acs
+type ServiceProvider struct {
+	sp *crewsaml.ServiceProvider
+}
+
+// NewServiceProvider creates a service provider from its public root URL, its
+// signing key pair, and the identity provider's metadata
+func NewServiceProvider(rootURL, certFile, keyFile string, idpMetadata *crewsaml.EntityDescriptor) (*ServiceProvider, error) {
+	root, err := url.Parse(strings.TrimSuffix(rootURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid root URL: %v", err)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SP key pair: %v", err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SP certificate: %v", err)
+	}
+
+	key, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported SP key type %T", keyPair.PrivateKey)
+	}
+
+	return newServiceProvider(root, key, cert, idpMetadata), nil
+}
+
+func newServiceProvider(root *url.URL, key crypto.Signer, cert *x509.Certificate, idpMetadata *crewsaml.EntityDescriptor) *ServiceProvider {
+	metadataURL := root.ResolveReference(&url.URL{Path: root.Path + "/saml/metadata"})
+	acsURL := root.ResolveReference(&url.URL{Path: root.Path + "/saml/acs"})
+
+	return &ServiceProvider{
+		sp: &crewsaml.ServiceProvider{
+			EntityID:          metadataURL.String(),
+			Key:               key,
+			Certificate:       cert,
+			MetadataURL:       *metadataURL,
+			AcsURL:            *acsURL,
+			IDPMetadata:       idpMetadata,
+			AuthnNameIDFormat: crewsaml.UnspecifiedNameIDFormat,
+		},
+	}
+}
+
+// LoadIdPMetadata fetches identity provider metadata from an http(s) URL or reads it from a file
+func LoadIdPMetadata(ctx context.Context, location string) (*crewsaml.EntityDescriptor, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		metadataURL, err := url.Parse(location)
+		if err != nil {
+			return nil, err
+		}
+		client := &http.Client{Timeout: 10 * time.Second}
+		return samlsp.FetchMetadata(ctx, client, *metadataURL)
+	}
+
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	return samlsp.ParseMetadata(data)
+}
+
+// Metadata returns the SP metadata document to register with the identity provider
+func (s *ServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(s.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// AuthnRequestURL returns the identity provider URL that starts sign-in and the
+// request ID that the response must answer
+func (s *ServiceProvider) AuthnRequestURL(relayState string) (string, string, error) {
+	req, err := s.sp.MakeAuthenticationRequest(s.sp.GetSSOBindingLocation(crewsaml.HTTPRedirectBinding),
+		crewsaml.HTTPRedirectBinding, crewsaml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+
+	redirectURL, err := req.Redirect(relayState, s.sp)
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL.String(), req.ID, nil
+}
+
+// ParseResponse validates the identity provider's POST to the ACS endpoint and
+// returns the signed-in identity. requestID is the ID of the AuthnRequest the
+// response must be answering.
+func (s *ServiceProvider) ParseResponse(r *http.Request, requestID string) (*oauth.Identity, error) {
+	assertion, err := s.sp.ParseResponse(r, []string{requestID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return identityFromAssertion(assertion)
+}
+
+// identityFromAssertion maps the NameID and email of an assertion to an identity
+func identityFromAssertion(assertion *crewsaml.Assertion) (*oauth.Identity, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, ErrMissingNameID
+	}
+	nameID := assertion.Subject.NameID
+
+	email := ""
+	if nameID.Format == string(crewsaml.EmailAddressNameIDFormat) {
+		email = nameID.Value
+	}
+	if email == "" {
+		email = findAttribute(assertion, emailAttributes)
+	}
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, ErrMissingEmail
+	}
+
+	return &oauth.Identity{
+		Provider:       ProviderName,
+		ProviderUserID: nameID.Value,
+		Email:          strings.ToLower(email),
+	}, nil
+}
+
+// Helper function to find the first value of any of the named attributes
+func findAttribute(assertion *crewsaml.Assertion, names []string) string {
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			for _, name := range names {
+				if (strings.EqualFold(attr.Name, name) || strings.EqualFold(attr.FriendlyName, name)) && len(attr.Values) > 0 {
+					return attr.Values[0].Value
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	crewsaml "github.com/crewjam/saml"
+)
+
+func TestIdentityFromAssertion(t *testing.T) {
+	emailAttr := crewsaml.AttributeStatement{Attributes: []crewsaml.Attribute{
+		{Name: "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress", Values: []crewsaml.AttributeValue{{Value: "Jane@Corp.example"}}},
+	}}
+
+	tests := []struct {
+		name      string
+		assertion *crewsaml.Assertion
+		wantID    string
+		wantEmail string
+		wantErr   error
+	}{
+		{
+			name: "email name id",
+			assertion: &crewsaml.Assertion{Subject: &crewsaml.Subject{NameID: &crewsaml.NameID{
+				Format: string(crewsaml.EmailAddressNameIDFormat), Value: "jane@corp.example",
+			}}},
+			wantID:    "jane@corp.example",
+			wantEmail: "jane@corp.example",
+		},
+		{
+			name: "opaque name id with email attribute",
+			assertion: &crewsaml.Assertion{
+				Subject:             &crewsaml.Subject{NameID: &crewsaml.NameID{Value: "00u1abcd"}},
+				AttributeStatements: []crewsaml.AttributeStatement{emailAttr},
+			},
+			wantID:    "00u1abcd",
+			wantEmail: "jane@corp.example",
+		},
+		{
+			name:      "opaque name id without email",
+			assertion: &crewsaml.Assertion{Subject: &crewsaml.Subject{NameID: &crewsaml.NameID{Value: "00u1abcd"}}},
+			wantErr:   ErrMissingEmail,
+		},
+		{
+			name:      "missing subject",
+			assertion: &crewsaml.Assertion{AttributeStatements: []crewsaml.AttributeStatement{emailAttr}},
+			wantErr:   ErrMissingNameID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := identityFromAssertion(tt.assertion)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if identity.Provider != ProviderName || identity.ProviderUserID != tt.wantID || identity.Email != tt.wantEmail {
+				t.Errorf("unexpected identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestServiceProviderMetadataAndAuthnRequest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "auth.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	idpMetadata := &crewsaml.EntityDescriptor{
+		EntityID: "https://idp.example.com",
+		IDPSSODescriptors: []crewsaml.IDPSSODescriptor{{
+			SingleSignOnServices: []crewsaml.Endpoint{{
+				Binding:  crewsaml.HTTPRedirectBinding,
+				Location: "https://idp.example.com/sso",
+			}},
+		}},
+	}
+
+	root, _ := url.Parse("https://auth.example.com")
+	sp := newServiceProvider(root, key, cert, idpMetadata)
+
+	metadata, err := sp.Metadata()
+	if err != nil {
+		t.Fatalf("failed to build metadata: %v", err)
+	}
+	for _, want := range []string{`entityID="https://auth.example.com/saml/metadata"`, `Location="https://auth.example.com/saml/acs"`} {
+		if !strings.Contains(string(metadata), want) {
+			t.Errorf("metadata missing %s", want)
+		}
+	}
+
+	redirectURL, requestID, err := sp.AuthnRequestURL("relay")
+	if err != nil {
+		t.Fatalf("failed to build authn request: %v", err)
+	}
+	if requestID == "" || !strings.HasPrefix(redirectURL, "https://idp.example.com/sso?") {
+		t.Errorf("unexpected authn request %q (id %q)", redirectURL, requestID)
+	}
+}
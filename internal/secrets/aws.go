@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Prefixes of configuration values to read from AWS, as in
+// secretsmanager://prod/auth-service#jwt_secret or
+// ssm:///prod/auth-service/database-url
+const (
+	SecretsManagerPrefix = "secretsmanager://"
+	SSMPrefix            = "ssm://"
+)
+
+// secretsManagerAPI is the part of the Secrets Manager client AWS uses
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// ssmAPI is the part of the SSM client AWS uses
+type ssmAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// AWS reads secrets from AWS Secrets Manager and SSM Parameter Store
+type AWS struct {
+	secretsManager secretsManagerAPI
+	ssm            ssmAPI
+}
+
+// NewAWS creates clients with the AWS SDK's default configuration:
+// credentials and region come from the environment (AWS_REGION,
+// AWS_ACCESS_KEY_ID, ...), shared config files, or the instance or task role
+func NewAWS(ctx context.Context) (*AWS, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: failed to load AWS configuration: %v", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("secrets: AWS_REGION is required for Secrets Manager and SSM")
+	}
+	return &AWS{secretsManager: secretsmanager.NewFromConfig(cfg), ssm: ssm.NewFromConfig(cfg)}, nil
+}
+
+// IsAWSReference reports whether value refers to a secret in AWS
+func IsAWSReference(value string) bool {
+	return strings.HasPrefix(value, SecretsManagerPrefix) || strings.HasPrefix(value, SSMPrefix)
+}
+
+// Get reads the secret a reference refers to:
+//
+//   - secretsmanager://<name or ARN> is the secret's string value, and
+//     secretsmanager://<name or ARN>#<key> one key of a JSON secret, such as
+//     the password of RDS-managed credentials
+//   - ssm://<name> is a parameter, decrypted if it is a SecureString; names
+//     in a hierarchy start with a slash, as in ssm:///prod/auth/jwt-secret
+func (a *AWS) Get(ctx context.Context, ref string) (string, error) {
+	if name, ok := strings.CutPrefix(ref, SSMPrefix); ok {
+		if name == "" {
+			return "", ErrInvalidReference
+		}
+		out, err := a.ssm.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name), WithDecryption: aws.Bool(true)})
+		if err != nil {
+			return "", fmt.Errorf("ssm: get %s: %w", name, err)
+		}
+		if out.Parameter == nil || out.Parameter.Value == nil {
+			return "", fmt.Errorf("ssm: %s has no value", name)
+		}
+		return *out.Parameter.Value, nil
+	}
+
+	id, ok := strings.CutPrefix(ref, SecretsManagerPrefix)
+	if !ok || id == "" {
+		return "", ErrInvalidReference
+	}
+	id, key, hasKey := strings.Cut(id, "#")
+	out, err := a.secretsManager.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("secretsmanager: get %s: %w", id, err)
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secretsmanager: %s is not a string secret", id)
+	}
+	if !hasKey {
+		return *out.SecretString, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(*out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secretsmanager: %s is not a JSON secret", id)
+	}
+	switch value := fields[key].(type) {
+	case string:
+		return value, nil
+	case float64, bool:
+		return fmt.Sprint(value), nil
+	}
+	return "", fmt.Errorf("secretsmanager: no %q in %s", key, id)
+}
@@ -0,0 +1,71 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+var errNotFound = errors.New("not found")
+
+// fakeSecretsManager holds secret strings by ID
+type fakeSecretsManager map[string]string
+
+func (f fakeSecretsManager) GetSecretValue(_ context.Context, in *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	value, ok := f[*in.SecretId]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(value)}, nil
+}
+
+// fakeSSM holds parameter values by name, refusing to return them undecrypted
+type fakeSSM map[string]string
+
+func (f fakeSSM) GetParameter(_ context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	value, ok := f[*in.Name]
+	if !ok || in.WithDecryption == nil || !*in.WithDecryption {
+		return nil, errNotFound
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Value: aws.String(value)}}, nil
+}
+
+func TestAWSGet(t *testing.T) {
+	a := &AWS{
+		secretsManager: fakeSecretsManager{
+			"prod/jwt": "plain-secret",
+			"prod/db":  `{"username":"auth","password":"db-secret","port":5432}`,
+		},
+		ssm: fakeSSM{"/prod/auth/database-url": "postgres://auth@db/auth"},
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "secretsmanager://prod/jwt", want: "plain-secret"},
+		{ref: "secretsmanager://prod/db#password", want: "db-secret"},
+		{ref: "secretsmanager://prod/db#port", want: "5432"},
+		{ref: "secretsmanager://prod/db#missing", wantErr: true},
+		{ref: "secretsmanager://prod/jwt#password", wantErr: true},
+		{ref: "secretsmanager://prod/other", wantErr: true},
+		{ref: "secretsmanager://", wantErr: true},
+		{ref: "ssm:///prod/auth/database-url", want: "postgres://auth@db/auth"},
+		{ref: "ssm:///prod/auth/other", wantErr: true},
+		{ref: "ssm://", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := a.Get(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
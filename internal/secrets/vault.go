@@ -0,0 +1,294 @@
+// Package secrets fetches secrets for the configuration from a secret
+// manager, and keeps leased secrets such as dynamic database credentials
+// fresh while the service runs.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// VaultPrefix marks a configuration value to read from Vault, as in
+// vault:secret/data/auth-service#jwt_secret
+const VaultPrefix = "vault:"
+
+// DefaultKubernetesTokenPath is where Kubernetes mounts the pod's service
+// account token
+const DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// retryInterval is how long to wait before logging in or fetching
+// credentials again after Vault failed
+const retryInterval = 5 * time.Second
+
+var ErrInvalidReference = errors.New("secret reference must be <path>#<key>")
+
+// VaultConfig selects how to authenticate to Vault. The address, TLS settings
+// and, for token auth, VAULT_TOKEN are read from the standard VAULT_*
+// environment variables.
+type VaultConfig struct {
+	Address string // overrides VAULT_ADDR
+
+	// Log in with the Kubernetes auth method as this role instead of using
+	// VAULT_TOKEN
+	KubernetesRole      string
+	KubernetesMount     string // default "kubernetes"
+	KubernetesTokenPath string // default DefaultKubernetesTokenPath
+}
+
+// Vault reads secrets and database credentials from HashiCorp Vault
+type Vault struct {
+	client *vault.Client
+	login  func(ctx context.Context) (*vault.Secret, error) // nil with token auth
+	auth   *vault.Secret                                    // the login to renew; nil with token auth
+
+	stop context.CancelFunc
+	ctx  context.Context
+	wg   sync.WaitGroup
+}
+
+// Credentials are a database user and password leased from Vault
+type Credentials struct {
+	Username string
+	Password string
+
+	path  string
+	lease *vault.Secret
+}
+
+// NewVault connects to Vault and, with Kubernetes auth, logs in
+func NewVault(ctx context.Context, cfg VaultConfig) (*Vault, error) {
+	config := vault.DefaultConfig()
+	if config.Error != nil {
+		return nil, config.Error
+	}
+	if cfg.Address != "" {
+		config.Address = cfg.Address
+	}
+	client, err := vault.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{client: client}
+	v.ctx, v.stop = context.WithCancel(context.Background())
+	if cfg.KubernetesRole != "" {
+		v.login = kubernetesLogin(client, cfg)
+		if v.auth, err = v.login(ctx); err != nil {
+			return nil, fmt.Errorf("vault: kubernetes login: %w", err)
+		}
+	} else if client.Token() == "" {
+		return nil, errors.New("vault: VAULT_TOKEN or Kubernetes auth is required")
+	}
+	return v, nil
+}
+
+// kubernetesLogin logs in with the pod's service account token, read again
+// for each login since Kubernetes rotates it
+func kubernetesLogin(client *vault.Client, cfg VaultConfig) func(ctx context.Context) (*vault.Secret, error) {
+	mount := cfg.KubernetesMount
+	if mount == "" {
+		mount = "kubernetes"
+	}
+	tokenPath := cfg.KubernetesTokenPath
+	if tokenPath == "" {
+		tokenPath = DefaultKubernetesTokenPath
+	}
+	return func(ctx context.Context) (*vault.Secret, error) {
+		jwt, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := client.Logical().WriteWithContext(ctx, "auth/"+mount+"/login", map[string]any{
+			"role": cfg.KubernetesRole,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil || secret.Auth == nil {
+			return nil, errors.New("no token in the login response")
+		}
+		client.SetToken(secret.Auth.ClientToken)
+		return secret, nil
+	}
+}
+
+// Get reads one key of a secret, given as <path>#<key> with the API path of
+// the secret, e.g. secret/data/auth-service#jwt_secret for a KV version 2
+// secret in the "secret" mount
+func (v *Vault) Get(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", ErrInvalidReference
+	}
+	secret, err := v.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("vault: read %s: %w", path, err)
+	}
+	if secret == nil {
+		return "", fmt.Errorf("vault: no secret at %s", path)
+	}
+
+	data := secret.Data
+	// KV version 2 nests the secret's own data under "data"
+	if nested, ok := data["data"].(map[string]any); ok && data["metadata"] != nil {
+		data = nested
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault: no string %q in %s", key, path)
+	}
+	return value, nil
+}
+
+// DatabaseCredentials issues database credentials from a database secrets
+// engine role, e.g. database/creds/auth-service
+func (v *Vault) DatabaseCredentials(ctx context.Context, path string) (*Credentials, error) {
+	secret, err := v.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("vault: read %s: %w", path, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("vault: no credentials at %s", path)
+	}
+	username, _ := secret.Data["username"].(string)
+	password, _ := secret.Data["password"].(string)
+	if username == "" {
+		return nil, fmt.Errorf("vault: no username in the credentials from %s", path)
+	}
+	return &Credentials{Username: username, Password: password, path: path, lease: secret}, nil
+}
+
+// KeepLogin renews the Vault token in the background until Close. With
+// Kubernetes auth, it logs in again once the token cannot be renewed further.
+func (v *Vault) KeepLogin() {
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+
+		auth := v.auth
+		if auth == nil {
+			// Renew VAULT_TOKEN when it has a TTL; root tokens do not expire
+			var err error
+			if auth, err = v.client.Auth().Token().RenewSelfWithContext(v.ctx, 0); err != nil {
+				slog.Debug("vault: token not renewed", "err", err)
+				return
+			}
+		}
+		for {
+			v.watchLease(auth)
+			if v.ctx.Err() != nil {
+				return
+			}
+			if v.login == nil {
+				slog.Error("vault: token can no longer be renewed; restart with a new VAULT_TOKEN")
+				return
+			}
+			if auth = v.retry("log in", v.login); auth == nil {
+				return
+			}
+		}
+	}()
+}
+
+// WatchCredentials renews the lease of database credentials in the
+// background until Close. Before the lease runs out, new credentials are
+// issued and passed to rotated, which should make the pool reconnect with
+// them.
+func (v *Vault) WatchCredentials(creds *Credentials, rotated func(*Credentials)) {
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+
+		for {
+			v.watchLease(creds.lease)
+			if v.ctx.Err() != nil {
+				return
+			}
+			lease := v.retry("issue database credentials", func(ctx context.Context) (*vault.Secret, error) {
+				next, err := v.DatabaseCredentials(ctx, creds.path)
+				if err != nil {
+					return nil, err
+				}
+				creds = next
+				return next.lease, nil
+			})
+			if lease == nil {
+				return
+			}
+			slog.Info("vault: database credentials rotated", "username", creds.Username)
+			rotated(creds)
+		}
+	}()
+}
+
+// watchLease renews a lease for as long as Vault allows, returning shortly
+// before it expires or when the Vault is closed
+func (v *Vault) watchLease(secret *vault.Secret) {
+	ttl, err := secret.TokenTTL()
+	if err != nil || ttl == 0 {
+		ttl = time.Duration(secret.LeaseDuration) * time.Second
+	}
+	renewable, _ := secret.TokenIsRenewable()
+	if !renewable && !secret.Renewable {
+		if ttl == 0 {
+			<-v.ctx.Done() // never expires
+			return
+		}
+		select {
+		case <-v.ctx.Done():
+		case <-time.After(ttl * 2 / 3):
+		}
+		return
+	}
+
+	watcher, err := v.client.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: secret})
+	if err != nil {
+		slog.Error("vault: cannot renew lease", "err", err)
+		return
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+	for {
+		select {
+		case <-v.ctx.Done():
+			return
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				slog.Warn("vault: lease renewal stopped", "err", err)
+			}
+			return
+		case <-watcher.RenewCh():
+		}
+	}
+}
+
+// retry calls fn until it succeeds or the Vault is closed, returning nil then
+func (v *Vault) retry(what string, fn func(ctx context.Context) (*vault.Secret, error)) *vault.Secret {
+	for {
+		secret, err := fn(v.ctx)
+		if err == nil {
+			return secret
+		}
+		slog.Error("vault: failed to "+what, "err", err)
+		select {
+		case <-v.ctx.Done():
+			return nil
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// Close stops renewing the token and leases
+func (v *Vault) Close() {
+	v.stop()
+	v.wg.Wait()
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeVault serves the Vault API paths the service uses
+func fakeVault(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var issued atomic.Int64
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "test-token" {
+				http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("GET /v1/secret/data/auth-service", authorized(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]any{"data": map[string]any{
+			"data":     map[string]any{"jwt_secret": "from-kv-v2"},
+			"metadata": map[string]any{"version": 3},
+		}})
+	}))
+	mux.HandleFunc("GET /v1/kv/auth-service", authorized(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]any{"data": map[string]any{"jwt_secret": "from-kv-v1"}})
+	}))
+	mux.HandleFunc("GET /v1/database/creds/auth-service", authorized(func(w http.ResponseWriter, r *http.Request) {
+		n := issued.Add(1)
+		reply(w, map[string]any{
+			"lease_id":       fmt.Sprintf("database/creds/auth-service/%d", n),
+			"lease_duration": 1,
+			"renewable":      false,
+			"data":           map[string]any{"username": fmt.Sprintf("v-auth-%d", n), "password": "secret"},
+		})
+	}))
+	mux.HandleFunc("PUT /v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Role, JWT string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Role != "auth-service" || body.JWT != "service-account-jwt" {
+			http.Error(w, `{"errors":["invalid role or JWT"]}`, http.StatusForbidden)
+			return
+		}
+		reply(w, map[string]any{"auth": map[string]any{"client_token": "test-token", "lease_duration": 3600, "renewable": true}})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func TestVaultGet(t *testing.T) {
+	srv, _ := fakeVault(t)
+	t.Setenv("VAULT_TOKEN", "test-token")
+	v, err := NewVault(context.Background(), VaultConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "secret/data/auth-service#jwt_secret", want: "from-kv-v2"},
+		{ref: "kv/auth-service#jwt_secret", want: "from-kv-v1"},
+		{ref: "secret/data/auth-service#missing", wantErr: true},
+		{ref: "secret/data/other#jwt_secret", wantErr: true},
+		{ref: "secret/data/auth-service", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := v.Get(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultKubernetesLogin(t *testing.T) {
+	srv, _ := fakeVault(t)
+	t.Setenv("VAULT_TOKEN", "")
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("service-account-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewVault(context.Background(), VaultConfig{Address: srv.URL, KubernetesRole: "other", KubernetesTokenPath: tokenPath}); err == nil {
+		t.Fatal("expected the login of an unknown role to fail")
+	}
+	v, err := NewVault(context.Background(), VaultConfig{Address: srv.URL, KubernetesRole: "auth-service", KubernetesTokenPath: tokenPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if got, err := v.Get(context.Background(), "secret/data/auth-service#jwt_secret"); err != nil || got != "from-kv-v2" {
+		t.Fatalf("Get() after login = %q, %v", got, err)
+	}
+}
+
+func TestVaultWatchCredentials(t *testing.T) {
+	srv, issued := fakeVault(t)
+	t.Setenv("VAULT_TOKEN", "test-token")
+	v, err := NewVault(context.Background(), VaultConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := v.DatabaseCredentials(context.Background(), "database/creds/auth-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Username != "v-auth-1" {
+		t.Fatalf("Username = %q", creds.Username)
+	}
+
+	rotated := make(chan *Credentials, 1)
+	v.WatchCredentials(creds, func(next *Credentials) {
+		select {
+		case rotated <- next:
+		default:
+		}
+	})
+	select {
+	case next := <-rotated:
+		if next.Username != "v-auth-2" {
+			t.Errorf("rotated to %q, want v-auth-2", next.Username)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("credentials were not rotated before the lease expired")
+	}
+
+	v.Close()
+	n := issued.Load()
+	time.Sleep(1500 * time.Millisecond)
+	if issued.Load() != n {
+		t.Error("credentials were issued after Close")
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// ErrInvalidAPIKey is returned when an API key is malformed, unknown, or revoked
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// apiKeyPrefix marks API keys so they are recognisable in configs and secret scanners
+const apiKeyPrefix = "ak_"
+
+// apiKeyDisplayLength is how much of a key is kept in clear text to identify it
+const apiKeyDisplayLength = 11
+
+// APIKeyService issues and validates long-lived user API keys
+type APIKeyService struct {
+	apiKeyRepo interfaces.APIKeyRepository
+	userRepo   interfaces.UserStore
+	lockout    model.LockoutPolicy
+}
+
+// NewAPIKeyService creates a new API key service. Keys of accounts locked
+// under the lockout policy are rejected.
+func NewAPIKeyService(apiKeyRepo interfaces.APIKeyRepository, userRepo interfaces.UserStore, lockout model.LockoutPolicy) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		lockout:    lockout,
+	}
+}
+
+// CreateAPIKey issues a new key for the user. The plaintext key is returned
+// once and cannot be recovered afterwards.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID int64, name string) (*model.APIKey, string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := apiKeyPrefix + secret
+
+	key := &model.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  plaintext[:apiKeyDisplayLength],
+		KeyHash: hashToken(plaintext),
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, plaintext, nil
+}
+
+// ListAPIKeys returns the user's active keys without their secrets
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey revokes one of the user's keys
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	return s.apiKeyRepo.RevokeAPIKey(ctx, userID, keyID)
+}
+
+// ValidateAPIKey resolves an API key to the ID of the user that owns it.
+// Keys of locked accounts are rejected.
+func (s *APIKeyService) ValidateAPIKey(ctx context.Context, plaintext string) (int64, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return 0, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.UseAPIKey(ctx, hashToken(plaintext))
+	if err != nil {
+		if err == repository.ErrAPIKeyNotFound {
+			return 0, ErrInvalidAPIKey
+		}
+		return 0, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		switch err {
+		case repository.ErrUserNotFound:
+			return 0, ErrInvalidAPIKey
+		case repository.ErrTooManyAttempts:
+			return 0, ErrAccountLocked
+		case repository.ErrUserDisabled:
+			return 0, ErrAccountDisabled
+		case repository.ErrTenantSuspended:
+			return 0, ErrTenantSuspended
+		}
+		return 0, err
+	}
+	if s.lockout.IsLocked(user.FailedAttempts) {
+		return 0, ErrAccountLocked
+	}
+
+	return user.ID, nil
+}
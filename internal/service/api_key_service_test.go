@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+	apiKeyService := NewAPIKeyService(test.NewMockAPIKeyRepository(), mockRepo, authService.LockoutPolicy())
+
+	user, err := authService.RegisterUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	key, plaintext, err := apiKeyService.CreateAPIKey(ctx, user.ID, "ci")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, key.Prefix) || key.KeyHash == plaintext {
+		t.Errorf("unexpected key %+v for plaintext %q", key, plaintext)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantUserID int64
+		wantErr    error
+	}{
+		{name: "valid key", key: plaintext, wantUserID: user.ID},
+		{name: "unknown key", key: "ak_unknown", wantErr: ErrInvalidAPIKey},
+		{name: "missing prefix", key: strings.TrimPrefix(plaintext, "ak_"), wantErr: ErrInvalidAPIKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := apiKeyService.ValidateAPIKey(ctx, tt.key)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if userID != tt.wantUserID {
+				t.Errorf("got user %d, want %d", userID, tt.wantUserID)
+			}
+		})
+	}
+
+	t.Run("other users cannot revoke", func(t *testing.T) {
+		if err := apiKeyService.RevokeAPIKey(ctx, user.ID+1, key.ID); err != repository.ErrAPIKeyNotFound {
+			t.Errorf("got error %v, want %v", err, repository.ErrAPIKeyNotFound)
+		}
+	})
+
+	t.Run("revoked key is rejected", func(t *testing.T) {
+		if err := apiKeyService.RevokeAPIKey(ctx, user.ID, key.ID); err != nil {
+			t.Fatalf("failed to revoke key: %v", err)
+		}
+		if _, err := apiKeyService.ValidateAPIKey(ctx, plaintext); err != ErrInvalidAPIKey {
+			t.Errorf("got error %v, want %v", err, ErrInvalidAPIKey)
+		}
+	})
+}
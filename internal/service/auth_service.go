@@ -3,117 +3,926 @@ package service
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/device"
+	"github.com/Stewz00/go-auth-service/internal/email"
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/i18n"
 	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/metering"
+	"github.com/Stewz00/go-auth-service/internal/metrics"
 	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/password"
 	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/tracing"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// tracer records spans for the expensive steps of sign-in, so slow logins can
+// be attributed to password hashing, the database or the network
+var tracer = tracing.Tracer()
+
 var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrAccountLocked      = errors.New("account is locked due to too many failed attempts")
+	ErrAccountDisabled    = errors.New("account is disabled")
 	ErrInvalidToken       = errors.New("invalid token")
-	ErrTokenExpired       = errors.New("token has expired")
+	ErrTokenExpired       = error(tokenExpiredError{})
+	ErrCanaryAccount      = errors.New("sign-in attempt against canary account")
+	ErrInvalidUserScope   = errors.New("requested scope is not allowed for users")
+	ErrTenantSuspended    = errors.New("tenant is suspended")
+	ErrLoginThrottled     = errors.New("too many failed sign-in attempts from this address")
+	ErrBreachCheckFailed  = errors.New("could not check the password against known breaches")
+	ErrPasswordExpired    = errors.New("password has expired and must be changed")
 )
 
+// tokenExpiredError matches jwt.ErrTokenExpired, so middleware that only sees
+// a TokenValidator can tell expired tokens apart
+type tokenExpiredError struct{}
+
+func (tokenExpiredError) Error() string { return "token has expired" }
+
+func (tokenExpiredError) Is(target error) bool { return target == jwt.ErrTokenExpired }
+
+// DefaultUserScopes are granted to user tokens when no scope is requested
+var DefaultUserScopes = []string{"profile", "consents", "api-keys"}
+
 type AuthService struct {
-	userRepo    interfaces.UserRepository
-	jwtSecret   []byte
-	tokenExpiry time.Duration
+	userRepo    interfaces.UserStore
+	sessions    interfaces.SessionStore
+	jwtKeys     atomic.Pointer[jwtKeys] // replaced by SetJWTSecrets on reload
+	tokenExpiry atomic.Int64            // a time.Duration; replaced by SetTokenExpiry
+	rememberFor atomic.Int64            // a time.Duration, zero ignoring remember me; replaced by SetRememberMeExpiry
+	userScopes  []string
+	lockout     model.LockoutPolicy
+	tenantRepo  interfaces.TenantRepository // nil when tenants are not used
+	meter       *metering.Meter             // nil disables usage metering
+	security    *metrics.Security           // nil disables security metrics
+	metrics     *metrics.Auth               // nil disables authentication metrics
+	throttle    *LoginThrottle              // nil disables per-address throttling
+	auditLogger audit.Logger                // nil disables audit events
+	publisher   events.Publisher            // nil disables published events
+	mailer      email.Sender                // nil disables account emails
+	templates   *email.Templates            // nil uses email.DefaultTemplates
+	hasher      *password.Hasher
+	policy      password.Policy
+	breaches    password.BreachChecker // nil disables breached password checks
+	failOpen    bool                   // accept passwords when breaches cannot be checked
+	maxAge      time.Duration          // zero disables password expiry by age
+	bindDevice  bool                   // reject tokens used from another device
+	domains     *EmailDomainPolicy     // nil allows registrations from every email domain
+	foldGmail   bool                   // treat Gmail addresses that reach the same inbox as one
+	cache       *SessionCache          // nil checks every token against the session store
+
+	// Reused across requests to keep token validation allocation-free where possible
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+}
+
+// jwtKeys are the HMAC keys tokens are signed and validated with
+type jwtKeys struct {
+	sign   []byte
+	verify any // sign, or a jwt.VerificationKeySet adding the previous keys
+}
+
+// AuthServiceOption configures optional AuthService settings
+type AuthServiceOption func(*AuthService)
+
+// WithSessionCache caches whether sessions are valid, so validating a token
+// does not always query the session store. Sessions revoked through this
+// service are dropped from the cache at once.
+func WithSessionCache(cache *SessionCache) AuthServiceOption {
+	return func(s *AuthService) {
+		s.cache = cache
+	}
+}
+
+// WithEmailDomainPolicy refuses registrations with email addresses at the
+// domains policy blocks
+func WithEmailDomainPolicy(policy *EmailDomainPolicy) AuthServiceOption {
+	return func(s *AuthService) {
+		s.domains = policy
+	}
+}
+
+// WithGmailFolding stores and looks up Gmail addresses by the inbox they reach,
+// see model.FoldGmailAddress, so j.doe+shop@gmail.com signs in as
+// jdoe@gmail.com and cannot register a second account
+func WithGmailFolding() AuthServiceOption {
+	return func(s *AuthService) {
+		s.foldGmail = true
+	}
+}
+
+// WithUserScopes sets the scopes users may request at login, replacing DefaultUserScopes
+func WithUserScopes(scopes ...string) AuthServiceOption {
+	return func(s *AuthService) {
+		s.userScopes = scopes
+	}
+}
+
+// WithLockoutPolicy sets when failed logins lock an account, replacing model.DefaultLockoutPolicy
+func WithLockoutPolicy(policy model.LockoutPolicy) AuthServiceOption {
+	return func(s *AuthService) {
+		s.lockout = policy
+	}
+}
+
+// WithTenants applies each tenant's lockout policy to its users
+func WithTenants(tenantRepo interfaces.TenantRepository) AuthServiceOption {
+	return func(s *AuthService) {
+		s.tenantRepo = tenantRepo
+	}
+}
+
+// WithUsageMeter counts logins, active users, and issued tokens for billing
+func WithUsageMeter(meter *metering.Meter) AuthServiceOption {
+	return func(s *AuthService) {
+		s.meter = meter
+	}
+}
+
+// WithSecurityMetrics counts account lockouts in the security metrics
+func WithSecurityMetrics(m *metrics.Security) AuthServiceOption {
+	return func(s *AuthService) {
+		s.security = m
+	}
+}
+
+// WithAuthMetrics counts sign-ins, registrations and token validations
+func WithAuthMetrics(m *metrics.Auth) AuthServiceOption {
+	return func(s *AuthService) {
+		s.metrics = m
+	}
+}
+
+// WithLoginThrottle limits failed sign-ins per client address, for requests
+// whose context carries one from ContextWithClientIP
+func WithLoginThrottle(throttle *LoginThrottle) AuthServiceOption {
+	return func(s *AuthService) {
+		s.throttle = throttle
+	}
+}
+
+// WithAuditLogger records registrations, sign-ins, lockouts and logouts
+func WithAuditLogger(logger audit.Logger) AuthServiceOption {
+	return func(s *AuthService) {
+		s.auditLogger = logger
+	}
 }
 
-// NewAuthService creates a new authentication service
-func NewAuthService(userRepo interfaces.UserRepository, jwtSecret string) *AuthService {
-	return &AuthService{
-		userRepo:    userRepo,
-		jwtSecret:   []byte(jwtSecret),
-		tokenExpiry: 24 * time.Hour, // tokens expire after 24 hours
+// WithEventPublisher publishes registrations, sign-ins, lockouts and session
+// revocations to other systems. With a user repository that keeps an outbox
+// (interfaces.EventOutbox), events are stored there instead and an
+// events.OutboxRelay must publish them.
+func WithEventPublisher(publisher events.Publisher) AuthServiceOption {
+	return func(s *AuthService) {
+		s.publisher = publisher
 	}
 }
 
-// RegisterUser creates a new user account with a hashed password
+// WithEmailSender emails users when their account is locked or an
+// administrator resets their password
+func WithEmailSender(sender email.Sender) AuthServiceOption {
+	return func(s *AuthService) {
+		s.mailer = sender
+	}
+}
+
+// WithEmailTemplates renders account emails from a deployment's templates
+// instead of the embedded ones
+func WithEmailTemplates(templates *email.Templates) AuthServiceOption {
+	return func(s *AuthService) {
+		s.templates = templates
+	}
+}
+
+// WithPasswordHasher hashes new passwords with hasher, replacing password.Default
+func WithPasswordHasher(hasher *password.Hasher) AuthServiceOption {
+	return func(s *AuthService) {
+		s.hasher = hasher
+	}
+}
+
+// WithPasswordPolicy sets the passwords users may choose, replacing
+// password.DefaultPolicy
+func WithPasswordPolicy(policy password.Policy) AuthServiceOption {
+	return func(s *AuthService) {
+		s.policy = policy
+	}
+}
+
+// WithBreachCheck rejects new passwords that checker finds in a data breach.
+// When the check fails, failOpen accepts the password rather than failing
+// with ErrBreachCheckFailed.
+func WithBreachCheck(checker password.BreachChecker, failOpen bool) AuthServiceOption {
+	return func(s *AuthService) {
+		s.breaches = checker
+		s.failOpen = failOpen
+	}
+}
+
+// WithPasswordMaxAge expires passwords that have not been changed for maxAge.
+// Users signing in with an expired password can only change it.
+func WithPasswordMaxAge(maxAge time.Duration) AuthServiceOption {
+	return func(s *AuthService) {
+		s.maxAge = maxAge
+	}
+}
+
+// WithDeviceBinding rejects tokens issued to a client that sent a device
+// fingerprint when they are used without the same fingerprint
+func WithDeviceBinding() AuthServiceOption {
+	return func(s *AuthService) {
+		s.bindDevice = true
+	}
+}
+
+// DefaultRememberMeExpiry is how long tokens issued with remember me stay valid
+const DefaultRememberMeExpiry = 30 * 24 * time.Hour
+
+// WithRememberMeExpiry sets how long tokens stay valid when the user asks to
+// be remembered (DefaultRememberMeExpiry by default). Zero ignores the
+// request and issues tokens for the usual expiry.
+func WithRememberMeExpiry(expiry time.Duration) AuthServiceOption {
+	return func(s *AuthService) {
+		s.rememberFor.Store(int64(expiry))
+	}
+}
+
+// WithTokenExpiry sets how long issued tokens stay valid (24 hours by default)
+func WithTokenExpiry(expiry time.Duration) AuthServiceOption {
+	return func(s *AuthService) {
+		s.tokenExpiry.Store(int64(expiry))
+	}
+}
+
+// WithPreviousJWTSecrets accepts tokens signed with earlier secrets, which
+// are never used to sign new ones
+func WithPreviousJWTSecrets(previous ...string) AuthServiceOption {
+	return func(s *AuthService) {
+		s.SetJWTSecrets(string(s.jwtKeys.Load().sign), previous...)
+	}
+}
+
+// NewAuthService creates a new authentication service keeping users and
+// sessions in the given stores. A UserRepository can be passed as both.
+func NewAuthService(userRepo interfaces.UserStore, sessions interfaces.SessionStore, jwtSecret string, opts ...AuthServiceOption) *AuthService {
+	s := &AuthService{
+		userRepo:   userRepo,
+		sessions:   sessions,
+		userScopes: DefaultUserScopes,
+		lockout:    model.DefaultLockoutPolicy,
+		hasher:     password.Default,
+		policy:     password.DefaultPolicy,
+		parser:     jwt.NewParser(),
+	}
+	s.SetJWTSecrets(jwtSecret)
+	s.tokenExpiry.Store(int64(24 * time.Hour)) // tokens expire after 24 hours
+	s.rememberFor.Store(int64(DefaultRememberMeExpiry))
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.keyFunc = func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return s.jwtKeys.Load().verify, nil
+	}
+	return s
+}
+
+// SetJWTSecrets replaces the secret new tokens are signed with. Tokens signed
+// with it or one of the previous secrets are accepted; tokens signed with any
+// other secret stop validating at once.
+func (s *AuthService) SetJWTSecrets(secret string, previous ...string) {
+	keys := &jwtKeys{sign: []byte(secret)}
+	keys.verify = keys.sign
+	if len(previous) > 0 {
+		set := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{keys.sign}}
+		for _, p := range previous {
+			set.Keys = append(set.Keys, []byte(p))
+		}
+		keys.verify = set
+	}
+	s.jwtKeys.Store(keys)
+}
+
+// SetTokenExpiry changes how long tokens issued from now on stay valid
+func (s *AuthService) SetTokenExpiry(expiry time.Duration) {
+	s.tokenExpiry.Store(int64(expiry))
+}
+
+// SetRememberMeExpiry changes how long tokens issued from now on to users who
+// ask to be remembered stay valid
+func (s *AuthService) SetRememberMeExpiry(expiry time.Duration) {
+	s.rememberFor.Store(int64(expiry))
+}
+
+// RegisterUser creates a new user account with a hashed password. A password
+// the policy rejects, or found in a breach, fails with password.Violations.
 func (s *AuthService) RegisterUser(ctx context.Context, email, password string) (*model.User, error) {
-	// Hash the password with a cost factor of 12 (recommended minimum)
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	return s.RegisterUserWithProfile(ctx, email, password, model.Profile{})
+}
+
+// RegisterUserWithProfile creates a new user account like RegisterUser, with
+// the profile given on the sign-up form
+func (s *AuthService) RegisterUserWithProfile(ctx context.Context, email, password string, profile model.Profile) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+
+	email = s.NormalizeEmail(email)
+	if err := s.domains.Check(ctx, email); err != nil {
+		if err == ErrEmailDomainBlocked {
+			s.record(ctx, audit.Event{
+				Type:     "auth.registration_blocked",
+				Severity: audit.SeverityWarning,
+				Details:  map[string]any{"email": email},
+			})
+		}
+		return nil, err
+	}
+	if err := s.checkNewPassword(ctx, password, email); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hashPassword(ctx, s.hasher, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+	err = s.inTx(ctx, func(repo interfaces.UserRepository) error {
+		var err error
+		user, err = repo.CreateUser(ctx, email, hashedPassword)
+		if err != nil {
+			return err
+		}
+		if profile != (model.Profile{}) {
+			if err := repo.UpdateUserProfile(ctx, user.ID, profile); err != nil {
+				return err
+			}
+			user.Profile = profile
+		}
+		return s.emit(ctx, repo, events.UserRegistered, map[string]any{"user_id": user.ID, "email": user.Email})
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.metrics.Registered()
+	s.record(ctx, audit.Event{
+		Type:     "auth.registered",
+		Severity: audit.SeverityInfo,
+		ActorID:  user.ID,
+		Details:  map[string]any{"email": email},
+	})
+	return user, nil
+}
+
+// NormalizeEmail returns the form an email address is stored and looked up
+// in: trimmed and lowercased, and folded with WithGmailFolding
+func (s *AuthService) NormalizeEmail(email string) string {
+	email = model.NormalizeEmail(email)
+	if s.foldGmail {
+		email = model.FoldGmailAddress(email)
+	}
+	return email
+}
 
-	return s.userRepo.CreateUser(ctx, email, string(hashedPassword))
+// userByEmail looks up the account of an email address. With Gmail folding,
+// accounts registered before it was enabled are found by their own address.
+func (s *AuthService) userByEmail(ctx context.Context, email string) (*model.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, s.NormalizeEmail(email))
+	if err == repository.ErrUserNotFound && s.foldGmail {
+		return s.userRepo.GetUserByEmail(ctx, email)
+	}
+	return user, err
 }
 
-// LoginUser authenticates a user and returns a JWT token
+// checkNewPassword checks a password a user chose against the policy and,
+// when configured, known breaches
+func (s *AuthService) checkNewPassword(ctx context.Context, plain, email string) error {
+	if err := s.policy.Check(plain, email); err != nil {
+		return err
+	}
+	if s.breaches == nil {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "password.Breached")
+	defer span.End()
+	breached, err := s.breaches.Breached(ctx, plain)
+	switch {
+	case err != nil && s.failOpen:
+		slog.WarnContext(ctx, "breached password check failed, accepting the password", "err", err)
+	case err != nil:
+		slog.ErrorContext(ctx, "breached password check failed", "err", err)
+		return ErrBreachCheckFailed
+	case breached:
+		return password.Violations{{Code: password.ViolationBreached, Message: "has appeared in a data breach; choose another"}}
+	}
+	return nil
+}
+
+// hashPassword hashes a password with Argon2id, or bcrypt when configured
+func hashPassword(ctx context.Context, hasher *password.Hasher, plain string) (string, error) {
+	ctx, span := tracer.Start(ctx, "password.Hash")
+	defer span.End()
+
+	return hasher.Hash(ctx, plain)
+}
+
+// verifyPassword checks a password against its Argon2id or bcrypt hash and
+// reports whether the hash should be upgraded
+func (s *AuthService) verifyPassword(ctx context.Context, hash, plain string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "password.Verify")
+	defer span.End()
+
+	rehash, err := s.hasher.Verify(ctx, hash, plain)
+	if err == password.ErrUnknownPepper {
+		slog.ErrorContext(ctx, "password hash uses a pepper that is not configured", "err", err)
+	}
+	return rehash, err
+}
+
+// isWrongPassword reports whether a verifyPassword error counts as a failed
+// attempt, rather than a configuration problem the user cannot fix
+func isWrongPassword(err error) bool {
+	return err != password.ErrUnknownPepper
+}
+
+// upgradePassword re-hashes the password of a user who just signed in with a
+// bcrypt or outdated Argon2id hash. Failures are logged: the user signed in
+// and the upgrade is retried on the next sign-in.
+func (s *AuthService) upgradePassword(ctx context.Context, user *model.User, plain string) {
+	hash, err := hashPassword(ctx, s.hasher, plain)
+	if err == nil {
+		err = s.userRepo.RehashPassword(ctx, user.ID, user.Password, hash)
+	}
+	switch err {
+	case nil:
+		user.Password = hash
+	case repository.ErrUserNotFound:
+		// The password changed since it was verified; keep the new one
+	default:
+		slog.ErrorContext(ctx, "failed to upgrade password hash", "user_id", user.ID, "err", err)
+	}
+}
+
+// LoginUser authenticates a user and returns a JWT token with the default scopes
 func (s *AuthService) LoginUser(ctx context.Context, email, password string) (string, error) {
-	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	return s.LoginUserWithScope(ctx, email, password, "")
+}
+
+// LoginUserWithScope authenticates a user and returns a JWT token limited to the
+// requested space-separated scopes, or carrying every user scope when none are requested
+func (s *AuthService) LoginUserWithScope(ctx context.Context, email, password, scope string) (string, error) {
+	result, err := s.Login(ctx, email, password, scope, false)
+	if err != nil {
+		return "", err
+	}
+	return result.Token, nil
+}
+
+// LoginResult is the outcome of a password login
+type LoginResult struct {
+	Token  string
+	UserID int64
+	// PasswordExpired is set when the token may only be used to change the
+	// password, see PasswordChangeTokens
+	PasswordExpired bool
+	// RememberMe is set when the token lives for RememberMeExpiry rather than
+	// TokenExpiry
+	RememberMe bool
+}
+
+// Login authenticates a user like LoginUserWithScope. A user whose password
+// expired still signs in, but with a token that only allows changing it.
+// With rememberMe, the token lives for RememberMeExpiry.
+func (s *AuthService) Login(ctx context.Context, email, password, scope string, rememberMe bool) (*LoginResult, error) {
+	scope, err := s.resolveScope(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.Authenticate(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := s.PasswordExpired(user)
+	// A token that only allows changing the password is never long-lived
+	remember := rememberMe && !expired && s.RememberMeExpiry() > 0
+	token, err := s.signIn(ctx, user, scope, "", expired, remember)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Token: token, UserID: user.ID, PasswordExpired: expired, RememberMe: remember}, nil
+}
+
+// PasswordExpired reports whether a user must change their password, because
+// an admin forced it or it is older than the configured maximum age
+func (s *AuthService) PasswordExpired(user *model.User) bool {
+	return user.PasswordExpired(s.maxAge, time.Now())
+}
+
+// ChangePassword replaces the password of a signed-in user who proved they
+// know the current one. The new password must differ from it and pass the
+// same checks as at registration. Every session of the user is revoked, and
+// a new token is returned in place of the one used.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, current, next string) (string, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
-			return "", ErrInvalidCredentials
+			return "", ErrInvalidToken
 		}
 		return "", err
 	}
+	if user.IsServiceAccount() {
+		return "", ErrInvalidToken
+	}
+	if _, err := s.verifyPassword(ctx, user.Password, current); err == password.ErrMismatch {
+		return "", ErrInvalidCredentials
+	} else if err != nil {
+		return "", err
+	}
+	if next == current {
+		return "", password.Violations{{Code: password.ViolationReused, Message: "must differ from the current password"}}
+	}
+	if err := s.checkNewPassword(ctx, next, user.Email); err != nil {
+		return "", err
+	}
+	hash, err := hashPassword(ctx, s.hasher, next)
+	if err != nil {
+		return "", err
+	}
+
+	// Changing the password without revoking the sessions would keep stolen
+	// ones valid, so the two writes always share a transaction
+	err = s.withTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repo.UpdatePassword(ctx, userID, hash); err != nil {
+			return err
+		}
+		revoked, err := repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.emitRevoked(ctx, repo, userID, RevokedByPasswordChange, revoked)
+	})
+	s.cache.ForgetUser(userID)
+	if err != nil {
+		return "", err
+	}
+	s.record(ctx, audit.Event{
+		Type:     "auth.password_changed",
+		Severity: audit.SeverityInfo,
+		ActorID:  userID,
+	})
+
+	scope := strings.Join(s.userScopes, " ")
+	return s.signIn(ctx, user, scope, "", false, false)
+}
+
+// resolveScope validates requested scopes against the user scopes
+func (s *AuthService) resolveScope(requested string) (string, error) {
+	if requested == "" {
+		return strings.Join(s.userScopes, " "), nil
+	}
+
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(s.userScopes, scope) {
+			return "", ErrInvalidUserScope
+		}
+	}
+	return strings.Join(strings.Fields(requested), " "), nil
+}
+
+// LockoutPolicy returns the policy that decides when accounts are locked
+func (s *AuthService) LockoutPolicy() model.LockoutPolicy {
+	return s.lockout
+}
+
+// TokenExpiry returns how long issued tokens stay valid
+func (s *AuthService) TokenExpiry() time.Duration {
+	return time.Duration(s.tokenExpiry.Load())
+}
+
+// RememberMeExpiry returns how long tokens issued with remember me stay
+// valid, or zero when remember me is turned off
+func (s *AuthService) RememberMeExpiry() time.Duration {
+	return time.Duration(s.rememberFor.Load())
+}
+
+// lockoutPolicyFor returns the lockout policy of the user's tenant, or the service policy
+func (s *AuthService) lockoutPolicyFor(ctx context.Context, user *model.User) (model.LockoutPolicy, error) {
+	if user.TenantID == nil || s.tenantRepo == nil {
+		return s.lockout, nil
+	}
+
+	tenant, err := s.tenantRepo.GetTenant(ctx, *user.TenantID)
+	if err != nil {
+		return model.LockoutPolicy{}, err
+	}
+	if tenant.Settings.Lockout != nil {
+		return *tenant.Settings.Lockout, nil
+	}
+	return s.lockout, nil
+}
+
+// Authenticate verifies a user's credentials, applying the account lockout
+// rules and the per-address login throttle. Failed attempts are recorded here;
+// the successful sign-in is recorded when a token is issued.
+func (s *AuthService) Authenticate(ctx context.Context, email, password string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	ip, normalized := clientIPFromContext(ctx), s.NormalizeEmail(email)
+	if !s.throttle.Allow(ip, normalized) {
+		return nil, ErrLoginThrottled
+	}
+
+	user, err := s.authenticate(ctx, email, password)
+	switch err {
+	case nil:
+		s.throttle.Succeeded(ip, normalized)
+	case ErrInvalidCredentials, ErrAccountLocked:
+		// Unknown accounts count too, since spraying guesses finds them
+		s.throttle.Failed(ip, normalized)
+	}
+	outcome := loginOutcome(err)
+	span.SetAttributes(attribute.String("auth.outcome", outcome))
+	if outcome == metrics.LoginError {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	s.metrics.Login(outcome)
+	s.recordLogin(ctx, normalized, user, outcome, err)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// loginOutcome classifies a sign-in result for the auth_logins_total metric
+func loginOutcome(err error) string {
+	switch err {
+	case nil:
+		return metrics.LoginSuccess
+	case ErrInvalidCredentials:
+		return metrics.LoginInvalidCredentials
+	case ErrAccountLocked:
+		return metrics.LoginLocked
+	case ErrLoginThrottled:
+		return metrics.LoginThrottled
+	case ErrCanaryAccount, ErrTenantSuspended, ErrAccountDisabled:
+		return metrics.LoginRejected
+	}
+	return metrics.LoginError
+}
+
+// recordLogin emits an audit event for a password sign-in attempt. Failed
+// attempts on an existing account carry its ID, so they show in the user's
+// login history.
+func (s *AuthService) recordLogin(ctx context.Context, email string, user *model.User, outcome string, err error) {
+	event := audit.Event{
+		Type:     audit.LoginSucceeded,
+		Severity: audit.SeverityInfo,
+		Details:  map[string]any{"email": email, "outcome": outcome},
+	}
+	if user != nil {
+		event.ActorID = user.ID
+	}
+	if err != nil {
+		event.Type = audit.LoginFailed
+		event.Severity = audit.SeverityWarning
+		event.Details["reason"] = err.Error()
+	}
+	s.record(ctx, event)
+}
+
+// authenticate checks a password sign-in. A failed attempt on an existing
+// account returns the account with the error, for recordLogin.
+func (s *AuthService) authenticate(ctx context.Context, email, password string) (*model.User, error) {
+	user, err := s.userByEmail(ctx, email)
+	if err != nil {
+		switch err {
+		case repository.ErrUserNotFound:
+			return nil, ErrInvalidCredentials
+		case repository.ErrTenantSuspended:
+			return nil, ErrTenantSuspended
+		case repository.ErrUserDisabled:
+			return nil, ErrAccountDisabled
+		}
+		return nil, err
+	}
+
+	// Canary accounts are never used legitimately. Fail before the password
+	// check so attempts neither lock the account nor reveal its password.
+	if user.IsCanary {
+		return nil, ErrCanaryAccount
+	}
+
+	// Service accounts have no password. Reject them like an unknown user,
+	// without counting a failed attempt that could lock the account.
+	if user.IsServiceAccount() {
+		return nil, ErrInvalidCredentials
+	}
+
+	lockout, err := s.lockoutPolicyFor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if account is already locked
-	if user.FailedAttempts >= 5 {
-		return "", ErrAccountLocked
+	if lockout.IsLocked(user.FailedAttempts) {
+		return user, ErrAccountLocked
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	rehash, err := s.verifyPassword(ctx, user.Password, password)
+	if err != nil && !isWrongPassword(err) {
+		return nil, err
+	}
+	if err != nil {
 		// Increment failed login attempts
-		if err := s.userRepo.IncrementFailedAttempts(ctx, user.ID); err != nil {
-			if err == repository.ErrTooManyAttempts {
-				return "", ErrAccountLocked
+		var locked bool
+		err := s.inTx(ctx, func(repo interfaces.UserRepository) error {
+			err := repo.IncrementFailedAttempts(ctx, user.ID, lockout)
+			if err != repository.ErrTooManyAttempts {
+				return err
 			}
-			return "", err
+			locked = true
+			return s.emit(ctx, repo, events.UserLocked, map[string]any{"user_id": user.ID, "email": user.Email})
+		})
+		if err != nil {
+			return nil, err
 		}
-		return "", ErrInvalidCredentials
+		if locked {
+			// This attempt locked the account
+			s.security.Lockout(user.TenantID)
+			s.record(ctx, audit.Event{
+				Type:     "auth.account_locked",
+				Severity: audit.SeverityHigh,
+				ActorID:  user.ID,
+				Details:  map[string]any{"email": user.Email, "failed_attempts": lockout.MaxFailedAttempts},
+			})
+			s.notifyLockout(ctx, user, lockout.MaxFailedAttempts)
+			return user, ErrAccountLocked
+		}
+		return user, ErrInvalidCredentials
+	}
+	if rehash {
+		s.upgradePassword(ctx, user, password)
 	}
 
-	// Reset failed attempts and update last login on successful authentication
-	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+	return user, nil
+}
+
+// signIn issues a token to a user who just authenticated, directly or through
+// the OAuth client clientID, and meters the login. Resetting the failed
+// attempts and storing the session happen atomically, so a failure never
+// leaves one without the other. A token for an expired password only allows
+// changing it, and a remembered one lives for RememberMeExpiry.
+func (s *AuthService) signIn(ctx context.Context, user *model.User, scope, clientID string, passwordExpired, remember bool) (string, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.signIn")
+	defer span.End()
+
+	expiry := s.TokenExpiry()
+	if remember {
+		expiry = s.RememberMeExpiry()
+	}
+	fingerprint := device.FromContext(ctx)
+	token, tokenID, expiresAt, err := s.signToken(user, scope, fingerprint, passwordExpired, expiry)
+	if err != nil {
+		return "", err
+	}
+	err = s.inTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repository.RecordLogin(ctx, repo, user.ID, tokenID, fingerprint, remember, expiresAt); err != nil {
+			return err
+		}
+		return s.emit(ctx, repo, events.UserLogin, map[string]any{
+			"user_id":    user.ID,
+			"email":      user.Email,
+			"client_id":  clientID,
+			"ip_address": clientIPFromContext(ctx),
+		})
+	})
+	if err != nil {
 		return "", err
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	key := usageKey(user, clientID)
+	s.meter.Login(key, user.ID)
+	s.meter.Count(key, model.UsageTokensIssued, 1)
+	return token, nil
+}
+
+// usageKey attributes usage to the user's tenant and the OAuth client, if any
+func usageKey(user *model.User, clientID string) model.UsageKey {
+	key := model.UsageKey{ClientID: clientID}
+	if user.TenantID != nil {
+		key.TenantID = *user.TenantID
+	}
+	return key
+}
+
+// signToken generates a signed JWT for the user valid for expiry, bound to the
+// hash of a device fingerprint if there is one, returning it with its token ID
+// and expiry for the session
+func (s *AuthService) signToken(user *model.User, scope, fingerprint string, passwordExpired bool, expiry time.Duration) (string, string, time.Time, error) {
+	claims := jwt.MapClaims{
 		"sub":   user.ID,
 		"email": user.Email,
-		"exp":   time.Now().Add(s.tokenExpiry).Unix(),
-		"jti":   generateTokenID(),
-	})
+		"scope": scope,
+	}
+	if fingerprint != "" {
+		claims[deviceClaim] = fingerprint
+	}
+	if passwordExpired {
+		claims[passwordExpiredClaim] = true
+	}
+	return s.signClaims(claims, expiry)
+}
 
-	// Sign and return the token
-	tokenString, err := token.SignedString(s.jwtSecret)
+// signClaims signs claims with a new token ID and an expiry after expiry
+func (s *AuthService) signClaims(claims jwt.MapClaims, expiry time.Duration) (string, string, time.Time, error) {
+	tokenID := generateTokenID()
+	expiresAt := time.Unix(time.Now().Add(expiry).Unix(), 0)
+	claims["exp"] = expiresAt.Unix()
+	claims["jti"] = tokenID
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	tokenString, err := token.SignedString(s.jwtKeys.Load().sign)
 	if err != nil {
-		return "", err
+		return "", "", time.Time{}, err
 	}
+	return tokenString, tokenID, expiresAt, nil
+}
 
-	// Store the session
-	claims := token.Claims.(jwt.MapClaims)
-	err = s.userRepo.CreateSession(
-		ctx,
-		user.ID,
-		claims["jti"].(string),
-		time.Unix(claims["exp"].(int64), 0),
-	)
+// ValidateToken validates a JWT token and returns the user claims
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ValidateToken")
+	defer span.End()
+
+	claims, err := s.validateToken(ctx, tokenString)
+	if err == nil && claims[passwordExpiredClaim] == true {
+		err = ErrPasswordExpired
+	}
+	switch err {
+	case nil, ErrPasswordExpired:
+		s.metrics.TokenValidation(metrics.TokenValid)
+	case ErrTokenExpired:
+		s.metrics.TokenValidation(metrics.TokenExpired)
+	case ErrInvalidToken:
+		s.metrics.TokenValidation(metrics.TokenInvalid)
+	default:
+		s.metrics.TokenValidation(metrics.TokenError)
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return claims, nil
+}
+
+// passwordExpiredClaim marks tokens issued for an expired password
+const passwordExpiredClaim = "pwd_expired"
 
-	return tokenString, nil
+// deviceClaim holds the hash of the device fingerprint a token was issued to
+const deviceClaim = "dfp"
+
+// PasswordChangeTokens returns a validator for the change-password endpoint,
+// which also accepts the tokens of users whose password expired
+func (s *AuthService) PasswordChangeTokens() PasswordChangeValidator {
+	return PasswordChangeValidator{s}
+}
+
+// PasswordChangeValidator validates tokens like AuthService.ValidateToken, but
+// accepts those issued for an expired password
+type PasswordChangeValidator struct {
+	s *AuthService
 }
 
 // ValidateToken validates a JWT token and returns the user claims
-func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return s.jwtSecret, nil
-	})
+func (v PasswordChangeValidator) ValidateToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ValidateToken")
+	defer span.End()
+
+	return v.s.validateToken(ctx, tokenString)
+}
+
+func (s *AuthService) validateToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	token, err := s.parser.Parse(tokenString, s.keyFunc)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -131,8 +940,20 @@ func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (jw
 		return nil, ErrInvalidToken
 	}
 
+	if s.bindDevice {
+		if bound, _ := claims[deviceClaim].(string); bound != "" && bound != device.FromContext(ctx) {
+			sub, _ := claims["sub"].(float64)
+			s.record(ctx, audit.Event{
+				Type:     "auth.device_mismatch",
+				Severity: audit.SeverityWarning,
+				ActorID:  int64(sub),
+			})
+			return nil, ErrInvalidToken
+		}
+	}
+
 	// Check if token is revoked
-	if valid, err := s.userRepo.IsSessionValid(ctx, claims["jti"].(string)); err != nil {
+	if valid, err := s.sessionValid(ctx, claims); err != nil {
 		return nil, err
 	} else if !valid {
 		return nil, ErrInvalidToken
@@ -141,14 +962,26 @@ func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (jw
 	return claims, nil
 }
 
+// sessionValid reports whether the session of a token was not revoked, from
+// the session cache when it holds the session
+func (s *AuthService) sessionValid(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	tokenID, _ := claims["jti"].(string)
+	if valid, ok := s.cache.Get(tokenID); ok {
+		return valid, nil
+	}
+	generation := s.cache.Generation()
+	valid, err := s.sessions.IsSessionValid(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+	sub, _ := claims["sub"].(float64)
+	s.cache.Put(tokenID, int64(sub), valid, generation)
+	return valid, nil
+}
+
 // LogoutUser revokes the user's token
 func (s *AuthService) LogoutUser(ctx context.Context, tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return s.jwtSecret, nil
-	})
+	token, err := s.parser.Parse(tokenString, s.keyFunc)
 	if err != nil {
 		return ErrInvalidToken
 	}
@@ -159,20 +992,151 @@ func (s *AuthService) LogoutUser(ctx context.Context, tokenString string) error
 	}
 
 	// Check if token is already revoked before attempting to revoke
-	if valid, err := s.userRepo.IsSessionValid(ctx, claims["jti"].(string)); err != nil {
+	if valid, err := s.sessions.IsSessionValid(ctx, claims["jti"].(string)); err != nil {
 		return err
 	} else if !valid {
 		return ErrInvalidToken
 	}
 
-	return s.userRepo.RevokeSession(ctx, claims["jti"].(string))
+	sub, _ := claims["sub"].(float64)
+	err = s.inTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repo.RevokeSession(ctx, claims["jti"].(string)); err != nil {
+			return err
+		}
+		return s.emitRevoked(ctx, repo, int64(sub), RevokedByLogout, 1)
+	})
+	s.cache.Forget(claims["jti"].(string))
+	if err != nil {
+		return err
+	}
+	s.record(ctx, audit.Event{
+		Type:     "auth.logout",
+		Severity: audit.SeverityInfo,
+		ActorID:  int64(sub),
+	})
+	return nil
+}
+
+// User returns the account of a signed-in user
+func (s *AuthService) User(ctx context.Context, userID int64) (*model.User, error) {
+	return s.userRepo.GetUserByID(ctx, userID)
+}
+
+// ListSessions returns a page of the user's active sessions, with the cursor
+// of the next page
+func (s *AuthService) ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	return s.sessions.ListSessions(ctx, userID, page)
+}
+
+// RevokeUserSessions revokes all of a user's active sessions, e.g. after a compromise
+func (s *AuthService) RevokeUserSessions(ctx context.Context, userID int64) (int64, error) {
+	var revoked int64
+	err := s.inTx(ctx, func(repo interfaces.UserRepository) error {
+		var err error
+		revoked, err = repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.emitRevoked(ctx, repo, userID, RevokedByAdmin, revoked)
+	})
+	s.cache.ForgetUser(userID)
+	return revoked, err
+}
+
+// SetCanary marks or unmarks a user as a canary account
+func (s *AuthService) SetCanary(ctx context.Context, userID int64, canary bool) error {
+	return s.userRepo.SetCanary(ctx, userID, canary)
+}
+
+// Reasons sessions are revoked, published in session.revoked events
+const (
+	RevokedByLogout         = "logout"
+	RevokedByAdmin          = "admin"
+	RevokedByDisable        = "disabled"
+	RevokedByPasswordReset  = "password_reset"
+	RevokedByPasswordChange = "password_change"
+	RevokedByPasswordExpiry = "password_expired"
+	RevokedByDeletion       = "deleted"
+)
+
+// notifyLockout emails a user that failed sign-ins locked their account
+func (s *AuthService) notifyLockout(ctx context.Context, user *model.User, failedAttempts int64) {
+	s.sendEmail(ctx, user.Email, email.TemplateLockout, email.LockoutData{FailedAttempts: failedAttempts})
+}
+
+// sendEmail renders and sends an account email, if there is a sender. A
+// failure is logged rather than failing the operation that already happened.
+func (s *AuthService) sendEmail(ctx context.Context, to, template string, data any) {
+	if s.mailer == nil {
+		return
+	}
+	templates := s.templates
+	if templates == nil {
+		templates = email.DefaultTemplates()
+	}
+	msg, err := templates.Message(i18n.FromContext(ctx), template, to, data)
+	if err == nil {
+		err = s.mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "email: failed to send", "template", template, "err", err)
+	}
+}
+
+// inTx runs fn with the user and session stores, in a transaction when the
+// service publishes events, so the events fn stores in the outbox commit
+// with its changes
+func (s *AuthService) inTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	if s.publisher == nil {
+		return fn(repository.NewCombinedRepository(s.userRepo, s.sessions))
+	}
+	return s.withTx(ctx, fn)
+}
+
+// withTx runs fn with the user and session stores in a transaction, for
+// changes that must not be left half done
+func (s *AuthService) withTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	return repository.NewCombinedRepository(s.userRepo, s.sessions).WithTx(ctx, fn)
+}
+
+// emitRevoked publishes that sessions of a user were revoked, like emit
+func (s *AuthService) emitRevoked(ctx context.Context, repo interfaces.UserRepository, userID int64, reason string, revoked int64) error {
+	return s.emit(ctx, repo, events.SessionRevoked, map[string]any{"user_id": userID, "reason": reason, "revoked": revoked})
+}
+
+// emit publishes an event of a change made through repo, if there is a
+// publisher. When repo keeps an outbox, the event is stored in it, in the
+// transaction of the change, and the outbox relay publishes it after the
+// commit. Otherwise it is published right away, and a failure is logged
+// rather than failing the change that already happened.
+func (s *AuthService) emit(ctx context.Context, repo interfaces.UserRepository, eventType string, data map[string]any) error {
+	if s.publisher == nil {
+		return nil
+	}
+	event := events.New(eventType, data)
+	if outbox, ok := repo.(interfaces.EventOutbox); ok {
+		stored, err := events.OutboxEvent(event)
+		if err != nil {
+			return err
+		}
+		return outbox.AddOutboxEvent(ctx, stored)
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "events: failed to publish event", "event_type", eventType, "err", err)
+	}
+	return nil
+}
+
+// record sends an event to the audit logger, if there is one
+func (s *AuthService) record(ctx context.Context, event audit.Event) {
+	if s.auditLogger != nil {
+		s.auditLogger.Record(ctx, event)
+	}
 }
 
-// Helper function to generate a unique token ID
+// generateTokenID returns a random token ID, which identifies the session of
+// a token; crypto/rand does not fail, so neither does this
 func generateTokenID() string {
-	// Simple implementation - in production, use a more robust method
-	token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
-		"rand": time.Now().UnixNano(),
-	}).SignedString(nil)
-	return token
+	id, _ := randomToken(16)
+	return id
 }
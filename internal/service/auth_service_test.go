@@ -2,16 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/device"
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/repository"
 	"github.com/Stewz00/go-auth-service/internal/test"
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestRegisterUser(t *testing.T) {
 	mockRepo := test.NewMockUserRepository()
-	authService := NewAuthService(mockRepo, "test-secret")
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
 
 	tests := []struct {
 		name        string
@@ -33,6 +43,13 @@ func TestRegisterUser(t *testing.T) {
 			wantErr:     true,
 			errContains: "email already exists",
 		},
+		{
+			name:        "duplicate email in another case",
+			email:       " Test@Example.COM ",
+			password:    "password123",
+			wantErr:     true,
+			errContains: "email already exists",
+		},
 	}
 
 	for _, tt := range tests {
@@ -64,7 +81,7 @@ func TestRegisterUser(t *testing.T) {
 
 func TestLoginUser(t *testing.T) {
 	mockRepo := test.NewMockUserRepository()
-	authService := NewAuthService(mockRepo, "test-secret")
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
 
 	// Register a test user first
 	email := "test@example.com"
@@ -74,6 +91,15 @@ func TestLoginUser(t *testing.T) {
 		t.Fatalf("failed to create test user: %v", err)
 	}
 
+	// And a canary account
+	canary, err := authService.RegisterUser(context.Background(), "canary@example.com", password)
+	if err != nil {
+		t.Fatalf("failed to create canary user: %v", err)
+	}
+	if err := authService.SetCanary(context.Background(), canary.ID, true); err != nil {
+		t.Fatalf("failed to mark canary user: %v", err)
+	}
+
 	tests := []struct {
 		name        string
 		email       string
@@ -87,6 +113,12 @@ func TestLoginUser(t *testing.T) {
 			password: password,
 			wantErr:  false,
 		},
+		{
+			name:     "email in another case",
+			email:    " TEST@Example.com",
+			password: password,
+			wantErr:  false,
+		},
 		{
 			name:        "invalid password",
 			email:       email,
@@ -101,6 +133,13 @@ func TestLoginUser(t *testing.T) {
 			wantErr:     true,
 			errContains: "invalid email or password",
 		},
+		{
+			name:        "canary account with correct password",
+			email:       "canary@example.com",
+			password:    password,
+			wantErr:     true,
+			errContains: "canary",
+		},
 	}
 
 	for _, tt := range tests {
@@ -127,9 +166,302 @@ func TestLoginUser(t *testing.T) {
 	}
 }
 
+func TestGmailFolding(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithGmailFolding())
+
+	user, err := authService.RegisterUser(ctx, "J.Doe+shop@Gmail.com", "password123")
+	if err != nil {
+		t.Fatalf("RegisterUser() error = %v", err)
+	}
+	if user.Email != "jdoe@gmail.com" {
+		t.Errorf("got email %q, want jdoe@gmail.com", user.Email)
+	}
+	if _, err := authService.RegisterUser(ctx, "jdoe+other@googlemail.com", "password123"); err != repository.ErrDuplicateEmail {
+		t.Errorf("RegisterUser() for the same inbox: error = %v, want ErrDuplicateEmail", err)
+	}
+	if _, err := authService.LoginUser(ctx, "j.doe@gmail.com", "password123"); err != nil {
+		t.Errorf("LoginUser() with dots: error = %v", err)
+	}
+
+	// Accounts registered before folding was enabled still sign in
+	if _, err := NewAuthService(mockRepo, mockRepo, "test-secret").RegisterUser(ctx, "a.smith@gmail.com", "password123"); err != nil {
+		t.Fatalf("RegisterUser() without folding: error = %v", err)
+	}
+	if _, err := authService.LoginUser(ctx, "A.Smith@gmail.com", "password123"); err != nil {
+		t.Errorf("LoginUser() for an unfolded account: error = %v", err)
+	}
+}
+
+func TestLoginMigratesPasswordHash(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret",
+		WithPasswordHasher(password.NewHasher(password.Argon2Params{Memory: 64, Time: 1, Parallelism: 1})))
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user, err := mockRepo.CreateUser(ctx, "legacy@example.com", string(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := authService.LoginUser(ctx, user.Email, "wrongpassword"); err != ErrInvalidCredentials {
+		t.Fatalf("LoginUser() with a wrong password error = %v", err)
+	}
+	if stored, _ := mockRepo.GetUserByID(ctx, user.ID); stored.Password != string(legacy) {
+		t.Fatalf("password hash changed after a failed login: %q", stored.Password)
+	}
+
+	if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != nil {
+		t.Fatalf("LoginUser() with a bcrypt hash error = %v", err)
+	}
+	stored, _ := mockRepo.GetUserByID(ctx, user.ID)
+	if !strings.HasPrefix(stored.Password, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("password hash after login = %q, want an argon2id hash", stored.Password)
+	}
+
+	// The migrated hash keeps working and is left alone
+	if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != nil {
+		t.Fatalf("LoginUser() with an argon2id hash error = %v", err)
+	}
+	if again, _ := mockRepo.GetUserByID(ctx, user.ID); again.Password != stored.Password {
+		t.Error("argon2id hash with current parameters was re-hashed")
+	}
+}
+
+func TestPasswordExpiry(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithPasswordMaxAge(90*24*time.Hour))
+
+	if _, err := authService.RegisterUser(ctx, "rotate@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := authService.Login(ctx, "rotate@example.com", "password123", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PasswordExpired {
+		t.Fatal("fresh password reported as expired")
+	}
+
+	// Age the password past the maximum
+	user, _ := mockRepo.GetUserByEmail(ctx, "rotate@example.com")
+	user.PasswordChangedAt = time.Now().Add(-91 * 24 * time.Hour)
+
+	result, err = authService.Login(ctx, "rotate@example.com", "password123", "", false)
+	if err != nil {
+		t.Fatalf("Login() with an expired password error = %v", err)
+	}
+	if !result.PasswordExpired {
+		t.Fatal("Login() did not report the expired password")
+	}
+	if _, err := authService.ValidateToken(ctx, result.Token); err != ErrPasswordExpired {
+		t.Fatalf("ValidateToken() error = %v, want ErrPasswordExpired", err)
+	}
+	if _, err := authService.PasswordChangeTokens().ValidateToken(ctx, result.Token); err != nil {
+		t.Fatalf("PasswordChangeTokens().ValidateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		current  string
+		next     string
+		wantErr  error
+		wantCode string
+	}{
+		{"wrong current password", "wrongpassword", "newpassword456", ErrInvalidCredentials, ""},
+		{"same password", "password123", "password123", nil, password.ViolationReused},
+		{"policy violation", "password123", "short", nil, password.ViolationTooShort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authService.ChangePassword(ctx, user.ID, tt.current, tt.next)
+			var violations password.Violations
+			switch {
+			case tt.wantErr != nil && err != tt.wantErr:
+				t.Fatalf("ChangePassword() error = %v, want %v", err, tt.wantErr)
+			case tt.wantCode != "" && (!errors.As(err, &violations) || violations[0].Code != tt.wantCode):
+				t.Fatalf("ChangePassword() error = %v, want violation %s", err, tt.wantCode)
+			}
+		})
+	}
+
+	token, err := authService.ChangePassword(ctx, user.ID, "password123", "newpassword456")
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err := authService.ValidateToken(ctx, token); err != nil {
+		t.Fatalf("ValidateToken() after the change error = %v", err)
+	}
+	result, err = authService.Login(ctx, "rotate@example.com", "newpassword456", "", false)
+	if err != nil || result.PasswordExpired {
+		t.Fatalf("Login() after the change = %+v, %v; want a token for a current password", result, err)
+	}
+
+	// An admin can force expiry regardless of age
+	if err := mockRepo.ExpirePassword(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = authService.Login(ctx, "rotate@example.com", "newpassword456", "", false); err != nil || !result.PasswordExpired {
+		t.Fatalf("Login() after a forced expiry = %+v, %v; want an expired password", result, err)
+	}
+}
+
+// rollbackRepository fails to revoke sessions and applies the password
+// changes of a unit of work only when it succeeds, like a transaction
+type rollbackRepository struct {
+	*test.MockUserRepository
+}
+
+func (r *rollbackRepository) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	return 0, errors.New("session store unavailable")
+}
+
+func (r *rollbackRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	tx := &rollbackTx{rollbackRepository: r, passwords: make(map[int64]string)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for userID, hash := range tx.passwords {
+		if err := r.MockUserRepository.UpdatePassword(ctx, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackTx holds the password changes of a rollbackRepository unit of work
+type rollbackTx struct {
+	*rollbackRepository
+	passwords map[int64]string
+}
+
+func (tx *rollbackTx) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	tx.passwords[userID] = passwordHash
+	return nil
+}
+
+func TestChangePasswordKeepsPasswordWhenRevokeFails(t *testing.T) {
+	ctx := context.Background()
+	repo := &rollbackRepository{MockUserRepository: test.NewMockUserRepository()}
+	authService := NewAuthService(repo, repo, "test-secret")
+
+	user, err := authService.RegisterUser(ctx, "revoke@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	if _, err := authService.ChangePassword(ctx, user.ID, "password123", "newpassword456"); err == nil {
+		t.Fatal("ChangePassword() succeeded although the sessions were not revoked")
+	}
+	if _, err := authService.LoginUser(ctx, "revoke@example.com", "password123"); err != nil {
+		t.Errorf("Login() with the old password error = %v; want the password unchanged", err)
+	}
+	if _, err := authService.LoginUser(ctx, "revoke@example.com", "newpassword456"); err != ErrInvalidCredentials {
+		t.Errorf("Login() with the new password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+// fakeBreaches reports the passwords in breached, or fails with err
+type fakeBreaches struct {
+	breached map[string]bool
+	err      error
+	calls    int
+}
+
+func (f *fakeBreaches) Breached(ctx context.Context, plain string) (bool, error) {
+	f.calls++
+	return f.breached[plain], f.err
+}
+
+func TestRegisterUserBreachCheck(t *testing.T) {
+	unavailable := errors.New("pwned passwords unreachable")
+
+	tests := []struct {
+		name      string
+		password  string
+		err       error
+		failOpen  bool
+		wantErr   error
+		wantCode  string
+		wantCalls int
+	}{
+		{name: "not breached", password: "kestrel-lantern-91", wantCalls: 1},
+		{name: "breached", password: "password123", wantCode: password.ViolationBreached, wantCalls: 1},
+		{name: "too short is not looked up", password: "short", wantCode: password.ViolationTooShort},
+		{name: "check fails open", password: "kestrel-lantern-91", err: unavailable, failOpen: true, wantCalls: 1},
+		{name: "check fails closed", password: "kestrel-lantern-91", err: unavailable, wantErr: ErrBreachCheckFailed, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := test.NewMockUserRepository()
+			breaches := &fakeBreaches{breached: map[string]bool{"password123": true}, err: tt.err}
+			authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithBreachCheck(breaches, tt.failOpen))
+
+			_, err := authService.RegisterUser(context.Background(), "jane@example.com", tt.password)
+			var violations password.Violations
+			switch {
+			case tt.wantCode != "":
+				if !errors.As(err, &violations) || violations[0].Code != tt.wantCode {
+					t.Errorf("RegisterUser() error = %v, want a %s violation", err, tt.wantCode)
+				}
+			case err != tt.wantErr:
+				t.Errorf("RegisterUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if breaches.calls != tt.wantCalls {
+				t.Errorf("breach checker called %d times, want %d", breaches.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestLoginUserWithScope(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithUserScopes("profile", "users:write"))
+
+	email := "test@example.com"
+	password := "password123"
+	if _, err := authService.RegisterUser(context.Background(), email, password); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		scope     string
+		wantScope string
+		wantErr   error
+	}{
+		{name: "defaults to all user scopes", wantScope: "profile users:write"},
+		{name: "narrower scope", scope: "users:write", wantScope: "users:write"},
+		{name: "scope not allowed", scope: "profile admin", wantErr: ErrInvalidUserScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := authService.LoginUserWithScope(context.Background(), email, password, tt.scope)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			claims, err := authService.ValidateToken(context.Background(), token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims["scope"] != tt.wantScope {
+				t.Errorf("got scope %v, want %q", claims["scope"], tt.wantScope)
+			}
+		})
+	}
+}
+
 func TestValidateToken(t *testing.T) {
 	mockRepo := test.NewMockUserRepository()
-	authService := NewAuthService(mockRepo, "test-secret")
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
 
 	// Create and login a test user to get a valid token
 	email := "test@example.com"
@@ -200,9 +532,152 @@ func TestValidateToken(t *testing.T) {
 	}
 }
 
+func TestJWTSecretRotation(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "old-secret")
+	if _, err := authService.RegisterUser(context.Background(), "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	oldToken, err := authService.LoginUser(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to login test user: %v", err)
+	}
+
+	authService.SetJWTSecrets("new-secret", "old-secret")
+	newToken, err := authService.LoginUser(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to login test user: %v", err)
+	}
+	signedWith := func(token, secret string) bool {
+		_, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return []byte(secret), nil })
+		return err == nil
+	}
+	if !signedWith(newToken, "new-secret") {
+		t.Error("new tokens should be signed with the first secret")
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := authService.ValidateToken(context.Background(), token); err != nil {
+			t.Errorf("%s token rejected during rotation: %v", name, err)
+		}
+	}
+
+	authService.SetJWTSecrets("new-secret")
+	if _, err := authService.ValidateToken(context.Background(), oldToken); err != ErrInvalidToken {
+		t.Errorf("old token after the old secret was dropped: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := authService.ValidateToken(context.Background(), newToken); err != nil {
+		t.Errorf("new token rejected: %v", err)
+	}
+}
+
+func TestDeviceBinding(t *testing.T) {
+	recorder := &eventRecorder{}
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithDeviceBinding(), WithAuditLogger(recorder))
+	if _, err := authService.RegisterUser(context.Background(), "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	phone := device.WithFingerprint(context.Background(), "phone")
+	bound, err := authService.LoginUser(phone, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to login test user: %v", err)
+	}
+	sessions, _, err := authService.ListSessions(context.Background(), 1, pagination.Page{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].DeviceFingerprint != device.Hash("phone") {
+		t.Errorf("got sessions %+v, want one bound to the phone", sessions)
+	}
+	unbound, err := authService.LoginUser(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to login test user: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		token   string
+		wantErr error
+	}{
+		{name: "same device", ctx: phone, token: bound},
+		{name: "other device", ctx: device.WithFingerprint(context.Background(), "laptop"), token: bound, wantErr: ErrInvalidToken},
+		{name: "no fingerprint", ctx: context.Background(), token: bound, wantErr: ErrInvalidToken},
+		{name: "unbound token", ctx: phone, token: unbound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := authService.ValidateToken(tt.ctx, tt.token); err != tt.wantErr {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var mismatches int
+	for _, event := range recorder.events {
+		if event.Type == "auth.device_mismatch" {
+			mismatches++
+		}
+	}
+	if mismatches != 2 {
+		t.Errorf("got %d auth.device_mismatch events, want 2", mismatches)
+	}
+
+	// Without binding the fingerprint is only recorded
+	lenient := NewAuthService(mockRepo, mockRepo, "test-secret")
+	if _, err := lenient.ValidateToken(context.Background(), bound); err != nil {
+		t.Errorf("ValidateToken() without binding: %v", err)
+	}
+}
+
+func TestRememberMe(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithTokenExpiry(time.Hour), WithRememberMeExpiry(7*24*time.Hour))
+	ctx := context.Background()
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		rememberMe bool
+		expiry     time.Duration
+		want       time.Duration
+	}{
+		{name: "browser session", rememberMe: false, expiry: 7 * 24 * time.Hour, want: time.Hour},
+		{name: "remembered", rememberMe: true, expiry: 7 * 24 * time.Hour, want: 7 * 24 * time.Hour},
+		{name: "turned off", rememberMe: true, expiry: 0, want: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService.SetRememberMeExpiry(tt.expiry)
+			result, err := authService.Login(ctx, "test@example.com", "password123", "", tt.rememberMe)
+			if err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+			if result.RememberMe != (tt.want != time.Hour) {
+				t.Errorf("RememberMe = %v", result.RememberMe)
+			}
+			claims, err := authService.ValidateToken(ctx, result.Token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if lifetime := time.Until(time.Unix(int64(claims["exp"].(float64)), 0)); lifetime < tt.want-time.Minute || lifetime > tt.want {
+				t.Errorf("token lives %v, want %v", lifetime, tt.want)
+			}
+
+			sessions, _, err := authService.ListSessions(ctx, 1, pagination.Page{Limit: 10})
+			if err != nil || len(sessions) == 0 || sessions[len(sessions)-1].RememberMe != result.RememberMe {
+				t.Errorf("ListSessions() = %+v, %v; want the latest session with RememberMe %v", sessions, err, result.RememberMe)
+			}
+		})
+	}
+}
+
 func TestLogoutUser(t *testing.T) {
 	mockRepo := test.NewMockUserRepository()
-	authService := NewAuthService(mockRepo, "test-secret")
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
 
 	// Create and login a test user
 	email := "test@example.com"
@@ -265,6 +740,40 @@ func TestLogoutUser(t *testing.T) {
 	}
 }
 
+func TestLogoutKeepsOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	var tokens []string
+	tokenIDs := map[string]bool{}
+	for range 2 {
+		token, err := authService.LoginUser(ctx, "test@example.com", "password123")
+		if err != nil {
+			t.Fatalf("failed to login test user: %v", err)
+		}
+		claims, err := authService.ValidateToken(ctx, token)
+		if err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+		tokens = append(tokens, token)
+		tokenIDs[claims["jti"].(string)] = true
+	}
+	if len(tokenIDs) != 2 || tokenIDs[""] {
+		t.Fatalf("got token IDs %v, want two distinct IDs", tokenIDs)
+	}
+
+	if err := authService.LogoutUser(ctx, tokens[0]); err != nil {
+		t.Fatalf("LogoutUser() error = %v", err)
+	}
+	if _, err := authService.ValidateToken(ctx, tokens[1]); err != nil {
+		t.Errorf("got error %v for the other session after logout, want it valid", err)
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	for i := 0; i <= len(s)-len(substr); i++ {
@@ -274,3 +783,175 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+// eventRecorder collects audit events in memory
+type eventRecorder struct {
+	events []audit.Event
+}
+
+func (r *eventRecorder) Record(ctx context.Context, event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestAuthServiceAuditEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithAuditLogger(recorder))
+	ctx := context.Background()
+
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	token, err := authService.LoginUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to login test user: %v", err)
+	}
+	if err := authService.LogoutUser(ctx, token); err != nil {
+		t.Fatalf("failed to logout test user: %v", err)
+	}
+	if _, err := authService.LoginUser(ctx, "test@example.com", "wrongpassword"); err != ErrInvalidCredentials {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	want := []string{"auth.registered", "auth.login_succeeded", "auth.logout", "auth.login_failed"}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(recorder.events), len(want), recorder.events)
+	}
+	for i, event := range recorder.events {
+		if event.Type != want[i] {
+			t.Errorf("event %d: got type %q, want %q", i, event.Type, want[i])
+		}
+	}
+	// Failed attempts on the account carry its ID for the login history
+	for _, event := range recorder.events {
+		if event.ActorID != 1 {
+			t.Errorf("%s: got actor %d, want 1", event.Type, event.ActorID)
+		}
+	}
+	if reason := recorder.events[3].Details["reason"]; reason != ErrInvalidCredentials.Error() {
+		t.Errorf("got failure reason %v", reason)
+	}
+	if outcome := recorder.events[3].Details["outcome"]; outcome != "invalid_credentials" {
+		t.Errorf("got failure outcome %v", outcome)
+	}
+}
+
+// eventPublisher collects published events in memory
+type eventPublisher struct {
+	events []events.Event
+}
+
+func (p *eventPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestAuthServicePublishesEvents(t *testing.T) {
+	publisher := &eventPublisher{}
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithEventPublisher(publisher))
+	ctx := context.Background()
+
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	token, err := authService.LoginUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to login test user: %v", err)
+	}
+	if err := authService.LogoutUser(ctx, token); err != nil {
+		t.Fatalf("failed to logout test user: %v", err)
+	}
+	if _, err := authService.LoginUser(ctx, "test@example.com", "wrongpassword"); err != ErrInvalidCredentials {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := authService.RevokeUserSessions(ctx, 1); err != nil {
+		t.Fatalf("failed to revoke sessions: %v", err)
+	}
+
+	// Failed sign-ins publish nothing; lockouts are covered by the server tests
+	want := []string{events.UserRegistered, events.UserLogin, events.SessionRevoked, events.SessionRevoked}
+	if len(publisher.events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(publisher.events), len(want), publisher.events)
+	}
+	for i, event := range publisher.events {
+		if event.Type != want[i] || event.Data["user_id"] != int64(1) {
+			t.Errorf("event %d: got %s for user %v, want %s for user 1", i, event.Type, event.Data["user_id"], want[i])
+		}
+	}
+	for i, reason := range []string{RevokedByLogout, RevokedByAdmin} {
+		if got := publisher.events[2+i].Data["reason"]; got != reason {
+			t.Errorf("got revocation reason %v, want %v", got, reason)
+		}
+	}
+}
+
+func TestAuthServiceSeparateSessionStore(t *testing.T) {
+	users := test.NewMockUserRepository()
+	sessions := test.NewMockUserRepository()
+	authService := NewAuthService(users, sessions, "test-secret")
+	ctx := context.Background()
+
+	user, err := authService.RegisterUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	token, err := authService.LoginUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+
+	page := pagination.Page{Limit: 10}
+	if got, _, _ := sessions.ListSessions(ctx, user.ID, page); len(got) != 1 {
+		t.Errorf("session store holds %d sessions, want 1", len(got))
+	}
+	if got, _, _ := users.ListSessions(ctx, user.ID, page); len(got) != 0 {
+		t.Errorf("user store holds %d sessions, want 0", len(got))
+	}
+
+	if _, err := authService.ValidateToken(ctx, token); err != nil {
+		t.Errorf("token not validated against the session store: %v", err)
+	}
+	if err := authService.LogoutUser(ctx, token); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+	if _, err := authService.ValidateToken(ctx, token); err == nil {
+		t.Error("expected token to be invalid after logout")
+	}
+}
+
+// txRepository counts the units of work run on a mock repository
+type txRepository struct {
+	*test.MockUserRepository
+	txs int
+}
+
+func (r *txRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	r.txs++
+	return fn(r.MockUserRepository)
+}
+
+func TestLoginRunsInTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := &txRepository{MockUserRepository: test.NewMockUserRepository()}
+	authService := NewAuthService(repo, repo, "test-secret")
+
+	if _, err := authService.RegisterUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	if _, err := authService.LoginUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	if repo.txs != 1 {
+		t.Errorf("login ran %d transactions, want 1", repo.txs)
+	}
+
+	// Stores in different backends cannot share a transaction
+	separate := NewAuthService(repo, test.NewMockUserRepository(), "test-secret")
+	if _, err := separate.LoginUser(ctx, "test@example.com", "password123"); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	if repo.txs != 1 {
+		t.Errorf("login with a separate session store ran a transaction")
+	}
+}
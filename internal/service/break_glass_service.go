@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+var (
+	ErrInvalidBreakGlassCredential = errors.New("invalid break-glass credential")
+	ErrBreakGlassExpired           = errors.New("break-glass credential has expired")
+	ErrBreakGlassUsed              = errors.New("break-glass credential has already been used")
+)
+
+// breakGlassSessionTTL bounds how long a redeemed credential unlocks the admin API
+const breakGlassSessionTTL = time.Hour
+
+// breakGlassSessionPrefix marks break-glass session tokens, so other bearer
+// tokens are told apart without a store lookup
+const breakGlassSessionPrefix = "bgs_"
+
+// BreakGlassService redeems a sealed, single-use operator credential for a
+// short-lived admin session. Every attempt and every use is audited. Sessions
+// are stored with the redemption, so every replica accepts them.
+type BreakGlassService struct {
+	repo           interfaces.BreakGlassRepository
+	credentialHash string
+	expiresAt      time.Time
+	auditLogger    audit.Logger
+}
+
+// NewBreakGlassService creates a break-glass service for the credential with the given SHA-256 hex hash
+func NewBreakGlassService(repo interfaces.BreakGlassRepository, credentialHash string, expiresAt time.Time, auditLogger audit.Logger) *BreakGlassService {
+	return &BreakGlassService{
+		repo:           repo,
+		credentialHash: credentialHash,
+		expiresAt:      expiresAt,
+		auditLogger:    auditLogger,
+	}
+}
+
+// GenerateBreakGlassCredential creates a new credential and the hash to configure
+// in BREAK_GLASS_CREDENTIAL_HASH. The credential itself should be stored offline.
+func GenerateBreakGlassCredential() (credential, hash string, err error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	credential = "bg_" + secret
+	return credential, hashToken(credential), nil
+}
+
+// Redeem exchanges the credential for an admin session token. The credential
+// can only be redeemed once and not after it has expired.
+func (s *BreakGlassService) Redeem(ctx context.Context, credential, ipAddress string) (string, time.Time, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token := breakGlassSessionPrefix + secret
+	expiresAt := time.Now().Add(breakGlassSessionTTL)
+
+	if err := s.redeem(ctx, credential, ipAddress, hashToken(token), expiresAt); err != nil {
+		s.auditLogger.Record(ctx, audit.Event{
+			Type:      "breakglass.redeem_failed",
+			Severity:  audit.SeverityHigh,
+			IPAddress: ipAddress,
+			Details:   map[string]any{"reason": err.Error()},
+		})
+		return "", time.Time{}, err
+	}
+
+	s.auditLogger.Record(ctx, audit.Event{
+		Type:      "breakglass.redeemed",
+		Severity:  audit.SeverityHigh,
+		IPAddress: ipAddress,
+		Details:   map[string]any{"session_expires_at": expiresAt},
+	})
+
+	return token, expiresAt, nil
+}
+
+func (s *BreakGlassService) redeem(ctx context.Context, credential, ipAddress, sessionHash string, sessionExpiresAt time.Time) error {
+	hash := hashToken(credential)
+	if s.credentialHash == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(s.credentialHash)) != 1 {
+		return ErrInvalidBreakGlassCredential
+	}
+
+	if time.Now().After(s.expiresAt) {
+		return ErrBreakGlassExpired
+	}
+
+	if err := s.repo.RedeemBreakGlassCredential(ctx, hash, ipAddress, sessionHash, sessionExpiresAt); err != nil {
+		if err == repository.ErrCredentialAlreadyUsed {
+			return ErrBreakGlassUsed
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ValidateSession reports whether token is an unexpired break-glass admin session
+func (s *BreakGlassService) ValidateSession(ctx context.Context, token string) (bool, error) {
+	if !strings.HasPrefix(token, breakGlassSessionPrefix) {
+		return false, nil
+	}
+	return s.repo.IsBreakGlassSessionValid(ctx, hashToken(token))
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+// discardLogger drops audit events
+type discardLogger struct{}
+
+func (discardLogger) Record(ctx context.Context, event audit.Event) {}
+
+func TestBreakGlassRedeem(t *testing.T) {
+	ctx := context.Background()
+	credential, hash, err := GenerateBreakGlassCredential()
+	if err != nil {
+		t.Fatalf("failed to generate credential: %v", err)
+	}
+
+	t.Run("expired credential", func(t *testing.T) {
+		expired := NewBreakGlassService(test.NewMockBreakGlassRepository(), hash, time.Now().Add(-time.Minute), discardLogger{})
+		if _, _, err := expired.Redeem(ctx, credential, "127.0.0.1"); err != ErrBreakGlassExpired {
+			t.Errorf("got error %v, want %v", err, ErrBreakGlassExpired)
+		}
+	})
+
+	repo := test.NewMockBreakGlassRepository()
+	breakGlass := NewBreakGlassService(repo, hash, time.Now().Add(time.Hour), discardLogger{})
+
+	if _, _, err := breakGlass.Redeem(ctx, "bg_wrong", "127.0.0.1"); err != ErrInvalidBreakGlassCredential {
+		t.Errorf("got error %v, want %v", err, ErrInvalidBreakGlassCredential)
+	}
+
+	token, expiresAt, err := breakGlass.Redeem(ctx, credential, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to redeem credential: %v", err)
+	}
+	if time.Until(expiresAt) > breakGlassSessionTTL {
+		t.Errorf("session expires too late: %v", expiresAt)
+	}
+
+	if valid, err := breakGlass.ValidateSession(ctx, token); err != nil || !valid {
+		t.Errorf("ValidateSession() = %v, %v; want a valid session", valid, err)
+	}
+	// Another replica sharing the store accepts the session
+	replica := NewBreakGlassService(repo, hash, time.Now().Add(time.Hour), discardLogger{})
+	if valid, err := replica.ValidateSession(ctx, token); err != nil || !valid {
+		t.Errorf("ValidateSession() on another replica = %v, %v; want a valid session", valid, err)
+	}
+	for _, unknown := range []string{"not-a-session", breakGlassSessionPrefix + "unknown"} {
+		if valid, _ := breakGlass.ValidateSession(ctx, unknown); valid {
+			t.Errorf("expected unknown session %q to be invalid", unknown)
+		}
+	}
+
+	if _, _, err := replica.Redeem(ctx, credential, "127.0.0.1"); err != ErrBreakGlassUsed {
+		t.Errorf("got error %v, want %v", err, ErrBreakGlassUsed)
+	}
+}
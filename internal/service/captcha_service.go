@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/captcha"
+	"github.com/Stewz00/go-auth-service/internal/metrics"
+)
+
+var (
+	ErrCaptchaRequired = errors.New("CAPTCHA verification required")
+	ErrCaptchaFailed   = errors.New("CAPTCHA verification failed")
+)
+
+// DefaultCaptchaAfterFailures is how many recent failed sign-ins make an address suspicious
+const DefaultCaptchaAfterFailures = 3
+
+// CaptchaService demands a solved CAPTCHA from suspicious addresses: those
+// with recent failed sign-ins counted by the login throttle. A nil
+// CaptchaService never demands one.
+type CaptchaService struct {
+	verifier      captcha.Verifier
+	throttle      *LoginThrottle
+	afterFailures int
+	security      *metrics.Security // nil disables security metrics
+}
+
+// NewCaptchaService creates a service that demands a CAPTCHA from addresses
+// with afterFailures or more failed sign-ins in the throttle's window
+func NewCaptchaService(verifier captcha.Verifier, throttle *LoginThrottle, afterFailures int, security *metrics.Security) *CaptchaService {
+	if afterFailures <= 0 {
+		afterFailures = DefaultCaptchaAfterFailures
+	}
+	return &CaptchaService{
+		verifier:      verifier,
+		throttle:      throttle,
+		afterFailures: afterFailures,
+		security:      security,
+	}
+}
+
+// Required reports whether ip must solve a CAPTCHA
+func (s *CaptchaService) Required(ip string) bool {
+	return s != nil && s.throttle.Failures(ip) >= s.afterFailures
+}
+
+// Check verifies token when ip must solve a CAPTCHA. It returns
+// ErrCaptchaRequired when a required token is missing, which counts as a
+// challenge in the security metrics.
+func (s *CaptchaService) Check(ctx context.Context, ip, token string) error {
+	if !s.Required(ip) {
+		return nil
+	}
+	return s.Verify(ctx, ip, token)
+}
+
+// Verify verifies token whether or not ip is suspicious, for clients another
+// policy challenges, like Check when a CAPTCHA is required
+func (s *CaptchaService) Verify(ctx context.Context, ip, token string) error {
+	if token == "" {
+		s.security.CaptchaChallenge(nil)
+		return ErrCaptchaRequired
+	}
+
+	err := s.verifier.Verify(ctx, token, ip)
+	if err == captcha.ErrVerificationFailed {
+		return ErrCaptchaFailed
+	}
+	return err
+}
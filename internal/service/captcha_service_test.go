@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/captcha"
+)
+
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token != "solved" {
+		return captcha.ErrVerificationFailed
+	}
+	return nil
+}
+
+func TestCaptchaService(t *testing.T) {
+	throttle := NewLoginThrottle(DefaultLoginThrottlePolicy, &banRecorder{bans: map[string]time.Duration{}})
+	for i := 0; i < 2; i++ {
+		throttle.Failed("203.0.113.9", "alice@example.com")
+	}
+	s := NewCaptchaService(fakeVerifier{}, throttle, 2, nil)
+
+	tests := []struct {
+		name    string
+		ip      string
+		token   string
+		wantErr error
+	}{
+		{name: "clean address needs no token", ip: "198.51.100.7"},
+		{name: "suspicious address without token", ip: "203.0.113.9", wantErr: ErrCaptchaRequired},
+		{name: "suspicious address with forged token", ip: "203.0.113.9", token: "forged", wantErr: ErrCaptchaFailed},
+		{name: "suspicious address with solved token", ip: "203.0.113.9", token: "solved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Check(context.Background(), tt.ip, tt.token); err != tt.wantErr {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var disabled *CaptchaService
+	if err := disabled.Check(context.Background(), "203.0.113.9", ""); err != nil {
+		t.Errorf("nil service must not demand a CAPTCHA: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+var (
+	// ErrInvalidConsent is returned when a consent receipt is missing required fields
+	ErrInvalidConsent = errors.New("invalid consent purpose or version")
+	// ErrTermsVersionNotCurrent is returned when a user accepts a version of
+	// the terms that is not the current one
+	ErrTermsVersionNotCurrent = errors.New("terms version is not the current one")
+)
+
+// TermsVersions are the current versions of the documents users must accept.
+// Empty versions are not tracked.
+type TermsVersions struct {
+	TermsOfService string
+	PrivacyPolicy  string
+}
+
+// termsDocument is the version of a document under its consent purpose
+type termsDocument struct {
+	purpose string
+	version string
+}
+
+// documents returns the tracked documents, the terms of service first
+func (v TermsVersions) documents() []termsDocument {
+	var docs []termsDocument
+	if v.TermsOfService != "" {
+		docs = append(docs, termsDocument{model.ConsentTermsOfService, v.TermsOfService})
+	}
+	if v.PrivacyPolicy != "" {
+		docs = append(docs, termsDocument{model.ConsentPrivacyPolicy, v.PrivacyPolicy})
+	}
+	return docs
+}
+
+// ConsentService records and retrieves consent receipts for privacy compliance
+type ConsentService struct {
+	consentRepo interfaces.ConsentRepository
+	terms       atomic.Pointer[TermsVersions] // replaced by SetTermsVersions on reload
+}
+
+// ConsentServiceOption configures optional ConsentService settings
+type ConsentServiceOption func(*ConsentService)
+
+// WithTermsVersions sets the current versions of the documents users must accept
+func WithTermsVersions(versions TermsVersions) ConsentServiceOption {
+	return func(s *ConsentService) {
+		s.terms.Store(&versions)
+	}
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(consentRepo interfaces.ConsentRepository, opts ...ConsentServiceOption) *ConsentService {
+	s := &ConsentService{
+		consentRepo: consentRepo,
+	}
+	s.terms.Store(&TermsVersions{})
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetTermsVersions replaces the current versions of the documents users must
+// accept, so users who accepted older ones are asked again
+func (s *ConsentService) SetTermsVersions(versions TermsVersions) {
+	s.terms.Store(&versions)
+}
+
+// TermsVersions returns the current versions of the documents users must accept
+func (s *ConsentService) TermsVersions() TermsVersions {
+	return *s.terms.Load()
+}
+
+// RecordConsent validates and stores a consent receipt
+func (s *ConsentService) RecordConsent(ctx context.Context, receipt *model.ConsentReceipt) error {
+	switch receipt.Purpose {
+	case model.ConsentTermsOfService, model.ConsentPrivacyPolicy:
+		// Accepting terms is only meaningful against a specific document version
+		if receipt.Version == "" || !receipt.Granted {
+			return ErrInvalidConsent
+		}
+	case model.ConsentMarketing:
+	case model.ConsentOAuthScopes:
+		if receipt.Subject == "" {
+			return ErrInvalidConsent
+		}
+	default:
+		return ErrInvalidConsent
+	}
+
+	return s.consentRepo.CreateConsentReceipt(ctx, receipt)
+}
+
+// ListConsents returns the full consent history for a user
+func (s *ConsentService) ListConsents(ctx context.Context, userID int64) ([]*model.ConsentReceipt, error) {
+	receipts, err := s.consentRepo.ListConsentReceipts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if receipts == nil {
+		receipts = []*model.ConsentReceipt{}
+	}
+	return receipts, nil
+}
+
+// TermsStatus reports, for each tracked document, the latest version the user
+// accepted and whether it is the current one
+func (s *ConsentService) TermsStatus(ctx context.Context, userID int64) ([]model.TermsStatus, error) {
+	receipts, err := s.consentRepo.ListConsentReceipts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []model.TermsStatus{}
+	for _, doc := range s.TermsVersions().documents() {
+		status := model.TermsStatus{Purpose: doc.purpose, CurrentVersion: doc.version}
+		// Receipts are listed oldest first, so the last acceptance wins
+		for _, receipt := range receipts {
+			if receipt.Purpose == status.Purpose && receipt.Granted {
+				status.AcceptedVersion = receipt.Version
+				status.AcceptedAt = &receipt.Created
+			}
+		}
+		status.UpToDate = status.AcceptedVersion == status.CurrentVersion
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// TermsAcceptanceRequired reports whether the user has yet to accept the
+// current version of any tracked document
+func (s *ConsentService) TermsAcceptanceRequired(ctx context.Context, userID int64) (bool, error) {
+	if len(s.TermsVersions().documents()) == 0 {
+		return false, nil
+	}
+	statuses, err := s.TermsStatus(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, status := range statuses {
+		if !status.UpToDate {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AcceptTerms records the user accepting the given versions of the tracked
+// documents, each of which must be the current one. receipt supplies the
+// user and client of the receipts returned.
+func (s *ConsentService) AcceptTerms(ctx context.Context, receipt model.ConsentReceipt, versions TermsVersions) ([]*model.ConsentReceipt, error) {
+	current := s.TermsVersions()
+	accepted := versions.documents()
+	if len(accepted) == 0 {
+		return nil, ErrInvalidConsent
+	}
+	if (versions.TermsOfService != "" && versions.TermsOfService != current.TermsOfService) ||
+		(versions.PrivacyPolicy != "" && versions.PrivacyPolicy != current.PrivacyPolicy) {
+		return nil, ErrTermsVersionNotCurrent
+	}
+
+	receipts := make([]*model.ConsentReceipt, len(accepted))
+	for i, doc := range accepted {
+		r := receipt
+		r.Purpose, r.Version, r.Granted = doc.purpose, doc.version, true
+		if err := s.RecordConsent(ctx, &r); err != nil {
+			return nil, err
+		}
+		receipts[i] = &r
+	}
+	return receipts, nil
+}
+
+// WriteConsentsCSV exports consent receipts in CSV form for compliance reviews
+func WriteConsentsCSV(w io.Writer, receipts []*model.ConsentReceipt) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "user_id", "purpose", "subject", "scope", "version", "granted", "ip_address", "user_agent", "created_at"}); err != nil {
+		return err
+	}
+
+	for _, c := range receipts {
+		err := cw.Write([]string{
+			strconv.FormatInt(c.ID, 10),
+			strconv.FormatInt(c.UserID, 10),
+			c.Purpose,
+			c.Subject,
+			c.Scope,
+			c.Version,
+			strconv.FormatBool(c.Granted),
+			c.IPAddress,
+			c.UserAgent,
+			c.Created.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestRecordConsent(t *testing.T) {
+	consentService := NewConsentService(test.NewMockConsentRepository())
+
+	tests := []struct {
+		name    string
+		receipt *model.ConsentReceipt
+		wantErr error
+	}{
+		{
+			name:    "terms of service with version",
+			receipt: &model.ConsentReceipt{UserID: 1, Purpose: model.ConsentTermsOfService, Version: "2024-01", Granted: true, IPAddress: "127.0.0.1"},
+		},
+		{
+			name:    "terms of service without version",
+			receipt: &model.ConsentReceipt{UserID: 1, Purpose: model.ConsentTermsOfService, Granted: true, IPAddress: "127.0.0.1"},
+			wantErr: ErrInvalidConsent,
+		},
+		{
+			name:    "marketing opt out",
+			receipt: &model.ConsentReceipt{UserID: 1, Purpose: model.ConsentMarketing, Granted: false, IPAddress: "127.0.0.1"},
+		},
+		{
+			name:    "oauth scopes without client",
+			receipt: &model.ConsentReceipt{UserID: 1, Purpose: model.ConsentOAuthScopes, Scope: "openid", Granted: true, IPAddress: "127.0.0.1"},
+			wantErr: ErrInvalidConsent,
+		},
+		{
+			name:    "unknown purpose",
+			receipt: &model.ConsentReceipt{UserID: 1, Purpose: "profiling", Granted: true, IPAddress: "127.0.0.1"},
+			wantErr: ErrInvalidConsent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := consentService.RecordConsent(context.Background(), tt.receipt)
+			if err != tt.wantErr {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	receipts, err := consentService.ListConsents(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(receipts) != 2 {
+		t.Fatalf("got %d receipts, want 2", len(receipts))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteConsentsCSV(&buf, receipts); err != nil {
+		t.Fatalf("failed to export receipts: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "1,1,tos,,,2024-01,true,127.0.0.1") {
+		t.Errorf("unexpected CSV export:\n%s", buf.String())
+	}
+}
+
+func TestTermsAcceptance(t *testing.T) {
+	ctx := context.Background()
+	consentService := NewConsentService(test.NewMockConsentRepository(), WithTermsVersions(TermsVersions{TermsOfService: "2026-01"}))
+	client := model.ConsentReceipt{UserID: 1, IPAddress: "127.0.0.1"}
+
+	required := func() bool {
+		t.Helper()
+		got, err := consentService.TermsAcceptanceRequired(ctx, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return got
+	}
+
+	if !required() {
+		t.Error("expected acceptance required before any receipt")
+	}
+	if _, err := consentService.AcceptTerms(ctx, client, TermsVersions{TermsOfService: "2025-06"}); err != ErrTermsVersionNotCurrent {
+		t.Errorf("got error %v, want %v", err, ErrTermsVersionNotCurrent)
+	}
+	if _, err := consentService.AcceptTerms(ctx, client, TermsVersions{}); err != ErrInvalidConsent {
+		t.Errorf("got error %v, want %v", err, ErrInvalidConsent)
+	}
+	receipts, err := consentService.AcceptTerms(ctx, client, TermsVersions{TermsOfService: "2026-01"})
+	if err != nil || len(receipts) != 1 || receipts[0].Purpose != model.ConsentTermsOfService || receipts[0].IPAddress != "127.0.0.1" {
+		t.Fatalf("got receipts %+v (%v), want one terms of service receipt", receipts, err)
+	}
+	if required() {
+		t.Error("expected no acceptance required after accepting the current terms")
+	}
+
+	// A new version of the terms and a privacy policy ask the user again
+	consentService.SetTermsVersions(TermsVersions{TermsOfService: "2026-10", PrivacyPolicy: "v2"})
+	if !required() {
+		t.Error("expected acceptance required after the terms changed")
+	}
+	statuses, err := consentService.TermsStatus(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []model.TermsStatus{
+		{Purpose: model.ConsentTermsOfService, CurrentVersion: "2026-10", AcceptedVersion: "2026-01"},
+		{Purpose: model.ConsentPrivacyPolicy, CurrentVersion: "v2"},
+	}
+	if len(statuses) != len(want) || statuses[0].AcceptedAt == nil {
+		t.Fatalf("got statuses %+v, want %+v", statuses, want)
+	}
+	for i, status := range statuses {
+		status.AcceptedAt = nil
+		if status != want[i] {
+			t.Errorf("got status %+v, want %+v", statuses, want)
+			break
+		}
+	}
+
+	if _, err := consentService.AcceptTerms(ctx, client, TermsVersions{TermsOfService: "2026-10", PrivacyPolicy: "v2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if required() {
+		t.Error("expected no acceptance required after accepting both documents")
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/email"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+var (
+	ErrEmailDomainBlocked = errors.New("registrations from this email domain are not allowed")
+	ErrInvalidDomain      = errors.New("invalid domain")
+)
+
+// EmailDomainPolicy refuses registrations with email addresses at blocked
+// domains or their subdomains. Domains are blocked by configuration, by the
+// bundled list of disposable email services when enabled, and by admins at
+// runtime. A nil EmailDomainPolicy allows every domain.
+type EmailDomainPolicy struct {
+	repo       interfaces.EmailDomainRepository // nil disables runtime blocking
+	blocked    map[string]bool
+	disposable bool
+}
+
+// NewEmailDomainPolicy creates a policy blocking the configured domains, and
+// disposable email services when blockDisposable is set, along with those
+// stored in repo
+func NewEmailDomainPolicy(repo interfaces.EmailDomainRepository, blocked []string, blockDisposable bool) *EmailDomainPolicy {
+	p := &EmailDomainPolicy{
+		repo:       repo,
+		blocked:    map[string]bool{},
+		disposable: blockDisposable,
+	}
+	for _, domain := range blocked {
+		if domain, ok := model.NormalizeDomain(domain); ok {
+			p.blocked[domain] = true
+		}
+	}
+	return p
+}
+
+// Check returns ErrEmailDomainBlocked when the domain of address, or one of
+// its parent domains, is blocked
+func (p *EmailDomainPolicy) Check(ctx context.Context, address string) error {
+	if p == nil {
+		return nil
+	}
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return nil
+	}
+	domains := parentDomains(strings.ToLower(strings.TrimSuffix(address[at+1:], ".")))
+
+	for _, domain := range domains {
+		if p.blocked[domain] || p.disposable && email.DisposableDomains()[domain] {
+			return ErrEmailDomainBlocked
+		}
+	}
+	if p.repo == nil || len(domains) == 0 {
+		return nil
+	}
+	blocked, err := p.repo.IsDomainBlocked(ctx, domains...)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrEmailDomainBlocked
+	}
+	return nil
+}
+
+// BlockDomain blocks registrations from domain and its subdomains
+func (p *EmailDomainPolicy) BlockDomain(ctx context.Context, domain, reason string) (*model.BlockedDomain, error) {
+	domain, ok := model.NormalizeDomain(domain)
+	if !ok {
+		return nil, ErrInvalidDomain
+	}
+	blocked := &model.BlockedDomain{Domain: domain, Reason: strings.TrimSpace(reason)}
+	if err := p.repo.BlockDomain(ctx, blocked); err != nil {
+		return nil, err
+	}
+	return blocked, nil
+}
+
+// UnblockDomain allows registrations from a domain blocked with BlockDomain
+// again. Domains blocked by configuration stay blocked.
+func (p *EmailDomainPolicy) UnblockDomain(ctx context.Context, domain string) error {
+	domain, ok := model.NormalizeDomain(domain)
+	if !ok {
+		return ErrInvalidDomain
+	}
+	return p.repo.UnblockDomain(ctx, domain)
+}
+
+// BlockedDomains returns the domains blocked with BlockDomain
+func (p *EmailDomainPolicy) BlockedDomains(ctx context.Context) ([]*model.BlockedDomain, error) {
+	return p.repo.ListBlockedDomains(ctx)
+}
+
+// RuntimeBlocking reports whether admins can block domains at runtime
+func (p *EmailDomainPolicy) RuntimeBlocking() bool {
+	return p != nil && p.repo != nil
+}
+
+// parentDomains returns domain followed by each of its parent domains with at
+// least two labels, so "mail.example.com" gives itself and "example.com"
+func parentDomains(domain string) []string {
+	var domains []string
+	for strings.Contains(domain, ".") {
+		domains = append(domains, domain)
+		domain = domain[strings.IndexByte(domain, '.')+1:]
+	}
+	return domains
+}
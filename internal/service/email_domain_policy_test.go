@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/repository/memory"
+)
+
+func TestEmailDomainPolicy(t *testing.T) {
+	ctx := context.Background()
+	p := NewEmailDomainPolicy(memory.NewEmailDomainRepository(memory.New()), []string{"Spam.example"}, true)
+	if _, err := p.BlockDomain(ctx, " @Blocked.Example ", "abuse"); err != nil {
+		t.Fatalf("BlockDomain() error = %v", err)
+	}
+
+	tests := []struct {
+		email string
+		want  error
+	}{
+		{email: "user@example.com", want: nil},
+		{email: "user@spam.example", want: ErrEmailDomainBlocked},
+		{email: "user@mx.SPAM.example", want: ErrEmailDomainBlocked}, // subdomains are blocked too
+		{email: "user@notspam.example", want: nil},
+		{email: "user@mailinator.com", want: ErrEmailDomainBlocked},
+		{email: "user@blocked.example", want: ErrEmailDomainBlocked},
+		{email: "user@mail.blocked.example.", want: ErrEmailDomainBlocked},
+		{email: "not-an-email", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if err := p.Check(ctx, tt.email); err != tt.want {
+				t.Errorf("Check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := p.BlockDomain(ctx, "blocked.example", ""); err != repository.ErrDomainAlreadyBlocked {
+		t.Errorf("BlockDomain() again: error = %v, want ErrDomainAlreadyBlocked", err)
+	}
+	for _, domain := range []string{"", "localhost", "-bad.example", "bad_domain.example", "a..example"} {
+		if _, err := p.BlockDomain(ctx, domain, ""); err != ErrInvalidDomain {
+			t.Errorf("BlockDomain(%q): error = %v, want ErrInvalidDomain", domain, err)
+		}
+	}
+	if err := p.UnblockDomain(ctx, "blocked.example"); err != nil {
+		t.Fatalf("UnblockDomain() error = %v", err)
+	}
+	if err := p.Check(ctx, "user@blocked.example"); err != nil {
+		t.Errorf("Check() after unblocking = %v, want nil", err)
+	}
+	if err := p.UnblockDomain(ctx, "blocked.example"); err != repository.ErrDomainNotFound {
+		t.Errorf("UnblockDomain() again: error = %v, want ErrDomainNotFound", err)
+	}
+
+	var disabled *EmailDomainPolicy
+	if err := disabled.Check(ctx, "user@mailinator.com"); err != nil {
+		t.Errorf("nil policy: Check() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/geoip"
+)
+
+var ErrCountryBlocked = errors.New("sign-in is not available in your country")
+
+// GeoDecision is what a GeoPolicy allows a client to do
+type GeoDecision int
+
+const (
+	GeoAllow     GeoDecision = iota
+	GeoChallenge             // the client must solve a CAPTCHA
+	GeoBlock
+)
+
+// Actions a GeoPolicy checks
+const (
+	GeoActionLogin    = "login"
+	GeoActionRegister = "register"
+)
+
+// GeoPolicy blocks sign-ins and registrations from some countries and
+// challenges those from others with a CAPTCHA, by the country of the client
+// address. Clients whose country is unknown, such as private addresses, are
+// allowed. A nil GeoPolicy allows everyone.
+type GeoPolicy struct {
+	locator     geoip.Locator
+	block       map[string]bool
+	challenge   map[string]bool
+	auditLogger audit.Logger // nil disables audit events
+}
+
+// NewGeoPolicy creates a policy that blocks clients in the block countries
+// and challenges those in the challenge countries, both ISO 3166-1 alpha-2
+// codes. Blocking wins for a country in both.
+func NewGeoPolicy(locator geoip.Locator, block, challenge []string, auditLogger audit.Logger) *GeoPolicy {
+	p := &GeoPolicy{
+		locator:     locator,
+		block:       map[string]bool{},
+		challenge:   map[string]bool{},
+		auditLogger: auditLogger,
+	}
+	for _, country := range block {
+		p.block[strings.ToUpper(country)] = true
+	}
+	for _, country := range challenge {
+		p.challenge[strings.ToUpper(country)] = true
+	}
+	return p
+}
+
+// Check decides whether the client at ip may take action, recording blocks
+// and challenges in the audit log. Lookup failures are logged and allow the
+// client, so a broken database never locks everyone out.
+func (p *GeoPolicy) Check(ctx context.Context, ip, action string) GeoDecision {
+	if p == nil {
+		return GeoAllow
+	}
+	country, err := p.locator.Country(ip)
+	if err != nil {
+		slog.ErrorContext(ctx, "geoip: lookup failed", "ip", ip, "err", err)
+		return GeoAllow
+	}
+
+	event := audit.Event{
+		Type:     "auth.geo_restricted",
+		Severity: audit.SeverityInfo,
+		Details:  map[string]any{"country": country, "action": action, "decision": "challenge"},
+	}
+	switch {
+	case p.block[country]:
+		event.Severity = audit.SeverityWarning
+		event.Details["decision"] = "block"
+		p.record(ctx, event)
+		return GeoBlock
+	case p.challenge[country]:
+		p.record(ctx, event)
+		return GeoChallenge
+	}
+	return GeoAllow
+}
+
+func (p *GeoPolicy) record(ctx context.Context, event audit.Event) {
+	if p.auditLogger != nil {
+		p.auditLogger.Record(ctx, event)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeLocator maps addresses to countries; "bad" fails the lookup
+type fakeLocator map[string]string
+
+func (l fakeLocator) Country(ip string) (string, error) {
+	if ip == "bad" {
+		return "", errors.New("corrupt database")
+	}
+	return l[ip], nil
+}
+
+func TestGeoPolicy(t *testing.T) {
+	recorder := &eventRecorder{}
+	locator := fakeLocator{"198.51.100.1": "KP", "198.51.100.2": "RU", "198.51.100.3": "DE"}
+	p := NewGeoPolicy(locator, []string{"kp"}, []string{"RU", "KP"}, recorder)
+
+	tests := []struct {
+		ip   string
+		want GeoDecision
+	}{
+		{ip: "198.51.100.1", want: GeoBlock}, // blocking wins over challenging
+		{ip: "198.51.100.2", want: GeoChallenge},
+		{ip: "198.51.100.3", want: GeoAllow},
+		{ip: "10.0.0.1", want: GeoAllow},
+		{ip: "bad", want: GeoAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := p.Check(context.Background(), tt.ip, GeoActionLogin); got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("got %d audit events, want 2: %+v", len(recorder.events), recorder.events)
+	}
+	for i, want := range []map[string]any{
+		{"country": "KP", "action": "login", "decision": "block"},
+		{"country": "RU", "action": "login", "decision": "challenge"},
+	} {
+		event := recorder.events[i]
+		if event.Type != "auth.geo_restricted" || event.Details["country"] != want["country"] || event.Details["decision"] != want["decision"] {
+			t.Errorf("event %d: got %+v, want details %v", i, event, want)
+		}
+	}
+
+	var disabled *GeoPolicy
+	if got := disabled.Check(context.Background(), "198.51.100.1", GeoActionRegister); got != GeoAllow {
+		t.Errorf("nil policy: Check() = %v, want GeoAllow", got)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestImpersonate(t *testing.T) {
+	ctx := context.Background()
+	recorder := &eventRecorder{}
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithAuditLogger(recorder))
+	users := NewUserAdminService(mockRepo, authService)
+
+	admin, err := test.NewMockTenantRepository(mockRepo).OnboardTenant(ctx, &model.Tenant{Name: "Acme", Slug: "acme"}, "admin@example.com", "hash")
+	if err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+	user, err := authService.RegisterUser(ctx, "user@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	otherTenant := int64(99)
+
+	tests := []struct {
+		name    string
+		tenant  *int64
+		target  int64
+		expiry  time.Duration
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "default expiry", target: user.ID, want: DefaultImpersonationExpiry},
+		{name: "expiry capped", target: user.ID, expiry: 24 * time.Hour, want: MaxImpersonationExpiry},
+		{name: "outside tenant", tenant: &otherTenant, target: user.ID, wantErr: repository.ErrUserNotFound},
+		{name: "admin target", target: admin.ID, wantErr: ErrImpersonationNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.events = nil
+			got, err := users.Impersonate(ctx, tt.tenant, admin.ID, tt.target, tt.expiry, "ticket 42")
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if d := time.Until(got.ExpiresAt); d > tt.want || d < tt.want-time.Minute {
+				t.Errorf("got token valid for %v, want %v", d, tt.want)
+			}
+
+			claims, err := authService.ValidateToken(ctx, got.Token)
+			if err != nil {
+				t.Fatalf("impersonation token rejected: %v", err)
+			}
+			act, _ := claims["act"].(map[string]any)
+			if claims["sub"] != float64(user.ID) || act["sub"] != float64(admin.ID) {
+				t.Errorf("got sub %v act %v, want %d impersonated by %d", claims["sub"], claims["act"], user.ID, admin.ID)
+			}
+
+			if len(recorder.events) != 1 || recorder.events[0].Type != audit.Impersonated {
+				t.Fatalf("got events %+v, want one %s", recorder.events, audit.Impersonated)
+			}
+			event := recorder.events[0]
+			if event.ActorID != user.ID || event.Details["impersonator_id"] != admin.ID || event.Details["reason"] != "ticket 42" {
+				t.Errorf("got event %+v", event)
+			}
+		})
+	}
+
+	t.Run("disabled users cannot be impersonated", func(t *testing.T) {
+		if err := users.SetDisabled(ctx, nil, user.ID, true); err != nil {
+			t.Fatalf("failed to disable: %v", err)
+		}
+		if _, err := users.Impersonate(ctx, nil, admin.ID, user.ID, 0, "ticket 42"); err != ErrAccountDisabled {
+			t.Errorf("got error %v, want %v", err, ErrAccountDisabled)
+		}
+	})
+}
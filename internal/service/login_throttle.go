@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginThrottlePolicy limits failed sign-ins by source, independently of the
+// per-account lockout, so one address cannot spray guesses across many accounts
+type LoginThrottlePolicy struct {
+	// Window over which failures are counted
+	Window time.Duration
+
+	// MaxPerIP failures from one address, across all accounts, block the address
+	MaxPerIP int
+
+	// MaxPerIPAndEmail failures for one account from one address refuse further
+	// attempts for that pair until the window passes
+	MaxPerIPAndEmail int
+
+	// BlockDuration is how long an address is blocked
+	BlockDuration time.Duration
+}
+
+// DefaultLoginThrottlePolicy blocks an address for 15 minutes after 20 failed
+// sign-ins in 15 minutes, and refuses an account from one address after 5
+var DefaultLoginThrottlePolicy = LoginThrottlePolicy{
+	Window:           15 * time.Minute,
+	MaxPerIP:         20,
+	MaxPerIPAndEmail: 5,
+	BlockDuration:    15 * time.Minute,
+}
+
+// IPBlocker blocks every request from an address for a while, such as
+// middleware.IPBanList
+type IPBlocker interface {
+	Ban(ip string, duration time.Duration)
+}
+
+type failureCount struct {
+	count int
+	start time.Time
+}
+
+// LoginThrottle counts failed sign-ins per address and per address and email
+// in memory. A nil LoginThrottle allows every attempt.
+type LoginThrottle struct {
+	policy  LoginThrottlePolicy
+	blocker IPBlocker
+	now     func() time.Time
+
+	mu        sync.Mutex
+	failures  map[string]*failureCount
+	lastSweep time.Time
+}
+
+// NewLoginThrottle creates a throttle that blocks offending addresses with blocker
+func NewLoginThrottle(policy LoginThrottlePolicy, blocker IPBlocker) *LoginThrottle {
+	return &LoginThrottle{
+		policy:   policy,
+		blocker:  blocker,
+		now:      time.Now,
+		failures: make(map[string]*failureCount),
+	}
+}
+
+// Allow reports whether ip may try to sign in as email
+func (t *LoginThrottle) Allow(ip, email string) bool {
+	if t == nil || ip == "" {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current(pairKey(ip, email)) < t.policy.MaxPerIPAndEmail
+}
+
+// Failures returns the recent failed sign-ins from ip across all accounts
+func (t *LoginThrottle) Failures(ip string) int {
+	if t == nil || ip == "" {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current("ip:" + ip)
+}
+
+// Failed records a failed sign-in and blocks ip once it reaches MaxPerIP
+func (t *LoginThrottle) Failed(ip, email string) {
+	if t == nil || ip == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep()
+	t.increment(pairKey(ip, email))
+	if t.increment("ip:"+ip) >= t.policy.MaxPerIP {
+		delete(t.failures, "ip:"+ip)
+		t.blocker.Ban(ip, t.policy.BlockDuration)
+	}
+}
+
+// Succeeded forgets the failures for the account from ip. Failures from ip
+// against other accounts still count.
+func (t *LoginThrottle) Succeeded(ip, email string) {
+	if t == nil || ip == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, pairKey(ip, email))
+}
+
+// current returns the failures for key in the current window
+func (t *LoginThrottle) current(key string) int {
+	f, exists := t.failures[key]
+	if !exists || t.now().Sub(f.start) > t.policy.Window {
+		return 0
+	}
+	return f.count
+}
+
+func (t *LoginThrottle) increment(key string) int {
+	now := t.now()
+	f, exists := t.failures[key]
+	if !exists || now.Sub(f.start) > t.policy.Window {
+		f = &failureCount{start: now}
+		t.failures[key] = f
+	}
+	f.count++
+	return f.count
+}
+
+// sweep drops expired counters, at most once per window
+func (t *LoginThrottle) sweep() {
+	now := t.now()
+	if now.Sub(t.lastSweep) < t.policy.Window {
+		return
+	}
+	t.lastSweep = now
+	for key, f := range t.failures {
+		if now.Sub(f.start) > t.policy.Window {
+			delete(t.failures, key)
+		}
+	}
+}
+
+func pairKey(ip, email string) string {
+	return "pair:" + ip + "|" + strings.ToLower(strings.TrimSpace(email))
+}
+
+type clientIPKey struct{}
+
+// ContextWithClientIP records the address a request came from, so sign-ins
+// made with ctx are throttled by source
+func ContextWithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// clientIPFromContext returns the address recorded by ContextWithClientIP
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
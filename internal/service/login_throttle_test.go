@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+type banRecorder struct {
+	bans map[string]time.Duration
+}
+
+func (b *banRecorder) Ban(ip string, duration time.Duration) {
+	b.bans[ip] = duration
+}
+
+func TestLoginThrottle(t *testing.T) {
+	blocker := &banRecorder{bans: map[string]time.Duration{}}
+	throttle := NewLoginThrottle(LoginThrottlePolicy{
+		Window:           time.Minute,
+		MaxPerIP:         4,
+		MaxPerIPAndEmail: 2,
+		BlockDuration:    time.Hour,
+	}, blocker)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+
+	throttle.Failed("203.0.113.9", "alice@example.com")
+	throttle.Failed("203.0.113.9", "Alice@Example.com ")
+
+	tests := []struct {
+		name  string
+		ip    string
+		email string
+		want  bool
+	}{
+		{name: "pair refused after repeated failures", ip: "203.0.113.9", email: "alice@example.com", want: false},
+		{name: "other account from same address", ip: "203.0.113.9", email: "bob@example.com", want: true},
+		{name: "same account from other address", ip: "198.51.100.7", email: "alice@example.com", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := throttle.Allow(tt.ip, tt.email); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("pair allowed after window", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		if !throttle.Allow("203.0.113.9", "alice@example.com") {
+			t.Error("expected the pair to be allowed again")
+		}
+	})
+
+	t.Run("spraying accounts blocks the address", func(t *testing.T) {
+		for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+			throttle.Failed("192.0.2.1", email)
+		}
+		if _, banned := blocker.bans["192.0.2.1"]; banned {
+			t.Fatal("blocked before reaching the limit")
+		}
+		throttle.Failed("192.0.2.1", "d@example.com")
+		if blocker.bans["192.0.2.1"] != time.Hour {
+			t.Errorf("expected a one hour block, got %v", blocker.bans)
+		}
+	})
+
+	var disabled *LoginThrottle
+	if !disabled.Allow("192.0.2.1", "a@example.com") {
+		t.Error("nil throttle must allow attempts")
+	}
+}
+
+func TestAuthenticateThrottlesByAddress(t *testing.T) {
+	userRepo := test.NewMockUserRepository()
+	blocker := &banRecorder{bans: map[string]time.Duration{}}
+	policy := DefaultLoginThrottlePolicy
+	policy.MaxPerIPAndEmail = 2
+	s := NewAuthService(userRepo, userRepo, "secret", WithLoginThrottle(NewLoginThrottle(policy, blocker)))
+
+	if _, err := s.RegisterUser(context.Background(), "alice@example.com", "correct-password"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	ctx := ContextWithClientIP(context.Background(), "203.0.113.9")
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Authenticate(ctx, "alice@example.com", "wrong"); err != ErrInvalidCredentials {
+			t.Fatalf("attempt %d: got %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := s.Authenticate(ctx, "alice@example.com", "correct-password"); err != ErrLoginThrottled {
+		t.Errorf("got %v, want %v", err, ErrLoginThrottled)
+	}
+	if _, err := s.Authenticate(context.Background(), "alice@example.com", "correct-password"); err != nil {
+		t.Errorf("requests without an address are not throttled: %v", err)
+	}
+}
@@ -0,0 +1,553 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/oidc"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned by the OpenID provider, mapped to OAuth error codes by the handler
+var (
+	ErrInvalidClient           = errors.New("invalid client")
+	ErrInvalidRedirectURI      = errors.New("redirect uri is not registered for client")
+	ErrInvalidGrant            = errors.New("invalid or expired authorization code")
+	ErrInvalidScope            = errors.New("scope must include openid")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrPKCERequired            = errors.New("public clients must use PKCE with the S256 method")
+	ErrInvalidCodeChallenge    = errors.New("code_challenge_method must be S256")
+	ErrUnauthorizedClient      = errors.New("client is not allowed to use this grant type")
+	ErrScopeNotAllowed         = errors.New("requested scope is not allowed for client")
+	ErrInvalidRegistration     = errors.New("invalid client registration")
+	ErrInvalidSubjectToken     = errors.New("subject token is invalid, expired, or of an unsupported type")
+)
+
+// pkceMethodS256 is the only supported PKCE transformation; plain offers no protection
+// against an intercepted authorization request
+const pkceMethodS256 = "S256"
+
+// AuthorizeRequest holds the parameters of an authorization request
+type AuthorizeRequest struct {
+	ClientID     string
+	RedirectURI  string
+	ResponseType string
+	Scope        string
+	State        string
+	Nonce        string
+
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// TokenRequest holds the parameters of an authorization code token request
+type TokenRequest struct {
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// TokenExchangeRequest holds the parameters of an RFC 8693 token exchange
+type TokenExchangeRequest struct {
+	ClientID         string
+	ClientSecret     string
+	SubjectToken     string
+	SubjectTokenType string
+	Scope            string
+	Audience         string
+}
+
+// ClientRegistration describes a new OAuth client
+type ClientRegistration struct {
+	Name         string
+	RedirectURIs []string
+	Public       bool
+	GrantTypes   []string // defaults to authorization_code
+	Scopes       []string // scopes a machine client may request
+}
+
+// TokenResponse is returned by the token endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+
+	IssuedTokenType string `json:"issued_token_type,omitempty"` // token exchange only
+}
+
+// OIDCService implements a minimal OpenID Provider using the authorization code flow
+type OIDCService struct {
+	authService *AuthService
+	userRepo    interfaces.UserStore
+	oauthRepo   interfaces.OAuthRepository
+	signingKey  *oidc.SigningKey
+	issuer      string
+	codeExpiry  time.Duration
+
+	// Machine tokens are short-lived since they cannot be revoked individually
+	clientTokenExpiry time.Duration
+}
+
+// NewOIDCService creates a new OpenID provider service for the given issuer URL
+func NewOIDCService(authService *AuthService, userRepo interfaces.UserStore, oauthRepo interfaces.OAuthRepository, signingKey *oidc.SigningKey, issuer string) *OIDCService {
+	return &OIDCService{
+		authService: authService,
+		userRepo:    userRepo,
+		oauthRepo:   oauthRepo,
+		signingKey:  signingKey,
+		issuer:      strings.TrimSuffix(issuer, "/"),
+		codeExpiry:  5 * time.Minute, // codes must be redeemed promptly
+
+		clientTokenExpiry: time.Hour,
+	}
+}
+
+// Discovery returns the provider configuration document
+func (s *OIDCService) Discovery() oidc.Discovery {
+	return oidc.NewDiscovery(s.issuer)
+}
+
+// JWKS returns the public keys used to verify ID tokens
+func (s *OIDCService) JWKS() oidc.JWKS {
+	return oidc.JWKS{Keys: []oidc.JWK{s.signingKey.JWK()}}
+}
+
+// RegisterClient creates a new OAuth client and returns its plaintext secret once.
+// Public clients get no secret and must use PKCE instead.
+func (s *OIDCService) RegisterClient(ctx context.Context, reg *ClientRegistration) (*model.OAuthClient, string, error) {
+	grantTypes := reg.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{model.GrantAuthorizationCode}
+	}
+
+	for _, grant := range grantTypes {
+		switch grant {
+		case model.GrantAuthorizationCode:
+			if len(reg.RedirectURIs) == 0 {
+				return nil, "", ErrInvalidRegistration
+			}
+		case model.GrantClientCredentials, model.GrantTokenExchange:
+			// A machine client without a secret could not authenticate at all
+			if reg.Public {
+				return nil, "", ErrInvalidRegistration
+			}
+		default:
+			return nil, "", ErrInvalidRegistration
+		}
+	}
+
+	clientID, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &model.OAuthClient{
+		ClientID:     clientID,
+		Name:         reg.Name,
+		RedirectURIs: reg.RedirectURIs,
+		IsPublic:     reg.Public,
+		GrantTypes:   grantTypes,
+		Scopes:       reg.Scopes,
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+	if client.Scopes == nil {
+		client.Scopes = []string{}
+	}
+
+	var secret string
+	if !reg.Public {
+		secret, err = randomToken(32)
+		if err != nil {
+			return nil, "", err
+		}
+		hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), 12)
+		if err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = string(hashedSecret)
+	}
+
+	if err := s.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// ValidateAuthorizeRequest checks the client, redirect URI, and requested scope.
+// Errors about the client or redirect URI must not be reported by redirecting.
+func (s *OIDCService) ValidateAuthorizeRequest(ctx context.Context, req *AuthorizeRequest) (*model.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if err == repository.ErrClientNotFound {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	// Redirect URIs must match a registered value exactly
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return client, ErrUnsupportedResponseType
+	}
+
+	if !slices.Contains(client.GrantTypes, model.GrantAuthorizationCode) {
+		return client, ErrUnauthorizedClient
+	}
+
+	if !slices.Contains(strings.Fields(req.Scope), "openid") {
+		return client, ErrInvalidScope
+	}
+
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != pkceMethodS256 {
+		return client, ErrInvalidCodeChallenge
+	}
+	if client.IsPublic && req.CodeChallenge == "" {
+		return client, ErrPKCERequired
+	}
+
+	return client, nil
+}
+
+// IssueAuthorizationCode creates a single-use code for an authenticated user
+func (s *OIDCService) IssueAuthorizationCode(ctx context.Context, req *AuthorizeRequest, userID int64) (string, error) {
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.oauthRepo.SaveAuthorizationCode(ctx, &model.AuthorizationCode{
+		CodeHash:    hashToken(code),
+		ClientID:    req.ClientID,
+		UserID:      userID,
+		RedirectURI: req.RedirectURI,
+		Scope:       req.Scope,
+		Nonce:       req.Nonce,
+		ExpiresAt:   time.Now().Add(s.codeExpiry),
+
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// ExchangeAuthorizationCode redeems a code for an access token and ID token
+func (s *OIDCService) ExchangeAuthorizationCode(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(client.GrantTypes, model.GrantAuthorizationCode) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	grant, err := s.oauthRepo.ConsumeAuthorizationCode(ctx, hashToken(req.Code))
+	if err != nil {
+		if err == repository.ErrCodeNotFound {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	// The code must be redeemed by the client it was issued to, with the same redirect URI
+	if grant.ClientID != req.ClientID || grant.RedirectURI != req.RedirectURI {
+		return nil, ErrInvalidGrant
+	}
+
+	// Public clients prove possession of the original request through PKCE alone
+	if grant.CodeChallenge != "" || client.IsPublic {
+		if !verifyCodeChallenge(grant.CodeChallenge, req.CodeVerifier) {
+			return nil, ErrInvalidGrant
+		}
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, grant.UserID)
+	if err != nil {
+		if err == repository.ErrUserNotFound || err == repository.ErrTooManyAttempts || err == repository.ErrUserDisabled {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	// The access token carries the scopes the user consented to
+	accessToken, err := s.authService.signIn(ctx, user, grant.Scope, req.ClientID, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := s.signIDToken(user, req.ClientID, grant.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.authService.TokenExpiry().Seconds()),
+		IDToken:     idToken,
+		Scope:       grant.Scope,
+	}, nil
+}
+
+// ClientCredentials issues a scoped access token to a machine client. Requested
+// scopes must be a subset of the client's allowed scopes; none requested means all.
+func (s *OIDCService) ClientCredentials(ctx context.Context, clientID, clientSecret, scope string) (*TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.IsPublic || !slices.Contains(client.GrantTypes, model.GrantClientCredentials) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	granted, err := allowedScope(client, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	accessToken, err := s.signAccessToken(jwt.MapClaims{
+		"iss":       s.issuer,
+		"sub":       client.ClientID,
+		"client_id": client.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.clientTokenExpiry).Unix(),
+		"jti":       jti,
+		"scope":     granted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.authService.meter.Count(model.UsageKey{ClientID: client.ClientID}, model.UsageTokensIssued, 1)
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.clientTokenExpiry.Seconds()),
+		Scope:       granted,
+	}, nil
+}
+
+// ExchangeToken implements RFC 8693 token exchange: a trusted service trades a
+// user's access token for a short-lived token scoped to a downstream service.
+// Only confidential clients registered for the token exchange grant may call
+// it, and only for scopes in their allowlist. The issued token names the
+// client in an act claim so downstream services can tell it was delegated.
+func (s *OIDCService) ExchangeToken(ctx context.Context, req *TokenExchangeRequest) (*TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.IsPublic || !slices.Contains(client.GrantTypes, model.GrantTokenExchange) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	if req.SubjectTokenType != model.TokenTypeAccessToken {
+		return nil, ErrInvalidSubjectToken
+	}
+	subject, err := s.authService.ValidateToken(ctx, req.SubjectToken)
+	if err != nil {
+		if err == ErrInvalidToken || err == ErrTokenExpired || err == ErrPasswordExpired {
+			return nil, ErrInvalidSubjectToken
+		}
+		return nil, err
+	}
+
+	// Locked users and suspended tenants lose delegated access too
+	sub, _ := subject["sub"].(float64)
+	user, err := s.userRepo.GetUserByID(ctx, int64(sub))
+	if err != nil {
+		if err == repository.ErrUserNotFound || err == repository.ErrTooManyAttempts || err == repository.ErrTenantSuspended ||
+			err == repository.ErrUserDisabled {
+			return nil, ErrInvalidSubjectToken
+		}
+		return nil, err
+	}
+
+	granted, err := allowedScope(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// The exchanged token never outlives the token it was derived from
+	now := time.Now()
+	expiresAt := now.Add(s.clientTokenExpiry)
+	if exp, err := subject.GetExpirationTime(); err == nil && exp != nil && exp.Before(expiresAt) {
+		expiresAt = exp.Time
+	}
+
+	claims := jwt.MapClaims{
+		"iss":       s.issuer,
+		"sub":       strconv.FormatInt(user.ID, 10),
+		"email":     user.Email,
+		"client_id": client.ClientID,
+		"act":       map[string]any{"sub": client.ClientID},
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+		"jti":       jti,
+		"scope":     granted,
+	}
+	if req.Audience != "" {
+		claims["aud"] = req.Audience
+	}
+
+	accessToken, err := s.signAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	s.authService.meter.Count(usageKey(user, client.ClientID), model.UsageTokensIssued, 1)
+
+	return &TokenResponse{
+		AccessToken:     accessToken,
+		IssuedTokenType: model.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(expiresAt.Sub(now).Seconds()),
+		Scope:           granted,
+	}, nil
+}
+
+// UserInfo returns the standard claims for the user owning an access token
+func (s *OIDCService) UserInfo(ctx context.Context, accessToken string) (map[string]any, error) {
+	claims, err := s.authService.ValidateToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return map[string]any{
+		"sub":   strconv.FormatInt(int64(sub), 10),
+		"email": claims["email"],
+	}, nil
+}
+
+// authenticateClient verifies the client's secret; public clients have none to verify
+func (s *OIDCService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*model.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClient(ctx, clientID)
+	if err != nil {
+		if err == repository.ErrClientNotFound {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if client.IsPublic {
+		return client, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)); err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// allowedScope checks requested scopes against the client's allowlist. No
+// requested scope means every allowed scope.
+func allowedScope(client *model.OAuthClient, scope string) (string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(client.Scopes, sc) {
+			return "", ErrScopeNotAllowed
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+// signAccessToken creates an RS256 signed access token verifiable through JWKS
+func (s *OIDCService) signAccessToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.signingKey.ID
+	return token.SignedString(s.signingKey.PrivateKey)
+}
+
+// signIDToken creates an RS256 signed ID token for the client
+func (s *OIDCService) signIDToken(user *model.User, clientID, nonce string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   s.issuer,
+		"sub":   strconv.FormatInt(user.ID, 10),
+		"aud":   clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(s.authService.TokenExpiry()).Unix(),
+		"email": user.Email,
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.signingKey.ID
+	return token.SignedString(s.signingKey.PrivateKey)
+}
+
+// verifyCodeChallenge checks a PKCE code_verifier against its S256 challenge
+func verifyCodeChallenge(challenge, verifier string) bool {
+	// RFC 7636 verifiers are 43-128 characters
+	if challenge == "" || len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// Helper function to generate a random URL-safe token
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Helper function to hash tokens before they are stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
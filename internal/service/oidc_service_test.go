@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/oidc"
+	"github.com/Stewz00/go-auth-service/internal/test"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	oauthRepo := test.NewMockOAuthRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+
+	key, err := oidc.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	oidcService := NewOIDCService(authService, mockRepo, oauthRepo, key, "https://auth.example.com")
+
+	user, err := authService.RegisterUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	client, secret, err := oidcService.RegisterClient(ctx, &ClientRegistration{Name: "test app", RedirectURIs: []string{"https://app.example.com/callback"}})
+	if err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+
+	req := &AuthorizeRequest{
+		ClientID:     client.ClientID,
+		RedirectURI:  "https://app.example.com/callback",
+		ResponseType: "code",
+		Scope:        "openid email",
+		Nonce:        "n-123",
+	}
+
+	t.Run("rejects unregistered redirect uri", func(t *testing.T) {
+		bad := *req
+		bad.RedirectURI = "https://evil.example.com/callback"
+		if _, err := oidcService.ValidateAuthorizeRequest(ctx, &bad); err != ErrInvalidRedirectURI {
+			t.Errorf("got error %v, want %v", err, ErrInvalidRedirectURI)
+		}
+	})
+
+	t.Run("requires openid scope", func(t *testing.T) {
+		bad := *req
+		bad.Scope = "email"
+		if _, err := oidcService.ValidateAuthorizeRequest(ctx, &bad); err != ErrInvalidScope {
+			t.Errorf("got error %v, want %v", err, ErrInvalidScope)
+		}
+	})
+
+	if _, err := oidcService.ValidateAuthorizeRequest(ctx, req); err != nil {
+		t.Fatalf("unexpected error validating request: %v", err)
+	}
+
+	code, err := oidcService.IssueAuthorizationCode(ctx, req, user.ID)
+	if err != nil {
+		t.Fatalf("failed to issue code: %v", err)
+	}
+
+	t.Run("rejects wrong client secret", func(t *testing.T) {
+		if _, err := oidcService.ExchangeAuthorizationCode(ctx, &TokenRequest{ClientID: client.ClientID, ClientSecret: "wrong", Code: code, RedirectURI: req.RedirectURI}); err != ErrInvalidClient {
+			t.Errorf("got error %v, want %v", err, ErrInvalidClient)
+		}
+	})
+
+	resp, err := oidcService.ExchangeAuthorizationCode(ctx, &TokenRequest{ClientID: client.ClientID, ClientSecret: secret, Code: code, RedirectURI: req.RedirectURI})
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	t.Run("id token verifies against jwks", func(t *testing.T) {
+		idToken, err := jwt.Parse(resp.IDToken, func(token *jwt.Token) (any, error) {
+			return &key.PrivateKey.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(client.ClientID), jwt.WithIssuer("https://auth.example.com"))
+		if err != nil {
+			t.Fatalf("invalid id token: %v", err)
+		}
+		claims := idToken.Claims.(jwt.MapClaims)
+		if claims["nonce"] != "n-123" || claims["email"] != user.Email {
+			t.Errorf("unexpected id token claims: %v", claims)
+		}
+		if idToken.Header["kid"] != oidcService.JWKS().Keys[0].Kid {
+			t.Error("id token kid does not match published key")
+		}
+	})
+
+	t.Run("userinfo returns subject", func(t *testing.T) {
+		info, err := oidcService.UserInfo(ctx, resp.AccessToken)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info["sub"] != "1" || info["email"] != user.Email {
+			t.Errorf("unexpected userinfo: %v", info)
+		}
+	})
+
+	t.Run("code is single use", func(t *testing.T) {
+		if _, err := oidcService.ExchangeAuthorizationCode(ctx, &TokenRequest{ClientID: client.ClientID, ClientSecret: secret, Code: code, RedirectURI: req.RedirectURI}); err != ErrInvalidGrant {
+			t.Errorf("got error %v, want %v", err, ErrInvalidGrant)
+		}
+	})
+}
+
+func TestOIDCPKCEPublicClient(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+
+	key, err := oidc.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	oidcService := NewOIDCService(authService, mockRepo, test.NewMockOAuthRepository(), key, "https://auth.example.com")
+
+	user, err := authService.RegisterUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	client, secret, err := oidcService.RegisterClient(ctx, &ClientRegistration{Name: "spa", RedirectURIs: []string{"https://spa.example.com/callback"}, Public: true})
+	if err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+	if secret != "" {
+		t.Error("public clients must not receive a secret")
+	}
+
+	verifier := "a-sufficiently-long-random-code-verifier-for-pkce"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	req := &AuthorizeRequest{
+		ClientID:     client.ClientID,
+		RedirectURI:  "https://spa.example.com/callback",
+		ResponseType: "code",
+		Scope:        "openid",
+	}
+
+	t.Run("requires code challenge", func(t *testing.T) {
+		if _, err := oidcService.ValidateAuthorizeRequest(ctx, req); err != ErrPKCERequired {
+			t.Errorf("got error %v, want %v", err, ErrPKCERequired)
+		}
+	})
+
+	t.Run("rejects plain method", func(t *testing.T) {
+		plain := *req
+		plain.CodeChallenge = verifier
+		plain.CodeChallengeMethod = "plain"
+		if _, err := oidcService.ValidateAuthorizeRequest(ctx, &plain); err != ErrInvalidCodeChallenge {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCodeChallenge)
+		}
+	})
+
+	req.CodeChallenge = challenge
+	req.CodeChallengeMethod = "S256"
+	if _, err := oidcService.ValidateAuthorizeRequest(ctx, req); err != nil {
+		t.Fatalf("unexpected error validating request: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier string
+		wantErr  error
+	}{
+		{
+			name:     "wrong verifier",
+			verifier: "wrong-verifier-wrong-verifier-wrong-verifier",
+			wantErr:  ErrInvalidGrant,
+		},
+		{
+			name:     "valid verifier",
+			verifier: verifier,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := oidcService.IssueAuthorizationCode(ctx, req, user.ID)
+			if err != nil {
+				t.Fatalf("failed to issue code: %v", err)
+			}
+
+			resp, err := oidcService.ExchangeAuthorizationCode(ctx, &TokenRequest{
+				ClientID:     client.ClientID,
+				Code:         code,
+				RedirectURI:  req.RedirectURI,
+				CodeVerifier: tt.verifier,
+			})
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && resp.AccessToken == "" {
+				t.Error("expected access token")
+			}
+		})
+	}
+}
+
+func TestOIDCClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+
+	key, err := oidc.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	oidcService := NewOIDCService(authService, mockRepo, test.NewMockOAuthRepository(), key, "https://auth.example.com")
+
+	if _, _, err := oidcService.RegisterClient(ctx, &ClientRegistration{
+		Name:       "public machine",
+		Public:     true,
+		GrantTypes: []string{"client_credentials"},
+	}); err != ErrInvalidRegistration {
+		t.Errorf("got error %v, want %v", err, ErrInvalidRegistration)
+	}
+
+	client, secret, err := oidcService.RegisterClient(ctx, &ClientRegistration{
+		Name:       "billing worker",
+		GrantTypes: []string{"client_credentials"},
+		Scopes:     []string{"users:read", "users:write"},
+	})
+	if err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		scope     string
+		wantScope string
+		wantErr   error
+	}{
+		{name: "defaults to all allowed scopes", secret: secret, wantScope: "users:read users:write"},
+		{name: "narrower scope", secret: secret, scope: "users:read", wantScope: "users:read"},
+		{name: "scope not allowed", secret: secret, scope: "admin", wantErr: ErrScopeNotAllowed},
+		{name: "wrong secret", secret: "wrong", wantErr: ErrInvalidClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := oidcService.ClientCredentials(ctx, client.ClientID, tt.secret, tt.scope)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			token, err := jwt.Parse(resp.AccessToken, func(token *jwt.Token) (any, error) {
+				return &key.PrivateKey.PublicKey, nil
+			}, jwt.WithValidMethods([]string{"RS256"}))
+			if err != nil {
+				t.Fatalf("invalid access token: %v", err)
+			}
+			claims := token.Claims.(jwt.MapClaims)
+			if claims["scope"] != tt.wantScope || claims["sub"] != client.ClientID {
+				t.Errorf("unexpected claims: %v", claims)
+			}
+		})
+	}
+
+	t.Run("machine client cannot use authorization code flow", func(t *testing.T) {
+		_, err := oidcService.ValidateAuthorizeRequest(ctx, &AuthorizeRequest{ClientID: client.ClientID, ResponseType: "code", Scope: "openid"})
+		if err != ErrInvalidRedirectURI && err != ErrUnauthorizedClient {
+			t.Errorf("got error %v, want rejection", err)
+		}
+	})
+}
+
+func TestOIDCTokenExchange(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+
+	key, err := oidc.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	oidcService := NewOIDCService(authService, mockRepo, test.NewMockOAuthRepository(), key, "https://auth.example.com")
+
+	user, err := authService.RegisterUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	userToken, err := authService.LoginUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+
+	gateway, secret, err := oidcService.RegisterClient(ctx, &ClientRegistration{
+		Name:       "api gateway",
+		GrantTypes: []string{"urn:ietf:params:oauth:grant-type:token-exchange"},
+		Scopes:     []string{"orders:read"},
+	})
+	if err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+	worker, workerSecret, err := oidcService.RegisterClient(ctx, &ClientRegistration{
+		Name:       "billing worker",
+		GrantTypes: []string{"client_credentials"},
+		Scopes:     []string{"orders:read"},
+	})
+	if err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+
+	accessTokenType := "urn:ietf:params:oauth:token-type:access_token"
+	tests := []struct {
+		name    string
+		req     TokenExchangeRequest
+		wantErr error
+	}{
+		{name: "allowed scope", req: TokenExchangeRequest{ClientID: gateway.ClientID, ClientSecret: secret, SubjectToken: userToken, SubjectTokenType: accessTokenType, Scope: "orders:read", Audience: "orders"}},
+		{name: "scope not allowed", req: TokenExchangeRequest{ClientID: gateway.ClientID, ClientSecret: secret, SubjectToken: userToken, SubjectTokenType: accessTokenType, Scope: "orders:write"}, wantErr: ErrScopeNotAllowed},
+		{name: "client not allowed to exchange", req: TokenExchangeRequest{ClientID: worker.ClientID, ClientSecret: workerSecret, SubjectToken: userToken, SubjectTokenType: accessTokenType}, wantErr: ErrUnauthorizedClient},
+		{name: "wrong secret", req: TokenExchangeRequest{ClientID: gateway.ClientID, ClientSecret: "wrong", SubjectToken: userToken, SubjectTokenType: accessTokenType}, wantErr: ErrInvalidClient},
+		{name: "unsupported token type", req: TokenExchangeRequest{ClientID: gateway.ClientID, ClientSecret: secret, SubjectToken: userToken, SubjectTokenType: "urn:ietf:params:oauth:token-type:id_token"}, wantErr: ErrInvalidSubjectToken},
+		{name: "invalid subject token", req: TokenExchangeRequest{ClientID: gateway.ClientID, ClientSecret: secret, SubjectToken: "not-a-token", SubjectTokenType: accessTokenType}, wantErr: ErrInvalidSubjectToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := oidcService.ExchangeToken(ctx, &tt.req)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			token, err := jwt.Parse(resp.AccessToken, func(token *jwt.Token) (any, error) {
+				return &key.PrivateKey.PublicKey, nil
+			}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience("orders"))
+			if err != nil {
+				t.Fatalf("invalid access token: %v", err)
+			}
+			claims := token.Claims.(jwt.MapClaims)
+			act, _ := claims["act"].(map[string]any)
+			if claims["sub"] != strconv.FormatInt(user.ID, 10) || claims["scope"] != "orders:read" || act["sub"] != gateway.ClientID {
+				t.Errorf("unexpected claims: %v", claims)
+			}
+			if resp.IssuedTokenType != accessTokenType || resp.ExpiresIn <= 0 {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// MaxMetadataBytes limits the encoded size of each of a user's app and user
+// metadata
+const MaxMetadataBytes = 16 << 10
+
+// ErrMetadataTooLarge is returned when an update would make metadata larger
+// than MaxMetadataBytes
+var ErrMetadataTooLarge = errors.New("metadata is too large")
+
+// ProfileService lets signed-in users read and update their own account
+type ProfileService struct {
+	userRepo interfaces.UserRepository
+}
+
+// NewProfileService creates a new profile service
+func NewProfileService(userRepo interfaces.UserRepository) *ProfileService {
+	return &ProfileService{
+		userRepo: userRepo,
+	}
+}
+
+// Profile returns a user with their metadata
+func (s *ProfileService) Profile(ctx context.Context, userID int64) (*model.User, *model.UserMetadata, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err := s.userRepo.GetUserMetadata(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, metadata, nil
+}
+
+// UpdateProfile applies update to the user's profile and returns the updated
+// user. Callers validate the fields they set.
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID int64, update func(*model.Profile)) (*model.User, error) {
+	var user *model.User
+	err := s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		var err error
+		if user, err = repo.GetUserByID(ctx, userID); err != nil {
+			return err
+		}
+		update(&user.Profile)
+		return repo.UpdateUserProfile(ctx, userID, user.Profile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUserMetadata merges patch into the user's user_metadata, see
+// model.MergeMetadata, and returns the updated metadata
+func (s *ProfileService) UpdateUserMetadata(ctx context.Context, userID int64, patch map[string]any) (*model.UserMetadata, error) {
+	return updateMetadata(ctx, s.userRepo, userID, nil, patch)
+}
+
+// updateMetadata merges the patches into a user's app and user metadata in a
+// transaction, so concurrent updates are not lost
+func updateMetadata(ctx context.Context, userRepo interfaces.UserRepository, userID int64, app, user map[string]any) (*model.UserMetadata, error) {
+	var metadata *model.UserMetadata
+	err := userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		var err error
+		if metadata, err = repo.GetUserMetadata(ctx, userID); err != nil {
+			return err
+		}
+		metadata.AppMetadata = model.MergeMetadata(metadata.AppMetadata, app)
+		metadata.UserMetadata = model.MergeMetadata(metadata.UserMetadata, user)
+		for _, section := range []map[string]any{metadata.AppMetadata, metadata.UserMetadata} {
+			data, err := json.Marshal(section)
+			if err != nil {
+				return err
+			}
+			if len(data) > MaxMetadataBytes {
+				return ErrMetadataTooLarge
+			}
+		}
+		return repo.SetUserMetadata(ctx, userID, metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// ErrNotServiceAccount is returned when a service account operation targets a human user
+var ErrNotServiceAccount = errors.New("user is not a service account")
+
+// ServiceAccountService manages non-human users that authenticate with API keys
+type ServiceAccountService struct {
+	userRepo      interfaces.UserStore
+	apiKeyService *APIKeyService
+}
+
+// NewServiceAccountService creates a new service account service
+func NewServiceAccountService(userRepo interfaces.UserStore, apiKeyService *APIKeyService) *ServiceAccountService {
+	return &ServiceAccountService{
+		userRepo:      userRepo,
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateServiceAccount creates a service account identified by email
+func (s *ServiceAccountService) CreateServiceAccount(ctx context.Context, email string) (*model.User, error) {
+	return s.userRepo.CreateServiceAccount(ctx, email)
+}
+
+// ListServiceAccounts returns every service account with its lock state
+func (s *ServiceAccountService) ListServiceAccounts(ctx context.Context) ([]*model.User, error) {
+	return s.userRepo.ListServiceAccounts(ctx)
+}
+
+// SetLocked locks or unlocks a service account. API keys of a locked account are rejected.
+func (s *ServiceAccountService) SetLocked(ctx context.Context, userID int64, locked bool) error {
+	return s.userRepo.SetServiceAccountLocked(ctx, userID, locked)
+}
+
+// DeleteServiceAccount deletes a service account and its API keys
+func (s *ServiceAccountService) DeleteServiceAccount(ctx context.Context, userID int64) error {
+	return s.userRepo.DeleteServiceAccount(ctx, userID)
+}
+
+// CreateAPIKey issues an API key for a service account. The plaintext key is
+// returned once and cannot be recovered afterwards.
+func (s *ServiceAccountService) CreateAPIKey(ctx context.Context, userID int64, name string) (*model.APIKey, string, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == repository.ErrTooManyAttempts {
+			return nil, "", ErrAccountLocked
+		}
+		return nil, "", err
+	}
+	if !user.IsServiceAccount() {
+		return nil, "", ErrNotServiceAccount
+	}
+
+	return s.apiKeyService.CreateAPIKey(ctx, user.ID, name)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestServiceAccountLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+	apiKeyService := NewAPIKeyService(test.NewMockAPIKeyRepository(), mockRepo, authService.LockoutPolicy())
+	accounts := NewServiceAccountService(mockRepo, apiKeyService)
+
+	account, err := accounts.CreateServiceAccount(ctx, "ci-bot@example.com")
+	if err != nil {
+		t.Fatalf("failed to create service account: %v", err)
+	}
+	human, err := authService.RegisterUser(ctx, "human@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	t.Run("cannot log in with a password", func(t *testing.T) {
+		for _, password := range []string{"", "!"} {
+			if _, err := authService.LoginUser(ctx, account.Email, password); err != ErrInvalidCredentials {
+				t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+			}
+		}
+	})
+
+	t.Run("api keys only for service accounts", func(t *testing.T) {
+		if _, _, err := accounts.CreateAPIKey(ctx, human.ID, "ci"); err != ErrNotServiceAccount {
+			t.Errorf("got error %v, want %v", err, ErrNotServiceAccount)
+		}
+	})
+
+	_, plaintext, err := accounts.CreateAPIKey(ctx, account.ID, "ci")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if userID, err := apiKeyService.ValidateAPIKey(ctx, plaintext); err != nil || userID != account.ID {
+		t.Fatalf("got user %d and error %v, want %d", userID, err, account.ID)
+	}
+
+	t.Run("locking rejects api keys", func(t *testing.T) {
+		if err := accounts.SetLocked(ctx, account.ID, true); err != nil {
+			t.Fatalf("failed to lock: %v", err)
+		}
+		if _, err := apiKeyService.ValidateAPIKey(ctx, plaintext); err != ErrAccountLocked {
+			t.Errorf("got error %v, want %v", err, ErrAccountLocked)
+		}
+
+		listed, err := accounts.ListServiceAccounts(ctx)
+		if err != nil || len(listed) != 1 || !listed[0].IsLocked {
+			t.Errorf("expected one locked service account, got %v (%v)", listed, err)
+		}
+
+		if err := accounts.SetLocked(ctx, account.ID, false); err != nil {
+			t.Fatalf("failed to unlock: %v", err)
+		}
+		if _, err := apiKeyService.ValidateAPIKey(ctx, plaintext); err != nil {
+			t.Errorf("unexpected error after unlock: %v", err)
+		}
+	})
+
+	t.Run("human users cannot be managed as service accounts", func(t *testing.T) {
+		if err := accounts.SetLocked(ctx, human.ID, true); err != repository.ErrUserNotFound {
+			t.Errorf("got error %v, want %v", err, repository.ErrUserNotFound)
+		}
+		if err := accounts.DeleteServiceAccount(ctx, human.ID); err != repository.ErrUserNotFound {
+			t.Errorf("got error %v, want %v", err, repository.ErrUserNotFound)
+		}
+	})
+
+	if err := accounts.DeleteServiceAccount(ctx, account.ID); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := apiKeyService.ValidateAPIKey(ctx, plaintext); err != ErrInvalidAPIKey {
+		t.Errorf("got error %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultSessionCacheSize is the number of sessions a SessionCache holds
+const DefaultSessionCacheSize = 100000
+
+// SessionCache remembers for a short time whether sessions are valid, so
+// validating a token does not query the session store every time. The
+// services drop the entries of sessions they revoke; revocations by another
+// replica or process only apply once the entries expire. A nil SessionCache
+// caches nothing.
+type SessionCache struct {
+	ttl     time.Duration
+	size    int
+	metrics *metrics.SessionCache
+	now     func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]sessionCacheEntry // by token ID
+	generation uint64                       // counts invalidations
+}
+
+type sessionCacheEntry struct {
+	userID  int64
+	valid   bool
+	expires time.Time
+}
+
+// SessionCacheOption configures a SessionCache
+type SessionCacheOption func(*SessionCache)
+
+// WithSessionCacheSize holds at most n sessions (default DefaultSessionCacheSize)
+func WithSessionCacheSize(n int) SessionCacheOption {
+	return func(c *SessionCache) {
+		if n > 0 {
+			c.size = n
+		}
+	}
+}
+
+// WithSessionCacheMetrics exports the hit rate and size of the cache to reg
+func WithSessionCacheMetrics(reg prometheus.Registerer) SessionCacheOption {
+	return func(c *SessionCache) {
+		c.metrics = metrics.NewSessionCache(reg, c.Len)
+	}
+}
+
+// NewSessionCache creates a cache keeping the validity of a session for ttl
+func NewSessionCache(ttl time.Duration, opts ...SessionCacheOption) *SessionCache {
+	c := &SessionCache{ttl: ttl, size: DefaultSessionCacheSize, now: time.Now, entries: make(map[string]sessionCacheEntry)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the cached validity of a session, and whether there was one
+func (c *SessionCache) Get(tokenID string) (valid, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[tokenID]
+	if ok && !c.now().Before(entry.expires) {
+		delete(c.entries, tokenID)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	c.metrics.Lookup(ok)
+	return entry.valid && ok, ok
+}
+
+// Generation returns the number of invalidations so far. Take it before
+// looking a session up in the session store, and pass it to Put.
+func (c *SessionCache) Generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Put caches the validity of a session of a user, looked up at generation.
+// It caches nothing if sessions were invalidated since, as the lookup may
+// have raced with their revocation. When the cache is full, expired entries
+// are removed first; if none are, the session is not cached.
+func (c *SessionCache) Put(tokenID string, userID int64, valid bool, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+	if _, exists := c.entries[tokenID]; !exists && len(c.entries) >= c.size {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= c.size {
+			return
+		}
+	}
+	c.entries[tokenID] = sessionCacheEntry{userID: userID, valid: valid, expires: now.Add(c.ttl)}
+}
+
+// Forget drops a session, after it was revoked
+func (c *SessionCache) Forget(tokenID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, tokenID)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// ForgetUser drops every session of a user, after they were revoked. It scans
+// the cache, which is cheap next to how rarely all of a user's sessions are
+// revoked.
+func (c *SessionCache) ForgetUser(userID int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for id, entry := range c.entries {
+		if entry.userID == userID {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// Clear drops every session, after revocations of many users
+func (c *SessionCache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.entries)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached sessions
+func (c *SessionCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
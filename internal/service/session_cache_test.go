@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestSessionCache(t *testing.T) {
+	cache := NewSessionCache(time.Minute, WithSessionCacheSize(3))
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	cache.Put("a1", 1, true, cache.Generation())
+	cache.Put("a2", 1, true, cache.Generation())
+	cache.Put("b1", 2, false, cache.Generation())
+	cache.Put("c1", 3, true, cache.Generation()) // full
+
+	tests := []struct {
+		name      string
+		tokenID   string
+		wantValid bool
+		wantOK    bool
+	}{
+		{name: "valid session", tokenID: "a1", wantValid: true, wantOK: true},
+		{name: "revoked session", tokenID: "b1", wantValid: false, wantOK: true},
+		{name: "unknown session", tokenID: "x", wantOK: false},
+		{name: "not cached when full", tokenID: "c1", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, ok := cache.Get(tt.tokenID)
+			if valid != tt.wantValid || ok != tt.wantOK {
+				t.Errorf("Get(%q) = %v, %v, want %v, %v", tt.tokenID, valid, ok, tt.wantValid, tt.wantOK)
+			}
+		})
+	}
+
+	t.Run("forget", func(t *testing.T) {
+		cache.Forget("a1")
+		if _, ok := cache.Get("a1"); ok {
+			t.Error("expected a forgotten session to be a miss")
+		}
+		cache.Put("a1", 1, true, cache.Generation())
+		cache.ForgetUser(1)
+		if cache.Len() != 1 {
+			t.Errorf("got %d sessions after forgetting a user, want 1", cache.Len())
+		}
+		cache.Clear()
+		if cache.Len() != 0 {
+			t.Errorf("got %d sessions after clearing, want 0", cache.Len())
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		for _, id := range []string{"a1", "a2", "b1"} {
+			cache.Put(id, 1, true, cache.Generation())
+		}
+		now = now.Add(time.Minute)
+		cache.Put("c1", 3, true, cache.Generation()) // makes room by dropping the expired sessions
+		if valid, ok := cache.Get("c1"); !valid || !ok || cache.Len() != 1 {
+			t.Errorf("Get(c1) = %v, %v with %d sessions, want the only cached session", valid, ok, cache.Len())
+		}
+		cache.Put("a1", 1, true, cache.Generation())
+		now = now.Add(time.Minute)
+		if _, ok := cache.Get("a1"); ok {
+			t.Error("expected an expired session to be a miss")
+		}
+	})
+
+	t.Run("invalidated during lookup", func(t *testing.T) {
+		generation := cache.Generation()
+		cache.ForgetUser(1) // the session was revoked while it was looked up
+		cache.Put("a1", 1, true, generation)
+		if _, ok := cache.Get("a1"); ok {
+			t.Error("expected a lookup older than the invalidation not to be cached")
+		}
+	})
+
+	var disabled *SessionCache
+	disabled.Put("a1", 1, true, 0) // must not panic
+	if _, ok := disabled.Get("a1"); ok {
+		t.Error("expected a nil cache to cache nothing")
+	}
+}
+
+func TestValidateTokenSessionCache(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	cache := NewSessionCache(time.Minute)
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithSessionCache(cache))
+	user, err := authService.RegisterUser(ctx, "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	login := func() (string, string) {
+		token, err := authService.LoginUser(ctx, "test@example.com", "password123")
+		if err != nil {
+			t.Fatalf("failed to login test user: %v", err)
+		}
+		claims, err := authService.ValidateToken(ctx, token)
+		if err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+		return token, claims["jti"].(string)
+	}
+	first, firstID := login()
+	second, _ := login()
+
+	// Revoked behind the cache's back, the session is trusted until it expires
+	if err := mockRepo.RevokeSession(ctx, firstID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authService.ValidateToken(ctx, first); err != nil {
+		t.Errorf("got error %v for a cached session, want it valid", err)
+	}
+	cache.Forget(firstID)
+	if _, err := authService.ValidateToken(ctx, first); err != ErrInvalidToken {
+		t.Errorf("got error %v once the cache was checked again, want %v", err, ErrInvalidToken)
+	}
+
+	if err := authService.LogoutUser(ctx, second); err != nil {
+		t.Fatalf("LogoutUser() error = %v", err)
+	}
+	if _, err := authService.ValidateToken(ctx, second); err != ErrInvalidToken {
+		t.Errorf("got error %v after logout, want %v", err, ErrInvalidToken)
+	}
+
+	third, _ := login()
+	if _, err := authService.RevokeUserSessions(ctx, user.ID); err != nil {
+		t.Fatalf("RevokeUserSessions() error = %v", err)
+	}
+	if _, err := authService.ValidateToken(ctx, third); err != ErrInvalidToken {
+		t.Errorf("got error %v after revoking the user's sessions, want %v", err, ErrInvalidToken)
+	}
+}
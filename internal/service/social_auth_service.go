@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/oauth"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// ErrUnknownProvider is returned when a social login provider is not configured
+var ErrUnknownProvider = errors.New("unknown login provider")
+
+// SocialAuthService signs users in through external OAuth providers
+type SocialAuthService struct {
+	authService  *AuthService
+	userRepo     interfaces.UserStore
+	identityRepo interfaces.IdentityRepository
+	providers    map[string]oauth.Provider
+}
+
+// NewSocialAuthService creates a social login service for the given providers
+func NewSocialAuthService(authService *AuthService, userRepo interfaces.UserStore, identityRepo interfaces.IdentityRepository, providers ...oauth.Provider) *SocialAuthService {
+	byName := make(map[string]oauth.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &SocialAuthService{
+		authService:  authService,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		providers:    byName,
+	}
+}
+
+// AuthCodeURL returns the provider URL that starts the login flow
+func (s *SocialAuthService) AuthCodeURL(provider, state string) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+	return p.AuthCodeURL(state), nil
+}
+
+// LoginWithProvider completes the provider callback and returns a JWT token.
+// Identities are linked to existing accounts by verified email, and a new
+// account without a usable password is created when none exists.
+func (s *SocialAuthService) LoginWithProvider(ctx context.Context, provider, code string) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	identity, err := p.Exchange(ctx, code)
+	if err != nil {
+		return "", err
+	}
+
+	return s.LoginWithIdentity(ctx, identity)
+}
+
+// LoginWithIdentity signs in the user for an identity already verified by an
+// external identity provider, such as a SAML IdP, and returns a JWT token
+func (s *SocialAuthService) LoginWithIdentity(ctx context.Context, identity *oauth.Identity) (string, error) {
+	user, err := s.resolveUser(ctx, identity)
+	if err != nil {
+		switch err {
+		case repository.ErrTooManyAttempts:
+			return "", ErrAccountLocked
+		case repository.ErrTenantSuspended:
+			return "", ErrTenantSuspended
+		case repository.ErrUserDisabled:
+			return "", ErrAccountDisabled
+		}
+		return "", err
+	}
+
+	lockout, err := s.authService.lockoutPolicyFor(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	if lockout.IsLocked(user.FailedAttempts) {
+		return "", ErrAccountLocked
+	}
+
+	scope, err := s.authService.resolveScope("")
+	if err != nil {
+		return "", err
+	}
+	return s.authService.signIn(ctx, user, scope, "", false, false)
+}
+
+// resolveUser finds the user for an identity, linking or creating an account as needed
+func (s *SocialAuthService) resolveUser(ctx context.Context, identity *oauth.Identity) (*model.User, error) {
+	user, err := s.identityRepo.GetUserByIdentity(ctx, identity.Provider, identity.ProviderUserID)
+	if err == nil {
+		return user, nil
+	}
+	if err != repository.ErrUserNotFound {
+		return nil, err
+	}
+
+	// Link by email to an existing account, or register a new one
+	email := s.authService.NormalizeEmail(identity.Email)
+	user, err = s.userRepo.GetUserByEmail(ctx, email)
+	if err == repository.ErrUserNotFound {
+		user, err = s.createPasswordlessUser(ctx, email)
+		// The email belongs to a deleted account, which must not be revived by signing in
+		if err == repository.ErrDuplicateEmail {
+			return nil, ErrInvalidCredentials
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Service accounts never sign in interactively, so identities are not linked to them
+	if user.IsServiceAccount() {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.identityRepo.LinkIdentity(ctx, user.ID, identity.Provider, identity.ProviderUserID, identity.Email); err != nil {
+		return nil, err
+	}
+	// Providers only return verified emails
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createPasswordlessUser registers a user whose password hash can never match
+func (s *SocialAuthService) createPasswordlessUser(ctx context.Context, email string) (*model.User, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(ctx, s.authService.hasher, hex.EncodeToString(random))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.CreateUser(ctx, email, hashedPassword)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/oauth"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+// fakeProvider returns a fixed identity for any code
+type fakeProvider struct {
+	identity *oauth.Identity
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+func (p *fakeProvider) AuthCodeURL(state string) string {
+	return "https://provider.test/authorize?state=" + state
+}
+func (p *fakeProvider) Exchange(ctx context.Context, code string) (*oauth.Identity, error) {
+	return p.identity, nil
+}
+
+func TestLoginWithProvider(t *testing.T) {
+	mockRepo := test.NewMockUserRepository()
+	identityRepo := test.NewMockIdentityRepository(mockRepo)
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+
+	// Register an existing password user to link against
+	existing, err := authService.RegisterUser(context.Background(), "linked@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		provider   string
+		identity   *oauth.Identity
+		wantUserID int64
+		wantErr    error
+	}{
+		{
+			name:       "links existing account by email",
+			provider:   "fake",
+			identity:   &oauth.Identity{Provider: "fake", ProviderUserID: "1", Email: "linked@example.com"},
+			wantUserID: existing.ID,
+		},
+		{
+			name:       "reuses linked identity",
+			provider:   "fake",
+			identity:   &oauth.Identity{Provider: "fake", ProviderUserID: "1", Email: "changed@example.com"},
+			wantUserID: existing.ID,
+		},
+		{
+			name:       "creates new account",
+			provider:   "fake",
+			identity:   &oauth.Identity{Provider: "fake", ProviderUserID: "2", Email: "new@example.com"},
+			wantUserID: existing.ID + 1,
+		},
+		{
+			name:     "unknown provider",
+			provider: "other",
+			wantErr:  ErrUnknownProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socialService := NewSocialAuthService(authService, mockRepo, identityRepo, &fakeProvider{identity: tt.identity})
+
+			token, err := socialService.LoginWithProvider(context.Background(), tt.provider, "code")
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			claims, err := authService.ValidateToken(context.Background(), token)
+			if err != nil {
+				t.Fatalf("issued token is invalid: %v", err)
+			}
+			if sub := int64(claims["sub"].(float64)); sub != tt.wantUserID {
+				t.Errorf("got user %d, want %d", sub, tt.wantUserID)
+			}
+			if user, _ := mockRepo.GetUserForAdmin(context.Background(), tt.wantUserID); user == nil || !user.EmailVerified {
+				t.Error("expected the provider's email to be marked verified")
+			}
+		})
+	}
+}
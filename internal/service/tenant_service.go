@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/password"
+)
+
+// Errors returned by the tenant service
+var (
+	ErrInvalidTenant         = errors.New("invalid tenant")
+	ErrInvalidTenantSettings = errors.New("invalid tenant settings")
+)
+
+var (
+	tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+	colorPattern      = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// TenantService manages the tenant lifecycle: onboarding, configuration,
+// suspension, and deletion
+type TenantService struct {
+	tenantRepo interfaces.TenantRepository
+	users      interfaces.UserStore    // nil when sessions are kept with tenants
+	sessions   interfaces.SessionStore // nil when sessions are kept with tenants
+	hasher     *password.Hasher
+	policy     password.Policy
+	cache      *SessionCache // nil when sessions are not cached
+}
+
+// TenantServiceOption configures a TenantService
+type TenantServiceOption func(*TenantService)
+
+// WithTenantSessions revokes the sessions of a tenant's users in sessions, one
+// user at a time, for sessions kept in a different backend than tenants
+func WithTenantSessions(users interfaces.UserStore, sessions interfaces.SessionStore) TenantServiceOption {
+	return func(s *TenantService) {
+		s.users = users
+		s.sessions = sessions
+	}
+}
+
+// WithTenantSessionCache clears cache after revoking the sessions of a
+// tenant's users
+func WithTenantSessionCache(cache *SessionCache) TenantServiceOption {
+	return func(s *TenantService) {
+		s.cache = cache
+	}
+}
+
+// WithTenantPasswords hashes admin passwords with hasher and checks them
+// against policy, replacing password.Default and password.DefaultPolicy
+func WithTenantPasswords(hasher *password.Hasher, policy password.Policy) TenantServiceOption {
+	return func(s *TenantService) {
+		s.hasher = hasher
+		s.policy = policy
+	}
+}
+
+// NewTenantService creates a new tenant service
+func NewTenantService(tenantRepo interfaces.TenantRepository, opts ...TenantServiceOption) *TenantService {
+	s := &TenantService{tenantRepo: tenantRepo, hasher: password.Default, policy: password.DefaultPolicy}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// TenantOnboarding describes a new tenant and its first admin
+type TenantOnboarding struct {
+	Slug          string
+	Name          string
+	Settings      model.TenantSettings
+	AdminEmail    string
+	AdminPassword string // generated when empty
+}
+
+// OnboardTenant creates a tenant and its first admin. When no admin password
+// is given, a random one is generated and returned; it cannot be recovered later.
+// A given password the policy rejects fails with password.Violations.
+func (s *TenantService) OnboardTenant(ctx context.Context, req *TenantOnboarding) (*model.Tenant, *model.User, string, error) {
+	if !tenantSlugPattern.MatchString(req.Slug) || req.Name == "" {
+		return nil, nil, "", fmt.Errorf("%w: slug must be 2-63 lowercase letters, digits, or dashes and name is required", ErrInvalidTenant)
+	}
+	if err := ValidateTenantSettings(req.Settings); err != nil {
+		return nil, nil, "", err
+	}
+
+	password, generated := req.AdminPassword, ""
+	if password == "" {
+		var err error
+		if password, err = s.policy.Generate(req.AdminEmail); err != nil {
+			return nil, nil, "", err
+		}
+		generated = password
+	} else if err := s.policy.Check(password, req.AdminEmail); err != nil {
+		return nil, nil, "", err
+	}
+	hashed, err := hashPassword(ctx, s.hasher, password)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	tenant := &model.Tenant{Slug: req.Slug, Name: req.Name, Settings: req.Settings}
+	admin, err := s.tenantRepo.OnboardTenant(ctx, tenant, req.AdminEmail, hashed)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return tenant, admin, generated, nil
+}
+
+// GetTenant returns a tenant
+func (s *TenantService) GetTenant(ctx context.Context, tenantID int64) (*model.Tenant, error) {
+	return s.tenantRepo.GetTenant(ctx, tenantID)
+}
+
+// ListTenants returns every tenant
+func (s *TenantService) ListTenants(ctx context.Context) ([]*model.Tenant, error) {
+	return s.tenantRepo.ListTenants(ctx)
+}
+
+// ConfigureTenant validates and replaces a tenant's settings
+func (s *TenantService) ConfigureTenant(ctx context.Context, tenantID int64, settings model.TenantSettings) error {
+	if err := ValidateTenantSettings(settings); err != nil {
+		return err
+	}
+	return s.tenantRepo.UpdateTenantSettings(ctx, tenantID, settings)
+}
+
+// SuspendTenant blocks sign-in and API keys for the tenant's users and revokes
+// their sessions. It returns how many sessions were revoked.
+func (s *TenantService) SuspendTenant(ctx context.Context, tenantID int64) (int64, error) {
+	if err := s.tenantRepo.SetTenantStatus(ctx, tenantID, model.TenantStatusSuspended); err != nil {
+		return 0, err
+	}
+	return s.revokeSessions(ctx, tenantID)
+}
+
+// revokeSessions revokes every active session of the tenant's users. The
+// session cache is cleared rather than searched for the users' sessions.
+func (s *TenantService) revokeSessions(ctx context.Context, tenantID int64) (int64, error) {
+	defer s.cache.Clear()
+	if s.sessions == nil {
+		return s.tenantRepo.RevokeTenantSessions(ctx, tenantID)
+	}
+
+	var revoked int64
+	filter := model.UserFilter{TenantID: &tenantID, Page: pagination.Page{Limit: pagination.MaxLimit}}
+	for {
+		users, next, err := s.users.SearchUsers(ctx, filter)
+		if err != nil {
+			return revoked, err
+		}
+		for _, user := range users {
+			n, err := s.sessions.RevokeAllSessions(ctx, user.ID)
+			if err != nil {
+				return revoked, err
+			}
+			revoked += n
+		}
+		if next == "" {
+			return revoked, nil
+		}
+		filter.Page.Cursor = next
+	}
+}
+
+// ActivateTenant lifts a suspension
+func (s *TenantService) ActivateTenant(ctx context.Context, tenantID int64) error {
+	return s.tenantRepo.SetTenantStatus(ctx, tenantID, model.TenantStatusActive)
+}
+
+// DeleteTenant deletes a tenant with all of its users and their sessions,
+// identities, and API keys. It returns how many users were deleted.
+func (s *TenantService) DeleteTenant(ctx context.Context, tenantID int64) (int64, error) {
+	// Sessions in another backend are not deleted by cascade
+	if s.sessions != nil {
+		if _, err := s.revokeSessions(ctx, tenantID); err != nil {
+			return 0, err
+		}
+	}
+	defer s.cache.Clear()
+	return s.tenantRepo.DeleteTenant(ctx, tenantID)
+}
+
+// ValidateTenantSettings checks tenant settings before they are stored
+func ValidateTenantSettings(settings model.TenantSettings) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTenantSettings, reason)
+	}
+
+	if settings.Lockout != nil && settings.Lockout.MaxFailedAttempts < 1 {
+		return invalid("lockout.max_failed_attempts must be at least 1")
+	}
+
+	branding := settings.Branding
+	if branding.PrimaryColor != "" && !colorPattern.MatchString(branding.PrimaryColor) {
+		return invalid("branding.primary_color must be a #RRGGBB color")
+	}
+	if branding.LogoURL != "" && !isHTTPSURL(branding.LogoURL) {
+		return invalid("branding.logo_url must be an https URL")
+	}
+
+	for _, idp := range settings.IdentityProviders {
+		switch {
+		case idp.Name == "":
+			return invalid("identity providers need a name")
+		case idp.Type == "saml" && !isHTTPSURL(idp.MetadataURL):
+			return invalid("SAML identity providers need an https metadata_url")
+		case idp.Type == "oidc" && (!isHTTPSURL(idp.Issuer) || idp.ClientID == ""):
+			return invalid("OIDC identity providers need an https issuer and a client_id")
+		case idp.Type != "saml" && idp.Type != "oidc":
+			return invalid("identity provider type must be saml or oidc")
+		}
+	}
+
+	seen := make(map[string]bool, len(settings.Keys))
+	for _, key := range settings.Keys {
+		if key.ID == "" || seen[key.ID] {
+			return invalid("keys need a unique kid")
+		}
+		seen[key.ID] = true
+
+		block, _ := pem.Decode([]byte(key.PublicKeyPEM))
+		if block == nil {
+			return invalid("key " + key.ID + " is not PEM encoded")
+		}
+		if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return invalid("key " + key.ID + " is not a valid public key")
+		}
+	}
+
+	return nil
+}
+
+// isHTTPSURL reports whether s is an absolute https URL
+func isHTTPSURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+func TestTenantLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	tenantRepo := test.NewMockTenantRepository(mockRepo)
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithTenants(tenantRepo))
+	tenantService := NewTenantService(tenantRepo)
+
+	tenant, admin, password, err := tenantService.OnboardTenant(ctx, &TenantOnboarding{
+		Slug:       "acme",
+		Name:       "Acme Corp",
+		AdminEmail: "admin@acme.example.com",
+	})
+	if err != nil {
+		t.Fatalf("failed to onboard tenant: %v", err)
+	}
+	if admin.Role != model.RoleAdmin || admin.TenantID == nil || *admin.TenantID != tenant.ID {
+		t.Errorf("unexpected admin: %+v", admin)
+	}
+	if password == "" {
+		t.Fatal("expected a generated admin password")
+	}
+
+	if _, _, _, err := tenantService.OnboardTenant(ctx, &TenantOnboarding{Slug: "acme", Name: "Again", AdminEmail: "other@acme.example.com"}); err != repository.ErrDuplicateTenantSlug {
+		t.Errorf("got error %v, want %v", err, repository.ErrDuplicateTenantSlug)
+	}
+
+	token, err := authService.LoginUser(ctx, admin.Email, password)
+	if err != nil {
+		t.Fatalf("admin failed to log in: %v", err)
+	}
+
+	t.Run("tenant lockout policy", func(t *testing.T) {
+		admin.FailedAttempts = 2
+		defer func() { admin.FailedAttempts = 0 }()
+
+		if _, err := authService.Authenticate(ctx, admin.Email, password); err != nil {
+			t.Fatalf("unexpected error under default policy: %v", err)
+		}
+
+		admin.FailedAttempts = 2
+		if err := tenantService.ConfigureTenant(ctx, tenant.ID, model.TenantSettings{Lockout: &model.LockoutPolicy{MaxFailedAttempts: 2}}); err != nil {
+			t.Fatalf("failed to configure tenant: %v", err)
+		}
+		if _, err := authService.Authenticate(ctx, admin.Email, password); err != ErrAccountLocked {
+			t.Errorf("got error %v, want %v", err, ErrAccountLocked)
+		}
+	})
+
+	t.Run("suspension blocks sign-in and revokes sessions", func(t *testing.T) {
+		revoked, err := tenantService.SuspendTenant(ctx, tenant.ID)
+		if err != nil || revoked != 1 {
+			t.Fatalf("got %d revoked and error %v, want 1", revoked, err)
+		}
+		if _, err := authService.ValidateToken(ctx, token); err == nil {
+			t.Error("expected session to be revoked")
+		}
+		if _, err := authService.LoginUser(ctx, admin.Email, password); err != ErrTenantSuspended {
+			t.Errorf("got error %v, want %v", err, ErrTenantSuspended)
+		}
+
+		if err := tenantService.ActivateTenant(ctx, tenant.ID); err != nil {
+			t.Fatalf("failed to activate tenant: %v", err)
+		}
+		admin.FailedAttempts = 0
+		if _, err := authService.LoginUser(ctx, admin.Email, password); err != nil {
+			t.Errorf("unexpected error after activation: %v", err)
+		}
+	})
+
+	deleted, err := tenantService.DeleteTenant(ctx, tenant.ID)
+	if err != nil || deleted != 1 {
+		t.Fatalf("got %d deleted and error %v, want 1", deleted, err)
+	}
+	if _, err := authService.LoginUser(ctx, admin.Email, password); err != ErrInvalidCredentials {
+		t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := tenantService.GetTenant(ctx, tenant.ID); err != repository.ErrTenantNotFound {
+		t.Errorf("got error %v, want %v", err, repository.ErrTenantNotFound)
+	}
+}
+
+func TestTenantSuspensionRevokesSeparateSessions(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	sessions := test.NewMockUserRepository()
+	tenantRepo := test.NewMockTenantRepository(mockRepo)
+	authService := NewAuthService(mockRepo, sessions, "test-secret", WithTenants(tenantRepo))
+	tenantService := NewTenantService(tenantRepo, WithTenantSessions(mockRepo, sessions))
+
+	tenant, admin, password, err := tenantService.OnboardTenant(ctx, &TenantOnboarding{
+		Slug:       "acme",
+		Name:       "Acme Corp",
+		AdminEmail: "admin@acme.example.com",
+	})
+	if err != nil {
+		t.Fatalf("failed to onboard tenant: %v", err)
+	}
+	token, err := authService.LoginUser(ctx, admin.Email, password)
+	if err != nil {
+		t.Fatalf("admin failed to log in: %v", err)
+	}
+
+	revoked, err := tenantService.SuspendTenant(ctx, tenant.ID)
+	if err != nil || revoked != 1 {
+		t.Fatalf("got %d revoked and error %v, want 1", revoked, err)
+	}
+	if _, err := authService.ValidateToken(ctx, token); err == nil {
+		t.Error("expected session in the session store to be revoked")
+	}
+}
+
+func TestValidateTenantSettings(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	tests := []struct {
+		name     string
+		settings model.TenantSettings
+		wantErr  bool
+	}{
+		{name: "empty", settings: model.TenantSettings{}},
+		{
+			name: "full",
+			settings: model.TenantSettings{
+				Lockout:  &model.LockoutPolicy{MaxFailedAttempts: 3},
+				Branding: model.TenantBranding{DisplayName: "Acme", LogoURL: "https://cdn.example.com/logo.png", PrimaryColor: "#336699"},
+				IdentityProviders: []model.TenantIdP{
+					{Type: "saml", Name: "Okta", MetadataURL: "https://acme.okta.com/metadata"},
+					{Type: "oidc", Name: "Google", Issuer: "https://accounts.google.com", ClientID: "abc"},
+				},
+				Keys: []model.TenantKey{{ID: "k1", PublicKeyPEM: publicKeyPEM}},
+			},
+		},
+		{name: "zero lockout", settings: model.TenantSettings{Lockout: &model.LockoutPolicy{}}, wantErr: true},
+		{name: "bad color", settings: model.TenantSettings{Branding: model.TenantBranding{PrimaryColor: "blue"}}, wantErr: true},
+		{name: "http logo", settings: model.TenantSettings{Branding: model.TenantBranding{LogoURL: "http://example.com/logo.png"}}, wantErr: true},
+		{name: "unknown idp type", settings: model.TenantSettings{IdentityProviders: []model.TenantIdP{{Type: "ldap", Name: "AD"}}}, wantErr: true},
+		{name: "oidc without client", settings: model.TenantSettings{IdentityProviders: []model.TenantIdP{{Type: "oidc", Name: "G", Issuer: "https://accounts.google.com"}}}, wantErr: true},
+		{name: "invalid key", settings: model.TenantSettings{Keys: []model.TenantKey{{ID: "k1", PublicKeyPEM: "not a key"}}}, wantErr: true},
+		{name: "duplicate kid", settings: model.TenantSettings{Keys: []model.TenantKey{{ID: "k1", PublicKeyPEM: publicKeyPEM}, {ID: "k1", PublicKeyPEM: publicKeyPEM}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTenantSettings(tt.settings)
+			if tt.wantErr != errors.Is(err, ErrInvalidTenantSettings) {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
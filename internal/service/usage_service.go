@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// UsageService reports metered usage per tenant and OAuth client for billing
+type UsageService struct {
+	usageRepo interfaces.UsageRepository
+}
+
+// NewUsageService creates a new usage reporting service
+func NewUsageService(usageRepo interfaces.UsageRepository) *UsageService {
+	return &UsageService{usageRepo: usageRepo}
+}
+
+// Report returns the usage of the month containing period. Recent usage
+// appears once the meter has flushed it.
+func (s *UsageService) Report(ctx context.Context, period time.Time) (*model.UsageReport, error) {
+	return s.usageRepo.GetUsage(ctx, model.UsagePeriod(period))
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Stewz00/go-auth-service/internal/email"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+)
+
+// ErrHumanUsersOnly is returned when a user management operation targets a service account
+var ErrHumanUsersOnly = errors.New("operation only applies to human users")
+
+// UserAdminService lets administrators inspect and manage user accounts.
+// Every operation takes the tenant the administrator is scoped to; nil means
+// all users. Users outside the scope are reported as not found.
+type UserAdminService struct {
+	userRepo    interfaces.UserRepository
+	authService *AuthService
+}
+
+// NewUserAdminService creates a new user administration service
+func NewUserAdminService(userRepo interfaces.UserRepository, authService *AuthService) *UserAdminService {
+	return &UserAdminService{
+		userRepo:    userRepo,
+		authService: authService,
+	}
+}
+
+// SearchUsers returns a page of the users in scope matching filter, with the cursor of the next page
+func (s *UserAdminService) SearchUsers(ctx context.Context, tenantID *int64, filter model.UserFilter) ([]*model.User, string, error) {
+	if tenantID != nil {
+		filter.TenantID = tenantID
+	}
+	return s.userRepo.SearchUsers(ctx, filter)
+}
+
+// GetUser returns a user with the state of its account lockout
+func (s *UserAdminService) GetUser(ctx context.Context, tenantID *int64, userID int64) (*model.User, *model.LockoutState, error) {
+	user, err := s.lookup(ctx, tenantID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy, err := s.authService.lockoutPolicyFor(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &model.LockoutState{
+		FailedAttempts:    user.FailedAttempts,
+		MaxFailedAttempts: policy.MaxFailedAttempts,
+		Locked:            user.IsLocked,
+	}, nil
+}
+
+// SetDisabled disables or re-enables a user. Disabling also revokes the user's sessions.
+func (s *UserAdminService) SetDisabled(ctx context.Context, tenantID *int64, userID int64, disabled bool) error {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	err := s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repo.SetUserDisabled(ctx, userID, disabled); err != nil {
+			return err
+		}
+		if !disabled {
+			return nil
+		}
+		revoked, err := repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.authService.emitRevoked(ctx, repo, userID, RevokedByDisable, revoked)
+	})
+	if disabled {
+		s.authService.cache.ForgetUser(userID)
+	}
+	return err
+}
+
+// exportPageSize is the number of users an export reads per query
+const exportPageSize = 1000
+
+// ExportUsers calls fn with every user in scope matching filter, in ID order.
+// Users are read a page at a time, following the cursor, so exporting a large
+// table does not hold it in memory. It stops at the first error of fn.
+func (s *UserAdminService) ExportUsers(ctx context.Context, tenantID *int64, filter model.UserFilter, fn func(*model.User) error) error {
+	if tenantID != nil {
+		filter.TenantID = tenantID
+	}
+	filter.Page = pagination.Page{Limit: exportPageSize}
+	for {
+		users, next, err := s.userRepo.SearchUsers(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		filter.Page.Cursor = next
+	}
+}
+
+// CreateUser creates a user with the given password, or with a random
+// temporary one that must be changed at the first sign-in when plain is
+// empty. Only a generated password is returned.
+func (s *UserAdminService) CreateUser(ctx context.Context, email, plain string) (*model.User, string, error) {
+	temporary := plain == ""
+	if temporary {
+		var err error
+		if plain, err = s.authService.policy.Generate(email); err != nil {
+			return nil, "", err
+		}
+	}
+
+	user, err := s.authService.RegisterUser(ctx, email, plain)
+	if err != nil {
+		return nil, "", err
+	}
+	if !temporary {
+		return user, "", nil
+	}
+	if err := s.userRepo.ExpirePassword(ctx, user.ID); err != nil {
+		return nil, "", err
+	}
+	return user, plain, nil
+}
+
+// ResetPassword replaces a user's password with a random temporary one,
+// clears its lockout, revokes its sessions and notifies the user by email.
+// The temporary password is returned once and cannot be recovered afterwards.
+func (s *UserAdminService) ResetPassword(ctx context.Context, tenantID *int64, userID int64) (string, error) {
+	user, err := s.humanUser(ctx, tenantID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := s.authService.policy.Generate(user.Email)
+	if err != nil {
+		return "", err
+	}
+	hash, err := hashPassword(ctx, s.authService.hasher, password)
+	if err != nil {
+		return "", err
+	}
+	err = s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repo.UpdatePassword(ctx, userID, hash); err != nil {
+			return err
+		}
+		revoked, err := repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.authService.emitRevoked(ctx, repo, userID, RevokedByPasswordReset, revoked)
+	})
+	s.authService.cache.ForgetUser(userID)
+	if err != nil {
+		return "", err
+	}
+	s.authService.sendEmail(ctx, user.Email, email.TemplatePasswordReset, email.PasswordResetData{})
+	return password, nil
+}
+
+// ExpirePassword forces a user to change their password: their sessions are
+// revoked, and their next sign-in returns a token that only allows changing it.
+func (s *UserAdminService) ExpirePassword(ctx context.Context, tenantID *int64, userID int64) error {
+	if _, err := s.humanUser(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	err := s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repo.ExpirePassword(ctx, userID); err != nil {
+			return err
+		}
+		revoked, err := repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.authService.emitRevoked(ctx, repo, userID, RevokedByPasswordExpiry, revoked)
+	})
+	s.authService.cache.ForgetUser(userID)
+	return err
+}
+
+// Unlock clears the failed login attempts that locked a user
+func (s *UserAdminService) Unlock(ctx context.Context, tenantID *int64, userID int64) error {
+	if _, err := s.humanUser(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	return s.userRepo.UnlockUser(ctx, userID)
+}
+
+// RevokeSessions revokes all of a user's active sessions
+func (s *UserAdminService) RevokeSessions(ctx context.Context, tenantID *int64, userID int64) (int64, error) {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return 0, err
+	}
+	var revoked int64
+	err := s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		var err error
+		revoked, err = repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.authService.emitRevoked(ctx, repo, userID, RevokedByAdmin, revoked)
+	})
+	s.authService.cache.ForgetUser(userID)
+	return revoked, err
+}
+
+// DeleteUser soft-deletes a user and revokes its sessions. The user can be
+// restored until the retention job purges it.
+func (s *UserAdminService) DeleteUser(ctx context.Context, tenantID *int64, userID int64) error {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	err := s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		if err := repo.SoftDeleteUser(ctx, userID); err != nil {
+			return err
+		}
+		revoked, err := repo.RevokeAllSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.authService.emitRevoked(ctx, repo, userID, RevokedByDeletion, revoked)
+	})
+	s.authService.cache.ForgetUser(userID)
+	return err
+}
+
+// RestoreUser undoes the soft deletion of a user
+func (s *UserAdminService) RestoreUser(ctx context.Context, tenantID *int64, userID int64) error {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	return s.userRepo.RestoreUser(ctx, userID)
+}
+
+// ListSessions returns a page of a user's active sessions, with the cursor of the next page
+func (s *UserAdminService) ListSessions(ctx context.Context, tenantID *int64, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return nil, "", err
+	}
+	return s.userRepo.ListSessions(ctx, userID, page)
+}
+
+// Metadata returns a user's app and user metadata
+func (s *UserAdminService) Metadata(ctx context.Context, tenantID *int64, userID int64) (*model.UserMetadata, error) {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetUserMetadata(ctx, userID)
+}
+
+// UpdateMetadata merges the patches into a user's app and user metadata, see
+// model.MergeMetadata, and returns the updated metadata
+func (s *UserAdminService) UpdateMetadata(ctx context.Context, tenantID *int64, userID int64, app, user map[string]any) (*model.UserMetadata, error) {
+	if _, err := s.lookup(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	return updateMetadata(ctx, s.userRepo, userID, app, user)
+}
+
+// lookup returns the user if it is within the administrator's scope
+func (s *UserAdminService) lookup(ctx context.Context, tenantID *int64, userID int64) (*model.User, error) {
+	user, err := s.userRepo.GetUserForAdmin(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantID != nil && (user.TenantID == nil || *user.TenantID != *tenantID) {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// humanUser is lookup for operations that do not apply to service accounts
+func (s *UserAdminService) humanUser(ctx context.Context, tenantID *int64, userID int64) (*model.User, error) {
+	user, err := s.lookup(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsServiceAccount() {
+		return nil, ErrHumanUsersOnly
+	}
+	return user, nil
+}
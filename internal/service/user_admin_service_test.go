@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/email"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/test"
+)
+
+// sentEmails records the emails sent through it
+type sentEmails []email.Message
+
+func (s *sentEmails) Send(ctx context.Context, msg email.Message) error {
+	*s = append(*s, msg)
+	return nil
+}
+
+func TestUserAdminService(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	var sent sentEmails
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret", WithEmailSender(&sent))
+	users := NewUserAdminService(mockRepo, authService)
+
+	user, err := authService.RegisterUser(ctx, "user@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	tenantID := int64(7)
+	otherTenant := int64(8)
+
+	t.Run("scoped administrators only see their tenant", func(t *testing.T) {
+		if _, _, err := users.GetUser(ctx, &otherTenant, user.ID); err != repository.ErrUserNotFound {
+			t.Errorf("got error %v, want %v", err, repository.ErrUserNotFound)
+		}
+		if err := users.SetDisabled(ctx, &otherTenant, user.ID, true); err != repository.ErrUserNotFound {
+			t.Errorf("got error %v, want %v", err, repository.ErrUserNotFound)
+		}
+		listed, _, err := users.SearchUsers(ctx, &tenantID, model.UserFilter{Page: pagination.Page{Limit: 10}})
+		if err != nil || len(listed) != 0 {
+			t.Errorf("expected no users in tenant, got %v (%v)", listed, err)
+		}
+		listed, _, err = users.SearchUsers(ctx, nil, model.UserFilter{EmailPrefix: "user@", Page: pagination.Page{Limit: 10}})
+		if err != nil || len(listed) != 1 {
+			t.Errorf("expected one user, got %v (%v)", listed, err)
+		}
+	})
+
+	t.Run("lockout state", func(t *testing.T) {
+		_, lockout, err := users.GetUser(ctx, nil, user.ID)
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		want := model.LockoutState{MaxFailedAttempts: model.DefaultLockoutPolicy.MaxFailedAttempts}
+		if *lockout != want {
+			t.Errorf("got lockout %+v, want %+v", *lockout, want)
+		}
+	})
+
+	t.Run("disabled users cannot sign in", func(t *testing.T) {
+		if err := users.SetDisabled(ctx, nil, user.ID, true); err != nil {
+			t.Fatalf("failed to disable: %v", err)
+		}
+		if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != ErrAccountDisabled {
+			t.Errorf("got error %v, want %v", err, ErrAccountDisabled)
+		}
+		if err := users.SetDisabled(ctx, nil, user.ID, false); err != nil {
+			t.Fatalf("failed to enable: %v", err)
+		}
+		if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != nil {
+			t.Errorf("unexpected error after enabling: %v", err)
+		}
+	})
+
+	t.Run("password reset replaces the password", func(t *testing.T) {
+		password, err := users.ResetPassword(ctx, nil, user.ID)
+		if err != nil || password == "" {
+			t.Fatalf("failed to reset password: %v", err)
+		}
+		if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != ErrInvalidCredentials {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+		}
+		if _, err := authService.LoginUser(ctx, user.Email, password); err != nil {
+			t.Errorf("unexpected error with temporary password: %v", err)
+		}
+		if len(sent) != 1 || sent[0].To != user.Email || strings.Contains(sent[0].Text, password) {
+			t.Errorf("sent %+v, want one notification without the temporary password", sent)
+		}
+	})
+
+	t.Run("expiring a password revokes sessions", func(t *testing.T) {
+		token, err := authService.LoginUser(ctx, "expiring@example.com", "password123")
+		if err == ErrInvalidCredentials {
+			if _, err = authService.RegisterUser(ctx, "expiring@example.com", "password123"); err == nil {
+				token, err = authService.LoginUser(ctx, "expiring@example.com", "password123")
+			}
+		}
+		if err != nil {
+			t.Fatalf("failed to sign in: %v", err)
+		}
+		claims, err := authService.ValidateToken(ctx, token)
+		if err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+		if err := users.ExpirePassword(ctx, nil, int64(claims["sub"].(float64))); err != nil {
+			t.Fatalf("failed to expire password: %v", err)
+		}
+		if _, err := authService.ValidateToken(ctx, token); err != ErrInvalidToken {
+			t.Errorf("got error %v for a token issued before the expiry, want %v", err, ErrInvalidToken)
+		}
+	})
+
+	t.Run("created users without a password must change the generated one", func(t *testing.T) {
+		created, temporary, err := users.CreateUser(ctx, "new@example.com", "")
+		if err != nil || temporary == "" {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		result, err := authService.Login(ctx, created.Email, temporary, "", false)
+		if err != nil || !result.PasswordExpired {
+			t.Errorf("got %+v (%v), want a token for an expired password", result, err)
+		}
+
+		if _, temporary, err := users.CreateUser(ctx, "chosen@example.com", "password123"); err != nil || temporary != "" {
+			t.Errorf("got temporary password %q (%v), want none", temporary, err)
+		}
+		if _, _, err := users.CreateUser(ctx, "chosen@example.com", "password123"); err != repository.ErrDuplicateEmail {
+			t.Errorf("got error %v, want %v", err, repository.ErrDuplicateEmail)
+		}
+	})
+
+	t.Run("service accounts have no password", func(t *testing.T) {
+		account, err := mockRepo.CreateServiceAccount(ctx, "bot@example.com")
+		if err != nil {
+			t.Fatalf("failed to create service account: %v", err)
+		}
+		if _, err := users.ResetPassword(ctx, nil, account.ID); err != ErrHumanUsersOnly {
+			t.Errorf("got error %v, want %v", err, ErrHumanUsersOnly)
+		}
+		if err := users.Unlock(ctx, nil, account.ID); err != ErrHumanUsersOnly {
+			t.Errorf("got error %v, want %v", err, ErrHumanUsersOnly)
+		}
+	})
+}
+
+func TestUserAdminServiceSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+	users := NewUserAdminService(mockRepo, authService)
+
+	user, err := authService.RegisterUser(ctx, "user@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	if err := users.DeleteUser(ctx, nil, user.ID); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != ErrInvalidCredentials {
+		t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := authService.RegisterUser(ctx, user.Email, "password123"); err != repository.ErrDuplicateEmail {
+		t.Errorf("got error %v, want %v", err, repository.ErrDuplicateEmail)
+	}
+
+	page := pagination.Page{Limit: 10}
+	if live, _, _ := users.SearchUsers(ctx, nil, model.UserFilter{Page: page}); len(live) != 0 {
+		t.Errorf("expected deleted users to be hidden, got %v", live)
+	}
+	if deleted, _, _ := users.SearchUsers(ctx, nil, model.UserFilter{Deleted: true, Page: page}); len(deleted) != 1 {
+		t.Errorf("expected one deleted user, got %v", deleted)
+	}
+
+	if err := users.RestoreUser(ctx, nil, user.ID); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if _, err := authService.LoginUser(ctx, user.Email, "password123"); err != nil {
+		t.Errorf("unexpected error after restore: %v", err)
+	}
+
+	if err := users.DeleteUser(ctx, nil, user.ID); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if purged, err := mockRepo.PurgeDeletedUsers(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Errorf("got %d purged (%v), want 1", purged, err)
+	}
+	if err := users.RestoreUser(ctx, nil, user.ID); err != repository.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, repository.ErrUserNotFound)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+	users := NewUserAdminService(mockRepo, authService)
+	profiles := NewProfileService(mockRepo)
+
+	user, err := authService.RegisterUser(ctx, "user@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	otherTenant := int64(8)
+
+	if _, err := users.UpdateMetadata(ctx, nil, user.ID, map[string]any{"plan": "pro"}, map[string]any{"theme": "dark"}); err != nil {
+		t.Fatalf("failed to update metadata: %v", err)
+	}
+	metadata, err := profiles.UpdateUserMetadata(ctx, user.ID, map[string]any{"theme": nil, "locale": "de"})
+	if err != nil {
+		t.Fatalf("failed to update user metadata: %v", err)
+	}
+	if metadata.AppMetadata["plan"] != "pro" {
+		t.Errorf("expected app_metadata to be kept, got %v", metadata.AppMetadata)
+	}
+	if _, ok := metadata.UserMetadata["theme"]; ok || metadata.UserMetadata["locale"] != "de" {
+		t.Errorf("got user_metadata %v, want only locale", metadata.UserMetadata)
+	}
+
+	if _, metadata, err = profiles.Profile(ctx, user.ID); err != nil || metadata.UserMetadata["locale"] != "de" {
+		t.Errorf("got profile metadata %v (%v)", metadata, err)
+	}
+	if _, err := users.Metadata(ctx, &otherTenant, user.ID); err != repository.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, repository.ErrUserNotFound)
+	}
+	big := map[string]any{"bio": strings.Repeat("x", MaxMetadataBytes)}
+	if _, err := profiles.UpdateUserMetadata(ctx, user.ID, big); err != ErrMetadataTooLarge {
+		t.Errorf("got error %v, want %v", err, ErrMetadataTooLarge)
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/validate"
+)
+
+// MaxImportRows caps the users of one import; larger files are imported in parts
+const MaxImportRows = 10000
+
+// importBatchSize is the number of users inserted per transaction
+const importBatchSize = 500
+
+var (
+	ErrImportTooLarge = fmt.Errorf("an import holds at most %d users", MaxImportRows)
+	ErrInvalidImport  = errors.New("invalid import file")
+)
+
+// Statuses of an imported row
+const (
+	ImportStatusCreated = "created"
+	ImportStatusValid   = "valid" // would be created, in a dry run
+	ImportStatusFailed  = "failed"
+)
+
+// ImportUser is a user to import, one row of an import file. It has either a
+// password, which must meet the password policy, or the bcrypt hash of one
+// from a legacy system, which is stored as is and replaced by a hash of the
+// configured algorithm at the user's first sign-in.
+type ImportUser struct {
+	Email         string `json:"email" validate:"required,email"`
+	Password      string `json:"password,omitempty"`
+	PasswordHash  string `json:"password_hash,omitempty"`
+	EmailVerified bool   `json:"email_verified,omitempty"`
+	DisplayName   string `json:"display_name,omitempty" validate:"omitempty,max=100"`
+	GivenName     string `json:"given_name,omitempty" validate:"omitempty,max=100"`
+	FamilyName    string `json:"family_name,omitempty" validate:"omitempty,max=100"`
+	Locale        string `json:"locale,omitempty" validate:"omitempty,max=35,locale"`
+	Timezone      string `json:"timezone,omitempty" validate:"omitempty,max=64,timezone"`
+	AvatarURL     string `json:"avatar_url,omitempty" validate:"omitempty,max=2048,url"`
+}
+
+func (u *ImportUser) profile() model.Profile {
+	return model.Profile{
+		DisplayName: u.DisplayName,
+		GivenName:   u.GivenName,
+		FamilyName:  u.FamilyName,
+		Locale:      u.Locale,
+		Timezone:    u.Timezone,
+		AvatarURL:   u.AvatarURL,
+	}
+}
+
+// importColumns sets the field of an ImportUser for each CSV column
+var importColumns = map[string]func(u *ImportUser, v string) error{
+	"email":         func(u *ImportUser, v string) error { u.Email = v; return nil },
+	"password":      func(u *ImportUser, v string) error { u.Password = v; return nil },
+	"password_hash": func(u *ImportUser, v string) error { u.PasswordHash = v; return nil },
+	"email_verified": func(u *ImportUser, v string) (err error) {
+		if v != "" {
+			u.EmailVerified, err = strconv.ParseBool(v)
+		}
+		return err
+	},
+	"display_name": func(u *ImportUser, v string) error { u.DisplayName = v; return nil },
+	"given_name":   func(u *ImportUser, v string) error { u.GivenName = v; return nil },
+	"family_name":  func(u *ImportUser, v string) error { u.FamilyName = v; return nil },
+	"locale":       func(u *ImportUser, v string) error { u.Locale = v; return nil },
+	"timezone":     func(u *ImportUser, v string) error { u.Timezone = v; return nil },
+	"avatar_url":   func(u *ImportUser, v string) error { u.AvatarURL = v; return nil },
+}
+
+// ReadImportCSV reads users to import from CSV with a header row naming the
+// columns after the JSON fields of ImportUser; only email is required
+func ReadImportCSV(r io.Reader) ([]ImportUser, error) {
+	records := csv.NewReader(r)
+	records.TrimLeadingSpace = true
+	header, err := records.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+
+	setters := make([]func(*ImportUser, string) error, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if setters[i] = importColumns[name]; setters[i] == nil {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+	}
+
+	var users []ImportUser
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+		if len(users) == MaxImportRows {
+			return nil, ErrImportTooLarge
+		}
+		var user ImportUser
+		for i, value := range record {
+			if err := setters[i](&user, strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("%w: row %d: invalid %s", ErrInvalidImport, len(users)+1, header[i])
+			}
+		}
+		users = append(users, user)
+	}
+}
+
+// ImportRowResult is the outcome of one row of an import, numbered from 1
+// without the CSV header
+type ImportRowResult struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email"`
+	Status string `json:"status"` // an ImportStatus* value
+	UserID int64  `json:"user_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportResult is the outcome of an import
+type ImportResult struct {
+	DryRun  bool              `json:"dry_run"`
+	Created int               `json:"created"`
+	Valid   int               `json:"valid"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// ImportUsers creates users from an import, such as the export of a legacy
+// system. Every row is validated first and reported on; rows that fail are
+// skipped and the others are inserted in batches. With dryRun nothing is
+// created and valid rows are reported as such. Emails are normalized in place.
+//
+// Each batch is committed on its own. When an error stops the import after
+// validation, it is returned with the result so far: rows of committed
+// batches are created, and the valid rows that were not are failed.
+func (s *UserAdminService) ImportUsers(ctx context.Context, users []ImportUser, dryRun bool) (*ImportResult, error) {
+	if len(users) > MaxImportRows {
+		return nil, ErrImportTooLarge
+	}
+
+	result := &ImportResult{DryRun: dryRun, Rows: make([]ImportRowResult, len(users))}
+	seen := make(map[string]bool, len(users))
+	var valid []int
+	for i := range users {
+		row := &result.Rows[i]
+		row.Row = i + 1
+		users[i].Email = s.authService.NormalizeEmail(users[i].Email)
+		row.Email = users[i].Email
+		message, err := s.checkImportRow(ctx, &users[i], seen)
+		if err != nil {
+			return nil, err
+		}
+		if message != "" {
+			row.Status, row.Error = ImportStatusFailed, message
+			result.Failed++
+			continue
+		}
+		seen[row.Email] = true
+		row.Status = ImportStatusValid
+		valid = append(valid, i)
+	}
+	if dryRun {
+		result.Valid = len(valid)
+		return result, nil
+	}
+
+	for start := 0; start < len(valid); start += importBatchSize {
+		batch := valid[start:min(start+importBatchSize, len(valid))]
+		if err := s.importBatch(ctx, users, batch, result); err != nil {
+			for _, i := range valid[start:] {
+				if result.Rows[i].Status == ImportStatusValid {
+					result.Rows[i].Status, result.Rows[i].Error = ImportStatusFailed, "not imported, the import stopped at an error"
+					result.Failed++
+				}
+			}
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// importBatch creates the users of a batch of valid rows in one transaction
+// and reports them in result
+func (s *UserAdminService) importBatch(ctx context.Context, users []ImportUser, batch []int, result *ImportResult) error {
+	hashes, err := s.importHashes(ctx, users, batch)
+	if err != nil {
+		return err
+	}
+
+	created := make([]int64, len(batch))
+	err = s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+		for j, i := range batch {
+			userID, err := s.createImported(ctx, repo, &users[i], hashes[j])
+			if err != nil {
+				return err
+			}
+			created[j] = userID
+		}
+		return nil
+	})
+	if err == repository.ErrDuplicateEmail {
+		// An address was registered since the check: insert the batch row
+		// by row so only the taken ones fail
+		for j, i := range batch {
+			var userID int64
+			err := s.userRepo.WithTx(ctx, func(repo interfaces.UserRepository) error {
+				var err error
+				userID, err = s.createImported(ctx, repo, &users[i], hashes[j])
+				return err
+			})
+			switch err {
+			case nil:
+				result.Rows[i].Status, result.Rows[i].UserID = ImportStatusCreated, userID
+				result.Created++
+			case repository.ErrDuplicateEmail:
+				result.Rows[i].Status, result.Rows[i].Error = ImportStatusFailed, "email is already registered"
+				result.Failed++
+			default:
+				return err
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for j, i := range batch {
+		result.Rows[i].Status, result.Rows[i].UserID = ImportStatusCreated, created[j]
+		result.Created++
+	}
+	return nil
+}
+
+// importHashes returns the password hash of each row of a batch. Passwords
+// are hashed in parallel on at most half of the hasher's workers, so sign-ins
+// keep the others.
+func (s *UserAdminService) importHashes(ctx context.Context, users []ImportUser, batch []int) ([]string, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	hashes := make([]string, len(batch))
+	slots := make(chan struct{}, max(1, s.authService.hasher.Workers()/2))
+	var wg sync.WaitGroup
+	for j, i := range batch {
+		if hashes[j] = users[i].PasswordHash; hashes[j] != "" {
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			hash, err := hashPassword(ctx, s.authService.hasher, users[i].Password)
+			if err != nil {
+				cancel(err)
+				return
+			}
+			hashes[j] = hash
+		}()
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// checkImportRow returns why a row cannot be imported, or nothing when it can
+func (s *UserAdminService) checkImportRow(ctx context.Context, user *ImportUser, seen map[string]bool) (string, error) {
+	email := user.Email
+	if err := validate.Struct(user); err != nil {
+		return err.Error(), nil
+	}
+	switch {
+	case user.Password != "" && user.PasswordHash != "":
+		return "set password or password_hash, not both", nil
+	case user.PasswordHash != "":
+		if !password.IsBcryptHash(user.PasswordHash) {
+			return "password_hash is not a bcrypt hash", nil
+		}
+	case user.Password != "":
+		if err := s.authService.policy.Check(user.Password, email); err != nil {
+			return err.Error(), nil
+		}
+	default:
+		return "password or password_hash is required", nil
+	}
+	if seen[email] {
+		return "email is repeated in the import", nil
+	}
+
+	switch err := s.authService.domains.Check(ctx, email); err {
+	case nil:
+	case ErrEmailDomainBlocked:
+		return "registrations from this email domain are not allowed", nil
+	default:
+		return "", err
+	}
+	switch _, err := s.authService.userByEmail(ctx, email); err {
+	case nil:
+		return "email is already registered", nil
+	case repository.ErrUserNotFound:
+		return "", nil
+	default:
+		return "", err
+	}
+}
+
+// createImported stores an imported user with its password hash and returns its ID
+func (s *UserAdminService) createImported(ctx context.Context, repo interfaces.UserRepository, user *ImportUser, hash string) (int64, error) {
+	created, err := repo.CreateUser(ctx, user.Email, hash)
+	if err != nil {
+		return 0, err
+	}
+	if user.EmailVerified {
+		if err := repo.MarkEmailVerified(ctx, created.ID); err != nil {
+			return 0, err
+		}
+	}
+	if profile := user.profile(); profile != (model.Profile{}) {
+		if err := repo.UpdateUserProfile(ctx, created.ID, profile); err != nil {
+			return 0, err
+		}
+	}
+	err = s.authService.emit(ctx, repo, events.UserRegistered, map[string]any{"user_id": created.ID, "email": created.Email, "imported": true})
+	return created.ID, err
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/test"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestImportUsers(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := test.NewMockUserRepository()
+	authService := NewAuthService(mockRepo, mockRepo, "test-secret")
+	users := NewUserAdminService(mockRepo, authService)
+	if _, err := authService.RegisterUser(ctx, "existing@example.com", "password123"); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("legacy-secret"), bcrypt.MinCost)
+
+	rows := []ImportUser{
+		{Email: "Plain@Example.com", Password: "password123", EmailVerified: true, GivenName: "Ada"},
+		{Email: "legacy@example.com", PasswordHash: string(legacy)},
+		{Email: "not-an-email", Password: "password123"},
+		{Email: "both@example.com", Password: "password123", PasswordHash: string(legacy)},
+		{Email: "none@example.com"},
+		{Email: "weak@example.com", Password: "short"},
+		{Email: "md5@example.com", PasswordHash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{Email: "plain@example.com", Password: "password123"},
+		{Email: "EXISTING@example.com", Password: "password123"},
+		{Email: "zone@example.com", Password: "password123", Timezone: "Mars/Olympus"},
+	}
+	wantErrors := []string{
+		"", "", "email must be a valid email address", "set password or password_hash, not both",
+		"password or password_hash is required", "password must be at least", "password_hash is not a bcrypt hash",
+		"email is repeated in the import", "email is already registered", "timezone must be",
+	}
+
+	dryRun, err := users.ImportUsers(ctx, append([]ImportUser(nil), rows...), true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if dryRun.Valid != 2 || dryRun.Failed != 8 || dryRun.Created != 0 {
+		t.Errorf("dry run got %d valid, %d failed, %d created, want 2, 8, 0", dryRun.Valid, dryRun.Failed, dryRun.Created)
+	}
+	if _, err := mockRepo.GetUserByEmail(ctx, "legacy@example.com"); err == nil {
+		t.Error("dry run created a user")
+	}
+
+	result, err := users.ImportUsers(ctx, rows, false)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Created != 2 || result.Failed != 8 {
+		t.Errorf("got %d created, %d failed, want 2, 8", result.Created, result.Failed)
+	}
+	for i, row := range result.Rows {
+		if row.Row != i+1 || !strings.HasPrefix(row.Error, wantErrors[i]) || (wantErrors[i] == "") != (row.Status == ImportStatusCreated) {
+			t.Errorf("row %d = %+v, want error %q", i+1, row, wantErrors[i])
+		}
+	}
+
+	plain, err := mockRepo.GetUserByEmail(ctx, "plain@example.com")
+	if err != nil || !plain.EmailVerified || plain.Profile.GivenName != "Ada" || plain.ID != result.Rows[0].UserID {
+		t.Errorf("got imported user %+v (%v), want a verified user with a profile", plain, err)
+	}
+	if _, err := authService.LoginUser(ctx, "legacy@example.com", "legacy-secret"); err != nil {
+		t.Errorf("failed to sign in with an imported bcrypt hash: %v", err)
+	}
+	if _, err := users.ImportUsers(ctx, make([]ImportUser, MaxImportRows+1), true); err != ErrImportTooLarge {
+		t.Errorf("got error %v for an oversized import, want %v", err, ErrImportTooLarge)
+	}
+}
+
+// failingCreateRepository fails to create users once it created limit of them
+type failingCreateRepository struct {
+	*test.MockUserRepository
+	limit int
+}
+
+func (r *failingCreateRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	return fn(r)
+}
+
+func (r *failingCreateRepository) CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error) {
+	if r.limit == 0 {
+		return nil, errors.New("database unavailable")
+	}
+	r.limit--
+	return r.MockUserRepository.CreateUser(ctx, email, passwordHash)
+}
+
+func TestImportUsersStoppedPartway(t *testing.T) {
+	ctx := context.Background()
+	repo := &failingCreateRepository{MockUserRepository: test.NewMockUserRepository(), limit: importBatchSize}
+	authService := NewAuthService(repo, repo, "test-secret")
+	users := NewUserAdminService(repo, authService)
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("legacy-secret"), bcrypt.MinCost)
+
+	rows := make([]ImportUser, importBatchSize+2)
+	for i := range rows {
+		rows[i] = ImportUser{Email: fmt.Sprintf("user%d@example.com", i), PasswordHash: string(legacy)}
+	}
+	rows[len(rows)-1].Password, rows[len(rows)-1].PasswordHash = "password123", ""
+
+	result, err := users.ImportUsers(ctx, rows, false)
+	if err == nil || result == nil {
+		t.Fatalf("ImportUsers() = %v, %v; want the result so far and the error", result, err)
+	}
+	if result.Created != importBatchSize || result.Failed != 2 {
+		t.Errorf("got %d created, %d failed, want %d, 2", result.Created, result.Failed, importBatchSize)
+	}
+	for _, row := range result.Rows[importBatchSize:] {
+		if row.Status != ImportStatusFailed || row.UserID != 0 {
+			t.Errorf("row %d = %+v, want a failed row of the batch that was not committed", row.Row, row)
+		}
+	}
+}
+
+func TestReadImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []ImportUser
+		wantErr bool
+	}{
+		{name: "columns in any order", csv: "\ufeffPassword_Hash, email,email_verified\n$2a$hash, a@example.com,true\n,b@example.com,\n",
+			want: []ImportUser{{Email: "a@example.com", PasswordHash: "$2a$hash", EmailVerified: true}, {Email: "b@example.com"}}},
+		{name: "header only", csv: "email,password\n"},
+		{name: "unknown column", csv: "email,role\na@example.com,admin\n", wantErr: true},
+		{name: "invalid boolean", csv: "email,email_verified\na@example.com,maybe\n", wantErr: true},
+		{name: "missing field", csv: "email,password\na@example.com\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadImportCSV(strings.NewReader(tt.csv))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidImport) {
+					t.Errorf("got error %v, want %v", err, ErrInvalidImport)
+				}
+				return
+			}
+			if err != nil || len(got) != len(tt.want) {
+				t.Fatalf("ReadImportCSV() = %+v, %v, want %+v", got, err, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("row %d = %+v, want %+v", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
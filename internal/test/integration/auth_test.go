@@ -3,6 +3,7 @@ package integration
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,6 +14,10 @@ import (
 	"github.com/Stewz00/go-auth-service/internal/config"
 	"github.com/Stewz00/go-auth-service/internal/database"
 	"github.com/Stewz00/go-auth-service/internal/handler"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/problem"
 	"github.com/Stewz00/go-auth-service/internal/repository"
 	"github.com/Stewz00/go-auth-service/internal/service"
 	"github.com/go-chi/chi/v5"
@@ -20,8 +25,9 @@ import (
 )
 
 var (
-	testDB     *database.DB
-	testRouter *chi.Mux
+	testDB      *testDatabase
+	testRouter  *chi.Mux
+	testLockout model.LockoutPolicy
 )
 
 func TestMain(m *testing.M) {
@@ -49,32 +55,81 @@ func TestMain(m *testing.M) {
 	}
 
 	// Initialize test database
-	testDB, err = database.New(cfg.DbURL)
+	testDB, err = openTestDatabase(cfg.DbURL)
 	if err != nil {
 		fmt.Printf("Failed to connect to test database: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Set up router and handlers
-	testRouter = setupTestRouter(testDB, cfg.JwtSecret)
+	testLockout = cfg.Lockout
+	testRouter = setupTestRouter(testDB.users, cfg)
 
 	// Run tests
 	code := m.Run()
 
 	// Clean up
-	testDB.Close()
+	testDB.close()
 	os.Exit(code)
 }
 
-func setupTestRouter(db *database.DB, jwtSecret string) *chi.Mux {
-	userRepo := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepo, jwtSecret)
+// testDatabase is the database of DATABASE_URL, which may be PostgreSQL,
+// MySQL or SQLite
+type testDatabase struct {
+	users interfaces.UserRepository
+	reset func(ctx context.Context) error
+	close func()
+}
+
+// openTestDatabase connects to the database selected by the scheme of dbURL
+func openTestDatabase(dbURL string) (*testDatabase, error) {
+	driver, err := database.DriverFor(dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case database.DriverSQLite:
+		db, err := database.NewSQLite(dbURL)
+		if err != nil {
+			return nil, err
+		}
+		return &testDatabase{users: repository.NewSQLiteUserRepository(db), reset: deleteUsers(db.DB), close: db.Close}, nil
+	case database.DriverMySQL:
+		db, err := database.NewMySQL(dbURL)
+		if err != nil {
+			return nil, err
+		}
+		return &testDatabase{users: repository.NewMySQLUserRepository(db), reset: deleteUsers(db.DB), close: db.Close}, nil
+	}
+
+	db, err := database.New(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	reset := func(ctx context.Context) error {
+		_, err := db.Pool.Exec(ctx, "TRUNCATE users, sessions CASCADE")
+		return err
+	}
+	return &testDatabase{users: repository.NewUserRepository(db), reset: reset, close: db.Close}, nil
+}
+
+// deleteUsers empties the users table; sessions are deleted by cascade
+func deleteUsers(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, "DELETE FROM users")
+		return err
+	}
+}
+
+func setupTestRouter(userRepo interfaces.UserRepository, cfg *config.Config) *chi.Mux {
+	authService := service.NewAuthService(userRepo, userRepo, cfg.JwtSecret, service.WithLockoutPolicy(cfg.Lockout))
 	authHandler := handler.NewAuthHandler(authService)
 
 	r := chi.NewRouter()
 	r.Post("/auth/register", authHandler.Register)
 	r.Post("/auth/login", authHandler.Login)
-	r.Post("/auth/logout", authHandler.Logout)
+	r.With(middleware.Authenticate(authService)).Post("/auth/logout", authHandler.Logout)
 
 	return r
 }
@@ -184,18 +239,19 @@ func TestFailedLoginAttempts(t *testing.T) {
 		"password": "wrongpassword",
 	}
 
-	for i := 0; i < 5; i++ { // Changed from 6 to 5 attempts
+	maxAttempts := int(testLockout.MaxFailedAttempts)
+	for i := 0; i < maxAttempts; i++ {
 		body, _ := json.Marshal(wrongPassword)
 		req := httptest.NewRequest("POST", "/auth/login", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		testRouter.ServeHTTP(w, req)
 
-		if i < 4 { // First 4 attempts should fail with unauthorized
+		if i < maxAttempts-1 { // Attempts before the limit fail with unauthorized
 			if w.Code != http.StatusUnauthorized {
 				t.Errorf("attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, w.Code)
 			}
-		} else { // 5th attempt should trigger the lock
+		} else { // The last attempt triggers the lock
 			if w.Code != http.StatusForbidden {
 				t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
 			}
@@ -214,23 +270,20 @@ func TestFailedLoginAttempts(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
 	}
 
-	// Verify the error message
-	var response struct {
-		Error string `json:"error"`
-	}
+	// Verify the error code
+	var response problem.Problem
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Errorf("failed to decode response: %v", err)
 	}
-	if response.Error != "Account is locked due to too many failed attempts" {
-		t.Errorf("unexpected error message: %s", response.Error)
+	if response.Code != problem.AccountLocked {
+		t.Errorf("unexpected error code: %s", response.Code)
 	}
 }
 
 // Helper function to clean up test data
 func cleanup(t *testing.T) {
 	ctx := context.Background()
-	_, err := testDB.Pool.Exec(ctx, "TRUNCATE users, sessions CASCADE")
-	if err != nil {
+	if err := testDB.reset(ctx); err != nil {
 		t.Errorf("failed to clean up test data: %v", err)
 	}
 }
@@ -1,24 +1,42 @@
 package test
 
 import (
+	"cmp"
 	"context"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Stewz00/go-auth-service/internal/audit"
 	"github.com/Stewz00/go-auth-service/internal/interfaces"
 	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
 	"github.com/Stewz00/go-auth-service/internal/repository"
 )
 
 // MockDB implements a mock database for testing
 type MockDB struct {
-	users    map[string]*model.User
-	sessions map[string]bool
+	users        map[string]*model.User // by normalized email
+	sessions     map[string]bool
+	sessionUsers map[string]int64
+	sessionInfo  map[string]*model.Session
+	identities   map[string]int64
+	tenants      map[int64]*model.Tenant
+	metadata     map[int64]*model.UserMetadata
+	lastUserID   int64
+	lastSession  int64
 }
 
 func NewMockDB() *MockDB {
 	return &MockDB{
-		users:    make(map[string]*model.User),
-		sessions: make(map[string]bool),
+		users:        make(map[string]*model.User),
+		sessions:     make(map[string]bool),
+		sessionUsers: make(map[string]int64),
+		sessionInfo:  make(map[string]*model.Session),
+		identities:   make(map[string]int64),
+		tenants:      make(map[int64]*model.Tenant),
+		metadata:     make(map[int64]*model.UserMetadata),
 	}
 }
 
@@ -36,44 +54,319 @@ func NewMockUserRepository() *MockUserRepository {
 	}
 }
 
+// WithTx mocks a transaction by running fn with the repository
+func (r *MockUserRepository) WithTx(ctx context.Context, fn func(repo interfaces.UserRepository) error) error {
+	return fn(r)
+}
+
 // CreateUser mocks creating a new user
 func (r *MockUserRepository) CreateUser(ctx context.Context, email, passwordHash string) (*model.User, error) {
-	if _, exists := r.db.users[email]; exists {
+	if _, exists := r.db.users[model.NormalizeEmail(email)]; exists {
 		return nil, repository.ErrDuplicateEmail
 	}
 
+	r.db.lastUserID++
 	user := &model.User{
-		ID:       int64(len(r.db.users) + 1),
+		ID:       r.db.lastUserID,
 		Email:    email,
 		Password: passwordHash,
 		Created:  time.Now(),
+		Type:     model.UserTypeHuman,
+		Role:     model.RoleUser,
 	}
-	r.db.users[email] = user
+	user.PasswordChangedAt = user.Created
+	r.db.users[model.NormalizeEmail(email)] = user
 	return user, nil
 }
 
 // GetUserByEmail mocks retrieving a user by email
 func (r *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
-	user, exists := r.db.users[email]
+	user, exists := r.db.users[model.NormalizeEmail(email)]
 	if !exists {
 		return nil, repository.ErrUserNotFound
 	}
+	return r.db.checkUser(user)
+}
+
+// checkUser reports locked users and users of suspended tenants like the repository does
+func (db *MockDB) checkUser(user *model.User) (*model.User, error) {
+	if user.TenantID != nil {
+		if tenant, ok := db.tenants[*user.TenantID]; ok && tenant.Status != model.TenantStatusActive {
+			return nil, repository.ErrTenantSuspended
+		}
+	}
+	if user.DeletedAt != nil {
+		return nil, repository.ErrUserNotFound
+	}
+	if user.IsDisabled {
+		return nil, repository.ErrUserDisabled
+	}
+	if user.IsLocked {
+		return nil, repository.ErrTooManyAttempts
+	}
 	return user, nil
 }
 
+// GetUserByID mocks retrieving a user by ID
+func (r *MockUserRepository) GetUserByID(ctx context.Context, userID int64) (*model.User, error) {
+	user := r.findUser(userID)
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return r.db.checkUser(user)
+}
+
+// findUser returns the user with the ID regardless of lock state, or nil
+func (r *MockUserRepository) findUser(userID int64) *model.User {
+	for _, user := range r.db.users {
+		if user.ID == userID {
+			return user
+		}
+	}
+	return nil
+}
+
+// CreateServiceAccount mocks creating a service account
+func (r *MockUserRepository) CreateServiceAccount(ctx context.Context, email string) (*model.User, error) {
+	user, err := r.CreateUser(ctx, email, "!")
+	if err != nil {
+		return nil, err
+	}
+	user.Type = model.UserTypeService
+	return user, nil
+}
+
+// ListServiceAccounts mocks listing service accounts
+func (r *MockUserRepository) ListServiceAccounts(ctx context.Context) ([]*model.User, error) {
+	var users []*model.User
+	for _, user := range r.db.users {
+		if user.IsServiceAccount() {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b *model.User) int { return cmp.Compare(a.ID, b.ID) })
+	return users, nil
+}
+
+// SetServiceAccountLocked mocks locking a service account
+func (r *MockUserRepository) SetServiceAccountLocked(ctx context.Context, userID int64, locked bool) error {
+	user := r.findUser(userID)
+	if user == nil || !user.IsServiceAccount() {
+		return repository.ErrUserNotFound
+	}
+	user.IsLocked = locked
+	return nil
+}
+
+// DeleteServiceAccount mocks deleting a service account
+func (r *MockUserRepository) DeleteServiceAccount(ctx context.Context, userID int64) error {
+	user := r.findUser(userID)
+	if user == nil || !user.IsServiceAccount() {
+		return repository.ErrUserNotFound
+	}
+	delete(r.db.users, user.Email)
+	return nil
+}
+
+// SearchUsers mocks searching a page of users matching a filter
+func (r *MockUserRepository) SearchUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error) {
+	var users []*model.User
+	for _, user := range r.db.users {
+		switch {
+		case filter.TenantID != nil && (user.TenantID == nil || *user.TenantID != *filter.TenantID),
+			!strings.HasPrefix(user.Email, filter.EmailPrefix),
+			filter.Role != "" && user.Role != filter.Role,
+			filter.Type != "" && user.Type != filter.Type,
+			filter.Locked != nil && user.IsLocked != *filter.Locked,
+			filter.Disabled != nil && user.IsDisabled != *filter.Disabled,
+			filter.Verified != nil && user.EmailVerified != *filter.Verified,
+			(user.DeletedAt != nil) != filter.Deleted,
+			!filter.CreatedAfter.IsZero() && user.Created.Before(filter.CreatedAfter),
+			!filter.CreatedBefore.IsZero() && !user.Created.Before(filter.CreatedBefore):
+			continue
+		}
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b *model.User) int { return cmp.Compare(a.ID, b.ID) })
+	return pagination.Slice(users, filter.Page, false, func(u *model.User) int64 { return u.ID })
+}
+
+// GetUserForAdmin mocks retrieving a user whatever its state
+func (r *MockUserRepository) GetUserForAdmin(ctx context.Context, userID int64) (*model.User, error) {
+	user := r.findUser(userID)
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// GetUserMetadata mocks retrieving a user's metadata
+func (r *MockUserRepository) GetUserMetadata(ctx context.Context, userID int64) (*model.UserMetadata, error) {
+	if r.findUser(userID) == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	if metadata, ok := r.db.metadata[userID]; ok {
+		copied := *metadata
+		return &copied, nil
+	}
+	return &model.UserMetadata{AppMetadata: map[string]any{}, UserMetadata: map[string]any{}}, nil
+}
+
+// SetUserMetadata mocks replacing a user's metadata
+func (r *MockUserRepository) SetUserMetadata(ctx context.Context, userID int64, metadata *model.UserMetadata) error {
+	if r.findUser(userID) == nil {
+		return repository.ErrUserNotFound
+	}
+	copied := *metadata
+	r.db.metadata[userID] = &copied
+	return nil
+}
+
+// UpdateUserProfile mocks replacing a user's profile
+func (r *MockUserRepository) UpdateUserProfile(ctx context.Context, userID int64, profile model.Profile) error {
+	user := r.findUser(userID)
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+	user.Profile = profile
+	return nil
+}
+
+// SetUserDisabled mocks disabling a user
+func (r *MockUserRepository) SetUserDisabled(ctx context.Context, userID int64, disabled bool) error {
+	user := r.findUser(userID)
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+	user.IsDisabled = disabled
+	return nil
+}
+
+// RehashPassword mocks upgrading a user's password hash
+func (r *MockUserRepository) RehashPassword(ctx context.Context, userID int64, oldHash, newHash string) error {
+	user := r.findUser(userID)
+	if user == nil || user.Password != oldHash {
+		return repository.ErrUserNotFound
+	}
+	user.Password = newHash
+	return nil
+}
+
+// UpdatePassword mocks replacing a user's password hash
+func (r *MockUserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	user := r.findUser(userID)
+	if user == nil || user.IsServiceAccount() {
+		return repository.ErrUserNotFound
+	}
+	user.Password = passwordHash
+	user.FailedAttempts = 0
+	user.IsLocked = false
+	user.PasswordChangedAt = time.Now()
+	user.PasswordExpiresAt = nil
+	return nil
+}
+
+// ExpirePassword mocks forcing a password change
+func (r *MockUserRepository) ExpirePassword(ctx context.Context, userID int64) error {
+	user := r.findUser(userID)
+	if user == nil || user.IsServiceAccount() {
+		return repository.ErrUserNotFound
+	}
+	now := time.Now()
+	user.PasswordExpiresAt = &now
+	return nil
+}
+
+// SoftDeleteUser mocks soft-deleting a user
+func (r *MockUserRepository) SoftDeleteUser(ctx context.Context, userID int64) error {
+	user := r.findUser(userID)
+	if user == nil || user.DeletedAt != nil {
+		return repository.ErrUserNotFound
+	}
+	now := time.Now()
+	user.DeletedAt = &now
+	return nil
+}
+
+// RestoreUser mocks restoring a soft-deleted user
+func (r *MockUserRepository) RestoreUser(ctx context.Context, userID int64) error {
+	user := r.findUser(userID)
+	if user == nil || user.DeletedAt == nil {
+		return repository.ErrUserNotFound
+	}
+	user.DeletedAt = nil
+	return nil
+}
+
+// PurgeDeletedUsers mocks permanently deleting users soft-deleted before a time
+func (r *MockUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	for email, user := range r.db.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(before) {
+			delete(r.db.users, email)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// MarkEmailVerified mocks marking a user's email as verified
+func (r *MockUserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	if user := r.findUser(userID); user != nil {
+		user.EmailVerified = true
+	}
+	return nil
+}
+
+// UnlockUser mocks clearing a lockout
+func (r *MockUserRepository) UnlockUser(ctx context.Context, userID int64) error {
+	user := r.findUser(userID)
+	if user == nil || user.IsServiceAccount() {
+		return repository.ErrUserNotFound
+	}
+	user.FailedAttempts = 0
+	user.IsLocked = false
+	return nil
+}
+
+// SetCanary mocks marking a user as a canary account
+func (r *MockUserRepository) SetCanary(ctx context.Context, userID int64, canary bool) error {
+	user, err := r.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.IsCanary = canary
+	return nil
+}
+
 // UpdateLastLogin mocks updating the last login time
 func (r *MockUserRepository) UpdateLastLogin(ctx context.Context, userID int64) error {
+	if user := r.findUser(userID); user != nil {
+		now := time.Now()
+		user.LastLogin = &now
+	}
 	return nil
 }
 
 // IncrementFailedAttempts mocks incrementing failed login attempts
-func (r *MockUserRepository) IncrementFailedAttempts(ctx context.Context, userID int64) error {
+func (r *MockUserRepository) IncrementFailedAttempts(ctx context.Context, userID int64, policy model.LockoutPolicy) error {
 	return nil
 }
 
 // CreateSession mocks creating a new session
-func (r *MockUserRepository) CreateSession(ctx context.Context, userID int64, tokenID string, expiresAt time.Time) error {
+func (r *MockUserRepository) CreateSession(ctx context.Context, userID int64, tokenID, deviceFingerprint string, rememberMe bool, expiresAt time.Time) error {
 	r.db.sessions[tokenID] = true
+	r.db.sessionUsers[tokenID] = userID
+	r.db.lastSession++
+	r.db.sessionInfo[tokenID] = &model.Session{
+		ID:                r.db.lastSession,
+		UserID:            userID,
+		TokenID:           tokenID,
+		DeviceFingerprint: deviceFingerprint,
+		RememberMe:        rememberMe,
+		Created:           time.Now(),
+		ExpiresAt:         expiresAt,
+	}
 	return nil
 }
 
@@ -86,6 +379,30 @@ func (r *MockUserRepository) RevokeSession(ctx context.Context, tokenID string)
 	return nil
 }
 
+// RevokeAllSessions mocks revoking every active session for a user
+func (r *MockUserRepository) RevokeAllSessions(ctx context.Context, userID int64) (int64, error) {
+	var revoked int64
+	for tokenID, owner := range r.db.sessionUsers {
+		if owner == userID && r.db.sessions[tokenID] {
+			r.db.sessions[tokenID] = false
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// ListSessions mocks listing a page of a user's active sessions
+func (r *MockUserRepository) ListSessions(ctx context.Context, userID int64, page pagination.Page) ([]*model.Session, string, error) {
+	var sessions []*model.Session
+	for tokenID, session := range r.db.sessionInfo {
+		if session.UserID == userID && r.db.sessions[tokenID] && time.Now().Before(session.ExpiresAt) {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b *model.Session) int { return cmp.Compare(a.ID, b.ID) })
+	return pagination.Slice(sessions, page, false, func(s *model.Session) int64 { return s.ID })
+}
+
 // IsSessionValid mocks checking if a session is valid
 func (r *MockUserRepository) IsSessionValid(ctx context.Context, tokenID string) (bool, error) {
 	valid, exists := r.db.sessions[tokenID]
@@ -94,3 +411,483 @@ func (r *MockUserRepository) IsSessionValid(ctx context.Context, tokenID string)
 	}
 	return valid, nil
 }
+
+// MockIdentityRepository implements the interfaces.IdentityRepository interface
+type MockIdentityRepository struct {
+	db *MockDB
+}
+
+// Verify that MockIdentityRepository implements IdentityRepository interface
+var _ interfaces.IdentityRepository = (*MockIdentityRepository)(nil)
+
+// NewMockIdentityRepository creates an identity mock sharing the user mock's data
+func NewMockIdentityRepository(userRepo *MockUserRepository) *MockIdentityRepository {
+	return &MockIdentityRepository{
+		db: userRepo.db,
+	}
+}
+
+// GetUserByIdentity mocks retrieving the user linked to a provider identity
+func (r *MockIdentityRepository) GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*model.User, error) {
+	userID, exists := r.db.identities[provider+":"+providerUserID]
+	if !exists {
+		return nil, repository.ErrUserNotFound
+	}
+	for _, user := range r.db.users {
+		if user.ID == userID {
+			return r.db.checkUser(user)
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// LinkIdentity mocks linking a provider identity to a user
+func (r *MockIdentityRepository) LinkIdentity(ctx context.Context, userID int64, provider, providerUserID, email string) error {
+	key := provider + ":" + providerUserID
+	if _, exists := r.db.identities[key]; exists {
+		return repository.ErrIdentityAlreadyLinked
+	}
+	r.db.identities[key] = userID
+	return nil
+}
+
+// MockOAuthRepository implements the interfaces.OAuthRepository interface
+type MockOAuthRepository struct {
+	clients map[string]*model.OAuthClient
+	codes   map[string]*model.AuthorizationCode
+}
+
+// Verify that MockOAuthRepository implements OAuthRepository interface
+var _ interfaces.OAuthRepository = (*MockOAuthRepository)(nil)
+
+func NewMockOAuthRepository() *MockOAuthRepository {
+	return &MockOAuthRepository{
+		clients: make(map[string]*model.OAuthClient),
+		codes:   make(map[string]*model.AuthorizationCode),
+	}
+}
+
+// CreateClient mocks registering an OAuth client
+func (r *MockOAuthRepository) CreateClient(ctx context.Context, client *model.OAuthClient) error {
+	if _, exists := r.clients[client.ClientID]; exists {
+		return repository.ErrDuplicateClientID
+	}
+	client.ID = int64(len(r.clients) + 1)
+	client.Created = time.Now()
+	r.clients[client.ClientID] = client
+	return nil
+}
+
+// GetClient mocks retrieving an OAuth client
+func (r *MockOAuthRepository) GetClient(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	client, exists := r.clients[clientID]
+	if !exists {
+		return nil, repository.ErrClientNotFound
+	}
+	return client, nil
+}
+
+// SaveAuthorizationCode mocks storing an authorization code
+func (r *MockOAuthRepository) SaveAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error {
+	r.codes[code.CodeHash] = code
+	return nil
+}
+
+// ConsumeAuthorizationCode mocks single-use code redemption
+func (r *MockOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	code, exists := r.codes[codeHash]
+	if !exists || time.Now().After(code.ExpiresAt) {
+		return nil, repository.ErrCodeNotFound
+	}
+	delete(r.codes, codeHash)
+	return code, nil
+}
+
+// MockConsentRepository implements the interfaces.ConsentRepository interface
+type MockConsentRepository struct {
+	receipts []*model.ConsentReceipt
+}
+
+// Verify that MockConsentRepository implements ConsentRepository interface
+var _ interfaces.ConsentRepository = (*MockConsentRepository)(nil)
+
+func NewMockConsentRepository() *MockConsentRepository {
+	return &MockConsentRepository{}
+}
+
+// CreateConsentReceipt mocks storing a consent receipt
+func (r *MockConsentRepository) CreateConsentReceipt(ctx context.Context, receipt *model.ConsentReceipt) error {
+	receipt.ID = int64(len(r.receipts) + 1)
+	receipt.Created = time.Now()
+	r.receipts = append(r.receipts, receipt)
+	return nil
+}
+
+// ListConsentReceipts mocks listing a user's consent receipts
+func (r *MockConsentRepository) ListConsentReceipts(ctx context.Context, userID int64) ([]*model.ConsentReceipt, error) {
+	var receipts []*model.ConsentReceipt
+	for _, receipt := range r.receipts {
+		if receipt.UserID == userID {
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts, nil
+}
+
+// MockAPIKeyRepository implements the interfaces.APIKeyRepository interface
+type MockAPIKeyRepository struct {
+	keys []*model.APIKey
+}
+
+// Verify that MockAPIKeyRepository implements APIKeyRepository interface
+var _ interfaces.APIKeyRepository = (*MockAPIKeyRepository)(nil)
+
+func NewMockAPIKeyRepository() *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{}
+}
+
+// CreateAPIKey mocks storing an API key
+func (r *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.ID = int64(len(r.keys) + 1)
+	key.Created = time.Now()
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+// ListAPIKeys mocks listing a user's API keys
+func (r *MockAPIKeyRepository) ListAPIKeys(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// UseAPIKey mocks looking up an API key by hash
+func (r *MockAPIKeyRepository) UseAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			now := time.Now()
+			key.LastUsedAt = &now
+			return key, nil
+		}
+	}
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+// RevokeAPIKey mocks revoking an API key
+func (r *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	for i, key := range r.keys {
+		if key.ID == keyID && key.UserID == userID {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrAPIKeyNotFound
+}
+
+// MockBreakGlassRepository implements the interfaces.BreakGlassRepository interface
+type MockBreakGlassRepository struct {
+	redeemed map[string]bool
+	sessions map[string]time.Time
+}
+
+// Verify that MockBreakGlassRepository implements BreakGlassRepository interface
+var _ interfaces.BreakGlassRepository = (*MockBreakGlassRepository)(nil)
+
+func NewMockBreakGlassRepository() *MockBreakGlassRepository {
+	return &MockBreakGlassRepository{
+		redeemed: make(map[string]bool),
+		sessions: make(map[string]time.Time),
+	}
+}
+
+// RedeemBreakGlassCredential mocks marking a credential as used
+func (r *MockBreakGlassRepository) RedeemBreakGlassCredential(ctx context.Context, credentialHash, ipAddress, sessionHash string, sessionExpiresAt time.Time) error {
+	if r.redeemed[credentialHash] {
+		return repository.ErrCredentialAlreadyUsed
+	}
+	r.redeemed[credentialHash] = true
+	r.sessions[sessionHash] = sessionExpiresAt
+	return nil
+}
+
+// IsBreakGlassSessionValid mocks checking a break-glass session
+func (r *MockBreakGlassRepository) IsBreakGlassSessionValid(ctx context.Context, sessionHash string) (bool, error) {
+	expiresAt, exists := r.sessions[sessionHash]
+	return exists && time.Now().Before(expiresAt), nil
+}
+
+// MockTenantRepository implements the interfaces.TenantRepository interface
+type MockTenantRepository struct {
+	db     *MockDB
+	lastID int64
+}
+
+// Verify that MockTenantRepository implements TenantRepository interface
+var _ interfaces.TenantRepository = (*MockTenantRepository)(nil)
+
+// NewMockTenantRepository creates a tenant mock sharing the user mock's data
+func NewMockTenantRepository(userRepo *MockUserRepository) *MockTenantRepository {
+	return &MockTenantRepository{
+		db: userRepo.db,
+	}
+}
+
+// OnboardTenant mocks creating a tenant with its first admin
+func (r *MockTenantRepository) OnboardTenant(ctx context.Context, tenant *model.Tenant, adminEmail, adminPasswordHash string) (*model.User, error) {
+	for _, existing := range r.db.tenants {
+		if existing.Slug == tenant.Slug {
+			return nil, repository.ErrDuplicateTenantSlug
+		}
+	}
+	if _, exists := r.db.users[model.NormalizeEmail(adminEmail)]; exists {
+		return nil, repository.ErrDuplicateEmail
+	}
+
+	r.lastID++
+	tenant.ID = r.lastID
+	tenant.Status = model.TenantStatusActive
+	tenant.Created = time.Now()
+	stored := *tenant
+	r.db.tenants[tenant.ID] = &stored
+
+	r.db.lastUserID++
+	admin := &model.User{
+		ID:       r.db.lastUserID,
+		Email:    adminEmail,
+		Password: adminPasswordHash,
+		Created:  time.Now(),
+		Type:     model.UserTypeHuman,
+		Role:     model.RoleAdmin,
+		TenantID: &stored.ID,
+	}
+	r.db.users[model.NormalizeEmail(adminEmail)] = admin
+	return admin, nil
+}
+
+// GetTenant mocks retrieving a tenant
+func (r *MockTenantRepository) GetTenant(ctx context.Context, tenantID int64) (*model.Tenant, error) {
+	tenant, exists := r.db.tenants[tenantID]
+	if !exists {
+		return nil, repository.ErrTenantNotFound
+	}
+	copied := *tenant
+	return &copied, nil
+}
+
+// ListTenants mocks listing tenants
+func (r *MockTenantRepository) ListTenants(ctx context.Context) ([]*model.Tenant, error) {
+	var tenants []*model.Tenant
+	for _, tenant := range r.db.tenants {
+		copied := *tenant
+		tenants = append(tenants, &copied)
+	}
+	slices.SortFunc(tenants, func(a, b *model.Tenant) int { return cmp.Compare(a.ID, b.ID) })
+	return tenants, nil
+}
+
+// UpdateTenantSettings mocks replacing tenant settings
+func (r *MockTenantRepository) UpdateTenantSettings(ctx context.Context, tenantID int64, settings model.TenantSettings) error {
+	tenant, exists := r.db.tenants[tenantID]
+	if !exists {
+		return repository.ErrTenantNotFound
+	}
+	tenant.Settings = settings
+	return nil
+}
+
+// SetTenantStatus mocks activating or suspending a tenant
+func (r *MockTenantRepository) SetTenantStatus(ctx context.Context, tenantID int64, status string) error {
+	tenant, exists := r.db.tenants[tenantID]
+	if !exists {
+		return repository.ErrTenantNotFound
+	}
+	tenant.Status = status
+	return nil
+}
+
+// RevokeTenantSessions mocks revoking the sessions of a tenant's users
+func (r *MockTenantRepository) RevokeTenantSessions(ctx context.Context, tenantID int64) (int64, error) {
+	var revoked int64
+	for tokenID, owner := range r.db.sessionUsers {
+		if r.db.sessions[tokenID] && r.inTenant(owner, tenantID) {
+			r.db.sessions[tokenID] = false
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// DeleteTenant mocks deleting a tenant and cascading to its users and sessions
+func (r *MockTenantRepository) DeleteTenant(ctx context.Context, tenantID int64) (int64, error) {
+	if _, exists := r.db.tenants[tenantID]; !exists {
+		return 0, repository.ErrTenantNotFound
+	}
+
+	var deleted int64
+	for email, user := range r.db.users {
+		if user.TenantID != nil && *user.TenantID == tenantID {
+			for tokenID, owner := range r.db.sessionUsers {
+				if owner == user.ID {
+					delete(r.db.sessions, tokenID)
+					delete(r.db.sessionUsers, tokenID)
+				}
+			}
+			delete(r.db.users, email)
+			deleted++
+		}
+	}
+	delete(r.db.tenants, tenantID)
+	return deleted, nil
+}
+
+// inTenant reports whether the user belongs to the tenant
+func (r *MockTenantRepository) inTenant(userID, tenantID int64) bool {
+	for _, user := range r.db.users {
+		if user.ID == userID {
+			return user.TenantID != nil && *user.TenantID == tenantID
+		}
+	}
+	return false
+}
+
+// MockUsageRepository implements the interfaces.UsageRepository interface. It is
+// safe for concurrent use since meters flush from a background goroutine.
+type MockUsageRepository struct {
+	mu     sync.Mutex
+	counts map[time.Time][]model.UsageCount
+	active map[time.Time]map[model.ActiveUser]bool
+	err    error // returned by AddUsage when set
+}
+
+// Verify that MockUsageRepository implements UsageRepository interface
+var _ interfaces.UsageRepository = (*MockUsageRepository)(nil)
+
+// NewMockUsageRepository creates an empty usage mock
+func NewMockUsageRepository() *MockUsageRepository {
+	return &MockUsageRepository{
+		counts: make(map[time.Time][]model.UsageCount),
+		active: make(map[time.Time]map[model.ActiveUser]bool),
+	}
+}
+
+// SetErr makes AddUsage fail with err, or succeed again when err is nil
+func (r *MockUsageRepository) SetErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// AddUsage mocks adding usage for a period
+func (r *MockUsageRepository) AddUsage(ctx context.Context, period time.Time, counts []model.UsageCount, active []model.ActiveUser) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.counts[period] = append(r.counts[period], counts...)
+	if r.active[period] == nil {
+		r.active[period] = make(map[model.ActiveUser]bool)
+	}
+	for _, a := range active {
+		r.active[period][a] = true
+	}
+	return nil
+}
+
+// GetUsage mocks totalling the usage of a period
+func (r *MockUsageRepository) GetUsage(ctx context.Context, period time.Time) (*model.UsageReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenants := make(map[int64]*model.UsageRecord)
+	clients := make(map[model.UsageKey]*model.UsageRecord)
+	record := func(key model.UsageKey) (*model.UsageRecord, *model.UsageRecord) {
+		if tenants[key.TenantID] == nil {
+			tenants[key.TenantID] = &model.UsageRecord{UsageKey: model.UsageKey{TenantID: key.TenantID}}
+		}
+		if clients[key] == nil {
+			clients[key] = &model.UsageRecord{UsageKey: key}
+		}
+		return tenants[key.TenantID], clients[key]
+	}
+
+	for _, c := range r.counts[period] {
+		tenant, client := record(c.UsageKey)
+		tenant.Add(c.Metric, c.Count)
+		client.Add(c.Metric, c.Count)
+	}
+	tenantUsers := make(map[int64]map[int64]bool)
+	for a := range r.active[period] {
+		_, client := record(a.UsageKey)
+		client.MonthlyActiveUsers++
+		if tenantUsers[a.TenantID] == nil {
+			tenantUsers[a.TenantID] = make(map[int64]bool)
+		}
+		tenantUsers[a.TenantID][a.UserID] = true
+	}
+	for tenantID, users := range tenantUsers {
+		tenants[tenantID].MonthlyActiveUsers = int64(len(users))
+	}
+
+	report := &model.UsageReport{Period: period}
+	for _, tenant := range tenants {
+		report.Tenants = append(report.Tenants, tenant)
+	}
+	for _, client := range clients {
+		report.Clients = append(report.Clients, client)
+	}
+	byKey := func(a, b *model.UsageRecord) int {
+		return cmp.Or(cmp.Compare(a.TenantID, b.TenantID), cmp.Compare(a.ClientID, b.ClientID))
+	}
+	slices.SortFunc(report.Tenants, byKey)
+	slices.SortFunc(report.Clients, byKey)
+	return report, nil
+}
+
+// MockAuditRepository implements the interfaces.AuditRepository interface. It
+// is safe for concurrent use since audit events are written in the background.
+type MockAuditRepository struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+// Verify that MockAuditRepository implements AuditRepository interface
+var _ interfaces.AuditRepository = (*MockAuditRepository)(nil)
+
+// NewMockAuditRepository creates an empty audit mock
+func NewMockAuditRepository() *MockAuditRepository {
+	return &MockAuditRepository{}
+}
+
+// Record mocks storing an event
+func (r *MockAuditRepository) Record(ctx context.Context, event audit.Event) {
+	r.RecordBatch(ctx, []audit.Event{event})
+}
+
+// RecordBatch mocks storing events, numbering them like a serial column
+func (r *MockAuditRepository) RecordBatch(ctx context.Context, events []audit.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range events {
+		event.ID = int64(len(r.events) + 1)
+		r.events = append(r.events, event)
+	}
+}
+
+// ListEvents mocks listing a page of events matching a filter, newest first
+func (r *MockAuditRepository) ListEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []audit.Event
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+	return pagination.Slice(events, filter.Page, true, func(e audit.Event) int64 { return e.ID })
+}
@@ -0,0 +1,288 @@
+// Package validate checks request structs against rules declared in
+// `validate` struct tags, reporting every invalid field at once:
+//
+//	type RegisterRequest struct {
+//		Email    string `json:"email" validate:"required,email"`
+//		Password string `json:"password" validate:"required,min=8,max=72"`
+//	}
+//
+// Rules are comma-separated:
+//
+//	required   the field must not be the zero value
+//	omitempty  skip the remaining rules when the field is the zero value
+//	email      a string holding an email address
+//	locale     a string holding a BCP 47 language tag, such as de-CH
+//	timezone   a string holding an IANA time zone, such as Europe/Zurich
+//	url        a string holding an absolute http or https URL
+//	min=N      at least N characters, N elements for slices and maps, or
+//	           a value of at least N for integers
+//	max=N      at most N characters, N elements, or a value of at most N
+//	oneof=a b  one of the space-separated values
+//
+// Nested structs are validated too, with dotted field names, and embedded
+// structs as if their fields were declared in the outer struct. Fields are
+// named after their JSON keys so clients can map errors back to inputs.
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // timezone rules must not depend on the host's zone database
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// FieldError describes why one field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors lists every invalid field of a value
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// IsEmail reports whether s looks like an email address
+func IsEmail(s string) bool {
+	return emailPattern.MatchString(s)
+}
+
+// isTimezone reports whether s names an IANA time zone
+func isTimezone(s string) bool {
+	if s == "" || s == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(s)
+	return err == nil
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Struct validates v, a struct or pointer to one, returning Errors when any
+// field breaks its rules. It panics on malformed rules, which are programming
+// errors caught by the first request in any test.
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+
+	var errs Errors
+	validateStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// field is a struct field with its parsed rules
+type field struct {
+	index    int
+	name     string
+	rules    []rule
+	embedded bool // an embedded struct whose fields are named without a prefix
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+// fieldCache holds the parsed fields of each struct type
+var fieldCache sync.Map // reflect.Type -> []field
+
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		embedded := name == "" && sf.Anonymous
+		if name == "" {
+			name = sf.Name
+		}
+
+		f := field{index: i, name: name, embedded: embedded}
+		if tag := sf.Tag.Get("validate"); tag != "" {
+			for _, spec := range strings.Split(tag, ",") {
+				r := rule{}
+				r.name, r.param, _ = strings.Cut(spec, "=")
+				checkRule(t, sf, r)
+				f.rules = append(f.rules, r)
+			}
+		}
+		fields = append(fields, f)
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// checkRule panics on rules that cannot apply to the field
+func checkRule(t reflect.Type, sf reflect.StructField, r rule) {
+	switch r.name {
+	case "required", "omitempty":
+	case "email", "locale", "timezone", "url":
+		kind := sf.Type.Kind()
+		if kind == reflect.Pointer {
+			kind = sf.Type.Elem().Kind()
+		}
+		if kind != reflect.String {
+			panic(fmt.Sprintf("validate: %s.%s: %s applies to strings", t.Name(), sf.Name, r.name))
+		}
+	case "min", "max":
+		if _, err := strconv.Atoi(r.param); err != nil {
+			panic(fmt.Sprintf("validate: %s.%s: %s needs a number", t.Name(), sf.Name, r.name))
+		}
+		if _, ok := measure(reflect.Zero(sf.Type)); !ok {
+			panic(fmt.Sprintf("validate: %s.%s: %s applies to strings, collections and integers", t.Name(), sf.Name, r.name))
+		}
+	case "oneof":
+		if r.param == "" {
+			panic(fmt.Sprintf("validate: %s.%s: oneof needs values", t.Name(), sf.Name))
+		}
+	default:
+		panic(fmt.Sprintf("validate: %s.%s: unknown rule %q", t.Name(), sf.Name, r.name))
+	}
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *Errors) {
+	for _, f := range fieldsOf(rv.Type()) {
+		fv := rv.Field(f.index)
+		name := prefix + f.name
+		if message := check(fv, f.rules); message != "" {
+			*errs = append(*errs, FieldError{Field: name, Message: message})
+			continue
+		}
+
+		if fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			if f.embedded {
+				validateStruct(fv, prefix, errs)
+			} else {
+				validateStruct(fv, name+".", errs)
+			}
+		}
+	}
+}
+
+// check returns the message for the first rule v breaks, or ""
+func check(v reflect.Value, rules []rule) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if slices.Contains(rules, rule{name: "required"}) {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	for _, r := range rules {
+		switch r.name {
+		case "required":
+			if v.IsZero() {
+				return "is required"
+			}
+		case "omitempty":
+			if v.IsZero() {
+				return ""
+			}
+		case "email":
+			if !IsEmail(v.String()) {
+				return "must be a valid email address"
+			}
+		case "locale":
+			if _, err := language.Parse(v.String()); err != nil {
+				return "must be a BCP 47 language tag"
+			}
+		case "timezone":
+			if !isTimezone(v.String()) {
+				return "must be an IANA time zone"
+			}
+		case "url":
+			if !isHTTPURL(v.String()) {
+				return "must be an http or https URL"
+			}
+		case "min":
+			n, _ := strconv.Atoi(r.param)
+			if size, _ := measure(v); size < n {
+				return strings.TrimSpace("must be at least " + r.param + " " + unit(v))
+			}
+		case "max":
+			n, _ := strconv.Atoi(r.param)
+			if size, _ := measure(v); size > n {
+				return strings.TrimSpace("must be at most " + r.param + " " + unit(v))
+			}
+		case "oneof":
+			values := strings.Fields(r.param)
+			if !slices.Contains(values, fmt.Sprint(v.Interface())) {
+				return "must be one of " + strings.Join(values, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the character count of strings, the element count of
+// collections and the value of integers, or false for other kinds
+func measure(v reflect.Value) (int, bool) {
+	if v.Kind() == reflect.Pointer {
+		return measure(reflect.Zero(v.Type().Elem()))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+func unit(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	default:
+		return ""
+	}
+}
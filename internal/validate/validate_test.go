@@ -0,0 +1,112 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+type address struct {
+	Country string `json:"country" validate:"required,oneof=DE FR US"`
+}
+
+type Preferences struct {
+	Locale   string  `json:"locale,omitempty" validate:"omitempty,locale"`
+	Timezone string  `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Website  *string `json:"website,omitempty" validate:"omitempty,url"`
+}
+
+type signup struct {
+	Preferences
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required,min=8,max=72"`
+	Nickname string   `json:"nickname,omitempty" validate:"omitempty,min=3"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Age      *int     `json:"age" validate:"omitempty,min=13"`
+	Address  *address `json:"address"`
+	internal string
+}
+
+func TestStruct(t *testing.T) {
+	twelve := 12
+	tests := []struct {
+		name  string
+		value signup
+		want  Errors
+	}{
+		{
+			name:  "valid",
+			value: signup{Email: "a@example.com", Password: "password123", Address: &address{Country: "DE"}},
+		},
+		{
+			name: "valid preferences",
+			value: signup{Email: "a@example.com", Password: "password123",
+				Preferences: Preferences{Locale: "de-CH", Timezone: "Europe/Zurich", Website: ptr("https://example.com/me")}},
+		},
+		{
+			name:  "cleared preference",
+			value: signup{Email: "a@example.com", Password: "password123", Preferences: Preferences{Website: ptr("")}},
+		},
+		{
+			name:  "missing fields",
+			value: signup{},
+			want: Errors{
+				{Field: "email", Message: "is required"},
+				{Field: "password", Message: "is required"},
+			},
+		},
+		{
+			name: "every rule broken",
+			value: signup{
+				Email: "not-an-email", Password: "short", Nickname: "ab",
+				Tags: []string{"a", "b", "c"}, Age: &twelve, Address: &address{Country: "XX"},
+				Preferences: Preferences{Locale: "not a locale", Timezone: "Mars/Olympus", Website: ptr("javascript:alert(1)")},
+			},
+			want: Errors{
+				{Field: "locale", Message: "must be a BCP 47 language tag"},
+				{Field: "timezone", Message: "must be an IANA time zone"},
+				{Field: "website", Message: "must be an http or https URL"},
+				{Field: "email", Message: "must be a valid email address"},
+				{Field: "password", Message: "must be at least 8 characters"},
+				{Field: "nickname", Message: "must be at least 3 characters"},
+				{Field: "tags", Message: "must be at most 2 items"},
+				{Field: "age", Message: "must be at least 13"},
+				{Field: "address.country", Message: "must be one of DE, FR, US"},
+			},
+		},
+		{
+			name:  "length counts characters, not bytes",
+			value: signup{Email: "a@example.com", Password: "pässwörd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Struct(&tt.value)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStruct_MalformedRules(t *testing.T) {
+	type bad struct {
+		Name string `validate:"min=three"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a malformed rule")
+		}
+	}()
+	Struct(bad{})
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/model"
+)
+
+// Defaults used by NewDispatcher
+const (
+	DefaultMaxAttempts = 6
+	DefaultBackoff     = 2 * time.Second
+	DefaultMaxPending  = 1000
+)
+
+// Dispatcher delivers events to the endpoints subscribed to them. Each
+// delivery runs in the background: a failed attempt is retried after a wait
+// that doubles each time, unless the endpoint answered with a client error
+// other than 429. Events are dropped rather than queued without bound when
+// too many deliveries are pending.
+type Dispatcher struct {
+	endpoints   []Endpoint
+	deliveries  interfaces.WebhookDeliveryRepository // nil only logs failures
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxPending  int64
+
+	mu      sync.RWMutex // guards closed against deliveries started after Close
+	closed  bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	pending atomic.Int64
+	dropped atomic.Int64
+}
+
+// Verify that Dispatcher implements events.Publisher interface
+var _ events.Publisher = (*Dispatcher)(nil)
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithDeliveryLog stores every delivery attempt in repo
+func WithDeliveryLog(repo interfaces.WebhookDeliveryRepository) Option {
+	return func(d *Dispatcher) {
+		d.deliveries = repo
+	}
+}
+
+// WithRetries sets how many times a delivery is attempted and the wait before
+// the first retry
+func WithRetries(maxAttempts int, backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.backoff = backoff
+	}
+}
+
+// WithHTTPClient sends deliveries with client instead of one with a 10s
+// timeout that does not follow redirects
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// NewDispatcher creates a dispatcher for endpoints
+func NewDispatcher(endpoints []Endpoint, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		endpoints: endpoints,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			// A redirect is a failed delivery rather than a resend elsewhere
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxPending:  DefaultMaxPending,
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Publish starts delivering event to every endpoint subscribed to its type
+// without blocking. Failed deliveries are retried and logged rather than
+// reported to the caller.
+func (d *Dispatcher) Publish(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, endpoint := range d.endpoints {
+		if !endpoint.Subscribes(event.Type) {
+			continue
+		}
+		if d.closed || d.pending.Load() >= d.maxPending {
+			d.drop(event)
+			continue
+		}
+		d.pending.Add(1)
+		d.wg.Add(1)
+		go d.deliver(endpoint, event, payload)
+	}
+	return nil
+}
+
+// drop counts a delivery that was not started
+func (d *Dispatcher) drop(event events.Event) {
+	// Log the first drop and every thousandth after it to avoid flooding the log
+	if n := d.dropped.Add(1); n%1000 == 1 {
+		slog.Warn("webhook: too many pending deliveries, dropped event", "event_type", event.Type, "dropped", n)
+	}
+}
+
+// Dropped returns how many deliveries were not started because too many were
+// pending or the dispatcher was closed
+func (d *Dispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+// Close stops accepting events and abandons pending retries, then waits until
+// the attempts in flight finish or ctx is done
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.stop)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver attempts a delivery until it succeeds, fails permanently, or runs
+// out of attempts
+func (d *Dispatcher) deliver(endpoint Endpoint, event events.Event, payload []byte) {
+	defer d.wg.Done()
+	defer d.pending.Add(-1)
+
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		status, err := d.post(endpoint, event, payload)
+		d.record(&model.WebhookDelivery{
+			EventID:    event.ID,
+			EventType:  event.Type,
+			URL:        endpoint.URL,
+			Attempt:    attempt,
+			StatusCode: status,
+			Error:      errorText(err),
+			Delivered:  err == nil,
+		})
+		if err == nil {
+			return
+		}
+		if !retryable(status) || attempt >= d.maxAttempts {
+			slog.Error("webhook: delivery failed", "event_id", event.ID, "event_type", event.Type,
+				"url", endpoint.URL, "attempts", attempt, "err", err)
+			return
+		}
+
+		select {
+		case <-d.stop:
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// post sends one signed attempt, returning the response status, if any
+func (d *Dispatcher) post(endpoint Endpoint, event events.Event, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderEventType, event.Type)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now(), payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt may succeed later: the endpoint
+// could not be reached, failed, or asked to slow down
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// record stores an attempt in the delivery log, if there is one
+func (d *Dispatcher) record(delivery *model.WebhookDelivery) {
+	if d.deliveries == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.deliveries.RecordDelivery(ctx, delivery); err != nil {
+		slog.Error("webhook: failed to log delivery", "event_id", delivery.EventID, "err", err)
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
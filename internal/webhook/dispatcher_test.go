@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/repository/memory"
+)
+
+func TestDispatcherRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []int // status of each attempt; the last one repeats
+		attempts  int
+		delivered bool
+	}{
+		{name: "delivered", responses: []int{http.StatusNoContent}, attempts: 1, delivered: true},
+		{name: "recovers", responses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, attempts: 3, delivered: true},
+		{name: "gives up", responses: []int{http.StatusServiceUnavailable}, attempts: 4},
+		{name: "rejected", responses: []int{http.StatusBadRequest}, attempts: 1},
+		{name: "redirected", responses: []int{http.StatusFound}, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				w.Header().Set("Location", "/elsewhere")
+				w.WriteHeader(tt.responses[min(n, len(tt.responses))-1])
+			}))
+			defer srv.Close()
+
+			log := memory.NewWebhookDeliveryRepository(memory.New())
+			d := NewDispatcher([]Endpoint{{URL: srv.URL, Secret: "secret"}},
+				WithDeliveryLog(log), WithRetries(4, time.Millisecond))
+			event := events.New(events.UserLogin, map[string]any{"user_id": 1})
+			d.Publish(context.Background(), event)
+			waitForDeliveries(t, d)
+
+			deliveries, _, err := log.ListDeliveries(context.Background(), model.WebhookDeliveryFilter{EventID: event.ID, Page: pagination.Page{Limit: 10}})
+			if err != nil {
+				t.Fatalf("failed to list deliveries: %v", err)
+			}
+			if len(deliveries) != tt.attempts || int(calls.Load()) != tt.attempts {
+				t.Fatalf("got %d logged attempts and %d requests, want %d", len(deliveries), calls.Load(), tt.attempts)
+			}
+			if latest := deliveries[0]; latest.Delivered != tt.delivered || latest.Attempt != tt.attempts {
+				t.Errorf("latest attempt = %+v, want attempt %d delivered %v", latest, tt.attempts, tt.delivered)
+			}
+		})
+	}
+}
+
+func TestDispatcherSubscriptions(t *testing.T) {
+	var logins, all atomic.Int32
+	loginSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { logins.Add(1) }))
+	defer loginSrv.Close()
+	allSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { all.Add(1) }))
+	defer allSrv.Close()
+
+	d := NewDispatcher([]Endpoint{
+		{URL: loginSrv.URL, Secret: "secret", Events: []string{events.UserLogin}},
+		{URL: allSrv.URL, Secret: "secret"},
+	})
+	d.Publish(context.Background(), events.New(events.UserLogin, map[string]any{"user_id": 1}))
+	d.Publish(context.Background(), events.New(events.UserRegistered, map[string]any{"user_id": 1}))
+	waitForDeliveries(t, d)
+
+	if logins.Load() != 1 || all.Load() != 2 {
+		t.Errorf("login endpoint got %d events and catch-all %d, want 1 and 2", logins.Load(), all.Load())
+	}
+}
+
+func TestDispatcherClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// Close abandons a delivery waiting to retry instead of waiting an hour
+	d := NewDispatcher([]Endpoint{{URL: srv.URL, Secret: "secret"}}, WithRetries(3, time.Hour))
+	d.Publish(context.Background(), events.New(events.UserLocked, nil))
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	d.Publish(context.Background(), events.New(events.UserLocked, nil))
+	if d.Dropped() != 1 {
+		t.Errorf("dropped %d events after Close, want 1", d.Dropped())
+	}
+}
+
+// waitForDeliveries waits for every delivery started so far to finish
+func waitForDeliveries(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deliveries did not finish")
+	}
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"user.login"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		err     error
+	}{
+		{name: "valid", secret: "secret", header: Sign("secret", now, payload), payload: payload},
+		{name: "wrong secret", secret: "other", header: Sign("secret", now, payload), payload: payload, err: ErrInvalidSignature},
+		{name: "tampered payload", secret: "secret", header: Sign("secret", now, payload), payload: []byte(`{}`), err: ErrInvalidSignature},
+		{name: "replayed", secret: "secret", header: Sign("secret", now.Add(-time.Hour), payload), payload: payload, err: ErrExpiredSignature},
+		{name: "malformed", secret: "secret", header: "v1=abc", payload: payload, err: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.payload, 5*time.Minute); err != tt.err {
+				t.Errorf("Verify returned %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
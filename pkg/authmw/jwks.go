@@ -0,0 +1,234 @@
+// Package authmw lets other services validate access tokens issued by the
+// OpenID Provider locally, using the keys it publishes through JWKS.
+package authmw
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/oidc"
+)
+
+// ErrKeyNotFound is returned when no published key matches a token's key ID
+var ErrKeyNotFound = errors.New("signing key not found in JWKS")
+
+// Defaults used by NewJWKSClient
+const (
+	DefaultJWKSTTL            = time.Hour
+	DefaultRefreshAhead       = 0.2 // refresh during the last 20% of the TTL
+	DefaultJitter             = 0.1 // shorten each TTL by up to 10%
+	DefaultMinRefreshInterval = 10 * time.Second
+)
+
+// JWKSClient fetches and caches a JSON Web Key Set. Keys are refreshed in the
+// background shortly before the cache expires, so lookups rarely wait on the
+// network, and a random jitter keeps many instances from refreshing in step.
+// Unknown key IDs trigger an early refresh at most once per minimum refresh
+// interval. If a refresh fails, the previously fetched keys stay in use.
+type JWKSClient struct {
+	url                string
+	client             *http.Client
+	ttl                time.Duration
+	refreshAhead       float64
+	jitter             float64
+	minRefreshInterval time.Duration
+	now                func() time.Time
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	refreshAt   time.Time
+	expiresAt   time.Time
+	lastAttempt time.Time
+
+	fetchMu    sync.Mutex // serializes fetches
+	refreshing atomic.Bool
+}
+
+// JWKSOption configures a JWKSClient
+type JWKSOption func(*JWKSClient)
+
+// WithHTTPClient sets the HTTP client used to fetch the key set
+func WithHTTPClient(client *http.Client) JWKSOption {
+	return func(c *JWKSClient) {
+		c.client = client
+	}
+}
+
+// WithTTL sets how long keys are cached when the response has no Cache-Control max-age
+func WithTTL(ttl time.Duration) JWKSOption {
+	return func(c *JWKSClient) {
+		c.ttl = ttl
+	}
+}
+
+// WithRefreshAhead sets the fraction of the TTL, before expiry, in which lookups
+// start a background refresh
+func WithRefreshAhead(fraction float64) JWKSOption {
+	return func(c *JWKSClient) {
+		c.refreshAhead = fraction
+	}
+}
+
+// WithJitter sets the maximum fraction by which each TTL is randomly shortened
+func WithJitter(fraction float64) JWKSOption {
+	return func(c *JWKSClient) {
+		c.jitter = fraction
+	}
+}
+
+// WithMinRefreshInterval limits how often unknown key IDs or failed fetches
+// cause the key set to be fetched again
+func WithMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(c *JWKSClient) {
+		c.minRefreshInterval = d
+	}
+}
+
+// NewJWKSClient creates a client for the key set at url, typically
+// https://auth.example.com/.well-known/jwks.json. Keys are fetched on first use.
+func NewJWKSClient(url string, opts ...JWKSOption) *JWKSClient {
+	c := &JWKSClient{
+		url:                url,
+		client:             &http.Client{Timeout: 10 * time.Second},
+		ttl:                DefaultJWKSTTL,
+		refreshAhead:       DefaultRefreshAhead,
+		jitter:             DefaultJitter,
+		minRefreshInterval: DefaultMinRefreshInterval,
+		now:                time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Key returns the public key with the given key ID
+func (c *JWKSClient) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	now := c.now()
+
+	c.mu.RLock()
+	key, found := c.keys[kid]
+	fresh := now.Before(c.expiresAt)
+	refreshDue := !now.Before(c.refreshAt)
+	canRetry := now.Sub(c.lastAttempt) >= c.minRefreshInterval
+	c.mu.RUnlock()
+
+	switch {
+	case found && fresh:
+		if refreshDue && c.refreshing.CompareAndSwap(false, true) {
+			go func() {
+				defer c.refreshing.Store(false)
+				_ = c.refresh(context.Background())
+			}()
+		}
+		return key, nil
+	case !canRetry:
+		// Throttled; fall back to whatever is cached
+		if found {
+			return key, nil
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	err := c.refresh(ctx)
+
+	c.mu.RLock()
+	key, found = c.keys[kid]
+	c.mu.RUnlock()
+	if found {
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrKeyNotFound
+}
+
+// refresh fetches the key set and replaces the cached keys
+func (c *JWKSClient) refresh(ctx context.Context) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	// Another caller may have refreshed while this one waited
+	started := c.now()
+	c.mu.Lock()
+	if started.Sub(c.lastAttempt) < c.minRefreshInterval && started.Before(c.refreshAt) {
+		c.mu.Unlock()
+		return nil
+	}
+	c.lastAttempt = started
+	c.mu.Unlock()
+
+	keys, ttl, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	ttl -= time.Duration(float64(ttl) * c.jitter * rand.Float64())
+	c.mu.Lock()
+	c.keys = keys
+	c.expiresAt = started.Add(ttl)
+	c.refreshAt = started.Add(ttl - time.Duration(float64(ttl)*c.refreshAhead))
+	c.mu.Unlock()
+	return nil
+}
+
+// fetch downloads the key set and returns its RSA signing keys by key ID
+func (c *JWKSClient) fetch(ctx context.Context) (map[string]*rsa.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("error fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set oidc.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, 0, fmt.Errorf("error decoding JWKS: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue // skip key types this client does not support
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, maxAge(resp.Header.Get("Cache-Control"), c.ttl), nil
+}
+
+// maxAge returns the max-age directive of a Cache-Control header, or fallback
+func maxAge(cacheControl string, fallback time.Duration) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age=")
+		if !ok {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return fallback
+}
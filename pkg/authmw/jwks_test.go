@@ -0,0 +1,175 @@
+package authmw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/oidc"
+)
+
+// jwksServer publishes the given keys and counts fetches
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []*oidc.SigningKey
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...*oidc.SigningKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		var set oidc.JWKS
+		for _, k := range s.keys {
+			set.Keys = append(set.Keys, k.JWK())
+		}
+		s.mu.Unlock()
+		w.Header().Set("Cache-Control", "public, max-age=100")
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...*oidc.SigningKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func generateKey(t *testing.T) *oidc.SigningKey {
+	key, err := oidc.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	return key
+}
+
+func TestJWKSClientCachesKeys(t *testing.T) {
+	ctx := context.Background()
+	key := generateKey(t)
+	server := newJWKSServer(t, key)
+	client := NewJWKSClient(server.URL, WithJitter(0))
+
+	for i := 0; i < 10; i++ {
+		pub, err := client.Key(ctx, key.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !pub.Equal(&key.PrivateKey.PublicKey) {
+			t.Fatal("returned key does not match published key")
+		}
+	}
+
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("got %d fetches, want 1", got)
+	}
+}
+
+func TestJWKSClientUnknownKeyID(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := generateKey(t), generateKey(t)
+	server := newJWKSServer(t, oldKey)
+
+	now := time.Now()
+	client := NewJWKSClient(server.URL, WithJitter(0), WithMinRefreshInterval(time.Minute))
+	client.now = func() time.Time { return now }
+
+	if _, err := client.Key(ctx, oldKey.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A rotated key is not visible until the throttle allows a refetch
+	server.setKeys(oldKey, newKey)
+	if _, err := client.Key(ctx, newKey.ID); err != ErrKeyNotFound {
+		t.Errorf("got error %v, want %v", err, ErrKeyNotFound)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := client.Key(ctx, newKey.ID); err != nil {
+		t.Errorf("unexpected error after rotation: %v", err)
+	}
+
+	if _, err := client.Key(ctx, "unknown"); err != ErrKeyNotFound {
+		t.Errorf("got error %v, want %v", err, ErrKeyNotFound)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("got %d fetches, want 2", got)
+	}
+}
+
+func TestJWKSClientRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	key := generateKey(t)
+	server := newJWKSServer(t, key)
+
+	start := time.Now()
+	var mu sync.Mutex
+	now := start
+	client := NewJWKSClient(server.URL, WithJitter(0), WithRefreshAhead(0.2), WithMinRefreshInterval(0))
+	client.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	if _, err := client.Key(ctx, key.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Inside the refresh-ahead window (last 20s of a 100s max-age) the cached
+	// key is returned immediately and a refresh runs in the background
+	mu.Lock()
+	now = start.Add(90 * time.Second)
+	mu.Unlock()
+	if _, err := client.Key(ctx, key.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.fetches.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Fatalf("got %d fetches, want background refresh", got)
+	}
+
+	// The refreshed keys are valid for another max-age from the refresh
+	for client.refreshing.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	now = start.Add(150 * time.Second)
+	mu.Unlock()
+	if _, err := client.Key(ctx, key.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("got %d fetches, want 2", got)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "public, max-age=300", want: 300 * time.Second},
+		{header: "no-cache", want: time.Hour},
+		{header: "max-age=abc", want: time.Hour},
+		{header: "", want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := maxAge(tt.header, time.Hour); got != tt.want {
+			t.Errorf("maxAge(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package authmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrMissingKeyID is returned for tokens without a kid header
+var ErrMissingKeyID = errors.New("token has no key ID")
+
+// Verifier validates RS256 access tokens against keys from a JWKSClient
+type Verifier struct {
+	keys   *JWKSClient
+	parser *jwt.Parser
+}
+
+// NewVerifier creates a verifier. Pass jwt.WithIssuer and jwt.WithAudience to
+// also check those claims.
+func NewVerifier(keys *JWKSClient, opts ...jwt.ParserOption) *Verifier {
+	opts = append(opts, jwt.WithValidMethods([]string{"RS256"}), jwt.WithExpirationRequired())
+	return &Verifier{
+		keys:   keys,
+		parser: jwt.NewParser(opts...),
+	}
+}
+
+// Verify checks the token signature and claims and returns the claims
+func (v *Verifier) Verify(ctx context.Context, token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrMissingKeyID
+		}
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Middleware rejects requests without a valid Bearer token with a 401 problem
+// (RFC 7807), coded TOKEN_EXPIRED for expired tokens, and makes
+// the token claims available through ClaimsFromContext
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "Authentication required")
+			return
+		}
+
+		claims, err := v.Verify(r.Context(), token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				problem.Error(w, r, http.StatusUnauthorized, problem.TokenExpired, "Token has expired")
+				return
+			}
+			problem.Error(w, r, http.StatusUnauthorized, problem.Unauthorized, "Invalid token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(middleware.WithClaims(r.Context(), claims)))
+	})
+}
+
+// ClaimsFromContext returns the claims of the token verified by Middleware
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	return middleware.ClaimsFromContext(ctx)
+}
+
+// RequireScope rejects requests whose token does not carry scope with 403.
+// Use it after Middleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return middleware.RequireScope(scope)
+}
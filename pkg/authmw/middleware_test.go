@@ -0,0 +1,81 @@
+package authmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifierMiddleware(t *testing.T) {
+	key, otherKey := generateKey(t), generateKey(t)
+	server := newJWKSServer(t, key)
+	verifier := NewVerifier(NewJWKSClient(server.URL), jwt.WithIssuer("https://auth.example.com"))
+
+	sign := func(signer *jwt.Token, kid string, signingKey any) string {
+		signer.Header["kid"] = kid
+		token, err := signer.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+	claims := func(issuer string, exp time.Duration) jwt.MapClaims {
+		return jwt.MapClaims{"sub": "1", "iss": issuer, "exp": time.Now().Add(exp).Unix(), "scope": "users:read"}
+	}
+
+	validToken := sign(jwt.NewWithClaims(jwt.SigningMethodRS256, claims("https://auth.example.com", time.Hour)), key.ID, key.PrivateKey)
+
+	handler := verifier.Middleware(RequireScope("users:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := ClaimsFromContext(r.Context()); !ok || claims["sub"] != "1" {
+			t.Error("expected claims in context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name           string
+		token          string
+		wantStatusCode int
+	}{
+		{name: "valid token", token: validToken, wantStatusCode: http.StatusOK},
+		{name: "missing token", wantStatusCode: http.StatusUnauthorized},
+		{
+			name:           "signed by unpublished key",
+			token:          sign(jwt.NewWithClaims(jwt.SigningMethodRS256, claims("https://auth.example.com", time.Hour)), key.ID, otherKey.PrivateKey),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong issuer",
+			token:          sign(jwt.NewWithClaims(jwt.SigningMethodRS256, claims("https://evil.example.com", time.Hour)), key.ID, key.PrivateKey),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "expired",
+			token:          sign(jwt.NewWithClaims(jwt.SigningMethodRS256, claims("https://auth.example.com", -time.Hour)), key.ID, key.PrivateKey),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "hmac token",
+			token:          sign(jwt.NewWithClaims(jwt.SigningMethodHS256, claims("https://auth.example.com", time.Hour)), key.ID, []byte("secret")),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
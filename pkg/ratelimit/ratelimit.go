@@ -0,0 +1,53 @@
+// Package ratelimit exposes the service's token-bucket rate limiter so other
+// services can apply their own policies with it
+package ratelimit
+
+import (
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// Limit is a token bucket: Burst requests at once, refilled at Rate per second
+	Limit = middleware.Limit
+
+	// Option configures a limiter built by New
+	Option = middleware.Option
+
+	// KeyFunc derives the bucket a request is counted in; an empty key exempts it
+	KeyFunc = middleware.KeyFunc
+
+	// RouteLimits overrides the limit for route patterns
+	RouteLimits = middleware.RouteLimits
+
+	// Store keeps the token buckets
+	Store = middleware.RateLimitStore
+)
+
+var (
+	// New creates a middleware that limits requests per bucket
+	New = middleware.RateLimiter
+
+	// Every returns a limit refilling n requests per interval with room for burst
+	Every = middleware.Every
+
+	WithStore            = middleware.WithStore
+	WithName             = middleware.WithName
+	WithLimit            = middleware.WithLimit
+	WithRouteLimits      = middleware.WithRouteLimits
+	WithKeyFunc          = middleware.WithKeyFunc
+	WithRejectionHandler = middleware.WithRejectionHandler
+
+	// KeyByIP counts requests per client IP address
+	KeyByIP KeyFunc = middleware.KeyByIP
+)
+
+// NewMemoryStore keeps buckets in process memory; Close it to stop eviction
+func NewMemoryStore() *middleware.MemoryRateLimitStore {
+	return middleware.NewMemoryRateLimitStore()
+}
+
+// NewRedisStore keeps buckets in Redis, shared by every replica
+func NewRedisStore(client redis.UniversalClient) Store {
+	return middleware.NewRedisRateLimitStore(client)
+}
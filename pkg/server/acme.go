@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeManager obtains and renews certificates for the configured domains,
+// accepting the certificate authority's terms of service
+func acmeManager(cfg config.ACME) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		Email:      cfg.Email,
+	}
+	if cfg.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+	return m
+}
+
+// challengeServer answers HTTP-01 challenges on the ACME HTTP port and
+// redirects every other plain HTTP request to HTTPS
+func challengeServer(cfg config.ACME, m *autocert.Manager) *http.Server {
+	return &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.HTTPPort),
+		Handler:      m.HTTPHandler(nil),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/go-chi/chi/v5"
+)
+
+// Stores holds the persistence implementations used by the server. Nil fields
+// fall back to the PostgreSQL, MySQL or SQLite repositories, as selected by the
+// scheme of DATABASE_URL; Audit and Webhooks stay nil when the other stores need
+// no database, and events and failed webhook deliveries then only go to the log. Sessions are kept in Users unless Sessions is
+// set, e.g. to keep them in a different backend.
+type Stores struct {
+	Users      interfaces.UserRepository
+	Sessions   interfaces.SessionStore
+	Identities interfaces.IdentityRepository
+	OAuth      interfaces.OAuthRepository
+	Consents   interfaces.ConsentRepository
+	APIKeys    interfaces.APIKeyRepository
+	BreakGlass interfaces.BreakGlassRepository
+	Tenants    interfaces.TenantRepository
+	Usage      interfaces.UsageRepository
+	Audit      interfaces.AuditRepository
+	Webhooks   interfaces.WebhookDeliveryRepository
+	Outbox     interfaces.OutboxRepository
+
+	// Domains blocked at runtime; nil leaves only the configured ones
+	EmailDomains interfaces.EmailDomainRepository
+}
+
+// complete reports whether every store is set, so no database is needed
+func (s Stores) complete() bool {
+	return s.Users != nil && s.Identities != nil && s.OAuth != nil &&
+		s.Consents != nil && s.APIKeys != nil && s.BreakGlass != nil && s.Tenants != nil && s.Usage != nil
+}
+
+// withDefaults returns s with its nil fields taken from defaults
+func (s Stores) withDefaults(defaults Stores) Stores {
+	if s.Users == nil {
+		s.Users = defaults.Users
+	}
+	if s.Identities == nil {
+		s.Identities = defaults.Identities
+	}
+	if s.OAuth == nil {
+		s.OAuth = defaults.OAuth
+	}
+	if s.Consents == nil {
+		s.Consents = defaults.Consents
+	}
+	if s.APIKeys == nil {
+		s.APIKeys = defaults.APIKeys
+	}
+	if s.BreakGlass == nil {
+		s.BreakGlass = defaults.BreakGlass
+	}
+	if s.Tenants == nil {
+		s.Tenants = defaults.Tenants
+	}
+	if s.Usage == nil {
+		s.Usage = defaults.Usage
+	}
+	if s.Audit == nil {
+		s.Audit = defaults.Audit
+	}
+	if s.Webhooks == nil {
+		s.Webhooks = defaults.Webhooks
+	}
+	if s.Outbox == nil {
+		s.Outbox = defaults.Outbox
+	}
+	if s.EmailDomains == nil {
+		s.EmailDomains = defaults.EmailDomains
+	}
+	return s
+}
+
+// Option customizes a Server
+type Option func(*options)
+
+type options struct {
+	db          *database.DB
+	stores      Stores
+	middlewares []func(http.Handler) http.Handler
+	routes      []func(chi.Router)
+	auditLogger audit.Logger
+	logger      *slog.Logger
+}
+
+// WithDB uses an existing database pool instead of connecting to DATABASE_URL.
+// The caller remains responsible for closing it.
+func WithDB(db *database.DB) Option {
+	return func(o *options) {
+		o.db = db
+	}
+}
+
+// WithStores replaces the database repositories with alternate stores,
+// e.g. in-memory mocks for tests. Only the non-nil fields are replaced.
+func WithStores(stores Stores) Option {
+	return func(o *options) {
+		if stores.Users != nil {
+			o.stores.Users = stores.Users
+		}
+		if stores.Sessions != nil {
+			o.stores.Sessions = stores.Sessions
+		}
+		if stores.Identities != nil {
+			o.stores.Identities = stores.Identities
+		}
+		if stores.OAuth != nil {
+			o.stores.OAuth = stores.OAuth
+		}
+		if stores.Consents != nil {
+			o.stores.Consents = stores.Consents
+		}
+		if stores.APIKeys != nil {
+			o.stores.APIKeys = stores.APIKeys
+		}
+		if stores.BreakGlass != nil {
+			o.stores.BreakGlass = stores.BreakGlass
+		}
+		if stores.Tenants != nil {
+			o.stores.Tenants = stores.Tenants
+		}
+		if stores.Usage != nil {
+			o.stores.Usage = stores.Usage
+		}
+		if stores.Audit != nil {
+			o.stores.Audit = stores.Audit
+		}
+		if stores.EmailDomains != nil {
+			o.stores.EmailDomains = stores.EmailDomains
+		}
+	}
+}
+
+// WithMiddleware appends middleware that runs after the built-in global middleware
+func WithMiddleware(middlewares ...func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithRoutes registers extra routes on the server's router
+func WithRoutes(register func(r chi.Router)) Option {
+	return func(o *options) {
+		o.routes = append(o.routes, register)
+	}
+}
+
+// WithAuditLogger replaces the default audit logger
+func WithAuditLogger(logger audit.Logger) Option {
+	return func(o *options) {
+		o.auditLogger = logger
+	}
+}
+
+// WithLogger writes request logs to logger instead of slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
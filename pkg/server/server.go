@@ -0,0 +1,1093 @@
+// Package server wires configuration, storage, services, and routes into a
+// runnable auth service, so it can be embedded in other binaries and booted
+// in-process by tests.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/captcha"
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"github.com/Stewz00/go-auth-service/internal/database"
+	"github.com/Stewz00/go-auth-service/internal/device"
+	"github.com/Stewz00/go-auth-service/internal/email"
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/geoip"
+	"github.com/Stewz00/go-auth-service/internal/handler"
+	"github.com/Stewz00/go-auth-service/internal/i18n"
+	"github.com/Stewz00/go-auth-service/internal/interfaces"
+	"github.com/Stewz00/go-auth-service/internal/metering"
+	"github.com/Stewz00/go-auth-service/internal/metrics"
+	"github.com/Stewz00/go-auth-service/internal/middleware"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/oauth"
+	"github.com/Stewz00/go-auth-service/internal/oidc"
+	"github.com/Stewz00/go-auth-service/internal/password"
+	"github.com/Stewz00/go-auth-service/internal/repository"
+	"github.com/Stewz00/go-auth-service/internal/repository/memory"
+	"github.com/Stewz00/go-auth-service/internal/saml"
+	"github.com/Stewz00/go-auth-service/internal/secrets"
+	"github.com/Stewz00/go-auth-service/internal/service"
+	"github.com/Stewz00/go-auth-service/internal/tracing"
+	"github.com/Stewz00/go-auth-service/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+// Server is a fully wired auth service
+type Server struct {
+	cfg         *config.Config
+	db          *database.DB
+	ownsDB      bool
+	mysql       *database.MySQL  // nil unless DATABASE_URL selects MySQL
+	sqlite      *database.SQLite // nil unless DATABASE_URL selects SQLite
+	memory      *memory.Store    // nil unless STORAGE=memory
+	router      chi.Router
+	httpServer  *http.Server
+	challenges  *http.Server // nil unless certificates are obtained with ACME
+	auditQueue  *audit.AsyncLogger
+	eventQueue  *events.AsyncPublisher // nil unless webhooks or an event bus are configured
+	outboxRelay *events.OutboxRelay    // nil unless events are published and the database keeps an outbox
+	webhooks    *webhook.Dispatcher    // nil unless WEBHOOKS lists endpoints
+	nats        *events.NATSPublisher  // nil unless EVENT_BUS=nats
+	mailQueue   *email.AsyncSender     // nil unless EMAIL_SENDER is set
+	geoIP       *geoip.DB              // nil unless GEOIP_DATABASE is set
+	usageMeter  *metering.Meter
+	registry    *prometheus.Registry
+	redis       *redis.Client                    // nil unless rate limits or sessions are kept in Redis
+	limitStore  *middleware.MemoryRateLimitStore // nil when rate limits are kept in Redis
+	limits      *middleware.LiveLimits           // the tier and route limits, replaced by Reload
+	auth        *service.AuthService
+	consents    *service.ConsentService
+	jwtSecrets  []string // the secrets last applied by New or Reload, signing one first
+}
+
+// New builds the server from configuration. A database connection is only
+// opened when some store was not supplied through WithStores or WithDB.
+func New(cfg *config.Config, opts ...Option) (*Server, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &Server{cfg: cfg, db: o.db}
+	if s.db == nil && !o.stores.complete() {
+		if err := s.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	router, err := s.routes(o)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.router = router
+
+	s.httpServer = &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	if cfg.ACME.Enabled() {
+		m := acmeManager(cfg.ACME)
+		s.httpServer.TLSConfig = m.TLSConfig()
+		s.challenges = challengeServer(cfg.ACME, m)
+	}
+	if cfg.Vault != nil {
+		cfg.Vault.KeepLogin()
+	}
+	return s, nil
+}
+
+// connect opens the database selected by the scheme of DATABASE_URL, or
+// creates an empty in-memory store with STORAGE=memory
+func (s *Server) connect() error {
+	if s.cfg.Storage == "memory" {
+		s.memory = memory.New()
+		return nil
+	}
+
+	driver, err := database.DriverFor(s.cfg.DbURL)
+	if err != nil {
+		return err
+	}
+	if driver != database.DriverPostgres && s.cfg.TracingEnabled {
+		slog.Info("SQL statements are only traced with PostgreSQL", "driver", driver)
+	}
+
+	switch driver {
+	case database.DriverMySQL:
+		db, err := database.Retry(s.cfg.DBConnectTimeout, func(ctx context.Context) (*database.MySQL, error) {
+			return database.NewMySQLContext(ctx, s.cfg.DbURL)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %v", err)
+		}
+		s.mysql = db
+		return nil
+	case database.DriverSQLite:
+		db, err := database.NewSQLite(s.cfg.DbURL)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %v", err)
+		}
+		s.sqlite = db
+		return nil
+	}
+
+	var dbOpts []database.Option
+	if s.cfg.TracingEnabled {
+		dbOpts = append(dbOpts, database.WithQueryTracing())
+	}
+	if s.cfg.SlowQueryThreshold > 0 {
+		dbOpts = append(dbOpts, database.WithSlowQueryLog(s.cfg.SlowQueryThreshold))
+	}
+	var creds *database.Credentials
+	if s.cfg.DBCredentials != nil {
+		creds = &database.Credentials{}
+		creds.Set(s.cfg.DBCredentials.Username, s.cfg.DBCredentials.Password)
+		dbOpts = append(dbOpts, database.WithCredentials(creds))
+	}
+	db, err := database.Retry(s.cfg.DBConnectTimeout, func(ctx context.Context) (*database.DB, error) {
+		return database.NewContext(ctx, s.cfg.DbURL, dbOpts...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %v", err)
+	}
+	s.db = db
+	s.ownsDB = true
+
+	if creds != nil {
+		// Reconnect with new credentials before Vault revokes the old ones
+		s.cfg.Vault.WatchCredentials(s.cfg.DBCredentials, func(next *secrets.Credentials) {
+			creds.Set(next.Username, next.Password)
+			db.Reconnect()
+		})
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler serving every route
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// ListenAndServe serves on the configured port until Shutdown is called.
+// With ACME, the port serves HTTPS and the ACME HTTP port answers challenges.
+func (s *Server) ListenAndServe() error {
+	if s.challenges != nil {
+		ln, err := net.Listen("tcp", s.challenges.Addr)
+		if err != nil {
+			return fmt.Errorf("acme: cannot listen for HTTP-01 challenges: %v", err)
+		}
+		go func() {
+			if err := s.challenges.Serve(ln); err != nil && err != http.ErrServerClosed {
+				slog.Error("acme: challenge server failed", "err", err)
+			}
+		}()
+		slog.Info("server starting", "port", s.cfg.Port, "tls", "acme", "domains", s.cfg.ACME.Domains)
+		if err := s.httpServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+
+	slog.Info("server starting", "port", s.cfg.Port)
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Reload applies the settings of next that can change while the server runs:
+// the rate limits, the JWT secret and how long new tokens stay valid. Requests
+// in flight are not interrupted. Changes to settings that need a restart are
+// logged and otherwise ignored.
+func (s *Server) Reload(next *config.Config) {
+	s.limits.Set(liveLimits(next.RateLimits))
+	if s.auth != nil {
+		secrets := append([]string{next.JwtSecret}, next.PreviousJwtSecrets...)
+		if !slices.Equal(secrets, s.jwtSecrets) {
+			for _, old := range s.jwtSecrets {
+				if !slices.Contains(secrets, old) {
+					slog.Warn("a JWT secret was removed; tokens signed with it are no longer accepted")
+					break
+				}
+			}
+			s.auth.SetJWTSecrets(next.JwtSecret, next.PreviousJwtSecrets...)
+			s.jwtSecrets = secrets
+		}
+		if next.TokenTTL > 0 {
+			s.auth.SetTokenExpiry(next.TokenTTL)
+		}
+		s.auth.SetRememberMeExpiry(next.RememberMeTTL)
+	}
+	if s.consents != nil {
+		s.consents.SetTermsVersions(service.TermsVersions{TermsOfService: next.TosVersion, PrivacyPolicy: next.PrivacyPolicyVersion})
+	}
+	for _, name := range restartRequired(s.cfg, next) {
+		slog.Warn("configuration change ignored until restart", "setting", name)
+	}
+	slog.Info("configuration reloaded")
+}
+
+// restartRequired returns the settings changed in next that only apply at
+// startup
+func restartRequired(cfg, next *config.Config) []string {
+	var names []string
+	for _, setting := range []struct {
+		name    string
+		changed bool
+	}{
+		{"PORT", cfg.Port != next.Port},
+		{"DATABASE_URL", cfg.DBCredentials == nil && cfg.DbURL != next.DbURL},
+		{"STORAGE", cfg.Storage != next.Storage},
+		{"SESSION_STORE", cfg.SessionStore != next.SessionStore},
+		{"SESSION_CACHE_TTL", cfg.SessionCacheTTL != next.SessionCacheTTL},
+		{"RATE_LIMIT_STORE", cfg.RateLimitStore != next.RateLimitStore},
+		{"REDIS_URL", cfg.RedisURL != next.RedisURL},
+		{"EVENT_BUS", cfg.EventBus != next.EventBus},
+		{"EMAIL_SENDER", cfg.EmailSender != next.EmailSender},
+		{"OIDC_ISSUER", cfg.OIDCIssuer != next.OIDCIssuer},
+		{"SAML_ROOT_URL", cfg.SAMLRootURL != next.SAMLRootURL},
+		{"ACME_DOMAINS", !slices.Equal(cfg.ACME.Domains, next.ACME.Domains)},
+		{"GRAPHQL_ENABLED", cfg.GraphQL != next.GraphQL},
+		{"API_DOCS", cfg.APIDocs != next.APIDocs},
+		{"LOCALES_DIR", cfg.LocalesDir != next.LocalesDir},
+		{"GEOIP_DATABASE", cfg.GeoIPDatabase != next.GeoIPDatabase},
+		{"GEOIP_BLOCK_COUNTRIES", !slices.Equal(cfg.GeoIPBlockCountries, next.GeoIPBlockCountries)},
+		{"GEOIP_CHALLENGE_COUNTRIES", !slices.Equal(cfg.GeoIPChallengeCountries, next.GeoIPChallengeCountries)},
+		{"DEVICE_BINDING", cfg.DeviceBinding != next.DeviceBinding},
+		{"BLOCKED_EMAIL_DOMAINS", !slices.Equal(cfg.BlockedEmailDomains, next.BlockedEmailDomains)},
+		{"BLOCK_DISPOSABLE_EMAILS", cfg.BlockDisposableEmails != next.BlockDisposableEmails},
+		{"FOLD_GMAIL_ADDRESSES", cfg.FoldGmailAddresses != next.FoldGmailAddresses},
+	} {
+		if setting.changed {
+			names = append(names, setting.name)
+		}
+	}
+	return names
+}
+
+// Shutdown gracefully stops the HTTP server, flushes queued audit events,
+// published events, webhook deliveries in flight, queued emails and metered
+// usage, and releases the database pool
+func (s *Server) Shutdown(ctx context.Context) error {
+	defer s.Close()
+	err := s.httpServer.Shutdown(ctx)
+	if s.challenges != nil {
+		if closeErr := s.challenges.Shutdown(ctx); err == nil {
+			err = closeErr
+		}
+	}
+	if flushErr := s.auditQueue.Close(ctx); err == nil {
+		err = flushErr
+	}
+	if s.eventQueue != nil {
+		if flushErr := s.eventQueue.Close(ctx); err == nil {
+			err = flushErr
+		}
+	}
+	if s.outboxRelay != nil {
+		if flushErr := s.outboxRelay.Close(ctx); err == nil {
+			err = flushErr
+		}
+	}
+	if s.webhooks != nil {
+		if flushErr := s.webhooks.Close(ctx); err == nil {
+			err = flushErr
+		}
+	}
+	if s.mailQueue != nil {
+		if flushErr := s.mailQueue.Close(ctx); err == nil {
+			err = flushErr
+		}
+	}
+	if flushErr := s.usageMeter.Close(ctx); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// Close flushes queued audit and published events, emails and metered usage
+// for up to five seconds and releases the database pool if the server opened it
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if s.auditQueue != nil {
+		if err := s.auditQueue.Close(ctx); err != nil {
+			slog.Error("audit: queued events not written", "queued", s.auditQueue.QueueLength(), "err", err)
+		}
+	}
+	if s.eventQueue != nil {
+		if err := s.eventQueue.Close(ctx); err != nil {
+			slog.Error("events: queued events not published", "err", err)
+		}
+	}
+	if s.outboxRelay != nil {
+		if err := s.outboxRelay.Close(ctx); err != nil {
+			slog.Error("events: outbox relay not stopped", "err", err)
+		}
+	}
+	if s.webhooks != nil {
+		if err := s.webhooks.Close(ctx); err != nil {
+			slog.Error("webhook: deliveries in flight not finished", "err", err)
+		}
+	}
+	if s.nats != nil {
+		if err := s.nats.Close(); err != nil {
+			slog.Error("events: NATS connection not drained", "err", err)
+		}
+	}
+	if s.mailQueue != nil {
+		if err := s.mailQueue.Close(ctx); err != nil {
+			slog.Error("email: queued emails not sent", "err", err)
+		}
+	}
+	if err := s.usageMeter.Close(ctx); err != nil {
+		slog.Error("metering: usage not written", "err", err)
+	}
+	if s.geoIP != nil {
+		s.geoIP.Close()
+	}
+	if s.redis != nil {
+		s.redis.Close()
+	}
+	if s.limitStore != nil {
+		s.limitStore.Close()
+	}
+	if s.ownsDB && s.db != nil {
+		s.db.Close()
+	}
+	if s.mysql != nil {
+		s.mysql.Close()
+	}
+	if s.sqlite != nil {
+		s.sqlite.Close()
+	}
+	if s.cfg.Vault != nil {
+		s.cfg.Vault.Close()
+	}
+}
+
+// stores fills in database repositories for any store not supplied as an
+// option. Sessions are kept in Redis with SESSION_STORE=redis, else with users.
+func (s *Server) stores(o *options) (Stores, error) {
+	stores := o.stores
+	switch {
+	case s.mysql != nil:
+		stores = o.stores.withDefaults(Stores{
+			Users:        repository.NewMySQLUserRepository(s.mysql),
+			Identities:   repository.NewMySQLIdentityRepository(s.mysql),
+			OAuth:        repository.NewMySQLOAuthRepository(s.mysql),
+			Consents:     repository.NewMySQLConsentRepository(s.mysql),
+			APIKeys:      repository.NewMySQLAPIKeyRepository(s.mysql),
+			BreakGlass:   repository.NewMySQLBreakGlassRepository(s.mysql),
+			Tenants:      repository.NewMySQLTenantRepository(s.mysql),
+			Usage:        repository.NewMySQLUsageRepository(s.mysql),
+			Audit:        repository.NewMySQLAuditRepository(s.mysql),
+			Webhooks:     repository.NewMySQLWebhookDeliveryRepository(s.mysql),
+			Outbox:       repository.NewMySQLOutboxRepository(s.mysql),
+			EmailDomains: repository.NewMySQLEmailDomainRepository(s.mysql),
+		})
+	case s.sqlite != nil:
+		stores = o.stores.withDefaults(Stores{
+			Users:        repository.NewSQLiteUserRepository(s.sqlite),
+			Identities:   repository.NewSQLiteIdentityRepository(s.sqlite),
+			OAuth:        repository.NewSQLiteOAuthRepository(s.sqlite),
+			Consents:     repository.NewSQLiteConsentRepository(s.sqlite),
+			APIKeys:      repository.NewSQLiteAPIKeyRepository(s.sqlite),
+			BreakGlass:   repository.NewSQLiteBreakGlassRepository(s.sqlite),
+			Tenants:      repository.NewSQLiteTenantRepository(s.sqlite),
+			Usage:        repository.NewSQLiteUsageRepository(s.sqlite),
+			Audit:        repository.NewSQLiteAuditRepository(s.sqlite),
+			Webhooks:     repository.NewSQLiteWebhookDeliveryRepository(s.sqlite),
+			Outbox:       repository.NewSQLiteOutboxRepository(s.sqlite),
+			EmailDomains: repository.NewSQLiteEmailDomainRepository(s.sqlite),
+		})
+	case s.memory != nil:
+		stores = o.stores.withDefaults(Stores{
+			Users:        memory.NewUserRepository(s.memory),
+			Identities:   memory.NewIdentityRepository(s.memory),
+			OAuth:        memory.NewOAuthRepository(s.memory),
+			Consents:     memory.NewConsentRepository(s.memory),
+			APIKeys:      memory.NewAPIKeyRepository(s.memory),
+			BreakGlass:   memory.NewBreakGlassRepository(s.memory),
+			Tenants:      memory.NewTenantRepository(s.memory),
+			Usage:        memory.NewUsageRepository(s.memory),
+			Audit:        memory.NewAuditRepository(s.memory),
+			Webhooks:     memory.NewWebhookDeliveryRepository(s.memory),
+			EmailDomains: memory.NewEmailDomainRepository(s.memory),
+		})
+	case s.db != nil:
+		stores = o.stores.withDefaults(Stores{
+			Users:        repository.NewUserRepository(s.db),
+			Identities:   repository.NewIdentityRepository(s.db),
+			OAuth:        repository.NewOAuthRepository(s.db),
+			Consents:     repository.NewConsentRepository(s.db),
+			APIKeys:      repository.NewAPIKeyRepository(s.db),
+			BreakGlass:   repository.NewBreakGlassRepository(s.db),
+			Tenants:      repository.NewTenantRepository(s.db),
+			Usage:        repository.NewUsageRepository(s.db),
+			Audit:        repository.NewAuditRepository(s.db),
+			Webhooks:     repository.NewWebhookDeliveryRepository(s.db),
+			Outbox:       repository.NewOutboxRepository(s.db),
+			EmailDomains: repository.NewEmailDomainRepository(s.db),
+		})
+	}
+	if stores.Sessions == nil && s.cfg.SessionStore == "redis" {
+		client, err := s.redisClient()
+		if err != nil {
+			return Stores{}, err
+		}
+		stores.Sessions = repository.NewRedisSessionStore(client)
+	}
+	if stores.Sessions == nil && stores.Users != nil {
+		stores.Sessions = stores.Users
+	}
+	return stores, nil
+}
+
+// healthChecks returns the readiness checks of the dependencies in use
+func (s *Server) healthChecks() []handler.HealthCheck {
+	var checks []handler.HealthCheck
+	if s.db != nil {
+		checks = append(checks, handler.HealthCheck{Name: "postgres", Check: s.db.Pool.Ping})
+	}
+	if s.mysql != nil {
+		checks = append(checks, handler.HealthCheck{Name: "mysql", Check: s.mysql.DB.PingContext})
+	}
+	if s.sqlite != nil {
+		checks = append(checks, handler.HealthCheck{Name: "sqlite", Check: s.sqlite.DB.PingContext})
+	}
+	if s.redis != nil {
+		checks = append(checks, handler.HealthCheck{Name: "redis", Check: func(ctx context.Context) error {
+			return s.redis.Ping(ctx).Err()
+		}})
+	}
+	return checks
+}
+
+// routes creates the services and handlers and registers every route
+func (s *Server) routes(o *options) (chi.Router, error) {
+	cfg := s.cfg
+	stores, err := s.stores(o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Security events are written to the log and the audit_events table, and
+	// high-severity alerts are also posted to the alert webhook when set
+	auditLogger := o.auditLogger
+	if auditLogger == nil {
+		sinks := audit.MultiLogger{audit.LogLogger{}}
+		if stores.Audit != nil {
+			sinks = append(sinks, stores.Audit)
+		}
+		if cfg.AlertWebhookURL != "" {
+			sinks = append(sinks, audit.NewWebhookLogger(cfg.AlertWebhookURL))
+		}
+		auditLogger = sinks
+	}
+	// Registrations, logins, lockouts and session revocations are posted to
+	// the configured webhooks and published to the event bus
+	var publishers events.Multi
+	if len(cfg.Webhooks) > 0 {
+		var webhookOpts []webhook.Option
+		if stores.Webhooks != nil {
+			webhookOpts = append(webhookOpts, webhook.WithDeliveryLog(stores.Webhooks))
+		}
+		s.webhooks = webhook.NewDispatcher(cfg.Webhooks, webhookOpts...)
+		publishers = append(publishers, s.webhooks)
+	}
+	switch cfg.EventBus {
+	case "kafka":
+		publishers = append(publishers, events.NewKafkaPublisher(cfg.KafkaRESTURL, cfg.KafkaTopic))
+	case "nats":
+		s.nats, err = events.NewNATSPublisher(cfg.NATSURL, cfg.NATSSubject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+		}
+		publishers = append(publishers, s.nats)
+	}
+	// Events stored in the database's outbox with the changes they describe
+	// are published by the outbox relay; the others never add latency to
+	// requests, see events.AsyncPublisher
+	if len(publishers) > 0 {
+		if stores.Outbox != nil {
+			s.outboxRelay = events.NewOutboxRelay(stores.Outbox, publishers, 0)
+		}
+		s.eventQueue = events.NewAsyncPublisher(publishers, 0)
+	}
+	// Audit writes never add latency to requests; see audit.AsyncLogger
+	s.auditQueue = audit.NewAsyncLogger(auditLogger, cfg.AuditQueueSize, 0)
+	auditLogger = s.auditQueue
+
+	// Each server has its own metrics registry so several can run in one process
+	s.registry = prometheus.NewRegistry()
+	securityMetrics := metrics.NewSecurity(s.registry)
+	authMetrics := metrics.NewAuth(s.registry)
+	httpMetrics := metrics.NewHTTP(s.registry)
+	if s.db != nil {
+		metrics.RegisterPool(s.registry, s.db.Pool)
+	}
+	if s.mysql != nil {
+		metrics.RegisterSQLPool(s.registry, s.mysql.DB)
+	}
+	if s.sqlite != nil {
+		metrics.RegisterSQLPool(s.registry, s.sqlite.DB)
+	}
+
+	// Sign-in attempts against canary accounts alert and optionally ban the client IP
+	banList := middleware.NewIPBanList(middleware.WithBanMetrics(securityMetrics))
+	canary := handler.NewCanaryTripwire(auditLogger, banList, cfg.CanaryBanDuration)
+
+	// Initialize services and handlers
+	// Usage is counted in memory and written in the background; see metering.Meter
+	s.usageMeter = metering.NewMeter(stores.Usage, 0)
+	loginThrottle := service.NewLoginThrottle(service.DefaultLoginThrottlePolicy, banList)
+	authOpts := []service.AuthServiceOption{
+		service.WithTenants(stores.Tenants),
+		service.WithUsageMeter(s.usageMeter),
+		service.WithSecurityMetrics(securityMetrics),
+		service.WithAuthMetrics(authMetrics),
+		service.WithLoginThrottle(loginThrottle),
+		service.WithAuditLogger(auditLogger),
+	}
+	if s.eventQueue != nil {
+		authOpts = append(authOpts, service.WithEventPublisher(s.eventQueue))
+	}
+	// Token validation trusts a session's validity for SESSION_CACHE_TTL;
+	// revocations on other replicas are seen once it passes
+	var sessionCache *service.SessionCache
+	if cfg.SessionCacheTTL > 0 {
+		sessionCache = service.NewSessionCache(cfg.SessionCacheTTL, service.WithSessionCacheMetrics(s.registry))
+		authOpts = append(authOpts, service.WithSessionCache(sessionCache))
+	}
+	// Emails are sent in the background and never delay requests; see
+	// email.AsyncSender
+	var sender email.Sender
+	switch cfg.EmailSender {
+	case "log":
+		sender = email.LogSender{}
+	case "smtp":
+		sender, err = email.NewSMTPSender(email.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.EmailFrom,
+			TLS:      cfg.SMTPTLS,
+		})
+		if err != nil {
+			return nil, err
+		}
+	case "sendgrid":
+		sender, err = email.NewSendGridSender(cfg.SendGridAPIKey, cfg.EmailFrom)
+		if err != nil {
+			return nil, err
+		}
+	case "ses":
+		sender, err = email.NewSESSender(context.Background(), cfg.EmailFrom, cfg.SESConfigurationSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sender != nil && cfg.EmailRateLimit > 0 {
+		sender = email.NewThrottledSender(sender, cfg.EmailRateLimit)
+	}
+	if sender != nil {
+		templates, err := email.LoadTemplates(cfg.EmailTemplatesDir)
+		if err != nil {
+			return nil, err
+		}
+		s.mailQueue = email.NewAsyncSender(sender, 0)
+		authOpts = append(authOpts, service.WithEmailSender(s.mailQueue), service.WithEmailTemplates(templates))
+	}
+	if cfg.Lockout.MaxFailedAttempts > 0 {
+		authOpts = append(authOpts, service.WithLockoutPolicy(cfg.Lockout))
+	}
+	if len(cfg.UserScopes) > 0 {
+		authOpts = append(authOpts, service.WithUserScopes(cfg.UserScopes...))
+	}
+	argon2 := cfg.Argon2
+	if argon2 == (password.Argon2Params{}) {
+		argon2 = password.DefaultArgon2Params
+	}
+	hashOpts := []password.HasherOption{password.WithWorkers(cfg.PasswordHashWorkers)}
+	if cfg.PasswordHash == "bcrypt" {
+		hashOpts = append(hashOpts, password.WithBcrypt(cfg.BcryptCost))
+	}
+	if len(cfg.PasswordPeppers) > 0 {
+		hashOpts = append(hashOpts, password.WithPepper(cfg.PasswordPepperID, cfg.PasswordPeppers))
+	}
+	hasher := password.NewHasher(argon2, hashOpts...)
+	metrics.RegisterHasher(s.registry, hasher)
+	policy := cfg.PasswordPolicy
+	if policy.MinLength == 0 {
+		policy = password.DefaultPolicy
+	}
+	authOpts = append(authOpts, service.WithPasswordHasher(hasher), service.WithPasswordPolicy(policy),
+		service.WithPasswordMaxAge(cfg.PasswordMaxAge))
+	if cfg.PwnedPasswords {
+		authOpts = append(authOpts, service.WithBreachCheck(password.NewPwnedPasswords(cfg.PwnedPasswordsTimeout), cfg.PwnedPasswordsFailOpen))
+	}
+	if cfg.DeviceBinding {
+		authOpts = append(authOpts, service.WithDeviceBinding())
+	}
+	if cfg.FoldGmailAddresses {
+		authOpts = append(authOpts, service.WithGmailFolding())
+	}
+	if cfg.TokenTTL > 0 {
+		authOpts = append(authOpts, service.WithTokenExpiry(cfg.TokenTTL))
+	}
+	authOpts = append(authOpts, service.WithRememberMeExpiry(cfg.RememberMeTTL))
+	if len(cfg.PreviousJwtSecrets) > 0 {
+		authOpts = append(authOpts, service.WithPreviousJWTSecrets(cfg.PreviousJwtSecrets...))
+	}
+	var domainPolicy *service.EmailDomainPolicy
+	if stores.EmailDomains != nil || len(cfg.BlockedEmailDomains) > 0 || cfg.BlockDisposableEmails {
+		domainPolicy = service.NewEmailDomainPolicy(stores.EmailDomains, cfg.BlockedEmailDomains, cfg.BlockDisposableEmails)
+		authOpts = append(authOpts, service.WithEmailDomainPolicy(domainPolicy))
+	}
+	authService := service.NewAuthService(stores.Users, stores.Sessions, cfg.JwtSecret, authOpts...)
+	s.auth, s.jwtSecrets = authService, append([]string{cfg.JwtSecret}, cfg.PreviousJwtSecrets...)
+	consentService := service.NewConsentService(stores.Consents, service.WithTermsVersions(service.TermsVersions{
+		TermsOfService: cfg.TosVersion,
+		PrivacyPolicy:  cfg.PrivacyPolicyVersion,
+	}))
+	s.consents = consentService
+	csrf := middleware.NewCSRF(cfg.JwtSecret)
+	authHandlerOpts := []handler.AuthHandlerOption{
+		handler.WithSessionCookies(csrf, cfg.SessionMode == config.SessionModeCookie),
+		handler.WithConsentService(consentService),
+		handler.WithCanaryTripwire(canary),
+	}
+	if cfg.CaptchaProvider != "" {
+		verifier, err := captcha.NewSiteVerifier(cfg.CaptchaProvider, cfg.CaptchaSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CAPTCHA_PROVIDER: %v", err)
+		}
+		captchaService := service.NewCaptchaService(verifier, loginThrottle, cfg.CaptchaAfterFailures, securityMetrics)
+		authHandlerOpts = append(authHandlerOpts, handler.WithCaptcha(captchaService))
+	}
+	if cfg.GeoIPDatabase != "" {
+		s.geoIP, err = geoip.Open(cfg.GeoIPDatabase)
+		if err != nil {
+			return nil, err
+		}
+		geo := service.NewGeoPolicy(s.geoIP, cfg.GeoIPBlockCountries, cfg.GeoIPChallengeCountries, auditLogger)
+		authHandlerOpts = append(authHandlerOpts, handler.WithGeoPolicy(geo))
+	}
+	authHandler := handler.NewAuthHandler(authService, authHandlerOpts...)
+	consentHandler := handler.NewConsentHandler(consentService, authService)
+	profileHandler := handler.NewProfileHandler(service.NewProfileService(stores.Users), authService)
+	apiKeyService := service.NewAPIKeyService(stores.APIKeys, stores.Users, authService.LockoutPolicy())
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, authService)
+
+	// Social login providers are optional and enabled through configuration
+	var providers []oauth.Provider
+	if cfg.GitHubClientID != "" {
+		providers = append(providers, oauth.NewGitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.GitHubRedirectURL))
+	}
+	socialService := service.NewSocialAuthService(authService, stores.Users, stores.Identities, providers...)
+	socialHandler := handler.NewSocialHandler(socialService)
+
+	// Enterprise SSO through a corporate SAML identity provider is optional
+	var samlHandler *handler.SAMLHandler
+	if cfg.SAMLRootURL != "" {
+		idpMetadata, err := saml.LoadIdPMetadata(context.Background(), cfg.SAMLIdPMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SAML IdP metadata: %v", err)
+		}
+		sp, err := saml.NewServiceProvider(cfg.SAMLRootURL, cfg.SAMLCertFile, cfg.SAMLKeyFile, idpMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure SAML service provider: %v", err)
+		}
+		samlHandler = handler.NewSAMLHandler(sp, socialService)
+	}
+
+	// The OpenID Provider endpoints are enabled when an issuer is configured
+	var oidcService *service.OIDCService
+	var oidcHandler *handler.OIDCHandler
+	if cfg.OIDCIssuer != "" {
+		signingKey, err := loadSigningKey(cfg.OIDCSigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OIDC signing key: %v", err)
+		}
+		oidcService = service.NewOIDCService(authService, stores.Users, stores.OAuth, signingKey, cfg.OIDCIssuer)
+		oidcHandler = handler.NewOIDCHandler(oidcService, authService, consentService, canary, csrf)
+	}
+	adminHandler := handler.NewAdminHandler(authService, oidcService, auditLogger)
+	userAdminHandler := handler.NewUserAdminHandler(service.NewUserAdminService(repository.NewCombinedRepository(stores.Users, stores.Sessions), authService), auditLogger)
+	serviceAccountHandler := handler.NewServiceAccountHandler(service.NewServiceAccountService(stores.Users, apiKeyService), auditLogger)
+	tenantOpts := []service.TenantServiceOption{service.WithTenantPasswords(hasher, policy)}
+	if stores.Sessions != stores.Users {
+		tenantOpts = append(tenantOpts, service.WithTenantSessions(stores.Users, stores.Sessions))
+	}
+	if sessionCache != nil {
+		tenantOpts = append(tenantOpts, service.WithTenantSessionCache(sessionCache))
+	}
+	tenantHandler := handler.NewTenantHandler(service.NewTenantService(stores.Tenants, tenantOpts...), auditLogger)
+	usageHandler := handler.NewUsageHandler(service.NewUsageService(stores.Usage))
+
+	// The break-glass credential unlocks the admin API when normal admin access is unavailable
+	var breakGlassService *service.BreakGlassService
+	if cfg.BreakGlassCredentialHash != "" {
+		breakGlassService = service.NewBreakGlassService(stores.BreakGlass, cfg.BreakGlassCredentialHash, cfg.BreakGlassExpiresAt, auditLogger)
+	}
+
+	// Rate limit counters are per replica unless kept in Redis
+	rateLimits, err := s.rateLimitStore()
+	if err != nil {
+		return nil, err
+	}
+	s.limits = middleware.NewLiveLimits(liveLimits(cfg.RateLimits))
+	limitOpts := func(name, tier string) []middleware.Option {
+		return []middleware.Option{
+			middleware.WithStore(rateLimits),
+			middleware.WithName(name),
+			middleware.WithLiveLimits(s.limits, tier),
+			middleware.WithRejectionMetrics(httpMetrics),
+		}
+	}
+
+	// Registration responses are replayed to retries with the same Idempotency-Key,
+	// from Redis when rate limits are kept there too
+	var idempotencyStore middleware.IdempotencyStore = middleware.NewMemoryIdempotencyStore()
+	if s.redis != nil {
+		idempotencyStore = middleware.NewRedisIdempotencyStore(s.redis)
+	}
+	idempotencyTTL := cfg.IdempotencyTTL
+	if idempotencyTTL == 0 {
+		idempotencyTTL = middleware.DefaultIdempotencyTTL
+	}
+
+	maxBodyBytes := cfg.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = middleware.DefaultMaxBodySize
+	}
+
+	// Error details and emails are translated by the request's Accept-Language
+	catalog, err := i18n.LoadCatalog(cfg.LocalesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create router with middleware
+	r := chi.NewRouter()
+
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	// Global middleware
+	r.Use(chimiddleware.RequestID)
+	r.Use(middleware.RealIP(cfg.TrustedProxies))
+	r.Use(middleware.AuditClient)
+	r.Use(middleware.Tracing(tracing.Tracer()))
+	r.Use(middleware.RequestLogger(logger))
+	r.Use(middleware.Metrics(httpMetrics))
+	r.Use(chimiddleware.Recoverer)
+	r.Use(catalog.Middleware)
+	r.Use(device.Middleware)
+	r.Use(middleware.CORS(cfg.CORS))
+	r.Use(middleware.SecurityHeaders(securityHeaderOpts(cfg)...))
+	r.Use(middleware.MaxBodySize(maxBodyBytes))
+	r.Use(banList.Middleware)
+	r.Use(middleware.RateLimiter(limitOpts("global", "default")...))
+
+	// State-changing requests authenticated by the session cookie need a CSRF token
+	r.Use(csrf.Middleware)
+
+	// Authentication is enforced per route according to the configured policies
+	r.Use(middleware.RouteAuth(cfg.RoutePolicies, map[config.AuthStrategy]middleware.Authenticator{
+		config.StrategyJWT:    middleware.JWTAuthenticator(authService),
+		config.StrategyAPIKey: middleware.APIKeyAuthenticator(apiKeyService),
+		config.StrategyMTLS:   middleware.MTLSAuthenticator(),
+	}))
+
+	// Middleware supplied by the embedding application
+	for _, mw := range o.middlewares {
+		r.Use(mw)
+	}
+
+	// Prometheus metrics in the OpenMetrics format when the scraper accepts it
+	r.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	// Health check endpoint
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	// The OpenAPI document of the routes below, and optionally Swagger UI for it
+	docsHandler := handler.NewAPIDocsHandler()
+	r.Get("/openapi.json", docsHandler.Document)
+	if cfg.APIDocs {
+		r.Get("/docs", docsHandler.UI)
+		r.Get("/docs/init.js", docsHandler.Script)
+	}
+
+	// Liveness and readiness probes; readiness pings the database and Redis
+	healthHandler := handler.NewHealthHandler(0, s.healthChecks()...)
+	r.Get("/healthz", healthHandler.Live)
+	r.Get("/readyz", healthHandler.Ready)
+
+	// Auth routes with strict rate limiting
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RateLimiter(limitOpts("auth", "strict")...))
+		r.With(middleware.Idempotency(idempotencyStore, idempotencyTTL)).Post("/auth/register", authHandler.Register)
+		r.Post("/auth/login", authHandler.Login)
+		r.With(middleware.Authenticate(authService.PasswordChangeTokens()), middleware.DenyImpersonation).Post("/auth/password", authHandler.ChangePassword)
+		r.Get("/auth/csrf", handler.NewCSRFHandler(csrf).Token)
+		r.Get("/auth/{provider}/login", socialHandler.Login)
+		r.Get("/auth/{provider}/callback", socialHandler.Callback)
+	})
+
+	// SAML service provider routes
+	if samlHandler != nil {
+		r.Get("/saml/metadata", samlHandler.Metadata)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RateLimiter(limitOpts("saml", "strict")...))
+			r.Get("/saml/login", samlHandler.Login)
+			r.Post("/saml/acs", samlHandler.ACS)
+		})
+	}
+
+	// OpenID Provider routes
+	if oidcHandler != nil {
+		r.Get("/.well-known/openid-configuration", oidcHandler.Discovery)
+		r.Get("/.well-known/jwks.json", oidcHandler.JWKS)
+		r.Get("/userinfo", oidcHandler.UserInfo)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RateLimiter(limitOpts("oidc", "strict")...))
+			r.Get("/authorize", oidcHandler.Authorize)
+			r.Post("/authorize", oidcHandler.Authorize)
+			r.Post("/token", oidcHandler.Token)
+			r.Post("/auth/token-exchange", oidcHandler.TokenExchange)
+		})
+	}
+
+	// GraphQL over the same services; each field checks whether it needs a user
+	if cfg.GraphQL {
+		graphQLHandler := handler.NewGraphQLHandler(authHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RateLimiter(limitOpts("graphql", "strict")...))
+			r.Use(middleware.Identify(middleware.JWTAuthenticator(authService), middleware.APIKeyAuthenticator(apiKeyService)))
+			r.Get("/graphql", graphQLHandler.Serve)
+			r.Post("/graphql", graphQLHandler.Serve)
+		})
+	}
+
+	// Protected routes; see config.DefaultRoutePolicies for how each authenticates
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.UserRateLimiter(limitOpts("protected", "default")...))
+		r.With(middleware.Authenticate(authService)).Post("/auth/logout", authHandler.Logout)
+		r.Get("/auth/me", profileHandler.Me)
+		r.Patch("/auth/me", profileHandler.UpdateMe)
+		r.Get("/auth/me/consents", consentHandler.List)
+		r.Post("/auth/me/consents", consentHandler.Record)
+		r.Get("/auth/me/terms", consentHandler.Terms)
+		r.Post("/auth/me/terms", consentHandler.AcceptTerms)
+		if stores.Audit != nil {
+			r.Get("/auth/me/login-history", handler.NewLoginHistoryHandler(stores.Audit, authService).List)
+		}
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope("api-keys"))
+			r.With(middleware.DenyImpersonation).Post("/auth/api-keys", apiKeyHandler.Create)
+			r.Get("/auth/api-keys", apiKeyHandler.List)
+			r.Delete("/auth/api-keys/{id}", apiKeyHandler.Revoke)
+		})
+	})
+
+	// Admin routes with stricter rate limits and velocity alerts on bulk operations.
+	// Without an admin token or break-glass credential only admin-role users get in.
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.AdminRateLimiter(auditLogger, limitOpts("admin", "admin")...))
+		if breakGlassService != nil {
+			breakGlassHandler := handler.NewBreakGlassHandler(breakGlassService)
+			r.With(middleware.RateLimiter(limitOpts("break-glass", "strict")...)).Post("/break-glass", breakGlassHandler.Redeem)
+		}
+		r.Group(func(r chi.Router) {
+			if breakGlassService != nil {
+				r.Use(middleware.BreakGlassAccess(breakGlassService, auditLogger))
+			}
+			r.Use(middleware.RequireAdminToken(cfg.AdminAPIToken))
+			r.Post("/oauth/clients", adminHandler.CreateClient)
+			r.Post("/users", userAdminHandler.Create)
+			r.Post("/users/import", userAdminHandler.Import)
+			r.Put("/users/{id}/canary", adminHandler.SetCanary)
+			r.Post("/service-accounts", serviceAccountHandler.Create)
+			r.Get("/service-accounts", serviceAccountHandler.List)
+			r.Put("/service-accounts/{id}/locked", serviceAccountHandler.SetLocked)
+			r.Delete("/service-accounts/{id}", serviceAccountHandler.Delete)
+			r.Post("/service-accounts/{id}/api-keys", serviceAccountHandler.CreateAPIKey)
+			r.Post("/tenants", tenantHandler.Create)
+			r.Get("/tenants", tenantHandler.List)
+			r.Get("/tenants/{id}", tenantHandler.Get)
+			r.Put("/tenants/{id}/settings", tenantHandler.Configure)
+			r.Post("/tenants/{id}/suspend", tenantHandler.Suspend)
+			r.Post("/tenants/{id}/activate", tenantHandler.Activate)
+			r.Delete("/tenants/{id}", tenantHandler.Delete)
+			r.Get("/usage", usageHandler.Get)
+			r.Get("/usage/export", usageHandler.Export)
+			r.Get("/usage/metrics", usageHandler.Metrics)
+			if stores.Audit != nil {
+				r.Get("/audit-events", handler.NewAuditHandler(stores.Audit).List)
+			}
+			if stores.Webhooks != nil {
+				r.Get("/webhooks/deliveries", handler.NewWebhookHandler(stores.Webhooks).ListDeliveries)
+			}
+			if domainPolicy.RuntimeBlocking() {
+				emailDomainHandler := handler.NewEmailDomainHandler(domainPolicy, auditLogger)
+				r.Get("/email-domains", emailDomainHandler.List)
+				r.Post("/email-domains", emailDomainHandler.Block)
+				r.Delete("/email-domains/{domain}", emailDomainHandler.Unblock)
+			}
+		})
+
+		// User management is also open to users with the admin role, scoped to their tenant
+		r.Group(func(r chi.Router) {
+			if breakGlassService != nil {
+				r.Use(middleware.BreakGlassAccess(breakGlassService, auditLogger))
+			}
+			r.Use(middleware.RequireAdmin(cfg.AdminAPIToken, authService, adminLookup(stores.Users)))
+			r.Get("/users", userAdminHandler.List)
+			r.Get("/users/export", userAdminHandler.Export)
+			r.Get("/users/{id}", userAdminHandler.Get)
+			r.Delete("/users/{id}", userAdminHandler.Delete)
+			r.Post("/users/{id}/restore", userAdminHandler.Restore)
+			r.Get("/users/{id}/sessions", userAdminHandler.Sessions)
+			r.Put("/users/{id}/disabled", userAdminHandler.SetDisabled)
+			r.Get("/users/{id}/metadata", userAdminHandler.Metadata)
+			r.Patch("/users/{id}/metadata", userAdminHandler.UpdateMetadata)
+			r.Post("/users/{id}/password-reset", userAdminHandler.ResetPassword)
+			r.Post("/users/{id}/password-expiry", userAdminHandler.ExpirePassword)
+			r.Delete("/users/{id}/lockout", userAdminHandler.Unlock)
+			r.With(middleware.VelocityAlert("session_revocation", 20, time.Minute, auditLogger)).
+				Post("/users/{id}/sessions/revoke", userAdminHandler.RevokeSessions)
+			r.Post("/users/{id}/impersonate", userAdminHandler.Impersonate)
+		})
+	})
+
+	// Profiling and runtime stats for diagnosing production issues, admin only.
+	// Break-glass sessions do not unlock them.
+	if cfg.DebugEndpoints {
+		r.Route("/debug", func(r chi.Router) {
+			r.Use(middleware.AdminRateLimiter(auditLogger, limitOpts("admin", "admin")...))
+			r.Use(middleware.RequireAdminToken(cfg.AdminAPIToken))
+			r.Mount("/", chimiddleware.Profiler())
+		})
+	}
+
+	// Routes supplied by the embedding application
+	for _, register := range o.routes {
+		register(r)
+	}
+
+	document, err := servedAPIDocument(r)
+	if err != nil {
+		return nil, err
+	}
+	docsHandler.SetDocument(document)
+
+	return r, nil
+}
+
+// securityHeaderOpts applies the configured Content-Security-Policy and HSTS lifetime
+func securityHeaderOpts(cfg *config.Config) []middleware.SecurityHeadersOption {
+	opts := []middleware.SecurityHeadersOption{middleware.WithHSTSMaxAge(cfg.HSTSMaxAge)}
+	switch cfg.ContentSecurityPolicy {
+	case "":
+	case "off":
+		opts = append(opts, middleware.WithContentSecurityPolicy(""))
+	default:
+		opts = append(opts, middleware.WithContentSecurityPolicy(cfg.ContentSecurityPolicy))
+	}
+	return opts
+}
+
+// liveLimits converts the configured rate limits to the limits of the
+// default, strict and admin tiers and the route overrides
+func liveLimits(limits config.RateLimits) (map[string]middleware.Limit, middleware.RouteLimits) {
+	tiers := map[string]middleware.Limit{
+		"default": limitOf(limits.Default, middleware.DefaultLimit),
+		"strict":  limitOf(limits.Strict, middleware.StrictLimit),
+		"admin":   limitOf(limits.Admin, middleware.AdminLimit),
+	}
+	routes := make(middleware.RouteLimits, len(limits.Routes))
+	for pattern, limit := range limits.Routes {
+		routes[pattern] = limitOf(limit, tiers["default"])
+	}
+	return tiers, routes
+}
+
+// limitOf converts a configured rate limit, using fallback when it is unset
+func limitOf(limit config.RateLimit, fallback middleware.Limit) middleware.Limit {
+	if limit.Requests == 0 {
+		return fallback
+	}
+	return middleware.Every(limit.Requests, limit.Window, limit.Burst)
+}
+
+// rateLimitStore returns the configured store for rate limit counters
+func (s *Server) rateLimitStore() (middleware.RateLimitStore, error) {
+	if s.cfg.RateLimitStore != "redis" {
+		s.limitStore = middleware.NewMemoryRateLimitStore(middleware.WithStoreMetrics(s.registry))
+		return s.limitStore, nil
+	}
+	client, err := s.redisClient()
+	if err != nil {
+		return nil, err
+	}
+	return middleware.NewRedisRateLimitStore(client), nil
+}
+
+// redisClient returns the Redis client shared by rate limits and sessions,
+// connecting on first use
+func (s *Server) redisClient() (*redis.Client, error) {
+	if s.redis != nil {
+		return s.redis, nil
+	}
+	opts, err := redis.ParseURL(s.cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+	}
+	s.redis = redis.NewClient(opts)
+	return s.redis, nil
+}
+
+// adminLookup grants admin access to users with the admin role, scoped to their tenant
+func adminLookup(users interfaces.UserRepository) middleware.AdminLookup {
+	return func(ctx context.Context, userID int64) (bool, *int64, error) {
+		user, err := users.GetUserByID(ctx, userID)
+		if err != nil {
+			return false, nil, err
+		}
+		return user.Role == model.RoleAdmin, user.TenantID, nil
+	}
+}
+
+// loadSigningKey reads the OIDC signing key, generating an ephemeral one when no file is configured
+func loadSigningKey(path string) (*oidc.SigningKey, error) {
+	if path == "" {
+		slog.Warn("OIDC_SIGNING_KEY_FILE not set, using an ephemeral signing key")
+		return oidc.GenerateSigningKey()
+	}
+	return oidc.LoadSigningKey(path)
+}
@@ -0,0 +1,834 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stewz00/go-auth-service/internal/audit"
+	"github.com/Stewz00/go-auth-service/internal/config"
+	"github.com/Stewz00/go-auth-service/internal/events"
+	"github.com/Stewz00/go-auth-service/internal/handler"
+	"github.com/Stewz00/go-auth-service/internal/model"
+	"github.com/Stewz00/go-auth-service/internal/pagination"
+	"github.com/Stewz00/go-auth-service/internal/problem"
+	"github.com/Stewz00/go-auth-service/internal/test"
+	"github.com/Stewz00/go-auth-service/internal/webhook"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+// eventRecorder collects audit events in memory
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (r *eventRecorder) Record(ctx context.Context, event audit.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestServerInProcess(t *testing.T) {
+	userRepo := test.NewMockUserRepository()
+	usageRepo := test.NewMockUsageRepository()
+	recorder := &eventRecorder{}
+	cfg := &config.Config{
+		JwtSecret:      "test-secret",
+		Environment:    "test",
+		RoutePolicies:  config.DefaultRoutePolicies(),
+		AdminAPIToken:  "admin-test-token",
+		DebugEndpoints: true,
+		RateLimits: config.RateLimits{
+			Routes: map[string]config.RateLimit{"/custom": {Requests: 1, Window: time.Minute, Burst: 1}},
+		},
+	}
+
+	srv, err := New(cfg,
+		WithStores(Stores{
+			Users:      userRepo,
+			Identities: test.NewMockIdentityRepository(userRepo),
+			OAuth:      test.NewMockOAuthRepository(),
+			Consents:   test.NewMockConsentRepository(),
+			APIKeys:    test.NewMockAPIKeyRepository(),
+			BreakGlass: test.NewMockBreakGlassRepository(),
+			Tenants:    test.NewMockTenantRepository(userRepo),
+			Usage:      usageRepo,
+			Audit:      test.NewMockAuditRepository(),
+		}),
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Embedded", "true")
+				next.ServeHTTP(w, r)
+			})
+		}),
+		WithRoutes(func(r chi.Router) {
+			r.Get("/custom", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+		}),
+		WithAuditLogger(recorder),
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body, token string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		return resp
+	}
+
+	resp := do("POST", "/auth/register", `{"email":"test@example.com","password":"password123"}`, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: got status %v, want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if resp.Header.Get("X-Embedded") != "true" {
+		t.Error("custom middleware did not run")
+	}
+	if resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("security headers were not set")
+	}
+
+	resp = do("POST", "/auth/login", `{"email":"test@example.com","password":"password123"}`, "")
+	var auth struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&auth)
+	resp.Body.Close()
+	if auth.Token == "" {
+		t.Fatalf("login: expected token, got status %v", resp.StatusCode)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		wantStatusCode int
+	}{
+		{name: "protected route without token", method: "POST", path: "/auth/api-keys", wantStatusCode: http.StatusUnauthorized},
+		{name: "protected route with token", method: "POST", path: "/auth/api-keys", token: auth.Token, wantStatusCode: http.StatusCreated},
+		{name: "extra route", method: "GET", path: "/custom", wantStatusCode: http.StatusTeapot},
+		{name: "user management without admin role", method: "GET", path: "/admin/users", token: auth.Token, wantStatusCode: http.StatusForbidden},
+		{name: "user management with admin token", method: "GET", path: "/admin/users", token: "admin-test-token", wantStatusCode: http.StatusOK},
+		{name: "impersonation needs a signed-in admin", method: "POST", path: "/admin/users/1/impersonate", token: "admin-test-token", wantStatusCode: http.StatusForbidden},
+		{name: "audit events with admin token", method: "GET", path: "/admin/audit-events?limit=10", token: "admin-test-token", wantStatusCode: http.StatusOK},
+		{name: "audit events with invalid cursor", method: "GET", path: "/admin/audit-events?cursor=x", token: "admin-test-token", wantStatusCode: http.StatusBadRequest},
+		{name: "debug endpoints without admin token", method: "GET", path: "/debug/vars", token: auth.Token, wantStatusCode: http.StatusUnauthorized},
+		{name: "debug endpoints with admin token", method: "GET", path: "/debug/vars", token: "admin-test-token", wantStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(tt.method, tt.path, `{"name":"ci"}`, tt.token)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("got status %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+	t.Run("route rate limit override", func(t *testing.T) {
+		resp := do("GET", "/custom", "", "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("X-RateLimit-Limit") != "1" {
+			t.Errorf("got status %v and limit %q, want the second request rejected by a limit of 1",
+				resp.StatusCode, resp.Header.Get("X-RateLimit-Limit"))
+		}
+	})
+	t.Run("security metrics in OpenMetrics format", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", ts.URL+"/metrics", nil)
+		req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to /metrics failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), `auth_security_lockouts_total{tenant=""} 0`) ||
+			!strings.Contains(string(body), "auth_ratelimit_visitors ") || !strings.HasSuffix(string(body), "# EOF\n") {
+			t.Errorf("unexpected metrics:\n%s", body)
+		}
+	})
+	t.Run("request and login metrics", func(t *testing.T) {
+		resp := do("GET", "/metrics", "", "")
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		for _, want := range []string{
+			`auth_logins_total{outcome="success"} 1`,
+			`auth_registrations_total 1`,
+			`auth_ratelimit_rejections_total{limiter="global"} 1`,
+			`auth_http_request_duration_seconds_count{method="POST",route="/auth/login",status="200"} 1`,
+		} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("metrics missing %s", want)
+			}
+		}
+	})
+	t.Run("login attempts are audited after flush", func(t *testing.T) {
+		srv.Close()
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		for _, event := range recorder.events {
+			if event.Type == "auth.login_succeeded" {
+				return
+			}
+		}
+		t.Errorf("no login event in %v", recorder.events)
+	})
+	t.Run("logins are metered after flush", func(t *testing.T) {
+		srv.Close()
+		report, _ := usageRepo.GetUsage(context.Background(), model.UsagePeriod(time.Now()))
+		if len(report.Tenants) != 1 || report.Tenants[0].Logins != 1 || report.Tenants[0].MonthlyActiveUsers != 1 {
+			t.Errorf("unexpected usage %+v", report.Tenants)
+		}
+	})
+}
+
+func TestServerRedisSessions(t *testing.T) {
+	mr := miniredis.RunT(t)
+	userRepo := test.NewMockUserRepository()
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		SessionStore:  "redis",
+		RedisURL:      "redis://" + mr.Addr(),
+	}
+
+	srv, err := New(cfg, WithStores(Stores{
+		Users:      userRepo,
+		Identities: test.NewMockIdentityRepository(userRepo),
+		OAuth:      test.NewMockOAuthRepository(),
+		Consents:   test.NewMockConsentRepository(),
+		APIKeys:    test.NewMockAPIKeyRepository(),
+		BreakGlass: test.NewMockBreakGlassRepository(),
+		Tenants:    test.NewMockTenantRepository(userRepo),
+		Usage:      test.NewMockUsageRepository(),
+	}))
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for _, path := range []string{"/auth/register", "/auth/login"} {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	user, err := userRepo.GetUserByEmail(context.Background(), "test@example.com")
+	if err != nil {
+		t.Fatalf("user not registered: %v", err)
+	}
+	if !mr.Exists("session_index:" + strconv.FormatInt(user.ID, 10)) {
+		t.Errorf("session not stored in Redis, keys: %v", mr.Keys())
+	}
+	if sessions, _, _ := userRepo.ListSessions(context.Background(), user.ID, pagination.Page{Limit: 10}); len(sessions) != 0 {
+		t.Errorf("user store holds %d sessions, want 0", len(sessions))
+	}
+}
+
+func TestServerMemoryStorage(t *testing.T) {
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		Storage:       "memory",
+		Lockout:       model.LockoutPolicy{MaxFailedAttempts: 3},
+	}
+
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("/auth/register", `{"email":"test@example.com","password":"password123"}`); status != http.StatusCreated {
+		t.Fatalf("register: got status %v, want %v", status, http.StatusCreated)
+	}
+	resp, err := http.Post(ts.URL+"/auth/login", "application/json", strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+	if err != nil {
+		t.Fatalf("request to /auth/login failed: %v", err)
+	}
+	var auth struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&auth)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: got status %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	// Failed attempts are counted, so the lockout policy applies
+	for range 3 {
+		post("/auth/login", `{"email":"test@example.com","password":"wrong-password"}`)
+	}
+	if status := post("/auth/login", `{"email":"test@example.com","password":"password123"}`); status == http.StatusOK {
+		t.Error("expected the account to be locked")
+	}
+
+	// Attempts show in the user's login history, newest first, once the audit
+	// queue has written them; throttled attempts are not recorded
+	want := []string{"locked", "invalid_credentials", "invalid_credentials", "success"}
+	var outcomes []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/auth/me/login-history", nil)
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to /auth/me/login-history failed: %v", err)
+		}
+		var history struct {
+			Attempts []handler.LoginAttempt `json:"attempts"`
+		}
+		json.NewDecoder(resp.Body).Decode(&history)
+		resp.Body.Close()
+		outcomes = outcomes[:0]
+		for _, attempt := range history.Attempts {
+			outcomes = append(outcomes, attempt.Outcome)
+		}
+		if len(outcomes) >= len(want) {
+			break
+		}
+	}
+	if !slices.Equal(outcomes, want) {
+		t.Errorf("login history: got outcomes %v, want %v", outcomes, want)
+	}
+
+	// Error details follow Accept-Language
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "de-CH, en;q=0.5")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request to /auth/login failed: %v", err)
+	}
+	var p problem.Problem
+	json.NewDecoder(resp.Body).Decode(&p)
+	resp.Body.Close()
+	if p.Code != problem.AccountLocked || p.Detail != "Das Konto ist nach zu vielen fehlgeschlagenen Versuchen gesperrt" || resp.Header.Get("Content-Language") != "de" {
+		t.Errorf("got %+v in %q, want the German ACCOUNT_LOCKED problem", p, resp.Header.Get("Content-Language"))
+	}
+
+	resp, err = http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("request to /readyz failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("readyz: got status %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServerUserExport(t *testing.T) {
+	srv, err := New(&config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		Storage:       "memory",
+		AdminAPIToken: "admin-test-token",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"email":"ann@example.com","password":"password123","display_name":"=HYPERLINK(1)"}`,
+		`{"email":"bob@example.com","password":"password123"}`,
+	} {
+		resp, err := http.Post(ts.URL+"/auth/register", "application/json", strings.NewReader(body))
+		if err != nil || resp.StatusCode != http.StatusCreated {
+			t.Fatalf("register failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantType   string
+		want       string
+	}{
+		{name: "csv", query: "fields=id,email,display_name,tenant_id", wantStatus: http.StatusOK, wantType: "text/csv",
+			want: "id,email,display_name,tenant_id\n1,ann@example.com,'=HYPERLINK(1),\n2,bob@example.com,,\n"},
+		{name: "ndjson with a filter", query: "format=ndjson&fields=email,status&email=bob", wantStatus: http.StatusOK, wantType: "application/x-ndjson",
+			want: `{"email":"bob@example.com","status":"active"}` + "\n"},
+		{name: "all fields", query: "", wantStatus: http.StatusOK, wantType: "text/csv",
+			want: "id,email,type,role,tenant_id,status,locked,disabled,email_verified,canary,created_at,last_login,deleted_at,display_name,"},
+		{name: "unknown field", query: "fields=email,password_hash", wantStatus: http.StatusBadRequest},
+		{name: "unknown format", query: "format=xml", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/admin/users/export?"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer admin-test-token")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("got status %v, want %v", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if resp.Header.Get("Content-Type") != tt.wantType || !strings.HasPrefix(string(body), tt.want) {
+				t.Errorf("got %q as %s, want %q as %s", body, resp.Header.Get("Content-Type"), tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestServerGraphQL(t *testing.T) {
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		Storage:       "memory",
+		GraphQL:       true,
+	}
+
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	// do runs a GraphQL request and returns the status and the response body
+	do := func(token, query string, variables map[string]any) (int, string) {
+		body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to /graphql failed: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	credentials := map[string]any{"email": "gql@example.com", "password": "password123"}
+	if _, body := do("", `mutation ($email: String!, $password: String!) { register(email: $email, password: $password) { email role } }`, credentials); body != `{"data":{"register":{"email":"gql@example.com","role":"user"}}}` {
+		t.Fatalf("register: got %s", body)
+	}
+
+	_, body := do("", `mutation { login(email: "gql@example.com", password: "wrong-password") { token } }`, nil)
+	if !strings.Contains(body, `"code":"UNAUTHENTICATED"`) {
+		t.Errorf("login with a wrong password: got %s", body)
+	}
+
+	_, body = do("", `mutation ($email: String!, $password: String!) { login(email: $email, password: $password) { token passwordExpired } }`, credentials)
+	var login struct {
+		Data struct{ Login struct{ Token string } }
+	}
+	if err := json.Unmarshal([]byte(body), &login); err != nil || login.Data.Login.Token == "" {
+		t.Fatalf("login: got %s", body)
+	}
+	token := login.Data.Login.Token
+
+	if _, body := do("", `{ me { email } }`, nil); !strings.Contains(body, `"code":"UNAUTHENTICATED"`) {
+		t.Errorf("me without a token: got %s", body)
+	}
+	if _, body := do(token, `{ me { email } sessions(first: 10) { sessions { id } nextCursor } }`, nil); !strings.Contains(body, `"me":{"email":"gql@example.com"}`) || !strings.Contains(body, `"nextCursor":null`) {
+		t.Errorf("me and sessions: got %s", body)
+	}
+
+	// Several logins in one request would get around the rate limit
+	status, _ := do("", `mutation { a: login(email: "gql@example.com", password: "x") { token } b: login(email: "gql@example.com", password: "y") { token } }`, nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("two mutations: got status %d, want %d", status, http.StatusBadRequest)
+	}
+	resp, err := http.Get(ts.URL + "/graphql?query=" + url.QueryEscape(`mutation { logout }`))
+	if err != nil {
+		t.Fatalf("GET /graphql failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("mutation over GET: got status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	if _, body := do(token, `mutation { logout }`, nil); body != `{"data":{"logout":true}}` {
+		t.Errorf("logout: got %s", body)
+	}
+	if _, body := do(token, `{ me { email } }`, nil); !strings.Contains(body, `"code":"UNAUTHENTICATED"`) {
+		t.Errorf("me after logout: got %s", body)
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		Storage:       "memory",
+		TokenTTL:      time.Hour,
+	}
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(path, body string) *http.Response {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	post("/auth/register", `{"email":"test@example.com","password":"password123"}`)
+	if limit := post("/auth/login", `{"email":"test@example.com","password":"password123"}`).Header.Get("X-RateLimit-Limit"); limit != "10" {
+		t.Fatalf("X-RateLimit-Limit = %q, want the default strict limit 10", limit)
+	}
+
+	next := *cfg
+	next.JwtSecret, next.PreviousJwtSecrets = "rotated-secret", []string{cfg.JwtSecret}
+	next.TokenTTL = 15 * time.Minute
+	next.TosVersion = "2026-10"
+	next.RateLimits = config.RateLimits{Strict: config.RateLimit{Requests: 50, Window: time.Minute, Burst: 50}}
+	srv.Reload(&next)
+
+	if limit := post("/auth/login", `{"email":"test@example.com","password":"password123"}`).Header.Get("X-RateLimit-Limit"); limit != "50" {
+		t.Errorf("X-RateLimit-Limit = %q after reload, want 50", limit)
+	}
+	if srv.auth.TokenExpiry() != 15*time.Minute {
+		t.Errorf("TokenExpiry() = %v after reload, want 15m", srv.auth.TokenExpiry())
+	}
+	if srv.consents.TermsVersions().TermsOfService != "2026-10" {
+		t.Errorf("TermsVersions() = %+v after reload, want the new terms of service", srv.consents.TermsVersions())
+	}
+	if !slices.Equal(srv.jwtSecrets, []string{"rotated-secret", cfg.JwtSecret}) {
+		t.Error("the JWT secret was not rotated")
+	}
+
+	next.Port, next.Storage = 9999, "database"
+	if got := restartRequired(cfg, &next); len(got) != 2 || got[0] != "PORT" || got[1] != "STORAGE" {
+		t.Errorf("restartRequired() = %v, want [PORT STORAGE]", got)
+	}
+}
+
+func TestServerACME(t *testing.T) {
+	acme, err := config.ParseACME("auth.example.com", t.TempDir(), "", "8081", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		ACME:          acme,
+	}
+	userRepo := test.NewMockUserRepository()
+	srv, err := New(cfg, WithStores(Stores{
+		Users:      userRepo,
+		Identities: test.NewMockIdentityRepository(userRepo),
+		OAuth:      test.NewMockOAuthRepository(),
+		Consents:   test.NewMockConsentRepository(),
+		APIKeys:    test.NewMockAPIKeyRepository(),
+		BreakGlass: test.NewMockBreakGlassRepository(),
+		Tenants:    test.NewMockTenantRepository(userRepo),
+		Usage:      test.NewMockUsageRepository(),
+	}))
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	tlsConfig := srv.httpServer.TLSConfig
+	if tlsConfig == nil || tlsConfig.GetCertificate == nil || !slices.Contains(tlsConfig.NextProtos, "acme-tls/1") {
+		t.Fatal("HTTPS is not configured with certificates from ACME")
+	}
+	if srv.challenges == nil || srv.challenges.Addr != ":8081" {
+		t.Fatal("no challenge server on ACME_HTTP_PORT")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.challenges.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://auth.example.com/auth/login?next=1", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://auth.example.com/auth/login?next=1" {
+		t.Errorf("plain HTTP request got %d to %q, want a redirect to HTTPS", rec.Code, rec.Header().Get("Location"))
+	}
+	rec = httptest.NewRecorder()
+	srv.challenges.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://auth.example.com/.well-known/acme-challenge/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown challenge got %d, want 404", rec.Code)
+	}
+}
+
+func TestServerWebhooks(t *testing.T) {
+	const secret = "0123456789abcdef"
+	var mu sync.Mutex
+	var received []events.Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify(secret, r.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+			t.Errorf("delivery with bad signature: %v", err)
+		}
+		var event events.Event
+		json.Unmarshal(body, &event)
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		AdminAPIToken: "admin-test-token",
+		Storage:       "memory",
+		Lockout:       model.LockoutPolicy{MaxFailedAttempts: 2},
+		Webhooks:      []webhook.Endpoint{{URL: receiver.URL, Secret: secret}},
+	}
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/auth/register", "application/json",
+		strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	resp.Body.Close()
+	for _, password := range []string{"password123", "wrong-password", "wrong-password"} {
+		resp, err := http.Post(ts.URL+"/auth/login", "application/json",
+			strings.NewReader(`{"email":"test@example.com","password":"`+password+`"}`))
+		if err != nil {
+			t.Fatalf("login failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	want := []string{events.UserRegistered, events.UserLogin, events.UserLocked}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n >= len(want) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	var got []string
+	for _, event := range received {
+		got = append(got, event.Type)
+	}
+	mu.Unlock()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("received events %v, want %v", got, want)
+	}
+
+	// Every attempt is in the delivery log, written once the endpoint answered
+	var log struct {
+		Deliveries []model.WebhookDelivery `json:"deliveries"`
+	}
+	for len(log.Deliveries) == 0 && time.Now().Before(deadline) {
+		req, _ := http.NewRequest("GET", ts.URL+"/admin/webhooks/deliveries?event_type=user.locked", nil)
+		req.Header.Set("Authorization", "Bearer admin-test-token")
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to the delivery log failed: %v", err)
+		}
+		json.NewDecoder(resp.Body).Decode(&log)
+		resp.Body.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(log.Deliveries) != 1 || !log.Deliveries[0].Delivered || log.Deliveries[0].URL != receiver.URL {
+		t.Errorf("delivery log: got %+v, want one delivered user.locked attempt", log.Deliveries)
+	}
+}
+
+func TestServerEventBus(t *testing.T) {
+	produced := make(chan string, 10)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Records []struct {
+				Value events.Event `json:"value"`
+			} `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, record := range body.Records {
+			produced <- r.URL.Path + " " + record.Value.Type
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":0}]}`))
+	}))
+	defer proxy.Close()
+
+	cfg := &config.Config{
+		JwtSecret:     "test-secret",
+		Environment:   "test",
+		RoutePolicies: config.DefaultRoutePolicies(),
+		Storage:       "memory",
+		EventBus:      "kafka",
+		KafkaRESTURL:  proxy.URL,
+		KafkaTopic:    "identity",
+	}
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/auth/register", "application/json",
+		strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case got := <-produced:
+		if got != "/topics/identity user.registered" {
+			t.Errorf("produced %q, want user.registered to the identity topic", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event produced")
+	}
+}
+
+func TestAPIDocumentMatchesRoutes(t *testing.T) {
+	cfg := &config.Config{
+		JwtSecret:                "test-secret",
+		Environment:              "test",
+		RoutePolicies:            config.DefaultRoutePolicies(),
+		Storage:                  "memory",
+		AdminAPIToken:            "admin-test-token",
+		DebugEndpoints:           true,
+		OIDCIssuer:               "https://auth.example.com",
+		BreakGlassCredentialHash: strings.Repeat("ab", 32),
+		BreakGlassExpiresAt:      time.Now().Add(time.Hour),
+		GraphQL:                  true,
+		APIDocs:                  true,
+	}
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	var served []string
+	chi.Walk(srv.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		if !slices.ContainsFunc(undocumentedPrefixes, func(prefix string) bool { return strings.HasPrefix(route, prefix) }) {
+			served = append(served, method+" "+route)
+		}
+		return nil
+	})
+	slices.Sort(served)
+
+	// SAML needs an identity provider, so its routes are not served here
+	var documented []string
+	for _, op := range apiDocument().Operations() {
+		if !strings.Contains(op, " /saml/") {
+			documented = append(documented, op)
+		}
+	}
+
+	for _, op := range served {
+		if !slices.Contains(documented, op) {
+			t.Errorf("%s is served but missing from apiDocument", op)
+		}
+	}
+	for _, op := range documented {
+		if !slices.Contains(served, op) {
+			t.Errorf("%s is documented but not served", op)
+		}
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("request to /openapi.json failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil || doc.OpenAPI != "3.0.3" {
+		t.Fatalf("got document %+v (%v)", doc, err)
+	}
+	if _, ok := doc.Paths["/saml/acs"]; ok {
+		t.Error("the served document must leave out routes that are not enabled")
+	}
+	if _, ok := doc.Paths["/admin/users/{id}"]["delete"]; !ok {
+		t.Error("the served document is missing DELETE /admin/users/{id}")
+	}
+
+	// Both check client secrets, so both get the strict limit
+	for _, path := range []string{"/token", "/auth/token-exchange"} {
+		resp, err := http.PostForm(ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if limit := resp.Header.Get("X-RateLimit-Limit"); limit != "10" {
+			t.Errorf("%s: X-RateLimit-Limit = %q, want the strict limit 10", path, limit)
+		}
+	}
+}